@@ -21,14 +21,47 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/apache/synapse-go/internal/app/synapse"
+	"github.com/apache/synapse-go/internal/pkg/core/profile"
 )
 
 func main() {
+	commands := map[string]func(args []string, out io.Writer) error{
+		"replay":      synapse.RunReplayCommand,
+		"import-wsdl": synapse.RunWSDLImportCommand,
+		"export-apis": synapse.RunExportCommand,
+	}
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	flags := flag.NewFlagSet("synapse", flag.ExitOnError)
+	profileFlag := flags.String("profile", "", "configuration profile such as dev, test or prod, defaults to $"+profile.Env)
+	flags.Parse(os.Args[1:])
+	name, err := profile.Resolve(*profileFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	profile.SetActive(name)
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
-	synapse.Run(ctx)
+	if err := synapse.Run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,245 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package batch accumulates the messages of an inbound endpoint into batches
+// and mediates each batch as a single JSON array, which reduces the
+// per-message overhead of high-volume ingestion such as telemetry.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// SizeProperty holds the number of messages in a batch
+	SizeProperty = "BATCH_SIZE"
+
+	// DefaultWindow bounds how long a batch limited only by size stays open
+	DefaultWindow = time.Second
+)
+
+// ParameterSchema describes the batching parameters shared by every inbound
+// protocol. A batch is delivered when it holds inbound.batch.size messages or
+// when inbound.batch.window has passed since its first message.
+var ParameterSchema = domain.ParameterSchema{
+	Parameters: []domain.ParameterSpec{
+		{Name: "inbound.batch.size", Type: domain.ParameterInt, Min: 1, Max: 100000},
+		{Name: "inbound.batch.window", Type: domain.ParameterString, Check: func(value string) error {
+			if window, err := time.ParseDuration(value); err != nil || window <= 0 {
+				return fmt.Errorf("must be a positive duration such as 500ms or 5s")
+			}
+			return nil
+		}},
+	},
+}
+
+// Config is the batching configuration of an inbound endpoint
+type Config struct {
+	// Size is the number of messages that completes a batch; 0 for no limit
+	Size   int
+	Window time.Duration
+}
+
+// ConfigFromParameters reads validated batching parameters and reports
+// whether batching is enabled
+func ConfigFromParameters(parameters map[string]string) (Config, bool) {
+	var config Config
+	config.Size, _ = strconv.Atoi(parameters["inbound.batch.size"])
+	config.Window, _ = time.ParseDuration(parameters["inbound.batch.window"])
+	if config.Size <= 1 && config.Window == 0 {
+		return Config{}, false
+	}
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+	return config, true
+}
+
+// pending is a message waiting for its batch to be mediated
+type pending struct {
+	msg  *synctx.MsgContext
+	done chan error
+}
+
+// Batcher is an InboundMessageMediator that groups messages into batches
+// before passing them on. Each caller blocks until its batch is mediated and
+// receives the batch's result, so endpoints acknowledge a message, e.g. by
+// deleting its file, only once it has been processed.
+type Batcher struct {
+	next   ports.InboundMessageMediator
+	config Config
+
+	mu       sync.Mutex
+	ctx      context.Context
+	sequence string
+	buffer   []pending
+	// generation identifies the open batch, so a window timer that fires
+	// after its batch was delivered by size does not cut the next one short
+	generation uint64
+	timer      *time.Timer
+}
+
+func NewBatcher(next ports.InboundMessageMediator, config Config) *Batcher {
+	return &Batcher{next: next, config: config}
+}
+
+// MediateInboundMessage adds the message to the open batch and waits for the
+// batch to be mediated
+func (b *Batcher) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	p := pending{msg: msg, done: make(chan error, 1)}
+
+	b.mu.Lock()
+	if len(b.buffer) > 0 && seqName != b.sequence {
+		// Messages for another sequence cannot share the open batch
+		batch, batchCtx, sequence := b.takeLocked()
+		b.mu.Unlock()
+		b.deliver(batchCtx, sequence, batch)
+		b.mu.Lock()
+	}
+	if len(b.buffer) == 0 {
+		b.ctx, b.sequence = ctx, seqName
+		generation := b.generation
+		b.timer = time.AfterFunc(b.config.Window, func() { b.flush(generation) })
+	}
+	b.buffer = append(b.buffer, p)
+	if b.config.Size > 0 && len(b.buffer) >= b.config.Size {
+		batch, batchCtx, sequence := b.takeLocked()
+		b.mu.Unlock()
+		b.deliver(batchCtx, sequence, batch)
+	} else {
+		b.mu.Unlock()
+	}
+	return <-p.done
+}
+
+// Flush delivers the open batch without waiting for it to fill, e.g. when the
+// endpoint shuts down
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	generation := b.generation
+	b.mu.Unlock()
+	b.flush(generation)
+}
+
+func (b *Batcher) flush(generation uint64) {
+	b.mu.Lock()
+	if generation != b.generation || len(b.buffer) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	batch, ctx, sequence := b.takeLocked()
+	b.mu.Unlock()
+	b.deliver(ctx, sequence, batch)
+}
+
+// takeLocked closes the open batch
+func (b *Batcher) takeLocked() ([]pending, context.Context, string) {
+	batch, ctx, sequence := b.buffer, b.ctx, b.sequence
+	b.buffer, b.ctx = nil, nil
+	b.generation++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return batch, ctx, sequence
+}
+
+func (b *Batcher) deliver(ctx context.Context, sequence string, batch []pending) {
+	messages := make([]*synctx.MsgContext, len(batch))
+	for i, p := range batch {
+		messages[i] = p.msg
+	}
+	aggregated, err := Aggregate(messages)
+	if err == nil {
+		err = b.next.MediateInboundMessage(ctx, sequence, aggregated)
+	}
+	for _, p := range batch {
+		p.done <- err
+	}
+}
+
+// Aggregate combines messages into one whose payload is a JSON array of their
+// payloads. JSON payloads are embedded as they are and other payloads as
+// strings. The properties and headers of the first message are kept.
+func Aggregate(messages []*synctx.MsgContext) (*synctx.MsgContext, error) {
+	payloads := make([]json.RawMessage, len(messages))
+	for i, msg := range messages {
+		payload := msg.Message.RawPayload
+		if !isJSON(msg.Message.ContentType) || !json.Valid(payload) {
+			encoded, err := json.Marshal(string(payload))
+			if err != nil {
+				return nil, err
+			}
+			payload = encoded
+		}
+		payloads[i] = payload
+	}
+	array, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, fmt.Errorf("cannot aggregate batch: %w", err)
+	}
+
+	aggregated := synctx.CreateMsgContext()
+	if len(messages) > 0 {
+		maps.Copy(aggregated.Properties, messages[0].Properties)
+		maps.Copy(aggregated.Headers, messages[0].Headers)
+	}
+	aggregated.Properties[SizeProperty] = len(messages)
+	aggregated.Message.RawPayload = array
+	aggregated.Message.ContentType = "application/json"
+	aggregated.Headers["Content-Type"] = "application/json"
+	return aggregated, nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// Endpoint batches the messages of an inbound endpoint
+type Endpoint struct {
+	ports.InboundEndpoint
+	config Config
+}
+
+// Wrap batches the messages the endpoint mediates
+func Wrap(endpoint ports.InboundEndpoint, config Config) *Endpoint {
+	return &Endpoint{InboundEndpoint: endpoint, config: config}
+}
+
+// Start runs the endpoint with a batching mediator. The open batch is
+// delivered when ctx is cancelled, so callers blocked on it are released.
+func (e *Endpoint) Start(ctx context.Context, mediator ports.InboundMessageMediator) error {
+	batcher := NewBatcher(mediator, e.config)
+	stop := context.AfterFunc(ctx, batcher.Flush)
+	defer stop()
+	return e.InboundEndpoint.Start(ctx, batcher)
+}
@@ -0,0 +1,159 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMediator records the batches it mediates
+type recordingMediator struct {
+	mu        sync.Mutex
+	batches   []*synctx.MsgContext
+	sequences []string
+	err       error
+}
+
+func (m *recordingMediator) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = append(m.batches, msg)
+	m.sequences = append(m.sequences, seqName)
+	return m.err
+}
+
+func (m *recordingMediator) payloads() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var payloads []string
+	for _, batch := range m.batches {
+		payloads = append(payloads, string(batch.Message.RawPayload))
+	}
+	return payloads
+}
+
+func message(contentType, payload string) *synctx.MsgContext {
+	msg := synctx.CreateMsgContext()
+	msg.Message.ContentType = contentType
+	msg.Message.RawPayload = []byte(payload)
+	return msg
+}
+
+// mediateAll sends the messages concurrently, in order, and returns their results
+func mediateAll(b *Batcher, messages ...*synctx.MsgContext) []error {
+	errs := make([]error, len(messages))
+	var wg sync.WaitGroup
+	for i, msg := range messages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = b.MediateInboundMessage(context.Background(), "ingest", msg)
+		}()
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestBatcher_DeliversBySize(t *testing.T) {
+	next := &recordingMediator{}
+	batcher := NewBatcher(next, Config{Size: 2, Window: time.Minute})
+
+	errs := mediateAll(batcher,
+		message("application/json", `{"cpu":0.5}`),
+		message("text/plain", "disk full"),
+		message("application/json", `{"cpu":0.7}`),
+		message("application/json", `{"cpu":0.9}`),
+	)
+	assert.Equal(t, []error{nil, nil, nil, nil}, errs)
+	assert.Equal(t, []string{`[{"cpu":0.5},"disk full"]`, `[{"cpu":0.7},{"cpu":0.9}]`}, next.payloads())
+	assert.Equal(t, []string{"ingest", "ingest"}, next.sequences)
+	assert.Equal(t, 2, next.batches[0].Properties[SizeProperty])
+	assert.Equal(t, "application/json", next.batches[0].Message.ContentType)
+}
+
+func TestBatcher_DeliversByWindow(t *testing.T) {
+	next := &recordingMediator{}
+	batcher := NewBatcher(next, Config{Size: 100, Window: 50 * time.Millisecond})
+
+	started := time.Now()
+	errs := mediateAll(batcher, message("application/json", `1`), message("application/json", `2`))
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond)
+	assert.Equal(t, []string{`[1,2]`}, next.payloads())
+}
+
+func TestBatcher_ReportsBatchFailure(t *testing.T) {
+	next := &recordingMediator{err: errors.New("sequence failed")}
+	batcher := NewBatcher(next, Config{Size: 2, Window: time.Minute})
+
+	errs := mediateAll(batcher, message("application/json", `1`), message("application/json", `2`))
+	for _, err := range errs {
+		assert.EqualError(t, err, "sequence failed")
+	}
+}
+
+func TestBatcher_Flush(t *testing.T) {
+	next := &recordingMediator{}
+	batcher := NewBatcher(next, Config{Window: time.Hour})
+
+	done := make(chan error)
+	go func() {
+		done <- batcher.MediateInboundMessage(context.Background(), "ingest", message("text/plain", "last"))
+	}()
+	require.Eventually(t, func() bool {
+		batcher.mu.Lock()
+		defer batcher.mu.Unlock()
+		return len(batcher.buffer) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	batcher.Flush()
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{`["last"]`}, next.payloads())
+}
+
+func TestConfigFromParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		expected   Config
+		enabled    bool
+	}{
+		{"disabled", map[string]string{}, Config{}, false},
+		{"single message batches", map[string]string{"inbound.batch.size": "1"}, Config{}, false},
+		{"size with default window", map[string]string{"inbound.batch.size": "50"}, Config{Size: 50, Window: DefaultWindow}, true},
+		{"window only", map[string]string{"inbound.batch.window": "5s"}, Config{Window: 5 * time.Second}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, enabled := ConfigFromParameters(tt.parameters)
+			assert.Equal(t, tt.enabled, enabled)
+			assert.Equal(t, tt.expected, config)
+		})
+	}
+}
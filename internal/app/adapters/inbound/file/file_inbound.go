@@ -29,6 +29,8 @@ import (
 
 	"github.com/apache/synapse-go/internal/app/core/domain"
 	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/polling"
 	"github.com/apache/synapse-go/internal/pkg/core/synctx"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -42,6 +44,7 @@ type FileInboundEndpoint struct {
 	mediator        ports.InboundMessageMediator
 	processingFiles sync.Map
 	protocolHandler ProtocolHandler
+	stats           *polling.Stats
 }
 
 // NewFileInboundEndpoint creates a new FileInboundEndpoint instance
@@ -53,6 +56,7 @@ func NewFileInboundEndpoint(
 		config:   config,
 		clock:    NewFileClock(),
 		mediator: mediator,
+		stats:    polling.Default().For(config.Name, "file"),
 	}
 }
 
@@ -119,14 +123,14 @@ func (f *FileInboundEndpoint) poll(ctx context.Context) error {
 
 		case <-ticker.C:
 			processingWg.Add(1)
-			go func() {
+			leaks.Default().Go("inbound/"+f.config.Name, func() {
 				defer processingWg.Done()
 				if err := f.processingCycle(ctx); err != nil {
 					if err != context.Canceled {
 						slog.Error("error in processing cycle", "error", err)
 					}
 				}
-			}()
+			})
 		}
 	}
 }
@@ -138,7 +142,9 @@ func (f *FileInboundEndpoint) processingCycle(ctx context.Context) error {
 		pattern = ".*"
 	}
 
+	started := time.Now()
 	files, err := f.protocolHandler.ListFiles(pattern)
+	f.stats.RecordPoll(started, len(files), err)
 	if err != nil {
 		return fmt.Errorf("failed to scan directory: %w", err)
 	}
@@ -180,12 +186,12 @@ func (f *FileInboundEndpoint) processFiles(ctx context.Context, files []string)
 				}
 			} else {
 				fileWg.Add(1)
-				go func(fileName string) {
+				leaks.Default().Go("inbound/"+f.config.Name, func() {
 					defer fileWg.Done()
-					if err := f.processFile(ctx, fileName); err != nil {
+					if err := f.processFile(ctx, file); err != nil {
 						slog.Error("failed to process file", "error", err)
 					}
-				}(file)
+				})
 			}
 		}
 	}
@@ -249,8 +255,11 @@ func (f *FileInboundEndpoint) processFile(ctx context.Context, fileURI string) e
 		return ctx.Err()
 	default:
 
-		// Process the file through mediator
-		if err := f.mediator.MediateInboundMessage(ctx, f.config.SequenceName, msgContext); err != nil {
+		// Process the file through mediator. The processing lag is measured from
+		// the time the file was last written.
+		err := f.mediator.MediateInboundMessage(ctx, f.config.SequenceName, msgContext)
+		f.stats.RecordMessage(lastModified, err)
+		if err != nil {
 			if err := f.handleFileAction(fileURI, "Failure"); err != nil {
 				return fmt.Errorf("failed to handle file after failure: %w", err)
 			}
@@ -284,76 +293,6 @@ func (f *FileInboundEndpoint) handleFileAction(fileURI, actionType string) error
 }
 
 func (f *FileInboundEndpoint) validateConfig() error {
-	// Check required interval parameter
-	interval, exists := f.config.Parameters["interval"]
-	if !exists || interval == "" {
-		return fmt.Errorf("missing required parameter: 'interval'")
-	}
-
-	intervalMs, err := strconv.Atoi(interval)
-	if err != nil {
-		return fmt.Errorf("invalid interval value: must be an integer, got '%s'", interval)
-	}
-	if intervalMs <= 0 {
-		return fmt.Errorf("invalid interval value: must be positive, got '%d'", intervalMs)
-	}
-
-	// Check sequential parameter (optional, default false)
-	if val, exists := f.config.Parameters["sequential"]; exists {
-		_, err := strconv.ParseBool(val)
-		if err != nil {
-			return fmt.Errorf("invalid sequential value: must be true/false, got '%s'", val)
-		}
-	}
-
-	// Check coordination parameter (optional, default false)
-	if val, exists := f.config.Parameters["coordination"]; exists {
-		_, err := strconv.ParseBool(val)
-		if err != nil {
-			return fmt.Errorf("invalid coordination value: must be true/false, got '%s'", val)
-		}
-	}
-
-	// Check required FileURI parameter
-	fileURI, exists := f.config.Parameters["transport.vfs.FileURI"]
-	if !exists || fileURI == "" {
-		return fmt.Errorf("missing required parameter: 'transport.vfs.FileURI'")
-	}
-
-	// Check required ContentType parameter
-	contentType, exists := f.config.Parameters["transport.vfs.ContentType"]
-	if !exists || contentType == "" {
-		return fmt.Errorf("missing required parameter: 'transport.vfs.ContentType'")
-	}
-
-	// Check action after process (optional, default DELETE)
-	if val, exists := f.config.Parameters["transport.vfs.ActionAfterProcess"]; exists && val != "" {
-		if val == "MOVE" {
-			if _, exists := f.config.Parameters["transport.vfs.MoveAfterProcess"]; !exists || f.config.Parameters["transport.vfs.MoveAfterProcess"] == "" {
-				return fmt.Errorf("missing required parameter: 'transport.vfs.MoveAfterProcess' is required when ActionAfterProcess is 'MOVE'")
-			}
-		}
-	}
-
-	if val, exists := f.config.Parameters["transport.vfs.ActionAfterFailure"]; exists && val != "" {
-		if val == "MOVE" {
-			if _, exists := f.config.Parameters["transport.vfs.MoveAfterFailure"]; !exists || f.config.Parameters["transport.vfs.MoveAfterFailure"] == "" {
-				return fmt.Errorf("missing required parameter: 'transport.vfs.MoveAfterFailure' is required when ActionAfterFailure is 'MOVE'")
-			}
-		}
-	}
-
-	// Validate AutoLockReleaseInterval if provided
-	if val, exists := f.config.Parameters["transport.vfs.AutoLockReleaseInterval"]; exists {
-		timeout, err := strconv.Atoi(val)
-		if err != nil {
-			return fmt.Errorf("invalid AutoLockReleaseInterval value: must be an integer, got '%s'", val)
-		}
-		// Allow -1 for never timeout, or positive values
-		if timeout != -1 && timeout <= 0 {
-			return fmt.Errorf("invalid AutoLockReleaseInterval value: must be -1 or positive integer, got '%d'", timeout)
-		}
-	}
-
-	return nil
+	_, err := ParameterSchema.Validate(f.config.Parameters)
+	return err
 }
@@ -0,0 +1,69 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package file
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+)
+
+// ParameterSchema describes the parameters of file inbound endpoints
+var ParameterSchema = domain.ParameterSchema{
+	Parameters: []domain.ParameterSpec{
+		{Name: "interval", Type: domain.ParameterInt, Required: true, Check: func(value string) error {
+			if interval, _ := strconv.Atoi(value); interval <= 0 {
+				return fmt.Errorf("must be positive")
+			}
+			return nil
+		}},
+		{Name: "sequential", Type: domain.ParameterBool, Default: "false"},
+		{Name: "coordination", Type: domain.ParameterBool, Default: "false"},
+		{Name: "transport.vfs.FileURI", Type: domain.ParameterString, Required: true},
+		{Name: "transport.vfs.ContentType", Type: domain.ParameterString, Required: true},
+		{Name: "transport.vfs.FileNamePattern", Type: domain.ParameterString, Default: ".*"},
+		{Name: "transport.vfs.ActionAfterProcess", Type: domain.ParameterEnum, Values: []string{"DELETE", "MOVE"}, Default: "DELETE"},
+		{Name: "transport.vfs.MoveAfterProcess", Type: domain.ParameterString},
+		{Name: "transport.vfs.ActionAfterFailure", Type: domain.ParameterEnum, Values: []string{"DELETE", "MOVE"}, Default: "DELETE"},
+		{Name: "transport.vfs.MoveAfterFailure", Type: domain.ParameterString},
+		{Name: "transport.vfs.AutoLockReleaseInterval", Type: domain.ParameterInt, Check: func(value string) error {
+			// -1 never releases the lock
+			if timeout, _ := strconv.Atoi(value); timeout != -1 && timeout <= 0 {
+				return fmt.Errorf("must be -1 or a positive integer")
+			}
+			return nil
+		}},
+	},
+	Rules: []func(parameters map[string]string) error{
+		requireMovePath("transport.vfs.ActionAfterProcess", "transport.vfs.MoveAfterProcess"),
+		requireMovePath("transport.vfs.ActionAfterFailure", "transport.vfs.MoveAfterFailure"),
+	},
+}
+
+// requireMovePath checks that a MOVE action names its destination
+func requireMovePath(action, path string) func(parameters map[string]string) error {
+	return func(parameters map[string]string) error {
+		if parameters[action] == "MOVE" && parameters[path] == "" {
+			return fmt.Errorf("missing required parameter: '%s' is required when %s is 'MOVE'", path, action)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,236 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/geoip"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/middleware"
+	"github.com/apache/synapse-go/internal/pkg/core/mtom"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/useragent"
+	"github.com/apache/synapse-go/internal/pkg/core/utils"
+)
+
+// HTTPInboundEndpoint hands every request it receives to the configured sequence.
+// It either listens on a dedicated port (inbound.http.port) or attaches to the
+// main router's listener under a path prefix (inbound.http.context). A dedicated
+// listener serves HTTPS when inbound.http.tls.certFile and keyFile are set, and
+// can verify client certificates against inbound.http.tls.clientCAFile. Mediation is
+// asynchronous, so requests are acknowledged with 202 Accepted once they have
+// been handed over.
+type HTTPInboundEndpoint struct {
+	config    domain.InboundConfig
+	mediator  ports.InboundMessageMediator
+	registrar ports.HTTPHandlerRegistrar
+	server    *http.Server
+	// stopped rejects requests on the shared listener, where handlers cannot be removed
+	stopped atomic.Bool
+}
+
+// NewHTTPInboundEndpoint creates a new HTTPInboundEndpoint instance
+func NewHTTPInboundEndpoint(
+	config domain.InboundConfig,
+	mediator ports.InboundMessageMediator,
+	registrar ports.HTTPHandlerRegistrar,
+) *HTTPInboundEndpoint {
+	return &HTTPInboundEndpoint{
+		config:    config,
+		mediator:  mediator,
+		registrar: registrar,
+	}
+}
+
+func (h *HTTPInboundEndpoint) Start(ctx context.Context, mediator ports.InboundMessageMediator) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if err := h.validateConfig(); err != nil {
+		slog.Error("invalid configuration", "error", err)
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	h.mediator = mediator
+	// Defaults are applied so the TLS settings see the client auth mode
+	parameters, _ := ParameterSchema.Validate(h.config.Parameters)
+
+	// Enforce the same CORS and security policies as the APIs served by the router
+	var deploymentConfig map[string]interface{}
+	if configContext, ok := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext); ok {
+		deploymentConfig = configContext.DeploymentConfig
+	}
+	// Validated by validateConfig
+	csrf, _ := strconv.ParseBool(h.config.Parameters["inbound.http.csrf"])
+	handler, err := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.stopped.Load() {
+			problem.Write(w, r, http.StatusServiceUnavailable, "The inbound endpoint is shutting down")
+			return
+		}
+		h.handleRequest(ctx, w, r)
+	}), deploymentConfig, middleware.Options{
+		CORS:           h.config.Parameters["inbound.http.cors"],
+		SecurityPolicy: h.config.Parameters["inbound.http.securityPolicy"],
+		Authorization:  h.config.Parameters["inbound.http.authorization"],
+		CSRF:           csrf,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply middleware: %w", err)
+	}
+
+	if prefix := h.config.Parameters["inbound.http.context"]; prefix != "" {
+		return h.startShared(ctx, prefix, handler)
+	}
+
+	serverTLS, err := tlsConfig(parameters)
+	if err != nil {
+		return fmt.Errorf("http inbound endpoint %s: %w", h.config.Name, err)
+	}
+	h.server = &http.Server{
+		Addr:      ":" + h.config.Parameters["inbound.http.port"],
+		Handler:   handler,
+		TLSConfig: serverTLS,
+		ConnState: leaks.Default().ConnState("inbound/" + h.config.Name),
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("starting http inbound endpoint", "name", h.config.Name, "address", h.server.Addr,
+			"tls", serverTLS != nil)
+		var err error
+		if serverTLS != nil {
+			// The key pair is already loaded into the TLS configuration
+			err = h.server.ListenAndServeTLS("", "")
+		} else {
+			err = h.server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("received shutdown signal, stopping http inbound endpoint", "name", h.config.Name)
+		return h.Stop()
+	case err := <-serverErr:
+		return fmt.Errorf("http inbound endpoint %s failed: %w", h.config.Name, err)
+	}
+}
+
+// startShared attaches the endpoint to the main router's listener and blocks until ctx is done
+func (h *HTTPInboundEndpoint) startShared(ctx context.Context, prefix string, handler http.Handler) error {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if err := h.registrar.RegisterHandler(prefix+"/", http.StripPrefix(prefix, handler)); err != nil {
+		return fmt.Errorf("failed to attach http inbound endpoint %s to the shared listener: %w", h.config.Name, err)
+	}
+	slog.Info("attached http inbound endpoint to the shared listener", "name", h.config.Name, "context", prefix)
+
+	<-ctx.Done()
+	slog.Info("received shutdown signal, stopping http inbound endpoint", "name", h.config.Name)
+	return h.Stop()
+}
+
+func (h *HTTPInboundEndpoint) Stop() error {
+	h.stopped.Store(true)
+	if h.server == nil {
+		return nil
+	}
+	shutdownCtx, shutdownRelease := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownRelease()
+	return h.server.Shutdown(shutdownCtx)
+}
+
+func (h *HTTPInboundEndpoint) handleRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "Cannot read request body")
+		return
+	}
+
+	msgContext := synctx.CreateMsgContext()
+	msgContext.Message.RawPayload = body
+	msgContext.Message.ContentType = r.Header.Get("Content-Type")
+	if mtom.IsMTOM(msgContext.Message.ContentType) {
+		message, err := mtom.Decode(msgContext.Message.ContentType, bytes.NewReader(body))
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "The MTOM request could not be decoded")
+			return
+		}
+		msgContext.Message.RawPayload = message.Payload
+		msgContext.Message.ContentType = message.ContentType
+		msgContext.Message.Attachments = message.Attachments
+	}
+	for name := range r.Header {
+		msgContext.Headers[name] = r.Header.Get(name)
+	}
+	msgContext.Properties["isInbound"] = "true"
+	msgContext.Properties["inboundEndpointName"] = h.config.Name
+	msgContext.Properties["HTTP_METHOD"] = r.Method
+	msgContext.Properties["REST_URL_POSTFIX"] = r.URL.RequestURI()
+	if principal, ok := middleware.Principal(r); ok {
+		msgContext.Properties["AUTHENTICATED_USER"] = principal
+	}
+	// Verified client certificates identify partners connecting over mutual TLS
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		msgContext.Properties["CLIENT_CERT_SUBJECT"] = r.TLS.VerifiedChains[0][0].Subject.String()
+	}
+	if locator := geoip.Default(); locator != nil {
+		locator.Enrich(msgContext, r)
+	}
+	if detector := useragent.Default(); detector != nil {
+		detector.Enrich(msgContext, r)
+	}
+
+	if err := h.mediator.MediateInboundMessage(ctx, h.config.SequenceName, msgContext); err != nil {
+		slog.Error("failed to mediate http inbound message", "error", err)
+		problem.Write(w, r, http.StatusInternalServerError, "The request could not be mediated")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *HTTPInboundEndpoint) validateConfig() error {
+	if _, err := ParameterSchema.Validate(h.config.Parameters); err != nil {
+		return err
+	}
+	if h.config.Parameters["inbound.http.context"] != "" && h.registrar == nil {
+		return fmt.Errorf("inbound.http.context requires a shared listener, but none is available")
+	}
+	return nil
+}
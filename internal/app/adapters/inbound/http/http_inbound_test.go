@@ -0,0 +1,153 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMediator struct {
+	mu       sync.Mutex
+	messages []*synctx.MsgContext
+}
+
+func (m *recordingMediator) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+type muxRegistrar struct {
+	mux *http.ServeMux
+}
+
+func (r *muxRegistrar) RegisterHandler(pattern string, handler http.Handler) error {
+	r.mux.Handle(pattern, handler)
+	return nil
+}
+
+func TestHTTPInboundEndpoint_ValidateConfig(t *testing.T) {
+	testCases := []struct {
+		name       string
+		parameters map[string]string
+		expected   string
+	}{
+		{
+			name:       "Missing port and context",
+			parameters: map[string]string{},
+			expected:   "missing required parameter: one of 'inbound.http.port' or 'inbound.http.context'",
+		},
+		{
+			name:       "Port and context together",
+			parameters: map[string]string{"inbound.http.port": "8000", "inbound.http.context": "/orders"},
+			expected:   "'inbound.http.port' and 'inbound.http.context' cannot be used together",
+		},
+		{
+			name:       "Invalid port",
+			parameters: map[string]string{"inbound.http.port": "abc"},
+			expected:   "invalid inbound.http.port value: must be between 1 and 65535, got 'abc'",
+		},
+		{
+			name:       "Key without certificate",
+			parameters: map[string]string{"inbound.http.port": "8443", "inbound.http.tls.keyFile": "server.key"},
+			expected:   "'inbound.http.tls.certFile' and 'inbound.http.tls.keyFile' must be set together",
+		},
+		{
+			name: "TLS on the shared listener",
+			parameters: map[string]string{"inbound.http.context": "/orders",
+				"inbound.http.tls.certFile": "server.crt", "inbound.http.tls.keyFile": "server.key"},
+			expected: "TLS requires a dedicated 'inbound.http.port', the shared listener uses the server's TLS settings",
+		},
+		{
+			name:       "Client auth without TLS",
+			parameters: map[string]string{"inbound.http.port": "8443", "inbound.http.tls.clientAuth": "required"},
+			expected:   "client authentication requires 'inbound.http.tls.certFile' and 'inbound.http.tls.keyFile'",
+		},
+		{
+			name: "Client auth without CA",
+			parameters: map[string]string{"inbound.http.port": "8443", "inbound.http.tls.clientAuth": "required",
+				"inbound.http.tls.certFile": "server.crt", "inbound.http.tls.keyFile": "server.key"},
+			expected: "'inbound.http.tls.clientAuth' requires 'inbound.http.tls.clientCAFile'",
+		},
+		{
+			name:       "Root context",
+			parameters: map[string]string{"inbound.http.context": "/"},
+			expected:   "invalid inbound.http.context value: must begin with '/' and not be the root, got '/'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := NewHTTPInboundEndpoint(domain.InboundConfig{Parameters: tc.parameters}, nil, &muxRegistrar{})
+			assert.EqualError(t, endpoint.validateConfig(), tc.expected)
+		})
+	}
+}
+
+func TestHTTPInboundEndpoint_SharedListener(t *testing.T) {
+	registrar := &muxRegistrar{mux: http.NewServeMux()}
+	mediator := &recordingMediator{}
+	endpoint := NewHTTPInboundEndpoint(domain.InboundConfig{
+		Name:         "orders",
+		SequenceName: "ordersSeq",
+		Parameters:   map[string]string{"inbound.http.context": "/inbound/orders"},
+	}, nil, registrar)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- endpoint.Start(ctx, mediator)
+	}()
+
+	// Wait for the endpoint to attach to the shared listener
+	assert.Eventually(t, func() bool {
+		_, pattern := registrar.mux.Handler(httptest.NewRequest(http.MethodPost, "/inbound/orders/new", nil))
+		return pattern != ""
+	}, time.Second, 10*time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodPost, "/inbound/orders/new", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	registrar.mux.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, mediator.messages, 1)
+	assert.Equal(t, `{"id":1}`, string(mediator.messages[0].Message.RawPayload))
+	assert.Equal(t, "/new", mediator.messages[0].Properties["REST_URL_POSTFIX"])
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	// Stopped endpoints keep their route but reject new requests
+	rec = httptest.NewRecorder()
+	registrar.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/inbound/orders/new", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
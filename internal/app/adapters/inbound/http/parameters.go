@@ -0,0 +1,74 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package http
+
+import (
+	"fmt"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+)
+
+// ParameterSchema describes the parameters of HTTP inbound endpoints
+var ParameterSchema = domain.ParameterSchema{
+	Parameters: []domain.ParameterSpec{
+		{Name: "inbound.http.port", Type: domain.ParameterInt, Min: 1, Max: 65535},
+		{Name: "inbound.http.context", Type: domain.ParameterString, Check: func(prefix string) error {
+			if prefix[0] != '/' || prefix == "/" {
+				return fmt.Errorf("must begin with '/' and not be the root")
+			}
+			return nil
+		}},
+		{Name: "inbound.http.cors", Type: domain.ParameterBool},
+		{Name: "inbound.http.csrf", Type: domain.ParameterBool},
+		{Name: "inbound.http.securityPolicy", Type: domain.ParameterString},
+		{Name: "inbound.http.authorization", Type: domain.ParameterString},
+		{Name: "inbound.http.tls.certFile", Type: domain.ParameterString},
+		{Name: "inbound.http.tls.keyFile", Type: domain.ParameterString},
+		{Name: "inbound.http.tls.clientCAFile", Type: domain.ParameterString},
+		{Name: "inbound.http.tls.clientAuth", Type: domain.ParameterEnum, Default: ClientAuthNone,
+			Values: []string{ClientAuthNone, ClientAuthOptional, ClientAuthRequired}},
+	},
+	Rules: []func(parameters map[string]string) error{
+		func(parameters map[string]string) error {
+			port, prefix := parameters["inbound.http.port"], parameters["inbound.http.context"]
+			switch {
+			case port == "" && prefix == "":
+				return fmt.Errorf("missing required parameter: one of 'inbound.http.port' or 'inbound.http.context'")
+			case port != "" && prefix != "":
+				return fmt.Errorf("'inbound.http.port' and 'inbound.http.context' cannot be used together")
+			}
+			return nil
+		},
+		func(parameters map[string]string) error {
+			certFile, keyFile := parameters["inbound.http.tls.certFile"], parameters["inbound.http.tls.keyFile"]
+			switch {
+			case (certFile == "") != (keyFile == ""):
+				return fmt.Errorf("'inbound.http.tls.certFile' and 'inbound.http.tls.keyFile' must be set together")
+			case certFile != "" && parameters["inbound.http.context"] != "":
+				return fmt.Errorf("TLS requires a dedicated 'inbound.http.port', the shared listener uses the server's TLS settings")
+			case certFile == "" && (parameters["inbound.http.tls.clientCAFile"] != "" || parameters["inbound.http.tls.clientAuth"] != ClientAuthNone):
+				return fmt.Errorf("client authentication requires 'inbound.http.tls.certFile' and 'inbound.http.tls.keyFile'")
+			case parameters["inbound.http.tls.clientAuth"] != ClientAuthNone && parameters["inbound.http.tls.clientCAFile"] == "":
+				return fmt.Errorf("'inbound.http.tls.clientAuth' requires 'inbound.http.tls.clientCAFile'")
+			}
+			return nil
+		},
+	},
+}
@@ -0,0 +1,70 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// Client authentication modes of inbound.http.tls.clientAuth
+const (
+	ClientAuthNone = "none"
+	// ClientAuthOptional verifies a client certificate when one is presented
+	ClientAuthOptional = "optional"
+	// ClientAuthRequired rejects handshakes without a valid client certificate
+	ClientAuthRequired = "required"
+)
+
+// tlsConfig builds the TLS configuration of a dedicated listener from validated
+// parameters, or returns nil when TLS is not configured
+func tlsConfig(parameters map[string]string) (*tls.Config, error) {
+	certFile := parameters["inbound.http.tls.certFile"]
+	if certFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, parameters["inbound.http.tls.keyFile"])
+	if err != nil {
+		return nil, fmt.Errorf("cannot load TLS key pair: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if caFile := parameters["inbound.http.tls.clientCAFile"]; caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read client CA file: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA file %s contains no PEM certificates", caFile)
+		}
+	}
+	switch parameters["inbound.http.tls.clientAuth"] {
+	case ClientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequired:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
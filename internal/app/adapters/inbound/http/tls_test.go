@@ -0,0 +1,135 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issueCertificate creates a key pair signed by parent, or a self-signed CA when parent is nil
+func issueCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func writePEM(t *testing.T, dir string, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (string, string) {
+	t.Helper()
+	certFile := filepath.Join(dir, name+".crt")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600))
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestHTTPInboundEndpoint_ClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := issueCertificate(t, "Partner CA", nil, nil)
+	caFile, _ := writePEM(t, dir, "ca", ca, caKey)
+	serverCert, serverKey := issueCertificate(t, "synapse", ca, caKey)
+	certFile, keyFile := writePEM(t, dir, "server", serverCert, serverKey)
+	clientCert, clientKey := issueCertificate(t, "partner-a", ca, caKey)
+
+	parameters, err := ParameterSchema.Validate(map[string]string{
+		"inbound.http.port":             "8443",
+		"inbound.http.tls.certFile":     certFile,
+		"inbound.http.tls.keyFile":      keyFile,
+		"inbound.http.tls.clientCAFile": caFile,
+		"inbound.http.tls.clientAuth":   ClientAuthRequired,
+	})
+	require.NoError(t, err)
+	serverTLS, err := tlsConfig(parameters)
+	require.NoError(t, err)
+
+	mediator := &recordingMediator{}
+	endpoint := NewHTTPInboundEndpoint(domain.InboundConfig{Name: "partners"}, mediator, nil)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint.handleRequest(context.Background(), w, r)
+	}))
+	server.TLS = serverTLS
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	client := func(certificates ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certificates,
+		}}}
+	}
+
+	_, err = client().Post(server.URL, "application/json", nil)
+	assert.Error(t, err, "handshakes without a client certificate are rejected")
+
+	resp, err := client(tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}).
+		Post(server.URL, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Len(t, mediator.messages, 1)
+	assert.Equal(t, "CN=partner-a", mediator.messages[0].Properties["CLIENT_CERT_SUBJECT"])
+}
+
+func TestTLSConfig_Disabled(t *testing.T) {
+	serverTLS, err := tlsConfig(map[string]string{"inbound.http.port": "8290"})
+	assert.NoError(t, err)
+	assert.Nil(t, serverTLS)
+}
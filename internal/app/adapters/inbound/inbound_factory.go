@@ -21,25 +21,86 @@ package inbound
 
 import (
 	"errors"
+	"fmt"
 
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/batch"
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/file"
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/http"
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/mapping"
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/poll"
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/sequencing"
 	"github.com/apache/synapse-go/internal/app/core/domain"
 	"github.com/apache/synapse-go/internal/app/core/ports"
-	"github.com/apache/synapse-go/internal/app/adapters/inbound/file"
 )
 
 var (
 	ErrInboundTypeNotFound = errors.New("inbound type not found")
 )
 
-func NewInbound(config domain.InboundConfig) (ports.InboundEndpoint, error) {
+// parameterSchemas holds the parameter schema of every supported protocol
+var parameterSchemas = map[string]domain.ParameterSchema{
+	"file": file.ParameterSchema,
+	"http": http.ParameterSchema,
+	"poll": poll.ParameterSchema,
+}
+
+// NewInbound creates the inbound endpoint for the configured protocol. registrar
+// is used by HTTP inbound endpoints that share the main router's listener.
+// Parameters are validated against the protocol's schema and completed with its
+// defaults, so configuration errors surface at deploy time.
+func NewInbound(config domain.InboundConfig, registrar ports.HTTPHandlerRegistrar) (ports.InboundEndpoint, error) {
+	schema, ok := parameterSchemas[config.Protocol]
+	if !ok {
+		return nil, ErrInboundTypeNotFound
+	}
+	parameters, err := schema.Validate(config.Parameters)
+	if err == nil {
+		parameters, err = batch.ParameterSchema.Validate(parameters)
+	}
+	if err == nil {
+		parameters, err = mapping.ParameterSchema.Validate(parameters)
+	}
+	if err == nil {
+		parameters, err = sequencing.ParameterSchema.Validate(parameters)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters for inbound endpoint %s: %w", config.Name, err)
+	}
+	config.Parameters = parameters
+
+	var endpoint ports.InboundEndpoint
 	switch config.Protocol {
 	case "file":
-		return file.NewFileInboundEndpoint(
+		endpoint = file.NewFileInboundEndpoint(
+			config,
+			nil,
+		)
+	case "http":
+		endpoint = http.NewHTTPInboundEndpoint(
+			config,
+			nil,
+			registrar,
+		)
+	case "poll":
+		endpoint = poll.NewPollInboundEndpoint(
 			config,
 			nil,
-		), nil
-		
+		)
 	default:
 		return nil, ErrInboundTypeNotFound
 	}
-}
\ No newline at end of file
+	// Any protocol can map its transport headers to properties, order its
+	// messages by key and deliver them in batches. Headers are mapped and keys
+	// computed per message, before batching; a batch keeps the key of its
+	// first message.
+	if rules, ok := mapping.RulesFromParameters(parameters); ok {
+		endpoint = mapping.Wrap(endpoint, rules)
+	}
+	if key, ok := sequencing.KeyFromParameters(parameters); ok {
+		endpoint = sequencing.Wrap(endpoint, key)
+	}
+	if batchConfig, ok := batch.ConfigFromParameters(parameters); ok {
+		return batch.Wrap(endpoint, batchConfig), nil
+	}
+	return endpoint, nil
+}
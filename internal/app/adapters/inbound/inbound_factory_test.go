@@ -0,0 +1,108 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package inbound
+
+import (
+	"testing"
+
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/batch"
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/mapping"
+	"github.com/apache/synapse-go/internal/app/adapters/inbound/poll"
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInbound_ValidatesParameters(t *testing.T) {
+	_, err := NewInbound(domain.InboundConfig{Name: "orders", Protocol: "ftp"}, nil)
+	assert.ErrorIs(t, err, ErrInboundTypeNotFound)
+
+	_, err = NewInbound(domain.InboundConfig{
+		Name:       "orders",
+		Protocol:   "file",
+		Parameters: map[string]string{"interval": "0", "transport.vfs.ActionAfterProcess": "MOVE"},
+	}, nil)
+	assert.EqualError(t, err, "invalid parameters for inbound endpoint orders: "+
+		"invalid interval value: must be positive, got '0'\n"+
+		"missing required parameter: 'transport.vfs.FileURI'\n"+
+		"missing required parameter: 'transport.vfs.ContentType'")
+
+	_, err = NewInbound(domain.InboundConfig{
+		Name:       "orders",
+		Protocol:   "http",
+		Parameters: map[string]string{"inbound.http.port": "8000", "inbound.http.cors": "maybe"},
+	}, nil)
+	assert.EqualError(t, err, "invalid parameters for inbound endpoint orders: invalid inbound.http.cors value: must be true/false, got 'maybe'")
+
+	endpoint, err := NewInbound(domain.InboundConfig{
+		Name:       "orders",
+		Protocol:   "http",
+		Parameters: map[string]string{"inbound.http.port": "8000"},
+	}, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, endpoint)
+
+	_, err = NewInbound(domain.InboundConfig{
+		Name:       "orders",
+		Protocol:   "poll",
+		Parameters: map[string]string{"inbound.poll.url": "https://example.com/orders", "inbound.poll.schedule": "* * 32 * *"},
+	}, nil)
+	assert.EqualError(t, err, "invalid parameters for inbound endpoint orders: "+
+		"invalid inbound.poll.schedule value: invalid day of month '32': must be within 1-31, got '* * 32 * *'")
+
+	endpoint, err = NewInbound(domain.InboundConfig{
+		Name:       "orders",
+		Protocol:   "poll",
+		Parameters: map[string]string{"inbound.poll.url": "https://example.com/orders", "inbound.poll.schedule": "*/5 * * * *"},
+	}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &poll.PollInboundEndpoint{}, endpoint)
+
+	_, err = NewInbound(domain.InboundConfig{
+		Name:       "orders",
+		Protocol:   "http",
+		Parameters: map[string]string{"inbound.http.port": "8000", "inbound.batch.window": "soon"},
+	}, nil)
+	assert.EqualError(t, err, "invalid parameters for inbound endpoint orders: "+
+		"invalid inbound.batch.window value: must be a positive duration such as 500ms or 5s, got 'soon'")
+
+	endpoint, err = NewInbound(domain.InboundConfig{
+		Name:       "telemetry",
+		Protocol:   "http",
+		Parameters: map[string]string{"inbound.http.port": "8000", "inbound.batch.size": "100"},
+	}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &batch.Endpoint{}, endpoint)
+
+	_, err = NewInbound(domain.InboundConfig{
+		Name:       "orders",
+		Protocol:   "http",
+		Parameters: map[string]string{"inbound.http.port": "8000", "inbound.mapping": "X-Tenant"},
+	}, nil)
+	assert.EqualError(t, err, "invalid parameters for inbound endpoint orders: "+
+		"invalid inbound.mapping value: rule 'X-Tenant' must have the form header=property or header=property:type, got 'X-Tenant'")
+
+	endpoint, err = NewInbound(domain.InboundConfig{
+		Name:       "orders",
+		Protocol:   "http",
+		Parameters: map[string]string{"inbound.http.port": "8000", "inbound.mapping": "X-Tenant=tenant"},
+	}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &mapping.Endpoint{}, endpoint)
+}
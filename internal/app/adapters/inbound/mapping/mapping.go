@@ -0,0 +1,159 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package mapping copies the transport metadata of inbound messages, such as
+// Kafka headers, AMQP properties or the name and size of a file, into message
+// properties, so sequences read it the same way whatever the protocol.
+package mapping
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// ParameterSchema describes the mapping parameter shared by every inbound
+// protocol. inbound.mapping is a comma separated list of header=property
+// rules, where a rule may end with :type to convert the value, e.g.
+// FILE_NAME=fileName, FILE_LENGTH=fileSize:integer
+var ParameterSchema = domain.ParameterSchema{
+	Parameters: []domain.ParameterSpec{
+		{Name: "inbound.mapping", Type: domain.ParameterString, Check: func(value string) error {
+			_, err := ParseRules(value)
+			return err
+		}},
+	},
+}
+
+// Rule copies one transport header into a property
+type Rule struct {
+	Header   string
+	Property string
+	// Type is a property type of the artifacts package
+	Type string
+}
+
+// ParseRules reads the rules of an inbound.mapping parameter
+func ParseRules(value string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		header, property, ok := strings.Cut(entry, "=")
+		header, property = strings.TrimSpace(header), strings.TrimSpace(property)
+		if !ok || header == "" || property == "" {
+			return nil, fmt.Errorf("rule '%s' must have the form header=property or header=property:type", entry)
+		}
+		rule := Rule{Header: header, Property: property, Type: artifacts.PropertyTypeString}
+		if name, typ, ok := strings.Cut(property, ":"); ok {
+			rule.Property, rule.Type = strings.TrimSpace(name), strings.TrimSpace(typ)
+			if !artifacts.IsPropertyType(rule.Type) {
+				return nil, fmt.Errorf("rule '%s': type must be string, integer, double, boolean or json", entry)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("must declare at least one header=property rule")
+	}
+	return rules, nil
+}
+
+// RulesFromParameters reads validated mapping parameters and reports whether
+// any header is mapped
+func RulesFromParameters(parameters map[string]string) ([]Rule, bool) {
+	value, ok := parameters["inbound.mapping"]
+	if !ok || value == "" {
+		return nil, false
+	}
+	rules, err := ParseRules(value)
+	return rules, err == nil
+}
+
+// Apply sets the property of every rule whose header the message carries.
+// Headers are matched exactly first and then without regard to case.
+func Apply(rules []Rule, msg *synctx.MsgContext) error {
+	for _, rule := range rules {
+		value, ok := header(msg.Headers, rule.Header)
+		if !ok {
+			continue
+		}
+		converted, err := artifacts.ConvertPropertyValue(value, rule.Type)
+		if err != nil {
+			return fmt.Errorf("cannot map header %s to property %s: %w", rule.Header, rule.Property, err)
+		}
+		msg.Properties[rule.Property] = converted
+	}
+	return nil
+}
+
+func header(headers map[string]string, name string) (string, bool) {
+	if value, ok := headers[name]; ok {
+		return value, true
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Mapper is an InboundMessageMediator that maps headers to properties before
+// passing each message on
+type Mapper struct {
+	next  ports.InboundMessageMediator
+	rules []Rule
+}
+
+func NewMapper(next ports.InboundMessageMediator, rules []Rule) *Mapper {
+	return &Mapper{next: next, rules: rules}
+}
+
+// MediateInboundMessage applies the rules and mediates the message. A header
+// that cannot be converted fails the message.
+func (m *Mapper) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	if err := Apply(m.rules, msg); err != nil {
+		return err
+	}
+	return m.next.MediateInboundMessage(ctx, seqName, msg)
+}
+
+// Endpoint maps the headers of the messages of an inbound endpoint
+type Endpoint struct {
+	ports.InboundEndpoint
+	rules []Rule
+}
+
+// Wrap maps the headers of the messages the endpoint mediates
+func Wrap(endpoint ports.InboundEndpoint, rules []Rule) *Endpoint {
+	return &Endpoint{InboundEndpoint: endpoint, rules: rules}
+}
+
+// Start runs the endpoint with a mapping mediator
+func (e *Endpoint) Start(ctx context.Context, mediator ports.InboundMessageMediator) error {
+	return e.InboundEndpoint.Start(ctx, NewMapper(mediator, e.rules))
+}
@@ -0,0 +1,100 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package mapping
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMediator keeps the last message it mediates
+type recordingMediator struct {
+	msg *synctx.MsgContext
+}
+
+func (m *recordingMediator) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	m.msg = msg
+	return nil
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []Rule
+		wantErr string
+	}{
+		{
+			name:  "file metadata",
+			value: "FILE_NAME=fileName, FILE_LENGTH=fileSize:integer",
+			want: []Rule{
+				{Header: "FILE_NAME", Property: "fileName", Type: "string"},
+				{Header: "FILE_LENGTH", Property: "fileSize", Type: "integer"},
+			},
+		},
+		{name: "trailing comma", value: "kafka_key=key,", want: []Rule{{Header: "kafka_key", Property: "key", Type: "string"}}},
+		{name: "no property", value: "FILE_NAME=", wantErr: "must have the form header=property"},
+		{name: "unknown type", value: "FILE_LENGTH=fileSize:long", wantErr: "type must be"},
+		{name: "empty", value: " , ", wantErr: "at least one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules(tt.value)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
+
+func TestMapper(t *testing.T) {
+	rules, err := ParseRules("FILE_NAME=fileName, FILE_LENGTH=fileSize:integer, amqp.priority=priority:integer, MISSING=missing")
+	require.NoError(t, err)
+	next := &recordingMediator{}
+	msg := synctx.CreateMsgContext()
+	msg.Headers["FILE_NAME"] = "orders.csv"
+	msg.Headers["FILE_LENGTH"] = "2048"
+	msg.Headers["AMQP.Priority"] = "5"
+
+	require.NoError(t, NewMapper(next, rules).MediateInboundMessage(context.Background(), "OrdersSeq", msg))
+	assert.Equal(t, "orders.csv", next.msg.Properties["fileName"])
+	assert.Equal(t, 2048, next.msg.Properties["fileSize"])
+	assert.Equal(t, 5, next.msg.Properties["priority"], "headers are matched without regard to case")
+	assert.NotContains(t, next.msg.Properties, "missing")
+
+	msg.Headers["FILE_LENGTH"] = "large"
+	err = NewMapper(next, rules).MediateInboundMessage(context.Background(), "OrdersSeq", msg)
+	assert.ErrorContains(t, err, "cannot map header FILE_LENGTH to property fileSize")
+}
+
+func TestRulesFromParameters(t *testing.T) {
+	_, ok := RulesFromParameters(map[string]string{"interval": "1000"})
+	assert.False(t, ok)
+	rules, ok := RulesFromParameters(map[string]string{"inbound.mapping": "FILE_NAME=fileName"})
+	assert.True(t, ok)
+	assert.Len(t, rules, 1)
+}
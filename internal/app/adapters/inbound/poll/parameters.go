@@ -0,0 +1,88 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package poll
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// HeaderParameterPrefix starts parameters that set a request header, e.g.
+// inbound.poll.header.Authorization
+const HeaderParameterPrefix = "inbound.poll.header."
+
+// ParameterSchema describes the parameters of HTTP poller inbound endpoints.
+// inbound.poll.items is a JSONPath or XPath selecting the items of a
+// response; without it the whole response is one message.
+// inbound.poll.nextCursor is an expression over the variables of the eval
+// mediator bound to the response, e.g. payload.next or headers['X-Next'].
+var ParameterSchema = domain.ParameterSchema{
+	Parameters: []domain.ParameterSpec{
+		{Name: "inbound.poll.url", Type: domain.ParameterString, Required: true, Check: func(value string) error {
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("must be an absolute http or https URL")
+			}
+			return nil
+		}},
+		{Name: "inbound.poll.method", Type: domain.ParameterEnum, Values: []string{"GET", "POST"}, Default: "GET"},
+		{Name: "inbound.poll.body", Type: domain.ParameterString},
+		{Name: "inbound.poll.schedule", Type: domain.ParameterString, Check: func(value string) error {
+			_, err := ParseSchedule(value)
+			return err
+		}},
+		{Name: "interval", Type: domain.ParameterInt, Check: positive},
+		{Name: "inbound.poll.timeout", Type: domain.ParameterInt, Check: positive, Default: "30000"},
+		{Name: "inbound.poll.items", Type: domain.ParameterString, Check: func(value string) error {
+			_, err := artifacts.CompileSplitPath(value)
+			return err
+		}},
+		{Name: "inbound.poll.nextCursor", Type: domain.ParameterString, Check: func(value string) error {
+			_, err := expression.Compile(value, artifacts.ExpressionVariables...)
+			return err
+		}},
+		{Name: "inbound.poll.cursorParameter", Type: domain.ParameterString, Default: "cursor"},
+		{Name: "inbound.poll.cursorFile", Type: domain.ParameterString},
+		{Name: "inbound.poll.maxPages", Type: domain.ParameterInt, Check: positive, Default: "10"},
+	},
+	Rules: []func(parameters map[string]string) error{
+		func(parameters map[string]string) error {
+			switch schedule, interval := parameters["inbound.poll.schedule"], parameters["interval"]; {
+			case schedule == "" && interval == "":
+				return fmt.Errorf("missing required parameter: one of 'inbound.poll.schedule' or 'interval'")
+			case schedule != "" && interval != "":
+				return fmt.Errorf("'inbound.poll.schedule' and 'interval' cannot be used together")
+			}
+			return nil
+		},
+	},
+}
+
+func positive(value string) error {
+	if number, _ := strconv.Atoi(value); number <= 0 {
+		return fmt.Errorf("must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package poll implements an inbound endpoint that calls an HTTP source on a
+// cron schedule or at a fixed interval and mediates each item of the response
+// as a message. Paged sources are followed with a cursor computed from each
+// response; the cursor is persisted so polling resumes where it stopped.
+package poll
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/polling"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Headers set on every polled message
+const (
+	URLHeader    = "POLL_URL"
+	CursorHeader = "POLL_CURSOR"
+	IndexHeader  = "POLL_INDEX"
+)
+
+// PollInboundEndpoint polls an HTTP source. Each run fetches up to
+// inbound.poll.maxPages pages, starting from the persisted cursor. The
+// cursor only advances past a page once every item of it was mediated, so a
+// failed item is fetched again on the next run. A run ends early when a page
+// yields no next cursor; the cursor then stays on that page.
+type PollInboundEndpoint struct {
+	config   domain.InboundConfig
+	mediator ports.InboundMessageMediator
+	client   *http.Client
+	stats    *polling.Stats
+
+	items      *artifacts.SplitPath
+	nextCursor *expression.Program
+
+	mu     sync.Mutex
+	cursor string
+	cancel context.CancelFunc
+}
+
+// NewPollInboundEndpoint creates a poller for validated parameters
+func NewPollInboundEndpoint(config domain.InboundConfig, mediator ports.InboundMessageMediator) *PollInboundEndpoint {
+	timeout, _ := strconv.Atoi(config.Parameters["inbound.poll.timeout"])
+	p := &PollInboundEndpoint{
+		config:   config,
+		mediator: mediator,
+		client:   &http.Client{Timeout: time.Duration(timeout) * time.Millisecond},
+		stats:    polling.Default().For(config.Name, "poll"),
+	}
+	if source := config.Parameters["inbound.poll.items"]; source != "" {
+		if items, err := artifacts.CompileSplitPath(source); err == nil {
+			p.items = &items
+		}
+	}
+	if source := config.Parameters["inbound.poll.nextCursor"]; source != "" {
+		p.nextCursor, _ = expression.Compile(source, artifacts.ExpressionVariables...)
+	}
+	return p
+}
+
+// Start loads the persisted cursor and polls until the context is done or
+// the endpoint is stopped
+func (p *PollInboundEndpoint) Start(ctx context.Context, mediator ports.InboundMessageMediator) error {
+	if _, err := ParameterSchema.Validate(p.config.Parameters); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	cursor, err := p.loadCursor()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p.mu.Lock()
+	p.mediator = mediator
+	p.cursor = cursor
+	p.cancel = cancel
+	p.mu.Unlock()
+	defer cancel()
+
+	slog.Info("starting HTTP poller inbound endpoint", "name", p.config.Name, "url", p.config.Parameters["inbound.poll.url"])
+	for {
+		timer := time.NewTimer(p.untilNextRun(time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			if err := p.run(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("HTTP poll failed", "name", p.config.Name, "error", err)
+			}
+		}
+	}
+}
+
+// Stop ends polling after the current run
+func (p *PollInboundEndpoint) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	return nil
+}
+
+// untilNextRun returns the wait before the next run
+func (p *PollInboundEndpoint) untilNextRun(now time.Time) time.Duration {
+	if spec := p.config.Parameters["inbound.poll.schedule"]; spec != "" {
+		schedule, _ := ParseSchedule(spec)
+		next := schedule.Next(now)
+		if next.IsZero() {
+			// A schedule that never matches never polls
+			return time.Duration(1<<63 - 1)
+		}
+		return next.Sub(now)
+	}
+	interval, _ := strconv.Atoi(p.config.Parameters["interval"])
+	return time.Duration(interval) * time.Millisecond
+}
+
+// run fetches and mediates pages until the source has no more, a page fails
+// or inbound.poll.maxPages is reached
+func (p *PollInboundEndpoint) run(ctx context.Context) error {
+	maxPages, _ := strconv.Atoi(p.config.Parameters["inbound.poll.maxPages"])
+	for range maxPages {
+		p.mu.Lock()
+		cursor := p.cursor
+		p.mu.Unlock()
+
+		started := time.Now()
+		response, err := p.fetch(ctx, cursor)
+		if err != nil {
+			p.stats.RecordPoll(started, 0, err)
+			return err
+		}
+		items, contentType, err := p.split(response)
+		p.stats.RecordPoll(started, len(items), err)
+		if err != nil {
+			return err
+		}
+		for i, item := range items {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			msg := p.message(item, contentType, cursor, i)
+			err := p.mediator.MediateInboundMessage(ctx, p.config.SequenceName, msg)
+			p.stats.RecordMessage(time.Time{}, err)
+			if err != nil {
+				return fmt.Errorf("item %d of page at cursor '%s' failed, the page will be fetched again: %w", i, cursor, err)
+			}
+		}
+
+		next, err := p.next(response)
+		if err != nil || next == "" || next == cursor {
+			return err
+		}
+		if err := p.saveCursor(next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch requests the page at the cursor and fails on a non-2xx response
+func (p *PollInboundEndpoint) fetch(ctx context.Context, cursor string) (*synctx.MsgContext, error) {
+	target, err := url.Parse(p.config.Parameters["inbound.poll.url"])
+	if err != nil {
+		return nil, err
+	}
+	if cursor != "" {
+		query := target.Query()
+		query.Set(p.config.Parameters["inbound.poll.cursorParameter"], cursor)
+		target.RawQuery = query.Encode()
+	}
+	var body io.Reader
+	if p.config.Parameters["inbound.poll.body"] != "" {
+		body = strings.NewReader(p.config.Parameters["inbound.poll.body"])
+	}
+	request, err := http.NewRequestWithContext(ctx, p.config.Parameters["inbound.poll.method"], target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range p.config.Parameters {
+		if header, ok := strings.CutPrefix(name, HeaderParameterPrefix); ok {
+			request.Header.Set(header, value)
+		}
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	payload, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response of %s: %w", target.Redacted(), err)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("%s returned %s", target.Redacted(), response.Status)
+	}
+
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = payload
+	msg.Message.ContentType = response.Header.Get("Content-Type")
+	for name := range response.Header {
+		msg.Headers[name] = response.Header.Get(name)
+	}
+	return msg, nil
+}
+
+// split returns the items of a response and their content type
+func (p *PollInboundEndpoint) split(response *synctx.MsgContext) ([][]byte, string, error) {
+	if p.items == nil {
+		return [][]byte{response.Message.RawPayload}, response.Message.ContentType, nil
+	}
+	items, err := p.items.Split(response.Message.RawPayload)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot split response on %s: %w", p.items, err)
+	}
+	contentType := "application/json"
+	if p.items.IsXPath() {
+		contentType = "application/xml"
+	}
+	return items, contentType, nil
+}
+
+// next evaluates inbound.poll.nextCursor over the response
+func (p *PollInboundEndpoint) next(response *synctx.MsgContext) (string, error) {
+	if p.nextCursor == nil {
+		return "", nil
+	}
+	env, err := artifacts.ExpressionEnv(response)
+	if err != nil {
+		return "", fmt.Errorf("cannot compute next cursor: %w", err)
+	}
+	result, err := p.nextCursor.Run(env)
+	if err != nil {
+		return "", fmt.Errorf("cannot compute next cursor: %w", err)
+	}
+	cursor, err := artifacts.ConvertPropertyValue(result, artifacts.PropertyTypeString)
+	if err != nil {
+		return "", fmt.Errorf("cannot compute next cursor: %w", err)
+	}
+	return cursor.(string), nil
+}
+
+func (p *PollInboundEndpoint) message(item []byte, contentType, cursor string, index int) *synctx.MsgContext {
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = item
+	msg.Message.ContentType = contentType
+	msg.Headers[URLHeader] = p.config.Parameters["inbound.poll.url"]
+	msg.Headers[CursorHeader] = cursor
+	msg.Headers[IndexHeader] = strconv.Itoa(index)
+	msg.Properties["isInbound"] = "true"
+	msg.Properties["ARTIFACT_NAME"] = "inboundendpoint" + p.config.Name
+	msg.Properties["inboundEndpointName"] = p.config.Name
+	return msg
+}
+
+// loadCursor reads the persisted cursor, empty when there is none yet
+func (p *PollInboundEndpoint) loadCursor() (string, error) {
+	path := p.config.Parameters["inbound.poll.cursorFile"]
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot read cursor file: %w", err)
+	}
+	return string(bytes.TrimSpace(data)), nil
+}
+
+// saveCursor advances the cursor and persists it. The file is replaced
+// atomically so a crash never leaves a partial cursor.
+func (p *PollInboundEndpoint) saveCursor(cursor string) error {
+	p.mu.Lock()
+	p.cursor = cursor
+	p.mu.Unlock()
+	path := p.config.Parameters["inbound.poll.cursorFile"]
+	if path == "" {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("cannot persist cursor: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(cursor + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot persist cursor: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot persist cursor: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot persist cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package poll
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMediator keeps the payloads it mediates and fails those listed
+type recordingMediator struct {
+	mu       sync.Mutex
+	payloads []string
+	messages []*synctx.MsgContext
+	fail     map[string]bool
+}
+
+func (m *recordingMediator) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	payload := string(msg.Message.RawPayload)
+	m.payloads = append(m.payloads, payload)
+	m.messages = append(m.messages, msg)
+	if m.fail[payload] {
+		return errors.New("mediation failed")
+	}
+	return nil
+}
+
+// pagedSource serves three pages of items linked by a next cursor
+func pagedSource(t *testing.T) *httptest.Server {
+	pages := map[string]string{
+		"":   `{"items":[{"id":1},{"id":2}],"next":"p2"}`,
+		"p2": `{"items":[{"id":3}],"next":"p3"}`,
+		"p3": `{"items":[{"id":4}],"next":null}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		page, ok := pages[r.URL.Query().Get("after")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestPoller(t *testing.T, parameters map[string]string) *PollInboundEndpoint {
+	validated, err := ParameterSchema.Validate(parameters)
+	require.NoError(t, err)
+	return NewPollInboundEndpoint(domain.InboundConfig{Name: "orders", SequenceName: "main", Parameters: validated}, nil)
+}
+
+func TestParameterSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		wantErr    bool
+	}{
+		{name: "interval", parameters: map[string]string{"inbound.poll.url": "http://example.com/orders", "interval": "1000"}},
+		{name: "schedule", parameters: map[string]string{"inbound.poll.url": "https://example.com/orders", "inbound.poll.schedule": "*/5 * * * *"}},
+		{name: "no trigger", parameters: map[string]string{"inbound.poll.url": "http://example.com/orders"}, wantErr: true},
+		{name: "both triggers", parameters: map[string]string{"inbound.poll.url": "http://example.com/orders", "interval": "1000", "inbound.poll.schedule": "* * * * *"}, wantErr: true},
+		{name: "relative URL", parameters: map[string]string{"inbound.poll.url": "/orders", "interval": "1000"}, wantErr: true},
+		{name: "invalid schedule", parameters: map[string]string{"inbound.poll.url": "http://example.com/orders", "inbound.poll.schedule": "* * *"}, wantErr: true},
+		{name: "invalid items path", parameters: map[string]string{"inbound.poll.url": "http://example.com/orders", "interval": "1000", "inbound.poll.items": "items"}, wantErr: true},
+		{name: "invalid cursor expression", parameters: map[string]string{"inbound.poll.url": "http://example.com/orders", "interval": "1000", "inbound.poll.nextCursor": "payload."}, wantErr: true},
+		{name: "zero pages", parameters: map[string]string{"inbound.poll.url": "http://example.com/orders", "interval": "1000", "inbound.poll.maxPages": "0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParameterSchema.Validate(tt.parameters)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPollInboundEndpoint_FollowsCursor(t *testing.T) {
+	server := pagedSource(t)
+	cursorFile := filepath.Join(t.TempDir(), "orders.cursor")
+	poller := newTestPoller(t, map[string]string{
+		"inbound.poll.url":                  server.URL,
+		"interval":                          "1000",
+		"inbound.poll.items":                "$.items[*]",
+		"inbound.poll.nextCursor":           "payload.next",
+		"inbound.poll.cursorParameter":      "after",
+		"inbound.poll.cursorFile":           cursorFile,
+		"inbound.poll.header.Authorization": "Bearer token",
+	})
+	mediator := &recordingMediator{}
+	poller.mediator = mediator
+
+	require.NoError(t, poller.run(context.Background()))
+	assert.Equal(t, []string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`}, mediator.payloads)
+	last := mediator.messages[3]
+	assert.Equal(t, "application/json", last.Message.ContentType)
+	assert.Equal(t, "p3", last.Headers[CursorHeader])
+	assert.Equal(t, "0", last.Headers[IndexHeader])
+	assert.Equal(t, "orders", last.Properties["inboundEndpointName"])
+
+	// The cursor stays on the last page and survives a restart
+	data, err := os.ReadFile(cursorFile)
+	require.NoError(t, err)
+	assert.Equal(t, "p3\n", string(data))
+	restarted := newTestPoller(t, poller.config.Parameters)
+	cursor, err := restarted.loadCursor()
+	require.NoError(t, err)
+	assert.Equal(t, "p3", cursor)
+}
+
+func TestPollInboundEndpoint_FailedItemRetriesPage(t *testing.T) {
+	server := pagedSource(t)
+	poller := newTestPoller(t, map[string]string{
+		"inbound.poll.url":                  server.URL,
+		"interval":                          "1000",
+		"inbound.poll.items":                "$.items",
+		"inbound.poll.nextCursor":           "payload.next",
+		"inbound.poll.cursorParameter":      "after",
+		"inbound.poll.header.Authorization": "Bearer token",
+	})
+	mediator := &recordingMediator{fail: map[string]bool{`{"id":3}`: true}}
+	poller.mediator = mediator
+
+	assert.Error(t, poller.run(context.Background()))
+	assert.Equal(t, "p2", poller.cursor)
+
+	delete(mediator.fail, `{"id":3}`)
+	mediator.payloads = nil
+	require.NoError(t, poller.run(context.Background()))
+	assert.Equal(t, []string{`{"id":3}`, `{"id":4}`}, mediator.payloads)
+}
+
+func TestPollInboundEndpoint_MaxPagesAndWholeResponse(t *testing.T) {
+	server := pagedSource(t)
+	poller := newTestPoller(t, map[string]string{
+		"inbound.poll.url":                  server.URL,
+		"interval":                          "1000",
+		"inbound.poll.nextCursor":           "payload.next",
+		"inbound.poll.cursorParameter":      "after",
+		"inbound.poll.maxPages":             "2",
+		"inbound.poll.header.Authorization": "Bearer token",
+	})
+	mediator := &recordingMediator{}
+	poller.mediator = mediator
+
+	require.NoError(t, poller.run(context.Background()))
+	require.Len(t, mediator.payloads, 2)
+	assert.JSONEq(t, `{"items":[{"id":3}],"next":"p3"}`, mediator.payloads[1])
+	assert.Equal(t, "p3", poller.cursor)
+}
+
+func TestPollInboundEndpoint_SourceFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	poller := newTestPoller(t, map[string]string{"inbound.poll.url": server.URL, "interval": "1000"})
+	mediator := &recordingMediator{}
+	poller.mediator = mediator
+
+	err := poller.run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	assert.Empty(t, mediator.payloads)
+	assert.Equal(t, uint64(1), poller.stats.Status().PollFailures)
+}
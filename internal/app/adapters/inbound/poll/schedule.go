@@ -0,0 +1,140 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package poll
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron schedule of five fields: minute, hour, day of month,
+// month and day of week. Each field is '*', a value, a range such as 1-5, or
+// a list of them, optionally stepped as in */15 or 0-30/10. As in cron, a day
+// matches when either day field does if both are restricted.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record that a day field is '*'
+	domAny, dowAny bool
+}
+
+// scheduleFields gives the bounds of each cron field
+var scheduleFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// ParseSchedule parses a five-field cron expression
+func ParseSchedule(spec string) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(scheduleFields) {
+		return Schedule{}, fmt.Errorf("cron expression must have %d fields, got %d", len(scheduleFields), len(fields))
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseScheduleField(field, scheduleFields[i].min, scheduleFields[i].max)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid %s '%s': %w", scheduleFields[i].name, field, err)
+		}
+		sets[i] = set
+	}
+	return Schedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("step must be a positive integer")
+			}
+		}
+		low, high := min, max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("'%s' is not a number", first)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("'%s' is not a number", last)
+				}
+			} else if stepped {
+				high = max
+			}
+			if low < min || high > max || low > high {
+				return 0, fmt.Errorf("must be within %d-%d", min, max)
+			}
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first time after t that the schedule matches, at the
+// start of a minute. The zero time is returned when the schedule never
+// matches, e.g. on the 31st of February.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Every combination of month and day repeats within eight years, leap
+	// years included
+	limit := t.AddDate(8, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<t.Weekday()) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
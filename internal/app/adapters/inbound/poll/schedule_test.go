@@ -0,0 +1,62 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package poll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// A Friday
+	from := time.Date(2026, time.March, 13, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "* * * * *", want: time.Date(2026, time.March, 13, 10, 8, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2026, time.March, 13, 10, 15, 0, 0, time.UTC)},
+		{spec: "0 9-17 * * *", want: time.Date(2026, time.March, 13, 11, 0, 0, 0, time.UTC)},
+		{spec: "30 2 * * *", want: time.Date(2026, time.March, 14, 2, 30, 0, 0, time.UTC)},
+		{spec: "0 0 * * 1", want: time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 * *", want: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted
+		{spec: "0 0 20 * 0", want: time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 29 2 *", want: time.Date(2028, time.February, 29, 12, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 2 *", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(from))
+		})
+	}
+}
+
+func TestParseSchedule_Errors(t *testing.T) {
+	for _, spec := range []string{"* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
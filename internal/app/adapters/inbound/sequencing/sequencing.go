@@ -0,0 +1,104 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package sequencing computes an ordering key for each message of an inbound
+// endpoint, e.g. the account ID of its payload, so the mediation engine
+// mediates messages sharing a key in the order they arrived while messages
+// with different keys are mediated in parallel.
+package sequencing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/synapse-go/internal/app/core/domain"
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/ordering"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// ParameterSchema describes the ordering parameter shared by every inbound
+// protocol. inbound.ordering.key is an expression over the variables of the
+// eval mediator, e.g. payload.accountId or headers['kafka_key'].
+var ParameterSchema = domain.ParameterSchema{
+	Parameters: []domain.ParameterSpec{
+		{Name: "inbound.ordering.key", Type: domain.ParameterString, Check: func(value string) error {
+			_, err := expression.Compile(value, artifacts.ExpressionVariables...)
+			return err
+		}},
+	},
+}
+
+// KeyFromParameters reads a validated ordering parameter and reports whether
+// messages are ordered
+func KeyFromParameters(parameters map[string]string) (*expression.Program, bool) {
+	value, ok := parameters["inbound.ordering.key"]
+	if !ok || value == "" {
+		return nil, false
+	}
+	key, err := expression.Compile(value, artifacts.ExpressionVariables...)
+	return key, err == nil
+}
+
+// Keyer is an InboundMessageMediator that sets the ordering key of each
+// message before passing it on. Messages whose key is empty are not ordered.
+type Keyer struct {
+	next ports.InboundMessageMediator
+	key  *expression.Program
+}
+
+func NewKeyer(next ports.InboundMessageMediator, key *expression.Program) *Keyer {
+	return &Keyer{next: next, key: key}
+}
+
+// MediateInboundMessage sets the ordering key and mediates the message. A key
+// that cannot be computed fails the message rather than mediating it out of
+// order.
+func (k *Keyer) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	env, err := artifacts.ExpressionEnv(msg)
+	if err != nil {
+		return fmt.Errorf("cannot compute ordering key: %w", err)
+	}
+	result, err := k.key.Run(env)
+	if err != nil {
+		return fmt.Errorf("cannot compute ordering key: %w", err)
+	}
+	if key, _ := artifacts.ConvertPropertyValue(result, artifacts.PropertyTypeString); key != "" {
+		msg.Properties[ordering.KeyProperty] = key
+	}
+	return k.next.MediateInboundMessage(ctx, seqName, msg)
+}
+
+// Endpoint orders the messages of an inbound endpoint
+type Endpoint struct {
+	ports.InboundEndpoint
+	key *expression.Program
+}
+
+// Wrap orders the messages the endpoint mediates by the key
+func Wrap(endpoint ports.InboundEndpoint, key *expression.Program) *Endpoint {
+	return &Endpoint{InboundEndpoint: endpoint, key: key}
+}
+
+// Start runs the endpoint with a keying mediator
+func (e *Endpoint) Start(ctx context.Context, mediator ports.InboundMessageMediator) error {
+	return e.InboundEndpoint.Start(ctx, NewKeyer(mediator, e.key))
+}
@@ -0,0 +1,81 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package sequencing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/ordering"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMediator keeps the last message it mediates
+type recordingMediator struct {
+	msg *synctx.MsgContext
+}
+
+func (m *recordingMediator) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	m.msg = msg
+	return nil
+}
+
+func TestParameterSchema(t *testing.T) {
+	_, err := ParameterSchema.Validate(map[string]string{"inbound.ordering.key": "payload."})
+	assert.Error(t, err)
+
+	parameters, err := ParameterSchema.Validate(map[string]string{"inbound.ordering.key": "payload.accountId"})
+	require.NoError(t, err)
+	_, ok := KeyFromParameters(parameters)
+	assert.True(t, ok)
+	_, ok = KeyFromParameters(map[string]string{})
+	assert.False(t, ok)
+}
+
+func TestKeyer(t *testing.T) {
+	key, ok := KeyFromParameters(map[string]string{"inbound.ordering.key": `payload.accountId != nil ? payload.accountId : headers['kafka_key']`})
+	require.True(t, ok)
+	tests := []struct {
+		name    string
+		payload string
+		headers map[string]string
+		wantKey any
+	}{
+		{name: "from the payload", payload: `{"accountId":"acct-7"}`, wantKey: "acct-7"},
+		{name: "numeric key", payload: `{"accountId":42}`, wantKey: "42"},
+		{name: "from a header", payload: `{}`, headers: map[string]string{"kafka_key": "acct-9"}, wantKey: "acct-9"},
+		{name: "no key", payload: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingMediator{}
+			msg := synctx.CreateMsgContext()
+			msg.Message.RawPayload = []byte(tt.payload)
+			for name, value := range tt.headers {
+				msg.Headers[name] = value
+			}
+			require.NoError(t, NewKeyer(next, key).MediateInboundMessage(context.Background(), "main", msg))
+			assert.Same(t, msg, next.msg)
+			assert.Equal(t, tt.wantKey, msg.Properties[ordering.KeyProperty])
+		})
+	}
+}
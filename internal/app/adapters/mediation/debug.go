@@ -0,0 +1,78 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package mediation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+// DebugEngine logs every mediation flow it hands to the wrapped engine, with
+// its outcome and duration
+type DebugEngine struct {
+	next   ports.MediationEngine
+	logger *slog.Logger
+}
+
+func NewDebugEngine(next ports.MediationEngine) *DebugEngine {
+	d := &DebugEngine{next: next}
+	d.logger = loggerfactory.GetLogger(componentName, d)
+	return d
+}
+
+func (d *DebugEngine) UpdateLogger() {
+	d.logger = loggerfactory.GetLogger(componentName, d)
+}
+
+func (d *DebugEngine) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
+	d.logger.Info("Dispatching inbound message", slog.String("sequence", seqName),
+		slog.String("inbound", inboundName(msg)), slog.Int("payloadBytes", len(msg.Message.RawPayload)))
+	err := d.next.MediateInboundMessage(ctx, seqName, msg)
+	if err != nil {
+		d.logger.Error("Inbound message dispatch failed", slog.String("sequence", seqName), slog.String("error", err.Error()))
+	}
+	return err
+}
+
+func (d *DebugEngine) MediateAPIResource(ctx context.Context, resource artifacts.Resource, msg *synctx.MsgContext) bool {
+	start := time.Now()
+	result := d.next.MediateAPIResource(ctx, resource, msg)
+	attrs := []any{
+		slog.String("resource", resource.URITemplate.FullTemplate),
+		slog.Bool("success", result),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if code, ok := msg.Properties[artifacts.ErrorCodeProperty].(string); ok {
+		attrs = append(attrs, slog.String("errorCode", code))
+	}
+	d.logger.Info("Mediated API resource", attrs...)
+	return result
+}
+
+func inboundName(msg *synctx.MsgContext) string {
+	name, _ := msg.Properties["inboundEndpointName"].(string)
+	return name
+}
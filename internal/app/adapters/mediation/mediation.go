@@ -26,6 +26,8 @@ import (
 	
 
 	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/ordering"
 	"github.com/apache/synapse-go/internal/pkg/core/synctx"
 	"github.com/apache/synapse-go/internal/pkg/core/utils"
 	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
@@ -37,10 +39,12 @@ const (
 
 type MediationEngine struct {
 	logger *slog.Logger
+	// sequencer mediates messages sharing an ordering key one at a time
+	sequencer *ordering.Sequencer
 }
 
 func NewMediationEngine() *MediationEngine {
-	m := &MediationEngine{}
+	m := &MediationEngine{sequencer: ordering.NewSequencer(componentName)}
 	m.logger = loggerfactory.GetLogger(componentName, m)
 	return m
 }
@@ -52,8 +56,11 @@ func (m *MediationEngine) UpdateLogger() {
 func (m *MediationEngine) MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error {
 	waitgroup := ctx.Value(utils.WaitGroupKey).(*sync.WaitGroup)
 	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
+	// The message is mediated against the artifacts deployed when it arrived
+	snapshot := configContext.Snapshot()
+	msg.Properties[artifacts.SnapshotProperty] = snapshot
 	waitgroup.Add(1)
-	go func() {
+	mediate := func() {
 		defer waitgroup.Done()
 		select {
 		case <-ctx.Done():
@@ -61,14 +68,26 @@ func (m *MediationEngine) MediateInboundMessage(ctx context.Context, seqName str
 			waitgroup.Done()
 			return
 		default:
-			sequence, exists := configContext.SequenceMap[seqName]
+			sequence, exists := snapshot.Sequences[seqName]
 			if !exists {
 				m.logger.Error("Sequence " + seqName + " not found")
 				return
 			}
 			sequence.Execute(msg)
 		}
-	}()
+	}
+	// Messages sharing an ordering key are mediated in the order they arrived
+	if key, ok := msg.Properties[ordering.KeyProperty].(string); ok && key != "" {
+		m.sequencer.Go(key, mediate)
+		return nil
+	}
+	leaks.Default().Go(componentName, mediate)
 	return nil
 
 }
+
+// MediateAPIResource runs the in sequence of the resource, and its fault
+// sequence if the in sequence fails, on the calling goroutine
+func (m *MediationEngine) MediateAPIResource(ctx context.Context, resource artifacts.Resource, msg *synctx.MsgContext) bool {
+	return resource.Mediate(msg)
+}
@@ -0,0 +1,66 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package mediation
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/app/core/ports"
+)
+
+// DefaultEngine is the name of the engine used unless [mediation] selects another
+const DefaultEngine = "default"
+
+var (
+	enginesMu sync.RWMutex
+	engines   = map[string]func() ports.MediationEngine{
+		DefaultEngine: func() ports.MediationEngine { return NewMediationEngine() },
+		"debug":       func() ports.MediationEngine { return NewDebugEngine(NewMediationEngine()) },
+	}
+)
+
+// Register makes an engine available under name. It is meant to be called
+// from init functions, before the runtime starts.
+func Register(name string, factory func() ports.MediationEngine) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	if _, exists := engines[name]; exists {
+		panic(fmt.Sprintf("mediation engine %s is already registered", name))
+	}
+	engines[name] = factory
+}
+
+// New creates the engine registered under name, or the default engine when
+// name is empty
+func New(name string) (ports.MediationEngine, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	factory, ok := engines[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown mediation engine '%s', available engines: %v", name, slices.Sorted(maps.Keys(engines)))
+	}
+	return factory(), nil
+}
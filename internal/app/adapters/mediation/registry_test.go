@@ -0,0 +1,69 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package mediation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingEngine struct {
+	*MediationEngine
+	calls int
+}
+
+func (c *countingEngine) MediateAPIResource(ctx context.Context, resource artifacts.Resource, msg *synctx.MsgContext) bool {
+	c.calls++
+	return c.MediationEngine.MediateAPIResource(ctx, resource, msg)
+}
+
+func TestNew(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{
+		Format:     "text",
+		OutputPath: "stdout",
+	})
+	engine, err := New("")
+	assert.NoError(t, err)
+	assert.IsType(t, &MediationEngine{}, engine)
+
+	engine, err = New("debug")
+	assert.NoError(t, err)
+	assert.IsType(t, &DebugEngine{}, engine)
+
+	_, err = New("compiled")
+	assert.EqualError(t, err, "unknown mediation engine 'compiled', available engines: [debug default]")
+
+	counting := &countingEngine{MediationEngine: NewMediationEngine()}
+	Register("counting", func() ports.MediationEngine { return counting })
+	engine, err = New("counting")
+	assert.NoError(t, err)
+	assert.True(t, NewDebugEngine(engine).MediateAPIResource(context.Background(), artifacts.Resource{}, synctx.CreateMsgContext()))
+	assert.Equal(t, 1, counting.calls, "the debug engine delegates to the wrapped engine")
+
+	assert.Panics(t, func() {
+		Register("counting", func() ports.MediationEngine { return counting })
+	})
+}
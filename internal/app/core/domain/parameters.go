@@ -0,0 +1,122 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParameterType is the type an inbound parameter value must parse as
+type ParameterType int
+
+const (
+	ParameterString ParameterType = iota
+	ParameterInt
+	ParameterBool
+	ParameterEnum
+)
+
+// ParameterSpec describes one inbound endpoint parameter
+type ParameterSpec struct {
+	Name     string
+	Type     ParameterType
+	Required bool
+	// Default is applied when the parameter is absent or empty
+	Default string
+	// Min and Max bound ParameterInt values when either is non-zero
+	Min, Max int
+	// Values lists the accepted ParameterEnum values
+	Values []string
+	// Check adds validation the type alone cannot express
+	Check func(value string) error
+}
+
+// ParameterSchema lists the parameters a protocol understands
+type ParameterSchema struct {
+	Parameters []ParameterSpec
+	// Rules check combinations of parameters, after defaults are applied
+	Rules []func(parameters map[string]string) error
+}
+
+// Validate checks parameters against the schema and returns a copy with the
+// defaults applied. Every problem is reported, not just the first one.
+// Parameters missing from the schema are passed through unchanged.
+func (s ParameterSchema) Validate(parameters map[string]string) (map[string]string, error) {
+	validated := maps.Clone(parameters)
+	if validated == nil {
+		validated = make(map[string]string)
+	}
+	var errs []error
+	for _, spec := range s.Parameters {
+		value := strings.TrimSpace(validated[spec.Name])
+		if value == "" {
+			if spec.Default != "" {
+				validated[spec.Name] = spec.Default
+			} else if spec.Required {
+				errs = append(errs, fmt.Errorf("missing required parameter: '%s'", spec.Name))
+			}
+			continue
+		}
+		validated[spec.Name] = value
+		if err := spec.validate(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s value: %w, got '%s'", spec.Name, err, value))
+		}
+	}
+	// Rules assume well-typed values, so they only run on a clean parameter set
+	if len(errs) == 0 {
+		for _, rule := range s.Rules {
+			if err := rule(validated); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return validated, errors.Join(errs...)
+}
+
+func (spec ParameterSpec) validate(value string) error {
+	switch spec.Type {
+	case ParameterInt:
+		number, err := strconv.Atoi(value)
+		bounded := spec.Min != 0 || spec.Max != 0
+		if err != nil || bounded && (number < spec.Min || number > spec.Max) {
+			if bounded {
+				return fmt.Errorf("must be between %d and %d", spec.Min, spec.Max)
+			}
+			return fmt.Errorf("must be an integer")
+		}
+	case ParameterBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be true/false")
+		}
+	case ParameterEnum:
+		if !slices.Contains(spec.Values, value) {
+			return fmt.Errorf("must be one of %s", strings.Join(spec.Values, ", "))
+		}
+	}
+	if spec.Check != nil {
+		return spec.Check(value)
+	}
+	return nil
+}
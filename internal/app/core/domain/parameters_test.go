@@ -0,0 +1,88 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameterSchema_Validate(t *testing.T) {
+	schema := ParameterSchema{
+		Parameters: []ParameterSpec{
+			{Name: "port", Type: ParameterInt, Required: true, Min: 1, Max: 65535},
+			{Name: "retries", Type: ParameterInt, Default: "3"},
+			{Name: "enabled", Type: ParameterBool, Default: "true"},
+			{Name: "mode", Type: ParameterEnum, Values: []string{"push", "pull"}},
+		},
+		Rules: []func(parameters map[string]string) error{
+			func(parameters map[string]string) error {
+				if parameters["mode"] == "push" && parameters["port"] == "80" {
+					return assert.AnError
+				}
+				return nil
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		want       map[string]string
+		wantErr    string
+	}{
+		{
+			name:       "defaults applied",
+			parameters: map[string]string{"port": " 8080 ", "custom": "kept"},
+			want:       map[string]string{"port": "8080", "retries": "3", "enabled": "true", "custom": "kept"},
+		},
+		{
+			name:       "all errors reported",
+			parameters: map[string]string{"retries": "many", "enabled": "yes", "mode": "poll"},
+			wantErr: "missing required parameter: 'port'\n" +
+				"invalid retries value: must be an integer, got 'many'\n" +
+				"invalid enabled value: must be true/false, got 'yes'\n" +
+				"invalid mode value: must be one of push, pull, got 'poll'",
+		},
+		{
+			name:       "out of range",
+			parameters: map[string]string{"port": "70000"},
+			wantErr:    "invalid port value: must be between 1 and 65535, got '70000'",
+		},
+		{
+			name:       "rule",
+			parameters: map[string]string{"port": "80", "mode": "push"},
+			wantErr:    assert.AnError.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.Validate(tt.parameters)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
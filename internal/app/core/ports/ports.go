@@ -21,7 +21,9 @@ package ports
 
 import (
 	"context"
+	"net/http"
 
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
 	"github.com/apache/synapse-go/internal/pkg/core/synctx"
 )
 
@@ -35,3 +37,19 @@ type InboundEndpoint interface {
 type InboundMessageMediator interface {
 	MediateInboundMessage(ctx context.Context, seqName string, msg *synctx.MsgContext) error
 }
+
+// MediationEngine runs the mediation flows of API resources and inbound
+// endpoints. Alternate engines, e.g. one that traces every flow, can replace
+// the default engine at startup.
+type MediationEngine interface {
+	InboundMessageMediator
+	// MediateAPIResource runs the resource's sequences synchronously and
+	// reports whether a response can be written
+	MediateAPIResource(ctx context.Context, resource artifacts.Resource, msg *synctx.MsgContext) bool
+}
+
+// HTTPHandlerRegistrar lets HTTP inbound endpoints attach to the main router's
+// listener instead of opening a port of their own
+type HTTPHandlerRegistrar interface {
+	RegisterHandler(pattern string, handler http.Handler) error
+}
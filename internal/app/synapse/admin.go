@@ -0,0 +1,399 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package synapse
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/admin"
+	"github.com/apache/synapse-go/internal/pkg/core/analytics"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/checksum"
+	"github.com/apache/synapse-go/internal/pkg/core/collection"
+	"github.com/apache/synapse-go/internal/pkg/core/deployers"
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/messageprocessor"
+	"github.com/apache/synapse-go/internal/pkg/core/middleware"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+	"github.com/apache/synapse-go/internal/pkg/core/polling"
+	"github.com/apache/synapse-go/internal/pkg/core/replay"
+	"github.com/apache/synapse-go/internal/pkg/core/router"
+	"github.com/apache/synapse-go/internal/pkg/core/useragent"
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+)
+
+// defaultVersion is the body of the default-version admin endpoints
+type defaultVersion struct {
+	Version  string   `json:"version"`
+	Versions []string `json:"versions,omitempty"`
+}
+
+// captureRequest is the body of the capture start endpoint
+type captureRequest struct {
+	capture.Filter
+	Count int    `json:"count"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+// registerAdminEndpoints exposes the runtime's subsystems through the admin API
+func registerAdminEndpoints(adminService *admin.Service, routerService *router.RouterService, container *Container,
+	driftDetector *checksum.Detector, rollout *deployers.Rollout) {
+	// Lifecycle state of the runtime components, in start order
+	adminService.HandleFunc("GET /components", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, container.Status())
+	})
+
+	// Checksum of the deployed configuration, for comparing instances of a
+	// fleet. The files on disk are checked again for drift on every request.
+	adminService.HandleFunc("GET /config/checksum", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := driftDetector.Check(); err != nil {
+			admin.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, driftDetector.Status())
+	})
+
+	// Artifacts staged for a later or manual activation, and their promotion
+	adminService.HandleFunc("GET /rollout", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, rollout.Staged())
+	})
+	adminService.HandleFunc("POST /rollout/{kind}/{name}/promote", func(w http.ResponseWriter, r *http.Request) {
+		if err := rollout.Promote(r.PathValue("kind"), r.PathValue("name")); err != nil {
+			admin.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Goroutines, files and connections opened per subsystem when leak
+	// detection is enabled
+	adminService.HandleFunc("GET /debug/resources", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, leaks.Default().Snapshot())
+	})
+	adminService.HandleFunc("GET /debug/resources/open", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, leaks.Default().Open())
+	})
+
+	// Registered routes, for debugging unexpected 404 responses
+	adminService.HandleFunc("GET /routes", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, routerService.GetRegisteredRoutes())
+	})
+
+	// Default version of a versioned API, served to requests without a version segment
+	adminService.HandleFunc("GET /apis/{name}/default-version", func(w http.ResponseWriter, r *http.Request) {
+		version, versions, err := routerService.GetDefaultVersion(r.PathValue("name"))
+		if err != nil {
+			admin.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, defaultVersion{Version: version, Versions: versions})
+	})
+	adminService.HandleFunc("PUT /apis/{name}/default-version", func(w http.ResponseWriter, r *http.Request) {
+		var body defaultVersion
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			admin.WriteError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		if err := routerService.SetDefaultVersion(r.PathValue("name"), body.Version); err != nil {
+			admin.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Postman collection (?format=postman, the default) or HAR archive
+	// (?format=har) with sample requests for the deployed APIs, optionally
+	// against another ?baseUrl= than the runtime's listener
+	exportAPIs := func(w http.ResponseWriter, r *http.Request, apis []artifacts.API, name string) {
+		baseURL := r.URL.Query().Get("baseUrl")
+		if baseURL == "" {
+			baseURL = routerService.BaseURL()
+		}
+		exported, err := collection.Export(r.URL.Query().Get("format"), name, baseURL, apis)
+		if err != nil {
+			admin.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(exported)
+	}
+	adminService.HandleFunc("GET /apis/export", func(w http.ResponseWriter, r *http.Request) {
+		var apis []artifacts.API
+		for _, api := range artifacts.GetConfigContext().Snapshot().APIs {
+			apis = append(apis, api)
+		}
+		exportAPIs(w, r, apis, "Synapse APIs")
+	})
+	adminService.HandleFunc("GET /apis/{name}/export", func(w http.ResponseWriter, r *http.Request) {
+		var apis []artifacts.API
+		for _, api := range artifacts.GetConfigContext().Snapshot().APIs {
+			if api.Name == r.PathValue("name") {
+				apis = append(apis, api)
+			}
+		}
+		if len(apis) == 0 {
+			admin.WriteError(w, http.StatusNotFound, "API "+r.PathValue("name")+" is not deployed")
+			return
+		}
+		exportAPIs(w, r, apis, r.PathValue("name"))
+	})
+
+	// Poll durations, batch sizes, lag and failures of the polling inbound endpoints
+	adminService.HandleFunc("GET /inbounds/pollers", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, polling.Default().Statuses())
+	})
+
+	// Service level objectives and whether they are currently breached
+	adminService.HandleFunc("GET /slo", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, routerService.GetSLOStatus())
+	})
+
+	// Latest results of the deployed health checks
+	adminService.HandleFunc("GET /health-checks", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, routerService.HealthChecks().Statuses())
+	})
+	adminService.HandleFunc("GET /health-checks/{name}", func(w http.ResponseWriter, r *http.Request) {
+		status, ok := routerService.HealthChecks().Get(r.PathValue("name"))
+		if !ok {
+			admin.WriteError(w, http.StatusNotFound, "health check "+r.PathValue("name")+" is not deployed")
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, status)
+	})
+
+	// Message processors, and resuming or pausing their forwarding
+	adminService.HandleFunc("GET /message-processors", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, messageprocessor.Default().Statuses())
+	})
+	adminService.HandleFunc("GET /message-processors/{name}", func(w http.ResponseWriter, r *http.Request) {
+		status, ok := messageprocessor.Default().Get(r.PathValue("name"))
+		if !ok {
+			admin.WriteError(w, http.StatusNotFound, "message processor "+r.PathValue("name")+" is not deployed")
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, status)
+	})
+	adminService.HandleFunc("POST /message-processors/{name}/activate", func(w http.ResponseWriter, r *http.Request) {
+		if err := messageprocessor.Default().Activate(r.PathValue("name")); err != nil {
+			admin.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		status, _ := messageprocessor.Default().Get(r.PathValue("name"))
+		admin.WriteJSON(w, http.StatusOK, status)
+	})
+	adminService.HandleFunc("POST /message-processors/{name}/deactivate", func(w http.ResponseWriter, r *http.Request) {
+		if err := messageprocessor.Default().Deactivate(r.PathValue("name")); err != nil {
+			admin.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		status, _ := messageprocessor.Default().Get(r.PathValue("name"))
+		admin.WriteJSON(w, http.StatusOK, status)
+	})
+
+	// Clients with recent authentication failures, and lifting a lockout early
+	adminService.HandleFunc("GET /security/lockouts", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, middleware.Lockouts())
+	})
+	adminService.HandleFunc("DELETE /security/lockouts/{policy}", func(w http.ResponseWriter, r *http.Request) {
+		client := r.URL.Query().Get("client")
+		if client == "" {
+			admin.WriteError(w, http.StatusBadRequest, "the client query parameter is required, e.g. ?client=ip:192.0.2.7")
+			return
+		}
+		if !middleware.ReleaseLockout(r.PathValue("policy"), client) {
+			admin.WriteError(w, http.StatusNotFound, "client "+client+" has no recent failures under policy "+r.PathValue("policy"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// User agent detection rules: replaced with a JSON ruleset in the body, or
+	// reloaded from the configured rules file
+	adminService.HandleFunc("GET /useragent/rules", func(w http.ResponseWriter, r *http.Request) {
+		detector := useragent.Default()
+		if detector == nil {
+			admin.WriteError(w, http.StatusNotFound, "user agent detection is not enabled")
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, detector.Ruleset())
+	})
+	adminService.HandleFunc("PUT /useragent/rules", func(w http.ResponseWriter, r *http.Request) {
+		detector := useragent.Default()
+		if detector == nil {
+			admin.WriteError(w, http.StatusNotFound, "user agent detection is not enabled")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			admin.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		ruleset, err := useragent.ParseRuleset(body)
+		if err != nil {
+			admin.WriteError(w, http.StatusBadRequest, "invalid ruleset: "+err.Error())
+			return
+		}
+		detector.SetRuleset(ruleset)
+		admin.WriteJSON(w, http.StatusOK, ruleset)
+	})
+	adminService.HandleFunc("POST /useragent/rules/reload", func(w http.ResponseWriter, r *http.Request) {
+		detector := useragent.Default()
+		if detector == nil {
+			admin.WriteError(w, http.StatusNotFound, "user agent detection is not enabled")
+			return
+		}
+		ruleset, err := detector.Reload()
+		if err != nil {
+			admin.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, ruleset)
+	})
+
+	// Endpoint responses recorded for replay
+	adminService.HandleFunc("GET /stubs", func(w http.ResponseWriter, r *http.Request) {
+		stubs := outbound.ActiveStubs()
+		if stubs == nil {
+			admin.WriteError(w, http.StatusNotFound, "endpoint stubs are not enabled")
+			return
+		}
+		recordings, err := stubs.Recordings()
+		if err != nil {
+			admin.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, map[string]any{"mode": stubs.Mode(), "recordings": recordings})
+	})
+
+	// Copies of production traffic sent to mirror endpoints
+	adminService.HandleFunc("GET /analytics", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, analytics.Default().Report(time.Now()))
+	})
+	adminService.HandleFunc("GET /mirrors", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, outbound.Mirrors())
+	})
+
+	// Second requests sent by hedged endpoints and how often they answered first
+	adminService.HandleFunc("GET /hedges", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, outbound.Hedges())
+	})
+
+	// Webhook deliveries, optionally filtered with ?status=failed, and redelivery
+	adminService.HandleFunc("GET /webhooks/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		status := webhook.Status(r.URL.Query().Get("status"))
+		switch status {
+		case "", webhook.StatusPending, webhook.StatusDelivered, webhook.StatusFailed:
+		default:
+			admin.WriteError(w, http.StatusBadRequest, "unknown delivery status "+string(status))
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, webhook.Default().Deliveries(status))
+	})
+	adminService.HandleFunc("GET /webhooks/deliveries/{id}", func(w http.ResponseWriter, r *http.Request) {
+		delivery, ok := webhook.Default().Get(r.PathValue("id"))
+		if !ok {
+			admin.WriteError(w, http.StatusNotFound, "webhook delivery "+r.PathValue("id")+" is not known")
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, delivery)
+	})
+	adminService.HandleFunc("POST /webhooks/deliveries/{id}/redeliver", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := webhook.Default().Get(r.PathValue("id")); !ok {
+			admin.WriteError(w, http.StatusNotFound, "webhook delivery "+r.PathValue("id")+" is not known")
+			return
+		}
+		delivery, err := webhook.Default().Redeliver(r.PathValue("id"))
+		if err != nil {
+			admin.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusAccepted, delivery)
+	})
+
+	// WebSocket stream of runtime events, optionally filtered with ?types=a,b
+	adminService.HandleFunc("GET /events/stream", events.StreamHandler(events.Default()).ServeHTTP)
+
+	// Flight recorder: capture the next matching messages with their mediation trace
+	adminService.HandleFunc("POST /capture", func(w http.ResponseWriter, r *http.Request) {
+		var body captureRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			admin.WriteError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		var ttl time.Duration
+		if body.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(body.TTL); err != nil {
+				admin.WriteError(w, http.StatusBadRequest, "invalid ttl: "+err.Error())
+				return
+			}
+		}
+		session, err := routerService.Capture().Start(body.Filter, body.Count, ttl)
+		if err != nil {
+			admin.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusCreated, session)
+	})
+	adminService.HandleFunc("GET /capture", func(w http.ResponseWriter, r *http.Request) {
+		session, ok := routerService.Capture().Status()
+		if !ok {
+			admin.WriteError(w, http.StatusNotFound, "no capture session has been started")
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, session)
+	})
+	adminService.HandleFunc("DELETE /capture", func(w http.ResponseWriter, r *http.Request) {
+		session, ok := routerService.Capture().Stop()
+		if !ok {
+			admin.WriteError(w, http.StatusNotFound, "no capture session is active")
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, session)
+	})
+
+	// Replay a captured message through its API or another sequence
+	replayer := &replay.Replayer{
+		Directory: routerService.Capture().Directory,
+		Handler:   routerService.Handler(),
+		Sequences: func(name string) (artifacts.Sequence, bool) {
+			sequence, ok := artifacts.GetConfigContext().Snapshot().Sequences[name]
+			return sequence, ok
+		},
+	}
+	adminService.HandleFunc("POST /replay", func(w http.ResponseWriter, r *http.Request) {
+		var body replay.Request
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			admin.WriteError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		result, err := replayer.Replay(r.Context(), body)
+		if err != nil {
+			admin.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, result)
+	})
+}
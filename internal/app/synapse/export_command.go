@@ -0,0 +1,96 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package synapse
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/collection"
+)
+
+// RunExportCommand implements "synapse export-apis", which downloads a Postman
+// collection or HAR archive of the APIs deployed on a running instance
+func RunExportCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export-apis", flag.ContinueOnError)
+	flags.SetOutput(out)
+	adminURL := flags.String("admin", "http://localhost:8290/admin", "base URL of the admin API")
+	format := flags.String("format", collection.FormatPostman, "export format, postman or har")
+	baseURL := flags.String("base-url", "", "base URL of the sample requests, the instance's listener if empty")
+	output := flags.String("o", "", "file the export is written to; standard output if empty")
+	var headers headerFlags
+	flags.Var(&headers, "H", "header sent to the admin API, e.g. -H 'X-API-Key: secret' (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "Usage: synapse export-apis [flags] [api name]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected at most one API name")
+	}
+
+	endpoint := strings.TrimSuffix(*adminURL, "/") + "/apis/export"
+	if flags.NArg() == 1 {
+		endpoint = strings.TrimSuffix(*adminURL, "/") + "/apis/" + url.PathEscape(flags.Arg(0)) + "/export"
+	}
+	query := url.Values{"format": {*format}}
+	if *baseURL != "" {
+		query.Set("baseUrl", *baseURL)
+	}
+	request, err := http.NewRequest(http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	for _, header := range headers {
+		name, value, _ := strings.Cut(header, ":")
+		request.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	response, err := (&http.Client{Timeout: time.Minute}).Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("export failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if *output == "" {
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Exported APIs to %s\n", *output)
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package synapse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Component states reported by the container
+const (
+	StatePending  = "pending"
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateFailed   = "failed"
+	StateStopped  = "stopped"
+)
+
+// Component is a runtime subsystem managed by the container. Start and Stop
+// may be nil for components that only need one of them.
+type Component struct {
+	Name  string
+	Start func(ctx context.Context) error
+	// Stop receives a context bounded by the shutdown timeout
+	Stop func(ctx context.Context) error
+}
+
+// ComponentStatus is the lifecycle state of a component
+type ComponentStatus struct {
+	Name    string        `json:"name"`
+	State   string        `json:"state"`
+	Error   string        `json:"error,omitempty"`
+	Startup time.Duration `json:"startupNanos,omitempty"`
+}
+
+type managedComponent struct {
+	Component
+	status ComponentStatus
+}
+
+// Container starts components in the order they were added and stops them in
+// reverse order. A component that fails to start aborts startup and stops the
+// components started before it.
+type Container struct {
+	mu         sync.Mutex
+	components []*managedComponent
+	logger     *slog.Logger
+}
+
+func NewContainer(logger *slog.Logger) *Container {
+	return &Container{logger: logger}
+}
+
+// Add registers a component. Components must be added before Start.
+func (c *Container) Add(component Component) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.components = append(c.components, &managedComponent{
+		Component: component,
+		status:    ComponentStatus{Name: component.Name, State: StatePending},
+	})
+}
+
+// Start starts every component in order
+func (c *Container) Start(ctx context.Context, shutdownTimeout time.Duration) error {
+	for i, component := range c.components {
+		c.setState(component, StateStarting, nil, 0)
+		began := time.Now()
+		var err error
+		if component.Start != nil {
+			err = component.Start(ctx)
+		}
+		if err != nil {
+			c.setState(component, StateFailed, err, time.Since(began))
+			c.logger.Error("Component failed to start", slog.String("component", component.Name), slog.String("error", err.Error()))
+			stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			c.stop(stopCtx, c.components[:i])
+			return fmt.Errorf("starting %s: %w", component.Name, err)
+		}
+		c.setState(component, StateRunning, nil, time.Since(began))
+		c.logger.Debug("Component started", slog.String("component", component.Name), slog.Duration("took", time.Since(began)))
+	}
+	return nil
+}
+
+// Stop stops the running components in reverse order and returns every error
+func (c *Container) Stop(ctx context.Context) error {
+	return c.stop(ctx, c.components)
+}
+
+func (c *Container) stop(ctx context.Context, components []*managedComponent) error {
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		component := components[i]
+		c.mu.Lock()
+		running := component.status.State == StateRunning
+		c.mu.Unlock()
+		if !running {
+			continue
+		}
+		var err error
+		if component.Stop != nil {
+			err = component.Stop(ctx)
+		}
+		if err != nil {
+			c.setState(component, StateFailed, err, component.status.Startup)
+			c.logger.Error("Component failed to stop", slog.String("component", component.Name), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("stopping %s: %w", component.Name, err))
+			continue
+		}
+		c.setState(component, StateStopped, nil, component.status.Startup)
+	}
+	return errors.Join(errs...)
+}
+
+// Status reports the state of every component in start order
+func (c *Container) Status() []ComponentStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	statuses := make([]ComponentStatus, len(c.components))
+	for i, component := range c.components {
+		statuses[i] = component.status
+	}
+	return statuses
+}
+
+func (c *Container) setState(component *managedComponent, state string, err error, startup time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	component.status.State = state
+	component.status.Startup = startup
+	component.status.Error = ""
+	if err != nil {
+		component.status.Error = err.Error()
+	}
+}
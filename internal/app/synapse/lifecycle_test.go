@@ -0,0 +1,106 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package synapse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContainer(order *[]string, failing string) *Container {
+	container := NewContainer(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, name := range []string{"config", "router", "deployer", "http-server"} {
+		container.Add(Component{
+			Name: name,
+			Start: func(ctx context.Context) error {
+				if name == failing {
+					return errors.New("boom")
+				}
+				*order = append(*order, "start "+name)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				*order = append(*order, "stop "+name)
+				return nil
+			},
+		})
+	}
+	return container
+}
+
+func TestContainerStartsAndStopsInOrder(t *testing.T) {
+	var order []string
+	container := newTestContainer(&order, "")
+
+	require.NoError(t, container.Start(context.Background(), time.Second))
+	for _, status := range container.Status() {
+		assert.Equal(t, StateRunning, status.State, status.Name)
+	}
+
+	require.NoError(t, container.Stop(context.Background()))
+	assert.Equal(t, []string{
+		"start config", "start router", "start deployer", "start http-server",
+		"stop http-server", "stop deployer", "stop router", "stop config",
+	}, order)
+	for _, status := range container.Status() {
+		assert.Equal(t, StateStopped, status.State, status.Name)
+	}
+}
+
+func TestContainerStartFailureStopsStartedComponents(t *testing.T) {
+	var order []string
+	container := newTestContainer(&order, "deployer")
+
+	err := container.Start(context.Background(), time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "starting deployer: boom")
+	assert.Equal(t, []string{"start config", "start router", "stop router", "stop config"}, order)
+
+	states := map[string]string{}
+	for _, status := range container.Status() {
+		states[status.Name] = status.State
+	}
+	assert.Equal(t, map[string]string{
+		"config":      StateStopped,
+		"router":      StateStopped,
+		"deployer":    StateFailed,
+		"http-server": StatePending,
+	}, states)
+}
+
+func TestContainerStopJoinsErrors(t *testing.T) {
+	container := NewContainer(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	container.Add(Component{Name: "first", Stop: func(ctx context.Context) error { return errors.New("first failed") }})
+	container.Add(Component{Name: "second"})
+	require.NoError(t, container.Start(context.Background(), time.Second))
+
+	err := container.Stop(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stopping first: first failed")
+	assert.Equal(t, StateFailed, container.Status()[0].State)
+	assert.Equal(t, StateStopped, container.Status()[1].State)
+}
@@ -0,0 +1,75 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package synapse
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/apache/synapse-go/internal/pkg/config"
+	"github.com/apache/synapse-go/internal/pkg/core/deployers"
+	"github.com/apache/synapse-go/internal/pkg/core/transactions"
+)
+
+// watchReload reloads the configuration on every SIGHUP until stop is called
+func watchReload(ctx context.Context, confPath string, deployer *deployers.Deployer) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				reload(ctx, confPath, deployer)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// reload applies the changes that need no restart: log levels, the [cors]
+// defaults, artifact files added since startup and changed endpoint files.
+// The transaction log file is reopened for log rotation. Listeners and
+// in-flight requests are left untouched, so other deployment.toml changes
+// still need a restart.
+func reload(ctx context.Context, confPath string, deployer *deployers.Deployer) {
+	log.Println("Reloading configuration")
+	if err := config.Reload(confPath); err != nil {
+		log.Printf("Error reloading configuration: %v", err)
+	}
+	if added, err := deployer.Rescan(ctx); err != nil {
+		log.Printf("Error scanning artifacts: %v", err)
+	} else if added > 0 {
+		log.Printf("Deployed %d new or changed artifact files", added)
+	}
+	if publisher := transactions.Default(); publisher != nil {
+		if err := publisher.Reopen(); err != nil {
+			log.Printf("Error reopening transaction log: %v", err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package synapse
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/replay"
+)
+
+// headerFlags collects repeated -H "Name: value" flags
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header must be in the form 'Name: value'")
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// RunReplayCommand implements "synapse replay", which asks a running instance
+// to replay a captured message through its admin API and prints the result
+func RunReplayCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(out)
+	adminURL := flags.String("admin", "http://localhost:8290/admin", "base URL of the admin API")
+	sequence := flags.String("sequence", "", "replay through this sequence instead of the original API")
+	dryRun := flags.Bool("dry-run", false, "report outbound calls instead of making them")
+	var headers headerFlags
+	flags.Var(&headers, "H", "header sent to the admin API, e.g. -H 'X-API-Key: secret' (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "Usage: synapse replay [flags] <capture>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one capture name")
+	}
+
+	body, _ := json.Marshal(replay.Request{Capture: flags.Arg(0), Sequence: *sequence, DryRun: *dryRun})
+	request, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(*adminURL, "/")+"/replay", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for _, header := range headers {
+		name, value, _ := strings.Cut(header, ":")
+		request.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	response, err := (&http.Client{Timeout: time.Minute}).Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("replay failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var formatted bytes.Buffer
+	if json.Indent(&formatted, data, "", "  ") == nil {
+		data = formatted.Bytes()
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
@@ -23,21 +23,55 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"sync"
 
 	"time"
 
 	"github.com/apache/synapse-go/internal/app/adapters/mediation"
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/certs"
 	"github.com/apache/synapse-go/internal/pkg/config"
+	"github.com/apache/synapse-go/internal/pkg/core/admin"
+	"github.com/apache/synapse-go/internal/pkg/core/analytics"
 	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/cache"
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/checksum"
+	"github.com/apache/synapse-go/internal/pkg/core/claimcheck"
+	"github.com/apache/synapse-go/internal/pkg/core/datasource"
 	"github.com/apache/synapse-go/internal/pkg/core/deployers"
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/apache/synapse-go/internal/pkg/core/geoip"
+	"github.com/apache/synapse-go/internal/pkg/core/ldap"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+	"github.com/apache/synapse-go/internal/pkg/core/middleware"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+	"github.com/apache/synapse-go/internal/pkg/core/profile"
 	"github.com/apache/synapse-go/internal/pkg/core/router"
+	"github.com/apache/synapse-go/internal/pkg/core/schemaregistry"
+	"github.com/apache/synapse-go/internal/pkg/core/selftest"
+	"github.com/apache/synapse-go/internal/pkg/core/transactions"
+	"github.com/apache/synapse-go/internal/pkg/core/useragent"
 	"github.com/apache/synapse-go/internal/pkg/core/utils"
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+	"github.com/apache/synapse-go/internal/pkg/core/xslt"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
 )
 
+// shutdownTimeout bounds how long components get to stop
+const shutdownTimeout = 30 * time.Second
+
+// Run wires the runtime components into a container, starts them in order and
+// blocks until ctx is cancelled. A component that fails to start stops the
+// ones before it and its error is returned.
 func Run(ctx context.Context) error {
 
 	start := time.Now()
@@ -48,34 +82,551 @@ func Run(ctx context.Context) error {
 	ctx = context.WithValue(ctx, utils.WaitGroupKey, &wg)
 	defer cancel()
 
-	// create instace variables
-
 	// Adding config context to the GO context
 	conCtx := artifacts.GetConfigContext()
 	ctx = context.WithValue(ctx, utils.ConfigContextKey, conCtx)
 
 	exePath, err := os.Executable()
 	if err != nil {
-		log.Fatalf("Error getting executable path: %s", err.Error())
+		return fmt.Errorf("error getting executable path: %w", err)
 	}
 
 	binDir := filepath.Dir(exePath)
 	confPath := filepath.Join(binDir, "..", "conf")
 
-	errConfig := config.InitializeConfig(ctx, confPath)
-	if errConfig != nil {
-		log.Fatalf("Initialization error: %s", errConfig.Error())
+	var routerService *router.RouterService
+	var mediationEngine ports.MediationEngine
+	container := NewContainer(loggerfactory.GetLogger("lifecycle", nil))
+
+	container.Add(Component{
+		Name: "config",
+		Start: func(ctx context.Context) error {
+			return config.InitializeConfig(ctx, confPath)
+		},
+	})
+
+	// The switches of the active profile apply before endpoints are called and
+	// artifacts are served
+	container.Add(Component{
+		Name: "profile",
+		Start: func(ctx context.Context) error {
+			profileConfig, _ := conCtx.DeploymentConfig["profile"].(profile.Config)
+			outbound.SetWireLogging(profileConfig.WireLogging)
+			middleware.RelaxSecurity(profileConfig.RelaxedSecurity)
+			logger := loggerfactory.GetLogger("profile", nil)
+			if profileConfig.Name != "" {
+				logger.Info("Active profile: " + profileConfig.Name)
+			}
+			if profileConfig.WireLogging {
+				logger.Warn("Messages exchanged with endpoints are logged, do not use in production")
+			}
+			if profileConfig.RelaxedSecurity {
+				logger.Warn("Security policies, authorization and CSRF checks are not enforced, do not use in production")
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			outbound.SetWireLogging(false)
+			middleware.RelaxSecurity(false)
+			return nil
+		},
+	})
+
+	// Leak detection starts before the other components so it sees everything
+	// they open, and reports what is still open after they have stopped
+	container.Add(Component{
+		Name: "leak-detector",
+		Start: func(ctx context.Context) error {
+			if debugConfig, ok := conCtx.DeploymentConfig["debug"].(leaks.Config); ok && debugConfig.LeakDetection {
+				leaks.Default().Enable()
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			leaks.Default().Report(loggerfactory.GetLogger("leaks", nil))
+			return nil
+		},
+	})
+
+	// Install the secret store before deployment so mediators can resolve keys
+	container.Add(Component{
+		Name: "secrets",
+		Start: func(ctx context.Context) error {
+			secretsConfig, ok := conCtx.DeploymentConfig["secrets"].(secrets.Config)
+			if !ok {
+				return nil
+			}
+			store, err := secrets.NewStore(secretsConfig, confPath)
+			if err != nil {
+				return err
+			}
+			secrets.SetDefault(store)
+			return nil
+		},
+	})
+
+	// Locate API clients before the HTTP entry points start serving
+	container.Add(Component{
+		Name: "geoip",
+		Start: func(ctx context.Context) error {
+			geoipConfig, ok := conCtx.DeploymentConfig["geoip"].(geoip.Config)
+			if !ok {
+				return nil
+			}
+			locator, err := geoip.Open(geoipConfig, confPath)
+			if err != nil {
+				return err
+			}
+			geoip.SetDefault(locator)
+			return nil
+		},
+	})
+
+	// Endpoint responses are recorded, or replayed instead of calling backends
+	container.Add(Component{
+		Name: "stubs",
+		Start: func(ctx context.Context) error {
+			stubsConfig, ok := conCtx.DeploymentConfig["stubs"].(outbound.StubConfig)
+			if !ok {
+				return nil
+			}
+			stubs, err := outbound.OpenStubs(stubsConfig, confPath)
+			if err != nil {
+				return err
+			}
+			outbound.SetStubs(stubs)
+			loggerfactory.GetLogger("stubs", nil).Warn("Endpoint stubs are active, do not use in production",
+				slog.String("mode", stubsConfig.Mode), slog.String("directory", stubsConfig.Directory))
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			outbound.SetStubs(nil)
+			return nil
+		},
+	})
+
+	// Outbound hosts resolve through the DNS cache and static hosts
+	container.Add(Component{
+		Name: "dns",
+		Start: func(ctx context.Context) error {
+			dnsConfig, ok := conCtx.DeploymentConfig["dns"].(outbound.DNSConfig)
+			if !ok {
+				return nil
+			}
+			resolver, err := outbound.NewResolver(dnsConfig)
+			if err != nil {
+				return err
+			}
+			outbound.SetResolver(resolver)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			outbound.SetResolver(nil)
+			return nil
+		},
+	})
+
+	// User agent detection with the built-in or a configured ruleset
+	container.Add(Component{
+		Name: "useragent",
+		Start: func(ctx context.Context) error {
+			useragentConfig, ok := conCtx.DeploymentConfig["useragent"].(useragent.Config)
+			if !ok || !useragentConfig.Enabled {
+				return nil
+			}
+			detector, err := useragent.Open(useragentConfig, confPath)
+			if err != nil {
+				return err
+			}
+			useragent.SetDefault(detector)
+			return nil
+		},
+	})
+
+	// Webhooks are signed with a key from the secret store
+	container.Add(Component{
+		Name: "webhooks",
+		Start: func(ctx context.Context) error {
+			webhooksConfig, ok := conCtx.DeploymentConfig["webhooks"].(webhook.Config)
+			if !ok {
+				return nil
+			}
+			var secret []byte
+			if webhooksConfig.SecretAlias != "" {
+				if secret, err = secrets.Default().Get(webhooksConfig.SecretAlias); err != nil {
+					return fmt.Errorf("webhooks: %w", err)
+				}
+			}
+			dispatcher, err := webhook.NewDispatcher(webhooksConfig, secret)
+			if err != nil {
+				return err
+			}
+			webhook.SetDefault(dispatcher)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			webhook.Default().Close()
+			return nil
+		},
+	})
+
+	// Subscribe the configured event consumers before deployment publishes events
+	container.Add(Component{
+		Name: "events",
+		Start: func(ctx context.Context) error {
+			if eventsConfig, ok := conCtx.DeploymentConfig["events"].(events.Config); ok {
+				eventsConfig.Start(ctx, events.Default(), loggerfactory.GetLogger("events", nil))
+			}
+			return nil
+		},
+	})
+
+	// Per-API analytics are published through the webhook dispatcher
+	var stopAnalytics context.CancelFunc = func() {}
+	container.Add(Component{
+		Name: "analytics",
+		Start: func(ctx context.Context) error {
+			analyticsConfig, ok := conCtx.DeploymentConfig["analytics"].(analytics.Config)
+			if !ok || !analyticsConfig.Enabled {
+				return nil
+			}
+			window, _ := analyticsConfig.WindowDuration()
+			aggregator := analytics.NewAggregator(window)
+			analytics.SetDefault(aggregator)
+			if analyticsConfig.PublishURL != "" {
+				interval, _ := analyticsConfig.Interval()
+				var publishCtx context.Context
+				publishCtx, stopAnalytics = context.WithCancel(context.Background())
+				go analytics.Publish(publishCtx, aggregator, webhook.Default(), analyticsConfig.PublishURL,
+					interval, loggerfactory.GetLogger("analytics", nil))
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			stopAnalytics()
+			return nil
+		},
+	})
+
+	// Transaction events are posted through the webhook dispatcher or sent to
+	// an endpoint artifact
+	container.Add(Component{
+		Name: "transactions",
+		Start: func(ctx context.Context) error {
+			transactionsConfig, ok := conCtx.DeploymentConfig["transactions"].(transactions.Config)
+			if !ok || !transactionsConfig.Enabled {
+				return nil
+			}
+			publisher, err := transactions.Open(transactionsConfig, confPath, webhook.Default(), outbound.Send,
+				loggerfactory.GetLogger("transactions", nil))
+			if err != nil {
+				return err
+			}
+			transactions.SetDefault(publisher)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if publisher := transactions.Default(); publisher != nil {
+				transactions.SetDefault(nil)
+				publisher.Close()
+			}
+			return nil
+		},
+	})
+
+	// Data sources must be open before artifacts using them run; their
+	// pools connect on first use
+	container.Add(Component{
+		Name: "datasources",
+		Start: func(ctx context.Context) error {
+			databaseConfig, ok := conCtx.DeploymentConfig["database"].(datasource.Config)
+			if !ok {
+				return nil
+			}
+			return datasource.Open(databaseConfig)
+		},
+		Stop: func(ctx context.Context) error {
+			datasource.Close()
+			return nil
+		},
+	})
+
+	// The directory of the [ldap] section serves the ldap mediator; its
+	// connections are opened on first use
+	container.Add(Component{
+		Name: "ldap",
+		Start: func(ctx context.Context) error {
+			ldapConfig, ok := conCtx.DeploymentConfig["ldap"].(ldap.Config)
+			if !ok {
+				return nil
+			}
+			directory, err := ldap.NewDirectory(ldapConfig)
+			if err != nil {
+				return err
+			}
+			ldap.SetDefault(directory)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if directory := ldap.Default(); directory != nil {
+				ldap.SetDefault(nil)
+				directory.Close()
+			}
+			return nil
+		},
+	})
+
+	// Cached responses are reloaded from the cache directory so a restart
+	// does not send every cached request to the backends
+	container.Add(Component{
+		Name: "cache",
+		Start: func(ctx context.Context) error {
+			cacheConfig, ok := conCtx.DeploymentConfig["cache"].(cache.Config)
+			if !ok {
+				return nil
+			}
+			store, err := cache.Open(cacheConfig, confPath)
+			if err != nil {
+				return err
+			}
+			cache.SetDefault(store)
+			return nil
+		},
+	})
+
+	// The file store must be registered before artifacts using it deploy
+	container.Add(Component{
+		Name: "claim-check",
+		Start: func(ctx context.Context) error {
+			claimCheckConfig, ok := conCtx.DeploymentConfig["claimCheck"].(claimcheck.Config)
+			if !ok {
+				return nil
+			}
+			store, err := claimcheck.OpenFileStore(claimCheckConfig, confPath)
+			if err != nil {
+				return err
+			}
+			claimcheck.Register(claimcheck.FileStoreName, store)
+			return nil
+		},
+	})
+
+	// Alternate engines are selected by name from those registered with the
+	// mediation package
+	container.Add(Component{
+		Name: "mediation-engine",
+		Start: func(ctx context.Context) error {
+			mediationConfig, _ := conCtx.DeploymentConfig["mediation"].(map[string]string)
+			mediationEngine, err = mediation.New(mediationConfig["engine"])
+			return err
+		},
+	})
+
+	container.Add(Component{
+		Name: "router",
+		Start: func(ctx context.Context) error {
+			routerService, err = newRouterService(ctx, conCtx.DeploymentConfig, confPath)
+			if err != nil {
+				return err
+			}
+			routerService.SetMediationEngine(mediationEngine)
+			return nil
+		},
+	})
+
+	// Inbound endpoints and health checks run on a context of their own so
+	// they stop after the listeners have drained
+	workersCtx, cancelWorkers := context.WithCancel(ctx)
+	defer cancelWorkers()
+	artifactsPath := filepath.Join(binDir, "..", "artifacts")
+	// Stylesheets of XSLT mediators are loaded from the registry by key
+	xslt.SetDefault(xslt.NewRegistry(filepath.Join(artifactsPath, "Registry")))
+
+	// Schemas in the registry are checked against their subjects before the
+	// artifacts producing them deploy
+	container.Add(Component{
+		Name: "schema-registry",
+		Start: func(ctx context.Context) error {
+			schemaRegistryConfig, ok := conCtx.DeploymentConfig["schemaRegistry"].(schemaregistry.Config)
+			if !ok {
+				return nil
+			}
+			client, err := schemaregistry.NewClient(schemaRegistryConfig)
+			if err != nil {
+				return err
+			}
+			if err := client.CheckSubjects(ctx, filepath.Join(artifactsPath, "Registry")); err != nil {
+				return err
+			}
+			schemaregistry.SetDefault(client)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			schemaregistry.SetDefault(nil)
+			return nil
+		},
+	})
+	driftDetector := checksum.NewDetector(filepath.Join(confPath, "deployment.toml"), artifactsPath,
+		loggerfactory.GetLogger("drift", nil))
+	// Assert mediators check the messages of contract tests, and are skipped
+	// unless the configuration enables them
+	container.Add(Component{
+		Name: "assertions",
+		Start: func(ctx context.Context) error {
+			assertionsConfig, _ := conCtx.DeploymentConfig["assertions"].(artifacts.AssertionsConfig)
+			artifacts.EnableAssertions(assertionsConfig.Enabled)
+			if assertionsConfig.Enabled {
+				loggerfactory.GetLogger("assertions", nil).Warn("Assert mediators are enabled, do not use in production")
+			}
+			return nil
+		},
+	})
+	var deployer *deployers.Deployer
+	container.Add(Component{
+		Name: "deployer",
+		Start: func(ctx context.Context) error {
+			// Fingerprint the files before they are read so the checksum
+			// describes what was deployed
+			if manifest, err := driftDetector.Baseline(); err != nil {
+				log.Printf("Warning: configuration checksum unavailable: %v", err)
+			} else {
+				log.Printf("Configuration checksum: %s (%d files)", manifest.Checksum, len(manifest.Files))
+			}
+			deployer = deployers.NewDeployer(artifactsPath, mediationEngine, routerService)
+			if err := deployer.Deploy(workersCtx); err != nil {
+				log.Printf("Error deploying artifacts: %v", err)
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancelWorkers()
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("inbound endpoints did not stop: %w", ctx.Err())
+			}
+		},
+	})
+
+	// Warn when artifacts or deployment.toml are edited after deployment
+	container.Add(Component{
+		Name: "drift-detector",
+		Start: func(ctx context.Context) error {
+			driftConfig, _ := conCtx.DeploymentConfig["drift"].(checksum.Config)
+			interval, err := driftConfig.Interval()
+			if err != nil {
+				return err
+			}
+			wg.Add(1)
+			leaks.Default().Go("drift-detector", func() {
+				defer wg.Done()
+				driftDetector.Run(workersCtx, interval)
+			})
+			return nil
+		},
+	})
+
+	// Expose the OpenMetrics endpoint on the main listener if enabled
+	container.Add(Component{
+		Name: "metrics",
+		Start: func(ctx context.Context) error {
+			metricsConfig, ok := conCtx.DeploymentConfig["metrics"].(metrics.Config)
+			if !ok || !metricsConfig.Enabled {
+				return nil
+			}
+			path := metricsConfig.Path
+			if path == "" {
+				path = "/metrics"
+			}
+			return routerService.RegisterHandler(path, metrics.Default().Handler())
+		},
+	})
+
+	// Mount the admin API on the main listener if enabled
+	container.Add(Component{
+		Name: "admin",
+		Start: func(ctx context.Context) error {
+			adminConfig, ok := conCtx.DeploymentConfig["admin"].(admin.Config)
+			if !ok || !adminConfig.Enabled {
+				return nil
+			}
+			adminService := admin.NewService()
+			registerAdminEndpoints(adminService, routerService, container, driftDetector, deployer.Rollout())
+			return adminService.Mount(routerService, adminConfig, conCtx.DeploymentConfig)
+		},
+	})
+
+	// Sample requests declared by APIs are served in process before the
+	// listener opens, so broken configurations show in /readyz before traffic
+	container.Add(Component{
+		Name: "self-test",
+		Start: func(ctx context.Context) error {
+			apis := slices.Collect(maps.Values(conCtx.Snapshot().APIs))
+			selftest.Run(ctx, routerService.HealthChecks(), routerService.Handler(), apis,
+				loggerfactory.GetLogger("selftest", nil))
+			return nil
+		},
+	})
+
+	container.Add(Component{
+		Name: "http-server",
+		Start: func(ctx context.Context) error {
+			routerService.StartServer(ctx)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			routerService.StopServer()
+			log.Println("HTTP server shutdown gracefully")
+			return nil
+		},
+	})
+
+	// SIGHUP reloads log levels, CORS defaults and new artifacts without
+	// restarting the listeners
+	var stopReload func()
+	container.Add(Component{
+		Name: "reload",
+		Start: func(ctx context.Context) error {
+			stopReload = watchReload(workersCtx, confPath, deployer)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			stopReload()
+			return nil
+		},
+	})
+
+	if err := container.Start(ctx, shutdownTimeout); err != nil {
+		return err
 	}
 
-	mediationEngine := mediation.NewMediationEngine()
+	elapsed := time.Since(start)
+	log.Printf("Server started in: %v", elapsed)
+
+	<-ctx.Done()
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer stopCancel()
+	return container.Stop(stopCtx)
+}
 
+// newRouterService creates the router on the configured port and enables TLS
+// and the flight recorder directory when configured
+func newRouterService(ctx context.Context, deploymentConfig map[string]interface{}, confPath string) (*router.RouterService, error) {
 	// Define default port
 	httpServerPort := 8290
+	portOffset := 0
 	var hostname string
-	if serverConfig, ok := conCtx.DeploymentConfig["server"].(map[string]string); ok {
+	if serverConfig, ok := deploymentConfig["server"].(map[string]string); ok {
 		hostname = serverConfig["hostname"]
 		if offsetStr, offsetExists := serverConfig["offset"]; offsetExists {
 			if offsetInt, err := strconv.Atoi(offsetStr); err == nil {
+				portOffset = offsetInt
 				httpServerPort += offsetInt
 				log.Printf("Using port offset: %d, final port: %d", offsetInt, httpServerPort)
 			} else {
@@ -90,24 +641,36 @@ func Run(ctx context.Context) error {
 	// Initialize the router service with the calculated port
 	routerService := router.NewRouterService(listenPort, hostname)
 
-	artifactsPath := filepath.Join(binDir, "..", "artifacts")
-	deployer := deployers.NewDeployer(artifactsPath, mediationEngine, routerService)
-	err = deployer.Deploy(ctx)
-	if err != nil {
-		log.Printf("Error deploying artifacts: %v", err)
+	// Configure the HTTPS listener if TLS is enabled
+	if tlsConfig, ok := deploymentConfig["tls"].(certs.Config); ok && tlsConfig.Enabled {
+		certManager, err := certs.NewManager(tlsConfig, confPath)
+		if err != nil {
+			return nil, fmt.Errorf("TLS initialization error: %w", err)
+		}
+		httpsPort := certs.DefaultHTTPSPort
+		if tlsConfig.Port != 0 {
+			httpsPort = tlsConfig.Port
+		}
+		routerService.EnableTLS(fmt.Sprintf(":%d", httpsPort+portOffset), certManager.TLSConfig())
+		routerService.SetHTTPHandlerWrapper(certManager.HTTPChallengeHandler)
+		if err := certManager.Watch(ctx); err != nil {
+			log.Printf("Warning: TLS certificate hot-reload disabled: %v", err)
+		}
 	}
 
-	// Start HTTP Server
-	routerService.StartServer(ctx)
-
-	elapsed := time.Since(start)
-	log.Printf("Server started in: %v", elapsed)
-
-	<-ctx.Done()
-	wg.Wait()
-	routerService.StopServer()
-	log.Println("HTTP server shutdown gracefully")
-	return nil
+	// Write flight recorder captures next to the configuration unless an
+	// absolute directory is configured
+	if captureConfig, ok := deploymentConfig["capture"].(capture.Config); ok {
+		if captureConfig.Directory != "" {
+			directory := captureConfig.Directory
+			if !filepath.IsAbs(directory) {
+				directory = filepath.Join(confPath, directory)
+			}
+			routerService.Capture().SetDirectory(directory)
+		}
+		routerService.Capture().SetRedactHeaders(captureConfig.RedactHeaders)
+	}
+	return routerService, nil
 }
 
 func PrintWelcomeMessage() {
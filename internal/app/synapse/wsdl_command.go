@@ -0,0 +1,135 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package synapse
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/wsdl"
+)
+
+// RunWSDLImportCommand implements "synapse import-wsdl", which generates an API
+// artifact exposing the operations of a SOAP service described by a WSDL file
+// or URL
+func RunWSDLImportCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import-wsdl", flag.ContinueOnError)
+	flags.SetOutput(out)
+	outputDir := flags.String("o", "", "directory the artifact is written to, e.g. artifacts/APIs; standard output if empty")
+	serviceName := flags.String("service", "", "service to import when the WSDL declares several")
+	portName := flags.String("port", "", "port to import when the service has several; SOAP 1.1 ports are preferred")
+	context := flags.String("context", "", "context of the generated API, derived from the service name if empty")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "Usage: synapse import-wsdl [flags] <wsdl file or URL>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one WSDL file or URL")
+	}
+
+	document, err := openWSDL(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer document.Close()
+	services, err := wsdl.Parse(document)
+	if err != nil {
+		return err
+	}
+
+	service, err := selectService(services, *serviceName)
+	if err != nil {
+		return err
+	}
+	port, err := selectPort(service, *portName)
+	if err != nil {
+		return err
+	}
+	if *context == "" {
+		*context = wsdl.DefaultContext(service.Name)
+	}
+	artifact, err := wsdl.GenerateAPI(service, port, *context)
+	if err != nil {
+		return err
+	}
+
+	if *outputDir == "" {
+		_, err = out.Write(artifact)
+		return err
+	}
+	path := filepath.Join(*outputDir, service.Name+".xml")
+	if err := os.WriteFile(path, artifact, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Generated API %s with %d operations at %s\n", service.Name, len(port.Operations), path)
+	return nil
+}
+
+func openWSDL(location string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.Open(location)
+	}
+	response, err := (&http.Client{Timeout: time.Minute}).Get(location)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("cannot fetch WSDL: %s", response.Status)
+	}
+	return response.Body, nil
+}
+
+func selectService(services []wsdl.Service, name string) (wsdl.Service, error) {
+	if name == "" {
+		if len(services) > 1 {
+			return wsdl.Service{}, fmt.Errorf("the WSDL declares %d services, choose one with -service", len(services))
+		}
+		return services[0], nil
+	}
+	for _, service := range services {
+		if service.Name == name {
+			return service, nil
+		}
+	}
+	return wsdl.Service{}, fmt.Errorf("service %s not found in the WSDL", name)
+}
+
+func selectPort(service wsdl.Service, name string) (wsdl.Port, error) {
+	for _, port := range service.Ports {
+		if port.Name == name || name == "" && port.SOAPVersion == "1.1" {
+			return port, nil
+		}
+	}
+	if name == "" {
+		return service.Ports[0], nil
+	}
+	return wsdl.Port{}, fmt.Errorf("port %s not found in service %s", name, service.Name)
+}
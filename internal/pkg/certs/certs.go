@@ -0,0 +1,244 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package certs manages the TLS certificates served by the HTTPS listener.
+//
+// Certificates can either be loaded from PEM files on disk or obtained and
+// renewed automatically from an ACME certificate authority such as Let's Encrypt.
+// PEM files are watched and reloaded on change, so certificates can be rotated
+// without restarting the listeners. A single listener can serve several
+// certificates selected by SNI, each with its own TLS profile.
+package certs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	componentName = "certs"
+
+	// DefaultHTTPSPort is used when the [tls] section does not declare a port
+	DefaultHTTPSPort = 8253
+)
+
+// Config holds the [tls] section of deployment.toml
+type Config struct {
+	Enabled  bool       `koanf:"enabled"`
+	Port     int        `koanf:"port"`
+	CertFile string     `koanf:"certFile"`
+	KeyFile  string     `koanf:"keyFile"`
+	ACME     ACMEConfig `koanf:"acme"`
+	// SNI lists per-hostname certificates served on the same listener
+	SNI []SNIConfig `koanf:"sni"`
+}
+
+// ACMEConfig holds the [tls.acme] section of deployment.toml
+type ACMEConfig struct {
+	Enabled bool   `koanf:"enabled"`
+	Email   string `koanf:"email"`
+	// Hostnames the runtime is allowed to request certificates for
+	Hostnames []string `koanf:"hostnames"`
+	// CacheDir stores issued certificates, and the account key unless
+	// AccountKeyAlias is set. Relative paths are resolved against the conf
+	// directory.
+	CacheDir string `koanf:"cacheDir"`
+	// AccountKeyAlias names the [secrets] entry holding the PEM encoded ACME
+	// account key. The secret store is read-only, so issued certificates are
+	// still kept in CacheDir.
+	AccountKeyAlias string `koanf:"accountKeyAlias"`
+	// DirectoryURL defaults to the Let's Encrypt production directory
+	DirectoryURL string `koanf:"directoryURL"`
+}
+
+// Validate checks that the configuration describes at least one certificate source
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Port < 0 {
+		return fmt.Errorf("tls port must be non-negative, got: %d", c.Port)
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("both certFile and keyFile must be specified together")
+	}
+	if c.ACME.Enabled && len(c.ACME.Hostnames) == 0 {
+		return fmt.Errorf("acme requires at least one hostname")
+	}
+	if !c.ACME.Enabled && c.CertFile == "" && len(c.SNI) == 0 {
+		return fmt.Errorf("tls is enabled but neither certFile/keyFile, sni nor acme is configured")
+	}
+	for i, sni := range c.SNI {
+		if err := sni.Validate(); err != nil {
+			return fmt.Errorf("invalid sni entry %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Manager resolves the certificate to present for each TLS handshake
+type Manager struct {
+	config Config
+	// static is the default key pair, used when no SNI profile matches
+	static   *keyPair
+	profiles []*sniProfile
+	autocert *autocert.Manager
+	logger   *slog.Logger
+}
+
+// NewManager creates a certificate manager. confPath is used to resolve relative
+// file locations in the configuration.
+func NewManager(config Config, confPath string) (*Manager, error) {
+	m := &Manager{config: config}
+	m.logger = loggerfactory.GetLogger(componentName, m)
+
+	if config.CertFile != "" {
+		static, err := newKeyPair(resolvePath(confPath, config.CertFile), resolvePath(confPath, config.KeyFile))
+		if err != nil {
+			return nil, err
+		}
+		m.static = static
+	}
+
+	for _, sni := range config.SNI {
+		profile, err := newSNIProfile(sni, confPath)
+		if err != nil {
+			return nil, err
+		}
+		m.profiles = append(m.profiles, profile)
+		m.logger.Info("SNI certificate loaded", slog.Any("hostnames", sni.Hostnames))
+	}
+
+	if config.ACME.Enabled {
+		cacheDir := config.ACME.CacheDir
+		if cacheDir == "" {
+			cacheDir = filepath.Join("security", "acme")
+		}
+		m.autocert = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(resolvePath(confPath, cacheDir)),
+			HostPolicy: autocert.HostWhitelist(config.ACME.Hostnames...),
+			Email:      config.ACME.Email,
+		}
+		if config.ACME.DirectoryURL != "" || config.ACME.AccountKeyAlias != "" {
+			m.autocert.Client = &acme.Client{DirectoryURL: config.ACME.DirectoryURL}
+		}
+		if config.ACME.AccountKeyAlias != "" {
+			key, err := secrets.Default().PrivateKey(config.ACME.AccountKeyAlias)
+			if err != nil {
+				return nil, fmt.Errorf("acme account key: %w", err)
+			}
+			m.autocert.Client.Key = key
+		}
+		m.logger.Info("ACME certificate management enabled",
+			slog.Any("hostnames", config.ACME.Hostnames))
+	}
+	return m, nil
+}
+
+func (m *Manager) UpdateLogger() {
+	m.logger = loggerfactory.GetLogger(componentName, m)
+}
+
+// TLSConfig returns the tls.Config to be used by the HTTPS listener
+func (m *Manager) TLSConfig() *tls.Config {
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: m.GetCertificate,
+	}
+	if m.autocert != nil {
+		// Advertise the ACME protocol so TLS-ALPN-01 challenges can be answered
+		tlsConfig.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+	}
+	// Apply the TLS profile of the SNI entry matching the requested server name
+	tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		profile := m.matchProfile(hello.ServerName)
+		if profile == nil || !profile.overridesDefaults() {
+			return nil, nil
+		}
+		profileConfig := tlsConfig.Clone()
+		profileConfig.GetConfigForClient = nil
+		profile.apply(profileConfig)
+		return profileConfig, nil
+	}
+	return tlsConfig
+}
+
+// GetCertificate returns the certificate for the requested server name. SNI
+// profiles are consulted first, then ACME; the static key pair is used as a
+// fallback for hosts that are not managed otherwise. TLS-ALPN-01 challenges
+// are always answered by ACME, so hostnames that also have an SNI profile can
+// still be issued certificates.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if m.autocert != nil && slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+		return m.autocert.GetCertificate(hello)
+	}
+	if profile := m.matchProfile(hello.ServerName); profile != nil {
+		return profile.keyPair.certificate(), nil
+	}
+	if m.autocert != nil {
+		cert, err := m.autocert.GetCertificate(hello)
+		if err == nil || m.static == nil {
+			return cert, err
+		}
+		m.logger.Debug("falling back to static certificate",
+			slog.String("server_name", hello.ServerName),
+			slog.String("error", err.Error()))
+	}
+	if m.static == nil {
+		return nil, fmt.Errorf("no certificate available for %q", hello.ServerName)
+	}
+	return m.static.certificate(), nil
+}
+
+// matchProfile returns the first SNI profile serving the given server name
+func (m *Manager) matchProfile(serverName string) *sniProfile {
+	for _, profile := range m.profiles {
+		if profile.matches(serverName) {
+			return profile
+		}
+	}
+	return nil
+}
+
+// HTTPChallengeHandler wraps the plain HTTP handler so HTTP-01 challenges are
+// answered before requests reach the router. It returns fallback unchanged when
+// ACME is disabled.
+func (m *Manager) HTTPChallengeHandler(fallback http.Handler) http.Handler {
+	if m.autocert == nil {
+		return fallback
+	}
+	return m.autocert.HTTPHandler(fallback)
+}
+
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
@@ -0,0 +1,124 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{
+			name:    "Disabled",
+			config:  Config{},
+			wantErr: false,
+		},
+		{
+			name:    "Static key pair",
+			config:  Config{Enabled: true, CertFile: "server.crt", KeyFile: "server.key"},
+			wantErr: false,
+		},
+		{
+			name:    "Certificate without key",
+			config:  Config{Enabled: true, CertFile: "server.crt"},
+			wantErr: true,
+		},
+		{
+			name:    "ACME with hostnames",
+			config:  Config{Enabled: true, ACME: ACMEConfig{Enabled: true, Hostnames: []string{"api.example.com"}}},
+			wantErr: false,
+		},
+		{
+			name:    "ACME without hostnames",
+			config:  Config{Enabled: true, ACME: ACMEConfig{Enabled: true}},
+			wantErr: true,
+		},
+		{
+			name: "SNI only",
+			config: Config{Enabled: true, SNI: []SNIConfig{
+				{Hostnames: []string{"api.example.com"}, CertFile: "api.crt", KeyFile: "api.key"},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "SNI with invalid minimum version",
+			config: Config{Enabled: true, SNI: []SNIConfig{
+				{Hostnames: []string{"api.example.com"}, CertFile: "api.crt", KeyFile: "api.key", MinVersion: "1.0"},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "No certificate source",
+			config:  Config{Enabled: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewManager_ACMEAccountKeyAlias(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{
+		Format:     "text",
+		OutputPath: "stdout",
+	})
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	store, err := secrets.NewStore(secrets.Config{Entries: []secrets.Entry{
+		{Alias: "acme-account", Value: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))},
+	}}, t.TempDir())
+	require.NoError(t, err)
+	previous := secrets.Default()
+	secrets.SetDefault(store)
+	t.Cleanup(func() { secrets.SetDefault(previous) })
+
+	config := Config{Enabled: true, ACME: ACMEConfig{Enabled: true, Hostnames: []string{"api.example.com"},
+		CacheDir: t.TempDir(), AccountKeyAlias: "acme-account"}}
+	m, err := NewManager(config, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, m.autocert.Client.Key.Public())
+
+	config.ACME.AccountKeyAlias = "missing"
+	_, err = NewManager(config, t.TempDir())
+	assert.ErrorContains(t, err, "acme account key")
+}
@@ -0,0 +1,157 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// keyPair is a certificate loaded from PEM files that can be reloaded in place
+type keyPair struct {
+	certFile string
+	keyFile  string
+	// cert is swapped atomically when the key pair is reloaded from disk
+	cert atomic.Pointer[tls.Certificate]
+}
+
+func newKeyPair(certFile, keyFile string) (*keyPair, error) {
+	kp := &keyPair{certFile: certFile, keyFile: keyFile}
+	if err := kp.load(); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+// load reads the PEM files and makes them the active certificate. The previous
+// certificate stays active if loading fails.
+func (kp *keyPair) load() error {
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load TLS key pair: %w", err)
+	}
+	kp.cert.Store(&cert)
+	return nil
+}
+
+func (kp *keyPair) certificate() *tls.Certificate {
+	return kp.cert.Load()
+}
+
+// watchedFile is a certificate or key file and the key pairs reading it
+type watchedFile struct {
+	path string
+	// realPath is the file path resolves to, which changes when a symlinked
+	// file is rotated by replacing the link
+	realPath string
+	keyPairs []*keyPair
+}
+
+// Watch reloads the key pairs whenever a certificate or key file changes.
+// The directories holding the files are watched rather than the files
+// themselves, so rotations that delete and recreate a file, or swap a
+// symlink, keep being picked up. New handshakes pick up the reloaded
+// certificate while established connections are left untouched. Watching
+// stops when ctx is done.
+func (m *Manager) Watch(ctx context.Context) error {
+	keyPairs := make([]*keyPair, 0, len(m.profiles)+1)
+	if m.static != nil {
+		keyPairs = append(keyPairs, m.static)
+	}
+	for _, profile := range m.profiles {
+		keyPairs = append(keyPairs, profile.keyPair)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("cannot watch certificates: %w", err)
+	}
+	files := make(map[string]*watchedFile)
+	for _, kp := range keyPairs {
+		for _, path := range []string{kp.certFile, kp.keyFile} {
+			path = filepath.Clean(path)
+			file, ok := files[path]
+			if !ok {
+				if err := watcher.Add(filepath.Dir(path)); err != nil {
+					watcher.Close()
+					return fmt.Errorf("cannot watch %s: %w", path, err)
+				}
+				file = &watchedFile{path: path}
+				file.realPath, _ = filepath.EvalSymlinks(path)
+				files[path] = file
+			}
+			file.keyPairs = append(file.keyPairs, kp)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// A removed file is reloaded once it is created again
+				if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				name := filepath.Clean(event.Name)
+				for _, file := range files {
+					realPath, _ := filepath.EvalSymlinks(file.path)
+					if name != file.path && name != file.realPath && realPath == file.realPath {
+						continue
+					}
+					file.realPath = realPath
+					m.reload(file)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				m.logger.Error("certificate watch error", slog.String("error", err.Error()))
+			}
+		}
+	}()
+	return nil
+}
+
+// reload loads the key pairs reading a changed file
+func (m *Manager) reload(file *watchedFile) {
+	for _, kp := range file.keyPairs {
+		// The certificate and key are usually rotated together, so a reload
+		// triggered by the first file may fail until the second one is written.
+		if err := kp.load(); err != nil {
+			m.logger.Warn("certificate reload failed, keeping the current certificate",
+				slog.String("file", file.path),
+				slog.String("error", err.Error()))
+			continue
+		}
+		m.logger.Info("TLS certificate reloaded", slog.String("file", file.path))
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeKeyPair writes a self-signed certificate for commonName into dir
+func writeKeyPair(t *testing.T, dir, commonName string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	// Write the key first so the reload triggered by the certificate succeeds
+	if err := os.WriteFile(filepath.Join(dir, "server.key"), keyPEM, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "server.crt"), certPEM, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func servedCommonName(t *testing.T, m *Manager, hello *tls.ClientHelloInfo) string {
+	t.Helper()
+	cert, err := m.GetCertificate(hello)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestManager_Watch_ReloadsKeyPair(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{
+		Format:     "text",
+		OutputPath: "stdout",
+	})
+	dir := t.TempDir()
+	writeKeyPair(t, dir, "old.example.com")
+
+	m, err := NewManager(Config{Enabled: true, CertFile: "server.crt", KeyFile: "server.key"}, dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	assert.Equal(t, "old.example.com", servedCommonName(t, m, &tls.ClientHelloInfo{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Watch(ctx); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	writeKeyPair(t, dir, "new.example.com")
+	assert.Eventually(t, func() bool {
+		return servedCommonName(t, m, &tls.ClientHelloInfo{}) == "new.example.com"
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestManager_Watch_SurvivesRemoval(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{
+		Format:     "text",
+		OutputPath: "stdout",
+	})
+	dir := t.TempDir()
+	writeKeyPair(t, dir, "first.example.com")
+
+	m, err := NewManager(Config{Enabled: true, CertFile: "server.crt", KeyFile: "server.key"}, dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Watch(ctx); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	// Rotations that delete the files before writing new ones keep being
+	// picked up
+	for _, commonName := range []string{"second.example.com", "third.example.com"} {
+		for _, name := range []string{"server.crt", "server.key"} {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				t.Fatalf("Remove() error = %v", err)
+			}
+		}
+		writeKeyPair(t, dir, commonName)
+		assert.Eventually(t, func() bool {
+			return servedCommonName(t, m, &tls.ClientHelloInfo{}) == commonName
+		}, 5*time.Second, 20*time.Millisecond)
+	}
+}
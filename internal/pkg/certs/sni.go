@@ -0,0 +1,150 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package certs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// SNIConfig holds one [[tls.sni]] entry of deployment.toml
+type SNIConfig struct {
+	// Hostnames served by this entry. A leading "*." matches a single label.
+	Hostnames []string `koanf:"hostnames"`
+	CertFile  string   `koanf:"certFile"`
+	KeyFile   string   `koanf:"keyFile"`
+	// MinVersion is "1.2" or "1.3"; the listener default applies when empty
+	MinVersion string `koanf:"minVersion"`
+	// CipherSuites uses the Go names, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+	CipherSuites []string `koanf:"cipherSuites"`
+}
+
+// Validate checks a single SNI entry
+func (c SNIConfig) Validate() error {
+	if len(c.Hostnames) == 0 {
+		return fmt.Errorf("at least one hostname is required")
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("both certFile and keyFile are required")
+	}
+	if _, err := parseTLSVersion(c.MinVersion); err != nil {
+		return err
+	}
+	if _, err := parseCipherSuites(c.CipherSuites); err != nil {
+		return err
+	}
+	return nil
+}
+
+// sniProfile is the runtime form of an SNIConfig
+type sniProfile struct {
+	hostnames    []string
+	keyPair      *keyPair
+	minVersion   uint16
+	cipherSuites []uint16
+}
+
+func newSNIProfile(config SNIConfig, confPath string) (*sniProfile, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	kp, err := newKeyPair(resolvePath(confPath, config.CertFile), resolvePath(confPath, config.KeyFile))
+	if err != nil {
+		return nil, err
+	}
+	// Errors were already reported by Validate
+	minVersion, _ := parseTLSVersion(config.MinVersion)
+	cipherSuites, _ := parseCipherSuites(config.CipherSuites)
+
+	profile := &sniProfile{
+		keyPair:      kp,
+		minVersion:   minVersion,
+		cipherSuites: cipherSuites,
+	}
+	for _, hostname := range config.Hostnames {
+		profile.hostnames = append(profile.hostnames, strings.ToLower(hostname))
+	}
+	return profile, nil
+}
+
+// matches reports whether the profile serves the given server name
+func (p *sniProfile) matches(serverName string) bool {
+	serverName = strings.ToLower(serverName)
+	for _, hostname := range p.hostnames {
+		if hostname == serverName {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(hostname, "*"); ok {
+			label, found := strings.CutSuffix(serverName, suffix)
+			if found && label != "" && !strings.Contains(label, ".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overridesDefaults reports whether the profile changes the listener's TLS settings
+func (p *sniProfile) overridesDefaults() bool {
+	return p.minVersion != 0 || len(p.cipherSuites) > 0
+}
+
+// apply copies the profile's TLS settings into config
+func (p *sniProfile) apply(config *tls.Config) {
+	if p.minVersion != 0 {
+		config.MinVersion = p.minVersion
+	}
+	if len(p.cipherSuites) > 0 {
+		config.CipherSuites = p.cipherSuites
+	}
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS minVersion '%s', must be '1.2' or '1.3'", version)
+	}
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite '%s'", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
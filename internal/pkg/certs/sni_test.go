@@ -0,0 +1,120 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package certs
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/acme"
+)
+
+func TestSNIProfile_Matches(t *testing.T) {
+	profile := &sniProfile{hostnames: []string{"api.foo.com", "*.bar.com"}}
+
+	testCases := []struct {
+		serverName string
+		expected   bool
+	}{
+		{serverName: "api.foo.com", expected: true},
+		{serverName: "API.FOO.COM", expected: true},
+		{serverName: "www.foo.com", expected: false},
+		{serverName: "api.bar.com", expected: true},
+		{serverName: "a.b.bar.com", expected: false},
+		{serverName: "bar.com", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.serverName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, profile.matches(tc.serverName))
+		})
+	}
+}
+
+func TestManager_GetCertificate_SelectsBySNI(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{
+		Format:     "text",
+		OutputPath: "stdout",
+	})
+	dir := t.TempDir()
+	for _, name := range []string{"default", "foo", "bar"} {
+		writeKeyPair(t, filepath.Join(dir, name), name+".example.com")
+	}
+
+	config := Config{
+		Enabled:  true,
+		CertFile: "default/server.crt",
+		KeyFile:  "default/server.key",
+		SNI: []SNIConfig{
+			{Hostnames: []string{"foo.example.com"}, CertFile: "foo/server.crt", KeyFile: "foo/server.key", MinVersion: "1.3"},
+			{Hostnames: []string{"*.bar.example.com", "bar.example.com"}, CertFile: "bar/server.crt", KeyFile: "bar/server.key"},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	m, err := NewManager(config, dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	hello := func(serverName string) *tls.ClientHelloInfo {
+		return &tls.ClientHelloInfo{ServerName: serverName}
+	}
+	assert.Equal(t, "foo.example.com", servedCommonName(t, m, hello("foo.example.com")))
+	assert.Equal(t, "bar.example.com", servedCommonName(t, m, hello("www.bar.example.com")))
+	assert.Equal(t, "default.example.com", servedCommonName(t, m, hello("other.example.com")))
+
+	// Only the foo profile overrides the listener defaults
+	tlsConfig := m.TLSConfig()
+	fooConfig, err := tlsConfig.GetConfigForClient(hello("foo.example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), fooConfig.MinVersion)
+	barConfig, err := tlsConfig.GetConfigForClient(hello("bar.example.com"))
+	assert.NoError(t, err)
+	assert.Nil(t, barConfig)
+}
+
+func TestManager_GetCertificate_ACMEChallengeBeforeSNI(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{
+		Format:     "text",
+		OutputPath: "stdout",
+	})
+	dir := t.TempDir()
+	writeKeyPair(t, filepath.Join(dir, "foo"), "foo.example.com")
+
+	m, err := NewManager(Config{
+		Enabled: true,
+		ACME:    ACMEConfig{Enabled: true, Hostnames: []string{"foo.example.com"}, CacheDir: "acme"},
+		SNI:     []SNIConfig{{Hostnames: []string{"foo.example.com"}, CertFile: "foo/server.crt", KeyFile: "foo/server.key"}},
+	}, dir)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	assert.Equal(t, "foo.example.com", servedCommonName(t, m, &tls.ClientHelloInfo{ServerName: "foo.example.com"}))
+	// Challenge handshakes reach ACME instead of being answered with the SNI
+	// certificate; no challenge is pending here
+	_, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "foo.example.com", SupportedProtos: []string{acme.ALPNProto}})
+	assert.ErrorContains(t, err, "no token cert")
+}
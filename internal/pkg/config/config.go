@@ -26,10 +26,35 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/apache/synapse-go/internal/pkg/certs"
+	"github.com/apache/synapse-go/internal/pkg/core/admin"
+	"github.com/apache/synapse-go/internal/pkg/core/analytics"
 	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/cache"
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/checksum"
+	"github.com/apache/synapse-go/internal/pkg/core/claimcheck"
+	"github.com/apache/synapse-go/internal/pkg/core/datasource"
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/apache/synapse-go/internal/pkg/core/geoip"
+	"github.com/apache/synapse-go/internal/pkg/core/ldap"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+	"github.com/apache/synapse-go/internal/pkg/core/middleware"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+	"github.com/apache/synapse-go/internal/pkg/core/profile"
+	"github.com/apache/synapse-go/internal/pkg/core/schemaregistry"
+	"github.com/apache/synapse-go/internal/pkg/core/slo"
+	"github.com/apache/synapse-go/internal/pkg/core/tracing"
+	"github.com/apache/synapse-go/internal/pkg/core/transactions"
+	"github.com/apache/synapse-go/internal/pkg/core/useragent"
 	"github.com/apache/synapse-go/internal/pkg/core/utils"
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
 	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
 
 	"github.com/knadh/koanf/parsers/toml"
 	"github.com/knadh/koanf/providers/file"
@@ -53,27 +78,62 @@ func ReadFile(filename string) (*Config, error) {
 	return cfg, nil
 }
 
+// profileFile returns the overlay of filename for the active profile, such as
+// deployment-dev.toml for deployment.toml, or "" when no profile is active
+func profileFile(filename string) string {
+	name := profile.Active()
+	if name == "" {
+		return ""
+	}
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "-" + name + ext
+}
+
+// readConfig reads filename over defaults, then merges the overlay of the
+// active profile over it when the overlay exists
+func readConfig(filename string, defaults map[string]interface{}) (*Config, error) {
+	k := koanf.New(".")
+	for key, value := range defaults {
+		if err := k.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := k.Load(file.Provider(filename), toml.Parser()); err != nil {
+		return nil, err
+	}
+	if overlay := profileFile(filename); overlay != "" {
+		if _, err := os.Stat(overlay); err == nil {
+			if err := k.Load(file.Provider(overlay), toml.Parser()); err != nil {
+				return nil, err
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return &Config{koanf: k}, nil
+}
+
 func (c *Config) IsSet(key string) bool {
 	return c.koanf.Exists(key)
 }
 
+// Watch reloads the logger configuration when filename, or its overlay for
+// the active profile, changes
 func (c *Config) Watch(ctx context.Context, filename string) {
-	f := file.Provider(filename)
-
-	f.Watch(func(event interface{}, err error) {
+	reload := func(event interface{}, err error) {
 		if err != nil {
 			log.Printf("watch error: %v", err)
 			return
 		}
 		// Throw away the old config and load a fresh copy.
 		log.Println("config changed. Reloading ...")
-		new_k := koanf.New(".")
-		if err := new_k.Load(f, toml.Parser()); err != nil {
+		newConfig, err := readConfig(filename, nil)
+		if err != nil {
 			log.Printf("error loading new config: %v", err)
 			return
 		}
 		// Update the config
-		c.koanf = new_k
+		c.koanf = newConfig.koanf
 
 		// Update the logger configuration
 		var levelMap map[string]string
@@ -85,7 +145,14 @@ func (c *Config) Watch(ctx context.Context, filename string) {
 		cm := loggerfactory.GetConfigManager()
 		cm.SetLogLevelMap(&levelMap)
 		cm.SetSlogHandlerConfig(slogHandlerConfig)
-	})
+	}
+
+	file.Provider(filename).Watch(reload)
+	if overlay := profileFile(filename); overlay != "" {
+		if _, err := os.Stat(overlay); err == nil {
+			file.Provider(overlay).Watch(reload)
+		}
+	}
 }
 
 func (c *Config) Unmarshal(key string, out interface{}) error {
@@ -103,6 +170,56 @@ func (c *Config) MustUnmarshal(key string, out interface{}) {
 	}
 }
 
+// applyLoggerConfig sets the log levels and handler of LoggerConfig.toml
+func applyLoggerConfig(cfg *Config) error {
+	var levelMap map[string]string
+	var slogHandlerConfig loggerfactory.SlogHandlerConfig
+	if cfg.IsSet("logger") {
+		if err := cfg.Unmarshal("logger.handler", &slogHandlerConfig); err != nil {
+			return err
+		}
+		if err := cfg.Unmarshal("logger.level.packages", &levelMap); err != nil {
+			return err
+		}
+	}
+
+	cm := loggerfactory.GetConfigManager()
+	cm.SetLogLevelMap(&levelMap)
+	cm.SetSlogHandlerConfig(slogHandlerConfig)
+	return nil
+}
+
+// Reload rereads the settings of confFolderPath that apply without restarting
+// listeners: the log levels and handler of LoggerConfig.toml and the [cors]
+// defaults of deployment.toml, with the overlays of the active profile. Both
+// files are read before either is applied, so an invalid file changes nothing.
+func Reload(confFolderPath string) error {
+	loggerConfig, err := readConfig(filepath.Join(confFolderPath, "LoggerConfig.toml"), nil)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+	deploymentConfig, err := readConfig(filepath.Join(confFolderPath, "deployment.toml"), profile.Defaults(profile.Active()))
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+	var corsConfig middleware.CORSConfig
+	if deploymentConfig.IsSet("cors") {
+		if err := deploymentConfig.Unmarshal("cors", &corsConfig); err != nil {
+			return err
+		}
+	}
+
+	if err := applyLoggerConfig(loggerConfig); err != nil {
+		return err
+	}
+	middleware.ReloadCORS(corsConfig)
+	return nil
+}
+
+// InitializeConfig reads LoggerConfig.toml and deployment.toml from
+// confFolderPath into the config context. With a profile active, the
+// settings it turns on are read first and its overlays, such as
+// deployment-dev.toml, are merged over the files.
 func InitializeConfig(ctx context.Context, confFolderPath string) error {
 	files, err := os.ReadDir(confFolderPath)
 	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
@@ -115,25 +232,21 @@ func InitializeConfig(ctx context.Context, confFolderPath string) error {
 	// {"LoggerConfig", "deployment"}
 	for _, configurationType := range []string{"LoggerConfig", "deployment"} {
 		configFilePath := filepath.Join(confFolderPath, configurationType+".toml")
-		cfg, err := ReadFile(configFilePath)
+		var defaults map[string]interface{}
+		if configurationType == "deployment" {
+			defaults = profile.Defaults(profile.Active())
+		}
+		cfg, err := readConfig(configFilePath, defaults)
 		if err != nil {
 			return fmt.Errorf("cannot read config file: %w", err)
 		}
 
 		switch configurationType {
 		case "LoggerConfig":
-			var levelMap map[string]string
-			var slogHandlerConfig loggerfactory.SlogHandlerConfig
-
-			if cfg.IsSet("logger") {
-				cfg.MustUnmarshal("logger.handler", &slogHandlerConfig)
-				cfg.MustUnmarshal("logger.level.packages", &levelMap)
+			if err := applyLoggerConfig(cfg); err != nil {
+				return err
 			}
 
-			cm := loggerfactory.GetConfigManager()
-			cm.SetLogLevelMap(&levelMap)
-			cm.SetSlogHandlerConfig(slogHandlerConfig)
-
 			// Start watching for config changes
 			cfg.Watch(context.Background(), configFilePath)
 
@@ -169,6 +282,351 @@ func InitializeConfig(ctx context.Context, confFolderPath string) error {
 				return fmt.Errorf("server configuration section is required in deployment.toml")
 			}
 
+			// TLS listener configuration is optional
+			if cfg.IsSet("tls") {
+				var tlsConfig certs.Config
+				if err := cfg.Unmarshal("tls", &tlsConfig); err != nil {
+					return err
+				}
+				if err := tlsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid tls configuration: %w", err)
+				}
+				deploymentConfigMap["tls"] = tlsConfig
+			}
+
+			// CORS defaults, CSRF settings, security policies and authorization services
+			// shared by APIs and HTTP inbound endpoints
+			if cfg.IsSet("cors") {
+				var corsConfig middleware.CORSConfig
+				if err := cfg.Unmarshal("cors", &corsConfig); err != nil {
+					return err
+				}
+				deploymentConfigMap["cors"] = corsConfig
+			}
+			if cfg.IsSet("csrf") {
+				var csrfConfig middleware.CSRFConfig
+				if err := cfg.Unmarshal("csrf", &csrfConfig); err != nil {
+					return err
+				}
+				if err := csrfConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid csrf configuration: %w", err)
+				}
+				deploymentConfigMap["csrf"] = csrfConfig
+			}
+			if cfg.IsSet("security") {
+				var securityConfig middleware.SecurityConfig
+				if err := cfg.Unmarshal("security", &securityConfig); err != nil {
+					return err
+				}
+				if err := securityConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid security configuration: %w", err)
+				}
+				deploymentConfigMap["security"] = securityConfig
+			}
+			if cfg.IsSet("authorization") {
+				var authorizationConfig middleware.AuthorizationConfig
+				if err := cfg.Unmarshal("authorization", &authorizationConfig); err != nil {
+					return err
+				}
+				if err := authorizationConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid authorization configuration: %w", err)
+				}
+				deploymentConfigMap["authorization"] = authorizationConfig
+			}
+
+			// SQL data sources of the dblookup and dbreport mediators
+			if cfg.IsSet("database") {
+				var databaseConfig datasource.Config
+				if err := cfg.Unmarshal("database", &databaseConfig); err != nil {
+					return err
+				}
+				if err := databaseConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid database configuration: %w", err)
+				}
+				deploymentConfigMap["database"] = databaseConfig
+			}
+
+			// Directory the ldap mediator looks users up in
+			if cfg.IsSet("ldap") {
+				var ldapConfig ldap.Config
+				if err := cfg.Unmarshal("ldap", &ldapConfig); err != nil {
+					return err
+				}
+				if err := ldapConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid ldap configuration: %w", err)
+				}
+				deploymentConfigMap["ldap"] = ldapConfig
+			}
+
+			// Registry resolving writer schemas, and subjects checked at deploy time
+			if cfg.IsSet("schemaRegistry") {
+				var schemaRegistryConfig schemaregistry.Config
+				if err := cfg.Unmarshal("schemaRegistry", &schemaRegistryConfig); err != nil {
+					return err
+				}
+				if err := schemaRegistryConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid schemaRegistry configuration: %w", err)
+				}
+				deploymentConfigMap["schemaRegistry"] = schemaRegistryConfig
+			}
+
+			// Key material and passwords referenced by alias from artifacts
+			if cfg.IsSet("secrets") {
+				var secretsConfig secrets.Config
+				if err := cfg.Unmarshal("secrets", &secretsConfig); err != nil {
+					return err
+				}
+				if err := secretsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid secrets configuration: %w", err)
+				}
+				deploymentConfigMap["secrets"] = secretsConfig
+			}
+
+			// Hooks notified when an API breaches its service level objective
+			if cfg.IsSet("alerts") {
+				var alertsConfig slo.AlertsConfig
+				if err := cfg.Unmarshal("alerts", &alertsConfig); err != nil {
+					return err
+				}
+				if err := alertsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid alerts configuration: %w", err)
+				}
+				deploymentConfigMap["alerts"] = alertsConfig
+			}
+
+			// OpenMetrics endpoint and W3C trace context propagation
+			if cfg.IsSet("metrics") {
+				var metricsConfig metrics.Config
+				if err := cfg.Unmarshal("metrics", &metricsConfig); err != nil {
+					return err
+				}
+				if err := metricsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid metrics configuration: %w", err)
+				}
+				deploymentConfigMap["metrics"] = metricsConfig
+			}
+			if cfg.IsSet("tracing") {
+				var tracingConfig tracing.Config
+				if err := cfg.Unmarshal("tracing", &tracingConfig); err != nil {
+					return err
+				}
+				deploymentConfigMap["tracing"] = tracingConfig
+			}
+
+			// Flight recorder output directory
+			if cfg.IsSet("capture") {
+				var captureConfig capture.Config
+				if err := cfg.Unmarshal("capture", &captureConfig); err != nil {
+					return err
+				}
+				deploymentConfigMap["capture"] = captureConfig
+			}
+
+			// Client location lookup from a MaxMind DB
+			if cfg.IsSet("geoip") {
+				var geoipConfig geoip.Config
+				if err := cfg.Unmarshal("geoip", &geoipConfig); err != nil {
+					return err
+				}
+				if err := geoipConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid geoip configuration: %w", err)
+				}
+				deploymentConfigMap["geoip"] = geoipConfig
+			}
+
+			// Recording or replaying of endpoint responses
+			if cfg.IsSet("stubs") {
+				var stubsConfig outbound.StubConfig
+				if err := cfg.Unmarshal("stubs", &stubsConfig); err != nil {
+					return err
+				}
+				if err := stubsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid stubs configuration: %w", err)
+				}
+				deploymentConfigMap["stubs"] = stubsConfig
+			}
+
+			// DNS caching, address family preference and static hosts
+			if cfg.IsSet("dns") {
+				var dnsConfig outbound.DNSConfig
+				if err := cfg.Unmarshal("dns", &dnsConfig); err != nil {
+					return err
+				}
+				if err := dnsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid dns configuration: %w", err)
+				}
+				deploymentConfigMap["dns"] = dnsConfig
+			}
+
+			// Rolling per-API traffic statistics
+			if cfg.IsSet("analytics") {
+				var analyticsConfig analytics.Config
+				if err := cfg.Unmarshal("analytics", &analyticsConfig); err != nil {
+					return err
+				}
+				if err := analyticsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid analytics configuration: %w", err)
+				}
+				deploymentConfigMap["analytics"] = analyticsConfig
+			}
+
+			// One event per completed API request for billing and audit
+			if cfg.IsSet("transactions") {
+				var transactionsConfig transactions.Config
+				if err := cfg.Unmarshal("transactions", &transactionsConfig); err != nil {
+					return err
+				}
+				if err := transactionsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid transactions configuration: %w", err)
+				}
+				deploymentConfigMap["transactions"] = transactionsConfig
+			}
+
+			// Response cache bounds and persistence across restarts
+			if cfg.IsSet("cache") {
+				var cacheConfig cache.Config
+				if err := cfg.Unmarshal("cache", &cacheConfig); err != nil {
+					return err
+				}
+				if err := cacheConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid cache configuration: %w", err)
+				}
+				deploymentConfigMap["cache"] = cacheConfig
+			}
+
+			// Filesystem store of the claim check mediators
+			if cfg.IsSet("claimCheck") {
+				var claimCheckConfig claimcheck.Config
+				if err := cfg.Unmarshal("claimCheck", &claimCheckConfig); err != nil {
+					return err
+				}
+				if err := claimCheckConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid claimCheck configuration: %w", err)
+				}
+				deploymentConfigMap["claimCheck"] = claimCheckConfig
+			}
+
+			// Fault sequence and error templates of APIs without their own
+			if cfg.IsSet("errors") {
+				var errorsConfig problem.Config
+				if err := cfg.Unmarshal("errors", &errorsConfig); err != nil {
+					return err
+				}
+				if err := errorsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid errors configuration: %w", err)
+				}
+				deploymentConfigMap["errors"] = errorsConfig
+			}
+
+			// Browser, operating system and bot detection from User-Agent
+			if cfg.IsSet("useragent") {
+				var useragentConfig useragent.Config
+				if err := cfg.Unmarshal("useragent", &useragentConfig); err != nil {
+					return err
+				}
+				if err := useragentConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid useragent configuration: %w", err)
+				}
+				deploymentConfigMap["useragent"] = useragentConfig
+			}
+
+			// Signing and retry schedule of outgoing webhooks
+			if cfg.IsSet("webhooks") {
+				var webhooksConfig webhook.Config
+				if err := cfg.Unmarshal("webhooks", &webhooksConfig); err != nil {
+					return err
+				}
+				if err := webhooksConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid webhooks configuration: %w", err)
+				}
+				deploymentConfigMap["webhooks"] = webhooksConfig
+			}
+
+			// Subscribers of the runtime event bus
+			if cfg.IsSet("events") {
+				var eventsConfig events.Config
+				if err := cfg.Unmarshal("events", &eventsConfig); err != nil {
+					return err
+				}
+				if err := eventsConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid events configuration: %w", err)
+				}
+				deploymentConfigMap["events"] = eventsConfig
+			}
+
+			// Mediation engine registered under the given name, "default" unless set
+			if cfg.IsSet("mediation") {
+				var mediationConfigMap map[string]string
+				if err := cfg.Unmarshal("mediation", &mediationConfigMap); err != nil {
+					return err
+				}
+				deploymentConfigMap["mediation"] = mediationConfigMap
+			}
+
+			// Debugging aids such as leak detection
+			if cfg.IsSet("debug") {
+				var debugConfig leaks.Config
+				if err := cfg.Unmarshal("debug", &debugConfig); err != nil {
+					return err
+				}
+				deploymentConfigMap["debug"] = debugConfig
+			}
+
+			// Assert mediators of contract tests, enabled by test configurations
+			if cfg.IsSet("assertions") {
+				var assertionsConfig artifacts.AssertionsConfig
+				if err := cfg.Unmarshal("assertions", &assertionsConfig); err != nil {
+					return err
+				}
+				deploymentConfigMap["assertions"] = assertionsConfig
+			}
+
+			// How often deployed files are compared with those on disk
+			if cfg.IsSet("drift") {
+				var driftConfig checksum.Config
+				if err := cfg.Unmarshal("drift", &driftConfig); err != nil {
+					return err
+				}
+				if err := driftConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid drift configuration: %w", err)
+				}
+				deploymentConfigMap["drift"] = driftConfig
+			}
+
+			// The admin API is disabled unless configured
+			if cfg.IsSet("admin") {
+				var adminConfig admin.Config
+				if err := cfg.Unmarshal("admin", &adminConfig); err != nil {
+					return err
+				}
+				if err := adminConfig.Validate(); err != nil {
+					return fmt.Errorf("invalid admin configuration: %w", err)
+				}
+				deploymentConfigMap["admin"] = adminConfig
+			}
+
+			// Switches of the active profile; the prod profile refuses the
+			// settings meant for development and tests
+			profileConfig := profile.Config{}
+			if cfg.IsSet("profile") {
+				if err := cfg.Unmarshal("profile", &profileConfig); err != nil {
+					return err
+				}
+			}
+			profileConfig.Name = profile.Active()
+			if err := profileConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid profile configuration: %w", err)
+			}
+			if profileConfig.Name == profile.Production {
+				if _, ok := deploymentConfigMap["stubs"]; ok {
+					return fmt.Errorf("stubs cannot be configured in the %s profile", profile.Production)
+				}
+				if assertionsConfig, _ := deploymentConfigMap["assertions"].(artifacts.AssertionsConfig); assertionsConfig.Enabled {
+					return fmt.Errorf("assertions cannot be enabled in the %s profile", profile.Production)
+				}
+			}
+			deploymentConfigMap["profile"] = profileConfig
+
 			configContext.AddDeploymentConfig(deploymentConfigMap)
 		}
 	}
@@ -0,0 +1,66 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfig_ProfileOverlay(t *testing.T) {
+	dir := t.TempDir()
+	deploymentFile := filepath.Join(dir, "deployment.toml")
+	require.NoError(t, os.WriteFile(deploymentFile, []byte(`
+[server]
+hostname = "localhost"
+offset = "0"
+
+[profile]
+relaxedSecurity = false
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "deployment-dev.toml"), []byte(`
+[server]
+offset = "10"
+`), 0o644))
+	t.Cleanup(func() { profile.SetActive("") })
+
+	profile.SetActive(profile.Development)
+	cfg, err := readConfig(deploymentFile, profile.Defaults(profile.Development))
+	require.NoError(t, err)
+	var server map[string]string
+	require.NoError(t, cfg.Unmarshal("server", &server))
+	assert.Equal(t, map[string]string{"hostname": "localhost", "offset": "10"}, server, "the overlay is merged over the base file")
+	var profileConfig profile.Config
+	require.NoError(t, cfg.Unmarshal("profile", &profileConfig))
+	assert.True(t, profileConfig.WireLogging, "the dev profile turns on wire logging")
+	assert.False(t, profileConfig.RelaxedSecurity, "the files override the profile's settings")
+
+	// Profiles without an overlay read the base file alone
+	profile.SetActive(profile.Production)
+	cfg, err = readConfig(deploymentFile, profile.Defaults(profile.Production))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Unmarshal("server", &server))
+	assert.Equal(t, "0", server["offset"])
+}
@@ -0,0 +1,122 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package admin serves the management API operators use to inspect and control
+// a running instance. Subsystems contribute endpoints through HandleFunc and the
+// service is mounted on the main listener under a configurable context.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/middleware"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	componentName = "admin"
+
+	// DefaultContext is used when the [admin] section does not declare a context
+	DefaultContext = "/admin"
+)
+
+// Config holds the [admin] section of deployment.toml
+type Config struct {
+	Enabled bool   `koanf:"enabled"`
+	Context string `koanf:"context"`
+	// SecurityPolicy names the [[security.policy]] protecting the admin API
+	SecurityPolicy string `koanf:"securityPolicy"`
+}
+
+// Validate checks the admin configuration
+func (c Config) Validate() error {
+	if c.Context != "" && (c.Context[0] != '/' || c.Context == "/") {
+		return fmt.Errorf("admin context must begin with '/' and not be the root, got: %s", c.Context)
+	}
+	return nil
+}
+
+// Service collects the admin endpoints contributed by the runtime's subsystems
+type Service struct {
+	mux    *http.ServeMux
+	logger *slog.Logger
+}
+
+// NewService creates an admin service without any endpoints
+func NewService() *Service {
+	s := &Service{mux: http.NewServeMux()}
+	s.logger = loggerfactory.GetLogger(componentName, s)
+	return s
+}
+
+func (s *Service) UpdateLogger() {
+	s.logger = loggerfactory.GetLogger(componentName, s)
+}
+
+// HandleFunc registers an admin endpoint. Patterns are relative to the admin
+// context, e.g. "GET /routes".
+func (s *Service) HandleFunc(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// ServeHTTP dispatches a request relative to the admin context
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Mount attaches the admin API to the main listener, protected by the configured
+// security policy. CORS is never applied to the admin API.
+func (s *Service) Mount(registrar ports.HTTPHandlerRegistrar, config Config, deploymentConfig map[string]interface{}) error {
+	context := strings.TrimSuffix(config.Context, "/")
+	if context == "" {
+		context = DefaultContext
+	}
+	handler, err := middleware.Wrap(http.StripPrefix(context, s), deploymentConfig, middleware.Options{
+		CORS:           "false",
+		SecurityPolicy: config.SecurityPolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot mount admin API: %w", err)
+	}
+	if config.SecurityPolicy == "" {
+		s.logger.Warn("admin API is not protected by a security policy")
+	}
+	if err := registrar.RegisterHandler(context+"/", handler); err != nil {
+		return err
+	}
+	s.logger.Info("admin API mounted", slog.String("context", context))
+	return nil
+}
+
+// WriteJSON writes body as a JSON response with the given status code
+func WriteJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// WriteError writes a JSON error response
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, map[string]string{"error": message})
+}
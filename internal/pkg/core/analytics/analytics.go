@@ -0,0 +1,345 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package analytics aggregates the traffic of each API over a rolling window:
+// request counts, error rates, latency percentiles and payload sizes. The
+// statistics are served by the admin API and can be published to an external
+// analytics endpoint on an interval.
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+)
+
+const (
+	// DefaultWindow is the rolling window of the statistics
+	DefaultWindow = time.Minute
+	// maxSamples bounds the memory used by the window of a busy API
+	maxSamples = 10000
+)
+
+// Config holds the [analytics] section of deployment.toml
+type Config struct {
+	Enabled bool   `koanf:"enabled"`
+	Window  string `koanf:"window"`
+	// PublishURL receives a report of every API on each publish interval,
+	// delivered with the signing and retries of [webhooks]
+	PublishURL      string `koanf:"publishUrl"`
+	PublishInterval string `koanf:"publishInterval"`
+}
+
+// Validate reports configuration errors in the [analytics] section
+func (c Config) Validate() error {
+	if _, err := c.WindowDuration(); err != nil {
+		return err
+	}
+	if _, err := c.Interval(); err != nil {
+		return err
+	}
+	if c.PublishURL != "" {
+		target, err := url.Parse(c.PublishURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("analytics: publishUrl must be an http or https URL, got '%s'", c.PublishURL)
+		}
+	}
+	return nil
+}
+
+// WindowDuration returns the rolling window, DefaultWindow unless configured
+func (c Config) WindowDuration() (time.Duration, error) {
+	return parseDuration("window", c.Window, DefaultWindow)
+}
+
+// Interval returns how often reports are published, the window unless
+// configured
+func (c Config) Interval() (time.Duration, error) {
+	window, err := c.WindowDuration()
+	if err != nil {
+		return 0, err
+	}
+	return parseDuration("publishInterval", c.PublishInterval, window)
+}
+
+func parseDuration(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("analytics: %s must be a positive duration, got '%s'", name, value)
+	}
+	return duration, nil
+}
+
+// Sample is one completed request
+type Sample struct {
+	Latency       time.Duration
+	Status        int
+	RequestBytes  int64
+	ResponseBytes int64
+	at            time.Time
+}
+
+// Latency holds latency percentiles in milliseconds
+type Latency struct {
+	P50 float64 `json:"p50Ms"`
+	P95 float64 `json:"p95Ms"`
+	P99 float64 `json:"p99Ms"`
+	Max float64 `json:"maxMs"`
+}
+
+// Sizes summarizes payload sizes in bytes
+type Sizes struct {
+	Total   int64   `json:"total"`
+	Average float64 `json:"average"`
+	Max     int64   `json:"max"`
+}
+
+// Stats is the traffic of one API over the window
+type Stats struct {
+	API          string  `json:"api"`
+	Requests     int     `json:"requests"`
+	Throughput   float64 `json:"requestsPerSecond"`
+	ClientErrors int     `json:"clientErrors"`
+	ServerErrors int     `json:"serverErrors"`
+	// ErrorRate is the fraction of requests answered with a 5xx status
+	ErrorRate     float64 `json:"errorRate"`
+	Latency       Latency `json:"latency"`
+	RequestBytes  Sizes   `json:"requestBytes"`
+	ResponseBytes Sizes   `json:"responseBytes"`
+}
+
+// Report is the body published to the analytics endpoint
+type Report struct {
+	Generated time.Time `json:"generated"`
+	Window    string    `json:"window"`
+	APIs      []Stats   `json:"apis"`
+}
+
+// Aggregator keeps the samples of each API within the window
+type Aggregator struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	apis map[string][]Sample
+}
+
+// NewAggregator creates an aggregator over a rolling window
+func NewAggregator(window time.Duration) *Aggregator {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Aggregator{window: window, apis: make(map[string][]Sample)}
+}
+
+// Record adds a completed request of api
+func (a *Aggregator) Record(api string, sample Sample, now time.Time) {
+	sample.at = now
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	samples := append(a.apis[api], sample)
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+	a.apis[api] = samples
+}
+
+// Stats computes the statistics of every API with traffic in the window,
+// sorted by API, and forgets older samples
+func (a *Aggregator) Stats(now time.Time) []Stats {
+	cutoff := now.Add(-a.window)
+	a.mu.Lock()
+	windows := make(map[string][]Sample, len(a.apis))
+	for api, samples := range a.apis {
+		first, _ := slices.BinarySearchFunc(samples, cutoff, func(s Sample, t time.Time) int { return s.at.Compare(t) })
+		if first == len(samples) {
+			delete(a.apis, api)
+			continue
+		}
+		a.apis[api] = samples[first:]
+		windows[api] = slices.Clone(samples[first:])
+	}
+	a.mu.Unlock()
+
+	stats := make([]Stats, 0, len(windows))
+	for api, samples := range windows {
+		stats = append(stats, summarize(api, samples, a.window))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].API < stats[j].API })
+	return stats
+}
+
+// Report computes the statistics for publishing
+func (a *Aggregator) Report(now time.Time) Report {
+	return Report{Generated: now.UTC(), Window: a.window.String(), APIs: a.Stats(now)}
+}
+
+func summarize(api string, samples []Sample, window time.Duration) Stats {
+	stats := Stats{API: api, Requests: len(samples), Throughput: float64(len(samples)) / window.Seconds()}
+	latencies := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		latencies[i] = sample.Latency
+		switch {
+		case sample.Status >= http.StatusInternalServerError:
+			stats.ServerErrors++
+		case sample.Status >= http.StatusBadRequest:
+			stats.ClientErrors++
+		}
+		stats.RequestBytes.Total += sample.RequestBytes
+		stats.RequestBytes.Max = max(stats.RequestBytes.Max, sample.RequestBytes)
+		stats.ResponseBytes.Total += sample.ResponseBytes
+		stats.ResponseBytes.Max = max(stats.ResponseBytes.Max, sample.ResponseBytes)
+	}
+	stats.ErrorRate = float64(stats.ServerErrors) / float64(len(samples))
+	stats.RequestBytes.Average = float64(stats.RequestBytes.Total) / float64(len(samples))
+	stats.ResponseBytes.Average = float64(stats.ResponseBytes.Total) / float64(len(samples))
+
+	slices.Sort(latencies)
+	percentile := func(p float64) float64 {
+		index := int(math.Ceil(p/100*float64(len(latencies)))) - 1
+		return milliseconds(latencies[max(0, min(index, len(latencies)-1))])
+	}
+	stats.Latency = Latency{P50: percentile(50), P95: percentile(95), P99: percentile(99), Max: milliseconds(latencies[len(latencies)-1])}
+	return stats
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Publish hands a report of aggregator to dispatcher for delivery to target
+// on every interval until ctx is done. Intervals without traffic are skipped.
+func Publish(ctx context.Context, aggregator *Aggregator, dispatcher *webhook.Dispatcher, target string,
+	interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			report := aggregator.Report(now)
+			if len(report.APIs) == 0 {
+				continue
+			}
+			body, err := json.Marshal(report)
+			if err != nil {
+				logger.Error("Cannot encode analytics report", slog.String("error", err.Error()))
+				continue
+			}
+			dispatcher.Enqueue(webhook.Request{URL: target, Source: webhook.SourceAnalytics, ContentType: "application/json", Payload: body})
+		}
+	}
+}
+
+// responseRecorder captures the status and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// bodyCounter counts the bytes of the request body read by the handler
+type bodyCounter struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (b *bodyCounter) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+// Middleware records every request to api with the default aggregator
+func Middleware(api string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &responseRecorder{ResponseWriter: w}
+		var body *bodyCounter
+		if r.Body != nil && r.Body != http.NoBody {
+			body = &bodyCounter{ReadCloser: r.Body}
+			r.Body = body
+		}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+		sample := Sample{Latency: time.Since(start), Status: recorder.status, ResponseBytes: recorder.bytes}
+		if sample.Status == 0 {
+			sample.Status = http.StatusOK
+		}
+		// A body the mediation did not read still counts at its declared size
+		if body != nil {
+			sample.RequestBytes = max(body.bytes, r.ContentLength)
+		}
+		Default().Record(api, sample, time.Now())
+	})
+}
+
+var (
+	defaultMu         sync.RWMutex
+	defaultAggregator = NewAggregator(DefaultWindow)
+)
+
+// SetDefault replaces the aggregator requests are recorded with
+func SetDefault(aggregator *Aggregator) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultAggregator = aggregator
+}
+
+// Default returns the aggregator requests are recorded with
+func Default() *Aggregator {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultAggregator
+}
@@ -0,0 +1,169 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "defaults", config: Config{Enabled: true}},
+		{name: "publishing", config: Config{Enabled: true, Window: "5m", PublishURL: "https://example.com/a", PublishInterval: "30s"}},
+		{name: "bad window", config: Config{Window: "soon"}, wantErr: "window"},
+		{name: "negative interval", config: Config{PublishInterval: "-1s"}, wantErr: "publishInterval"},
+		{name: "bad url", config: Config{PublishURL: "ftp://example.com"}, wantErr: "publishUrl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestConfigInterval(t *testing.T) {
+	interval, err := Config{Window: "5m"}.Interval()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, interval, "the interval defaults to the window")
+
+	interval, err = Config{Window: "5m", PublishInterval: "10s"}.Interval()
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, interval)
+}
+
+func TestAggregatorStats(t *testing.T) {
+	aggregator := NewAggregator(time.Minute)
+	now := time.Now()
+	for i := 1; i <= 100; i++ {
+		status := http.StatusOK
+		switch {
+		case i <= 5:
+			status = http.StatusBadGateway
+		case i <= 15:
+			status = http.StatusNotFound
+		}
+		aggregator.Record("orders:v1", Sample{Latency: time.Duration(i) * time.Millisecond, Status: status,
+			RequestBytes: int64(i), ResponseBytes: 10}, now.Add(-30*time.Second))
+	}
+	aggregator.Record("inventory:v1", Sample{Latency: time.Second, Status: http.StatusOK}, now.Add(-2*time.Minute))
+
+	stats := aggregator.Stats(now)
+	require.Len(t, stats, 1, "samples outside the window are dropped")
+	orders := stats[0]
+	assert.Equal(t, "orders:v1", orders.API)
+	assert.Equal(t, 100, orders.Requests)
+	assert.InDelta(t, 100.0/60, orders.Throughput, 0.001)
+	assert.Equal(t, 10, orders.ClientErrors)
+	assert.Equal(t, 5, orders.ServerErrors)
+	assert.InDelta(t, 0.05, orders.ErrorRate, 0.0001)
+	assert.Equal(t, Latency{P50: 50, P95: 95, P99: 99, Max: 100}, orders.Latency)
+	assert.Equal(t, Sizes{Total: 5050, Average: 50.5, Max: 100}, orders.RequestBytes)
+	assert.Equal(t, Sizes{Total: 1000, Average: 10, Max: 10}, orders.ResponseBytes)
+
+	assert.Empty(t, aggregator.Stats(now.Add(time.Minute)), "the window rolls forward")
+}
+
+func TestAggregatorBoundsSamples(t *testing.T) {
+	aggregator := NewAggregator(time.Hour)
+	now := time.Now()
+	for i := 0; i < maxSamples+50; i++ {
+		aggregator.Record("orders:v1", Sample{Status: http.StatusOK}, now)
+	}
+	stats := aggregator.Stats(now)
+	require.Len(t, stats, 1)
+	assert.Equal(t, maxSamples, stats[0].Requests)
+}
+
+func TestMiddleware(t *testing.T) {
+	previous := Default()
+	t.Cleanup(func() { SetDefault(previous) })
+	aggregator := NewAggregator(time.Minute)
+	SetDefault(aggregator)
+
+	handler := Middleware("orders:v1", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(bytes.ToUpper(body))
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("order-1")))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	assert.Equal(t, "ORDER-1", recorder.Body.String())
+	stats := aggregator.Stats(time.Now())
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].Requests)
+	assert.Equal(t, Sizes{Total: 7, Average: 3.5, Max: 7}, stats[0].RequestBytes)
+	assert.Equal(t, Sizes{Total: 7, Average: 3.5, Max: 7}, stats[0].ResponseBytes)
+}
+
+func TestPublish(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+	received := make(chan Report, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report Report
+		if err := json.NewDecoder(r.Body).Decode(&report); err == nil {
+			received <- report
+		}
+	}))
+	defer server.Close()
+
+	dispatcher, err := webhook.NewDispatcher(webhook.Config{}, nil)
+	require.NoError(t, err)
+	defer dispatcher.Close()
+
+	aggregator := NewAggregator(time.Minute)
+	aggregator.Record("orders:v1", Sample{Latency: time.Millisecond, Status: http.StatusOK}, time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Publish(ctx, aggregator, dispatcher, server.URL, 10*time.Millisecond, loggerfactory.GetLogger("analytics", nil))
+
+	select {
+	case report := <-received:
+		require.Len(t, report.APIs, 1)
+		assert.Equal(t, "orders:v1", report.APIs[0].API)
+		assert.Equal(t, "1m0s", report.Window)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no report was published")
+	}
+}
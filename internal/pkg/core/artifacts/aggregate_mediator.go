@@ -0,0 +1,191 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/beevik/etree"
+)
+
+const (
+	ErrorCodeAggregateFailed = "AGGREGATE_FAILED"
+	// AggregateCollectionProperty holds the Collection of the last clone or
+	// iterate mediator, until an aggregate mediator takes it
+	AggregateCollectionProperty = "AGGREGATE_COLLECTION"
+	// DefaultAggregateWrapper is the root element XML payloads are merged into
+	DefaultAggregateWrapper = "aggregate"
+)
+
+// Collection receives the messages of the branches of a clone or iterate
+// mediator as each branch finishes
+type Collection struct {
+	expected int
+	branches chan collectedBranch
+}
+
+type collectedBranch struct {
+	index   int
+	message *synctx.MsgContext
+	ok      bool
+}
+
+func newCollection(expected int) *Collection {
+	return &Collection{expected: expected, branches: make(chan collectedBranch, expected)}
+}
+
+// add records that a branch finished, and whether its sequence succeeded
+func (c *Collection) add(index int, message *synctx.MsgContext, ok bool) {
+	c.branches <- collectedBranch{index: index, message: message, ok: ok}
+}
+
+// AggregateMediator waits for the branches of the preceding clone or iterate
+// mediator and replaces the payload with theirs, merged into a JSON array, or
+// into a Wrapper element when every payload is XML. Branches that failed or
+// were dropped are left out, and so are those whose Correlation value differs
+// from the message's, when Correlation is set.
+//
+// Aggregation completes when every branch has finished, when Max branches are
+// collected, or when Timeout passes; fewer than Min collected branches fail
+// the flow. Payloads are merged in branch order.
+type AggregateMediator struct {
+	Correlation *expression.Program
+	Min         int
+	Max         int
+	Timeout     time.Duration
+	Wrapper     string
+	Position    Position
+}
+
+func (am AggregateMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	collection, ok := context.Properties[AggregateCollectionProperty].(*Collection)
+	if !ok {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeAggregateFailed, errors.New("aggregate: no clone or iterate mediator to aggregate"))
+	}
+	delete(context.Properties, AggregateCollectionProperty)
+	var correlation any
+	if am.Correlation != nil {
+		value, err := am.correlate(context)
+		if err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(context, ErrorCodeAggregateFailed, fmt.Errorf("aggregate: %w", err))
+		}
+		correlation = value
+	}
+
+	var timeout <-chan time.Time
+	if am.Timeout > 0 {
+		timer := time.NewTimer(am.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var collected []collectedBranch
+	timedOut := false
+	for received := 0; received < collection.expected && (am.Max <= 0 || len(collected) < am.Max) && !timedOut; {
+		select {
+		case branch := <-collection.branches:
+			received++
+			if !branch.ok || Dropped(branch.message) {
+				continue
+			}
+			if am.Correlation != nil {
+				// A branch whose correlation cannot be computed is not
+				// part of this aggregation
+				value, err := am.correlate(branch.message)
+				if err != nil || !reflect.DeepEqual(value, correlation) {
+					continue
+				}
+			}
+			collected = append(collected, branch)
+		case <-timeout:
+			timedOut = true
+		}
+	}
+	if len(collected) < am.Min {
+		status := http.StatusBadGateway
+		if timedOut {
+			status = http.StatusGatewayTimeout
+		}
+		context.Properties[HTTPStatusProperty] = status
+		return fail(context, ErrorCodeAggregateFailed, fmt.Errorf("aggregate: collected %d of %d messages, at least %d required", len(collected), collection.expected, am.Min))
+	}
+
+	slices.SortFunc(collected, func(a, b collectedBranch) int { return a.index - b.index })
+	payload, contentType, err := am.merge(collected)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeAggregateFailed, fmt.Errorf("aggregate: %w", err))
+	}
+	setPayload(context, payload, contentType)
+	return true, nil
+}
+
+func (am AggregateMediator) correlate(context *synctx.MsgContext) (any, error) {
+	env, err := expressionEnv(context)
+	if err != nil {
+		return nil, err
+	}
+	return am.Correlation.Run(env)
+}
+
+// merge combines the payloads into a wrapper element when all of them are
+// XML, and into a JSON array otherwise
+func (am AggregateMediator) merge(collected []collectedBranch) ([]byte, string, error) {
+	values := make([]any, len(collected))
+	allXML := len(collected) > 0
+	for i, branch := range collected {
+		value, err := ParseFragment(branch.message.Message.RawPayload, branch.message.Message.ContentType)
+		if err != nil {
+			return nil, "", fmt.Errorf("branch %d: %w", branch.index, err)
+		}
+		_, isXML := value.(*etree.Element)
+		allXML = allXML && isXML
+		values[i] = value
+	}
+	if allXML {
+		wrapper := am.Wrapper
+		if wrapper == "" {
+			wrapper = DefaultAggregateWrapper
+		}
+		document := etree.NewDocument()
+		root := document.CreateElement(wrapper)
+		for _, value := range values {
+			root.AddChild(value.(*etree.Element))
+		}
+		payload, err := document.WriteToBytes()
+		return payload, payloadContentTypes[PayloadMediaTypeXML], err
+	}
+	for i, value := range values {
+		if element, ok := value.(*etree.Element); ok {
+			values[i] = writeElement(element)
+		}
+	}
+	payload, err := json.Marshal(values)
+	return payload, payloadContentTypes[PayloadMediaTypeJSON], err
+}
@@ -0,0 +1,135 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockMediator holds its branch until released
+type blockMediator struct {
+	release chan struct{}
+}
+
+func (bm blockMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	<-bm.release
+	return true, nil
+}
+
+func staticTarget(payload, contentType string) CloneTarget {
+	return CloneTarget{Sequence: &Sequence{MediatorList: []Mediator{
+		StaticResponseMediator{Status: http.StatusOK, ContentType: contentType, Payload: payload},
+	}}}
+}
+
+func TestAggregateMediator_Clone(t *testing.T) {
+	clone := CloneMediator{Targets: []CloneTarget{
+		staticTarget(`{"orderId":7,"quote":1}`, "application/json"),
+		staticTarget(`{"orderId":8,"quote":2}`, "application/json"),
+		{Sequence: &Sequence{MediatorList: []Mediator{DropMediator{}}}},
+		staticTarget(`<quote orderId="7">3</quote>`, "application/xml"),
+		staticTarget(`{"orderId":7,"quote":4}`, "application/json"),
+	}}
+
+	msg := evalMessage(`{"orderId":7}`)
+	ok, err := clone.Execute(msg)
+	require.True(t, ok)
+	require.NoError(t, err)
+	ok, err = AggregateMediator{Correlation: propertyExpression(t, "payload.orderId")}.Execute(msg)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"orderId":7,"quote":1},{"orderId":7,"quote":4}]`, string(msg.Message.RawPayload),
+		"dropped and uncorrelated branches are left out")
+	assert.Equal(t, "application/json", msg.Headers["Content-Type"])
+	assert.NotContains(t, msg.Properties, AggregateCollectionProperty)
+
+	msg = evalMessage(`{"orderId":7}`)
+	_, _ = clone.Execute(msg)
+	ok, err = AggregateMediator{}.Execute(msg)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"orderId":7,"quote":1},{"orderId":8,"quote":2},"<quote orderId=\"7\">3</quote>",{"orderId":7,"quote":4}]`,
+		string(msg.Message.RawPayload), "XML payloads are kept as strings in a JSON array")
+}
+
+func TestAggregateMediator_Iterate(t *testing.T) {
+	target := &Sequence{MediatorList: []Mediator{appendMediator{value: ""}}}
+	msg := evalMessage(`<order><item>A</item><item>B</item></order>`)
+	msg.Message.ContentType = "application/xml"
+	ok, err := IterateMediator{Path: splitPath(t, "//item"), Sequence: target, Parallel: true}.Execute(msg)
+	require.True(t, ok)
+	require.NoError(t, err)
+	ok, err = AggregateMediator{Wrapper: "items"}.Execute(msg)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, `<items><item>A</item><item>B</item></items>`, string(msg.Message.RawPayload))
+}
+
+func TestAggregateMediator_Completion(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := CloneTarget{Sequence: &Sequence{MediatorList: []Mediator{blockMediator{release: release}}}}
+	fast := staticTarget(`{"quote":1}`, "application/json")
+
+	tests := []struct {
+		name       string
+		aggregate  AggregateMediator
+		wantOK     bool
+		wantBody   string
+		wantStatus int
+		wantErr    string
+	}{
+		{name: "timeout with enough messages", aggregate: AggregateMediator{Min: 1, Timeout: 20 * time.Millisecond}, wantOK: true, wantBody: `[{"quote":1}]`},
+		{name: "max reached", aggregate: AggregateMediator{Max: 1}, wantOK: true, wantBody: `[{"quote":1}]`},
+		{
+			name: "timeout with too few messages", aggregate: AggregateMediator{Min: 2, Timeout: 20 * time.Millisecond},
+			wantStatus: http.StatusGatewayTimeout, wantErr: "aggregate: collected 1 of 2 messages, at least 2 required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(`{}`)
+			ok, err := CloneMediator{Targets: []CloneTarget{slow, fast}, ContinueParent: true}.Execute(msg)
+			require.True(t, ok, "the parent does not wait for the slow target")
+			require.NoError(t, err)
+			ok, err = tt.aggregate.Execute(msg)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Equal(t, tt.wantStatus, msg.Properties[HTTPStatusProperty])
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.wantBody, string(msg.Message.RawPayload))
+		})
+	}
+
+	msg := evalMessage(`{}`)
+	ok, err := AggregateMediator{}.Execute(msg)
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "aggregate: no clone or iterate mediator to aggregate")
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+}
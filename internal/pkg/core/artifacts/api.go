@@ -20,14 +20,44 @@
 package artifacts
 
 import (
+	"strings"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
 	"github.com/apache/synapse-go/internal/pkg/core/synctx"
 )
 
 type Resource struct {
+	// Type is empty for resources mediated by their sequences. Echo and
+	// static resources are given an in sequence that answers for them.
+	Type          string
 	Methods       []string
 	URITemplate   URITemplateInfo
 	InSequence    Sequence
 	FaultSequence Sequence
+	// OutSequence mediates the response once the in sequence completes, or
+	// a loopback mediator ends it; respond and drop mediators skip it
+	OutSequence Sequence
+	// DefaultFaultSequence names the deployed sequence that runs in place of
+	// an empty FaultSequence, set from the gateway's configuration
+	DefaultFaultSequence string
+	// Async is nil unless requests are answered with 202 Accepted and
+	// mediated in the background
+	Async *AsyncReply
+	// StatusMappings are the API's, applied to backend responses of calls
+	// whose endpoint has no matching mapping
+	StatusMappings StatusMappings
+	// Timeout is the latency budget of a request, the resource's own or else
+	// the API's; 0 leaves it to the caller's X-Request-Timeout-Ms
+	Timeout time.Duration
+}
+
+// AsyncReply keeps the result of a background mediation retrievable for TTL
+type AsyncReply struct {
+	TTL time.Duration
+	// Callbacks are the URL prefixes clients may have the result posted to;
+	// callback URLs are refused when empty
+	Callbacks []string
 }
 
 type URITemplateInfo struct {
@@ -42,14 +72,139 @@ type API struct {
 	Name        string
 	Version     string
 	VersionType string
-	Resources   []Resource
-	Position    Position
+	// IsDefaultVersion routes requests without a version segment to this version
+	IsDefaultVersion bool
+	Hostname         string // optional virtual host the API is bound to
+	// CORS overrides the global CORS setting when set to "true" or "false"
+	CORS           string
+	SecurityPolicy string
+	// Authorization names an [[authorization.service]] consulted for every request
+	Authorization string
+	// CSRF marks a browser-facing API whose unsafe requests need a CSRF token
+	CSRF bool
+	// Deprecation is nil unless the API version is deprecated
+	Deprecation *Deprecation
+	// SLO is nil unless the API declares a service level objective
+	SLO *SLO
+	// Mirror is nil unless requests to the API are copied to a shadow endpoint
+	Mirror *Mirror
+	// Overflow is nil unless oversized requests bypass the resources
+	Overflow *Overflow
+	// ErrorTemplate overrides the problem+json format of error responses
+	ErrorTemplate *problem.Template
+	// StatusMappings translate backend statuses for every resource
+	StatusMappings StatusMappings
+	// Timeout is the latency budget of requests to resources without their own
+	Timeout time.Duration
+	// SelfTests are sample requests run against the API after startup
+	SelfTests []SelfTest
+	Resources []Resource
+	Position  Position
+}
+
+// Key identifies the API in the config context. Versions of the same API share a
+// name, so the version is part of the key.
+func (a API) Key() string {
+	if a.Version == "" {
+		return a.Name
+	}
+	return a.Name + ":v" + a.Version
+}
+
+// BasePath is the path the API is served at, with the version applied to the
+// context
+func (a API) BasePath() string {
+	basePath := a.Context
+
+	// Remove trailing slash from context if present
+	if len(basePath) > 1 && basePath[len(basePath)-1] == '/' {
+		basePath = basePath[:len(basePath)-1]
+	}
+
+	// Handle versioning based on versionType
+	if a.Version != "" && a.VersionType != "" {
+		switch a.VersionType {
+		case "url":
+			// For URL type, add version as a path segment
+			basePath = basePath + "/" + a.Version
+		case "context":
+			// For context type, replace {version} placeholder if it exists
+			basePath = strings.Replace(basePath, "{version}", a.Version, 1)
+		}
+	}
+	return basePath
 }
 
+// Deprecation describes a deprecated API version and when it will be removed
+type Deprecation struct {
+	Since  time.Time // zero when the deprecation date is not known
+	Sunset time.Time // zero when no removal date is planned
+	// Link points consumers to migration documentation
+	Link string
+	// WarningPercent is the share of responses carrying a 299 Warning header
+	WarningPercent int
+}
+
+// Overflow routes requests with a body larger than MaxSize bytes to a
+// sequence or straight to an endpoint instead of the API's resources, e.g. to
+// store the payload and forward a reference to it. Exactly one of Sequence
+// and Endpoint is set.
+type Overflow struct {
+	MaxSize  int64
+	Sequence string
+	Endpoint string
+}
+
+// SelfTest is a sample request and the status the API is expected to answer it with
+type SelfTest struct {
+	Name   string
+	Method string
+	// Path is relative to the base path of the API and may carry a query
+	Path           string
+	Headers        map[string]string
+	Payload        string
+	ExpectedStatus int
+	Timeout        time.Duration
+	// Critical self-tests must pass for the runtime to report ready
+	Critical bool
+}
+
+// SLO is the latency and error-rate objective of an API
+type SLO struct {
+	Window       time.Duration
+	MaxLatency   time.Duration
+	Percentile   float64
+	MaxErrorRate float64 // fraction of 5xx responses, between 0 and 1
+	MinRequests  int
+	// DegradedSequence replaces the resources while the objective is breached
+	DegradedSequence string
+}
+
+// Mediate runs the in sequence and then the out sequence, and the fault
+// sequence if either fails. It reports whether a response can be written; a
+// message dropped by any sequence is reported as successful and told apart
+// with Dropped.
 func (r *Resource) Mediate(context *synctx.MsgContext) bool {
 	isSuccessInSeq := r.InSequence.Execute(context)
+	if isSuccessInSeq && !Responding(context) && !Dropped(context) {
+		delete(context.Properties, LoopbackProperty)
+		isSuccessInSeq = r.OutSequence.Execute(context)
+	}
 	if !isSuccessInSeq {
-		isCompleteFaultSeq := r.FaultSequence.Execute(context)
+		faultSequence := r.FaultSequence
+		if len(faultSequence.MediatorList) == 0 && r.DefaultFaultSequence != "" {
+			// Resolved on each failure, since the sequence may be deployed
+			// after the API
+			if sequence, ok := SnapshotFromContext(context).Sequences[r.DefaultFaultSequence]; ok {
+				faultSequence = sequence
+			}
+		}
+		// Without a fault sequence to build a response, a mediator that chose
+		// an error status, or panicked, ends the flow with that status
+		if _, ok := context.Properties[HTTPStatusProperty].(int); ok && len(faultSequence.MediatorList) == 0 {
+			return false
+		}
+		isCompleteFaultSeq := faultSequence.Execute(context)
 		if !isCompleteFaultSeq {
 			return false
 		}
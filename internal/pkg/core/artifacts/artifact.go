@@ -20,9 +20,12 @@
 package artifacts
 
 import (
+	"maps"
 	"sync"
+	"sync/atomic"
 
 	"github.com/apache/synapse-go/internal/pkg/core/common"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
 )
 
 // Use the Position from common package
@@ -33,28 +36,74 @@ type EndpointProvider interface {
 	GetEndpoint(epName string) *Endpoint
 }
 
+// Snapshot is an immutable view of the deployed artifacts. Deployments replace
+// the snapshot as a whole, so a message that started on one snapshot keeps
+// seeing the same artifacts while others are deployed.
+type Snapshot struct {
+	APIs      map[string]API
+	Endpoints map[string]Endpoint
+	Sequences map[string]Sequence
+	Inbounds  map[string]Inbound
+}
+
+var emptySnapshot = &Snapshot{
+	APIs:      map[string]API{},
+	Endpoints: map[string]Endpoint{},
+	Sequences: map[string]Sequence{},
+	Inbounds:  map[string]Inbound{},
+}
+
+// ConfigContext is the registry of deployed artifacts. Reads are lock free;
+// writes copy the affected map and publish a new snapshot.
 type ConfigContext struct {
-	ApiMap       map[string]API
-	EndpointMap  map[string]Endpoint
-	SequenceMap  map[string]Sequence
-	InboundMap   map[string]Inbound
+	snapshot         atomic.Pointer[Snapshot]
+	mu               sync.Mutex // serializes writers
 	DeploymentConfig map[string]interface{}
 }
 
+// Snapshot returns the current artifacts
+func (c *ConfigContext) Snapshot() *Snapshot {
+	if snapshot := c.snapshot.Load(); snapshot != nil {
+		return snapshot
+	}
+	return emptySnapshot
+}
+
+// update publishes a copy of the current snapshot changed by apply
+func (c *ConfigContext) update(apply func(next *Snapshot)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := *c.Snapshot()
+	apply(&next)
+	c.snapshot.Store(&next)
+}
+
 func (c *ConfigContext) AddAPI(api API) {
-	c.ApiMap[api.Name] = api
+	c.update(func(next *Snapshot) {
+		next.APIs = maps.Clone(next.APIs)
+		next.APIs[api.Key()] = api
+	})
 }
 
 func (c *ConfigContext) AddEndpoint(endpoint Endpoint) {
-	c.EndpointMap[endpoint.Name] = endpoint
+	c.update(func(next *Snapshot) {
+		next.Endpoints = maps.Clone(next.Endpoints)
+		next.Endpoints[endpoint.Name] = endpoint
+	})
 }
 
 func (c *ConfigContext) AddSequence(sequence Sequence) {
-	c.SequenceMap[sequence.Name] = sequence
+	c.update(func(next *Snapshot) {
+		next.Sequences = maps.Clone(next.Sequences)
+		next.Sequences[sequence.Name] = sequence
+	})
 }
 
 func (c *ConfigContext) AddInbound(inbound Inbound) {
-	c.InboundMap[inbound.Name] = inbound
+	c.update(func(next *Snapshot) {
+		next.Inbounds = maps.Clone(next.Inbounds)
+		next.Inbounds[inbound.Name] = inbound
+	})
 }
 
 func (c *ConfigContext) AddDeploymentConfig(deploymentConfig map[string]interface{}) {
@@ -62,13 +111,25 @@ func (c *ConfigContext) AddDeploymentConfig(deploymentConfig map[string]interfac
 }
 
 func (c *ConfigContext) GetEndpoint(epName string) Endpoint {
-	endpoint, exists := c.EndpointMap[epName]
+	endpoint, exists := c.Snapshot().Endpoints[epName]
 	if !exists {
 		return Endpoint{}
 	}
 	return endpoint
 }
 
+// SnapshotProperty holds the snapshot a message is mediated against
+const SnapshotProperty = "ARTIFACT_SNAPSHOT"
+
+// SnapshotFromContext returns the snapshot the message started with, or the
+// current snapshot of the registry if none was attached
+func SnapshotFromContext(context *synctx.MsgContext) *Snapshot {
+	if snapshot, ok := context.Properties[SnapshotProperty].(*Snapshot); ok {
+		return snapshot
+	}
+	return GetConfigContext().Snapshot()
+}
+
 var instance *ConfigContext
 
 var once sync.Once
@@ -77,10 +138,6 @@ var once sync.Once
 func GetConfigContext() *ConfigContext {
 	once.Do(func() {
 		instance = &ConfigContext{
-			ApiMap:      make(map[string]API),
-			EndpointMap: make(map[string]Endpoint),
-			SequenceMap: make(map[string]Sequence),
-			InboundMap:  make(map[string]Inbound),
 			DeploymentConfig: make(map[string]interface{}),
 		}
 	})
@@ -0,0 +1,68 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigContext_SnapshotIsolation(t *testing.T) {
+	configContext := &ConfigContext{}
+	assert.Empty(t, configContext.Snapshot().Sequences)
+
+	configContext.AddSequence(Sequence{Name: "Orders", Position: Position{LineNo: 1}})
+	inFlight := configContext.Snapshot()
+
+	configContext.AddSequence(Sequence{Name: "Orders", Position: Position{LineNo: 2}})
+	configContext.AddAPI(API{Name: "OrdersAPI"})
+
+	assert.Equal(t, 1, inFlight.Sequences["Orders"].Position.LineNo, "earlier snapshots are not modified")
+	assert.Empty(t, inFlight.APIs)
+	current := configContext.Snapshot()
+	assert.Equal(t, 2, current.Sequences["Orders"].Position.LineNo)
+	assert.Contains(t, current.APIs, "OrdersAPI")
+
+	msgContext := synctx.CreateMsgContext()
+	msgContext.Properties[SnapshotProperty] = inFlight
+	assert.Same(t, inFlight, SnapshotFromContext(msgContext))
+}
+
+func TestConfigContext_ConcurrentDeployments(t *testing.T) {
+	configContext := &ConfigContext{}
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			configContext.AddSequence(Sequence{Name: fmt.Sprintf("Sequence%d", i)})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = len(configContext.Snapshot().Sequences)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, configContext.Snapshot().Sequences, 50, "no deployment is lost")
+}
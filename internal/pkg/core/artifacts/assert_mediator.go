@@ -0,0 +1,112 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const ErrorCodeAssertionFailed = "ASSERTION_FAILED"
+
+// AssertionsConfig holds the [assertions] section of deployment.toml, which
+// test configurations set so contract tests can check flows from within
+type AssertionsConfig struct {
+	Enabled bool `koanf:"enabled"`
+}
+
+var assertionsEnabled atomic.Bool
+
+// EnableAssertions turns assert mediators on or off. They are off unless the
+// configuration enables them, so flows carrying assertions run unchanged in
+// production.
+func EnableAssertions(enabled bool) {
+	assertionsEnabled.Store(enabled)
+}
+
+// AssertionsEnabled reports whether assert mediators check their expressions
+func AssertionsEnabled() bool {
+	return assertionsEnabled.Load()
+}
+
+// AssertMediator checks an expression over the live message while
+// assertions are enabled, and passes the message on untouched otherwise.
+// Without Equals the expression must yield true; with it, both must yield
+// equal values. A failed assertion fails the flow with 500 and an error
+// naming the assertion, where it is declared, and the value found.
+type AssertMediator struct {
+	Expression *expression.Program
+	Equals     *expression.Program // nil for a truth assertion
+	// Message describes what the assertion checks, e.g. "orders have an id"
+	Message  string
+	Position Position
+}
+
+func (am AssertMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	if !AssertionsEnabled() {
+		return true, nil
+	}
+	env, err := expressionEnv(context)
+	if err != nil {
+		return am.fail(context, err)
+	}
+	actual, err := am.Expression.Run(env)
+	if err != nil {
+		return am.fail(context, err)
+	}
+	if am.Equals == nil {
+		if !expression.Truthy(actual) {
+			return am.fail(context, fmt.Errorf("%s yielded %s, expected true", am.Expression, describeValue(actual)))
+		}
+		return true, nil
+	}
+	expected, err := am.Equals.Run(env)
+	if err != nil {
+		return am.fail(context, err)
+	}
+	if !reflect.DeepEqual(expression.Normalize(actual), expression.Normalize(expected)) {
+		return am.fail(context, fmt.Errorf("%s yielded %s, expected %s", am.Expression, describeValue(actual), describeValue(expected)))
+	}
+	return true, nil
+}
+
+func (am AssertMediator) fail(context *synctx.MsgContext, err error) (bool, error) {
+	context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+	message := "assertion failed at " + formatPosition(am.Position)
+	if am.Message != "" {
+		message += ": " + am.Message
+	}
+	return fail(context, ErrorCodeAssertionFailed, fmt.Errorf("%s: %w", message, err))
+}
+
+// describeValue renders a value in an assertion failure as JSON where it can
+func describeValue(value any) string {
+	value = expression.Normalize(value)
+	if data, err := json.Marshal(value); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
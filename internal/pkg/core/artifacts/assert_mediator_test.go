@@ -0,0 +1,90 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assertMediator(t *testing.T, source, equals, message string) AssertMediator {
+	program, err := expression.Compile(source, ExpressionVariables...)
+	require.NoError(t, err)
+	mediator := AssertMediator{Expression: program, Message: message,
+		Position: Position{FileName: "orders.xml", LineNo: 7, Hierarchy: "Orders->sequence->assert"}}
+	if equals != "" {
+		mediator.Equals, err = expression.Compile(equals, ExpressionVariables...)
+		require.NoError(t, err)
+	}
+	return mediator
+}
+
+func TestAssertMediator(t *testing.T) {
+	EnableAssertions(true)
+	t.Cleanup(func() { EnableAssertions(false) })
+
+	tests := []struct {
+		name     string
+		mediator AssertMediator
+		errMsg   string
+	}{
+		{"Truth holds", assertMediator(t, "payload.order.id == 7", "", ""), ""},
+		{"Equality holds", assertMediator(t, "payload.order.items", `["a1", "b2"]`, ""), ""},
+		{"Numbers compare by value", assertMediator(t, "len(payload.order.items)", "2", ""), ""},
+		{
+			"Truth fails",
+			assertMediator(t, "payload.order.status == 'shipped'", "", "orders are shipped"),
+			`assertion failed at orders.xml:7 (Orders->sequence->assert): orders are shipped: payload.order.status == 'shipped' yielded false, expected true`,
+		},
+		{
+			"Equality fails",
+			assertMediator(t, "payload.order.status", "'shipped'", ""),
+			`assertion failed at orders.xml:7 (Orders->sequence->assert): payload.order.status yielded "pending", expected "shipped"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := dispatchMessage(`{"order": {"id": 7, "status": "pending", "items": ["a1", "b2"]}}`)
+			ok, err := tt.mediator.Execute(msg)
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+				assert.True(t, ok)
+				return
+			}
+			assert.False(t, ok)
+			assert.EqualError(t, err, tt.errMsg)
+			assert.Equal(t, ErrorCodeAssertionFailed, msg.Properties[ErrorCodeProperty])
+			assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+		})
+	}
+}
+
+func TestAssertMediatorDisabled(t *testing.T) {
+	require.False(t, AssertionsEnabled())
+	msg := dispatchMessage(`{}`)
+	ok, err := assertMediator(t, "payload.missing == 1", "", "").Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotContains(t, msg.Properties, ErrorCodeProperty)
+}
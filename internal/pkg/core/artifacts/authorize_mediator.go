@@ -0,0 +1,215 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/tokenexchange"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+)
+
+const (
+	// ClaimsProperty holds the claims of the JWT verified by the authorize
+	// mediator, for later mediators and authorize mediators without a key
+	ClaimsProperty = "JWT_CLAIMS"
+
+	ErrorCodeAuthenticationFailed = "AUTHENTICATION_FAILED"
+	ErrorCodeAuthorizationDenied  = "AUTHORIZATION_DENIED"
+
+	EffectPermit = "permit"
+	EffectDeny   = "deny"
+)
+
+// Obligation is carried out when its rule decides the request. The value is a
+// template over the same data as rule conditions.
+type Obligation struct {
+	// Property or Header names what the obligation sets
+	Property string
+	Header   string
+	Value    *template.Template
+}
+
+// AuthorizationRule permits or denies the requests its condition holds for
+type AuthorizationRule struct {
+	Name   string
+	Effect string
+	// Condition yields true when the rule applies, e.g.
+	// .HasClaim "scope" "orders:write"
+	Condition   *template.Template
+	Obligations []Obligation
+	// Message explains a denial to the client
+	Message string
+}
+
+// AuthorizeMediator evaluates rules over the caller's JWT claims, the request
+// headers and the payload. The first rule that applies decides; requests no
+// rule applies to get the default effect. Denied requests are rejected with
+// 403 Forbidden. A rule whose condition cannot be evaluated, for instance
+// because it compares a payload value that is absent, denies the request.
+type AuthorizeMediator struct {
+	// Header carries the JWT, optionally prefixed with its scheme. The token is
+	// only read when VerificationKey, a secret store alias, is set; otherwise
+	// the claims verified by an earlier authorize mediator are used.
+	Header          string
+	VerificationKey string
+	Algorithms      []string
+	Issuer          string
+	Audience        string
+
+	Rules         []AuthorizationRule
+	DefaultEffect string
+	Position      Position
+}
+
+func (am AuthorizeMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	claims, _ := context.Properties[ClaimsProperty].(map[string]any)
+	if am.VerificationKey != "" {
+		var err error
+		if claims, err = am.verify(context); err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusUnauthorized
+			return fail(context, ErrorCodeAuthenticationFailed, fmt.Errorf("authorize: %w", err))
+		}
+	}
+
+	request := AuthorizationRequest{ValidationRequest: ValidationRequest{context: context}, claims: claims}
+	effect, message := am.DefaultEffect, "access denied"
+	for _, rule := range am.Rules {
+		var out bytes.Buffer
+		if err := rule.Condition.Execute(&out, request); err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusForbidden
+			return fail(context, ErrorCodeAuthorizationDenied, fmt.Errorf("authorize: rule %s could not be evaluated: %w", rule.Name, err))
+		}
+		if strings.TrimSpace(out.String()) != "true" {
+			continue
+		}
+		if err := fulfil(context, request, rule.Obligations); err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusForbidden
+			return fail(context, ErrorCodeAuthorizationDenied, fmt.Errorf("authorize: rule %s: %w", rule.Name, err))
+		}
+		effect = rule.Effect
+		if rule.Message != "" {
+			message = rule.Message
+		}
+		break
+	}
+	if effect != EffectPermit {
+		context.Properties[HTTPStatusProperty] = http.StatusForbidden
+		return fail(context, ErrorCodeAuthorizationDenied, errors.New(message))
+	}
+	return true, nil
+}
+
+// verify validates the caller's JWT and records its claims on the message
+func (am AuthorizeMediator) verify(context *synctx.MsgContext) (map[string]any, error) {
+	token := requestHeader(context, am.Header)
+	if scheme, value, found := strings.Cut(token, " "); found && !strings.ContainsAny(scheme, ".=") {
+		token = value
+	}
+	if token == "" {
+		return nil, fmt.Errorf("missing token in %s header", am.Header)
+	}
+	key, err := secrets.Default().PublicKey(am.VerificationKey)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := tokenexchange.ValidateJWT(tokenexchange.Config{
+		VerificationKey: key,
+		Algorithms:      am.Algorithms,
+		Issuer:          am.Issuer,
+		Audience:        am.Audience,
+	}, token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	context.Properties[ClaimsProperty] = identity.Claims
+	context.Properties["AUTHENTICATED_USER"] = identity.Subject
+	return identity.Claims, nil
+}
+
+// fulfil carries out the obligations of the deciding rule
+func fulfil(context *synctx.MsgContext, request AuthorizationRequest, obligations []Obligation) error {
+	for _, obligation := range obligations {
+		var out bytes.Buffer
+		if err := obligation.Value.Execute(&out, request); err != nil {
+			return fmt.Errorf("obligation could not be fulfilled: %w", err)
+		}
+		if obligation.Property != "" {
+			context.Properties[obligation.Property] = out.String()
+		} else {
+			context.Headers[obligation.Header] = out.String()
+		}
+	}
+	return nil
+}
+
+// AuthorizationRequest is the data of rule conditions and obligation values.
+// It offers the request accessors of precondition expressions as well as the
+// caller's claims and the payload.
+type AuthorizationRequest struct {
+	ValidationRequest
+	claims map[string]any
+}
+
+// Claim returns a claim of the caller's token. Nested claims are addressed
+// with dots, e.g. realm_access.roles.
+func (r AuthorizationRequest) Claim(name string) any {
+	var value any = r.claims
+	for _, key := range strings.Split(name, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// HasClaim reports whether a claim holds the value, either as its value, as
+// an element of a list claim or as a word of a space separated claim such as
+// scope
+func (r AuthorizationRequest) HasClaim(name, value string) bool {
+	switch claim := r.Claim(name).(type) {
+	case string:
+		for _, word := range strings.Fields(claim) {
+			if word == value {
+				return true
+			}
+		}
+	case []any:
+		for _, element := range claim {
+			if fmt.Sprint(element) == value {
+				return true
+			}
+		}
+	case nil:
+		return false
+	default:
+		return fmt.Sprint(claim) == value
+	}
+	return false
+}
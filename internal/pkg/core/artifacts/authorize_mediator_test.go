@@ -0,0 +1,149 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authorizationRule(t *testing.T, effect, condition string, obligations ...Obligation) AuthorizationRule {
+	t.Helper()
+	return AuthorizationRule{
+		Name:        condition,
+		Effect:      effect,
+		Condition:   template.Must(template.New("rule").Funcs(PreconditionFuncs).Parse("{{" + condition + "}}")),
+		Obligations: obligations,
+	}
+}
+
+func TestAuthorizeMediator_Rules(t *testing.T) {
+	mediator := AuthorizeMediator{
+		DefaultEffect: EffectDeny,
+		Rules: []AuthorizationRule{
+			authorizationRule(t, EffectDeny, `gt (.JSON "$.order.total") 1000.0`),
+			authorizationRule(t, EffectPermit, `.HasClaim "scope" "orders:write"`, Obligation{
+				Property: "tenant",
+				Value:    template.Must(template.New("value").Parse(`{{.Claim "org.tenant"}}`)),
+			}),
+			authorizationRule(t, EffectPermit, `and (.HasClaim "roles" "auditor") (eq (.Header "X-Mode") "read")`),
+		},
+	}
+	writer := map[string]any{"scope": "orders:read orders:write", "org": map[string]any{"tenant": "acme"}}
+	auditor := map[string]any{"roles": []any{"auditor"}}
+
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		header  string
+		payload string
+		allowed bool
+		tenant  any
+	}{
+		{name: "scope permits", claims: writer, payload: `{"order":{"total":10}}`, allowed: true, tenant: "acme"},
+		{name: "payload denies before scope", claims: writer, payload: `{"order":{"total":5000}}`},
+		{name: "role and header permit", claims: auditor, header: "read", payload: `{"order":{"total":1}}`, allowed: true},
+		{name: "no rule applies", claims: auditor, header: "write", payload: `{"order":{"total":1}}`},
+		{name: "no claims", payload: `{"order":{"total":1}}`},
+		{name: "missing payload field denies", claims: writer, payload: `{}`},
+		{name: "undecidable rule denies", claims: writer, payload: `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgContext := synctx.CreateMsgContext()
+			msgContext.Message.RawPayload = []byte(tt.payload)
+			msgContext.Properties["http_request_headers"] = map[string]string{"X-Mode": tt.header}
+			if tt.claims != nil {
+				msgContext.Properties[ClaimsProperty] = tt.claims
+			}
+
+			ok, err := mediator.Execute(msgContext)
+			assert.Equal(t, tt.allowed, ok)
+			if tt.allowed {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.tenant, msgContext.Properties["tenant"])
+				return
+			}
+			assert.Error(t, err)
+			assert.Equal(t, http.StatusForbidden, msgContext.Properties[HTTPStatusProperty])
+			assert.Equal(t, ErrorCodeAuthorizationDenied, msgContext.Properties[ErrorCodeProperty])
+		})
+	}
+}
+
+func TestAuthorizeMediator_VerifiesToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	store, err := secrets.NewStore(secrets.Config{Entries: []secrets.Entry{
+		{Alias: "idp", Value: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))},
+	}}, "")
+	require.NoError(t, err)
+	secrets.SetDefault(store)
+	defer secrets.SetDefault(&secrets.Store{})
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Subject: "alice",
+		Issuer:  "https://idp.example.com",
+		Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).Claims(map[string]any{"groups": []string{"admins"}}).Serialize()
+	require.NoError(t, err)
+
+	mediator := AuthorizeMediator{
+		Header:          "Authorization",
+		VerificationKey: "idp",
+		Issuer:          "https://idp.example.com",
+		DefaultEffect:   EffectDeny,
+		Rules: []AuthorizationRule{authorizationRule(t, EffectPermit, `.HasClaim "groups" "admins"`, Obligation{
+			Header: "X-Authorized-User",
+			Value:  template.Must(template.New("value").Parse(`{{.Claim "sub"}}`)),
+		})},
+	}
+
+	msgContext := synctx.CreateMsgContext()
+	msgContext.Properties["http_request_headers"] = map[string]string{"Authorization": "Bearer " + token}
+	ok, err := mediator.Execute(msgContext)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", msgContext.Properties["AUTHENTICATED_USER"])
+	assert.Equal(t, "alice", msgContext.Headers["X-Authorized-User"])
+
+	msgContext = synctx.CreateMsgContext()
+	ok, err = mediator.Execute(msgContext)
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "missing token in Authorization header")
+	assert.Equal(t, http.StatusUnauthorized, msgContext.Properties[HTTPStatusProperty])
+}
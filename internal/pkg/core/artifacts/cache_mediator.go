@@ -0,0 +1,156 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/cache"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	ErrorCodeCacheFailed = "CACHE_FAILED"
+	// CacheKeyProperty holds the key a finder missed, for the collector to
+	// store the response under
+	CacheKeyProperty = "CACHE_KEY"
+	// CacheTTLProperty holds how long the collector keeps the response
+	CacheTTLProperty = "CACHE_TTL"
+	// CacheHitProperty is true on messages answered from the cache
+	CacheHitProperty = "CACHE_HIT"
+	// DefaultCacheTTL is how long responses are cached when the finder does
+	// not say
+	DefaultCacheTTL = time.Minute
+)
+
+// uncachedHeaders are response headers that belong to one client
+var uncachedHeaders = []string{"Content-Length", "Set-Cookie"}
+
+// CacheMediator is either half of response caching. The finder hashes the
+// request method, path, payload and the named request Headers; on a hit it
+// answers with the cached response as the respond mediator does, and on a
+// miss it leaves the key for the collector. The collector, placed once the
+// response is built, stores successful responses for the finder's TTL.
+// Responses go to Store, or the default store of the [cache] section.
+type CacheMediator struct {
+	Collector bool
+	// Scope keeps the entries of different finders apart
+	Scope    string
+	TTL      time.Duration
+	Headers  []string
+	Store    *cache.Store
+	Position Position
+}
+
+func (cm CacheMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	store := cm.Store
+	if store == nil {
+		store = cache.Default()
+	}
+	if cm.Collector {
+		cm.collect(context, store)
+		return true, nil
+	}
+
+	payload, err := messagePayload(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeCacheFailed, fmt.Errorf("cache: cannot read payload: %w", err))
+	}
+	key := cm.key(context, payload)
+	entry, ok := store.Get(key)
+	if !ok {
+		context.Properties[CacheKeyProperty] = key
+		context.Properties[CacheTTLProperty] = cm.TTL
+		return true, nil
+	}
+	for name, value := range entry.Headers {
+		context.Headers[name] = value
+	}
+	setPayload(context, entry.Payload, entry.ContentType)
+	if entry.Status != 0 {
+		context.Properties[HTTPStatusProperty] = entry.Status
+	}
+	context.Properties[CacheHitProperty] = true
+	context.Properties[RespondProperty] = true
+	return true, nil
+}
+
+func (cm CacheMediator) key(context *synctx.MsgContext, payload []byte) string {
+	digest := sha256.New()
+	method, _ := context.Properties["HTTP_METHOD"].(string)
+	path, _ := context.Properties["REST_URL_POSTFIX"].(string)
+	fmt.Fprintf(digest, "%s\x00%s\x00%s\x00", cm.Scope, method, path)
+	for _, name := range cm.Headers {
+		fmt.Fprintf(digest, "%s\x00", requestHeader(context, name))
+	}
+	payloadDigest := sha256.Sum256(payload)
+	digest.Write(payloadDigest[:])
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// collect stores the response under the key the finder missed. Responses
+// with an error status are not cached, and failing to store one does not
+// fail the flow.
+func (cm CacheMediator) collect(context *synctx.MsgContext, store *cache.Store) {
+	key, ok := context.Properties[CacheKeyProperty].(string)
+	if !ok {
+		return
+	}
+	status, _ := context.Properties[HTTPStatusProperty].(int)
+	if status >= http.StatusMultipleChoices {
+		return
+	}
+	ttl, _ := context.Properties[CacheTTLProperty].(time.Duration)
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	headers := maps.Clone(context.Headers)
+	for name := range headers {
+		if slices.Contains(uncachedHeaders, http.CanonicalHeaderKey(name)) {
+			delete(headers, name)
+		}
+	}
+	contentType := context.Message.ContentType
+	if contentType == "" {
+		contentType = context.Headers["Content-Type"]
+	}
+	entry := cache.Entry{
+		Status:      status,
+		ContentType: contentType,
+		Headers:     headers,
+		Payload:     slices.Clone(context.Message.RawPayload),
+		Expires:     time.Now().Add(ttl),
+	}
+	if err := store.Put(key, entry); err != nil {
+		loggerfactory.GetLogger("mediation", nil).Warn("Response could not be cached",
+			slog.String("error", err.Error()),
+			slog.String("position", formatPosition(cm.Position)))
+	}
+	delete(context.Properties, CacheKeyProperty)
+}
@@ -0,0 +1,95 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/cache"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cacheRequest(method, path, payload, tenant string) *synctx.MsgContext {
+	msg := evalMessage(payload)
+	msg.Properties["HTTP_METHOD"] = method
+	msg.Properties["REST_URL_POSTFIX"] = path
+	msg.Properties["http_request_headers"] = map[string]string{"X-Tenant": tenant}
+	return msg
+}
+
+func TestCacheMediator_Execute(t *testing.T) {
+	store := cache.NewStore(10)
+	finder := CacheMediator{Scope: "orders", TTL: time.Minute, Headers: []string{"X-Tenant"}, Store: store}
+	collector := CacheMediator{Collector: true, Store: store}
+
+	msg := cacheRequest("GET", "/orders/7", "", "acme")
+	ok, err := finder.Execute(msg)
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.False(t, Responding(msg), "a miss continues the flow")
+	assert.Equal(t, time.Minute, msg.Properties[CacheTTLProperty])
+	key := msg.Properties[CacheKeyProperty]
+	assert.Len(t, key, 64)
+
+	// The backend answers and the collector stores the response
+	setPayload(msg, []byte(`{"id":7}`), "application/json")
+	msg.Headers["ETag"] = `"v1"`
+	msg.Headers["Set-Cookie"] = "session=abc"
+	msg.Properties[HTTPStatusProperty] = http.StatusOK
+	ok, err = collector.Execute(msg)
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.Len())
+
+	hit := cacheRequest("GET", "/orders/7", "", "acme")
+	ok, err = finder.Execute(hit)
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.True(t, Responding(hit), "a hit answers at once")
+	assert.Equal(t, true, hit.Properties[CacheHitProperty])
+	assert.Equal(t, `{"id":7}`, string(hit.Message.RawPayload))
+	assert.Equal(t, map[string]string{"Content-Type": "application/json", "ETag": `"v1"`}, hit.Headers)
+	assert.Equal(t, http.StatusOK, hit.Properties[HTTPStatusProperty])
+
+	for name, other := range map[string]*synctx.MsgContext{
+		"method":  cacheRequest("DELETE", "/orders/7", "", "acme"),
+		"path":    cacheRequest("GET", "/orders/8", "", "acme"),
+		"payload": cacheRequest("GET", "/orders/7", `{"x":1}`, "acme"),
+		"header":  cacheRequest("GET", "/orders/7", "", "globex"),
+	} {
+		_, _ = finder.Execute(other)
+		assert.False(t, Responding(other), "a different %s misses", name)
+	}
+	other := cacheRequest("GET", "/orders/7", "", "acme")
+	_, _ = CacheMediator{Scope: "stock", TTL: time.Minute, Headers: []string{"X-Tenant"}, Store: store}.Execute(other)
+	assert.False(t, Responding(other), "finders with another scope miss")
+
+	// Errors are not cached, and a collector without a finder does nothing
+	failed := cacheRequest("GET", "/orders/9", "", "acme")
+	_, _ = finder.Execute(failed)
+	failed.Properties[HTTPStatusProperty] = http.StatusBadGateway
+	_, _ = collector.Execute(failed)
+	_, _ = collector.Execute(cacheRequest("GET", "/orders/10", "", "acme"))
+	assert.Equal(t, 1, store.Len())
+}
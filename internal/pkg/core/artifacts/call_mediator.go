@@ -0,0 +1,87 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	ErrorCodeCallFailed       = "CALL_FAILED"
+	ErrorCodeEndpointNotFound = "ENDPOINT_NOT_FOUND"
+)
+
+// CallMediator sends the message to an endpoint and waits for its reply,
+// which replaces the payload, content type and headers of the message before
+// the next mediator runs. The response status is stored in HTTP_SC, mapped
+// by the status mappings of the endpoint or else of the API; only a failure
+// to get a response fails the flow, with a 502 status, or 504 when the
+// latency budget of the request ran out.
+type CallMediator struct {
+	// Endpoint names a deployed endpoint, resolved for every message
+	Endpoint string
+	// Inline is the endpoint of a call declared with a URL instead of an
+	// endpoint name
+	Inline   *Endpoint
+	Send     EndpointSender
+	Position Position
+}
+
+func (cm CallMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	var endpoint Endpoint
+	if cm.Inline != nil {
+		endpoint = *cm.Inline
+	} else {
+		deployed, ok := SnapshotFromContext(msgContext).Endpoints[cm.Endpoint]
+		if !ok {
+			msgContext.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(msgContext, ErrorCodeEndpointNotFound, fmt.Errorf("endpoint %s is not deployed", cm.Endpoint))
+		}
+		endpoint = deployed
+	}
+
+	// The request body of an API is read into the message so it is sent on
+	payload, err := messagePayload(msgContext)
+	if err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(msgContext, ErrorCodeCallFailed, fmt.Errorf("cannot read the request body: %w", err))
+	}
+	msgContext.Message.RawPayload = payload
+	delete(msgContext.Properties, HTTPStatusProperty)
+	if err := cm.Send(context.Background(), endpoint, msgContext); err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusBadGateway
+		if errors.Is(err, ErrBudgetExhausted) || errors.Is(err, context.DeadlineExceeded) {
+			msgContext.Properties[HTTPStatusProperty] = http.StatusGatewayTimeout
+		}
+		return fail(msgContext, ErrorCodeCallFailed, err)
+	}
+	// The endpoint's status mappings take precedence over the API's
+	if !endpoint.StatusMappings.Apply(msgContext) {
+		if mappings, ok := msgContext.Properties[StatusMappingsProperty].(StatusMappings); ok {
+			mappings.Apply(msgContext)
+		}
+	}
+	return true, nil
+}
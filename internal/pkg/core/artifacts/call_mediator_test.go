@@ -0,0 +1,172 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallMediator(t *testing.T) {
+	var sentTo string
+	var sentPayload string
+	send := func(ctx context.Context, endpoint Endpoint, msg *synctx.MsgContext) error {
+		sentTo = endpoint.Name
+		sentPayload = string(msg.Message.RawPayload)
+		msg.Message = synctx.Message{RawPayload: []byte(`{"stock":3}`), ContentType: "application/json"}
+		msg.Headers = map[string]string{"X-Backend": "inventory"}
+		msg.Properties[HTTPStatusProperty] = http.StatusOK
+		return nil
+	}
+	inventory := Endpoint{Name: "InventoryEP", Protocol: ProtocolHTTP}
+
+	t.Run("deployed endpoint", func(t *testing.T) {
+		msg := synctx.CreateMsgContext()
+		msg.Properties[SnapshotProperty] = &Snapshot{Endpoints: map[string]Endpoint{"InventoryEP": inventory}}
+		msg.Properties["http_request_body"] = io.NopCloser(bytes.NewReader([]byte(`{"item":"a"}`)))
+		msg.Properties[HTTPStatusProperty] = http.StatusCreated
+
+		ok, err := CallMediator{Endpoint: "InventoryEP", Send: send}.Execute(msg)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "InventoryEP", sentTo)
+		assert.Equal(t, `{"item":"a"}`, sentPayload, "the request body is sent")
+		assert.Equal(t, `{"stock":3}`, string(msg.Message.RawPayload))
+		assert.Equal(t, "inventory", msg.Headers["X-Backend"])
+		assert.Equal(t, http.StatusOK, msg.Properties[HTTPStatusProperty])
+	})
+
+	t.Run("inline endpoint", func(t *testing.T) {
+		inline := Endpoint{Name: "http://inventory/items", Protocol: ProtocolHTTP}
+		ok, err := CallMediator{Inline: &inline, Send: send}.Execute(synctx.CreateMsgContext())
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "http://inventory/items", sentTo)
+	})
+
+	t.Run("endpoint not deployed", func(t *testing.T) {
+		msg := synctx.CreateMsgContext()
+		ok, err := CallMediator{Endpoint: "MissingEP", Send: send}.Execute(msg)
+		assert.False(t, ok)
+		assert.EqualError(t, err, "endpoint MissingEP is not deployed")
+		assert.Equal(t, ErrorCodeEndpointNotFound, msg.Properties[ErrorCodeProperty])
+		assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+	})
+
+	t.Run("no response", func(t *testing.T) {
+		msg := synctx.CreateMsgContext()
+		failing := func(ctx context.Context, endpoint Endpoint, msg *synctx.MsgContext) error {
+			return errors.New("connection refused")
+		}
+		ok, err := CallMediator{Inline: &inventory, Send: failing}.Execute(msg)
+		assert.False(t, ok)
+		assert.EqualError(t, err, "connection refused")
+		assert.Equal(t, ErrorCodeCallFailed, msg.Properties[ErrorCodeProperty])
+		assert.Equal(t, http.StatusBadGateway, msg.Properties[HTTPStatusProperty])
+	})
+
+	t.Run("latency budget exhausted", func(t *testing.T) {
+		msg := synctx.CreateMsgContext()
+		late := func(ctx context.Context, endpoint Endpoint, msg *synctx.MsgContext) error {
+			return fmt.Errorf("endpoint %s: %w", endpoint.Name, ErrBudgetExhausted)
+		}
+		ok, err := CallMediator{Inline: &inventory, Send: late}.Execute(msg)
+		assert.False(t, ok)
+		assert.ErrorIs(t, err, ErrBudgetExhausted)
+		assert.Equal(t, http.StatusGatewayTimeout, msg.Properties[HTTPStatusProperty])
+	})
+}
+
+func TestDeadline(t *testing.T) {
+	msg := synctx.CreateMsgContext()
+	_, ok := Deadline(msg)
+	assert.False(t, ok)
+
+	now := time.Now()
+	SetDeadline(msg, now.Add(time.Minute))
+	SetDeadline(msg, now.Add(time.Second))
+	SetDeadline(msg, now.Add(time.Hour))
+	deadline, ok := Deadline(msg)
+	require.True(t, ok)
+	assert.Equal(t, now.Add(time.Second), deadline, "the earliest deadline applies")
+	assert.Equal(t, "1000", FormatRequestTimeout(deadline, now))
+	assert.Equal(t, "0", FormatRequestTimeout(deadline, now.Add(time.Minute)))
+
+	budget, ok := ParseRequestTimeout("250")
+	assert.True(t, ok)
+	assert.Equal(t, 250*time.Millisecond, budget)
+	for _, invalid := range []string{"", "0", "-5", "1s"} {
+		_, ok := ParseRequestTimeout(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestCallMediator_StatusMappings(t *testing.T) {
+	status := http.StatusNotFound
+	send := func(ctx context.Context, endpoint Endpoint, msg *synctx.MsgContext) error {
+		msg.Message = synctx.Message{RawPayload: []byte(`{"error":"no orders"}`), ContentType: "application/json"}
+		msg.Properties[HTTPStatusProperty] = status
+		return nil
+	}
+	notFound := StatusMapping{From: []StatusRange{{Min: 404, Max: 404}}, To: http.StatusOK, Payload: []byte(`[]`), ContentType: "application/json"}
+	unavailable := StatusMapping{From: []StatusRange{{Min: 502, Max: 504}}, To: http.StatusServiceUnavailable}
+	orders := Endpoint{Name: "OrdersEP", Protocol: ProtocolHTTP, StatusMappings: StatusMappings{notFound}}
+
+	t.Run("endpoint mapping", func(t *testing.T) {
+		msg := synctx.CreateMsgContext()
+		ok, err := CallMediator{Inline: &orders, Send: send}.Execute(msg)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusOK, msg.Properties[HTTPStatusProperty])
+		assert.Equal(t, http.StatusNotFound, msg.Properties[BackendStatusProperty])
+		assert.Equal(t, `[]`, string(msg.Message.RawPayload))
+	})
+
+	t.Run("api mapping", func(t *testing.T) {
+		status = http.StatusGatewayTimeout
+		msg := synctx.CreateMsgContext()
+		msg.Properties[StatusMappingsProperty] = StatusMappings{unavailable}
+		ok, err := CallMediator{Inline: &orders, Send: send}.Execute(msg)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusServiceUnavailable, msg.Properties[HTTPStatusProperty])
+		assert.Equal(t, `{"error":"no orders"}`, string(msg.Message.RawPayload), "the backend body is kept")
+	})
+
+	t.Run("no mapping", func(t *testing.T) {
+		status = http.StatusConflict
+		msg := synctx.CreateMsgContext()
+		msg.Properties[StatusMappingsProperty] = StatusMappings{unavailable}
+		_, err := CallMediator{Inline: &orders, Send: send}.Execute(msg)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, msg.Properties[HTTPStatusProperty])
+		assert.NotContains(t, msg.Properties, BackendStatusProperty)
+	})
+}
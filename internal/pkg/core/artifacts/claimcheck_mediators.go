@@ -0,0 +1,129 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/claimcheck"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	ErrorCodeClaimCheckFailed = "CLAIM_CHECK_FAILED"
+	// ClaimCheckTokenProperty holds the token of the last stored payload
+	ClaimCheckTokenProperty = "CLAIM_CHECK_TOKEN"
+	// DefaultClaimCheckToken reads the token from the reference payload the
+	// store payload mediator leaves
+	DefaultClaimCheckToken = "payload.claimCheck"
+)
+
+// StorePayloadMediator puts the payload in a claim check store and replaces
+// it with a JSON reference, {"claimCheck":"<token>"}. The token is also set
+// in Property. Payloads larger than MaxSize bytes are rejected; zero means no
+// limit.
+type StorePayloadMediator struct {
+	Store    claimcheck.Store
+	Property string
+	MaxSize  int64
+	Position Position
+}
+
+func (sm StorePayloadMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	payload, err := messagePayload(msgContext)
+	if err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(msgContext, ErrorCodeClaimCheckFailed, fmt.Errorf("store payload: cannot read payload: %w", err))
+	}
+	if sm.MaxSize > 0 && int64(len(payload)) > sm.MaxSize {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusRequestEntityTooLarge
+		return fail(msgContext, ErrorCodeClaimCheckFailed, fmt.Errorf("store payload: payload of %d bytes exceeds maxSize of %d bytes", len(payload), sm.MaxSize))
+	}
+	token, err := claimcheck.NewToken()
+	if err == nil {
+		err = sm.Store.Put(context.Background(), token, claimcheck.Blob{ContentType: msgContext.Message.ContentType, Data: payload})
+	}
+	if err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(msgContext, ErrorCodeClaimCheckFailed, fmt.Errorf("store payload: store unavailable: %w", err))
+	}
+	reference, _ := json.Marshal(map[string]string{"claimCheck": token})
+	msgContext.Properties[sm.Property] = token
+	setPayload(msgContext, reference, payloadContentTypes[PayloadMediaTypeJSON])
+	return true, nil
+}
+
+// RestorePayloadMediator replaces the payload with the one stored under the
+// token computed by Token, an expression over ExpressionVariables. With
+// Remove, the stored payload is deleted once restored.
+type RestorePayloadMediator struct {
+	Store    claimcheck.Store
+	Token    *expression.Program
+	Remove   bool
+	Position Position
+}
+
+func (rm RestorePayloadMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	env, err := expressionEnv(msgContext)
+	if err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(msgContext, ErrorCodeExpressionFailed, err)
+	}
+	result, err := rm.Token.Run(env)
+	if err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(msgContext, ErrorCodeExpressionFailed, err)
+	}
+	converted, _ := ConvertPropertyValue(result, PropertyTypeString)
+	token, _ := converted.(string)
+	if token == "" {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(msgContext, ErrorCodeClaimCheckFailed, errors.New("restore payload: the message has no claim check token"))
+	}
+
+	blob, err := rm.Store.Get(context.Background(), token)
+	if errors.Is(err, claimcheck.ErrNotFound) {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusNotFound
+		return fail(msgContext, ErrorCodeClaimCheckFailed, fmt.Errorf("restore payload: no payload stored for token %s", token))
+	}
+	if err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(msgContext, ErrorCodeClaimCheckFailed, fmt.Errorf("restore payload: store unavailable: %w", err))
+	}
+	setPayload(msgContext, blob.Data, blob.ContentType)
+	if rm.Remove {
+		// The payload is restored either way, so a failed delete only leaves
+		// it behind in the store
+		if err := rm.Store.Delete(context.Background(), token); err != nil {
+			loggerfactory.GetLogger("mediation", nil).Warn("Failed to delete restored payload",
+				slog.String("token", token),
+				slog.String("error", err.Error()),
+				slog.String("position", formatPosition(rm.Position)))
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,109 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/claimcheck"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unavailableStore is a claim check store that cannot be reached
+type unavailableStore struct{ claimcheck.MemoryStore }
+
+func (*unavailableStore) Put(ctx context.Context, token string, blob claimcheck.Blob) error {
+	return errors.New("connection refused")
+}
+
+func TestClaimCheckMediators_RoundTrip(t *testing.T) {
+	store := claimcheck.NewMemoryStore()
+	storePayload := StorePayloadMediator{Store: store, Property: ClaimCheckTokenProperty}
+	restore := RestorePayloadMediator{Store: store, Token: propertyExpression(t, DefaultClaimCheckToken), Remove: true}
+
+	msg := evalMessage(`<order><id>7</id></order>`)
+	msg.Message.ContentType = "application/xml"
+	ok, err := storePayload.Execute(msg)
+	require.True(t, ok)
+	require.NoError(t, err)
+	token, _ := msg.Properties[ClaimCheckTokenProperty].(string)
+	require.NotEmpty(t, token)
+	assert.JSONEq(t, `{"claimCheck":"`+token+`"}`, string(msg.Message.RawPayload))
+	assert.Equal(t, "application/json", msg.Message.ContentType)
+
+	ok, err = restore.Execute(msg)
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, `<order><id>7</id></order>`, string(msg.Message.RawPayload))
+	assert.Equal(t, "application/xml", msg.Message.ContentType)
+
+	// The payload was removed once restored
+	_, err = store.Get(context.Background(), token)
+	assert.ErrorIs(t, err, claimcheck.ErrNotFound)
+}
+
+func TestRestorePayloadMediator_Failures(t *testing.T) {
+	store := claimcheck.NewMemoryStore()
+	tests := []struct {
+		name       string
+		token      string
+		payload    string
+		wantStatus int
+		wantErr    string
+	}{
+		{"no token", DefaultClaimCheckToken, `{"id":1}`, http.StatusBadRequest, "restore payload: the message has no claim check token"},
+		{"unknown token", DefaultClaimCheckToken, `{"claimCheck":"abc"}`, http.StatusNotFound, "restore payload: no payload stored for token abc"},
+		{"token from a property", `properties.CLAIM`, `{}`, http.StatusBadRequest, "restore payload: the message has no claim check token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(tt.payload)
+			ok, err := RestorePayloadMediator{Store: store, Token: propertyExpression(t, tt.token)}.Execute(msg)
+			assert.False(t, ok)
+			assert.EqualError(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStatus, msg.Properties[HTTPStatusProperty])
+			assert.Equal(t, ErrorCodeClaimCheckFailed, msg.Properties[ErrorCodeProperty])
+		})
+	}
+}
+
+func TestStorePayloadMediator_StoreFailure(t *testing.T) {
+	msg := evalMessage(`{"id":1}`)
+	ok, err := StorePayloadMediator{Store: &unavailableStore{}, Property: ClaimCheckTokenProperty}.Execute(msg)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "store payload: store unavailable: connection refused")
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+	assert.JSONEq(t, `{"id":1}`, string(msg.Message.RawPayload), "the payload is left in place")
+}
+
+func TestStorePayloadMediator_MaxSize(t *testing.T) {
+	store := claimcheck.NewMemoryStore()
+	msg := evalMessage(`{"id":12345}`)
+	ok, err := StorePayloadMediator{Store: store, Property: ClaimCheckTokenProperty, MaxSize: 8}.Execute(msg)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "store payload: payload of 12 bytes exceeds maxSize of 8 bytes")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, 0, store.Len(), "nothing is stored")
+	assert.JSONEq(t, `{"id":12345}`, string(msg.Message.RawPayload), "the payload is left in place")
+}
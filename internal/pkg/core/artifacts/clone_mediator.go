@@ -0,0 +1,119 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	ErrorCodeCloneFailed = "CLONE_FAILED"
+	// CloneIDProperty holds the id of the clone mediator that made a copy
+	CloneIDProperty = "CLONE_ID"
+	// CloneIndexProperty holds the position of a copy's target, from 0
+	CloneIndexProperty = "CLONE_INDEX"
+	// CloneCountProperty holds the number of targets
+	CloneCountProperty = "CLONE_COUNT"
+	// DefaultCloneConcurrency is how many targets run at once when the
+	// mediator does not set a limit
+	DefaultCloneConcurrency = 8
+)
+
+// CloneTarget is a sequence a copy of the message is sent to: Sequence, or
+// the deployed sequence named SequenceName
+type CloneTarget struct {
+	Sequence     *Sequence
+	SequenceName string
+}
+
+// CloneMediator runs every target on its own copy of the message, each in a
+// goroutine, with at most Concurrency targets running at once. It waits for
+// all of them; the flow fails when any target fails, and otherwise continues
+// with the message as it was. With ContinueParent the flow continues at once
+// and failed targets are only left out by an aggregate mediator. Properties
+// and payloads set on the copies are not kept, other than by aggregating.
+type CloneMediator struct {
+	ID             string
+	Targets        []CloneTarget
+	Concurrency    int
+	ContinueParent bool
+	Position       Position
+}
+
+func (cm CloneMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	sequences := make([]*Sequence, len(cm.Targets))
+	for i, target := range cm.Targets {
+		if target.Sequence != nil {
+			sequences[i] = target.Sequence
+			continue
+		}
+		sequence, ok := SnapshotFromContext(context).Sequences[target.SequenceName]
+		if !ok {
+			context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(context, ErrorCodeSequenceNotFound, fmt.Errorf("clone: sequence %s is not deployed", target.SequenceName))
+		}
+		sequences[i] = &sequence
+	}
+
+	concurrency := cm.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultCloneConcurrency
+	}
+	slots := make(chan struct{}, concurrency)
+	messages := make([]*synctx.MsgContext, len(sequences))
+	results := make([]bool, len(sequences))
+	// Copies are made before any target runs, so every target starts from the
+	// message as it reached the mediator
+	for i := range sequences {
+		messages[i] = context.Clone()
+		if cm.ID != "" {
+			messages[i].Properties[CloneIDProperty] = cm.ID
+		}
+		messages[i].Properties[CloneIndexProperty] = i
+		messages[i].Properties[CloneCountProperty] = len(sequences)
+	}
+	collection := newCollection(len(sequences))
+	context.Properties[AggregateCollectionProperty] = collection
+	var wg sync.WaitGroup
+	for i, sequence := range sequences {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = sequence.Execute(messages[i])
+			collection.add(i, messages[i], results[i])
+		}()
+	}
+	if cm.ContinueParent {
+		return true, nil
+	}
+	wg.Wait()
+
+	if i, status, reason, failed := failedBranch(messages, results); failed {
+		context.Properties[HTTPStatusProperty] = status
+		return fail(context, ErrorCodeCloneFailed, fmt.Errorf("clone: target %d: %s", i, reason))
+	}
+	return true, nil
+}
@@ -0,0 +1,104 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordMediator records the copies it sees and how many run at once
+type recordMediator struct {
+	mu      *sync.Mutex
+	seen    map[int]string
+	running *atomic.Int32
+	peak    *atomic.Int32
+}
+
+func (rm recordMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	running := rm.running.Add(1)
+	defer rm.running.Add(-1)
+	for peak := rm.peak.Load(); running > peak && !rm.peak.CompareAndSwap(peak, running); peak = rm.peak.Load() {
+	}
+	time.Sleep(10 * time.Millisecond)
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.seen[context.Properties[CloneIndexProperty].(int)] = context.Properties[CloneIDProperty].(string) + ":" + string(context.Message.RawPayload)
+	context.Properties["touched"] = true
+	setPayload(context, []byte(`{}`), "")
+	return true, nil
+}
+
+func TestCloneMediator_Execute(t *testing.T) {
+	record := recordMediator{mu: &sync.Mutex{}, seen: map[int]string{}, running: &atomic.Int32{}, peak: &atomic.Int32{}}
+	target := CloneTarget{Sequence: &Sequence{MediatorList: []Mediator{record}}}
+	mediator := CloneMediator{ID: "orders", Targets: []CloneTarget{target, target, target, target, target}, Concurrency: 2}
+
+	msg := evalMessage(`{"id":1}`)
+	ok, err := mediator.Execute(msg)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Len(t, record.seen, 5)
+	for i := range 5 {
+		assert.Equal(t, `orders:{"id":1}`, record.seen[i], "target %d gets its own copy", i)
+	}
+	assert.LessOrEqual(t, record.peak.Load(), int32(2), "no more than concurrency targets run at once")
+	assert.Equal(t, `{"id":1}`, string(msg.Message.RawPayload), "the copies do not change the message")
+	assert.NotContains(t, msg.Properties, "touched")
+}
+
+func TestCloneMediator_Failures(t *testing.T) {
+	passing := CloneTarget{Sequence: &Sequence{}}
+	failing := CloneTarget{Sequence: &Sequence{MediatorList: []Mediator{
+		EvalMediator{Expression: propertyExpression(t, "payload.qty > 0"), Status: http.StatusUnprocessableEntity},
+	}}}
+	tests := []struct {
+		name       string
+		mediator   CloneMediator
+		wantStatus int
+		wantErr    string
+	}{
+		{
+			name:       "target fails",
+			mediator:   CloneMediator{Targets: []CloneTarget{passing, failing}},
+			wantStatus: http.StatusUnprocessableEntity, wantErr: "clone: target 1:",
+		},
+		{
+			name:       "unknown sequence",
+			mediator:   CloneMediator{Targets: []CloneTarget{passing, {SequenceName: "Audit"}}},
+			wantStatus: http.StatusInternalServerError, wantErr: "clone: sequence Audit is not deployed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(`{"qty":0}`)
+			ok, err := tt.mediator.Execute(msg)
+			assert.False(t, ok)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStatus, msg.Properties[HTTPStatusProperty])
+		})
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/datasource"
+	"github.com/apache/synapse-go/internal/pkg/core/dryrun"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const ErrorCodeDatabaseFailed = "DATABASE_FAILED"
+
+// DBParameter binds the result of an expression to a placeholder of a
+// statement, converted to Type when it is set. Lists and objects are bound
+// as JSON text.
+type DBParameter struct {
+	Expression *expression.Program
+	Type       string
+}
+
+// DBResult stores a column of the first row a query returns in a property,
+// converted to Type when it is set
+type DBResult struct {
+	Property string
+	Column   string
+	Type     string
+}
+
+// DBStatement is a parameterized SQL statement. Placeholders use the syntax
+// of the data source's driver, e.g. ? or $1.
+type DBStatement struct {
+	SQL        string
+	Parameters []DBParameter
+	Results    []DBResult
+}
+
+// DBLookupMediator runs queries against a data source and stores columns of
+// their first row in properties. Properties of a query returning no rows are
+// left unset.
+type DBLookupMediator struct {
+	DataSource string
+	Statements []DBStatement
+	Position   Position
+}
+
+func (dm DBLookupMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	source, err := datasource.Lookup(dm.DataSource)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeDatabaseFailed, fmt.Errorf("dblookup: %w", err))
+	}
+	for _, statement := range dm.Statements {
+		args, err := statement.bind(context)
+		if err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+			return fail(context, ErrorCodeDatabaseFailed, fmt.Errorf("dblookup: %w", err))
+		}
+		row, err := queryFirstRow(source, statement.SQL, args)
+		if err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusBadGateway
+			return fail(context, ErrorCodeDatabaseFailed, fmt.Errorf("dblookup %s: %w", dm.DataSource, err))
+		}
+		if row == nil {
+			continue
+		}
+		for _, result := range statement.Results {
+			value, ok := rowColumn(row, result.Column)
+			if !ok {
+				context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+				return fail(context, ErrorCodeDatabaseFailed, fmt.Errorf("dblookup: query returns no column %s", result.Column))
+			}
+			if result.Type != "" {
+				if value, err = ConvertPropertyValue(value, result.Type); err != nil {
+					context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+					return fail(context, ErrorCodeDatabaseFailed, fmt.Errorf("dblookup: column %s: %w", result.Column, err))
+				}
+			}
+			context.Properties[result.Property] = value
+		}
+	}
+	return true, nil
+}
+
+// DBReportMediator runs statements that change a data source, such as
+// inserts and updates, within one transaction when UseTransaction is set.
+// The number of rows they affect is stored in DBRowsAffectedProperty.
+type DBReportMediator struct {
+	DataSource     string
+	Statements     []DBStatement
+	UseTransaction bool
+	Position       Position
+}
+
+// DBRowsAffectedProperty holds the number of rows a dbreport mediator's
+// statements affected
+const DBRowsAffectedProperty = "DB_ROWS_AFFECTED"
+
+func (dm DBReportMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	source, err := datasource.Lookup(dm.DataSource)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeDatabaseFailed, fmt.Errorf("dbreport: %w", err))
+	}
+	bound := make([][]any, len(dm.Statements))
+	for i, statement := range dm.Statements {
+		if bound[i], err = statement.bind(context); err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+			return fail(context, ErrorCodeDatabaseFailed, fmt.Errorf("dbreport: %w", err))
+		}
+	}
+	if recorder := dryrun.FromContext(context); recorder != nil {
+		for range dm.Statements {
+			recorder.Record(dryrun.Call{Mediator: "dbreport", Method: "SQL", URL: dm.DataSource})
+		}
+		return true, nil
+	}
+
+	ctx, cancel := source.Context()
+	defer cancel()
+	affected, err := dm.exec(ctx, source.DB, bound)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadGateway
+		return fail(context, ErrorCodeDatabaseFailed, fmt.Errorf("dbreport %s: %w", dm.DataSource, err))
+	}
+	context.Properties[DBRowsAffectedProperty] = int(affected)
+	return true, nil
+}
+
+// execer runs statements on a pool or within a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (dm DBReportMediator) exec(ctx context.Context, db *sql.DB, bound [][]any) (int64, error) {
+	var target execer = db
+	var tx *sql.Tx
+	if dm.UseTransaction {
+		var err error
+		if tx, err = db.BeginTx(ctx, nil); err != nil {
+			return 0, err
+		}
+		defer tx.Rollback()
+		target = tx
+	}
+	var affected int64
+	for i, statement := range dm.Statements {
+		result, err := target.ExecContext(ctx, statement.SQL, bound[i]...)
+		if err != nil {
+			return 0, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		// Drivers that cannot count rows report an error, not a count
+		if rows, err := result.RowsAffected(); err == nil {
+			affected += rows
+		}
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+	}
+	return affected, nil
+}
+
+// bind evaluates the parameters of a statement
+func (s DBStatement) bind(context *synctx.MsgContext) ([]any, error) {
+	if len(s.Parameters) == 0 {
+		return nil, nil
+	}
+	env, err := expressionEnv(context)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]any, len(s.Parameters))
+	for i, parameter := range s.Parameters {
+		value, err := parameter.Expression.Run(env)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %s: %w", i+1, parameter.Expression, err)
+		}
+		if parameter.Type != "" {
+			if value, err = ConvertPropertyValue(value, parameter.Type); err != nil {
+				return nil, fmt.Errorf("parameter %d: %w", i+1, err)
+			}
+		}
+		switch value.(type) {
+		case map[string]any, []any:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %d: %w", i+1, err)
+			}
+			value = string(encoded)
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+// queryFirstRow returns the columns of the first row of a query by name, nil
+// when there are no rows
+func queryFirstRow(source *datasource.DataSource, query string, args []any) (map[string]any, error) {
+	ctx, cancel := source.Context()
+	defer cancel()
+	rows, err := source.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+	row := make(map[string]any, len(columns))
+	for i, column := range columns {
+		switch value := values[i].(type) {
+		case []byte:
+			row[column] = string(value)
+		case int64:
+			row[column] = int(value)
+		case time.Time:
+			row[column] = value.Format(time.RFC3339Nano)
+		default:
+			row[column] = value
+		}
+	}
+	return row, nil
+}
+
+// rowColumn returns a column of a row. Databases differ in how they case
+// unquoted column names, so names match regardless of case.
+func rowColumn(row map[string]any, name string) (any, bool) {
+	if value, ok := row[name]; ok {
+		return value, true
+	}
+	for column, value := range row {
+		if strings.EqualFold(column, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,225 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/datasource"
+	"github.com/apache/synapse-go/internal/pkg/core/dryrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB is a database/sql driver answering queries with canned rows and
+// recording the statements it executes
+type fakeDB struct {
+	mu       sync.Mutex
+	columns  []string
+	rows     [][]driver.Value
+	execs    [][]driver.Value
+	failExec string
+	commits  int
+}
+
+var fakeDBs sync.Map
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	db, _ := fakeDBs.Load(name)
+	return &fakeConn{db: db.(*fakeDB)}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.commits++
+	return nil
+}
+func (c *fakeConn) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if s.query == s.db.failExec {
+		return nil, errors.New("constraint violated")
+	}
+	s.db.execs = append(s.db.execs, append([]driver.Value{s.query}, args...))
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.execs = append(s.db.execs, append([]driver.Value{s.query}, args...))
+	return &fakeRows{columns: s.db.columns, rows: s.db.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// registerFakeDB registers a data source backed by a fake database
+func registerFakeDB(t *testing.T, name string, db *fakeDB) {
+	fakeDBs.Store(name, db)
+	pool, err := sql.Open("fakedb", name)
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close() })
+	datasource.Register(&datasource.DataSource{Name: name, DB: pool, QueryTimeout: datasource.DefaultQueryTimeout})
+}
+
+func TestDBLookupMediator_Execute(t *testing.T) {
+	db := &fakeDB{
+		columns: []string{"NAME", "tier", "credit"},
+		rows:    [][]driver.Value{{[]byte("Acme"), int64(2), 1500.5}, {[]byte("Ignored"), int64(0), 0.0}},
+	}
+	registerFakeDB(t, "customers", db)
+	mediator := DBLookupMediator{DataSource: "customers", Statements: []DBStatement{{
+		SQL:        "SELECT name, tier, credit FROM customers WHERE id = ?",
+		Parameters: []DBParameter{{Expression: propertyExpression(t, "payload.id"), Type: PropertyTypeInteger}},
+		Results: []DBResult{
+			{Property: "customer.name", Column: "name"},
+			{Property: "customer.tier", Column: "tier"},
+			{Property: "customer.credit", Column: "credit", Type: PropertyTypeString},
+		},
+	}}}
+
+	msg := evalMessage(`{"id":"42"}`)
+	ok, err := mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Acme", msg.Properties["customer.name"])
+	assert.Equal(t, 2, msg.Properties["customer.tier"])
+	assert.Equal(t, "1500.5", msg.Properties["customer.credit"])
+	assert.Equal(t, []driver.Value{"SELECT name, tier, credit FROM customers WHERE id = ?", int64(42)}, db.execs[0])
+
+	// No rows leaves the properties unset
+	db.rows = nil
+	msg = evalMessage(`{"id":7}`)
+	ok, err = mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotContains(t, msg.Properties, "customer.name")
+
+	tests := []struct {
+		name       string
+		mediator   DBLookupMediator
+		payload    string
+		wantStatus int
+		wantErr    string
+	}{
+		{name: "unknown datasource", mediator: DBLookupMediator{DataSource: "missing"}, wantStatus: http.StatusInternalServerError, wantErr: "datasource missing is not configured"},
+		{name: "parameter type", mediator: mediator, payload: `{"id":"abc"}`, wantStatus: http.StatusBadRequest, wantErr: "parameter 1"},
+		{name: "missing column", mediator: DBLookupMediator{DataSource: "customers", Statements: []DBStatement{{
+			SQL: "SELECT name FROM customers", Results: []DBResult{{Property: "email", Column: "email"}},
+		}}}, wantStatus: http.StatusInternalServerError, wantErr: "query returns no column email"},
+	}
+	db.rows = [][]driver.Value{{[]byte("Acme"), int64(2), 1500.5}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(tt.payload)
+			ok, err := tt.mediator.Execute(msg)
+			assert.False(t, ok)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStatus, msg.Properties[HTTPStatusProperty])
+		})
+	}
+}
+
+func TestDBReportMediator_Execute(t *testing.T) {
+	db := &fakeDB{}
+	registerFakeDB(t, "orders", db)
+	mediator := DBReportMediator{DataSource: "orders", UseTransaction: true, Statements: []DBStatement{
+		{SQL: "INSERT INTO orders (id, items) VALUES (?, ?)", Parameters: []DBParameter{
+			{Expression: propertyExpression(t, "payload.id")},
+			{Expression: propertyExpression(t, "payload.items")},
+		}},
+		{SQL: "UPDATE stock SET reserved = reserved + 1"},
+	}}
+
+	msg := evalMessage(`{"id":"o-1","items":[{"sku":"a"}]}`)
+	ok, err := mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, msg.Properties[DBRowsAffectedProperty])
+	assert.Equal(t, [][]driver.Value{
+		{"INSERT INTO orders (id, items) VALUES (?, ?)", "o-1", `[{"sku":"a"}]`},
+		{"UPDATE stock SET reserved = reserved + 1"},
+	}, db.execs)
+	assert.Equal(t, 1, db.commits)
+
+	// A failed statement rolls the transaction back
+	db.execs, db.failExec = nil, "UPDATE stock SET reserved = reserved + 1"
+	msg = evalMessage(`{"id":"o-2","items":[]}`)
+	ok, err = mediator.Execute(msg)
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "statement 2: constraint violated")
+	assert.Equal(t, http.StatusBadGateway, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, 1, db.commits)
+
+	// Dry runs record the statements without running them
+	db.execs, db.failExec = nil, ""
+	msg = evalMessage(`{"id":"o-3","items":[]}`)
+	recorder := &dryrun.Recorder{}
+	dryrun.AddToContext(msg, recorder)
+	ok, err = mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, db.execs)
+	assert.Len(t, recorder.Calls(), 2)
+}
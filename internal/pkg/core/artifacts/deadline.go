@@ -0,0 +1,73 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// RequestTimeoutHeader carries the milliseconds the caller is still
+	// willing to wait for a response. It is read from requests to APIs and
+	// set on calls to HTTP backends, so a backend can shed work whose result
+	// would arrive too late.
+	RequestTimeoutHeader = "X-Request-Timeout-Ms"
+	// DeadlineProperty holds the time.Time by which the response is due
+	DeadlineProperty = "REQUEST_DEADLINE"
+)
+
+// ErrBudgetExhausted is returned for calls made after the deadline of the
+// request has passed
+var ErrBudgetExhausted = errors.New("latency budget exhausted")
+
+// SetDeadline bounds the time left for the message, keeping an earlier
+// deadline already set
+func SetDeadline(msg *synctx.MsgContext, deadline time.Time) {
+	if current, ok := Deadline(msg); ok && current.Before(deadline) {
+		return
+	}
+	msg.Properties[DeadlineProperty] = deadline
+}
+
+// Deadline returns the time by which the response to the message is due
+func Deadline(msg *synctx.MsgContext) (time.Time, bool) {
+	deadline, ok := msg.Properties[DeadlineProperty].(time.Time)
+	return deadline, ok
+}
+
+// ParseRequestTimeout reads the value of a RequestTimeoutHeader; values that
+// are not a positive number of milliseconds are ignored
+func ParseRequestTimeout(value string) (time.Duration, bool) {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// FormatRequestTimeout renders the time left until deadline as a
+// RequestTimeoutHeader value, rounded down to whole milliseconds
+func FormatRequestTimeout(deadline time.Time, now time.Time) string {
+	return strconv.FormatInt(max(deadline.Sub(now).Milliseconds(), 0), 10)
+}
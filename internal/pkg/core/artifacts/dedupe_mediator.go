@@ -0,0 +1,92 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/dedupe"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	ErrorCodeDuplicateMessage = "DUPLICATE_MESSAGE"
+	ErrorCodeDedupeFailed     = "DEDUPE_FAILED"
+	// DuplicateProperty is "true" on messages a marking dedupe mediator has
+	// seen before
+	DuplicateProperty = "DUPLICATE_MESSAGE"
+
+	// DuplicateReject stops the flow of a duplicate with a 409 status, or the
+	// fault sequence when there is one
+	DuplicateReject = "reject"
+	// DuplicateMark lets a duplicate continue with DuplicateProperty set
+	DuplicateMark = "mark"
+)
+
+// DedupeMediator suppresses messages whose key, computed by an expression such
+// as {{.Header "Message-Id"}}, was seen within the TTL. The key is recorded
+// when the message is first seen, so a redelivery after a failed flow is also
+// treated as a duplicate until the TTL passes. Messages with an empty key are
+// not deduplicated.
+type DedupeMediator struct {
+	Key *template.Template
+	TTL time.Duration
+	// Scope keeps the keys of different mediators apart in a shared store
+	Scope       string
+	Store       dedupe.Store
+	OnDuplicate string
+	Position    Position
+}
+
+func (dm DedupeMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	var out bytes.Buffer
+	if err := dm.Key.Execute(&out, ValidationRequest{context: msgContext}); err != nil {
+		return fail(msgContext, ErrorCodeDedupeFailed, fmt.Errorf("dedupe: key could not be computed: %w", err))
+	}
+	key := strings.TrimSpace(out.String())
+	if key == "" {
+		return true, nil
+	}
+
+	duplicate, err := dm.Store.Add(context.Background(), dm.Scope+"\x00"+key, dm.TTL)
+	if err != nil {
+		return fail(msgContext, ErrorCodeDedupeFailed, fmt.Errorf("dedupe: store unavailable: %w", err))
+	}
+	if !duplicate {
+		return true, nil
+	}
+	loggerfactory.GetLogger("mediation", nil).Info("Duplicate message detected",
+		slog.String("key", key),
+		slog.String("position", formatPosition(dm.Position)))
+	if dm.OnDuplicate == DuplicateMark {
+		msgContext.Properties[DuplicateProperty] = "true"
+		return true, nil
+	}
+	msgContext.Properties[HTTPStatusProperty] = http.StatusConflict
+	return fail(msgContext, ErrorCodeDuplicateMessage, fmt.Errorf("duplicate message %s", key))
+}
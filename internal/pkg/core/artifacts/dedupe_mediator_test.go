@@ -0,0 +1,91 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/dedupe"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore is a dedupe store that cannot be reached
+type failingStore struct{}
+
+func (failingStore) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func dedupeMessage(messageID string) *synctx.MsgContext {
+	msg := synctx.CreateMsgContext()
+	msg.Headers["Message-Id"] = messageID
+	return msg
+}
+
+func TestDedupeMediator_Execute(t *testing.T) {
+	key := template.Must(template.New("").Funcs(PreconditionFuncs).Parse(`{{.Header "Message-Id"}}`))
+	store := dedupe.NewMemoryStore()
+	reject := DedupeMediator{Key: key, TTL: time.Minute, Scope: "orders", Store: store, OnDuplicate: DuplicateReject}
+
+	ok, err := reject.Execute(dedupeMessage("m-1"))
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	duplicate := dedupeMessage("m-1")
+	ok, err = reject.Execute(duplicate)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "duplicate message m-1")
+	assert.Equal(t, http.StatusConflict, duplicate.Properties[HTTPStatusProperty])
+	assert.Equal(t, ErrorCodeDuplicateMessage, duplicate.Properties[ErrorCodeProperty])
+
+	// Messages without a key pass through every time
+	for range 2 {
+		ok, err = reject.Execute(dedupeMessage(""))
+		assert.True(t, ok)
+		assert.NoError(t, err)
+	}
+
+	// Scopes keep the keys of different mediators apart
+	mark := DedupeMediator{Key: key, TTL: time.Minute, Scope: "invoices", Store: store, OnDuplicate: DuplicateMark}
+	ok, _ = mark.Execute(dedupeMessage("m-1"))
+	assert.True(t, ok)
+	marked := dedupeMessage("m-1")
+	ok, err = mark.Execute(marked)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "true", marked.Properties[DuplicateProperty])
+}
+
+func TestDedupeMediator_StoreFailure(t *testing.T) {
+	key := template.Must(template.New("").Parse(`{{.Header "Message-Id"}}`))
+	mediator := DedupeMediator{Key: key, TTL: time.Minute, Store: failingStore{}, OnDuplicate: DuplicateReject}
+
+	msg := dedupeMessage("m-1")
+	ok, err := mediator.Execute(msg)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "dedupe: store unavailable: connection refused")
+	assert.Equal(t, ErrorCodeDedupeFailed, msg.Properties[ErrorCodeProperty])
+}
@@ -0,0 +1,90 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	ErrorCodeSequenceNotFound = "SEQUENCE_NOT_FOUND"
+	// DispatchedSequenceProperty names the sequence the last dispatch ran
+	DispatchedSequenceProperty = "DISPATCHED_SEQUENCE"
+	// dispatchDepthProperty counts nested dispatches of a message
+	dispatchDepthProperty = "dispatchDepth"
+	// maxDispatchDepth stops sequences that dispatch to themselves
+	maxDispatchDepth = 16
+)
+
+// DispatchMediator runs the deployed sequence whose name Key computes, e.g.
+// 'handler_' + payload.eventType, so new message types are handled by
+// deploying a sequence. Default runs when no sequence has the computed name;
+// without a default the flow fails with a 500 status. The mediator's result
+// is that of the sequence it ran.
+type DispatchMediator struct {
+	Key      *expression.Program
+	Default  string
+	Position Position
+}
+
+func (dm DispatchMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	env, err := expressionEnv(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeExpressionFailed, err)
+	}
+	key, err := dm.Key.Run(env)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeExpressionFailed, err)
+	}
+
+	sequences := SnapshotFromContext(context).Sequences
+	name := ""
+	if key != nil {
+		name = fmt.Sprint(expression.Normalize(key))
+	}
+	sequence, ok := sequences[name]
+	if !ok && dm.Default != "" {
+		name = dm.Default
+		sequence, ok = sequences[name]
+	}
+	if !ok {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		if dm.Default != "" {
+			return fail(context, ErrorCodeSequenceNotFound, fmt.Errorf("default sequence %s is not deployed", dm.Default))
+		}
+		return fail(context, ErrorCodeSequenceNotFound, fmt.Errorf("no sequence %q is deployed for dispatch key %s", name, dm.Key))
+	}
+
+	depth, _ := context.Properties[dispatchDepthProperty].(int)
+	if depth >= maxDispatchDepth {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeSequenceNotFound, fmt.Errorf("dispatch to %s exceeds %d nested dispatches", name, maxDispatchDepth))
+	}
+	context.Properties[dispatchDepthProperty] = depth + 1
+	defer func() { context.Properties[dispatchDepthProperty] = depth }()
+	context.Properties[DispatchedSequenceProperty] = name
+	return sequence.Execute(context), nil
+}
@@ -0,0 +1,95 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dispatchMessage(payload string, sequences ...Sequence) *synctx.MsgContext {
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte(payload)
+	snapshot := &Snapshot{Sequences: map[string]Sequence{}}
+	for _, sequence := range sequences {
+		snapshot.Sequences[sequence.Name] = sequence
+	}
+	msg.Properties[SnapshotProperty] = snapshot
+	return msg
+}
+
+func dispatchMediator(t *testing.T, key, fallback string) DispatchMediator {
+	program, err := expression.Compile(key, ExpressionVariables...)
+	require.NoError(t, err)
+	return DispatchMediator{Key: program, Default: fallback}
+}
+
+func TestDispatchMediator(t *testing.T) {
+	created := Sequence{Name: "handler_created", MediatorList: []Mediator{appendMediator{value: "|created"}}}
+	unknown := Sequence{Name: "UnknownEvent", MediatorList: []Mediator{appendMediator{value: "|unknown"}}}
+	tests := []struct {
+		name         string
+		payload      string
+		fallback     string
+		wantOK       bool
+		wantPayload  string
+		wantSequence any
+		wantErr      string
+	}{
+		{name: "computed key", payload: `{"eventType":"created"}`, wantOK: true, wantPayload: `{"eventType":"created"}|created`, wantSequence: "handler_created"},
+		{name: "default", payload: `{"eventType":"deleted"}`, fallback: "UnknownEvent", wantOK: true, wantPayload: `{"eventType":"deleted"}|unknown`, wantSequence: "UnknownEvent"},
+		{name: "missing key uses default", payload: `{}`, fallback: "UnknownEvent", wantOK: true, wantPayload: `{}|unknown`, wantSequence: "UnknownEvent"},
+		{name: "no sequence", payload: `{"eventType":"deleted"}`, wantErr: `no sequence "handler_deleted" is deployed for dispatch key 'handler_' + (payload.eventType ?? '')`},
+		{name: "default not deployed", payload: `{"eventType":"deleted"}`, fallback: "Missing", wantErr: "default sequence Missing is not deployed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := dispatchMessage(tt.payload, created, unknown)
+			ok, err := dispatchMediator(t, `'handler_' + (payload.eventType ?? '')`, tt.fallback).Execute(msg)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+				assert.Equal(t, ErrorCodeSequenceNotFound, msg.Properties[ErrorCodeProperty])
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPayload, string(msg.Message.RawPayload))
+			assert.Equal(t, tt.wantSequence, msg.Properties[DispatchedSequenceProperty])
+		})
+	}
+}
+
+func TestDispatchMediator_StopsRecursion(t *testing.T) {
+	loop := Sequence{Name: "Loop"}
+	msg := dispatchMessage(`{}`)
+	loop.MediatorList = []Mediator{dispatchMediator(t, `'Loop'`, "")}
+	msg.Properties[SnapshotProperty].(*Snapshot).Sequences["Loop"] = loop
+
+	ok, err := dispatchMediator(t, `'Loop'`, "").Execute(msg)
+	assert.False(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "dispatch to Loop exceeds 16 nested dispatches", msg.Properties[ErrorMessageProperty])
+}
@@ -0,0 +1,45 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import "github.com/apache/synapse-go/internal/pkg/core/synctx"
+
+// DropProperty is set by the drop mediator to end mediation
+const DropProperty = "DROP"
+
+// DropMediator silently ends mediation. The mediators after it, including
+// those of enclosing sequences, are not executed and the client receives 202
+// Accepted without a body.
+type DropMediator struct {
+	Position Position
+}
+
+func (dm DropMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	context.Properties[DropProperty] = true
+	return true, nil
+}
+
+// Dropped reports whether a drop mediator has ended mediation. Mediation of
+// a dropped message succeeds, so callers check Dropped before writing a
+// response.
+func Dropped(context *synctx.MsgContext) bool {
+	dropped, _ := context.Properties[DropProperty].(bool)
+	return dropped
+}
@@ -0,0 +1,56 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropMediator_Execute(t *testing.T) {
+	dropped := switchRegex(t, "true")
+	dropped.Sequence = Sequence{MediatorList: []Mediator{appendMediator{value: "dropped"}, DropMediator{}, appendMediator{value: "-case"}}}
+	tests := []struct {
+		name        string
+		payload     string
+		wantPayload string
+		wantDropped bool
+	}{
+		{name: "drops from a branch", payload: `{"spam":"true"}`, wantPayload: `{"spam":"true"}dropped`, wantDropped: true},
+		{name: "continues without drop", payload: `{"spam":"false"}`, wantPayload: `{"spam":"false"}-after`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := Resource{
+				InSequence: Sequence{MediatorList: []Mediator{
+					SwitchMediator{Source: propertyExpression(t, "payload.spam"), Cases: []SwitchCase{dropped}},
+					appendMediator{value: "-after"},
+				}},
+				FaultSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "-fault"}}},
+			}
+			context := evalMessage(tt.payload)
+			assert.True(t, resource.Mediate(context), "dropping is not a failure")
+			assert.Equal(t, tt.wantPayload, string(context.Message.RawPayload))
+			assert.Equal(t, tt.wantDropped, Dropped(context))
+			assert.False(t, Responding(context))
+		})
+	}
+}
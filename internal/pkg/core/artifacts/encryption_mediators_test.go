@@ -0,0 +1,77 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecryptMediators(t *testing.T) {
+	store, err := secrets.NewStore(secrets.Config{Entries: []secrets.Entry{
+		{Alias: "shared", Value: "base64:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="},
+	}}, "")
+	assert.NoError(t, err)
+	secrets.SetDefault(store)
+	defer secrets.SetDefault(&secrets.Store{})
+
+	testCases := []struct {
+		name        string
+		format      string
+		fields      []string
+		contentType string
+		payload     string
+	}{
+		{name: "JWE payload", format: "jwe", contentType: "application/json", payload: `{"ssn":"123-45-6789"}`},
+		{name: "AES-GCM JSON fields", format: "aes-gcm", fields: []string{"$.card.number", "$.card.expiry"}, contentType: "application/json", payload: `{"card":{"expiry":{"month":1,"year":2030},"number":"4111111111111111"},"id":7}`},
+		{name: "JWE XML fields", format: "jwe", fields: []string{"//number"}, contentType: "application/xml", payload: `<card><number>4111&amp;1111</number><id>7</id></card>`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgContext := synctx.CreateMsgContext()
+			msgContext.Message.RawPayload = []byte(tc.payload)
+			msgContext.Message.ContentType = tc.contentType
+
+			ok, err := EncryptMediator{Format: tc.format, KeyAlias: "shared", Fields: tc.fields}.Execute(msgContext)
+			assert.True(t, ok)
+			assert.NoError(t, err)
+			assert.NotContains(t, string(msgContext.Message.RawPayload), "4111")
+			assert.NotContains(t, string(msgContext.Message.RawPayload), "123-45-6789")
+
+			ok, err = DecryptMediator{Format: tc.format, KeyAlias: "shared", Fields: tc.fields, ContentType: tc.contentType}.Execute(msgContext)
+			assert.True(t, ok)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.payload, string(msgContext.Message.RawPayload))
+			assert.Equal(t, tc.contentType, msgContext.Message.ContentType)
+		})
+	}
+
+	// Undecryptable messages are sent to the fault sequence
+	msgContext := synctx.CreateMsgContext()
+	msgContext.Message.RawPayload = []byte("not-a-jwe")
+	ok, err := DecryptMediator{Format: "jwe", KeyAlias: "shared"}.Execute(msgContext)
+	assert.False(t, ok)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeDecryptionFailed, msgContext.Properties[ErrorCodeProperty])
+}
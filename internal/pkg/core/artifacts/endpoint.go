@@ -19,11 +19,117 @@
 
 package artifacts
 
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Protocols of the endpoints the runtime can send to without extensions
+const (
+	// ProtocolHTTP is the protocol of endpoints declared with an <http> block
+	ProtocolHTTP = "http"
+	// ProtocolFile is the protocol of endpoints declared with a <file> block
+	ProtocolFile = "file"
+	// ProtocolSMPP is the protocol of endpoints declared with an <smpp> block,
+	// which send SMS through an SMS centre
+	ProtocolSMPP = "smpp"
+	// ProtocolFCM is the protocol of endpoints declared with an <fcm> block,
+	// which send Firebase Cloud Messaging push notifications
+	ProtocolFCM = "fcm"
+	// ProtocolAPNs is the protocol of endpoints declared with an <apns> block,
+	// which send Apple push notifications
+	ProtocolAPNs = "apns"
+)
+
+// Endpoint is a backend messages are sent to. Protocol selects the outbound
+// sender and Options holds the attributes of the endpoint's protocol block,
+// e.g. the topic of a kafka endpoint.
 type Endpoint struct {
-	Name        string
+	Name     string
+	Protocol string
+	// EndpointUrl is set for http endpoints
 	EndpointUrl EndpointUrl
-	FileName    string
-	Position    Position
+	// Options are templates rendered for every message, so a value such as
+	// key="{{.Property "orderId"}}" can depend on the message being sent
+	Options map[string]*template.Template
+	// Mirror is nil unless a copy of the messages sent to the endpoint also
+	// goes to a shadow endpoint
+	Mirror *Mirror
+	// Hedge is nil unless slow idempotent calls are raced by a second request
+	Hedge *Hedge
+	// StatusMappings translate the statuses of responses to calls
+	StatusMappings StatusMappings
+	// Headers copy message properties into transport headers, e.g. Kafka
+	// record headers or AMQP properties, before each message is sent
+	Headers  []HeaderMapping
+	FileName string
+	Position Position
+}
+
+// Mirror shadows production traffic to a second endpoint, e.g. a new version
+// of a backend. Copies are sent in the background and their responses are
+// discarded, so the mirror cannot affect the caller.
+type Mirror struct {
+	Endpoint string
+	// Percent is the share of messages mirrored, above 0 and at most 100
+	Percent float64
+}
+
+// Hedge cuts the tail latency of an endpoint by sending a second request
+// when an idempotent call gets no response within the Percentile of the
+// endpoint's recent response times. Whichever response arrives first is
+// used and the other request is cancelled.
+type Hedge struct {
+	Percentile float64
+	// MinDelay is the delay until enough responses have been observed, and
+	// the shortest delay after that, so fast backends are not doubled up
+	MinDelay time.Duration
+}
+
+// HeaderMapping sets a transport header from a message property
+type HeaderMapping struct {
+	Header   string
+	Property string
+}
+
+// MapHeaders sets the mapped headers of the endpoint from the properties of
+// the message. Properties the message does not have leave their header unset.
+func (e Endpoint) MapHeaders(context *synctx.MsgContext) {
+	for _, mapping := range e.Headers {
+		value, ok := context.Properties[mapping.Property]
+		if !ok {
+			continue
+		}
+		header, err := ConvertPropertyValue(value, PropertyTypeString)
+		if err != nil {
+			continue
+		}
+		context.Headers[mapping.Header] = header.(string)
+	}
+}
+
+// HasOption reports whether the protocol block sets the option
+func (e Endpoint) HasOption(name string) bool {
+	_, ok := e.Options[name]
+	return ok
+}
+
+// Option renders an option of the protocol block for the message. Options
+// the block does not set are empty.
+func (e Endpoint) Option(name string, context *synctx.MsgContext) (string, error) {
+	option, ok := e.Options[name]
+	if !ok {
+		return "", nil
+	}
+	var out bytes.Buffer
+	if err := option.Execute(&out, ValidationRequest{context: context}); err != nil {
+		return "", fmt.Errorf("endpoint %s: cannot render %s: %w", e.Name, name, err)
+	}
+	return out.String(), nil
 }
 
 type EndpointUrl struct {
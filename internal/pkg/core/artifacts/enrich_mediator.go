@@ -0,0 +1,337 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/beevik/etree"
+)
+
+const ErrorCodeEnrichFailed = "ENRICH_FAILED"
+
+// Enrich source types
+const (
+	EnrichSourceBody       = "body"
+	EnrichSourceProperty   = "property"
+	EnrichSourceExpression = "expression"
+	EnrichSourceInline     = "inline"
+)
+
+// Enrich target types. Key targets a field of a JSON payload and child an
+// element of an XML payload.
+const (
+	EnrichTargetBody     = "body"
+	EnrichTargetProperty = "property"
+	EnrichTargetKey      = "key"
+	EnrichTargetChild    = "child"
+)
+
+// Enrich actions
+const (
+	EnrichActionReplace = "replace"
+	EnrichActionMerge   = "merge"
+)
+
+// EnrichSource is where the enrich mediator copies a fragment from
+type EnrichSource struct {
+	Type       string
+	Property   string
+	Expression *expression.Program
+	// Inline is JSON, XML or text, parsed for every message so merged
+	// fragments never share state
+	Inline string
+}
+
+// EnrichTarget is where the enrich mediator puts the fragment. Path holds the
+// keys of a key target or the element names of a child target below the root
+// element; an empty path is the root of the payload.
+type EnrichTarget struct {
+	Type     string
+	Property string
+	Path     []string
+	Action   string
+}
+
+// EnrichMediator copies a fragment of the message into another part of it.
+// Fragments are decoded JSON values, XML elements or text. Replacing puts the
+// fragment in place of the target; merging adds the keys of a JSON object to
+// the target object, appends to a JSON array or adds XML children after the
+// existing ones.
+type EnrichMediator struct {
+	Source   EnrichSource
+	Target   EnrichTarget
+	Position Position
+}
+
+func (em EnrichMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	fragment, err := em.source(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		code := ErrorCodeEnrichFailed
+		if em.Source.Type == EnrichSourceExpression {
+			code = ErrorCodeExpressionFailed
+		}
+		return fail(context, code, fmt.Errorf("enrich source: %w", err))
+	}
+	if err := em.apply(context, fragment); err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeEnrichFailed, fmt.Errorf("enrich target: %w", err))
+	}
+	return true, nil
+}
+
+func (em EnrichMediator) source(context *synctx.MsgContext) (any, error) {
+	switch em.Source.Type {
+	case EnrichSourceBody:
+		payload, err := messagePayload(context)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read payload: %w", err)
+		}
+		if len(bytes.TrimSpace(payload)) == 0 {
+			return nil, nil
+		}
+		return ParseFragment(payload, context.Message.ContentType)
+	case EnrichSourceProperty:
+		value, ok := context.Properties[em.Source.Property]
+		if !ok {
+			return nil, fmt.Errorf("property %s is not set", em.Source.Property)
+		}
+		return cloneFragment(value)
+	case EnrichSourceExpression:
+		env, err := expressionEnv(context)
+		if err != nil {
+			return nil, err
+		}
+		return em.Source.Expression.Run(env)
+	default:
+		return ParseFragment([]byte(em.Source.Inline), "")
+	}
+}
+
+func (em EnrichMediator) apply(context *synctx.MsgContext, fragment any) error {
+	switch em.Target.Type {
+	case EnrichTargetProperty:
+		if element, ok := fragment.(*etree.Element); ok {
+			fragment = writeElement(element)
+		}
+		context.Properties[em.Target.Property] = fragment
+		return nil
+	case EnrichTargetBody:
+		switch value := fragment.(type) {
+		case *etree.Element:
+			setPayload(context, []byte(writeElement(value)), payloadContentTypes[PayloadMediaTypeXML])
+		case string:
+			setPayload(context, []byte(value), "")
+		default:
+			payload, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			setPayload(context, payload, payloadContentTypes[PayloadMediaTypeJSON])
+		}
+		return nil
+	case EnrichTargetKey:
+		return em.applyKey(context, fragment)
+	default:
+		return em.applyChild(context, fragment)
+	}
+}
+
+// applyKey puts the fragment at the key path of a JSON payload, creating the
+// objects on the way
+func (em EnrichMediator) applyKey(context *synctx.MsgContext, fragment any) error {
+	payload, err := messagePayload(context)
+	if err != nil {
+		return fmt.Errorf("cannot read payload: %w", err)
+	}
+	var document any = map[string]any{}
+	if len(bytes.TrimSpace(payload)) > 0 {
+		if err := json.Unmarshal(payload, &document); err != nil {
+			return fmt.Errorf("payload is not JSON: %w", err)
+		}
+	}
+	if element, ok := fragment.(*etree.Element); ok {
+		fragment = writeElement(element)
+	}
+
+	if len(em.Target.Path) == 0 {
+		document = enrichValue(document, fragment, em.Target.Action)
+	} else {
+		parent, ok := document.(map[string]any)
+		if !ok {
+			return errors.New("payload is not a JSON object")
+		}
+		path := em.Target.Path
+		for i, key := range path[:len(path)-1] {
+			next, exists := parent[key]
+			if !exists || next == nil {
+				next = map[string]any{}
+				parent[key] = next
+			}
+			if parent, ok = next.(map[string]any); !ok {
+				return fmt.Errorf("%s is not a JSON object", strings.Join(path[:i+1], "."))
+			}
+		}
+		last := path[len(path)-1]
+		parent[last] = enrichValue(parent[last], fragment, em.Target.Action)
+	}
+	updated, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	setPayload(context, updated, "")
+	return nil
+}
+
+// enrichValue returns the JSON value at a target after the action
+func enrichValue(existing, fragment any, action string) any {
+	if action != EnrichActionMerge {
+		return fragment
+	}
+	switch current := existing.(type) {
+	case map[string]any:
+		if additions, ok := fragment.(map[string]any); ok {
+			for key, value := range additions {
+				current[key] = value
+			}
+			return current
+		}
+	case []any:
+		if additions, ok := fragment.([]any); ok {
+			return append(current, additions...)
+		}
+		return append(current, fragment)
+	}
+	return fragment
+}
+
+// applyChild puts the fragment into the element at the path of an XML
+// payload: as its only content when replacing, after its content when merging
+func (em EnrichMediator) applyChild(context *synctx.MsgContext, fragment any) error {
+	payload, err := messagePayload(context)
+	if err != nil {
+		return fmt.Errorf("cannot read payload: %w", err)
+	}
+	document := etree.NewDocument()
+	if err := document.ReadFromBytes(payload); err != nil {
+		return fmt.Errorf("payload is not XML: %w", err)
+	}
+	target := document.Root()
+	if target == nil {
+		return errors.New("XML payload has no root element")
+	}
+	for i, name := range em.Target.Path {
+		if target = target.SelectElement(name); target == nil {
+			return fmt.Errorf("payload has no element %s", strings.Join(em.Target.Path[:i+1], "/"))
+		}
+	}
+
+	// XML kept in a property is a string
+	if text, ok := fragment.(string); ok && strings.HasPrefix(strings.TrimSpace(text), "<") {
+		if fragment, err = ParseFragment([]byte(text), "application/xml"); err != nil {
+			return err
+		}
+	}
+	if em.Target.Action != EnrichActionMerge {
+		for _, child := range slices.Clone(target.Child) {
+			target.RemoveChild(child)
+		}
+	}
+	switch value := fragment.(type) {
+	case *etree.Element:
+		target.AddChild(value)
+	case nil:
+	default:
+		text, err := ConvertPropertyValue(value, PropertyTypeString)
+		if err != nil {
+			return err
+		}
+		target.CreateText(text.(string))
+	}
+	updated, err := document.WriteToBytes()
+	if err != nil {
+		return err
+	}
+	setPayload(context, updated, "")
+	return nil
+}
+
+// ParseFragment decodes an XML payload to its root element and a JSON payload
+// to its value. Anything else is text.
+func ParseFragment(payload []byte, contentType string) (any, error) {
+	contentType = strings.ToLower(contentType)
+	trimmed := bytes.TrimSpace(payload)
+	isXML := strings.Contains(contentType, "xml") || (contentType == "" && len(trimmed) > 0 && trimmed[0] == '<')
+	if isXML {
+		document := etree.NewDocument()
+		if err := document.ReadFromBytes(trimmed); err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		root := document.Root()
+		if root == nil {
+			return nil, errors.New("XML has no root element")
+		}
+		document.RemoveChild(root)
+		return root, nil
+	}
+	isJSON := strings.Contains(contentType, "json") || (contentType == "" && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['))
+	var value any
+	if err := json.Unmarshal(trimmed, &value); err != nil {
+		if isJSON {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return string(payload), nil
+	}
+	return value, nil
+}
+
+// cloneFragment copies property values that later changes to the fragment
+// would otherwise modify
+func cloneFragment(value any) (any, error) {
+	switch v := value.(type) {
+	case *etree.Element:
+		return v.Copy(), nil
+	case map[string]any, []any:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var copied any
+		err = json.Unmarshal(encoded, &copied)
+		return copied, err
+	}
+	return value, nil
+}
+
+func writeElement(element *etree.Element) string {
+	document := etree.NewDocument()
+	document.SetRoot(element.Copy())
+	text, _ := document.WriteToString()
+	return text
+}
@@ -0,0 +1,150 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrichMediator(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		contentType string
+		mediator    EnrichMediator
+		wantPayload string
+		wantType    string
+		property    any
+	}{
+		{name: "property into key", payload: `{"order":{"id":1}}`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceProperty, Property: "customer"},
+				Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"order", "customer"}, Action: EnrichActionReplace}},
+			wantPayload: `{"order":{"customer":{"name":"Ada"},"id":1}}`},
+		{name: "merge objects", payload: `{"order":{"id":1}}`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceInline, Inline: `{"status":"new"}`},
+				Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"order"}, Action: EnrichActionMerge}},
+			wantPayload: `{"order":{"id":1,"status":"new"}}`},
+		{name: "merge into array", payload: `{"items":[1]}`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceInline, Inline: `[2,3]`},
+				Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"items"}, Action: EnrichActionMerge}},
+			wantPayload: `{"items":[1,2,3]}`},
+		{name: "create missing objects", payload: ``,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceExpression, Expression: propertyExpression(t, `params.orderId`)},
+				Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"meta", "orderId"}, Action: EnrichActionReplace}},
+			wantPayload: `{"meta":{"orderId":"42"}}`},
+		{name: "body into property", payload: `{"a":[1]}`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceBody},
+				Target: EnrichTarget{Type: EnrichTargetProperty, Property: "original"}},
+			wantPayload: `{"a":[1]}`, property: map[string]any{"a": []any{1.0}}},
+		{name: "XML body into property", payload: `<?xml version="1.0"?><order id="1"/>`, contentType: "application/xml",
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceBody},
+				Target: EnrichTarget{Type: EnrichTargetProperty, Property: "original"}},
+			wantPayload: `<?xml version="1.0"?><order id="1"/>`, wantType: "application/xml", property: `<order id="1"/>`},
+		{name: "inline XML into child", payload: `<order><items><item>a</item></items></order>`, contentType: "application/xml",
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceInline, Inline: `<item>b</item>`},
+				Target: EnrichTarget{Type: EnrichTargetChild, Path: []string{"items"}, Action: EnrichActionMerge}},
+			wantPayload: `<order><items><item>a</item><item>b</item></items></order>`, wantType: "application/xml"},
+		{name: "replace child content", payload: `<order><status>new</status></order>`, contentType: "application/xml",
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceExpression, Expression: propertyExpression(t, `'shipped & paid'`)},
+				Target: EnrichTarget{Type: EnrichTargetChild, Path: []string{"status"}, Action: EnrichActionReplace}},
+			wantPayload: `<order><status>shipped &amp; paid</status></order>`, wantType: "application/xml"},
+		{name: "XML property into child", payload: `<order/>`, contentType: "application/xml",
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceProperty, Property: "xmlCustomer"},
+				Target: EnrichTarget{Type: EnrichTargetChild, Action: EnrichActionMerge}},
+			wantPayload: `<order><customer>Ada</customer></order>`, wantType: "application/xml"},
+		{name: "inline JSON as body", payload: `<order/>`, contentType: "application/xml",
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceInline, Inline: `{"ok":true}`},
+				Target: EnrichTarget{Type: EnrichTargetBody}},
+			wantPayload: `{"ok":true}`, wantType: "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(tt.payload)
+			msg.Message.ContentType = tt.contentType
+			msg.Properties["customer"] = map[string]any{"name": "Ada"}
+			msg.Properties["xmlCustomer"] = `<customer>Ada</customer>`
+			ok, err := tt.mediator.Execute(msg)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantPayload, string(msg.Message.RawPayload))
+			assert.Equal(t, tt.wantType, msg.Message.ContentType)
+			if tt.property != nil {
+				assert.Equal(t, tt.property, msg.Properties["original"])
+			}
+		})
+	}
+}
+
+func TestEnrichMediator_PropertyIsCopied(t *testing.T) {
+	msg := evalMessage(`{}`)
+	msg.Properties["defaults"] = map[string]any{"tags": []any{"a"}}
+	mediator := EnrichMediator{Source: EnrichSource{Type: EnrichSourceProperty, Property: "defaults"},
+		Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"settings"}, Action: EnrichActionReplace}}
+	ok, err := mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	merge := EnrichMediator{Source: EnrichSource{Type: EnrichSourceInline, Inline: `{"extra":1}`},
+		Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"settings"}, Action: EnrichActionMerge}}
+	_, err = merge.Execute(msg)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"tags": []any{"a"}}, msg.Properties["defaults"])
+	assert.JSONEq(t, `{"settings":{"tags":["a"],"extra":1}}`, string(msg.Message.RawPayload))
+}
+
+func TestEnrichMediator_Fails(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		mediator EnrichMediator
+		wantCode string
+		wantErr  string
+	}{
+		{name: "missing property", payload: `{}`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceProperty, Property: "missing"}, Target: EnrichTarget{Type: EnrichTargetBody}},
+			wantCode: ErrorCodeEnrichFailed, wantErr: "enrich source: property missing is not set"},
+		{name: "key of XML payload", payload: `<order/>`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceInline, Inline: `1`}, Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"a"}}},
+			wantCode: ErrorCodeEnrichFailed, wantErr: "enrich target: payload is not JSON"},
+		{name: "key below a value", payload: `{"a":1}`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceInline, Inline: `1`}, Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"a", "b"}}},
+			wantCode: ErrorCodeEnrichFailed, wantErr: "enrich target: a is not a JSON object"},
+		{name: "missing element", payload: `<order/>`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceInline, Inline: `1`}, Target: EnrichTarget{Type: EnrichTargetChild, Path: []string{"items", "item"}}},
+			wantCode: ErrorCodeEnrichFailed, wantErr: "enrich target: payload has no element items"},
+		{name: "failing expression", payload: `{"id":"x"}`,
+			mediator: EnrichMediator{Source: EnrichSource{Type: EnrichSourceExpression, Expression: propertyExpression(t, `payload.id - 1`)}, Target: EnrichTarget{Type: EnrichTargetBody}},
+			wantCode: ErrorCodeExpressionFailed, wantErr: "enrich source:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(tt.payload)
+			ok, err := tt.mediator.Execute(msg)
+			assert.False(t, ok)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantCode, msg.Properties[ErrorCodeProperty])
+			assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	ErrorCodeGuardFailed      = "EXPRESSION_GUARD_FAILED"
+	ErrorCodeExpressionFailed = "EXPRESSION_FAILED"
+)
+
+// ExpressionVariables are the message data eval expressions can refer to:
+// the JSON payload (or its text when it is not JSON), request headers keyed
+// by lower-case name, message properties, and path and query parameters
+var ExpressionVariables = []string{"payload", "headers", "properties", "params", "query"}
+
+// EvalMediator evaluates an expression over the message. With Property set,
+// the result is stored in that property. Otherwise the expression is a guard
+// and the flow fails with Status when it does not yield true.
+type EvalMediator struct {
+	Expression *expression.Program
+	Property   string
+	Status     int
+	// Message describes a failed guard in the error response
+	Message  string
+	Position Position
+}
+
+func (em EvalMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	env, err := expressionEnv(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeExpressionFailed, err)
+	}
+	result, err := em.Expression.Run(env)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeExpressionFailed, err)
+	}
+	if em.Property != "" {
+		context.Properties[em.Property] = result
+		return true, nil
+	}
+	if !expression.Truthy(result) {
+		context.Properties[HTTPStatusProperty] = em.Status
+		message := em.Message
+		if message == "" {
+			message = fmt.Sprintf("guard %s is not satisfied", em.Expression)
+		}
+		return fail(context, ErrorCodeGuardFailed, fmt.Errorf("%s", message))
+	}
+	return true, nil
+}
+
+// ExpressionEnv binds ExpressionVariables to the message, for expressions
+// evaluated outside mediators
+func ExpressionEnv(context *synctx.MsgContext) (map[string]any, error) {
+	return expressionEnv(context)
+}
+
+// expressionEnv binds ExpressionVariables to the message
+func expressionEnv(context *synctx.MsgContext) (map[string]any, error) {
+	raw, err := messagePayload(context)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read payload: %w", err)
+	}
+	var payload any
+	if len(raw) > 0 && json.Unmarshal(raw, &payload) != nil {
+		payload = string(raw)
+	}
+
+	headers := make(map[string]any)
+	for name, value := range context.Headers {
+		headers[strings.ToLower(name)] = value
+	}
+	if requestHeaders, ok := context.Properties["http_request_headers"].(map[string]string); ok {
+		for name, value := range requestHeaders {
+			headers[strings.ToLower(name)] = value
+		}
+	}
+	properties := make(map[string]any, len(context.Properties))
+	for name, value := range context.Properties {
+		properties[name] = value
+	}
+	return map[string]any{
+		"payload":    payload,
+		"headers":    headers,
+		"properties": properties,
+		"params":     context.Properties["uriParams"],
+		"query":      context.Properties["queryParams"],
+	}, nil
+}
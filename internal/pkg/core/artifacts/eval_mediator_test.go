@@ -0,0 +1,115 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func evalMediator(t *testing.T, source string) EvalMediator {
+	program, err := expression.Compile(source, ExpressionVariables...)
+	require.NoError(t, err)
+	return EvalMediator{Expression: program, Status: http.StatusBadRequest}
+}
+
+func evalMessage(payload string) *synctx.MsgContext {
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte(payload)
+	msg.Properties["http_request_headers"] = map[string]string{"X-Tenant": "acme"}
+	msg.Properties["uriParams"] = map[string]string{"orderId": "42"}
+	msg.Properties["queryParams"] = map[string]string{"expand": "items"}
+	msg.Properties["HTTP_SC"] = 200
+	return msg
+}
+
+func TestEvalMediator_Guard(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		payload    string
+		wantOK     bool
+		wantStatus int
+		wantErr    string
+	}{
+		{name: "satisfied", expression: `len(payload.items) > 0 && headers['x-tenant'] != ''`, payload: `{"items":[1]}`, wantOK: true},
+		{name: "params and query", expression: `params.orderId == '42' && query.expand == 'items' && properties.HTTP_SC == 200`, payload: `{}`, wantOK: true},
+		{name: "text payload", expression: `payload startsWith 'PING'`, payload: `PING 1`, wantOK: true},
+		{
+			name:       "not satisfied",
+			expression: `len(payload.items) > 0`,
+			payload:    `{"items":[]}`,
+			wantStatus: http.StatusBadRequest,
+			wantErr:    "guard len(payload.items) > 0 is not satisfied",
+		},
+		{
+			name:       "not a boolean",
+			expression: `payload.total`,
+			payload:    `{"total":3}`,
+			wantStatus: http.StatusBadRequest,
+			wantErr:    "guard payload.total is not satisfied",
+		},
+		{
+			name:       "evaluation error",
+			expression: `int(payload.total) % 0 > 1`,
+			payload:    `{"total":3}`,
+			wantStatus: http.StatusInternalServerError,
+			wantErr:    "expression \"int(payload.total) % 0 > 1\": runtime error: integer divide by zero (1:20)\n | int(payload.total) % 0 > 1\n | ...................^",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(tt.payload)
+			ok, err := evalMediator(t, tt.expression).Execute(msg)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Equal(t, tt.wantStatus, msg.Properties[HTTPStatusProperty])
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	mediator := evalMediator(t, `payload.qty <= 10`)
+	mediator.Status = http.StatusUnprocessableEntity
+	mediator.Message = "at most 10 items per order"
+	msg := evalMessage(`{"qty":11}`)
+	_, err := mediator.Execute(msg)
+	assert.EqualError(t, err, "at most 10 items per order")
+	assert.Equal(t, http.StatusUnprocessableEntity, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, ErrorCodeGuardFailed, msg.Properties[ErrorCodeProperty])
+}
+
+func TestEvalMediator_Property(t *testing.T) {
+	mediator := evalMediator(t, `payload.total * 1.2`)
+	mediator.Property = "TOTAL_WITH_TAX"
+	msg := evalMessage(`{"total":50}`)
+	ok, err := mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 60.0, msg.Properties["TOTAL_WITH_TAX"])
+	assert.Equal(t, "60", ValidationRequest{context: msg}.Property("TOTAL_WITH_TAX"))
+}
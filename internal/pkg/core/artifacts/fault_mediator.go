@@ -0,0 +1,153 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/wssecurity"
+	"github.com/beevik/etree"
+)
+
+// Fault versions
+const (
+	FaultVersionSOAP11 = "soap11"
+	FaultVersionSOAP12 = "soap12"
+	FaultVersionJSON   = "json"
+)
+
+// FaultProperty is set by the fault mediator once it has built an error
+// response
+const FaultProperty = "FAULT"
+
+// FaultCodes lists the codes a SOAP fault of each version may carry. JSON
+// faults accept any code.
+var FaultCodes = map[string][]string{
+	FaultVersionSOAP11: {"VersionMismatch", "MustUnderstand", "Client", "Server"},
+	FaultVersionSOAP12: {"VersionMismatch", "MustUnderstand", "DataEncodingUnknown", "Sender", "Receiver"},
+}
+
+// FaultText is a constant or the result of an expression over
+// ExpressionVariables
+type FaultText struct {
+	Value      string
+	Expression *expression.Program
+}
+
+// FaultMediator replaces the payload with a SOAP 1.1, SOAP 1.2 or JSON fault,
+// sets Status as the HTTP status and stops the flow so the fault sequence
+// runs. Without a fault sequence, or when it fails, the fault itself is the
+// response.
+type FaultMediator struct {
+	Version  string
+	Status   int
+	Code     string
+	Reason   FaultText
+	Detail   FaultText
+	Position Position
+}
+
+func (fm FaultMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	var env map[string]any
+	text := func(t FaultText) (string, error) {
+		if t.Expression == nil {
+			return t.Value, nil
+		}
+		if env == nil {
+			var err error
+			if env, err = expressionEnv(context); err != nil {
+				return "", err
+			}
+		}
+		result, err := t.Expression.Run(env)
+		if err != nil {
+			return "", err
+		}
+		converted, err := ConvertPropertyValue(result, PropertyTypeString)
+		if err != nil {
+			return "", err
+		}
+		return converted.(string), nil
+	}
+	reason, err := text(fm.Reason)
+	if err == nil {
+		var detail string
+		if detail, err = text(fm.Detail); err == nil {
+			payload, contentType := fm.build(reason, detail)
+			setPayload(context, payload, contentType)
+		}
+	}
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeExpressionFailed, err)
+	}
+
+	context.Properties[HTTPStatusProperty] = fm.Status
+	context.Properties[FaultProperty] = true
+	return fail(context, fm.Code, errors.New(reason))
+}
+
+// build renders the fault and returns it with its content type
+func (fm FaultMediator) build(reason, detail string) ([]byte, string) {
+	if fm.Version == FaultVersionJSON {
+		fault := map[string]string{"code": fm.Code, "reason": reason}
+		if detail != "" {
+			fault["detail"] = detail
+		}
+		payload, _ := json.Marshal(fault)
+		return payload, "application/json"
+	}
+
+	doc := etree.NewDocument()
+	envelope := doc.CreateElement("soapenv:Envelope")
+	body := envelope.CreateElement("soapenv:Body")
+	fault := body.CreateElement("soapenv:Fault")
+	contentType := "text/xml; charset=utf-8"
+	if fm.Version == FaultVersionSOAP12 {
+		envelope.CreateAttr("xmlns:soapenv", wssecurity.NamespaceSOAP12)
+		fault.CreateElement("soapenv:Code").CreateElement("soapenv:Value").SetText("soapenv:" + fm.Code)
+		text := fault.CreateElement("soapenv:Reason").CreateElement("soapenv:Text")
+		text.CreateAttr("xml:lang", "en")
+		text.SetText(reason)
+		if detail != "" {
+			fault.CreateElement("soapenv:Detail").SetText(detail)
+		}
+		contentType = "application/soap+xml; charset=utf-8"
+	} else {
+		envelope.CreateAttr("xmlns:soapenv", wssecurity.NamespaceSOAP11)
+		fault.CreateElement("faultcode").SetText("soapenv:" + fm.Code)
+		fault.CreateElement("faultstring").SetText(reason)
+		if detail != "" {
+			fault.CreateElement("detail").SetText(detail)
+		}
+	}
+	payload, _ := doc.WriteToBytes()
+	return payload, contentType
+}
+
+// Faulted reports whether a fault mediator has built the error response
+func Faulted(context *synctx.MsgContext) bool {
+	faulted, _ := context.Properties[FaultProperty].(bool)
+	return faulted
+}
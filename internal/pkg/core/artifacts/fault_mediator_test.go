@@ -0,0 +1,95 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultMediator_Execute(t *testing.T) {
+	tests := []struct {
+		name        string
+		mediator    FaultMediator
+		wantPayload string
+		wantType    string
+	}{
+		{
+			name: "json",
+			mediator: FaultMediator{Version: FaultVersionJSON, Status: http.StatusUnprocessableEntity, Code: "ORDER_INVALID",
+				Reason: FaultText{Value: "The order has no items"}, Detail: FaultText{Expression: propertyExpression(t, "params.orderId")}},
+			wantPayload: `{"code":"ORDER_INVALID","detail":"42","reason":"The order has no items"}`,
+			wantType:    "application/json",
+		},
+		{
+			name: "soap 1.1",
+			mediator: FaultMediator{Version: FaultVersionSOAP11, Status: http.StatusUnprocessableEntity, Code: "Client",
+				Reason: FaultText{Value: "Quantity < 1"}},
+			wantPayload: `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><soapenv:Fault>` +
+				`<faultcode>soapenv:Client</faultcode><faultstring>Quantity &lt; 1</faultstring></soapenv:Fault></soapenv:Body></soapenv:Envelope>`,
+			wantType: "text/xml; charset=utf-8",
+		},
+		{
+			name: "soap 1.2",
+			mediator: FaultMediator{Version: FaultVersionSOAP12, Status: http.StatusUnprocessableEntity, Code: "Sender",
+				Reason: FaultText{Expression: propertyExpression(t, `"Order " + params.orderId + " is invalid"`)}, Detail: FaultText{Value: "no items"}},
+			wantPayload: `<soapenv:Envelope xmlns:soapenv="http://www.w3.org/2003/05/soap-envelope"><soapenv:Body><soapenv:Fault>` +
+				`<soapenv:Code><soapenv:Value>soapenv:Sender</soapenv:Value></soapenv:Code>` +
+				`<soapenv:Reason><soapenv:Text xml:lang="en">Order 42 is invalid</soapenv:Text></soapenv:Reason>` +
+				`<soapenv:Detail>no items</soapenv:Detail></soapenv:Fault></soapenv:Body></soapenv:Envelope>`,
+			wantType: "application/soap+xml; charset=utf-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(`{"id":1}`)
+			ok, err := tt.mediator.Execute(msg)
+			assert.False(t, ok, "the flow continues in the fault sequence")
+			assert.Error(t, err)
+			assert.Equal(t, tt.wantPayload, string(msg.Message.RawPayload))
+			assert.Equal(t, tt.wantType, msg.Headers["Content-Type"])
+			assert.Equal(t, http.StatusUnprocessableEntity, msg.Properties[HTTPStatusProperty])
+			assert.Equal(t, tt.mediator.Code, msg.Properties[ErrorCodeProperty])
+			assert.True(t, Faulted(msg))
+		})
+	}
+
+	msg := evalMessage(`{"id":"x"}`)
+	ok, err := FaultMediator{Version: FaultVersionJSON, Status: http.StatusBadRequest, Code: "BAD",
+		Reason: FaultText{Expression: propertyExpression(t, "payload.id - 1")}}.Execute(msg)
+	assert.False(t, ok)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, ErrorCodeExpressionFailed, msg.Properties[ErrorCodeProperty])
+	assert.False(t, Faulted(msg))
+}
+
+func TestFaultMediator_FaultSequence(t *testing.T) {
+	fault := FaultMediator{Version: FaultVersionJSON, Status: http.StatusBadRequest, Code: "BAD", Reason: FaultText{Value: "bad"}}
+	resource := Resource{
+		InSequence:    Sequence{MediatorList: []Mediator{fault, appendMediator{value: "-after"}}},
+		FaultSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "-fault"}}},
+	}
+	msg := evalMessage(`{}`)
+	assert.True(t, resource.Mediate(msg))
+	assert.Equal(t, `{"code":"BAD","reason":"bad"}-fault`, string(msg.Message.RawPayload))
+}
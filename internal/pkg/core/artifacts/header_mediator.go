@@ -0,0 +1,87 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Header scopes. Transport headers are the HTTP headers written to the
+// response; SOAP headers are reserved for when the envelope is mediated.
+const (
+	HeaderScopeTransport = "transport"
+	HeaderScopeSOAP      = "soap"
+)
+
+// HeaderMediator sets or removes a transport header of the message. Set
+// values are either a constant or the result of an expression over
+// ExpressionVariables, converted to a string as for a string property.
+type HeaderMediator struct {
+	Name   string
+	Action string
+	Scope  string
+	// Value is the constant to set. It is unused when Expression is set.
+	Value      string
+	Expression *expression.Program
+	Position   Position
+}
+
+func (hm HeaderMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	if hm.Action == PropertyActionRemove {
+		for name := range context.Headers {
+			if strings.EqualFold(name, hm.Name) {
+				delete(context.Headers, name)
+			}
+		}
+		return true, nil
+	}
+
+	value := hm.Value
+	if hm.Expression != nil {
+		env, err := expressionEnv(context)
+		if err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+			return fail(context, ErrorCodeExpressionFailed, err)
+		}
+		result, err := hm.Expression.Run(env)
+		if err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(context, ErrorCodeExpressionFailed, err)
+		}
+		converted, err := ConvertPropertyValue(result, PropertyTypeString)
+		if err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(context, ErrorCodeExpressionFailed, fmt.Errorf("header %s: %w", hm.Name, err))
+		}
+		value = converted.(string)
+	}
+	for name := range context.Headers {
+		if strings.EqualFold(name, hm.Name) {
+			delete(context.Headers, name)
+		}
+	}
+	context.Headers[http.CanonicalHeaderKey(hm.Name)] = value
+	return true, nil
+}
@@ -0,0 +1,62 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderMediator(t *testing.T) {
+	tests := []struct {
+		name     string
+		mediator HeaderMediator
+		want     map[string]string
+	}{
+		{name: "constant", mediator: HeaderMediator{Name: "cache-control", Value: "no-store"},
+			want: map[string]string{"X-Internal": "secret", "Cache-Control": "no-store"}},
+		{name: "expression", mediator: HeaderMediator{Name: "X-Order-Id", Expression: propertyExpression(t, `params.orderId`)},
+			want: map[string]string{"X-Internal": "secret", "X-Order-Id": "42"}},
+		{name: "replace ignoring case", mediator: HeaderMediator{Name: "X-INTERNAL", Expression: propertyExpression(t, `len(payload.items)`)},
+			want: map[string]string{"X-Internal": "2"}},
+		{name: "remove ignoring case", mediator: HeaderMediator{Name: "x-internal", Action: PropertyActionRemove},
+			want: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(`{"items":[1,2]}`)
+			msg.Headers = map[string]string{"X-Internal": "secret"}
+			ok, err := tt.mediator.Execute(msg)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, msg.Headers)
+		})
+	}
+
+	msg := evalMessage(`{"total":"x"}`)
+	ok, err := HeaderMediator{Name: "X-Total", Expression: propertyExpression(t, `payload.total - 1`)}.Execute(msg)
+	assert.False(t, ok)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, ErrorCodeExpressionFailed, msg.Properties[ErrorCodeProperty])
+}
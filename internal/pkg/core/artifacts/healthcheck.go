@@ -0,0 +1,41 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import "time"
+
+// HealthCheck periodically probes a backend endpoint, URL or sequence
+type HealthCheck struct {
+	Name string
+	// Exactly one of Endpoint, URL and Sequence is set
+	Endpoint string
+	URL      string
+	Method   string
+	Sequence string
+	// ExpectedStatus is the response status of a healthy backend; zero accepts any 2xx
+	ExpectedStatus int
+	Interval       time.Duration
+	Timeout        time.Duration
+	// FailureThreshold is the number of consecutive failures before the check is unhealthy
+	FailureThreshold int
+	// Critical checks must be healthy for the runtime to report ready
+	Critical bool
+	Position Position
+}
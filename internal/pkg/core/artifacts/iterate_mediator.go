@@ -0,0 +1,380 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/beevik/etree"
+)
+
+const (
+	ErrorCodeIterateFailed = "ITERATE_FAILED"
+	// IterateIndexProperty holds the position of a split message, from 0
+	IterateIndexProperty = "ITERATE_INDEX"
+	// IterateCountProperty holds the number of split messages
+	IterateCountProperty = "ITERATE_COUNT"
+)
+
+// splitPathSegment matches one step of a JSON split path: a key or an index
+var splitPathSegment = regexp.MustCompile(`^(?:\.([^.\[\]]+)|\[(\d+)\])`)
+
+// SplitPath selects what an iterate mediator splits: the items of the JSON
+// array at a JSONPath such as $.order.items[*], or the XML elements matched
+// by an XPath such as //order/item
+type SplitPath struct {
+	source string
+	// keys holds the steps of a JSONPath, string keys and int indexes
+	keys  []any
+	xpath *etree.Path
+}
+
+// CompileSplitPath parses a JSONPath starting with '$' or an XPath starting
+// with '/'
+func CompileSplitPath(source string) (SplitPath, error) {
+	path := SplitPath{source: source}
+	switch {
+	case strings.HasPrefix(source, "$"):
+		rest := strings.TrimSuffix(strings.TrimPrefix(source, "$"), "[*]")
+		for rest != "" {
+			match := splitPathSegment.FindStringSubmatch(rest)
+			if match == nil {
+				return SplitPath{}, fmt.Errorf("invalid JSONPath '%s'", source)
+			}
+			if match[1] != "" {
+				path.keys = append(path.keys, match[1])
+			} else {
+				index, _ := strconv.Atoi(match[2])
+				path.keys = append(path.keys, index)
+			}
+			rest = rest[len(match[0]):]
+		}
+	case strings.HasPrefix(source, "/"):
+		xpath, err := etree.CompilePath(source)
+		if err != nil {
+			return SplitPath{}, fmt.Errorf("invalid XPath '%s': %w", source, err)
+		}
+		path.xpath = &xpath
+	default:
+		return SplitPath{}, fmt.Errorf("path must be a JSONPath starting with '$' or an XPath starting with '/', got: %s", source)
+	}
+	return path, nil
+}
+
+// String returns the source of the path
+func (p SplitPath) String() string {
+	return p.source
+}
+
+// IsXPath reports whether the path splits XML payloads
+func (p SplitPath) IsXPath() bool {
+	return p.xpath != nil
+}
+
+// Split returns the items the path selects in the payload, each a JSON value
+// or an XML element
+func (p SplitPath) Split(payload []byte) ([][]byte, error) {
+	var split splitPayload
+	var err error
+	if p.IsXPath() {
+		split, err = splitXML(payload, *p.xpath)
+	} else {
+		split, err = splitJSON(payload, p.keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return split.items(), nil
+}
+
+// IterateMediator splits the payload on Path and runs the target sequence on
+// a copy of the message for each item, with IterateIndexProperty and
+// IterateCountProperty set. The target is Sequence, or the deployed sequence
+// named SequenceName. Items are mediated one after another, or all at once
+// with Parallel. The flow fails when the target fails for any item.
+//
+// With Aggregate, every item is replaced in the payload by the payload its
+// copy ends with, and items whose copy was dropped are removed. Otherwise the
+// payload is left as it was. Properties set on the copies are not kept. The
+// copies can also be combined by a following aggregate mediator.
+type IterateMediator struct {
+	Path         SplitPath
+	Sequence     *Sequence
+	SequenceName string
+	Parallel     bool
+	Aggregate    bool
+	Position     Position
+}
+
+func (im IterateMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	target := im.Sequence
+	if target == nil {
+		sequence, ok := SnapshotFromContext(context).Sequences[im.SequenceName]
+		if !ok {
+			context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(context, ErrorCodeSequenceNotFound, fmt.Errorf("iterate: sequence %s is not deployed", im.SequenceName))
+		}
+		target = &sequence
+	}
+	payload, err := messagePayload(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeIterateFailed, fmt.Errorf("iterate: cannot read payload: %w", err))
+	}
+
+	var split splitPayload
+	if im.Path.IsXPath() {
+		split, err = splitXML(payload, *im.Path.xpath)
+	} else {
+		split, err = splitJSON(payload, im.Path.keys)
+	}
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeIterateFailed, fmt.Errorf("iterate %s: %w", im.Path, err))
+	}
+
+	items := split.items()
+	messages := make([]*synctx.MsgContext, len(items))
+	results := make([]bool, len(items))
+	collection := newCollection(len(items))
+	run := func(i int) {
+		msg := context.Clone()
+		msg.Properties[IterateIndexProperty] = i
+		msg.Properties[IterateCountProperty] = len(items)
+		setPayload(msg, items[i], split.contentType(context))
+		messages[i], results[i] = msg, target.Execute(msg)
+		collection.add(i, msg, results[i])
+	}
+	if im.Parallel {
+		var wg sync.WaitGroup
+		for i := range items {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i := range items {
+			if run(i); !results[i] {
+				break
+			}
+		}
+	}
+
+	context.Properties[AggregateCollectionProperty] = collection
+	if i, status, reason, failed := failedBranch(messages, results); failed {
+		context.Properties[HTTPStatusProperty] = status
+		return fail(context, ErrorCodeIterateFailed, fmt.Errorf("iterate %s: item %d: %s", im.Path, i, reason))
+	}
+	if !im.Aggregate {
+		return true, nil
+	}
+	aggregated, err := split.aggregate(messages)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeIterateFailed, fmt.Errorf("iterate %s: %w", im.Path, err))
+	}
+	setPayload(context, aggregated, "")
+	return true, nil
+}
+
+// failedBranch finds the first branch whose sequence failed, with the error
+// status and reason it set. Branches that never ran have no message.
+func failedBranch(messages []*synctx.MsgContext, results []bool) (int, int, string, bool) {
+	for i, ok := range results {
+		if ok {
+			continue
+		}
+		if messages[i] == nil {
+			break
+		}
+		status, ok := messages[i].Properties[HTTPStatusProperty].(int)
+		if !ok || status < 400 {
+			status = http.StatusInternalServerError
+		}
+		reason, _ := messages[i].Properties[ErrorMessageProperty].(string)
+		if reason == "" {
+			reason = "the target sequence failed"
+		}
+		return i, status, reason, true
+	}
+	return 0, 0, "", false
+}
+
+// splitPayload is a payload split into items that can be put back together
+type splitPayload interface {
+	items() [][]byte
+	contentType(parent *synctx.MsgContext) string
+	// aggregate replaces each item with the payload of its message
+	aggregate(messages []*synctx.MsgContext) ([]byte, error)
+}
+
+type jsonSplit struct {
+	document any
+	keys     []any
+	array    []any
+}
+
+func splitJSON(payload []byte, keys []any) (splitPayload, error) {
+	var document any
+	if err := json.Unmarshal(payload, &document); err != nil {
+		return nil, fmt.Errorf("payload is not JSON: %w", err)
+	}
+	value := document
+	for _, key := range keys {
+		value = jsonStep(value, key)
+	}
+	if value == nil {
+		return &jsonSplit{document: document, keys: keys}, nil
+	}
+	array, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("path does not select an array")
+	}
+	return &jsonSplit{document: document, keys: keys, array: array}, nil
+}
+
+// jsonStep returns the value of a key of an object or an index of an array,
+// or nil when there is none
+func jsonStep(value any, key any) any {
+	switch node := value.(type) {
+	case map[string]any:
+		if name, ok := key.(string); ok {
+			return node[name]
+		}
+	case []any:
+		if index, ok := key.(int); ok && index < len(node) {
+			return node[index]
+		}
+	}
+	return nil
+}
+
+func (s *jsonSplit) items() [][]byte {
+	items := make([][]byte, len(s.array))
+	for i, item := range s.array {
+		items[i], _ = json.Marshal(item)
+	}
+	return items
+}
+
+func (s *jsonSplit) contentType(parent *synctx.MsgContext) string {
+	return payloadContentTypes[PayloadMediaTypeJSON]
+}
+
+func (s *jsonSplit) aggregate(messages []*synctx.MsgContext) ([]byte, error) {
+	if s.array == nil {
+		return json.Marshal(s.document)
+	}
+	array := make([]any, 0, len(messages))
+	for _, msg := range messages {
+		if Dropped(msg) {
+			continue
+		}
+		value, err := ParseFragment(msg.Message.RawPayload, msg.Message.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		if element, ok := value.(*etree.Element); ok {
+			value = writeElement(element)
+		}
+		array = append(array, value)
+	}
+	if len(s.keys) == 0 {
+		return json.Marshal(array)
+	}
+	// The path was resolved when the payload was split, so every step exists
+	parent := s.document
+	for _, key := range s.keys[:len(s.keys)-1] {
+		parent = jsonStep(parent, key)
+	}
+	switch last := s.keys[len(s.keys)-1].(type) {
+	case string:
+		parent.(map[string]any)[last] = array
+	case int:
+		parent.([]any)[last] = array
+	}
+	return json.Marshal(s.document)
+}
+
+type xmlSplit struct {
+	document *etree.Document
+	elements []*etree.Element
+}
+
+func splitXML(payload []byte, xpath etree.Path) (splitPayload, error) {
+	document := etree.NewDocument()
+	if err := document.ReadFromBytes(payload); err != nil {
+		return nil, fmt.Errorf("payload is not XML: %w", err)
+	}
+	if document.Root() == nil {
+		return nil, errors.New("payload is not XML: no root element")
+	}
+	return &xmlSplit{document: document, elements: document.FindElementsPath(xpath)}, nil
+}
+
+func (s *xmlSplit) items() [][]byte {
+	items := make([][]byte, len(s.elements))
+	for i, element := range s.elements {
+		items[i] = []byte(writeElement(element))
+	}
+	return items
+}
+
+func (s *xmlSplit) contentType(parent *synctx.MsgContext) string {
+	if strings.Contains(strings.ToLower(parent.Message.ContentType), "xml") {
+		return parent.Message.ContentType
+	}
+	return payloadContentTypes[PayloadMediaTypeXML]
+}
+
+func (s *xmlSplit) aggregate(messages []*synctx.MsgContext) ([]byte, error) {
+	for i, element := range s.elements {
+		parent := element.Parent()
+		if Dropped(messages[i]) {
+			parent.RemoveChild(element)
+			continue
+		}
+		value, err := ParseFragment(messages[i].Message.RawPayload, messages[i].Message.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		replacement, ok := value.(*etree.Element)
+		if !ok {
+			// Other payloads become the text of the item
+			replacement = element.Copy()
+			replacement.Child = nil
+			replacement.SetText(strings.TrimSpace(string(messages[i].Message.RawPayload)))
+		}
+		parent.InsertChildAt(element.Index(), replacement)
+		parent.RemoveChild(element)
+	}
+	return s.document.WriteToBytes()
+}
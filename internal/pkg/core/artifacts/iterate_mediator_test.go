@@ -0,0 +1,168 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func splitPath(t *testing.T, source string) SplitPath {
+	path, err := CompileSplitPath(source)
+	require.NoError(t, err)
+	return path
+}
+
+func TestCompileSplitPath(t *testing.T) {
+	tests := []struct {
+		source   string
+		wantKeys []any
+		wantErr  string
+	}{
+		{source: "$", wantKeys: nil},
+		{source: "$.order.items[*]", wantKeys: []any{"order", "items"}},
+		{source: "$.orders[1].lines", wantKeys: []any{"orders", 1, "lines"}},
+		{source: "//order/item"},
+		{source: "$.order..items", wantErr: "invalid JSONPath '$.order..items'"},
+		{source: "$.items[-1]", wantErr: "invalid JSONPath"},
+		{source: "//item[", wantErr: "invalid XPath"},
+		{source: "order.items", wantErr: "path must be a JSONPath starting with '$' or an XPath starting with '/'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			path, err := CompileSplitPath(tt.source)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKeys, path.keys)
+			assert.Equal(t, tt.source, path.String())
+		})
+	}
+}
+
+func TestIterateMediator_JSON(t *testing.T) {
+	// Items are tagged with their index, and those marked skip are dropped
+	skip := switchRegex(t, "true")
+	skip.Sequence = Sequence{MediatorList: []Mediator{DropMediator{}}}
+	target := &Sequence{MediatorList: []Mediator{
+		SwitchMediator{Source: propertyExpression(t, "payload.skip"), Cases: []SwitchCase{skip}},
+		EnrichMediator{
+			Source: EnrichSource{Type: EnrichSourceExpression, Expression: propertyExpression(t, "properties.ITERATE_INDEX + 1")},
+			Target: EnrichTarget{Type: EnrichTargetKey, Path: []string{"line"}, Action: EnrichActionReplace},
+		},
+	}}
+	payload := `{"order":{"id":7,"items":[{"sku":"A"},{"sku":"B","skip":"true"},{"sku":"C"}]}}`
+
+	for _, parallel := range []bool{false, true} {
+		msg := evalMessage(payload)
+		ok, err := IterateMediator{Path: splitPath(t, "$.order.items[*]"), Sequence: target, Parallel: parallel, Aggregate: true}.Execute(msg)
+		require.True(t, ok)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"order":{"id":7,"items":[{"sku":"A","line":1},{"sku":"C","line":3}]}}`, string(msg.Message.RawPayload))
+		assert.NotContains(t, msg.Properties, IterateIndexProperty, "the copies do not change the message")
+		assert.False(t, Dropped(msg))
+	}
+
+	msg := evalMessage(payload)
+	ok, err := IterateMediator{Path: splitPath(t, "$.order.items"), Sequence: target}.Execute(msg)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, string(msg.Message.RawPayload), "without aggregate the payload is kept")
+
+	msg = evalMessage(`[1,2]`)
+	ok, err = IterateMediator{Path: splitPath(t, "$"), Sequence: &Sequence{MediatorList: []Mediator{appendMediator{value: "0"}}}, Aggregate: true}.Execute(msg)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, `[10,20]`, string(msg.Message.RawPayload))
+
+	msg = evalMessage(`{"order":{}}`)
+	ok, err = IterateMediator{Path: splitPath(t, "$.order.items"), Sequence: target, Aggregate: true}.Execute(msg)
+	assert.True(t, ok, "a missing array has no items")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"order":{}}`, string(msg.Message.RawPayload))
+}
+
+func TestIterateMediator_XML(t *testing.T) {
+	target := &Sequence{MediatorList: []Mediator{
+		EnrichMediator{
+			Source: EnrichSource{Type: EnrichSourceInline, Inline: `<checked>true</checked>`},
+			Target: EnrichTarget{Type: EnrichTargetChild, Action: EnrichActionMerge},
+		},
+	}}
+	msg := evalMessage(`<order><item><sku>A</sku></item><note/><item><sku>B</sku></item></order>`)
+	msg.Message.ContentType = "application/xml"
+	ok, err := IterateMediator{Path: splitPath(t, "//item"), Sequence: target, Aggregate: true}.Execute(msg)
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, `<order><item><sku>A</sku><checked>true</checked></item><note/><item><sku>B</sku><checked>true</checked></item></order>`,
+		string(msg.Message.RawPayload))
+}
+
+func TestIterateMediator_Failures(t *testing.T) {
+	failing := &Sequence{MediatorList: []Mediator{
+		EvalMediator{Expression: propertyExpression(t, "payload.qty > 0"), Status: http.StatusUnprocessableEntity},
+	}}
+	tests := []struct {
+		name       string
+		mediator   IterateMediator
+		payload    string
+		wantStatus int
+		wantErr    string
+	}{
+		{
+			name:     "item fails",
+			mediator: IterateMediator{Path: splitPath(t, "$.items"), Sequence: failing},
+			payload:  `{"items":[{"qty":1},{"qty":0}]}`, wantStatus: http.StatusUnprocessableEntity,
+			wantErr: "iterate $.items: item 1:",
+		},
+		{
+			name:     "not an array",
+			mediator: IterateMediator{Path: splitPath(t, "$.items"), Sequence: failing},
+			payload:  `{"items":{"qty":1}}`, wantStatus: http.StatusBadRequest,
+			wantErr: "iterate $.items: path does not select an array",
+		},
+		{
+			name:     "not XML",
+			mediator: IterateMediator{Path: splitPath(t, "//item"), Sequence: failing},
+			payload:  `{"items":[]}`, wantStatus: http.StatusBadRequest,
+			wantErr: "iterate //item: payload is not XML",
+		},
+		{
+			name:     "unknown sequence",
+			mediator: IterateMediator{Path: splitPath(t, "$.items"), SequenceName: "ProcessItem"},
+			payload:  `{"items":[]}`, wantStatus: http.StatusInternalServerError,
+			wantErr: "iterate: sequence ProcessItem is not deployed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(tt.payload)
+			ok, err := tt.mediator.Execute(msg)
+			assert.False(t, ok)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStatus, msg.Properties[HTTPStatusProperty])
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/jq"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const ErrorCodeJSONTransformFailed = "JSON_TRANSFORM_FAILED"
+
+// JSONTransformVariables are the variables a jsontransform program can use
+// besides its input, the JSON payload: $headers, $properties, $params and
+// $query, as in expressions
+var JSONTransformVariables = []string{"headers", "properties", "params", "query"}
+
+// JSONTransformMediator replaces a JSON payload with the output of a jq
+// program. An empty payload is null. The flow fails with 400 when the payload
+// is not JSON, and with 500 when the program fails or does not produce exactly
+// one output.
+type JSONTransformMediator struct {
+	Program  *jq.Program
+	Position Position
+}
+
+func (jm JSONTransformMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	env, err := expressionEnv(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeJSONTransformFailed, fmt.Errorf("jsontransform: %w", err))
+	}
+	raw, _ := messagePayload(context)
+	var input any
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &input); err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+			return fail(context, ErrorCodeJSONTransformFailed, fmt.Errorf("jsontransform: payload is not JSON: %w", err))
+		}
+	}
+	vars := make(map[string]any, len(JSONTransformVariables))
+	for _, name := range JSONTransformVariables {
+		vars[name] = jsonValue(env[name])
+	}
+
+	outputs, err := jm.Program.Run(input, vars)
+	if err == nil && len(outputs) != 1 {
+		err = fmt.Errorf("jq program %q produced %d outputs, expected one", jm.Program, len(outputs))
+	}
+	var output []byte
+	if err == nil {
+		output, err = json.Marshal(outputs[0])
+	}
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeJSONTransformFailed, fmt.Errorf("jsontransform: %w", err))
+	}
+	setPayload(context, output, "application/json")
+	return true, nil
+}
+
+// jsonValue converts message data to the values of decoded JSON, recursively,
+// with anything else such as a request body reader in its string form
+func jsonValue(value any) any {
+	switch v := expression.Normalize(value).(type) {
+	case nil, bool, float64, string:
+		return v
+	case []any:
+		values := make([]any, len(v))
+		for i, item := range v {
+			values[i] = jsonValue(item)
+		}
+		return values
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for key, item := range v {
+			values[key] = jsonValue(item)
+		}
+		return values
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/jq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonTransformMediator(t *testing.T, source string) JSONTransformMediator {
+	program, err := jq.Compile(source, JSONTransformVariables...)
+	require.NoError(t, err)
+	return JSONTransformMediator{Program: program}
+}
+
+func TestJSONTransformMediator(t *testing.T) {
+	msg := dispatchMessage(`{"order": {"id": 7, "items": [{"sku": "a1", "qty": 2}, {"sku": "b2", "qty": 0}]}}`)
+	msg.Headers["X-Channel"] = "mobile"
+	msg.Properties["uriParams"] = map[string]string{"tenant": "acme"}
+	msg.Properties["attempt"] = 3
+	ok, err := jsonTransformMediator(t, `{
+		id: .order.id,
+		tenant: $params.tenant,
+		channel: $headers["x-channel"],
+		attempt: $properties.attempt,
+		skus: [.order.items[] | select(.qty > 0) | .sku]
+	}`).Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"id": 7, "tenant": "acme", "channel": "mobile", "attempt": 3, "skus": ["a1"]}`, string(msg.Message.RawPayload))
+	assert.Equal(t, "application/json", msg.Message.ContentType)
+
+	msg = dispatchMessage(``)
+	ok, err = jsonTransformMediator(t, `{empty: (. == null)}`).Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"empty": true}`, string(msg.Message.RawPayload))
+}
+
+func TestJSONTransformMediatorFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		program string
+		status  int
+		errMsg  string
+	}{
+		{"Payload is not JSON", `<order/>`, `.`, http.StatusBadRequest, "payload is not JSON"},
+		{"Program fails", `{"order": "x"}`, `.order.id`, http.StatusInternalServerError, `cannot index string`},
+		{"No output", `[]`, `.[]`, http.StatusInternalServerError, "produced 0 outputs"},
+		{"Several outputs", `[1, 2]`, `.[]`, http.StatusInternalServerError, "produced 2 outputs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := dispatchMessage(tt.payload)
+			ok, err := jsonTransformMediator(t, tt.program).Execute(msg)
+			assert.False(t, ok)
+			assert.ErrorContains(t, err, tt.errMsg)
+			assert.Equal(t, ErrorCodeJSONTransformFailed, msg.Properties[ErrorCodeProperty])
+			assert.Equal(t, tt.status, msg.Properties[HTTPStatusProperty])
+			assert.Equal(t, tt.payload, string(msg.Message.RawPayload))
+		})
+	}
+}
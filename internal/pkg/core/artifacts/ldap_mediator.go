@@ -0,0 +1,101 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/ldap"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// DefaultLDAPPrefix starts the properties set by the ldap mediator
+	DefaultLDAPPrefix = "ldap."
+
+	ErrorCodeLDAPFailed = "LDAP_FAILED"
+)
+
+// LDAPMediator looks up the entry of the user User evaluates to and stores
+// its DN and Attributes in properties named Prefix+"dn" and Prefix plus the
+// attribute name. An attribute with one value is stored as a string, one
+// with several as a list. Prefix+"found" tells whether the user has an
+// entry; a user without one is not an error. Directory is the [ldap]
+// directory when nil.
+type LDAPMediator struct {
+	User       *expression.Program
+	Attributes []string
+	Prefix     string
+	Directory  *ldap.Directory
+	Position   Position
+}
+
+func (lm LDAPMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	directory := lm.Directory
+	if directory == nil {
+		directory = ldap.Default()
+	}
+	if directory == nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeLDAPFailed, fmt.Errorf("ldap: no directory is configured in the [ldap] section"))
+	}
+	env, err := expressionEnv(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeLDAPFailed, fmt.Errorf("ldap: %w", err))
+	}
+	result, err := lm.User.Run(env)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeLDAPFailed, fmt.Errorf("ldap: %s: %w", lm.User, err))
+	}
+	user, _ := ConvertPropertyValue(result, PropertyTypeString)
+	if user == "" {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeLDAPFailed, fmt.Errorf("ldap: %s yields no user", lm.User))
+	}
+
+	entry, found, err := directory.Lookup(user.(string), lm.Attributes)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadGateway
+		return fail(context, ErrorCodeLDAPFailed, err)
+	}
+	context.Properties[lm.Prefix+"found"] = found
+	if !found {
+		return true, nil
+	}
+	context.Properties[lm.Prefix+"dn"] = entry.DN
+	for _, name := range lm.Attributes {
+		switch values := entry.Values(name); len(values) {
+		case 0:
+		case 1:
+			context.Properties[lm.Prefix+name] = values[0]
+		default:
+			list := make([]any, len(values))
+			for i, value := range values {
+				list[i] = value
+			}
+			context.Properties[lm.Prefix+name] = list
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,68 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/ldap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLDAPMediator_Failures(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	// Nothing listens on the directory's address
+	listener.Close()
+	unreachable, err := ldap.NewDirectory(ldap.Config{
+		URL:          "ldap://" + listener.Addr().String(),
+		BindDN:       "cn=service,dc=example,dc=com",
+		BindPassword: "secret",
+		BaseDN:       "dc=example,dc=com",
+		Timeout:      "200ms",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		directory  *ldap.Directory
+		user       string
+		wantStatus int
+		wantErr    string
+	}{
+		{name: "no directory", user: "'jdoe'", wantStatus: http.StatusInternalServerError, wantErr: "no directory is configured"},
+		{name: "no user", directory: unreachable, user: "properties['AUTHENTICATED_USER']", wantStatus: http.StatusBadRequest, wantErr: "yields no user"},
+		{name: "unreachable", directory: unreachable, user: "'jdoe'", wantStatus: http.StatusBadGateway, wantErr: "cannot connect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(`{}`)
+			mediator := LDAPMediator{User: propertyExpression(t, tt.user), Attributes: []string{"mail"}, Prefix: DefaultLDAPPrefix, Directory: tt.directory}
+			ok, err := mediator.Execute(msg)
+			assert.False(t, ok)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStatus, msg.Properties[HTTPStatusProperty])
+			assert.NotContains(t, msg.Properties, "ldap.found")
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/apache/synapse-go/internal/pkg/core/masking"
 	"github.com/apache/synapse-go/internal/pkg/core/synctx"
 )
 
@@ -44,8 +45,13 @@ func (lm LogMediator) Execute(context *synctx.MsgContext) (bool, error) {
 			// Read the body data
 			bodyBytes, err := io.ReadAll(requestBody)
 			if err == nil {
-				// Log the body content
-				fmt.Printf("%s : HTTP Request Body: %s\n", lm.Category, string(bodyBytes))
+				// Log the body content, with sensitive fields masked
+				masked, err := masking.FromContext(context).Apply(bodyBytes, context.Message.ContentType)
+				if err != nil {
+					fmt.Printf("%s : HTTP Request Body: <not logged: %v>\n", lm.Category, err)
+				} else {
+					fmt.Printf("%s : HTTP Request Body: %s\n", lm.Category, string(masked))
+				}
 
 				// Important: Create a new ReadCloser and put it back in the context
 				// so other mediators can also read it
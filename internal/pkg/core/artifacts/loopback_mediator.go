@@ -0,0 +1,44 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import "github.com/apache/synapse-go/internal/pkg/core/synctx"
+
+// LoopbackProperty is set by the loopback mediator to end the in sequence
+const LoopbackProperty = "LOOPBACK"
+
+// LoopbackMediator ends the in sequence and moves the message to the
+// response path: the mediators after it, including those of enclosing
+// sequences, are not executed, and the resource's out sequence mediates the
+// current payload as the response.
+type LoopbackMediator struct {
+	Position Position
+}
+
+func (lm LoopbackMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	context.Properties[LoopbackProperty] = true
+	return true, nil
+}
+
+// LoopingBack reports whether a loopback mediator has ended the in sequence
+func LoopingBack(context *synctx.MsgContext) bool {
+	loopback, _ := context.Properties[LoopbackProperty].(bool)
+	return loopback
+}
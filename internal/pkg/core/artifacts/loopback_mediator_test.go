@@ -0,0 +1,89 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoopbackMediator_Execute(t *testing.T) {
+	cached := switchRegex(t, "true")
+	cached.Sequence = Sequence{MediatorList: []Mediator{appendMediator{value: "cached"}, LoopbackMediator{}, appendMediator{value: "-case"}}}
+	inSequence := Sequence{MediatorList: []Mediator{
+		SwitchMediator{Source: propertyExpression(t, "payload.cached"), Cases: []SwitchCase{cached}},
+		appendMediator{value: "-backend"},
+	}}
+	tests := []struct {
+		name        string
+		resource    Resource
+		payload     string
+		wantSuccess bool
+		wantPayload string
+	}{
+		{
+			name:        "loops back from a branch",
+			resource:    Resource{InSequence: inSequence, OutSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "-out"}}}},
+			payload:     `{"cached":"true"}`,
+			wantSuccess: true,
+			wantPayload: `{"cached":"true"}cached-out`,
+		},
+		{
+			name:        "out sequence follows a completed in sequence",
+			resource:    Resource{InSequence: inSequence, OutSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "-out"}}}},
+			payload:     `{"cached":"false"}`,
+			wantSuccess: true,
+			wantPayload: `{"cached":"false"}-backend-out`,
+		},
+		{
+			name:        "responds without an out sequence",
+			resource:    Resource{InSequence: inSequence},
+			payload:     `{"cached":"true"}`,
+			wantSuccess: true,
+			wantPayload: `{"cached":"true"}cached`,
+		},
+		{
+			name: "respond skips the out sequence",
+			resource: Resource{InSequence: Sequence{MediatorList: []Mediator{RespondMediator{}}},
+				OutSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "-out"}}}},
+			payload:     `{}`,
+			wantSuccess: true,
+			wantPayload: `{}`,
+		},
+		{
+			name: "a failing out sequence runs the fault sequence",
+			resource: Resource{InSequence: Sequence{MediatorList: []Mediator{LoopbackMediator{}}},
+				OutSequence:   Sequence{MediatorList: []Mediator{EvalMediator{Expression: propertyExpression(t, "false"), Status: 502}}},
+				FaultSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "-fault"}}}},
+			payload:     `{}`,
+			wantSuccess: true,
+			wantPayload: `{}-fault`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context := evalMessage(tt.payload)
+			assert.Equal(t, tt.wantSuccess, tt.resource.Mediate(context))
+			assert.Equal(t, tt.wantPayload, string(context.Message.RawPayload))
+			assert.False(t, LoopingBack(context))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"github.com/apache/synapse-go/internal/pkg/core/masking"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// MaskMediator attaches a masking policy to the message. The policy is applied
+// to the response payload after mediation and to payloads written to the logs.
+type MaskMediator struct {
+	Policy   *masking.Policy
+	Position Position
+}
+
+func (mm MaskMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	masking.AddToContext(context, mm.Policy)
+	return true, nil
+}
@@ -0,0 +1,69 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import "time"
+
+// What a message processor does with a message that used up its delivery
+// attempts
+const (
+	// ProcessorOnFailureDeactivate keeps the message in the store and stops
+	// the processor until it is activated again, e.g. once the backend is
+	// fixed
+	ProcessorOnFailureDeactivate = "deactivate"
+	// ProcessorOnFailureDrop removes the message and goes on with the next
+	ProcessorOnFailureDrop = "drop"
+)
+
+// MessageProcessor forwards the messages of a message store to an endpoint,
+// one at a time and in the order the store hands them out. A message that
+// fails is retried with backoff until MaxDeliveryAttempts is reached.
+type MessageProcessor struct {
+	Name         string
+	MessageStore string
+	Endpoint     string
+	// Interval is how long the processor waits before polling an empty store
+	// again
+	Interval time.Duration
+	// RetryInterval is the wait before the first retry; each further retry
+	// waits BackoffMultiplier times longer, up to MaxRetryInterval
+	RetryInterval     time.Duration
+	BackoffMultiplier float64
+	// MaxRetryInterval caps the wait between retries; 0 is uncapped
+	MaxRetryInterval    time.Duration
+	MaxDeliveryAttempts int
+	// FaultSequence, when set, mediates a message that used up its attempts
+	// before OnFailure applies
+	FaultSequence string
+	OnFailure     string
+	Position      Position
+}
+
+// RetryWait returns the wait after the given number of failed attempts
+func (p MessageProcessor) RetryWait(attempts int) time.Duration {
+	wait := p.RetryInterval
+	for i := 1; i < attempts && (p.MaxRetryInterval == 0 || wait < p.MaxRetryInterval); i++ {
+		wait = time.Duration(float64(wait) * p.BackoffMultiplier)
+	}
+	if p.MaxRetryInterval > 0 {
+		return min(wait, p.MaxRetryInterval)
+	}
+	return wait
+}
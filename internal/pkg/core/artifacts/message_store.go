@@ -0,0 +1,47 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import "time"
+
+// Types of message stores
+const (
+	// MessageStoreTypeMemory keeps messages in memory, so they are lost on
+	// restart
+	MessageStoreTypeMemory = "memory"
+	// MessageStoreTypeFile keeps each message in a file of Directory
+	MessageStoreTypeFile = "file"
+)
+
+// MessageStore keeps messages for later processing, e.g. by a message
+// processor forwarding them to a backend that may be unavailable
+type MessageStore struct {
+	Name string
+	Type string
+	// Directory is set for file stores, relative to the Synapse home
+	// directory unless absolute
+	Directory string
+	// MaxSize bounds the number of messages kept; 0 is unbounded
+	MaxSize int
+	// Aging is how long a message waits to gain a priority level in memory
+	// stores; 0 orders messages by priority alone
+	Aging    time.Duration
+	Position Position
+}
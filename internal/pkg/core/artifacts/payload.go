@@ -0,0 +1,94 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// ErrorCodeProperty and ErrorMessageProperty describe why a mediator failed,
+	// for use in the fault sequence
+	ErrorCodeProperty    = "ERROR_CODE"
+	ErrorMessageProperty = "ERROR_MESSAGE"
+	// HTTPStatusProperty is the status of the error response when a mediator
+	// rejects the request, e.g. 400 for invalid input
+	HTTPStatusProperty = "HTTP_SC"
+)
+
+// messagePayload returns the current payload of the message: RawPayload once it
+// is set, otherwise the request body. The body is put back so later mediators
+// can read it again.
+func messagePayload(context *synctx.MsgContext) ([]byte, error) {
+	if context.Message.RawPayload != nil {
+		return context.Message.RawPayload, nil
+	}
+	body, ok := context.Properties["http_request_body"].(io.ReadCloser)
+	if !ok {
+		return nil, nil
+	}
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	context.Properties["http_request_body"] = io.NopCloser(bytes.NewReader(payload))
+	return payload, nil
+}
+
+// requestHeader returns a transport header of the incoming request. APIs keep
+// request headers in a property, while inbound endpoints place them on the
+// message context.
+func requestHeader(context *synctx.MsgContext, name string) string {
+	name = http.CanonicalHeaderKey(name)
+	if headers, ok := context.Properties["http_request_headers"].(map[string]string); ok {
+		if value, exists := headers[name]; exists {
+			return value
+		}
+	}
+	for key, value := range context.Headers {
+		if http.CanonicalHeaderKey(key) == name {
+			return value
+		}
+	}
+	return ""
+}
+
+// setPayload replaces the payload of the message. A length copied from a
+// backend response no longer applies.
+func setPayload(context *synctx.MsgContext, payload []byte, contentType string) {
+	context.Message.RawPayload = payload
+	delete(context.Headers, "Content-Length")
+	if contentType != "" {
+		context.Message.ContentType = contentType
+		context.Headers["Content-Type"] = contentType
+	}
+}
+
+// fail records the reason a mediator stopped the flow and returns false so the
+// fault sequence runs
+func fail(context *synctx.MsgContext, code string, err error) (bool, error) {
+	context.Properties[ErrorCodeProperty] = code
+	context.Properties[ErrorMessageProperty] = err.Error()
+	return false, err
+}
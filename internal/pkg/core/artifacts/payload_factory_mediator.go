@@ -0,0 +1,190 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const ErrorCodePayloadFactoryFailed = "PAYLOAD_FACTORY_FAILED"
+
+// Media types of payload factory templates
+const (
+	PayloadMediaTypeJSON = "json"
+	PayloadMediaTypeXML  = "xml"
+	PayloadMediaTypeText = "text"
+)
+
+// payloadContentTypes are the content types of the built payloads
+var payloadContentTypes = map[string]string{
+	PayloadMediaTypeJSON: "application/json",
+	PayloadMediaTypeXML:  "application/xml",
+	PayloadMediaTypeText: "text/plain",
+}
+
+// PayloadArgument is the value of one $n placeholder: a constant, or an
+// expression over ExpressionVariables when Expression is set
+type PayloadArgument struct {
+	Value      string
+	Expression *expression.Program
+}
+
+// PayloadSegment is a literal part of a template followed by the placeholder
+// of an argument. Argument is 0 for the last segment, which has none.
+type PayloadSegment struct {
+	Literal  string
+	Argument int
+}
+
+// PayloadFactoryMediator replaces the payload with a template whose $1, $2, ...
+// placeholders are filled with its arguments. Arguments are escaped for the
+// media type: in JSON, strings are inserted without quotes so the template
+// decides where they go, and other values are inserted as JSON.
+type PayloadFactoryMediator struct {
+	MediaType string
+	Template  []PayloadSegment
+	Arguments []PayloadArgument
+	Position  Position
+}
+
+// ParsePayloadTemplate splits a template at its $n placeholders and checks
+// that each refers to one of count arguments. $$ stands for a literal $.
+func ParsePayloadTemplate(template string, count int) ([]PayloadSegment, error) {
+	var segments []PayloadSegment
+	var literal bytes.Buffer
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '$' || i+1 == len(template) {
+			literal.WriteByte(c)
+			continue
+		}
+		if template[i+1] == '$' {
+			literal.WriteByte('$')
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(template) && template[end] >= '0' && template[end] <= '9' {
+			end++
+		}
+		if end == i+1 {
+			literal.WriteByte(c)
+			continue
+		}
+		argument, _ := strconv.Atoi(template[i+1 : end])
+		if argument < 1 || argument > count {
+			return nil, fmt.Errorf("placeholder $%s refers to a missing argument, %d declared", template[i+1:end], count)
+		}
+		segments = append(segments, PayloadSegment{Literal: literal.String(), Argument: argument})
+		literal.Reset()
+		i = end - 1
+	}
+	return append(segments, PayloadSegment{Literal: literal.String()}), nil
+}
+
+func (pm PayloadFactoryMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	values, err := pm.arguments(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeExpressionFailed, err)
+	}
+	var payload bytes.Buffer
+	for _, segment := range pm.Template {
+		payload.WriteString(segment.Literal)
+		if segment.Argument == 0 {
+			continue
+		}
+		if err := pm.write(&payload, values[segment.Argument-1]); err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(context, ErrorCodePayloadFactoryFailed, fmt.Errorf("argument $%d: %w", segment.Argument, err))
+		}
+	}
+	if pm.MediaType == PayloadMediaTypeJSON && !json.Valid(payload.Bytes()) {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodePayloadFactoryFailed, fmt.Errorf("the built payload is not valid JSON: %s", payload.String()))
+	}
+	setPayload(context, payload.Bytes(), payloadContentTypes[pm.MediaType])
+	return true, nil
+}
+
+// arguments evaluates the arguments against the message before the payload
+// is replaced, so each sees the original payload
+func (pm PayloadFactoryMediator) arguments(context *synctx.MsgContext) ([]any, error) {
+	values := make([]any, len(pm.Arguments))
+	var env map[string]any
+	for i, argument := range pm.Arguments {
+		if argument.Expression == nil {
+			values[i] = argument.Value
+			continue
+		}
+		if env == nil {
+			var err error
+			if env, err = expressionEnv(context); err != nil {
+				return nil, err
+			}
+		}
+		value, err := argument.Expression.Run(env)
+		if err != nil {
+			return nil, fmt.Errorf("argument $%d: %w", i+1, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (pm PayloadFactoryMediator) write(payload *bytes.Buffer, value any) error {
+	switch pm.MediaType {
+	case PayloadMediaTypeJSON:
+		if s, ok := value.(string); ok {
+			quoted, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			payload.Write(quoted[1 : len(quoted)-1])
+			return nil
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		payload.Write(encoded)
+	case PayloadMediaTypeXML:
+		text, err := ConvertPropertyValue(value, PropertyTypeString)
+		if err != nil {
+			return err
+		}
+		return xml.EscapeText(payload, []byte(text.(string)))
+	default:
+		text, err := ConvertPropertyValue(value, PropertyTypeString)
+		if err != nil {
+			return err
+		}
+		payload.WriteString(text.(string))
+	}
+	return nil
+}
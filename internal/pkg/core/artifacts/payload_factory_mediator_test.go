@@ -0,0 +1,103 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func payloadFactory(t *testing.T, mediaType, template string, arguments ...PayloadArgument) PayloadFactoryMediator {
+	segments, err := ParsePayloadTemplate(template, len(arguments))
+	require.NoError(t, err)
+	return PayloadFactoryMediator{MediaType: mediaType, Template: segments, Arguments: arguments}
+}
+
+func TestParsePayloadTemplate(t *testing.T) {
+	segments, err := ParsePayloadTemplate(`{"price": "$$$1", "id": $2}`, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []PayloadSegment{
+		{Literal: `{"price": "$`, Argument: 1},
+		{Literal: `", "id": `, Argument: 2},
+		{Literal: `}`},
+	}, segments)
+
+	segments, err = ParsePayloadTemplate(`cost in $ and $`, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []PayloadSegment{{Literal: `cost in $ and $`}}, segments)
+
+	_, err = ParsePayloadTemplate(`{"id": $3}`, 2)
+	assert.EqualError(t, err, "placeholder $3 refers to a missing argument, 2 declared")
+	_, err = ParsePayloadTemplate(`{"id": $0}`, 2)
+	assert.Error(t, err)
+}
+
+func TestPayloadFactoryMediator_JSON(t *testing.T) {
+	msg := evalMessage(`{"lines":[{"sku":"A-1","qty":2}],"note":"say \"hi\""}`)
+	msg.Headers["Content-Length"] = "120"
+	mediator := payloadFactory(t, PayloadMediaTypeJSON, `{"orderId": $1, "tenant": "$2", "items": $3, "note": "$4", "count": $5}`,
+		PayloadArgument{Expression: propertyExpression(t, `number(params.orderId)`)},
+		PayloadArgument{Value: "acme"},
+		PayloadArgument{Expression: propertyExpression(t, `payload.lines`)},
+		PayloadArgument{Expression: propertyExpression(t, `payload.note`)},
+		PayloadArgument{Expression: propertyExpression(t, `len(payload.lines)`)},
+	)
+	ok, err := mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"orderId":42,"tenant":"acme","items":[{"sku":"A-1","qty":2}],"note":"say \"hi\"","count":1}`,
+		string(msg.Message.RawPayload))
+	assert.Equal(t, "application/json", msg.Message.ContentType)
+	assert.Equal(t, "application/json", msg.Headers["Content-Type"])
+	assert.NotContains(t, msg.Headers, "Content-Length")
+}
+
+func TestPayloadFactoryMediator_XML(t *testing.T) {
+	msg := evalMessage(`{"customer":"Tom & Jerry","total":12.5}`)
+	mediator := payloadFactory(t, PayloadMediaTypeXML, `<order><customer>$1</customer><total>$2</total></order>`,
+		PayloadArgument{Expression: propertyExpression(t, `payload.customer`)},
+		PayloadArgument{Expression: propertyExpression(t, `payload.total`)},
+	)
+	ok, err := mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `<order><customer>Tom &amp; Jerry</customer><total>12.5</total></order>`, string(msg.Message.RawPayload))
+	assert.Equal(t, "application/xml", msg.Message.ContentType)
+}
+
+func TestPayloadFactoryMediator_Failures(t *testing.T) {
+	msg := evalMessage(`{"id":"A-1"}`)
+	ok, err := payloadFactory(t, PayloadMediaTypeJSON, `{"id": $1}`,
+		PayloadArgument{Expression: propertyExpression(t, `payload.id`)}).Execute(msg)
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "not valid JSON")
+	assert.Equal(t, ErrorCodePayloadFactoryFailed, msg.Properties[ErrorCodeProperty])
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, `{"id":"A-1"}`, string(msg.Message.RawPayload), "the payload is kept")
+
+	ok, err = payloadFactory(t, PayloadMediaTypeText, `$1`,
+		PayloadArgument{Expression: propertyExpression(t, `payload.id - 1`)}).Execute(msg)
+	assert.False(t, ok)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeExpressionFailed, msg.Properties[ErrorCodeProperty])
+}
@@ -0,0 +1,87 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/masking"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Plan is the executable form of a sequence. The name and configuration
+// position of every mediator are resolved once at deployment, so executing a
+// message needs no reflection.
+type Plan struct {
+	sequence string
+	steps    []planStep
+}
+
+type planStep struct {
+	mediator Mediator
+	name     string
+	position Position
+}
+
+func compileSequence(sequence *Sequence) *Plan {
+	plan := &Plan{sequence: sequence.Name, steps: make([]planStep, len(sequence.MediatorList))}
+	for i, mediator := range sequence.MediatorList {
+		plan.steps[i] = planStep{mediator: mediator, name: mediatorName(mediator), position: mediatorPosition(mediator)}
+	}
+	return plan
+}
+
+// Execute runs the mediators in order until one of them stops the flow, a
+// respond or drop mediator ends mediation successfully, or a loopback
+// mediator ends the in sequence
+func (p *Plan) Execute(context *synctx.MsgContext) bool {
+	trace := capture.TraceFromContext(context)
+	for _, step := range p.steps {
+		var start time.Time
+		if trace != nil {
+			start = time.Now()
+		}
+		result, err := executeMediator(step, p.sequence, context)
+		if trace != nil {
+			trace.Record(captureStep(p.sequence, step, result, err, time.Since(start), context), masking.FromContext(context))
+		}
+		if !result {
+			return false
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
+		if Responding(context) || Dropped(context) || LoopingBack(context) {
+			return true
+		}
+	}
+	return true
+}
+
+// Compile compiles the in, out and fault sequences of every resource
+func (a *API) Compile() {
+	for i := range a.Resources {
+		a.Resources[i].InSequence.Compile()
+		a.Resources[i].OutSequence.Compile()
+		a.Resources[i].FaultSequence.Compile()
+	}
+}
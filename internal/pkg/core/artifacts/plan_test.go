@@ -0,0 +1,74 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+)
+
+type appendMediator struct {
+	value    string
+	Position Position
+}
+
+func (m appendMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	context.Message.RawPayload = append(context.Message.RawPayload, m.value...)
+	return true, nil
+}
+
+func TestSequence_Compile(t *testing.T) {
+	sequence := Sequence{Name: "Orders", MediatorList: []Mediator{
+		appendMediator{value: "a", Position: Position{FileName: "Orders.xml", LineNo: 3}},
+		&appendMediator{value: "b"},
+	}}
+	sequence.Compile()
+
+	if assert.NotNil(t, sequence.plan) {
+		assert.Equal(t, "Orders", sequence.plan.sequence)
+		assert.Equal(t, []string{"appendMediator", "appendMediator"}, []string{sequence.plan.steps[0].name, sequence.plan.steps[1].name})
+		assert.Equal(t, Position{FileName: "Orders.xml", LineNo: 3}, sequence.plan.steps[0].position)
+	}
+
+	context := synctx.CreateMsgContext()
+	trace := &capture.Trace{}
+	capture.AddToContext(context, trace)
+	assert.True(t, sequence.Execute(context))
+	assert.Equal(t, "ab", string(context.Message.RawPayload))
+	if assert.Len(t, trace.Steps(), 2) {
+		assert.Equal(t, "appendMediator", trace.Steps()[1].Mediator)
+	}
+}
+
+func TestAPI_Compile(t *testing.T) {
+	api := API{Resources: []Resource{
+		{InSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "a"}}}},
+		{InSequence: Sequence{}, FaultSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "f"}}}},
+	}}
+	api.Compile()
+	for _, resource := range api.Resources {
+		assert.NotNil(t, resource.InSequence.plan)
+		assert.NotNil(t, resource.FaultSequence.plan)
+	}
+	assert.Len(t, api.Resources[1].FaultSequence.plan.steps, 1)
+}
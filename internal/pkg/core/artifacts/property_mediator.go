@@ -0,0 +1,193 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Property scopes. Default properties live on the message context for later
+// mediators; transport properties are the headers written to the response.
+const (
+	PropertyScopeDefault   = "default"
+	PropertyScopeTransport = "transport"
+)
+
+// Property actions
+const (
+	PropertyActionSet    = "set"
+	PropertyActionRemove = "remove"
+)
+
+// Property value types. Values of other types are converted when they are set.
+const (
+	PropertyTypeString  = "string"
+	PropertyTypeInteger = "integer"
+	PropertyTypeDouble  = "double"
+	PropertyTypeBoolean = "boolean"
+	PropertyTypeJSON    = "json"
+)
+
+// PropertyMediator sets or removes a property of the message. Set values are
+// either a constant or the result of an expression over ExpressionVariables,
+// so the mediator also reads properties, headers and the payload into a
+// property. Setting HTTP_SC to an integer chooses the response status.
+type PropertyMediator struct {
+	Name   string
+	Action string
+	Scope  string
+	Type   string
+	// Value is the constant to set, already converted to Type. It is unused
+	// when Expression is set.
+	Value      any
+	Expression *expression.Program
+	Position   Position
+}
+
+func (pm PropertyMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	if pm.Action == PropertyActionRemove {
+		if pm.Scope == PropertyScopeTransport {
+			for name := range context.Headers {
+				if strings.EqualFold(name, pm.Name) {
+					delete(context.Headers, name)
+				}
+			}
+			return true, nil
+		}
+		delete(context.Properties, pm.Name)
+		return true, nil
+	}
+
+	value := pm.Value
+	if pm.Expression != nil {
+		env, err := expressionEnv(context)
+		if err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+			return fail(context, ErrorCodeExpressionFailed, err)
+		}
+		result, err := pm.Expression.Run(env)
+		if err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(context, ErrorCodeExpressionFailed, err)
+		}
+		if value, err = ConvertPropertyValue(result, pm.Type); err != nil {
+			context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+			return fail(context, ErrorCodeExpressionFailed, fmt.Errorf("property %s: %w", pm.Name, err))
+		}
+	}
+	if pm.Scope == PropertyScopeTransport {
+		header, _ := ConvertPropertyValue(value, PropertyTypeString)
+		context.Headers[http.CanonicalHeaderKey(pm.Name)] = header.(string)
+		return true, nil
+	}
+	context.Properties[pm.Name] = value
+	return true, nil
+}
+
+// IsPropertyType reports whether typ is one of the property value types
+func IsPropertyType(typ string) bool {
+	switch typ {
+	case PropertyTypeString, PropertyTypeInteger, PropertyTypeDouble, PropertyTypeBoolean, PropertyTypeJSON:
+		return true
+	}
+	return false
+}
+
+// ConvertPropertyValue converts value to a property type. Strings are parsed
+// for the other types; numbers, booleans and JSON values are formatted for
+// strings.
+func ConvertPropertyValue(value any, typ string) (any, error) {
+	switch typ {
+	case PropertyTypeString, "":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case nil:
+			return "", nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case map[string]any, []any:
+			b, err := json.Marshal(v)
+			return string(b), err
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case PropertyTypeInteger:
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("%v is not an integer", v)
+			}
+			return int(v), nil
+		case string:
+			i, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not an integer", v)
+			}
+			return i, nil
+		}
+	case PropertyTypeDouble:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not a number", v)
+			}
+			return f, nil
+		}
+	case PropertyTypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not a boolean", v)
+			}
+			return b, nil
+		}
+	case PropertyTypeJSON:
+		if s, ok := value.(string); ok {
+			var decoded any
+			if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+				return nil, fmt.Errorf("'%s' is not JSON: %w", s, err)
+			}
+			return decoded, nil
+		}
+		return expression.Normalize(value), nil
+	default:
+		return nil, fmt.Errorf("unknown property type '%s'", typ)
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", value, typ)
+}
@@ -0,0 +1,123 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func propertyExpression(t *testing.T, source string) *expression.Program {
+	program, err := expression.Compile(source, ExpressionVariables...)
+	require.NoError(t, err)
+	return program
+}
+
+func TestPropertyMediator_Set(t *testing.T) {
+	tests := []struct {
+		name     string
+		mediator PropertyMediator
+		want     any
+	}{
+		{name: "constant", mediator: PropertyMediator{Name: "p", Value: 3}, want: 3},
+		{name: "expression", mediator: PropertyMediator{Name: "p", Type: PropertyTypeString,
+			Expression: propertyExpression(t, `upper(headers['x-tenant']) + '-' + params.orderId`)}, want: "ACME-42"},
+		{name: "expression as integer", mediator: PropertyMediator{Name: "p", Type: PropertyTypeInteger,
+			Expression: propertyExpression(t, `len(payload.items) * 2`)}, want: 4},
+		{name: "string of JSON", mediator: PropertyMediator{Name: "p", Type: PropertyTypeString,
+			Expression: propertyExpression(t, `payload.items`)}, want: `[1,2]`},
+		{name: "read property", mediator: PropertyMediator{Name: "p", Type: PropertyTypeDouble,
+			Expression: propertyExpression(t, `properties.HTTP_SC`)}, want: 200.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := evalMessage(`{"items":[1,2]}`)
+			ok, err := tt.mediator.Execute(msg)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, msg.Properties["p"])
+		})
+	}
+}
+
+func TestPropertyMediator_Transport(t *testing.T) {
+	msg := evalMessage(`{}`)
+	msg.Headers["X-Internal"] = "secret"
+
+	ok, err := PropertyMediator{Name: "x-order-id", Scope: PropertyScopeTransport, Type: PropertyTypeString,
+		Expression: propertyExpression(t, `params.orderId`)}.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = PropertyMediator{Name: "x-internal", Scope: PropertyScopeTransport, Action: PropertyActionRemove}.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"X-Order-Id": "42"}, msg.Headers)
+}
+
+func TestPropertyMediator_Remove(t *testing.T) {
+	msg := evalMessage(`{}`)
+	msg.Properties["token"] = "secret"
+	ok, err := PropertyMediator{Name: "token", Scope: PropertyScopeDefault, Action: PropertyActionRemove}.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotContains(t, msg.Properties, "token")
+}
+
+func TestPropertyMediator_ConversionFails(t *testing.T) {
+	msg := evalMessage(`{"total":"many"}`)
+	ok, err := PropertyMediator{Name: "p", Type: PropertyTypeInteger, Expression: propertyExpression(t, `payload.total`)}.Execute(msg)
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "'many' is not an integer")
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, ErrorCodeExpressionFailed, msg.Properties[ErrorCodeProperty])
+}
+
+func TestConvertPropertyValue(t *testing.T) {
+	tests := []struct {
+		value   any
+		typ     string
+		want    any
+		wantErr string
+	}{
+		{value: 1.5, typ: PropertyTypeString, want: "1.5"},
+		{value: true, typ: PropertyTypeString, want: "true"},
+		{value: nil, typ: PropertyTypeString, want: ""},
+		{value: "7", typ: PropertyTypeInteger, want: 7},
+		{value: 7.5, typ: PropertyTypeInteger, wantErr: "7.5 is not an integer"},
+		{value: "2.5", typ: PropertyTypeDouble, want: 2.5},
+		{value: "TRUE", typ: PropertyTypeBoolean, want: true},
+		{value: 1.0, typ: PropertyTypeBoolean, wantErr: "cannot convert float64 to boolean"},
+		{value: `{"a":1}`, typ: PropertyTypeJSON, want: map[string]any{"a": 1.0}},
+		{value: `{`, typ: PropertyTypeJSON, wantErr: "is not JSON"},
+	}
+	for _, tt := range tests {
+		got, err := ConvertPropertyValue(tt.value, tt.typ)
+		if tt.wantErr != "" {
+			assert.ErrorContains(t, err, tt.wantErr)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	// ErrorCodeMediatorPanic is set when a mediator panics during execution
+	ErrorCodeMediatorPanic = "MEDIATOR_PANIC"
+
+	// PanicMetric counts recovered panics by the component that raised them
+	PanicMetric = "synapse_panics"
+)
+
+// MediatorPanic is the error a mediator panic is converted into
+type MediatorPanic struct {
+	Sequence string
+	Mediator string
+	Position Position
+	Value    any
+	Stack    []byte
+}
+
+func (p *MediatorPanic) Error() string {
+	return fmt.Sprintf("mediator %s panicked at %s: %v", p.Mediator, formatPosition(p.Position), p.Value)
+}
+
+// executeMediator runs a plan step, converting a panic into a failed result so
+// the fault sequence runs instead of the server crashing
+func executeMediator(step planStep, sequence string, context *synctx.MsgContext) (result bool, err error) {
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+		p := &MediatorPanic{
+			Sequence: sequence,
+			Mediator: step.name,
+			Position: step.position,
+			Value:    value,
+			Stack:    debug.Stack(),
+		}
+		loggerfactory.GetLogger("mediation", nil).Error("Recovered from mediator panic",
+			slog.String("sequence", sequence),
+			slog.String("mediator", p.Mediator),
+			slog.String("position", formatPosition(p.Position)),
+			slog.String("panic", fmt.Sprint(value)),
+			slog.String("stack", string(p.Stack)))
+		metrics.Default().Counter(PanicMetric, "Panics recovered by the runtime.", "component").Inc(p.Mediator)
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		result, err = fail(context, ErrorCodeMediatorPanic, p)
+	}()
+	return step.mediator.Execute(context)
+}
+
+// mediatorPosition returns the configuration position of a mediator. Every
+// mediator keeps it in a Position field, which is read when the sequence is
+// compiled.
+func mediatorPosition(mediator Mediator) Position {
+	value := reflect.Indirect(reflect.ValueOf(mediator))
+	if value.Kind() != reflect.Struct {
+		return Position{}
+	}
+	if field := value.FieldByName("Position"); field.IsValid() {
+		if position, ok := field.Interface().(Position); ok {
+			return position
+		}
+	}
+	return Position{}
+}
+
+func formatPosition(position Position) string {
+	if position.FileName == "" {
+		return "unknown position"
+	}
+	if position.Hierarchy != "" {
+		return fmt.Sprintf("%s:%d (%s)", position.FileName, position.LineNo, position.Hierarchy)
+	}
+	return fmt.Sprintf("%s:%d", position.FileName, position.LineNo)
+}
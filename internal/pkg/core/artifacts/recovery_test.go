@@ -0,0 +1,89 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	// Recovered panics are logged through the logger factory
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{
+		Format:     "text",
+		OutputPath: "stdout",
+	})
+}
+
+type panickingMediator struct {
+	Position Position
+}
+
+func (panickingMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	panic("nil dereference")
+}
+
+type recordingMediator struct {
+	executed *bool
+}
+
+func (m recordingMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	*m.executed = true
+	return true, nil
+}
+
+func TestSequence_MediatorPanicRunsFaultSequence(t *testing.T) {
+	counter := metrics.Default().Counter(PanicMetric, "", "component")
+	before := counter.Value("panickingMediator")
+
+	var faultExecuted bool
+	resource := Resource{
+		InSequence: Sequence{Name: "OrdersIn", MediatorList: []Mediator{
+			panickingMediator{Position: Position{FileName: "OrdersAPI.xml", LineNo: 12, Hierarchy: "api->resource->inSequence"}},
+		}},
+		FaultSequence: Sequence{MediatorList: []Mediator{recordingMediator{executed: &faultExecuted}}},
+	}
+	context := synctx.CreateMsgContext()
+
+	assert.True(t, resource.Mediate(context))
+	assert.True(t, faultExecuted)
+	assert.Equal(t, ErrorCodeMediatorPanic, context.Properties[ErrorCodeProperty])
+	assert.Equal(t, "mediator panickingMediator panicked at OrdersAPI.xml:12 (api->resource->inSequence): nil dereference",
+		context.Properties[ErrorMessageProperty])
+	assert.Equal(t, before+1, counter.Value("panickingMediator"))
+}
+
+func TestSequence_MediatorPanicWithoutFaultSequence(t *testing.T) {
+	resource := Resource{InSequence: Sequence{MediatorList: []Mediator{panickingMediator{}}}}
+	assert.False(t, resource.Mediate(synctx.CreateMsgContext()))
+}
+
+func TestExecuteMediator_ReturnsPanicError(t *testing.T) {
+	_, err := executeMediator(planStep{mediator: panickingMediator{}, name: "panickingMediator"}, "OrdersIn", synctx.CreateMsgContext())
+	var mediatorPanic *MediatorPanic
+	assert.True(t, errors.As(err, &mediatorPanic))
+	assert.Equal(t, "OrdersIn", mediatorPanic.Sequence)
+	assert.NotEmpty(t, mediatorPanic.Stack)
+}
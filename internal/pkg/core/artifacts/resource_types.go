@@ -0,0 +1,99 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Resource types. Echo and static resources answer without a sequence, for
+// health checks, contract stubs and quick integration tests.
+const (
+	ResourceTypeEcho   = "echo"
+	ResourceTypeStatic = "static"
+)
+
+const ErrorCodeEchoFailed = "ECHO_FAILED"
+
+// echoSkippedHeaders are request headers an echo resource does not return:
+// those describing the connection or the request body, and credentials
+var echoSkippedHeaders = map[string]bool{
+	"Authorization": true, "Connection": true, "Content-Length": true, "Cookie": true, "Host": true,
+	"Keep-Alive": true, "Proxy-Authorization": true, "Te": true, "Trailer": true, "Transfer-Encoding": true,
+	"Upgrade": true,
+}
+
+// EchoMediator answers with the payload and headers of the request. It is the
+// in sequence of echo resources.
+type EchoMediator struct {
+	Position Position
+}
+
+func (em EchoMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	payload, err := messagePayload(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeEchoFailed, fmt.Errorf("echo: cannot read payload: %w", err))
+	}
+	if headers, ok := context.Properties["http_request_headers"].(map[string]string); ok {
+		for name, value := range headers {
+			if !echoSkippedHeaders[http.CanonicalHeaderKey(name)] {
+				context.Headers[name] = value
+			}
+		}
+	}
+	contentType := context.Message.ContentType
+	if contentType == "" {
+		contentType = requestHeader(context, "Content-Type")
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	setPayload(context, payload, contentType)
+	return true, nil
+}
+
+// StaticResponseMediator answers with a fixed status, headers and payload. It
+// is the in sequence of static resources.
+type StaticResponseMediator struct {
+	Status      int
+	ContentType string
+	Headers     map[string]string
+	Payload     string
+	Position    Position
+}
+
+func (sm StaticResponseMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	for name, value := range sm.Headers {
+		for existing := range context.Headers {
+			if strings.EqualFold(existing, name) {
+				delete(context.Headers, existing)
+			}
+		}
+		context.Headers[name] = value
+	}
+	context.Properties[HTTPStatusProperty] = sm.Status
+	setPayload(context, []byte(sm.Payload), sm.ContentType)
+	return true, nil
+}
@@ -0,0 +1,52 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEchoMediator_Execute(t *testing.T) {
+	msg := evalMessage(`{"id":1}`)
+	msg.Properties["http_request_headers"] = map[string]string{
+		"Content-Type": "application/json", "X-Tenant": "acme", "Authorization": "Bearer secret", "Content-Length": "8",
+	}
+	ok, err := EchoMediator{}.Execute(msg)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, string(msg.Message.RawPayload))
+	assert.Equal(t, map[string]string{"Content-Type": "application/json", "X-Tenant": "acme"}, msg.Headers,
+		"credentials and connection headers are not echoed")
+}
+
+func TestStaticResponseMediator_Execute(t *testing.T) {
+	msg := evalMessage(`{"id":1}`)
+	msg.Headers["cache-control"] = "max-age=60"
+	ok, err := StaticResponseMediator{Status: http.StatusServiceUnavailable, ContentType: "application/json",
+		Headers: map[string]string{"Cache-Control": "no-store"}, Payload: `{"status":"DOWN"}`}.Execute(msg)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, `{"status":"DOWN"}`, string(msg.Message.RawPayload))
+	assert.Equal(t, map[string]string{"Cache-Control": "no-store", "Content-Type": "application/json"}, msg.Headers)
+}
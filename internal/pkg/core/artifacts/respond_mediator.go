@@ -0,0 +1,43 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import "github.com/apache/synapse-go/internal/pkg/core/synctx"
+
+// RespondProperty is set by the respond mediator to end mediation
+const RespondProperty = "RESPOND"
+
+// RespondMediator ends mediation and has the current payload, headers and
+// HTTP_SC status written to the client. The mediators after it, including
+// those of enclosing sequences, are not executed.
+type RespondMediator struct {
+	Position Position
+}
+
+func (rm RespondMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	context.Properties[RespondProperty] = true
+	return true, nil
+}
+
+// Responding reports whether a respond mediator has ended mediation
+func Responding(context *synctx.MsgContext) bool {
+	respond, _ := context.Properties[RespondProperty].(bool)
+	return respond
+}
@@ -0,0 +1,63 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondMediator_Execute(t *testing.T) {
+	cached := switchRegex(t, "true")
+	cached.Sequence = Sequence{MediatorList: []Mediator{appendMediator{value: "cached"}, RespondMediator{}, appendMediator{value: "-case"}}}
+	tests := []struct {
+		name        string
+		payload     string
+		wantPayload string
+	}{
+		{name: "responds from a branch", payload: `{"cached":"true"}`, wantPayload: `{"cached":"true"}cached`},
+		{name: "continues without respond", payload: `{"cached":"false"}`, wantPayload: `{"cached":"false"}-after`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := Resource{
+				InSequence: Sequence{MediatorList: []Mediator{
+					SwitchMediator{Source: propertyExpression(t, "payload.cached"), Cases: []SwitchCase{cached}},
+					appendMediator{value: "-after"},
+				}},
+				FaultSequence: Sequence{MediatorList: []Mediator{appendMediator{value: "-fault"}}},
+			}
+			context := evalMessage(tt.payload)
+			assert.True(t, resource.Mediate(context))
+			assert.Equal(t, tt.wantPayload, string(context.Message.RawPayload))
+		})
+	}
+
+	context := synctx.CreateMsgContext()
+	context.Properties[HTTPStatusProperty] = http.StatusAccepted
+	sequence := Sequence{MediatorList: []Mediator{RespondMediator{}, appendMediator{value: "unreachable"}}}
+	assert.True(t, sequence.Execute(context))
+	assert.True(t, Responding(context))
+	assert.Empty(t, context.Message.RawPayload)
+	assert.Equal(t, http.StatusAccepted, context.Properties[HTTPStatusProperty])
+}
@@ -0,0 +1,172 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	ErrorCodeSagaFailed = "SAGA_FAILED"
+	// SagaFailedStepProperty names the step that failed a saga
+	SagaFailedStepProperty = "SAGA_FAILED_STEP"
+)
+
+// EndpointSender delivers a message to an endpoint over its protocol
+type EndpointSender func(ctx context.Context, endpoint Endpoint, msg *synctx.MsgContext) error
+
+// SagaAction sends a message to a deployed endpoint
+type SagaAction struct {
+	Endpoint string
+	// Payload replaces the message payload when set
+	Payload     *template.Template
+	ContentType string
+}
+
+// SagaStep is an action of a saga and the compensation that undoes it
+type SagaStep struct {
+	Name   string
+	Action SagaAction
+	// Compensation is nil for steps that need no undoing, e.g. reads
+	Compensation *SagaAction
+}
+
+// SagaMediator runs its steps in order. The response of each step becomes
+// the message payload, so later steps and compensations can refer to it, e.g.
+// {{.JSON "$.reservationId"}}. Each compensation is rendered when its step
+// succeeds. When a step fails, the recorded compensations run in reverse
+// order and the flow fails with a 502 status, or runs the fault sequence.
+type SagaMediator struct {
+	Name     string
+	Steps    []SagaStep
+	Send     EndpointSender
+	Position Position
+}
+
+// compensation is a rendered compensation waiting for a later step to fail
+type compensation struct {
+	step     string
+	endpoint Endpoint
+	msg      *synctx.MsgContext
+}
+
+func (sm SagaMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	endpoints := SnapshotFromContext(msgContext).Endpoints
+	var compensations []compensation
+	for _, step := range sm.Steps {
+		if err := sm.perform(endpoints, step.Action, msgContext); err != nil {
+			return sm.compensate(msgContext, step.Name, err, compensations)
+		}
+		if step.Compensation == nil {
+			continue
+		}
+		// Compensations are rendered now, while the step's response is the payload
+		endpoint, ok := endpoints[step.Compensation.Endpoint]
+		if !ok {
+			return sm.compensate(msgContext, step.Name, fmt.Errorf("compensation endpoint %s is not deployed", step.Compensation.Endpoint), compensations)
+		}
+		undo := synctx.CreateMsgContext()
+		maps.Copy(undo.Properties, msgContext.Properties)
+		undo.Message = msgContext.Message
+		if err := renderPayload(step.Compensation, msgContext, undo); err != nil {
+			return sm.compensate(msgContext, step.Name, err, compensations)
+		}
+		compensations = append(compensations, compensation{step: step.Name, endpoint: endpoint, msg: undo})
+	}
+	return true, nil
+}
+
+// perform sends the message to the action's endpoint. A response status of
+// 400 or above fails the step.
+func (sm SagaMediator) perform(endpoints map[string]Endpoint, action SagaAction, msg *synctx.MsgContext) error {
+	endpoint, ok := endpoints[action.Endpoint]
+	if !ok {
+		return fmt.Errorf("endpoint %s is not deployed", action.Endpoint)
+	}
+	if err := renderPayload(&action, msg, msg); err != nil {
+		return err
+	}
+	delete(msg.Properties, HTTPStatusProperty)
+	if err := sm.Send(context.Background(), endpoint, msg); err != nil {
+		return err
+	}
+	if status, ok := msg.Properties[HTTPStatusProperty].(int); ok && status >= http.StatusBadRequest {
+		return fmt.Errorf("endpoint %s responded with status %d", action.Endpoint, status)
+	}
+	return nil
+}
+
+// renderPayload sets the payload of target from the action's template over source
+func renderPayload(action *SagaAction, source, target *synctx.MsgContext) error {
+	if action.Payload == nil {
+		return nil
+	}
+	var out bytes.Buffer
+	if err := action.Payload.Execute(&out, ValidationRequest{context: source}); err != nil {
+		return fmt.Errorf("payload for endpoint %s could not be rendered: %w", action.Endpoint, err)
+	}
+	target.Message = synctx.Message{RawPayload: out.Bytes(), ContentType: action.ContentType}
+	return nil
+}
+
+// compensate undoes the completed steps, latest first, and fails the flow
+func (sm SagaMediator) compensate(msgContext *synctx.MsgContext, failedStep string, cause error, compensations []compensation) (bool, error) {
+	logger := loggerfactory.GetLogger("mediation", nil)
+	var compensated []string
+	var errs []error
+	for i := len(compensations) - 1; i >= 0; i-- {
+		undo := compensations[i]
+		err := sm.Send(context.Background(), undo.endpoint, undo.msg)
+		if status, ok := undo.msg.Properties[HTTPStatusProperty].(int); err == nil && ok && status >= http.StatusBadRequest {
+			err = fmt.Errorf("endpoint %s responded with status %d", undo.endpoint.Name, status)
+		}
+		if err != nil {
+			logger.Error("Saga compensation failed",
+				slog.String("saga", sm.Name),
+				slog.String("step", undo.step),
+				slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("compensation of %s failed: %w", undo.step, err))
+			continue
+		}
+		compensated = append(compensated, undo.step)
+	}
+
+	message := fmt.Sprintf("saga %s: step %s failed: %v", sm.Name, failedStep, cause)
+	if len(compensated) > 0 {
+		message += "; compensated " + strings.Join(compensated, ", ")
+	}
+	if len(errs) > 0 {
+		message += "; " + joinErrors(errs).Error()
+	}
+	msgContext.Properties[SagaFailedStepProperty] = failedStep
+	msgContext.Properties[HTTPStatusProperty] = http.StatusBadGateway
+	return fail(msgContext, ErrorCodeSagaFailed, errors.New(message))
+}
@@ -0,0 +1,143 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"text/template"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sagaBackend answers saga steps from canned responses and records the calls
+type sagaBackend struct {
+	responses map[string]string
+	statuses  map[string]int
+	calls     []string
+}
+
+func (b *sagaBackend) send(ctx context.Context, endpoint Endpoint, msg *synctx.MsgContext) error {
+	b.calls = append(b.calls, endpoint.Name+" "+string(msg.Message.RawPayload))
+	if endpoint.Name == "UnreachableEP" {
+		return errors.New("connection refused")
+	}
+	msg.Message.RawPayload = []byte(b.responses[endpoint.Name])
+	msg.Properties[HTTPStatusProperty] = http.StatusOK
+	if status, ok := b.statuses[endpoint.Name]; ok {
+		msg.Properties[HTTPStatusProperty] = status
+	}
+	return nil
+}
+
+func sagaPayload(text string) *template.Template {
+	return template.Must(template.New("").Funcs(PreconditionFuncs).Parse(text))
+}
+
+func sagaMessage() *synctx.MsgContext {
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte(`{"sku":"A1","amount":10}`)
+	snapshot := &Snapshot{Endpoints: map[string]Endpoint{}}
+	for _, name := range []string{"InventoryEP", "ReleaseEP", "PaymentEP", "RefundEP", "ShippingEP", "UnreachableEP"} {
+		snapshot.Endpoints[name] = Endpoint{Name: name}
+	}
+	msg.Properties[SnapshotProperty] = snapshot
+	return msg
+}
+
+func placeOrderSaga(backend *sagaBackend, shippingEndpoint string) SagaMediator {
+	return SagaMediator{
+		Name: "PlaceOrder",
+		Send: backend.send,
+		Steps: []SagaStep{
+			{
+				Name:   "reserve",
+				Action: SagaAction{Endpoint: "InventoryEP"},
+				Compensation: &SagaAction{Endpoint: "ReleaseEP",
+					Payload: sagaPayload(`{"reservation":"{{.JSON "$.id"}}"}`), ContentType: "application/json"},
+			},
+			{
+				Name:   "charge",
+				Action: SagaAction{Endpoint: "PaymentEP", Payload: sagaPayload(`{"amount":10}`)},
+				Compensation: &SagaAction{Endpoint: "RefundEP",
+					Payload: sagaPayload(`{"payment":"{{.JSON "$.id"}}"}`)},
+			},
+			{Name: "ship", Action: SagaAction{Endpoint: shippingEndpoint}},
+		},
+	}
+}
+
+func TestSagaMediator_Completes(t *testing.T) {
+	backend := &sagaBackend{responses: map[string]string{"InventoryEP": `{"id":"r-1"}`, "PaymentEP": `{"id":"p-1"}`, "ShippingEP": `{"tracking":"t-1"}`}}
+	msg := sagaMessage()
+
+	ok, err := placeOrderSaga(backend, "ShippingEP").Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		`InventoryEP {"sku":"A1","amount":10}`,
+		`PaymentEP {"amount":10}`,
+		`ShippingEP {"id":"p-1"}`,
+	}, backend.calls)
+	assert.Equal(t, `{"tracking":"t-1"}`, string(msg.Message.RawPayload))
+}
+
+func TestSagaMediator_CompensatesInReverse(t *testing.T) {
+	backend := &sagaBackend{
+		responses: map[string]string{"InventoryEP": `{"id":"r-1"}`, "PaymentEP": `{"id":"p-1"}`},
+		statuses:  map[string]int{"ShippingEP": http.StatusServiceUnavailable},
+	}
+	msg := sagaMessage()
+
+	ok, err := placeOrderSaga(backend, "ShippingEP").Execute(msg)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "saga PlaceOrder: step ship failed: endpoint ShippingEP responded with status 503; compensated charge, reserve")
+	assert.Equal(t, []string{
+		`InventoryEP {"sku":"A1","amount":10}`,
+		`PaymentEP {"amount":10}`,
+		`ShippingEP {"id":"p-1"}`,
+		`RefundEP {"payment":"p-1"}`,
+		`ReleaseEP {"reservation":"r-1"}`,
+	}, backend.calls)
+	assert.Equal(t, "ship", msg.Properties[SagaFailedStepProperty])
+	assert.Equal(t, http.StatusBadGateway, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, ErrorCodeSagaFailed, msg.Properties[ErrorCodeProperty])
+}
+
+func TestSagaMediator_ReportsFailedCompensations(t *testing.T) {
+	backend := &sagaBackend{
+		responses: map[string]string{"InventoryEP": `{"id":"r-1"}`, "PaymentEP": `{"id":"p-1"}`},
+		statuses:  map[string]int{"RefundEP": http.StatusInternalServerError},
+	}
+	msg := sagaMessage()
+
+	ok, err := placeOrderSaga(backend, "UnreachableEP").Execute(msg)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "saga PlaceOrder: step ship failed: connection refused; compensated reserve; "+
+		"compensation of charge failed: endpoint RefundEP responded with status 500")
+
+	ok, err = placeOrderSaga(backend, "MissingEP").Execute(sagaMessage())
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "step ship failed: endpoint MissingEP is not deployed")
+}
@@ -0,0 +1,185 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	ErrorCodeScatterGatherFailed = "SCATTER_GATHER_FAILED"
+	// ScatterGatherStatusProperty prefixes the outcome of each branch, e.g.
+	// SCATTER_GATHER_STATUS.flights is succeeded or failed
+	ScatterGatherStatusProperty = "SCATTER_GATHER_STATUS."
+	// ScatterGatherFailedProperty lists the failed branches, comma separated
+	ScatterGatherFailedProperty = "SCATTER_GATHER_FAILED"
+)
+
+// Branch outcomes recorded under ScatterGatherStatusProperty
+const (
+	BranchSucceeded = "succeeded"
+	BranchFailed    = "failed"
+)
+
+// Policies for assembling a response when some branches fail
+const (
+	// PartialFailureFail fails the whole request
+	PartialFailureFail = "fail"
+	// PartialFailurePlaceholder puts an error object in place of the response
+	PartialFailurePlaceholder = "placeholder"
+	// PartialFailureDrop leaves the failed branches out of the response
+	PartialFailureDrop = "drop"
+)
+
+// ScatterGatherBranch sends a copy of the message to one endpoint
+type ScatterGatherBranch struct {
+	Name   string
+	Action SagaAction
+}
+
+// ScatterGatherMediator sends a copy of the message to every branch at once
+// and replaces the payload with a JSON object of their responses keyed by
+// branch name. Responses that are not JSON are included as strings. A branch
+// fails when its endpoint cannot be reached, answers with a status of 400 or
+// above, or does not answer within Timeout; OnPartialFailure decides what
+// the response holds then. The flow fails with a 502 status when every
+// branch fails, whatever the policy.
+type ScatterGatherMediator struct {
+	Name     string
+	Branches []ScatterGatherBranch
+	Timeout  time.Duration
+	// OnPartialFailure is one of the PartialFailure policies
+	OnPartialFailure string
+	Send             EndpointSender
+	Position         Position
+}
+
+// branchResult is the outcome of one branch
+type branchResult struct {
+	response json.RawMessage
+	status   int
+	err      error
+}
+
+// branchError is the placeholder of a failed branch
+type branchError struct {
+	Error struct {
+		Status  int    `json:"status,omitempty"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (sg ScatterGatherMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	endpoints := SnapshotFromContext(msgContext).Endpoints
+	ctx := context.Background()
+	if sg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sg.Timeout)
+		defer cancel()
+	}
+
+	results := make([]branchResult, len(sg.Branches))
+	var wg sync.WaitGroup
+	for i, branch := range sg.Branches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = sg.scatter(ctx, endpoints, branch, msgContext)
+		}()
+	}
+	wg.Wait()
+
+	aggregated := make(map[string]json.RawMessage, len(sg.Branches))
+	var failed []string
+	var errs []error
+	for i, branch := range sg.Branches {
+		result := results[i]
+		if result.err == nil {
+			msgContext.Properties[ScatterGatherStatusProperty+branch.Name] = BranchSucceeded
+			aggregated[branch.Name] = result.response
+			continue
+		}
+		msgContext.Properties[ScatterGatherStatusProperty+branch.Name] = BranchFailed
+		failed = append(failed, branch.Name)
+		errs = append(errs, fmt.Errorf("branch %s: %w", branch.Name, result.err))
+		if sg.OnPartialFailure == PartialFailurePlaceholder {
+			var placeholder branchError
+			placeholder.Error.Status = result.status
+			placeholder.Error.Message = result.err.Error()
+			aggregated[branch.Name], _ = json.Marshal(placeholder)
+		}
+	}
+	msgContext.Properties[ScatterGatherFailedProperty] = strings.Join(failed, ",")
+
+	if len(failed) == len(sg.Branches) || (len(failed) > 0 && sg.OnPartialFailure == PartialFailureFail) {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusBadGateway
+		return fail(msgContext, ErrorCodeScatterGatherFailed,
+			fmt.Errorf("scatter-gather %s: %w", sg.Name, joinErrors(errs)))
+	}
+	payload, err := json.Marshal(aggregated)
+	if err != nil {
+		return fail(msgContext, ErrorCodeScatterGatherFailed, fmt.Errorf("scatter-gather %s: %w", sg.Name, err))
+	}
+	delete(msgContext.Properties, HTTPStatusProperty)
+	msgContext.Message = synctx.Message{RawPayload: payload, ContentType: "application/json"}
+	return true, nil
+}
+
+// scatter sends a copy of the message to the branch's endpoint
+func (sg ScatterGatherMediator) scatter(ctx context.Context, endpoints map[string]Endpoint, branch ScatterGatherBranch,
+	msgContext *synctx.MsgContext) branchResult {
+	endpoint, ok := endpoints[branch.Action.Endpoint]
+	if !ok {
+		return branchResult{err: fmt.Errorf("endpoint %s is not deployed", branch.Action.Endpoint)}
+	}
+	msg := synctx.CreateMsgContext()
+	maps.Copy(msg.Properties, msgContext.Properties)
+	maps.Copy(msg.Headers, msgContext.Headers)
+	msg.Message = msgContext.Message
+	delete(msg.Properties, HTTPStatusProperty)
+	if err := renderPayload(&branch.Action, msgContext, msg); err != nil {
+		return branchResult{err: err}
+	}
+	if err := sg.Send(ctx, endpoint, msg); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("no response within %s", sg.Timeout)
+		}
+		return branchResult{err: err}
+	}
+	status, _ := msg.Properties[HTTPStatusProperty].(int)
+	if status >= http.StatusBadRequest {
+		return branchResult{status: status, err: fmt.Errorf("endpoint %s responded with status %d", endpoint.Name, status)}
+	}
+	response := json.RawMessage(msg.Message.RawPayload)
+	if !json.Valid(response) {
+		response, _ = json.Marshal(string(msg.Message.RawPayload))
+	}
+	return branchResult{response: response, status: status}
+}
@@ -0,0 +1,153 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scatterBackend answers branches concurrently from canned responses
+type scatterBackend struct {
+	mu        sync.Mutex
+	responses map[string]string
+	statuses  map[string]int
+	requests  map[string]string
+}
+
+func (b *scatterBackend) send(ctx context.Context, endpoint Endpoint, msg *synctx.MsgContext) error {
+	b.mu.Lock()
+	b.requests[endpoint.Name] = string(msg.Message.RawPayload)
+	b.mu.Unlock()
+	switch endpoint.Name {
+	case "UnreachableEP":
+		return errors.New("connection refused")
+	case "SlowEP":
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	msg.Message.RawPayload = []byte(b.responses[endpoint.Name])
+	msg.Properties[HTTPStatusProperty] = http.StatusOK
+	if status, ok := b.statuses[endpoint.Name]; ok {
+		msg.Properties[HTTPStatusProperty] = status
+	}
+	return nil
+}
+
+func scatterGather(backend *scatterBackend, policy string, endpoints ...string) ScatterGatherMediator {
+	mediator := ScatterGatherMediator{Name: "Quote", OnPartialFailure: policy, Timeout: 200 * time.Millisecond, Send: backend.send}
+	for _, endpoint := range endpoints {
+		mediator.Branches = append(mediator.Branches, ScatterGatherBranch{Name: endpoint, Action: SagaAction{Endpoint: endpoint}})
+	}
+	return mediator
+}
+
+func scatterMessage() *synctx.MsgContext {
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte(`{"destination":"Lisbon"}`)
+	endpoints := map[string]Endpoint{}
+	for _, name := range []string{"FlightsEP", "HotelsEP", "CarsEP", "UnreachableEP", "SlowEP"} {
+		endpoints[name] = Endpoint{Name: name}
+	}
+	msg.Properties[SnapshotProperty] = &Snapshot{Endpoints: endpoints}
+	return msg
+}
+
+func newScatterBackend() *scatterBackend {
+	return &scatterBackend{
+		responses: map[string]string{"FlightsEP": `{"price":120}`, "HotelsEP": `[{"name":"Alfama"}]`, "CarsEP": "sold out"},
+		statuses:  map[string]int{"CarsEP": http.StatusServiceUnavailable},
+		requests:  map[string]string{},
+	}
+}
+
+func TestScatterGatherMediator_Aggregates(t *testing.T) {
+	backend := newScatterBackend()
+	backend.responses["CarsEP"] = "compact"
+	delete(backend.statuses, "CarsEP")
+	mediator := scatterGather(backend, PartialFailureFail, "FlightsEP", "HotelsEP", "CarsEP")
+	mediator.Branches[1].Action.Payload = sagaPayload(`{"city":"{{.JSON "$.destination"}}"}`)
+
+	msg := scatterMessage()
+	ok, err := mediator.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"FlightsEP":{"price":120},"HotelsEP":[{"name":"Alfama"}],"CarsEP":"compact"}`, string(msg.Message.RawPayload))
+	assert.Equal(t, "application/json", msg.Message.ContentType)
+	assert.Equal(t, `{"destination":"Lisbon"}`, backend.requests["FlightsEP"])
+	assert.Equal(t, `{"city":"Lisbon"}`, backend.requests["HotelsEP"])
+	assert.Equal(t, BranchSucceeded, msg.Properties[ScatterGatherStatusProperty+"HotelsEP"])
+	assert.Equal(t, "", msg.Properties[ScatterGatherFailedProperty])
+}
+
+func TestScatterGatherMediator_PartialFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		wantOK      bool
+		wantPayload string
+	}{
+		{
+			name:        "placeholder",
+			policy:      PartialFailurePlaceholder,
+			wantOK:      true,
+			wantPayload: `{"FlightsEP":{"price":120},"CarsEP":{"error":{"status":503,"message":"endpoint CarsEP responded with status 503"}},"SlowEP":{"error":{"message":"no response within 200ms"}}}`,
+		},
+		{name: "drop", policy: PartialFailureDrop, wantOK: true, wantPayload: `{"FlightsEP":{"price":120}}`},
+		{name: "fail", policy: PartialFailureFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := scatterMessage()
+			ok, err := scatterGather(newScatterBackend(), tt.policy, "FlightsEP", "CarsEP", "SlowEP").Execute(msg)
+			assert.Equal(t, BranchSucceeded, msg.Properties[ScatterGatherStatusProperty+"FlightsEP"])
+			assert.Equal(t, BranchFailed, msg.Properties[ScatterGatherStatusProperty+"CarsEP"])
+			assert.Equal(t, BranchFailed, msg.Properties[ScatterGatherStatusProperty+"SlowEP"])
+			assert.Equal(t, "CarsEP,SlowEP", msg.Properties[ScatterGatherFailedProperty])
+			if !tt.wantOK {
+				assert.False(t, ok)
+				assert.EqualError(t, err, "scatter-gather Quote: branch CarsEP: endpoint CarsEP responded with status 503; branch SlowEP: no response within 200ms")
+				assert.Equal(t, http.StatusBadGateway, msg.Properties[HTTPStatusProperty])
+				assert.Equal(t, ErrorCodeScatterGatherFailed, msg.Properties[ErrorCodeProperty])
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.JSONEq(t, tt.wantPayload, string(msg.Message.RawPayload))
+		})
+	}
+}
+
+func TestScatterGatherMediator_AllBranchesFail(t *testing.T) {
+	msg := scatterMessage()
+	ok, err := scatterGather(newScatterBackend(), PartialFailureDrop, "UnreachableEP", "MissingEP").Execute(msg)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "scatter-gather Quote: branch UnreachableEP: connection refused; branch MissingEP: endpoint MissingEP is not deployed")
+	assert.Equal(t, http.StatusBadGateway, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, `{"destination":"Lisbon"}`, string(msg.Message.RawPayload), "the payload is left alone")
+}
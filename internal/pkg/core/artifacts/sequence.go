@@ -21,7 +21,10 @@ package artifacts
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
 	"github.com/apache/synapse-go/internal/pkg/core/synctx"
 )
 
@@ -29,17 +32,36 @@ type Sequence struct {
 	MediatorList []Mediator
 	Position     Position
 	Name         string
+	// plan is set by Compile when the sequence is deployed
+	plan *Plan
+}
+
+// Compile prepares the executable plan of the sequence. Sequences are compiled
+// once at deployment; an uncompiled sequence is compiled on every execution.
+func (v *Sequence) Compile() {
+	v.plan = compileSequence(v)
 }
 
 func (v *Sequence) Execute(context *synctx.MsgContext) bool {
-	for _, mediator := range v.MediatorList {
-		result, err := mediator.Execute(context)
-		if !result {
-			return false
-		}
-		if err != nil {
-			fmt.Println(err)
-		}
+	plan := v.plan
+	if plan == nil {
+		plan = compileSequence(v)
+	}
+	return plan.Execute(context)
+}
+
+// mediatorName returns the type name of a mediator, such as LogMediator
+func mediatorName(mediator Mediator) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", mediator), "*")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// captureStep describes a mediator execution for the flight recorder
+func captureStep(sequence string, step planStep, result bool, err error, duration time.Duration, context *synctx.MsgContext) capture.Step {
+	captured := capture.Step{Sequence: sequence, Mediator: step.name, Success: result, Duration: duration,
+		Payload: capture.Truncate(context.Message.RawPayload)}
+	if err != nil {
+		captured.Error = err.Error()
 	}
-	return true
+	return captured
 }
@@ -0,0 +1,69 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	ErrorCodeSequenceCycle = "SEQUENCE_CYCLE"
+	// sequenceStackProperty holds the keys of the sequences a message is
+	// being mediated by, outermost first
+	sequenceStackProperty = "sequenceStack"
+)
+
+// SequenceMediator runs the deployed sequence named Key, resolved from the
+// artifacts the message started with, so common steps are declared once and
+// reused by APIs and other sequences. The mediator's result is that of the
+// sequence. A sequence reached again through its own references fails the
+// flow with a 500 status instead of recursing.
+type SequenceMediator struct {
+	Key      string
+	Position Position
+}
+
+func (sm SequenceMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	sequence, ok := SnapshotFromContext(context).Sequences[sm.Key]
+	if !ok {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeSequenceNotFound, fmt.Errorf("sequence %s is not deployed", sm.Key))
+	}
+	stack, _ := context.Properties[sequenceStackProperty].([]string)
+	if slices.Contains(stack, sm.Key) {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeSequenceCycle, fmt.Errorf("sequence %s is referenced in a cycle: %s -> %s",
+			sm.Key, strings.Join(stack, " -> "), sm.Key))
+	}
+	context.Properties[sequenceStackProperty] = append(slices.Clone(stack), sm.Key)
+	defer func() {
+		if len(stack) == 0 {
+			delete(context.Properties, sequenceStackProperty)
+		} else {
+			context.Properties[sequenceStackProperty] = stack
+		}
+	}()
+	return sequence.Execute(context), nil
+}
@@ -0,0 +1,63 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceMediator(t *testing.T) {
+	common := Sequence{Name: "CommonHeaders", MediatorList: []Mediator{appendMediator{value: "|common"}}}
+	outer := Sequence{Name: "Outer", MediatorList: []Mediator{
+		appendMediator{value: "|outer"},
+		SequenceMediator{Key: "CommonHeaders"},
+		SequenceMediator{Key: "CommonHeaders"},
+	}}
+	msg := dispatchMessage("in", common, outer)
+	ok, err := SequenceMediator{Key: "Outer"}.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "in|outer|common|common", string(msg.Message.RawPayload), "a sequence may be referenced more than once")
+	assert.NotContains(t, msg.Properties, sequenceStackProperty)
+
+	msg = dispatchMessage("in")
+	ok, err = SequenceMediator{Key: "Missing"}.Execute(msg)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "sequence Missing is not deployed")
+	assert.Equal(t, ErrorCodeSequenceNotFound, msg.Properties[ErrorCodeProperty])
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+}
+
+func TestSequenceMediator_Cycle(t *testing.T) {
+	a := Sequence{Name: "A", MediatorList: []Mediator{appendMediator{value: "|a"}, SequenceMediator{Key: "B"}}}
+	b := Sequence{Name: "B", MediatorList: []Mediator{appendMediator{value: "|b"}, SequenceMediator{Key: "A"}}}
+	msg := dispatchMessage("in", a, b)
+	ok, err := SequenceMediator{Key: "A"}.Execute(msg)
+	require.NoError(t, err, "the failure is reported by the mediator closing the cycle")
+	assert.False(t, ok)
+	assert.Equal(t, "in|a|b", string(msg.Message.RawPayload))
+	assert.Equal(t, ErrorCodeSequenceCycle, msg.Properties[ErrorCodeProperty])
+	assert.Equal(t, "sequence A is referenced in a cycle: A -> B -> A", msg.Properties[ErrorMessageProperty])
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+}
@@ -0,0 +1,167 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+
+	"github.com/apache/synapse-go/internal/pkg/core/signature"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+)
+
+const (
+	SignatureTargetPayload = "payload"
+	SignatureTargetHeader  = "header"
+
+	// DefaultSignatureHeader carries detached JWS signatures
+	DefaultSignatureHeader = "X-JWS-Signature"
+
+	ErrorCodeSigningFailed    = "SIGNING_FAILED"
+	ErrorCodeInvalidSignature = "INVALID_SIGNATURE"
+	jwsContentType            = "application/jose"
+)
+
+// SignMediator signs the payload with a key from the secret store. JWS
+// signatures either replace the payload or are sent detached in a header;
+// XML-DSig signatures are enveloped in the XML payload.
+type SignMediator struct {
+	Format   string
+	KeyAlias string
+	// CertificateAlias names the certificate embedded in XML-DSig signatures;
+	// defaults to KeyAlias
+	CertificateAlias string
+	Algorithm        string
+	Target           string
+	Header           string
+	Position         Position
+}
+
+func (sm SignMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	payload, err := messagePayload(context)
+	if err != nil {
+		return fail(context, ErrorCodeSigningFailed, fmt.Errorf("sign: cannot read payload: %w", err))
+	}
+	store := secrets.Default()
+
+	switch sm.Format {
+	case signature.FormatXMLDSig:
+		signer, err := store.PrivateKey(sm.KeyAlias)
+		if err != nil {
+			return fail(context, ErrorCodeSigningFailed, fmt.Errorf("sign: %w", err))
+		}
+		certificateAlias := sm.CertificateAlias
+		if certificateAlias == "" {
+			certificateAlias = sm.KeyAlias
+		}
+		certificate, err := store.Certificate(certificateAlias)
+		if err != nil {
+			return fail(context, ErrorCodeSigningFailed, fmt.Errorf("sign: %w", err))
+		}
+		signed, err := signature.SignXML(payload, signer, certificate)
+		if err != nil {
+			return fail(context, ErrorCodeSigningFailed, fmt.Errorf("sign: %w", err))
+		}
+		setPayload(context, signed, "")
+	default:
+		var key any
+		if signature.IsSymmetric(sm.Algorithm) {
+			key, err = store.SymmetricKey(sm.KeyAlias)
+		} else {
+			key, err = store.PrivateKey(sm.KeyAlias)
+		}
+		if err != nil {
+			return fail(context, ErrorCodeSigningFailed, fmt.Errorf("sign: %w", err))
+		}
+		detached := sm.Target == SignatureTargetHeader
+		token, err := signature.SignJWS(payload, key, sm.Algorithm, detached)
+		if err != nil {
+			return fail(context, ErrorCodeSigningFailed, fmt.Errorf("sign: %w", err))
+		}
+		if detached {
+			context.Headers[sm.Header] = token
+		} else {
+			setPayload(context, []byte(token), jwsContentType)
+		}
+	}
+	return true, nil
+}
+
+// VerifyMediator verifies the signature of the payload with a key or
+// certificate from the secret store. Messages with missing or invalid
+// signatures are sent to the fault sequence. A verified JWS payload replaces
+// the compact serialization it was carried in.
+type VerifyMediator struct {
+	Format     string
+	KeyAlias   string
+	Algorithms []string
+	Source     string
+	Header     string
+	Position   Position
+}
+
+func (vm VerifyMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	payload, err := messagePayload(context)
+	if err != nil {
+		return fail(context, ErrorCodeInvalidSignature, fmt.Errorf("verify: cannot read payload: %w", err))
+	}
+	store := secrets.Default()
+
+	switch vm.Format {
+	case signature.FormatXMLDSig:
+		certificate, err := store.Certificate(vm.KeyAlias)
+		if err != nil {
+			return fail(context, ErrorCodeInvalidSignature, fmt.Errorf("verify: %w", err))
+		}
+		if err := signature.VerifyXML(payload, certificate); err != nil {
+			return fail(context, ErrorCodeInvalidSignature, fmt.Errorf("verify: %w", err))
+		}
+	default:
+		var key any
+		if len(vm.Algorithms) > 0 && signature.IsSymmetric(vm.Algorithms[0]) {
+			key, err = store.SymmetricKey(vm.KeyAlias)
+		} else {
+			key, err = store.PublicKey(vm.KeyAlias)
+		}
+		if err != nil {
+			return fail(context, ErrorCodeInvalidSignature, fmt.Errorf("verify: %w", err))
+		}
+
+		if vm.Source == SignatureTargetHeader {
+			token := requestHeader(context, vm.Header)
+			if token == "" {
+				return fail(context, ErrorCodeInvalidSignature, fmt.Errorf("verify: missing %s header", vm.Header))
+			}
+			if payload == nil {
+				payload = []byte{}
+			}
+			if _, err := signature.VerifyJWS(token, key, vm.Algorithms, payload); err != nil {
+				return fail(context, ErrorCodeInvalidSignature, fmt.Errorf("verify: %w", err))
+			}
+			return true, nil
+		}
+		verified, err := signature.VerifyJWS(string(payload), key, vm.Algorithms, nil)
+		if err != nil {
+			return fail(context, ErrorCodeInvalidSignature, fmt.Errorf("verify: %w", err))
+		}
+		setPayload(context, verified, "")
+	}
+	return true, nil
+}
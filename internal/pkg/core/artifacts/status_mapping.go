@@ -0,0 +1,83 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"slices"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// StatusMappingsProperty holds the status mappings of the API a message
+	// is mediated for
+	StatusMappingsProperty = "STATUS_MAPPINGS"
+	// BackendStatusProperty holds the status the backend answered with when
+	// a mapping replaced it
+	BackendStatusProperty = "BACKEND_HTTP_SC"
+)
+
+// StatusRange is an inclusive range of HTTP statuses
+type StatusRange struct {
+	Min, Max int
+}
+
+// StatusMapping translates backend statuses to the status the client gets,
+// e.g. 404 to 200 with an empty array, or 502-504 to 503 with a standard
+// payload
+type StatusMapping struct {
+	From []StatusRange
+	To   int
+	// Payload replaces the response body unless nil
+	Payload     []byte
+	ContentType string
+}
+
+// Matches reports whether the mapping applies to a backend status
+func (m StatusMapping) Matches(status int) bool {
+	return slices.ContainsFunc(m.From, func(r StatusRange) bool {
+		return status >= r.Min && status <= r.Max
+	})
+}
+
+// StatusMappings are tried in order; the first matching mapping applies
+type StatusMappings []StatusMapping
+
+// Apply maps the response status in HTTP_SC with the first matching mapping
+// and reports whether one applied. The backend's status is kept in
+// BACKEND_HTTP_SC.
+func (m StatusMappings) Apply(context *synctx.MsgContext) bool {
+	status, ok := context.Properties[HTTPStatusProperty].(int)
+	if !ok {
+		return false
+	}
+	for _, mapping := range m {
+		if !mapping.Matches(status) {
+			continue
+		}
+		context.Properties[BackendStatusProperty] = status
+		context.Properties[HTTPStatusProperty] = mapping.To
+		if mapping.Payload != nil {
+			setPayload(context, slices.Clone(mapping.Payload), mapping.ContentType)
+		}
+		return true
+	}
+	return false
+}
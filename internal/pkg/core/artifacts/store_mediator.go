@@ -0,0 +1,79 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/priority"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	ErrorCodeStoreFailed = "STORE_FAILED"
+	// StoredMessageIDProperty holds the id the message store gave the last
+	// stored message
+	StoredMessageIDProperty = "STORED_MESSAGE_ID"
+)
+
+// MessageStorer puts a copy of the message in the named message store with
+// a priority, and returns the id of the stored message
+type MessageStorer func(ctx context.Context, store string, msg *synctx.MsgContext, priority int) (string, error)
+
+// StoreMediator puts a copy of the message in a message store for later
+// processing. Mediation continues with the message unchanged, so the flow
+// can answer the client once the message is stored.
+type StoreMediator struct {
+	// MessageStore names a deployed message store, resolved for every message
+	MessageStore string
+	// Priority is an expression over ExpressionVariables; nil stores messages
+	// with the default priority
+	Priority *expression.Program
+	Store    MessageStorer
+	Position Position
+}
+
+func (sm StoreMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	payload, err := messagePayload(msgContext)
+	if err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(msgContext, ErrorCodeStoreFailed, fmt.Errorf("store: cannot read payload: %w", err))
+	}
+	msgContext.Message.RawPayload = payload
+	level := priority.Default
+	if sm.Priority != nil {
+		env, err := expressionEnv(msgContext)
+		if err != nil {
+			msgContext.Properties[HTTPStatusProperty] = http.StatusBadRequest
+			return fail(msgContext, ErrorCodeExpressionFailed, err)
+		}
+		level = priority.Extract(sm.Priority, env)
+	}
+	id, err := sm.Store(context.Background(), sm.MessageStore, msgContext, level)
+	if err != nil {
+		msgContext.Properties[HTTPStatusProperty] = http.StatusServiceUnavailable
+		return fail(msgContext, ErrorCodeStoreFailed, fmt.Errorf("store: %w", err))
+	}
+	msgContext.Properties[StoredMessageIDProperty] = id
+	return true, nil
+}
@@ -0,0 +1,61 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreMediator_Execute(t *testing.T) {
+	var stored []int
+	store := func(ctx context.Context, name string, msg *synctx.MsgContext, priority int) (string, error) {
+		if name != "Orders" {
+			return "", errors.New("message store " + name + " is not deployed")
+		}
+		stored = append(stored, priority)
+		return "id-1", nil
+	}
+
+	msg := evalMessage(`{"priority":8}`)
+	ok, err := StoreMediator{MessageStore: "Orders", Priority: propertyExpression(t, "payload.priority"), Store: store}.Execute(msg)
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "id-1", msg.Properties[StoredMessageIDProperty])
+	assert.Equal(t, `{"priority":8}`, string(msg.Message.RawPayload))
+
+	ok, err = StoreMediator{MessageStore: "Orders", Store: store}.Execute(evalMessage(`{}`))
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, []int{8, 4}, stored)
+
+	msg = evalMessage(`{}`)
+	ok, err = StoreMediator{MessageStore: "Missing", Store: store}.Execute(msg)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "store: message store Missing is not deployed")
+	assert.Equal(t, http.StatusServiceUnavailable, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, ErrorCodeStoreFailed, msg.Properties[ErrorCodeProperty])
+}
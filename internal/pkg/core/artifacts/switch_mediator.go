@@ -0,0 +1,81 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// SwitchMediator routes a message to the mediators of the first case whose
+// regular expression matches the whole value of Source, or to Default when no
+// case matches. Source is an expression over ExpressionVariables, converted to
+// a string as for a string property. The result of the chosen branch is the
+// result of the mediator, so a branch that stops the flow stops the sequence.
+type SwitchMediator struct {
+	Source *expression.Program
+	Cases  []SwitchCase
+	// Default is nil when unmatched messages continue with the next mediator
+	Default  *Sequence
+	Position Position
+}
+
+// SwitchCase is a branch of the switch mediator
+type SwitchCase struct {
+	Regex    *regexp.Regexp
+	Sequence Sequence
+}
+
+// CompileSwitchRegex anchors a case regular expression so it matches whole values
+func CompileSwitchRegex(regex string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + regex + ")$")
+}
+
+func (sm SwitchMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	env, err := expressionEnv(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeExpressionFailed, err)
+	}
+	result, err := sm.Source.Run(env)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeExpressionFailed, err)
+	}
+	value, err := ConvertPropertyValue(result, PropertyTypeString)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeExpressionFailed, fmt.Errorf("switch source: %w", err))
+	}
+
+	for _, c := range sm.Cases {
+		if c.Regex.MatchString(value.(string)) {
+			return c.Sequence.Execute(context), nil
+		}
+	}
+	if sm.Default != nil {
+		return sm.Default.Execute(context), nil
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func switchBranch(value string) Sequence {
+	return Sequence{MediatorList: []Mediator{PropertyMediator{Name: "branch", Action: PropertyActionSet, Value: value}}}
+}
+
+func switchRegex(t *testing.T, regex string) SwitchCase {
+	compiled, err := CompileSwitchRegex(regex)
+	require.NoError(t, err)
+	return SwitchCase{Regex: compiled, Sequence: switchBranch(regex)}
+}
+
+func TestSwitchMediator(t *testing.T) {
+	defaultBranch := switchBranch("default")
+	tests := []struct {
+		name       string
+		source     string
+		payload    string
+		noDefault  bool
+		wantBranch any
+	}{
+		{name: "first matching case", source: `payload.tier`, payload: `{"tier":"gold"}`, wantBranch: "gold|platinum"},
+		{name: "whole value must match", source: `payload.tier`, payload: `{"tier":"golden"}`, wantBranch: "default"},
+		{name: "number source", source: `payload.items[0]`, payload: `{"items":[42]}`, wantBranch: `\d+`},
+		{name: "property source", source: `properties.HTTP_SC`, payload: `{}`, wantBranch: `\d+`},
+		{name: "missing value", source: `payload.tier`, payload: `{}`, wantBranch: "default"},
+		{name: "no default", source: `payload.tier`, payload: `{"tier":"bronze"}`, noDefault: true, wantBranch: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediator := SwitchMediator{Source: propertyExpression(t, tt.source),
+				Cases: []SwitchCase{switchRegex(t, "gold|platinum"), switchRegex(t, `\d+`)}, Default: &defaultBranch}
+			if tt.noDefault {
+				mediator.Default = nil
+			}
+			msg := evalMessage(tt.payload)
+			ok, err := mediator.Execute(msg)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantBranch, msg.Properties["branch"])
+		})
+	}
+}
+
+func TestSwitchMediator_BranchStopsFlow(t *testing.T) {
+	stop := Sequence{MediatorList: []Mediator{EvalMediator{Expression: propertyExpression(t, `false`)}}}
+	mediator := SwitchMediator{Source: propertyExpression(t, `'a'`), Cases: []SwitchCase{{Regex: switchRegex(t, "a").Regex, Sequence: stop}}}
+
+	ok, _ := mediator.Execute(evalMessage(`{}`))
+	assert.False(t, ok)
+}
+
+func TestSwitchMediator_SourceFails(t *testing.T) {
+	mediator := SwitchMediator{Source: propertyExpression(t, `payload.id - 1`), Cases: []SwitchCase{switchRegex(t, "a")}}
+	msg := evalMessage(`{"id":"x"}`)
+
+	ok, err := mediator.Execute(msg)
+	assert.False(t, ok)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+}
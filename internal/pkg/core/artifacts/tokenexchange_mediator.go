@@ -0,0 +1,130 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/dryrun"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/tokenexchange"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+)
+
+const (
+	// DefaultExchangedTokenProperty holds the backend token obtained by the
+	// tokenExchange mediator
+	DefaultExchangedTokenProperty = "EXCHANGED_TOKEN"
+
+	ErrorCodeTokenExchangeFailed = "TOKEN_EXCHANGE_FAILED"
+
+	// dryRunToken stands in for the exchanged token during a dry run
+	dryRunToken = "dry-run"
+)
+
+// TokenExchangeMediator validates the caller's JWT or SAML assertion and
+// exchanges it for a backend token, which is stored in a property for the
+// outbound call. Key and secret attributes are secret store aliases.
+type TokenExchangeMediator struct {
+	TokenType string
+	// Header carries the inbound token, optionally prefixed with its scheme
+	Header          string
+	VerificationKey string
+	Algorithms      []string
+	Issuer          string
+	Audience        string
+
+	Endpoint          string
+	ClientID          string
+	ClientSecretAlias string
+	TargetAudience    string
+	Scope             string
+	Property          string
+	Exchanger         *tokenexchange.Exchanger
+	Position          Position
+}
+
+func (tm TokenExchangeMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	token := requestHeader(msgContext, tm.Header)
+	if scheme, value, found := strings.Cut(token, " "); found && !strings.ContainsAny(scheme, ".=") {
+		token = value
+	}
+	if token == "" {
+		return fail(msgContext, ErrorCodeTokenExchangeFailed, fmt.Errorf("tokenExchange: missing token in %s header", tm.Header))
+	}
+
+	config, err := tm.config()
+	if err != nil {
+		return fail(msgContext, ErrorCodeTokenExchangeFailed, fmt.Errorf("tokenExchange: %w", err))
+	}
+	now := time.Now()
+	identity, err := tm.Exchanger.Validate(config, token, now)
+	if err != nil {
+		return fail(msgContext, ErrorCodeTokenExchangeFailed, fmt.Errorf("tokenExchange: %w", err))
+	}
+	msgContext.Properties["AUTHENTICATED_USER"] = identity.Subject
+	if recorder := dryrun.FromContext(msgContext); recorder != nil {
+		recorder.Record(dryrun.Call{Mediator: "tokenExchange", Method: http.MethodPost, URL: config.Endpoint})
+		msgContext.Properties[tm.Property] = dryRunToken
+		return true, nil
+	}
+	exchanged, err := tm.Exchanger.Exchange(context.Background(), config, token, identity, now)
+	if err != nil {
+		return fail(msgContext, ErrorCodeTokenExchangeFailed, fmt.Errorf("tokenExchange: %w", err))
+	}
+	msgContext.Properties[tm.Property] = exchanged
+	return true, nil
+}
+
+// config resolves the mediator's aliases from the secret store
+func (tm TokenExchangeMediator) config() (tokenexchange.Config, error) {
+	store := secrets.Default()
+	config := tokenexchange.Config{
+		TokenType:      tm.TokenType,
+		Algorithms:     tm.Algorithms,
+		Issuer:         tm.Issuer,
+		Audience:       tm.Audience,
+		Endpoint:       tm.Endpoint,
+		ClientID:       tm.ClientID,
+		TargetAudience: tm.TargetAudience,
+		Scope:          tm.Scope,
+	}
+	var err error
+	if tm.TokenType == tokenexchange.TokenTypeSAML2 {
+		config.Certificate, err = store.Certificate(tm.VerificationKey)
+	} else {
+		config.VerificationKey, err = store.PublicKey(tm.VerificationKey)
+	}
+	if err != nil {
+		return tokenexchange.Config{}, err
+	}
+	if tm.ClientSecretAlias != "" {
+		secret, err := store.Get(tm.ClientSecretAlias)
+		if err != nil {
+			return tokenexchange.Config{}, err
+		}
+		config.ClientSecret = string(secret)
+	}
+	return config, nil
+}
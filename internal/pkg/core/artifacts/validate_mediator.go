@@ -0,0 +1,219 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const ErrorCodeRequestValidationFailed = "REQUEST_VALIDATION_FAILED"
+
+// RequiredHeader is a header the request must carry, optionally matching a pattern
+type RequiredHeader struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// Precondition is a template expression over the request that must yield true
+type Precondition struct {
+	Expression *template.Template
+	Message    string
+}
+
+// ValidateRequestMediator rejects requests without the required headers with
+// 400 Bad Request and requests of other content types with 415 Unsupported
+// Media Type, before any other mediator runs
+type ValidateRequestMediator struct {
+	// ContentTypes are media types such as application/json or text/*
+	ContentTypes  []string
+	Headers       []RequiredHeader
+	Preconditions []Precondition
+	Position      Position
+}
+
+func (vm ValidateRequestMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	if len(vm.ContentTypes) > 0 {
+		contentType := requestHeader(context, "Content-Type")
+		if contentType == "" {
+			contentType = context.Message.ContentType
+		}
+		if !vm.allowsContentType(contentType) {
+			if contentType == "" {
+				contentType = "none"
+			}
+			context.Properties[HTTPStatusProperty] = http.StatusUnsupportedMediaType
+			return fail(context, ErrorCodeRequestValidationFailed, fmt.Errorf("unsupported content type %s, expected one of %s",
+				contentType, strings.Join(vm.ContentTypes, ", ")))
+		}
+	}
+
+	var errs []error
+	for _, header := range vm.Headers {
+		value := requestHeader(context, header.Name)
+		if value == "" {
+			errs = append(errs, fmt.Errorf("missing required header %s", header.Name))
+		} else if header.Pattern != nil && !header.Pattern.MatchString(value) {
+			errs = append(errs, fmt.Errorf("invalid %s header", header.Name))
+		}
+	}
+	for _, precondition := range vm.Preconditions {
+		var out bytes.Buffer
+		err := precondition.Expression.Execute(&out, ValidationRequest{context: context})
+		if err != nil || strings.TrimSpace(out.String()) != "true" {
+			errs = append(errs, errors.New(precondition.Message))
+		}
+	}
+	if len(errs) > 0 {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeRequestValidationFailed, joinErrors(errs))
+	}
+	return true, nil
+}
+
+func (vm ValidateRequestMediator) allowsContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range vm.ContentTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidationRequest is the data of precondition expressions and other
+// templates over the message, e.g. eq (.Header "X-Api-Version") "2"
+type ValidationRequest struct {
+	context *synctx.MsgContext
+}
+
+func (r ValidationRequest) Header(name string) string {
+	return requestHeader(r.context, name)
+}
+
+// Query returns the variable a declared query parameter is mapped to
+func (r ValidationRequest) Query(name string) string {
+	params, _ := r.context.Properties["queryParams"].(map[string]string)
+	return params[name]
+}
+
+func (r ValidationRequest) Param(name string) string {
+	params, _ := r.context.Properties["uriParams"].(map[string]string)
+	return params[name]
+}
+
+func (r ValidationRequest) Property(name string) string {
+	if value, ok := r.context.Properties[name]; ok {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// jsonPathSegment matches one step of a payload path: a key or an index
+var jsonPathSegment = regexp.MustCompile(`\.([^.\[]+)|\[(\d+)\]`)
+
+// JSON returns the value at a path such as $.order.items[0].sku in a JSON
+// payload, or nil when the payload has no such value
+func (r ValidationRequest) JSON(path string) (any, error) {
+	payload, err := messagePayload(r.context)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return nil, fmt.Errorf("payload is not JSON: %w", err)
+	}
+	for _, segment := range jsonPathSegment.FindAllStringSubmatch(strings.TrimPrefix(path, "$"), -1) {
+		switch node := value.(type) {
+		case map[string]any:
+			value = node[segment[1]]
+		case []any:
+			index, err := strconv.Atoi(segment[2])
+			if err != nil || index >= len(node) {
+				return nil, nil
+			}
+			value = node[index]
+		default:
+			return nil, nil
+		}
+	}
+	return value, nil
+}
+
+// PreconditionFuncs are the functions available to precondition expressions in
+// addition to the template builtins
+var PreconditionFuncs = template.FuncMap{
+	"matches": func(value, pattern string) (bool, error) {
+		return regexp.MatchString(pattern, value)
+	},
+	// in reports whether value is one of the candidates, e.g.
+	// {{not (in (.Property "GEOIP_COUNTRY") "KP" "IR")}}
+	"in": func(value string, candidates ...string) bool {
+		return slices.Contains(candidates, value)
+	},
+	// now formats the current UTC time with a Go layout, e.g. for file names
+	// {{now "20060102T150405"}}
+	"now": func(layout string) string {
+		return time.Now().UTC().Format(layout)
+	},
+	// The value generators of expressions, e.g. {{uuid}} for a message ID or
+	// {{hmac "sha256" (.Property "key") (.Property "body")}} for a signature
+	"uuid":      expression.NewUUID,
+	"randomInt": expression.RandomInt,
+	"base64encode": func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	},
+	"base64decode": func(value string) (string, error) {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		return string(decoded), err
+	},
+	"hmac": expression.HMAC,
+}
+
+// joinErrors combines validation errors into one, separated by "; " so the
+// details fit on one line of an error response
+func joinErrors(errs []error) error {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
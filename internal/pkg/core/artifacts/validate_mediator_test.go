@@ -0,0 +1,138 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"testing"
+	"text/template"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRequestMediator_Execute(t *testing.T) {
+	mediator := ValidateRequestMediator{
+		ContentTypes: []string{"application/json", "text/*"},
+		Headers: []RequiredHeader{
+			{Name: "X-Tenant", Pattern: regexp.MustCompile(`^[a-z]+$`)},
+		},
+		Preconditions: []Precondition{{
+			Expression: template.Must(template.New("").Funcs(PreconditionFuncs).Parse(`{{matches (.Param "id") "^[0-9]+$"}}`)),
+			Message:    "order id must be numeric",
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		id          string
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:    "valid",
+			headers: map[string]string{"Content-Type": "application/json; charset=utf-8", "X-Tenant": "acme"},
+			id:      "42",
+		},
+		{
+			name:    "wildcard content type",
+			headers: map[string]string{"Content-Type": "text/plain", "X-Tenant": "acme"},
+			id:      "42",
+		},
+		{
+			name:        "unsupported content type",
+			headers:     map[string]string{"Content-Type": "application/xml", "X-Tenant": "acme"},
+			id:          "42",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantMessage: "unsupported content type application/xml, expected one of application/json, text/*",
+		},
+		{
+			name:        "missing content type",
+			headers:     map[string]string{"X-Tenant": "acme"},
+			id:          "42",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantMessage: "unsupported content type none, expected one of application/json, text/*",
+		},
+		{
+			name:        "missing header and failed precondition",
+			headers:     map[string]string{"Content-Type": "application/json"},
+			id:          "abc",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "missing required header X-Tenant; order id must be numeric",
+		},
+		{
+			name:        "header not matching pattern",
+			headers:     map[string]string{"Content-Type": "application/json", "X-Tenant": "ACME"},
+			id:          "42",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "invalid X-Tenant header",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context := synctx.CreateMsgContext()
+			context.Properties["http_request_headers"] = tt.headers
+			context.Properties["uriParams"] = map[string]string{"id": tt.id}
+
+			result, err := mediator.Execute(context)
+			if tt.wantStatus == 0 {
+				assert.True(t, result)
+				assert.NoError(t, err)
+				return
+			}
+			assert.False(t, result)
+			assert.EqualError(t, err, tt.wantMessage)
+			assert.Equal(t, tt.wantStatus, context.Properties[HTTPStatusProperty])
+			assert.Equal(t, ErrorCodeRequestValidationFailed, context.Properties[ErrorCodeProperty])
+		})
+	}
+}
+
+func TestValidateRequestMediator_CountryPrecondition(t *testing.T) {
+	mediator := ValidateRequestMediator{
+		Preconditions: []Precondition{{
+			Expression: template.Must(template.New("").Funcs(PreconditionFuncs).Parse(`{{not (in (.Property "GEOIP_COUNTRY") "KP" "IR")}}`)),
+			Message:    "requests from this country are not accepted",
+		}},
+	}
+	for country, allowed := range map[string]bool{"GB": true, "KP": false, "": true} {
+		context := synctx.CreateMsgContext()
+		if country != "" {
+			context.Properties["GEOIP_COUNTRY"] = country
+		}
+		result, err := mediator.Execute(context)
+		assert.Equal(t, allowed, result, country)
+		if !allowed {
+			assert.EqualError(t, err, "requests from this country are not accepted")
+			assert.Equal(t, http.StatusBadRequest, context.Properties[HTTPStatusProperty])
+		}
+	}
+}
+
+func TestPreconditionFuncs_Generators(t *testing.T) {
+	var buf bytes.Buffer
+	text := `{{base64encode "a:b"}} {{base64decode "YTpi"}} {{hmac "sha1" "key" "msg"}} {{randomInt 7 7}} {{len uuid}}`
+	require.NoError(t, template.Must(template.New("").Funcs(PreconditionFuncs).Parse(text)).Execute(&buf, nil))
+	assert.Equal(t, "YTpi a:b 102900b72b7bf1031eec76b4804b66052376896b 7 36", buf.String())
+}
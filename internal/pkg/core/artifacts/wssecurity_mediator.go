@@ -0,0 +1,114 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/wssecurity"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+)
+
+const (
+	WSSecurityActionAdd      = "add"
+	WSSecurityActionValidate = "validate"
+
+	ErrorCodeWSSecurityFailed = "WS_SECURITY_FAILED"
+)
+
+// WSSecurityMediator adds a WS-Security header to outbound SOAP messages or
+// validates the header of inbound ones. Passwords are aliases resolved from the
+// secret store.
+type WSSecurityMediator struct {
+	Action string
+
+	// Outbound header
+	Username      string
+	PasswordAlias string
+	PasswordType  string
+	TimestampTTL  time.Duration
+
+	// Inbound validation; Users maps user names to password aliases
+	Users     map[string]string
+	Validator *wssecurity.Validator
+
+	Position Position
+}
+
+// NewWSSecurityValidator creates the validator of an inbound WS-Security
+// mediator, resolving user passwords from the secret store on each request
+func NewWSSecurityValidator(users map[string]string, requireUsernameToken, requireTimestamp bool, clockSkew time.Duration) *wssecurity.Validator {
+	return wssecurity.NewValidator(wssecurity.ValidationOptions{
+		Password: func(username string) (string, bool) {
+			alias, exists := users[username]
+			if !exists {
+				return "", false
+			}
+			password, err := secrets.Default().Get(alias)
+			if err != nil {
+				return "", false
+			}
+			return string(password), true
+		},
+		RequireUsernameToken: requireUsernameToken,
+		RequireTimestamp:     requireTimestamp,
+		ClockSkew:            clockSkew,
+	})
+}
+
+func (wm WSSecurityMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	payload, err := messagePayload(context)
+	if err != nil {
+		return fail(context, ErrorCodeWSSecurityFailed, fmt.Errorf("wsSecurity: cannot read payload: %w", err))
+	}
+
+	if wm.Action == WSSecurityActionValidate {
+		username, err := wm.Validator.Validate(payload, time.Now())
+		if err != nil {
+			return fail(context, ErrorCodeWSSecurityFailed, fmt.Errorf("wsSecurity: %w", err))
+		}
+		if username != "" {
+			context.Properties["AUTHENTICATED_USER"] = username
+		}
+		return true, nil
+	}
+
+	var password string
+	if wm.PasswordAlias != "" {
+		secret, err := secrets.Default().Get(wm.PasswordAlias)
+		if err != nil {
+			return fail(context, ErrorCodeWSSecurityFailed, fmt.Errorf("wsSecurity: %w", err))
+		}
+		password = string(secret)
+	}
+	secured, err := wssecurity.AddSecurityHeader(payload, wssecurity.Options{
+		Username:     wm.Username,
+		Password:     password,
+		PasswordType: wm.PasswordType,
+		TimestampTTL: wm.TimestampTTL,
+	}, time.Now())
+	if err != nil {
+		return fail(context, ErrorCodeWSSecurityFailed, fmt.Errorf("wsSecurity: %w", err))
+	}
+	setPayload(context, secured, "")
+	return true, nil
+}
@@ -0,0 +1,67 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/xslt"
+)
+
+const ErrorCodeXSLTFailed = "XSLT_FAILED"
+
+// XSLTMediator replaces an XML payload with the result of the stylesheet
+// stored under Key in the registry, a path below artifacts/Registry such as
+// transforms/order.xsl. Params set top-level parameters of the stylesheet.
+// The content type becomes that of the stylesheet's output. The flow fails
+// with 400 when the payload is not XML, and with 500 when the stylesheet
+// cannot be loaded or the transform fails.
+type XSLTMediator struct {
+	Key      string
+	Params   map[string]string
+	Position Position
+}
+
+func (xm XSLTMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	stylesheet, err := xslt.Default().Load(xm.Key)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusInternalServerError
+		return fail(context, ErrorCodeXSLTFailed, fmt.Errorf("xslt: %w", err))
+	}
+	payload, err := messagePayload(context)
+	if err != nil {
+		context.Properties[HTTPStatusProperty] = http.StatusBadRequest
+		return fail(context, ErrorCodeXSLTFailed, fmt.Errorf("xslt: cannot read payload: %w", err))
+	}
+	output, err := stylesheet.Transform(payload, xm.Params)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, xslt.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		context.Properties[HTTPStatusProperty] = status
+		return fail(context, ErrorCodeXSLTFailed, fmt.Errorf("xslt %s: %w", xm.Key, err))
+	}
+	setPayload(context, output, stylesheet.MediaType())
+	return true, nil
+}
@@ -0,0 +1,67 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package artifacts
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/xslt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXSLTMediator(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "order.xsl"), []byte(`<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+	<xsl:output omit-xml-declaration="yes"/>
+	<xsl:param name="channel" select="'web'"/>
+	<xsl:template match="/order">
+		<purchase channel="{$channel}"><xsl:for-each select="item"><sku><xsl:value-of select="@id"/></sku></xsl:for-each></purchase>
+	</xsl:template>
+</xsl:stylesheet>`), 0o644))
+	previous := xslt.Default()
+	xslt.SetDefault(xslt.NewRegistry(directory))
+	t.Cleanup(func() { xslt.SetDefault(previous) })
+
+	msg := dispatchMessage(`<order><item id="a1"/><item id="b2"/></order>`)
+	msg.Message.ContentType = "text/xml"
+	ok, err := XSLTMediator{Key: "order.xsl", Params: map[string]string{"channel": "mobile"}}.Execute(msg)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `<purchase channel="mobile"><sku>a1</sku><sku>b2</sku></purchase>`, string(msg.Message.RawPayload))
+	assert.Equal(t, "application/xml", msg.Message.ContentType)
+
+	msg = dispatchMessage(`{"order": {}}`)
+	ok, err = XSLTMediator{Key: "order.xsl"}.Execute(msg)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, xslt.ErrInvalidInput)
+	assert.Equal(t, ErrorCodeXSLTFailed, msg.Properties[ErrorCodeProperty])
+	assert.Equal(t, http.StatusBadRequest, msg.Properties[HTTPStatusProperty])
+
+	msg = dispatchMessage(`<order/>`)
+	ok, err = XSLTMediator{Key: "missing.xsl"}.Execute(msg)
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "xslt: stylesheet missing.xsl")
+	assert.Equal(t, http.StatusInternalServerError, msg.Properties[HTTPStatusProperty])
+	assert.Equal(t, `<order/>`, string(msg.Message.RawPayload))
+}
@@ -0,0 +1,227 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package asyncreply correlates requests answered with 202 Accepted with the
+// result of their background mediation. Results are kept in a Store for a
+// limited time, in memory by default, so clients can poll for them or be
+// notified through a callback URL.
+package asyncreply
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusPath is the path prefix clients poll for results
+	StatusPath = "/_async/"
+	// CallbackHeader names the URL the result is posted to once complete
+	CallbackHeader = "X-Callback-URL"
+	// IDHeader carries the correlation ID on results and callbacks
+	IDHeader = "X-Async-ID"
+	// DefaultTTL is how long results are kept when a resource does not say
+	DefaultTTL = 10 * time.Minute
+	// MaxWait bounds how long a long-polling client is held
+	MaxWait = time.Minute
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusComplete Status = "complete"
+)
+
+// Result is the outcome of a request mediated in the background
+type Result struct {
+	ID         string      `json:"id"`
+	Status     Status      `json:"status"`
+	StatusCode int         `json:"statusCode,omitempty"`
+	Header     http.Header `json:"-"`
+	Payload    []byte      `json:"-"`
+	Created    time.Time   `json:"created"`
+	Completed  time.Time   `json:"completed,omitempty"`
+}
+
+// Store keeps results for a limited time
+type Store interface {
+	// Put stores the result under its ID for ttl, replacing an earlier result
+	Put(ctx context.Context, result Result, ttl time.Duration) error
+	// Get returns the result stored under the ID unless it expired
+	Get(ctx context.Context, id string) (Result, bool, error)
+}
+
+type memoryEntry struct {
+	result Result
+	expiry time.Time
+}
+
+// MemoryStore keeps results in memory. Expired results are removed lazily, at
+// most once per sweep interval.
+type MemoryStore struct {
+	now           func() time.Time
+	sweepInterval time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, sweepInterval: time.Minute, entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, result Result, ttl time.Duration) error {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= s.sweepInterval {
+		for id, entry := range s.entries {
+			if !now.Before(entry.expiry) {
+				delete(s.entries, id)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.entries[result.ID] = memoryEntry{result: result, expiry: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Result, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[id]
+	if !ok || !s.now().Before(entry.expiry) {
+		return Result{}, false, nil
+	}
+	return entry.result, true, nil
+}
+
+// Len returns the number of results held, including expired results not yet swept
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Tracker records pending requests and their results, and wakes long-polling
+// clients when a result arrives
+type Tracker struct {
+	store Store
+	now   func() time.Time
+	// pollInterval rechecks the store while waiting, for results completed by
+	// other instances sharing it
+	pollInterval time.Duration
+
+	mu      sync.Mutex
+	waiters map[string][]chan struct{}
+}
+
+func NewTracker(store Store) *Tracker {
+	return &Tracker{store: store, now: time.Now, pollInterval: time.Second, waiters: make(map[string][]chan struct{})}
+}
+
+var defaultTracker = NewTracker(NewMemoryStore())
+
+// Default returns the tracker of the runtime's API resources
+func Default() *Tracker {
+	return defaultTracker
+}
+
+// Start records a new pending request
+func (t *Tracker) Start(ctx context.Context, ttl time.Duration) (Result, error) {
+	id := make([]byte, 16)
+	rand.Read(id)
+	result := Result{ID: hex.EncodeToString(id), Status: StatusPending, Created: t.now()}
+	if err := t.store.Put(ctx, result, ttl); err != nil {
+		return Result{}, fmt.Errorf("error recording async request: %w", err)
+	}
+	return result, nil
+}
+
+// Complete stores the result of a pending request and wakes its waiters
+func (t *Tracker) Complete(ctx context.Context, result Result, ttl time.Duration) error {
+	result.Status = StatusComplete
+	result.Completed = t.now()
+	if err := t.store.Put(ctx, result, ttl); err != nil {
+		return fmt.Errorf("error storing result of async request %s: %w", result.ID, err)
+	}
+	t.mu.Lock()
+	for _, waiter := range t.waiters[result.ID] {
+		close(waiter)
+	}
+	delete(t.waiters, result.ID)
+	t.mu.Unlock()
+	return nil
+}
+
+// Get returns the current state of a request
+func (t *Tracker) Get(ctx context.Context, id string) (Result, bool, error) {
+	return t.store.Get(ctx, id)
+}
+
+// Wait returns the result of a request once complete, or its pending state
+// when ctx is done first
+func (t *Tracker) Wait(ctx context.Context, id string) (Result, bool, error) {
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+	for {
+		waiter := make(chan struct{})
+		t.mu.Lock()
+		t.waiters[id] = append(t.waiters[id], waiter)
+		t.mu.Unlock()
+
+		result, ok, err := t.store.Get(ctx, id)
+		if err != nil || !ok || result.Status == StatusComplete {
+			t.forget(id, waiter)
+			return result, ok, err
+		}
+		select {
+		case <-waiter:
+		case <-ticker.C:
+			t.forget(id, waiter)
+		case <-ctx.Done():
+			t.forget(id, waiter)
+			return result, true, nil
+		}
+	}
+}
+
+// forget removes a waiter that was not woken
+func (t *Tracker) forget(id string, waiter chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	waiters := t.waiters[id]
+	for i, w := range waiters {
+		if w == waiter {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(t.waiters, id)
+	} else {
+		t.waiters[id] = waiters
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package asyncreply
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, Result{ID: "a", Status: StatusPending}, time.Minute))
+	require.NoError(t, store.Put(ctx, Result{ID: "b", Status: StatusPending}, 2*time.Minute))
+	result, ok, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, StatusPending, result.Status)
+
+	now = now.Add(time.Minute)
+	_, ok, _ = store.Get(ctx, "a")
+	assert.False(t, ok, "expired results are not returned")
+	require.NoError(t, store.Put(ctx, Result{ID: "c"}, time.Minute))
+	assert.Equal(t, 2, store.Len(), "a expired and was swept")
+}
+
+func TestTracker_Wait(t *testing.T) {
+	tracker := NewTracker(NewMemoryStore())
+	ctx := context.Background()
+	pending, err := tracker.Start(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, pending.ID, 32)
+
+	t.Run("times out while pending", func(t *testing.T) {
+		waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		result, ok, err := tracker.Wait(waitCtx, pending.ID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, StatusPending, result.Status)
+	})
+
+	t.Run("wakes on completion", func(t *testing.T) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			completed := pending
+			completed.StatusCode = http.StatusCreated
+			completed.Payload = []byte(`{"order":1}`)
+			tracker.Complete(ctx, completed, time.Minute)
+		}()
+		waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		result, ok, err := tracker.Wait(waitCtx, pending.ID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, StatusComplete, result.Status)
+		assert.Equal(t, http.StatusCreated, result.StatusCode)
+		assert.Equal(t, `{"order":1}`, string(result.Payload))
+		assert.Empty(t, tracker.waiters)
+	})
+
+	t.Run("unknown request", func(t *testing.T) {
+		_, ok, err := tracker.Wait(ctx, "missing")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestDeliver(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer server.Close()
+	dispatcher, err := webhook.NewDispatcher(webhook.Config{AllowPrivateCallbacks: true}, nil)
+	assert.NoError(t, err)
+	webhook.SetDefault(dispatcher)
+	defer func() {
+		restored, _ := webhook.NewDispatcher(webhook.Config{}, nil)
+		webhook.SetDefault(restored)
+	}()
+
+	result := Result{ID: "abc", StatusCode: http.StatusCreated, Header: http.Header{"Content-Type": {"application/json"}}, Payload: []byte(`{"order":1}`)}
+	delivery := Deliver(server.URL+"/hooks", result)
+	assert.Equal(t, webhook.SourceAsyncReply, delivery.Source)
+	select {
+	case request := <-received:
+		assert.Equal(t, http.MethodPost, request.Method)
+		assert.Equal(t, "/hooks", request.URL.Path)
+		assert.Equal(t, "abc", request.Header.Get(IDHeader))
+		assert.Equal(t, "201", request.Header.Get(StatusCodeHeader))
+		assert.Equal(t, delivery.ID, request.Header.Get(webhook.DeliveryHeader))
+		assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+		assert.Equal(t, `{"order":1}`, string(body))
+	case <-time.After(5 * time.Second):
+		t.Fatal("result was not delivered")
+	}
+
+	for _, callbackURL := range []string{"ftp://example.com/hooks", "/hooks", "http://"} {
+		assert.Error(t, ValidateCallback(callbackURL), callbackURL)
+	}
+	assert.NoError(t, ValidateCallback(server.URL))
+}
+
+func TestAllowCallback(t *testing.T) {
+	prefixes := []string{"https://hooks.example.com/orders/", "http://partner.example.org:8080"}
+	for _, callbackURL := range []string{
+		"https://hooks.example.com/orders/",
+		"https://hooks.example.com/orders/42?token=1",
+		"https://HOOKS.example.com/orders",
+		"http://partner.example.org:8080/any/path",
+	} {
+		assert.NoError(t, AllowCallback(callbackURL, prefixes), callbackURL)
+	}
+	for _, callbackURL := range []string{
+		"http://hooks.example.com/orders/42",
+		"https://hooks.example.com/ordersx",
+		"https://hooks.example.com/orders/../admin",
+		"https://hooks.example.com.evil.test/orders/42",
+		"https://user@hooks.example.com/orders/42",
+		"http://partner.example.org/any/path",
+		"http://169.254.169.254/latest/meta-data",
+		"file:///etc/passwd",
+	} {
+		assert.Error(t, AllowCallback(callbackURL, prefixes), callbackURL)
+	}
+	assert.EqualError(t, AllowCallback("https://hooks.example.com/orders/", nil), "callbacks are not enabled for this resource")
+}
@@ -0,0 +1,95 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package asyncreply
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+)
+
+// StatusCodeHeader carries the status the mediation ended with on callbacks,
+// whose own status belongs to the callback exchange
+const StatusCodeHeader = "X-Async-Status"
+
+// ValidateCallback checks that a callback URL can be posted to
+func ValidateCallback(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("callback URL %s must be an absolute http or https URL", callbackURL)
+	}
+	return nil
+}
+
+// AllowCallback checks that a callback URL sent by a client starts with one of
+// the prefixes the resource allows: the scheme and host must match exactly
+// and the path must lie under the prefix's path
+func AllowCallback(callbackURL string, prefixes []string) error {
+	if err := ValidateCallback(callbackURL); err != nil {
+		return err
+	}
+	if len(prefixes) == 0 {
+		return fmt.Errorf("callbacks are not enabled for this resource")
+	}
+	u, _ := url.Parse(callbackURL)
+	if u.User != nil || slices.Contains(strings.Split(u.Path, "/"), "..") {
+		return fmt.Errorf("callback URL %s is not allowed", callbackURL)
+	}
+	for _, prefix := range prefixes {
+		allowed, err := url.Parse(prefix)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(u.Scheme, allowed.Scheme) && strings.EqualFold(u.Host, allowed.Host) &&
+			underPath(u.Path, allowed.Path) {
+			return nil
+		}
+	}
+	return fmt.Errorf("callback URL %s is not allowed", callbackURL)
+}
+
+// underPath reports whether path equals prefix or lies below it
+func underPath(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// Deliver hands a completed result to the webhook dispatcher for posting to
+// the callback URL
+func Deliver(callbackURL string, result Result) webhook.Delivery {
+	header := make(map[string][]string, 2)
+	header[IDHeader] = []string{result.ID}
+	header[StatusCodeHeader] = []string{strconv.Itoa(result.StatusCode)}
+	return webhook.Default().Enqueue(webhook.Request{
+		URL:         callbackURL,
+		Source:      webhook.SourceAsyncReply,
+		Untrusted:   true,
+		ContentType: result.Header.Get("Content-Type"),
+		Header:      header,
+		Payload:     result.Payload,
+	})
+}
@@ -0,0 +1,316 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package cache stores mediated responses by key for a limited time. The store
+// keeps the most recently used entries in memory and can persist them to a
+// directory, so a restart does not send every cached request to the backends
+// at once.
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries bounds the entries kept when [cache] does not set maxEntries
+const DefaultMaxEntries = 10000
+
+// Config holds the [cache] section of deployment.toml
+type Config struct {
+	MaxEntries int `koanf:"maxEntries"`
+	// Directory persists entries across restarts, relative to the conf
+	// directory. Entries are kept in memory only when it is empty.
+	Directory string `koanf:"directory"`
+}
+
+// Validate reports configuration errors in the [cache] section
+func (c Config) Validate() error {
+	if c.MaxEntries < 0 {
+		return fmt.Errorf("cache: maxEntries must not be negative, got %d", c.MaxEntries)
+	}
+	return nil
+}
+
+// Entry is a cached response
+type Entry struct {
+	Status      int               `json:"status,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Payload     []byte            `json:"payload"`
+	Expires     time.Time         `json:"expires"`
+}
+
+// Expired reports whether the entry is no longer served at now
+func (e Entry) Expired(now time.Time) bool {
+	return !now.Before(e.Expires)
+}
+
+// tempSuffix ends the names of entry files still being written
+const tempSuffix = ".tmp"
+
+// persisted is the file form of an entry
+type persisted struct {
+	Key   string `json:"key"`
+	Entry Entry  `json:"entry"`
+}
+
+type item struct {
+	key   string
+	entry Entry
+}
+
+// Store keeps entries in least recently used order, evicting the oldest when
+// it is full. With a directory, every change is written through to a file
+// per entry.
+type Store struct {
+	maxEntries int
+	directory  string
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// NewStore creates a store kept in memory only
+func NewStore(maxEntries int) *Store {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Store{maxEntries: maxEntries, now: time.Now, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// Open creates the store of config, resolving a relative directory against
+// confPath and reloading the entries persisted there. Expired files are removed.
+func Open(config Config, confPath string) (*Store, error) {
+	store := NewStore(config.MaxEntries)
+	if config.Directory == "" {
+		return store, nil
+	}
+	directory := config.Directory
+	if !filepath.IsAbs(directory) {
+		directory = filepath.Join(confPath, directory)
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	store.directory = directory
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *Store) load() error {
+	// Temporary files of writes a crash interrupted
+	temps, _ := filepath.Glob(filepath.Join(s.directory, "*"+tempSuffix))
+	for _, temp := range temps {
+		os.Remove(temp)
+	}
+	files, err := filepath.Glob(filepath.Join(s.directory, "*.json"))
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	now := s.now()
+	var loaded []persisted
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("cache: %w", err)
+		}
+		var record persisted
+		// Files left half-written by a crash are dropped like expired ones
+		if json.Unmarshal(data, &record) != nil || record.Entry.Expired(now) || s.path(record.Key) != file {
+			os.Remove(file)
+			continue
+		}
+		loaded = append(loaded, record)
+	}
+	// The entries that expire last are the most recently stored
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Entry.Expires.Before(loaded[j].Entry.Expires) })
+	if excess := len(loaded) - s.maxEntries; excess > 0 {
+		for _, record := range loaded[:excess] {
+			os.Remove(s.path(record.Key))
+		}
+		loaded = loaded[excess:]
+	}
+	for _, record := range loaded {
+		s.entries[record.Key] = s.lru.PushFront(&item{key: record.Key, entry: record.Entry})
+	}
+	return nil
+}
+
+// Get returns the entry of key unless it is missing or expired
+func (s *Store) Get(key string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.entries[key]
+	if !ok {
+		return Entry{}, false
+	}
+	cached := element.Value.(*item)
+	if cached.entry.Expired(s.now()) {
+		s.removeLocked(element)
+		return Entry{}, false
+	}
+	s.lru.MoveToFront(element)
+	return cached.entry, true
+}
+
+// Put stores entry under key, evicting the least recently used entry when
+// the store is full. The file of a persisted entry is written before the lock
+// is taken; only the rename into place happens under it.
+func (s *Store) Put(key string, entry Entry) error {
+	temp, err := s.writeTemp(key, entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if temp != "" {
+		if err := os.Rename(temp, s.path(key)); err != nil {
+			os.Remove(temp)
+			return fmt.Errorf("cache: %w", err)
+		}
+	}
+	if element, ok := s.entries[key]; ok {
+		element.Value.(*item).entry = entry
+		s.lru.MoveToFront(element)
+	} else {
+		s.entries[key] = s.lru.PushFront(&item{key: key, entry: entry})
+		for s.lru.Len() > s.maxEntries {
+			s.removeLocked(s.lru.Back())
+		}
+	}
+	return nil
+}
+
+// Delete removes the entry of key
+func (s *Store) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[key]; ok {
+		s.removeLocked(element)
+	}
+}
+
+// Purge removes expired entries and returns how many there were
+func (s *Store) Purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	purged := 0
+	for element := s.lru.Back(); element != nil; {
+		previous := element.Prev()
+		if element.Value.(*item).entry.Expired(now) {
+			s.removeLocked(element)
+			purged++
+		}
+		element = previous
+	}
+	return purged
+}
+
+// Clear removes every entry
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for element := s.lru.Front(); element != nil; element = s.lru.Front() {
+		s.removeLocked(element)
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet purged
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// Directory returns where entries are persisted, or "" when they are not
+func (s *Store) Directory() string {
+	return s.directory
+}
+
+func (s *Store) removeLocked(element *list.Element) {
+	cached := s.lru.Remove(element).(*item)
+	delete(s.entries, cached.key)
+	if s.directory != "" {
+		os.Remove(s.path(cached.key))
+	}
+}
+
+// writeTemp writes the entry to a temporary file, to be renamed to its final
+// name so a crash never leaves a partial entry there. It returns "" when the
+// store is not persisted.
+func (s *Store) writeTemp(key string, entry Entry) (string, error) {
+	if s.directory == "" {
+		return "", nil
+	}
+	data, err := json.Marshal(persisted{Key: key, Entry: entry})
+	if err != nil {
+		return "", fmt.Errorf("cache: %w", err)
+	}
+	file, err := os.CreateTemp(s.directory, "*"+tempSuffix)
+	if err != nil {
+		return "", fmt.Errorf("cache: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("cache: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// path names the file of a key by its digest, since keys may hold any text
+func (s *Store) path(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return filepath.Join(s.directory, hex.EncodeToString(digest[:])+".json")
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore = NewStore(DefaultMaxEntries)
+)
+
+// SetDefault replaces the store responses are cached in
+func SetDefault(store *Store) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStore = store
+}
+
+// Default returns the store responses are cached in
+func Default() *Store {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStore
+}
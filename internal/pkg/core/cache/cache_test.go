@@ -0,0 +1,135 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreExpiryAndEviction(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewStore(2)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Put("a", Entry{Payload: []byte("A"), Expires: now.Add(time.Minute)}))
+	require.NoError(t, store.Put("b", Entry{Payload: []byte("B"), Expires: now.Add(time.Second)}))
+	_, ok := store.Get("a")
+	require.True(t, ok)
+	require.NoError(t, store.Put("c", Entry{Payload: []byte("C"), Expires: now.Add(time.Minute)}))
+
+	_, ok = store.Get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	entry, ok := store.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "A", string(entry.Payload))
+
+	now = now.Add(2 * time.Minute)
+	_, ok = store.Get("a")
+	assert.False(t, ok, "expired entry is not served")
+	assert.Equal(t, 1, store.Purge())
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestOpenReloadsPersistedEntries(t *testing.T) {
+	confPath := t.TempDir()
+	config := Config{MaxEntries: 2, Directory: "cache"}
+	store, err := Open(config, confPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(confPath, "cache"), store.Directory())
+
+	now := time.Now()
+	entry := Entry{Status: 200, ContentType: "application/json", Headers: map[string]string{"ETag": "1"},
+		Payload: []byte(`{"id":1}`), Expires: now.Add(time.Hour).Round(0)}
+	require.NoError(t, store.Put("GET /orders/1", entry))
+	require.NoError(t, store.Put("GET /orders/2", Entry{Payload: []byte("old"), Expires: now.Add(time.Hour)}))
+	require.NoError(t, store.Put("GET /orders/2", Entry{Payload: []byte("stale"), Expires: now.Add(-time.Second)}))
+	require.NoError(t, os.WriteFile(filepath.Join(store.Directory(), "partial.json"), []byte(`{"key":`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(store.Directory(), "123"+tempSuffix), []byte(`{"key":`), 0o600))
+
+	reopened, err := Open(config, confPath)
+	require.NoError(t, err)
+	loaded, ok := reopened.Get("GET /orders/1")
+	require.True(t, ok)
+	assert.Equal(t, entry.Status, loaded.Status)
+	assert.Equal(t, entry.Headers, loaded.Headers)
+	assert.Equal(t, entry.Payload, loaded.Payload)
+	assert.True(t, entry.Expires.Equal(loaded.Expires))
+	assert.Equal(t, 1, reopened.Len(), "expired and unreadable files are skipped")
+
+	files, err := filepath.Glob(filepath.Join(store.Directory(), "*"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "skipped and temporary files are removed")
+
+	reopened.Delete("GET /orders/1")
+	files, err = filepath.Glob(filepath.Join(store.Directory(), "*"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestConcurrentPuts(t *testing.T) {
+	store, err := Open(Config{MaxEntries: 4, Directory: "cache"}, t.TempDir())
+	require.NoError(t, err)
+	expires := time.Now().Add(time.Hour)
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, store.Put(fmt.Sprintf("key-%d", i%8), Entry{Payload: []byte("x"), Expires: expires}))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 4, store.Len())
+
+	files, err := filepath.Glob(filepath.Join(store.Directory(), "*"))
+	require.NoError(t, err)
+	assert.Len(t, files, 4, "only the files of the kept entries remain")
+}
+
+func TestOpenKeepsLatestEntriesWhenFull(t *testing.T) {
+	confPath := t.TempDir()
+	store, err := Open(Config{MaxEntries: 3, Directory: "cache"}, confPath)
+	require.NoError(t, err)
+	now := time.Now()
+	for i, key := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Put(key, Entry{Expires: now.Add(time.Duration(i+1) * time.Hour)}))
+	}
+
+	reopened, err := Open(Config{MaxEntries: 2, Directory: "cache"}, confPath)
+	require.NoError(t, err)
+	_, ok := reopened.Get("a")
+	assert.False(t, ok)
+	_, ok = reopened.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, reopened.Len())
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.Error(t, Config{MaxEntries: -1}.Validate())
+}
@@ -0,0 +1,422 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package capture implements the flight recorder: an admin-triggered session
+// that writes the next N matching messages, with the trace of every mediator
+// they passed through, to files for offline analysis.
+package capture
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/masking"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// TraceProperty holds the *Trace of a message being captured
+	TraceProperty = "CAPTURE_TRACE"
+	// MaxBodySize caps the bytes recorded per payload
+	MaxBodySize = 1 << 20
+	// MaxCount caps the number of messages a session may capture
+	MaxCount = 1000
+)
+
+// Config holds the [capture] section of deployment.toml
+type Config struct {
+	// Directory receives the capture files, relative to the conf directory
+	Directory string `koanf:"directory"`
+	// RedactHeaders lists headers recorded as "****", in addition to
+	// CredentialHeaders, such as the API key headers of security policies
+	RedactHeaders []string `koanf:"redactHeaders"`
+}
+
+// CredentialHeaders are always recorded as "****". Replaying a capture
+// through an API that authenticates its callers therefore fails unless the
+// API accepts the replay without credentials.
+var CredentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"}
+
+// Filter selects the messages a session captures. Empty fields match anything.
+type Filter struct {
+	API        string `json:"api,omitempty"`
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	Header     string `json:"header,omitempty"`
+	// HeaderPattern is a regular expression the Header value must match; with
+	// only Header set, the header just has to be present
+	HeaderPattern string `json:"headerPattern,omitempty"`
+
+	headerRegexp *regexp.Regexp
+}
+
+func (f *Filter) compile() error {
+	if f.HeaderPattern == "" {
+		return nil
+	}
+	if f.Header == "" {
+		return fmt.Errorf("headerPattern requires header")
+	}
+	re, err := regexp.Compile(f.HeaderPattern)
+	if err != nil {
+		return fmt.Errorf("invalid headerPattern: %w", err)
+	}
+	f.headerRegexp = re
+	return nil
+}
+
+func (f *Filter) matches(api string, r *http.Request) bool {
+	if f.API != "" && f.API != api {
+		return false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(r.URL.Path, f.PathPrefix) {
+		return false
+	}
+	if f.Header != "" {
+		values, ok := r.Header[http.CanonicalHeaderKey(f.Header)]
+		if !ok {
+			return false
+		}
+		if f.headerRegexp != nil && !f.headerRegexp.MatchString(strings.Join(values, ",")) {
+			return false
+		}
+	}
+	return true
+}
+
+// Session describes an active or finished capture session
+type Session struct {
+	ID        string    `json:"id"`
+	Filter    Filter    `json:"filter"`
+	Count     int       `json:"count"`
+	Claimed   int       `json:"claimed"`
+	Directory string    `json:"directory"`
+	Started   time.Time `json:"started"`
+	Expires   time.Time `json:"expires,omitzero"`
+	Active    bool      `json:"active"`
+}
+
+// Step is one mediator execution in a captured message
+type Step struct {
+	Sequence string        `json:"sequence,omitempty"`
+	Mediator string        `json:"mediator"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"durationNanos"`
+	// Payload is the message payload after the mediator ran
+	Payload string `json:"payload,omitempty"`
+}
+
+// Trace collects the mediation steps of a captured message
+type Trace struct {
+	mu     sync.Mutex
+	steps  []Step
+	policy *masking.Policy
+}
+
+// Record appends a mediator execution to the trace, together with the
+// masking policy the message had collected by then
+func (t *Trace) Record(step Step, policy *masking.Policy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, step)
+	if policy != nil {
+		t.policy = policy
+	}
+}
+
+// Policy returns the masking policy of the message at its last recorded
+// step, which applies to every payload of the capture file
+func (t *Trace) Policy() *masking.Policy {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.policy
+}
+
+// Steps returns the recorded steps
+func (t *Trace) Steps() []Step {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Step(nil), t.steps...)
+}
+
+// AddToContext makes mediation record its steps into trace
+func AddToContext(msgContext *synctx.MsgContext, trace *Trace) {
+	msgContext.Properties[TraceProperty] = trace
+}
+
+// TraceFromContext returns the trace of a message being captured, or nil
+func TraceFromContext(msgContext *synctx.MsgContext) *Trace {
+	trace, _ := msgContext.Properties[TraceProperty].(*Trace)
+	return trace
+}
+
+type traceKey struct{}
+
+// WithTrace attaches trace to a request context, for the handler that creates
+// the message context
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceFromRequestContext returns the trace attached by WithTrace, or nil
+func TraceFromRequestContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// Exchange is the HTTP side of a captured message
+type Exchange struct {
+	Method          string              `json:"method"`
+	Host            string              `json:"host,omitempty"`
+	URL             string              `json:"url"`
+	RequestHeaders  map[string][]string `json:"requestHeaders"`
+	RequestBody     string              `json:"requestBody,omitempty"`
+	Status          int                 `json:"status"`
+	ResponseHeaders map[string][]string `json:"responseHeaders"`
+	ResponseBody    string              `json:"responseBody,omitempty"`
+}
+
+// Record is the content of a capture file
+type Record struct {
+	Session  string        `json:"session"`
+	Sequence int           `json:"sequence"`
+	API      string        `json:"api"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"durationNanos"`
+	Exchange Exchange      `json:"exchange"`
+	Steps    []Step        `json:"steps"`
+}
+
+// Claim reserves one slot of a session for a matching message
+type Claim struct {
+	session       string
+	sequence      int
+	directory     string
+	redactHeaders []string
+}
+
+// Write stores record in the session directory. The values of credential
+// headers and of the claim's redacted headers are replaced, and policy masks
+// the request body and the payloads of the steps; the response body was
+// masked when it was written.
+func (c *Claim) Write(record Record, policy *masking.Policy) (string, error) {
+	record.Session = c.session
+	record.Sequence = c.sequence
+	record.Exchange.RequestHeaders = redact(record.Exchange.RequestHeaders, c.redactHeaders)
+	record.Exchange.ResponseHeaders = redact(record.Exchange.ResponseHeaders, c.redactHeaders)
+	if policy != nil {
+		record.Exchange.RequestBody = maskBody(policy, record.Exchange.RequestBody,
+			http.Header(record.Exchange.RequestHeaders).Get("Content-Type"))
+		steps := make([]Step, len(record.Steps))
+		for i, step := range record.Steps {
+			step.Payload = maskBody(policy, step.Payload, "")
+			steps[i] = step
+		}
+		record.Steps = steps
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(c.directory, fmt.Sprintf("%s-%04d.json", c.session, c.sequence))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("error writing capture file: %w", err)
+	}
+	return path, nil
+}
+
+// redact returns a copy of headers with the values of credential headers and
+// of the extra names replaced
+func redact(headers map[string][]string, extra []string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if slices.Contains(CredentialHeaders, canonical) || slices.ContainsFunc(extra, func(header string) bool {
+			return http.CanonicalHeaderKey(header) == canonical
+		}) {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = masking.DefaultReplacement
+			}
+			values = masked
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+// unmaskableBody replaces bodies the masking policy cannot parse, such as
+// truncated ones, rather than recording them unmasked
+const unmaskableBody = "[not recorded: the masking policy could not be applied]"
+
+// maskBody applies policy to a recorded body
+func maskBody(policy *masking.Policy, body, contentType string) string {
+	if body == "" {
+		return body
+	}
+	masked, err := policy.Apply([]byte(body), contentType)
+	if err != nil {
+		return unmaskableBody
+	}
+	return string(masked)
+}
+
+// Recorder manages the capture session. Only one session runs at a time.
+type Recorder struct {
+	mu            sync.Mutex
+	directory     string
+	redactHeaders []string
+	session       *Session
+	now           func() time.Time
+}
+
+// NewRecorder creates a recorder writing to directory
+func NewRecorder(directory string) *Recorder {
+	return &Recorder{directory: directory, now: time.Now}
+}
+
+// Directory returns the directory of new sessions
+func (r *Recorder) Directory() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.directory
+}
+
+// SetDirectory changes the directory of sessions started afterwards
+func (r *Recorder) SetDirectory(directory string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.directory = directory
+}
+
+// SetRedactHeaders sets the headers recorded as "****" in addition to
+// CredentialHeaders
+func (r *Recorder) SetRedactHeaders(headers []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redactHeaders = slices.Clone(headers)
+}
+
+// Start begins a session capturing the next count messages matching filter.
+// A positive ttl ends the session early if not enough messages arrive.
+func (r *Recorder) Start(filter Filter, count int, ttl time.Duration) (Session, error) {
+	if count < 1 || count > MaxCount {
+		return Session{}, fmt.Errorf("count must be between 1 and %d", MaxCount)
+	}
+	if err := filter.compile(); err != nil {
+		return Session{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.activeLocked() {
+		return Session{}, fmt.Errorf("capture session %s is already active", r.session.ID)
+	}
+	if err := os.MkdirAll(r.directory, 0o700); err != nil {
+		return Session{}, fmt.Errorf("error creating capture directory: %w", err)
+	}
+	id := make([]byte, 4)
+	rand.Read(id)
+	now := r.now()
+	session := &Session{
+		ID:        now.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(id),
+		Filter:    filter,
+		Count:     count,
+		Directory: r.directory,
+		Started:   now,
+		Active:    true,
+	}
+	if ttl > 0 {
+		session.Expires = now.Add(ttl)
+	}
+	r.session = session
+	return *session, nil
+}
+
+// Stop ends the active session. It returns false when no session was active.
+func (r *Recorder) Stop() (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.activeLocked() {
+		return Session{}, false
+	}
+	r.session.Active = false
+	return *r.session, true
+}
+
+// Status returns the latest session, active or not
+func (r *Recorder) Status() (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.session == nil {
+		return Session{}, false
+	}
+	r.activeLocked()
+	return *r.session, true
+}
+
+// Claim reserves a slot for the request when a session is active and the
+// request matches its filter. The session ends once every slot is claimed.
+func (r *Recorder) Claim(api string, req *http.Request) *Claim {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.activeLocked() || !r.session.Filter.matches(api, req) {
+		return nil
+	}
+	r.session.Claimed++
+	if r.session.Claimed >= r.session.Count {
+		r.session.Active = false
+	}
+	return &Claim{session: r.session.ID, sequence: r.session.Claimed, directory: r.session.Directory,
+		redactHeaders: r.redactHeaders}
+}
+
+// activeLocked reports whether a session is active, ending an expired one.
+// The caller must hold mu.
+func (r *Recorder) activeLocked() bool {
+	if r.session == nil || !r.session.Active {
+		return false
+	}
+	if !r.session.Expires.IsZero() && r.now().After(r.session.Expires) {
+		r.session.Active = false
+	}
+	return r.session.Active
+}
+
+// Truncate limits body to MaxBodySize for recording
+func Truncate(body []byte) string {
+	if len(body) > MaxBodySize {
+		return string(body[:MaxBodySize]) + "...[truncated]"
+	}
+	return string(body)
+}
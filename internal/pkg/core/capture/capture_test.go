@@ -0,0 +1,166 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package capture
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/masking"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		api    string
+		header string
+		want   bool
+	}{
+		{name: "empty filter", filter: Filter{}, api: "OrdersAPI", want: true},
+		{name: "api", filter: Filter{API: "OrdersAPI"}, api: "OrdersAPI", want: true},
+		{name: "other api", filter: Filter{API: "OrdersAPI"}, api: "StockAPI", want: false},
+		{name: "path prefix", filter: Filter{PathPrefix: "/orders/"}, want: true},
+		{name: "other path", filter: Filter{PathPrefix: "/stock"}, want: false},
+		{name: "header present", filter: Filter{Header: "x-debug"}, header: "anything", want: true},
+		{name: "header missing", filter: Filter{Header: "X-Debug"}, want: false},
+		{name: "header pattern", filter: Filter{Header: "X-Debug", HeaderPattern: "^tenant-[0-9]+$"}, header: "tenant-42", want: true},
+		{name: "header mismatch", filter: Filter{Header: "X-Debug", HeaderPattern: "^tenant-[0-9]+$"}, header: "tenant-x", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.filter.compile())
+			r := httptest.NewRequest(http.MethodGet, "/orders/items", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Debug", tt.header)
+			}
+			assert.Equal(t, tt.want, tt.filter.matches(tt.api, r))
+		})
+	}
+}
+
+func TestRecorder_SessionLifecycle(t *testing.T) {
+	recorder := NewRecorder(t.TempDir())
+	r := httptest.NewRequest(http.MethodGet, "/orders/items", nil)
+
+	assert.Nil(t, recorder.Claim("OrdersAPI", r), "no session")
+	_, err := recorder.Start(Filter{}, 0, 0)
+	assert.EqualError(t, err, "count must be between 1 and 1000")
+	_, err = recorder.Start(Filter{HeaderPattern: "x"}, 1, 0)
+	assert.EqualError(t, err, "headerPattern requires header")
+
+	session, err := recorder.Start(Filter{API: "OrdersAPI"}, 2, 0)
+	assert.NoError(t, err)
+	_, err = recorder.Start(Filter{}, 1, 0)
+	assert.ErrorContains(t, err, "is already active")
+
+	assert.Nil(t, recorder.Claim("StockAPI", r))
+	first := recorder.Claim("OrdersAPI", r)
+	assert.NotNil(t, first)
+	assert.NotNil(t, recorder.Claim("OrdersAPI", r))
+	assert.Nil(t, recorder.Claim("OrdersAPI", r), "the session ends after count messages")
+
+	status, ok := recorder.Status()
+	assert.True(t, ok)
+	assert.False(t, status.Active)
+	assert.Equal(t, 2, status.Claimed)
+
+	path, err := first.Write(Record{API: "OrdersAPI", Steps: []Step{{Mediator: "LogMediator", Success: true}}}, nil)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var record Record
+	assert.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, session.ID, record.Session)
+	assert.Equal(t, 1, record.Sequence)
+	assert.Equal(t, "LogMediator", record.Steps[0].Mediator)
+}
+
+func TestClaim_WriteRedacts(t *testing.T) {
+	recorder := NewRecorder(t.TempDir())
+	recorder.SetRedactHeaders([]string{"x-partner-key"})
+	_, err := recorder.Start(Filter{}, 1, 0)
+	assert.NoError(t, err)
+	claim := recorder.Claim("OrdersAPI", httptest.NewRequest(http.MethodPost, "/orders", nil))
+	policy, err := masking.NewPolicy([]masking.Rule{{Path: "$.card"}})
+	assert.NoError(t, err)
+
+	path, err := claim.Write(Record{
+		API: "OrdersAPI",
+		Exchange: Exchange{
+			RequestHeaders: map[string][]string{
+				"Authorization": {"Bearer secret"},
+				"Cookie":        {"session=secret"},
+				"X-Partner-Key": {"secret"},
+				"Content-Type":  {"application/json"},
+			},
+			RequestBody:     `{"card":"4111111111111111","id":1}`,
+			ResponseHeaders: map[string][]string{"Set-Cookie": {"session=secret"}},
+		},
+		Steps: []Step{
+			{Mediator: "LogMediator", Payload: `{"card":"4111111111111111","id":1}`},
+			{Mediator: "PayloadMediator", Payload: `{"card":"4111`},
+		},
+	}, policy)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.NotContains(t, string(data), "4111")
+
+	var record Record
+	assert.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, map[string][]string{
+		"Authorization": {"****"},
+		"Cookie":        {"****"},
+		"X-Partner-Key": {"****"},
+		"Content-Type":  {"application/json"},
+	}, record.Exchange.RequestHeaders)
+	assert.Equal(t, []string{"****"}, record.Exchange.ResponseHeaders["Set-Cookie"])
+	assert.JSONEq(t, `{"card":"****","id":1}`, record.Exchange.RequestBody)
+	assert.JSONEq(t, `{"card":"****","id":1}`, record.Steps[0].Payload)
+	assert.Equal(t, unmaskableBody, record.Steps[1].Payload, "truncated payloads are not recorded unmasked")
+}
+
+func TestRecorder_ExpiryAndStop(t *testing.T) {
+	recorder := NewRecorder(t.TempDir())
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder.now = func() time.Time { return now }
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	_, err := recorder.Start(Filter{}, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.NotNil(t, recorder.Claim("OrdersAPI", r))
+	now = now.Add(2 * time.Minute)
+	assert.Nil(t, recorder.Claim("OrdersAPI", r), "expired sessions stop capturing")
+	_, ok := recorder.Stop()
+	assert.False(t, ok)
+
+	_, err = recorder.Start(Filter{}, 10, 0)
+	assert.NoError(t, err)
+	session, ok := recorder.Stop()
+	assert.True(t, ok)
+	assert.False(t, session.Active)
+}
@@ -0,0 +1,259 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package checksum fingerprints the deployed configuration, deployment.toml
+// and every artifact file, so that instances of a fleet can be compared and
+// files edited on disk after deployment can be reported as drift.
+package checksum
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultCheckInterval is how often the files on disk are compared with the
+// deployed configuration unless configured otherwise
+const DefaultCheckInterval = time.Minute
+
+// Config holds the [drift] section of deployment.toml
+type Config struct {
+	CheckInterval string `koanf:"checkInterval"`
+}
+
+// Validate checks that the check interval is a positive duration
+func (c Config) Validate() error {
+	_, err := c.Interval()
+	return err
+}
+
+// Interval returns the configured check interval or DefaultCheckInterval
+func (c Config) Interval() (time.Duration, error) {
+	if c.CheckInterval == "" {
+		return DefaultCheckInterval, nil
+	}
+	interval, err := time.ParseDuration(c.CheckInterval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("checkInterval %q must be a positive duration", c.CheckInterval)
+	}
+	return interval, nil
+}
+
+// Manifest is the checksum of a configuration and the digests it was computed from
+type Manifest struct {
+	Checksum string `json:"checksum"`
+	// Files maps each file, relative to the runtime home, to its SHA-256 digest
+	Files      map[string]string `json:"files"`
+	ComputedAt time.Time         `json:"computedAt"`
+}
+
+// Compute hashes the configuration file and the files under the artifacts
+// directory. Files that do not exist are left out of the manifest. The
+// checksum only depends on file names and contents, so instances with the
+// same configuration report the same checksum.
+func Compute(configFile, artifactsDir string) (Manifest, error) {
+	manifest := Manifest{Files: make(map[string]string), ComputedAt: time.Now()}
+	home := filepath.Dir(artifactsDir)
+	add := func(path string) error {
+		digest, err := digestFile(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(home, path)
+		if err != nil {
+			name = path
+		}
+		manifest.Files[filepath.ToSlash(name)] = digest
+		return nil
+	}
+
+	if err := add(configFile); err != nil && !os.IsNotExist(err) {
+		return Manifest{}, err
+	}
+	err := filepath.WalkDir(artifactsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == artifactsDir && os.IsNotExist(err) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return add(path)
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+	manifest.Checksum = manifest.checksum()
+	return manifest, nil
+}
+
+// checksum hashes the sorted file names with their digests
+func (m Manifest) checksum() string {
+	names := make([]string, 0, len(m.Files))
+	for name := range m.Files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s\x00%s\n", name, m.Files[name])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func digestFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// Drift lists the files that differ between two manifests
+type Drift struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+// Empty reports whether the manifests had the same files and contents
+func (d Drift) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// Diff compares the files of the current manifest with the deployed one
+func Diff(deployed, current Manifest) Drift {
+	var drift Drift
+	for name, digest := range current.Files {
+		deployedDigest, ok := deployed.Files[name]
+		switch {
+		case !ok:
+			drift.Added = append(drift.Added, name)
+		case deployedDigest != digest:
+			drift.Modified = append(drift.Modified, name)
+		}
+	}
+	for name := range deployed.Files {
+		if _, ok := current.Files[name]; !ok {
+			drift.Removed = append(drift.Removed, name)
+		}
+	}
+	slices.Sort(drift.Added)
+	slices.Sort(drift.Removed)
+	slices.Sort(drift.Modified)
+	return drift
+}
+
+// Status is the deployed checksum with the drift found by the last check
+type Status struct {
+	Deployed Manifest `json:"deployed"`
+	// Current is the checksum of the files on disk at the last check
+	Current   string    `json:"current,omitempty"`
+	CheckedAt time.Time `json:"checkedAt,omitempty"`
+	Drift     Drift     `json:"drift"`
+}
+
+// Detector records the manifest of the deployed configuration and compares it
+// with the files on disk
+type Detector struct {
+	configFile   string
+	artifactsDir string
+	logger       *slog.Logger
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewDetector creates a detector for the given configuration file and artifacts directory
+func NewDetector(configFile, artifactsDir string, logger *slog.Logger) *Detector {
+	return &Detector{configFile: configFile, artifactsDir: artifactsDir, logger: logger}
+}
+
+// Baseline computes the manifest of the configuration being deployed
+func (d *Detector) Baseline() (Manifest, error) {
+	manifest, err := Compute(d.configFile, d.artifactsDir)
+	if err != nil {
+		return Manifest{}, err
+	}
+	d.mu.Lock()
+	d.status = Status{Deployed: manifest, Current: manifest.Checksum, CheckedAt: manifest.ComputedAt}
+	d.mu.Unlock()
+	return manifest, nil
+}
+
+// Check compares the files on disk with the deployed manifest and logs a
+// warning when they have drifted since the previous check
+func (d *Detector) Check() (Drift, error) {
+	current, err := Compute(d.configFile, d.artifactsDir)
+	if err != nil {
+		return Drift{}, err
+	}
+	d.mu.Lock()
+	previous := d.status.Current
+	drift := Diff(d.status.Deployed, current)
+	d.status.Current = current.Checksum
+	d.status.CheckedAt = current.ComputedAt
+	d.status.Drift = drift
+	deployed := d.status.Deployed.Checksum
+	d.mu.Unlock()
+
+	if !drift.Empty() && current.Checksum != previous {
+		d.logger.Warn("Configuration on disk differs from the deployed configuration",
+			"deployed", deployed,
+			"current", current.Checksum,
+			"added", drift.Added,
+			"removed", drift.Removed,
+			"modified", drift.Modified)
+	}
+	return drift, nil
+}
+
+// Status returns the deployed manifest and the result of the last check
+func (d *Detector) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// Run checks for drift every interval until ctx is done
+func (d *Detector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.Check(); err != nil {
+				d.logger.Error("Error checking configuration drift", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package checksum
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestCompute(t *testing.T) {
+	homeA, homeB := t.TempDir(), t.TempDir()
+	for _, home := range []string{homeA, homeB} {
+		writeFile(t, filepath.Join(home, "conf", "deployment.toml"), "[server]\nhostname = \"localhost\"\n")
+		writeFile(t, filepath.Join(home, "artifacts", "APIs", "orders.xml"), "<api name=\"Orders\"/>")
+	}
+
+	a, err := Compute(filepath.Join(homeA, "conf", "deployment.toml"), filepath.Join(homeA, "artifacts"))
+	require.NoError(t, err)
+	b, err := Compute(filepath.Join(homeB, "conf", "deployment.toml"), filepath.Join(homeB, "artifacts"))
+	require.NoError(t, err)
+	assert.Equal(t, a.Checksum, b.Checksum, "the checksum does not depend on where the runtime is installed")
+	assert.Contains(t, a.Files, "conf/deployment.toml")
+	assert.Contains(t, a.Files, "artifacts/APIs/orders.xml")
+
+	writeFile(t, filepath.Join(homeB, "artifacts", "APIs", "orders.xml"), "<api name=\"Orders\" context=\"/v2\"/>")
+	b, err = Compute(filepath.Join(homeB, "conf", "deployment.toml"), filepath.Join(homeB, "artifacts"))
+	require.NoError(t, err)
+	assert.NotEqual(t, a.Checksum, b.Checksum)
+
+	empty, err := Compute(filepath.Join(t.TempDir(), "deployment.toml"), filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err, "missing files are left out of the manifest")
+	assert.Empty(t, empty.Files)
+}
+
+func TestDetector_Check(t *testing.T) {
+	home := t.TempDir()
+	configFile := filepath.Join(home, "conf", "deployment.toml")
+	artifactsDir := filepath.Join(home, "artifacts")
+	writeFile(t, configFile, "[server]\n")
+	writeFile(t, filepath.Join(artifactsDir, "APIs", "orders.xml"), "<api/>")
+	writeFile(t, filepath.Join(artifactsDir, "Sequences", "log.xml"), "<sequence/>")
+
+	detector := NewDetector(configFile, artifactsDir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	deployed, err := detector.Baseline()
+	require.NoError(t, err)
+
+	drift, err := detector.Check()
+	require.NoError(t, err)
+	assert.True(t, drift.Empty())
+
+	writeFile(t, configFile, "[server]\nhostname = \"changed\"\n")
+	writeFile(t, filepath.Join(artifactsDir, "APIs", "payments.xml"), "<api/>")
+	require.NoError(t, os.Remove(filepath.Join(artifactsDir, "Sequences", "log.xml")))
+
+	drift, err = detector.Check()
+	require.NoError(t, err)
+	assert.Equal(t, Drift{
+		Added:    []string{"artifacts/APIs/payments.xml"},
+		Removed:  []string{"artifacts/Sequences/log.xml"},
+		Modified: []string{"conf/deployment.toml"},
+	}, drift)
+
+	status := detector.Status()
+	assert.Equal(t, deployed.Checksum, status.Deployed.Checksum)
+	assert.NotEqual(t, deployed.Checksum, status.Current)
+}
+
+func TestConfig_Interval(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+		wantErr  bool
+	}{
+		{name: "default", config: Config{}, expected: "1m0s"},
+		{name: "configured", config: Config{CheckInterval: "30s"}, expected: "30s"},
+		{name: "invalid", config: Config{CheckInterval: "soon"}, wantErr: true},
+		{name: "not positive", config: Config{CheckInterval: "0s"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, err := tt.config.Interval()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, interval.String())
+		})
+	}
+}
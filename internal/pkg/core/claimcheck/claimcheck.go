@@ -0,0 +1,298 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package claimcheck keeps large payloads in a blob store so messages can
+// carry a small reference token through transports with size limits, such as
+// Kafka, and have the payload restored at the other end. Payloads are kept in
+// memory by default; the [claimCheck] directory adds a filesystem store, and
+// shared stores such as S3 or Redis can be registered under their own name.
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Names of the built-in stores
+const (
+	MemoryStoreName = "memory"
+	FileStoreName   = "file"
+)
+
+// ErrNotFound is returned for tokens a store holds no payload for
+var ErrNotFound = errors.New("claim check not found")
+
+// Blob is a stored payload
+type Blob struct {
+	ContentType string
+	Data        []byte
+}
+
+// Store keeps payloads by token
+type Store interface {
+	Put(ctx context.Context, token string, blob Blob) error
+	// Get returns ErrNotFound when the store has no payload for the token
+	Get(ctx context.Context, token string) (Blob, error)
+	// Delete removes the payload; deleting a missing token is not an error
+	Delete(ctx context.Context, token string) error
+}
+
+// Config holds the [claimCheck] section of deployment.toml
+type Config struct {
+	// Directory is where the file store keeps payloads, relative to the conf
+	// directory
+	Directory string `koanf:"directory"`
+}
+
+// Validate reports configuration errors in the [claimCheck] section
+func (c Config) Validate() error {
+	if c.Directory == "" {
+		return errors.New("claimCheck: directory is required")
+	}
+	return nil
+}
+
+// NewToken returns a random token for a new payload
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validToken keeps tokens from naming files outside a store's directory
+func validToken(token string) error {
+	if token == "" || len(token) > 128 {
+		return fmt.Errorf("invalid claim check token '%s'", token)
+	}
+	for _, c := range token {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("invalid claim check token '%s'", token)
+		}
+	}
+	return nil
+}
+
+// Defaults of the in-memory store and of the store payload mediator
+const (
+	DefaultMemoryTTL        = time.Hour
+	DefaultMemoryMaxEntries = 10000
+	DefaultMaxSize          = 10 << 20
+)
+
+// MemoryStore keeps payloads in memory, for a single instance. Payloads
+// expire after a TTL and are removed lazily, at most once per sweep interval;
+// when the store is full the payload closest to expiry is evicted.
+type MemoryStore struct {
+	now           func() time.Time
+	ttl           time.Duration
+	maxEntries    int
+	sweepInterval time.Duration
+
+	mu        sync.Mutex
+	blobs     map[string]memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	blob   Blob
+	expiry time.Time
+}
+
+// NewMemoryStore returns a store with DefaultMemoryTTL and
+// DefaultMemoryMaxEntries
+func NewMemoryStore() *MemoryStore {
+	return NewMemoryStoreWithLimits(DefaultMemoryTTL, DefaultMemoryMaxEntries)
+}
+
+// NewMemoryStoreWithLimits returns a store keeping at most maxEntries
+// payloads, each for ttl
+func NewMemoryStoreWithLimits(ttl time.Duration, maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		now:           time.Now,
+		ttl:           ttl,
+		maxEntries:    maxEntries,
+		sweepInterval: time.Minute,
+		blobs:         make(map[string]memoryEntry),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, token string, blob Blob) error {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= s.sweepInterval {
+		s.sweep(now)
+	}
+	if _, ok := s.blobs[token]; !ok && len(s.blobs) >= s.maxEntries {
+		s.evict()
+	}
+	s.blobs[token] = memoryEntry{
+		blob:   Blob{ContentType: blob.ContentType, Data: bytes.Clone(blob.Data)},
+		expiry: now.Add(s.ttl),
+	}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, token string) (Blob, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.blobs[token]
+	if !ok || !now.Before(entry.expiry) {
+		return Blob{}, ErrNotFound
+	}
+	return Blob{ContentType: entry.blob.ContentType, Data: bytes.Clone(entry.blob.Data)}, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, token)
+	return nil
+}
+
+// Len returns the number of payloads held, including expired payloads not
+// yet swept
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.blobs)
+}
+
+// sweep removes expired payloads; s.mu must be held
+func (s *MemoryStore) sweep(now time.Time) {
+	for token, entry := range s.blobs {
+		if !now.Before(entry.expiry) {
+			delete(s.blobs, token)
+		}
+	}
+	s.lastSweep = now
+}
+
+// evict removes the payload closest to expiry; s.mu must be held
+func (s *MemoryStore) evict() {
+	oldest := ""
+	var oldestExpiry time.Time
+	for token, entry := range s.blobs {
+		if oldest == "" || entry.expiry.Before(oldestExpiry) {
+			oldest, oldestExpiry = token, entry.expiry
+		}
+	}
+	delete(s.blobs, oldest)
+}
+
+// FileStore keeps each payload in a file named after its token. The first
+// line of the file is the content type.
+type FileStore struct {
+	directory string
+}
+
+// OpenFileStore creates the store of config, creating its directory
+func OpenFileStore(config Config, confPath string) (*FileStore, error) {
+	directory := config.Directory
+	if !filepath.IsAbs(directory) {
+		directory = filepath.Join(confPath, directory)
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, fmt.Errorf("claimCheck: cannot create directory: %w", err)
+	}
+	return &FileStore{directory: directory}, nil
+}
+
+func (s *FileStore) Put(ctx context.Context, token string, blob Blob) error {
+	if err := validToken(token); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.directory, ".claim-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(blob.ContentType + "\n")
+	if err == nil {
+		_, err = file.Write(blob.Data)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filepath.Join(s.directory, token))
+}
+
+func (s *FileStore) Get(ctx context.Context, token string) (Blob, error) {
+	if validToken(token) != nil {
+		// No payload can have been stored under it
+		return Blob{}, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.directory, token))
+	if errors.Is(err, os.ErrNotExist) {
+		return Blob{}, ErrNotFound
+	}
+	if err != nil {
+		return Blob{}, err
+	}
+	contentType, payload, _ := bytes.Cut(data, []byte("\n"))
+	return Blob{ContentType: string(contentType), Data: payload}, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, token string) error {
+	if err := validToken(token); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.directory, token))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]Store{MemoryStoreName: NewMemoryStore()}
+)
+
+// Register makes a store available to claim check mediators under the name
+func Register(name string, store Store) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	stores[name] = store
+}
+
+// Lookup returns the store registered under the name
+func Lookup(name string) (Store, error) {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+	store, ok := stores[name]
+	if !ok {
+		return nil, fmt.Errorf("claim check store %s is not registered", name)
+	}
+	return store, nil
+}
@@ -0,0 +1,131 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package claimcheck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	file, err := OpenFileStore(Config{Directory: "claims"}, t.TempDir())
+	require.NoError(t, err)
+	stores := map[string]Store{"memory": NewMemoryStore(), "file": file}
+	ctx := context.Background()
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			token, err := NewToken()
+			require.NoError(t, err)
+			blob := Blob{ContentType: "application/xml", Data: []byte("<order>\n<id>1</id>\n</order>")}
+			require.NoError(t, store.Put(ctx, token, blob))
+
+			got, err := store.Get(ctx, token)
+			require.NoError(t, err)
+			assert.Equal(t, blob, got)
+
+			require.NoError(t, store.Delete(ctx, token))
+			_, err = store.Get(ctx, token)
+			assert.ErrorIs(t, err, ErrNotFound)
+			assert.NoError(t, store.Delete(ctx, token), "deleting twice is not an error")
+		})
+	}
+}
+
+func TestMemoryStore_Limits(t *testing.T) {
+	store := NewMemoryStoreWithLimits(time.Hour, 2)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	blob := Blob{ContentType: "text/plain", Data: []byte("x")}
+
+	require.NoError(t, store.Put(ctx, "a", blob))
+	now = now.Add(time.Second)
+	require.NoError(t, store.Put(ctx, "b", blob))
+	now = now.Add(time.Second)
+	require.NoError(t, store.Put(ctx, "c", blob))
+	assert.Equal(t, 2, store.Len(), "the store is bounded")
+	_, err := store.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrNotFound, "the oldest payload is evicted")
+	_, err = store.Get(ctx, "c")
+	assert.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "c", blob), "replacing a payload evicts nothing")
+	_, err = store.Get(ctx, "b")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = store.Get(ctx, "c")
+	assert.ErrorIs(t, err, ErrNotFound, "payloads expire after the TTL")
+	require.NoError(t, store.Put(ctx, "d", blob))
+	assert.Equal(t, 1, store.Len(), "expired payloads are swept")
+}
+
+func TestFileStore_Tokens(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFileStore(Config{Directory: dir}, "conf")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, token := range []string{"", "../secrets", "a/b", "a.b"} {
+		assert.Error(t, store.Put(ctx, token, Blob{Data: []byte("x")}), token)
+		_, err := store.Get(ctx, token)
+		assert.ErrorIs(t, err, ErrNotFound, token)
+	}
+
+	require.NoError(t, store.Put(ctx, "order-1", Blob{ContentType: "text/plain", Data: []byte("hello")}))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary files are left behind")
+	data, err := os.ReadFile(filepath.Join(dir, "order-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain\nhello", string(data))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{Directory: "claims"}.Validate())
+	assert.EqualError(t, Config{}.Validate(), "claimCheck: directory is required")
+}
+
+func TestRegistry(t *testing.T) {
+	store, err := Lookup(MemoryStoreName)
+	require.NoError(t, err)
+	assert.NotNil(t, store)
+
+	_, err = Lookup("s3")
+	assert.EqualError(t, err, "claim check store s3 is not registered")
+
+	shared := NewMemoryStore()
+	Register("shared", shared)
+	t.Cleanup(func() {
+		storesMu.Lock()
+		delete(stores, "shared")
+		storesMu.Unlock()
+	})
+	store, err = Lookup("shared")
+	require.NoError(t, err)
+	assert.Same(t, shared, store)
+}
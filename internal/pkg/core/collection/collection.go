@@ -0,0 +1,356 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package collection exports deployed APIs as Postman collections or HAR
+// archives, with a sample request for every method of every resource, to hand
+// to API consumers.
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+const (
+	FormatPostman = "postman"
+	FormatHAR     = "har"
+
+	postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+)
+
+// Request is the sample request of a resource method
+type Request struct {
+	Name   string
+	Method string
+	// BasePath and Path are joined to form the request path; path parameters
+	// keep their {name} placeholders
+	BasePath        string
+	Path            string
+	PathParameters  []string
+	QueryParameters []string // sorted query parameter names
+	Headers         map[string]string
+	Body            string
+}
+
+// Requests builds the sample requests of an API. Methods that carry a body get
+// an empty JSON object, and secured APIs an Authorization header to fill in.
+func Requests(api artifacts.API) []Request {
+	var requests []Request
+	for _, resource := range api.Resources {
+		var queryParameters []string
+		for name := range resource.URITemplate.QueryParameters {
+			queryParameters = append(queryParameters, name)
+		}
+		sort.Strings(queryParameters)
+		for _, method := range resource.Methods {
+			request := Request{
+				Name:            method + " " + resource.URITemplate.FullTemplate,
+				Method:          method,
+				BasePath:        api.BasePath(),
+				Path:            resource.URITemplate.PathTemplate,
+				PathParameters:  resource.URITemplate.PathParameters,
+				QueryParameters: queryParameters,
+				Headers:         map[string]string{"Accept": "application/json"},
+			}
+			if api.Hostname != "" {
+				request.Headers["Host"] = api.Hostname
+			}
+			if api.SecurityPolicy != "" || api.Authorization != "" {
+				request.Headers["Authorization"] = "Bearer <token>"
+			}
+			switch method {
+			case "POST", "PUT", "PATCH":
+				request.Headers["Content-Type"] = "application/json"
+				request.Body = "{}"
+			}
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// fullPath joins the base path and resource path without doubling slashes
+func (r Request) fullPath() string {
+	path := strings.TrimSuffix(r.BasePath, "/") + r.Path
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// sortedHeaders returns the header names in a stable order
+func (r Request) sortedHeaders() []string {
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export renders the APIs in the given format
+func Export(format, name, baseURL string, apis []artifacts.API) ([]byte, error) {
+	sortAPIs(apis)
+	switch format {
+	case FormatPostman, "":
+		return Postman(name, baseURL, apis)
+	case FormatHAR:
+		return HAR(baseURL, apis)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q, expected %s or %s", format, FormatPostman, FormatHAR)
+	}
+}
+
+func sortAPIs(apis []artifacts.API) {
+	sort.Slice(apis, func(i, j int) bool { return apis[i].Key() < apis[j].Key() })
+}
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Variable []postmanKeyValue `json:"variable"`
+	Item     []postmanItem     `json:"item"`
+}
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+type postmanKeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item,omitempty"`
+	Request *postmanRequest `json:"request,omitempty"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    postmanURL        `json:"url"`
+	Body   *postmanBody      `json:"body,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Host     []string          `json:"host"`
+	Path     []string          `json:"path"`
+	Query    []postmanKeyValue `json:"query,omitempty"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanBody struct {
+	Mode string `json:"mode"`
+	Raw  string `json:"raw"`
+}
+
+// Postman renders a Postman v2.1 collection with a folder per API. The base
+// URL is the collection variable baseUrl, and path and query parameters are
+// left for the consumer to fill in.
+func Postman(name, baseURL string, apis []artifacts.API) ([]byte, error) {
+	collection := postmanCollection{
+		Info:     postmanInfo{Name: name, Description: "Sample requests for the APIs deployed on Synapse", Schema: postmanSchema},
+		Variable: []postmanKeyValue{{Key: "baseUrl", Value: baseURL}},
+		Item:     []postmanItem{},
+	}
+	for _, api := range apis {
+		folder := postmanItem{Name: api.Name, Item: []postmanItem{}}
+		if api.Version != "" {
+			folder.Name += " " + api.Version
+		}
+		for _, request := range Requests(api) {
+			folder.Item = append(folder.Item, postmanItem{Name: request.Name, Request: postmanRequestOf(request)})
+		}
+		collection.Item = append(collection.Item, folder)
+	}
+	return json.MarshalIndent(collection, "", "  ")
+}
+
+func postmanRequestOf(request Request) *postmanRequest {
+	target := postmanURL{Host: []string{"{{baseUrl}}"}, Path: []string{}}
+	for _, segment := range strings.Split(strings.Trim(request.fullPath(), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segment = ":" + strings.Trim(segment, "{}")
+		}
+		target.Path = append(target.Path, segment)
+	}
+	for _, parameter := range request.PathParameters {
+		target.Variable = append(target.Variable, postmanKeyValue{Key: parameter, Value: ""})
+	}
+	var query []string
+	for _, parameter := range request.QueryParameters {
+		target.Query = append(target.Query, postmanKeyValue{Key: parameter, Value: ""})
+		query = append(query, parameter+"=")
+	}
+	target.Raw = "{{baseUrl}}/" + strings.Join(target.Path, "/")
+	if len(query) > 0 {
+		target.Raw += "?" + strings.Join(query, "&")
+	}
+
+	converted := &postmanRequest{Method: request.Method, Header: []postmanKeyValue{}, URL: target}
+	for _, header := range request.sortedHeaders() {
+		converted.Header = append(converted.Header, postmanKeyValue{Key: header, Value: request.Headers[header]})
+	}
+	if request.Body != "" {
+		converted.Body = &postmanBody{Mode: "raw", Raw: request.Body}
+	}
+	return converted
+}
+
+type harArchive struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	Comment         string         `json:"comment,omitempty"`
+	StartedDateTime string         `json:"startedDateTime"`
+	Time            int            `json:"time"`
+	Request         harRequest     `json:"request"`
+	Response        harResponse    `json:"response"`
+	Cache           struct{}       `json:"cache"`
+	Timings         map[string]int `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HAR renders a HAR 1.2 archive with an entry per sample request. The entries
+// were never sent, so their responses are empty and their timings zero.
+func HAR(baseURL string, apis []artifacts.API) ([]byte, error) {
+	archive := harArchive{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "synapse", Version: "1.0"},
+		Entries: []harEntry{},
+	}}
+	for _, api := range apis {
+		for _, request := range Requests(api) {
+			entry, err := harEntryOf(baseURL, api, request)
+			if err != nil {
+				return nil, err
+			}
+			archive.Log.Entries = append(archive.Log.Entries, entry)
+		}
+	}
+	return json.MarshalIndent(archive, "", "  ")
+}
+
+func harEntryOf(baseURL string, api artifacts.API, request Request) (harEntry, error) {
+	if _, err := url.Parse(baseURL); err != nil {
+		return harEntry{}, fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	converted := harRequest{
+		Method:      request.Method,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameValue{},
+		Headers:     []harNameValue{},
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(request.Body),
+	}
+	// Path parameters keep their {name} placeholders for the consumer to fill in
+	converted.URL = strings.TrimSuffix(baseURL, "/") + request.fullPath()
+	query := url.Values{}
+	for _, parameter := range request.QueryParameters {
+		query.Set(parameter, "")
+		converted.QueryString = append(converted.QueryString, harNameValue{Name: parameter, Value: ""})
+	}
+	if len(query) > 0 {
+		converted.URL += "?" + query.Encode()
+	}
+	for _, header := range request.sortedHeaders() {
+		converted.Headers = append(converted.Headers, harNameValue{Name: header, Value: request.Headers[header]})
+	}
+	if request.Body != "" {
+		converted.PostData = &harPostData{MimeType: request.Headers["Content-Type"], Text: request.Body}
+	}
+	return harEntry{
+		Comment:         api.Name + ": " + request.Name,
+		StartedDateTime: "1970-01-01T00:00:00Z",
+		Time:            0,
+		Request:         converted,
+		Response: harResponse{
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: map[string]int{"send": 0, "wait": 0, "receive": 0},
+	}, nil
+}
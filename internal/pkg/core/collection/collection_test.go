@@ -0,0 +1,123 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package collection
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ordersAPI() artifacts.API {
+	return artifacts.API{
+		Name:           "Orders",
+		Context:        "/orders/",
+		Version:        "2",
+		VersionType:    "url",
+		SecurityPolicy: "jwt",
+		Resources: []artifacts.Resource{
+			{
+				Methods: []string{"GET"},
+				URITemplate: artifacts.URITemplateInfo{
+					FullTemplate:    "/{id}?expand={expand}&fields={fields}",
+					PathTemplate:    "/{id}",
+					PathParameters:  []string{"id"},
+					QueryParameters: map[string]string{"fields": "fields", "expand": "expand"},
+				},
+			},
+			{
+				Methods:     []string{"POST"},
+				URITemplate: artifacts.URITemplateInfo{FullTemplate: "/", PathTemplate: "/"},
+			},
+		},
+	}
+}
+
+func TestRequests(t *testing.T) {
+	requests := Requests(ordersAPI())
+	require.Len(t, requests, 2)
+
+	get := requests[0]
+	assert.Equal(t, "GET /{id}?expand={expand}&fields={fields}", get.Name)
+	assert.Equal(t, "/orders/2/{id}", get.fullPath())
+	assert.Equal(t, []string{"expand", "fields"}, get.QueryParameters)
+	assert.Equal(t, "Bearer <token>", get.Headers["Authorization"])
+	assert.Empty(t, get.Body)
+
+	post := requests[1]
+	assert.Equal(t, "/orders/2/", post.fullPath())
+	assert.Equal(t, "application/json", post.Headers["Content-Type"])
+	assert.Equal(t, "{}", post.Body)
+}
+
+func TestPostman(t *testing.T) {
+	data, err := Export(FormatPostman, "Shop", "http://localhost:8290", []artifacts.API{ordersAPI()})
+	require.NoError(t, err)
+
+	var exported postmanCollection
+	require.NoError(t, json.Unmarshal(data, &exported))
+	assert.Equal(t, postmanSchema, exported.Info.Schema)
+	assert.Equal(t, []postmanKeyValue{{Key: "baseUrl", Value: "http://localhost:8290"}}, exported.Variable)
+	require.Len(t, exported.Item, 1)
+	assert.Equal(t, "Orders 2", exported.Item[0].Name)
+	require.Len(t, exported.Item[0].Item, 2)
+
+	get := exported.Item[0].Item[0].Request
+	assert.Equal(t, "{{baseUrl}}/orders/2/:id?expand=&fields=", get.URL.Raw)
+	assert.Equal(t, []string{"orders", "2", ":id"}, get.URL.Path)
+	assert.Equal(t, []postmanKeyValue{{Key: "id"}}, get.URL.Variable)
+	assert.Nil(t, get.Body)
+
+	post := exported.Item[0].Item[1].Request
+	assert.Equal(t, "POST", post.Method)
+	require.NotNil(t, post.Body)
+	assert.Equal(t, "raw", post.Body.Mode)
+}
+
+func TestHAR(t *testing.T) {
+	data, err := Export(FormatHAR, "Shop", "https://api.example.com/", []artifacts.API{ordersAPI()})
+	require.NoError(t, err)
+
+	var exported harArchive
+	require.NoError(t, json.Unmarshal(data, &exported))
+	assert.Equal(t, "1.2", exported.Log.Version)
+	require.Len(t, exported.Log.Entries, 2)
+
+	get := exported.Log.Entries[0].Request
+	assert.Equal(t, "https://api.example.com/orders/2/{id}?expand=&fields=", get.URL)
+	assert.Len(t, get.QueryString, 2)
+	assert.Nil(t, get.PostData)
+
+	post := exported.Log.Entries[1].Request
+	require.NotNil(t, post.PostData)
+	assert.Equal(t, "application/json", post.PostData.MimeType)
+	assert.Equal(t, 2, post.BodySize)
+}
+
+func TestExport_Errors(t *testing.T) {
+	_, err := Export("openapi", "Shop", "http://localhost:8290", nil)
+	assert.ErrorContains(t, err, "unsupported export format")
+
+	_, err = Export(FormatHAR, "Shop", "http://[::1", []artifacts.API{ordersAPI()})
+	assert.ErrorContains(t, err, "invalid base URL")
+}
@@ -0,0 +1,185 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package datasource opens the SQL databases declared as
+// [[database.datasource]] entries in deployment.toml, each a database/sql
+// connection pool, and makes them available by name to the dblookup and
+// dbreport mediators. Drivers are not bundled: a build adds the drivers it needs by
+// importing them, e.g. github.com/lib/pq for "postgres".
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultQueryTimeout bounds a statement when the data source sets no
+// queryTimeout
+const DefaultQueryTimeout = 30 * time.Second
+
+// Config holds the [database] section of deployment.toml
+type Config struct {
+	DataSources []DataSourceConfig `koanf:"datasource"`
+}
+
+// DataSourceConfig holds one [[database.datasource]] entry
+type DataSourceConfig struct {
+	Name string `koanf:"name"`
+	// Driver is the name a database/sql driver registered itself under
+	Driver string `koanf:"driver"`
+	DSN    string `koanf:"dsn"`
+	// MaxOpenConns bounds the pool, unbounded when zero
+	MaxOpenConns    int    `koanf:"maxOpenConns"`
+	MaxIdleConns    int    `koanf:"maxIdleConns"`
+	ConnMaxLifetime string `koanf:"connMaxLifetime"`
+	QueryTimeout    string `koanf:"queryTimeout"`
+}
+
+// Validate checks that every data source is complete and uniquely named.
+// Drivers are checked when the data sources are opened, since they register
+// themselves when the binary starts.
+func (c Config) Validate() error {
+	names := make(map[string]bool)
+	for _, source := range c.DataSources {
+		if source.Name == "" {
+			return errors.New("datasource name is required")
+		}
+		if names[source.Name] {
+			return fmt.Errorf("duplicate datasource: %s", source.Name)
+		}
+		names[source.Name] = true
+		if _, _, err := source.durations(); err != nil {
+			return fmt.Errorf("datasource %s: %w", source.Name, err)
+		}
+		switch {
+		case source.Driver == "":
+			return fmt.Errorf("datasource %s: driver is required", source.Name)
+		case source.DSN == "":
+			return fmt.Errorf("datasource %s: dsn is required", source.Name)
+		case source.MaxOpenConns < 0 || source.MaxIdleConns < 0:
+			return fmt.Errorf("datasource %s: maxOpenConns and maxIdleConns must not be negative", source.Name)
+		}
+	}
+	return nil
+}
+
+func (c DataSourceConfig) durations() (lifetime, timeout time.Duration, err error) {
+	timeout = DefaultQueryTimeout
+	if c.ConnMaxLifetime != "" {
+		if lifetime, err = time.ParseDuration(c.ConnMaxLifetime); err != nil || lifetime < 0 {
+			return 0, 0, fmt.Errorf("connMaxLifetime must be a duration, got: %s", c.ConnMaxLifetime)
+		}
+	}
+	if c.QueryTimeout != "" {
+		if timeout, err = time.ParseDuration(c.QueryTimeout); err != nil || timeout <= 0 {
+			return 0, 0, fmt.Errorf("queryTimeout must be a positive duration, got: %s", c.QueryTimeout)
+		}
+	}
+	return lifetime, timeout, nil
+}
+
+// DataSource is an open connection pool
+type DataSource struct {
+	Name string
+	DB   *sql.DB
+	// QueryTimeout bounds each statement run by a mediator
+	QueryTimeout time.Duration
+}
+
+// Context returns a context bounded by the query timeout
+func (d *DataSource) Context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), d.QueryTimeout)
+}
+
+// Open opens the pools of a validated configuration and registers them,
+// replacing those of a previous configuration. Pools connect lazily, so an
+// unreachable database does not prevent startup.
+func Open(config Config) error {
+	opened := make(map[string]*DataSource)
+	for _, source := range config.DataSources {
+		if !slices.Contains(sql.Drivers(), source.Driver) {
+			closeAll(opened)
+			return fmt.Errorf("datasource %s: driver %s is not compiled in, available drivers: %s",
+				source.Name, source.Driver, strings.Join(sql.Drivers(), ", "))
+		}
+		db, err := sql.Open(source.Driver, source.DSN)
+		if err != nil {
+			closeAll(opened)
+			return fmt.Errorf("datasource %s: %w", source.Name, err)
+		}
+		lifetime, timeout, _ := source.durations()
+		db.SetMaxOpenConns(source.MaxOpenConns)
+		if source.MaxIdleConns > 0 {
+			db.SetMaxIdleConns(source.MaxIdleConns)
+		}
+		db.SetConnMaxLifetime(lifetime)
+		opened[source.Name] = &DataSource{Name: source.Name, DB: db, QueryTimeout: timeout}
+	}
+
+	sourcesMu.Lock()
+	previous := sources
+	sources = opened
+	sourcesMu.Unlock()
+	closeAll(previous)
+	return nil
+}
+
+// Close closes every registered pool
+func Close() {
+	sourcesMu.Lock()
+	previous := sources
+	sources = make(map[string]*DataSource)
+	sourcesMu.Unlock()
+	closeAll(previous)
+}
+
+func closeAll(opened map[string]*DataSource) {
+	for _, source := range opened {
+		source.DB.Close()
+	}
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]*DataSource)
+)
+
+// Register makes a pool available to mediators under its name
+func Register(source *DataSource) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[source.Name] = source
+}
+
+// Lookup returns the pool registered under the name
+func Lookup(name string) (*DataSource, error) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	source, ok := sources[name]
+	if !ok {
+		return nil, fmt.Errorf("datasource %s is not configured", name)
+	}
+	return source, nil
+}
@@ -0,0 +1,94 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package datasource
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubDriver registers a driver name; its connections are never used
+type stubDriver struct{}
+
+func (stubDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("stub driver does not connect")
+}
+
+func init() {
+	sql.Register("stub", stubDriver{})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := DataSourceConfig{Name: "orders", Driver: "postgres", DSN: "postgres://localhost/orders"}
+	tests := []struct {
+		name    string
+		modify  func(c *DataSourceConfig)
+		wantErr string
+	}{
+		{name: "valid", modify: func(c *DataSourceConfig) {}},
+		{name: "no name", modify: func(c *DataSourceConfig) { c.Name = "" }, wantErr: "datasource name is required"},
+		{name: "no driver", modify: func(c *DataSourceConfig) { c.Driver = "" }, wantErr: "datasource orders: driver is required"},
+		{name: "no dsn", modify: func(c *DataSourceConfig) { c.DSN = "" }, wantErr: "datasource orders: dsn is required"},
+		{name: "negative pool", modify: func(c *DataSourceConfig) { c.MaxOpenConns = -1 }, wantErr: "must not be negative"},
+		{name: "bad lifetime", modify: func(c *DataSourceConfig) { c.ConnMaxLifetime = "forever" }, wantErr: "connMaxLifetime must be a duration"},
+		{name: "bad timeout", modify: func(c *DataSourceConfig) { c.QueryTimeout = "0s" }, wantErr: "queryTimeout must be a positive duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := valid
+			tt.modify(&source)
+			err := Config{DataSources: []DataSourceConfig{source}}.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+	assert.EqualError(t, Config{DataSources: []DataSourceConfig{valid, valid}}.Validate(), "duplicate datasource: orders")
+}
+
+func TestOpen(t *testing.T) {
+	defer Close()
+	err := Open(Config{DataSources: []DataSourceConfig{{Name: "orders", Driver: "missing", DSN: "x"}}})
+	assert.ErrorContains(t, err, "datasource orders: driver missing is not compiled in, available drivers:")
+
+	require.NoError(t, Open(Config{DataSources: []DataSourceConfig{
+		{Name: "orders", Driver: "stub", DSN: "orders", MaxOpenConns: 5, QueryTimeout: "2s"},
+	}}))
+	source, err := Lookup("orders")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, source.QueryTimeout)
+	assert.Equal(t, 5, source.DB.Stats().MaxOpenConnections)
+
+	// Reopening replaces the previous pools
+	require.NoError(t, Open(Config{DataSources: []DataSourceConfig{{Name: "audit", Driver: "stub", DSN: "audit"}}}))
+	_, err = Lookup("orders")
+	assert.EqualError(t, err, "datasource orders is not configured")
+	source, err = Lookup("audit")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultQueryTimeout, source.QueryTimeout)
+}
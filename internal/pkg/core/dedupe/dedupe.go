@@ -0,0 +1,105 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package dedupe remembers the keys of recently seen messages so duplicates
+// delivered by at-least-once transports can be suppressed. Keys are kept in a
+// Store, in memory by default; shared stores can be registered so a cluster
+// detects duplicates delivered to different instances.
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStoreName is the name of the built-in in-memory store
+const MemoryStoreName = "memory"
+
+// Store records message keys for a limited time
+type Store interface {
+	// Add records the key for ttl and reports whether it was already recorded
+	// and has not expired
+	Add(ctx context.Context, key string, ttl time.Duration) (duplicate bool, err error)
+}
+
+// MemoryStore keeps keys in memory. Expired keys are removed lazily, at most
+// once per sweep interval.
+type MemoryStore struct {
+	now           func() time.Time
+	sweepInterval time.Duration
+
+	mu        sync.Mutex
+	expiry    map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, sweepInterval: time.Minute, expiry: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= s.sweepInterval {
+		for k, expiry := range s.expiry {
+			if !now.Before(expiry) {
+				delete(s.expiry, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	if expiry, ok := s.expiry[key]; ok && now.Before(expiry) {
+		return true, nil
+	}
+	s.expiry[key] = now.Add(ttl)
+	return false, nil
+}
+
+// Len returns the number of keys held, including expired keys not yet swept
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.expiry)
+}
+
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]Store{MemoryStoreName: NewMemoryStore()}
+)
+
+// Register makes a store available to dedupe mediators under the name
+func Register(name string, store Store) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	stores[name] = store
+}
+
+// Lookup returns the store registered under the name
+func Lookup(name string) (Store, error) {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+	store, ok := stores[name]
+	if !ok {
+		return nil, fmt.Errorf("dedupe store %s is not registered", name)
+	}
+	return store, nil
+}
@@ -0,0 +1,70 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package dedupe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Add(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	duplicate, err := store.Add(ctx, "order-1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	duplicate, _ = store.Add(ctx, "order-1", time.Minute)
+	assert.True(t, duplicate, "seen within the TTL")
+	duplicate, _ = store.Add(ctx, "order-2", time.Minute)
+	assert.False(t, duplicate)
+
+	now = now.Add(time.Minute)
+	duplicate, _ = store.Add(ctx, "order-1", time.Minute)
+	assert.False(t, duplicate, "expired keys are forgotten")
+	assert.Equal(t, 1, store.Len(), "order-2 expired and was swept")
+}
+
+func TestRegistry(t *testing.T) {
+	store, err := Lookup(MemoryStoreName)
+	require.NoError(t, err)
+	assert.NotNil(t, store)
+
+	_, err = Lookup("redis")
+	assert.EqualError(t, err, "dedupe store redis is not registered")
+
+	shared := NewMemoryStore()
+	Register("shared", shared)
+	t.Cleanup(func() {
+		storesMu.Lock()
+		delete(stores, "shared")
+		storesMu.Unlock()
+	})
+	store, err = Lookup("shared")
+	require.NoError(t, err)
+	assert.Same(t, shared, store)
+}
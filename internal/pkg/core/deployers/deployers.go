@@ -21,17 +21,27 @@ package deployers
 
 import (
 	"context"
+	"crypto/sha256"
 	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/apache/synapse-go/internal/app/adapters/inbound"
 	"github.com/apache/synapse-go/internal/app/core/domain"
 	"github.com/apache/synapse-go/internal/app/core/ports"
 	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
 	"github.com/apache/synapse-go/internal/pkg/core/deployers/types"
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/apache/synapse-go/internal/pkg/core/health"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/messageprocessor"
+	"github.com/apache/synapse-go/internal/pkg/core/messagestore"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+	"github.com/apache/synapse-go/internal/pkg/core/profile"
 	"github.com/apache/synapse-go/internal/pkg/core/router"
 	"github.com/apache/synapse-go/internal/pkg/core/utils"
 	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
@@ -45,7 +55,13 @@ type Deployer struct {
 	inboundMediator ports.InboundMessageMediator
 	routerService   *router.RouterService
 	basePath        string
+	rollout         *Rollout
 	logger 			*slog.Logger
+
+	// scanMu serializes scans; scanned holds the digests of the files of
+	// earlier scans by <artifact type>/<file name>
+	scanMu  sync.Mutex
+	scanned map[string][sha256.Size]byte
 }
 
 // Synapse/
@@ -53,63 +69,166 @@ type Deployer struct {
 // │  └─ synapse           (the compiled binary)
 // └─ artifacts/
 //    ├─ APIs/
-//    |─ Endpoints/
+//    |─ Endpoints/        (optional)
+//    |─ MessageStores/    (optional)
 //    |─ Sequences/
-//    └─ Inbounds/
+//    |─ Inbounds/
+//    |─ HealthChecks/     (optional)
+//    |─ MessageProcessors/ (optional, started last so their stores and endpoints are deployed)
+//    └─ Registry/         (optional, resources such as XSLT stylesheets and schemas loaded by key)
+//
+// APIs, sequences and inbounds may declare activateAt="<RFC 3339 time>" or activation="manual"
+// on its root element. It is validated as usual, then staged until that time
+// or until it is promoted through the admin API.
+//
+// Any artifact may declare profiles="dev,test" or profiles="!prod" on its root
+// element; it is skipped unless the active profile matches.
 
 func NewDeployer(basePath string, inboundMediator ports.InboundMessageMediator, routerService *router.RouterService) *Deployer {
 	d := &Deployer{
 		basePath:        basePath,
 		inboundMediator: inboundMediator,
 		routerService:   routerService,
+		scanned:         make(map[string][sha256.Size]byte),
 	}
 	d.logger = loggerfactory.GetLogger(componentName, d)
+	d.rollout = NewRollout(d.logger)
 	return d
 }
 
+// Rollout returns the artifacts staged for later activation
+func (d *Deployer) Rollout() *Rollout {
+	return d.rollout
+}
+
 func (d *Deployer) UpdateLogger() {
 	d.logger = loggerfactory.GetLogger(componentName,d)
 }
 
 func (d *Deployer) Deploy(ctx context.Context) error {
+	_, err := d.scan(ctx)
+	return err
+}
+
+// Rescan deploys the artifact files added since the last scan, and endpoint
+// files changed since then, and returns how many there were. Other files
+// deployed before are left as they are, so changes to them still need a
+// restart.
+func (d *Deployer) Rescan(ctx context.Context) (int, error) {
+	return d.scan(ctx)
+}
+
+func (d *Deployer) scan(ctx context.Context) (int, error) {
+	d.scanMu.Lock()
+	defer d.scanMu.Unlock()
 	files, err := os.ReadDir(d.basePath)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if len(files) == 0 {
-		return nil
+		return 0, nil
 	}
-	for _, artifactType := range []string{"Sequences", "APIs", "Inbounds"} {
+	added := 0
+	// Endpoints and message stores come first so they are available when the
+	// flows using them start
+	for _, artifactType := range []string{"Endpoints", "MessageStores", "Sequences", "APIs", "Inbounds", "HealthChecks", "MessageProcessors"} {
 		folderPath := filepath.Join(d.basePath, artifactType)
 		files, err := os.ReadDir(folderPath)
 		if err != nil {
-			return err
+			if (artifactType == "Endpoints" || artifactType == "MessageStores" || artifactType == "HealthChecks" || artifactType == "MessageProcessors") && os.IsNotExist(err) {
+				continue
+			}
+			return added, err
 		}
 		for _, file := range files {
 			if file.IsDir() || filepath.Ext(file.Name()) != ".xml" {
 				continue
 			}
+			key := artifactType + "/" + file.Name()
+			digest, deployed := d.scanned[key]
+			if deployed && artifactType != "Endpoints" {
+				continue
+			}
 			xmlFile, err := os.Open(filepath.Join(folderPath, file.Name()))
 			if err != nil {
-				return err
+				return added, err
 			}
+			release := leaks.Default().Acquire("deployer", leaks.File)
+			defer release()
 			defer xmlFile.Close()
 			data, err := io.ReadAll(xmlFile)
 			if err != nil {
 				d.logger.Error("Error reading file:", "error", err)
 				continue
 			}
+			// Endpoints are redeployed when their file changes
+			if deployed && digest == sha256.Sum256(data) {
+				continue
+			}
+			d.scanned[key] = sha256.Sum256(data)
+			if !d.inProfile(key, string(data)) {
+				continue
+			}
+			added++
 			switch artifactType {
+			case "Endpoints":
+				d.DeployEndpoints(ctx, file.Name(), string(data))
+			case "MessageStores":
+				d.DeployMessageStores(ctx, file.Name(), string(data))
 			case "APIs":
 				d.DeployAPIs(ctx, file.Name(), string(data))
 			case "Sequences":
 				d.DeploySequences(ctx, file.Name(), string(data))
 			case "Inbounds":
 				d.DeployInbounds(ctx, file.Name(), string(data))
+			case "HealthChecks":
+				d.DeployHealthChecks(ctx, file.Name(), string(data))
+			case "MessageProcessors":
+				d.DeployMessageProcessors(ctx, file.Name(), string(data))
 			}
 		}
 	}
-	return nil
+	return added, nil
+}
+
+func (d *Deployer) DeployEndpoints(ctx context.Context, fileName string, xmlData string) {
+	position := artifacts.Position{FileName: fileName}
+	endpoint := types.Endpoint{}
+	newEndpoint, err := endpoint.Unmarshal(xmlData, position)
+	if err != nil {
+		d.logger.Error("Error unmarshalling endpoint:", "error", err, "file", fileName)
+		return
+	}
+	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
+	_, redeployed := configContext.Snapshot().Endpoints[newEndpoint.Name]
+	configContext.AddEndpoint(newEndpoint)
+	if redeployed {
+		// Calls in flight finish on the connections of the old definition
+		outbound.Drain(newEndpoint.Name)
+	}
+	d.logger.Info("Deployed endpoint: "+newEndpoint.Name, "protocol", newEndpoint.Protocol)
+	publishDeployed("endpoint", newEndpoint.Name, fileName)
+}
+
+// DeployMessageStores opens the store and registers it for store mediators.
+// Relative directories of file stores are resolved against the Synapse home
+// directory, the parent of the artifacts directory.
+func (d *Deployer) DeployMessageStores(ctx context.Context, fileName string, xmlData string) {
+	position := artifacts.Position{FileName: fileName}
+	messageStore := types.MessageStore{}
+	newStore, err := messageStore.Unmarshal(xmlData, position)
+	if err != nil {
+		d.logger.Error("Error unmarshalling message store:", "error", err, "file", fileName)
+		return
+	}
+	store, err := messagestore.Open(newStore, filepath.Dir(filepath.Clean(d.basePath)))
+	if err != nil {
+		d.logger.Error("Error opening message store:", "error", err, "file", fileName)
+		return
+	}
+	messagestore.Register(newStore.Name, store)
+	d.logger.Info("Deployed message store: "+newStore.Name, "type", newStore.Type, "messages", store.Len())
+	publishDeployed("messagestore", newStore.Name, fileName)
 }
 
 func (d *Deployer) DeploySequences(ctx context.Context, fileName string, xmlData string) {
@@ -120,9 +239,13 @@ func (d *Deployer) DeploySequences(ctx context.Context, fileName string, xmlData
 		d.logger.Error("Error unmarshalling sequence:", "error", err)
 		return
 	}
+	newSeq.Compile()
 	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
-	configContext.AddSequence(newSeq)
-	d.logger.Info("Deployed sequence: " + newSeq.Name)
+	d.activate(ctx, "sequence", newSeq.Name, fileName, xmlData, func() {
+		configContext.AddSequence(newSeq)
+		d.logger.Info("Deployed sequence: " + newSeq.Name)
+		publishDeployed("sequence", newSeq.Name, fileName)
+	})
 }
 
 func (d *Deployer) DeployAPIs(ctx context.Context, fileName string, xmlData string) {
@@ -134,15 +257,27 @@ func (d *Deployer) DeployAPIs(ctx context.Context, fileName string, xmlData stri
 		return
 	}
 	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
-	configContext.AddAPI(newApi)
-
-	d.logger.Info("Deployed API: " + newApi.Name)
-
-	// Register the API with the router service
-	if err := d.routerService.RegisterAPI(ctx, newApi); err != nil {
-		d.logger.Error("Error registering API with router service:", "error", err)
+	if existing, exists := configContext.Snapshot().APIs[newApi.Key()]; exists {
+		d.logger.Error("Error deploying API: duplicate API name and version",
+			"api", newApi.Key(),
+			"file", fileName,
+			"conflictsWith", existing.Position.FileName)
 		return
 	}
+
+	newApi.Compile()
+
+	d.activate(ctx, "api", newApi.Key(), fileName, xmlData, func() {
+		// Register the API with the router service. Conflicting APIs are rejected
+		// so the first deployment keeps serving its routes.
+		if err := d.routerService.RegisterAPI(ctx, newApi); err != nil {
+			d.logger.Error("Error registering API with router service:", "error", err, "file", fileName)
+			return
+		}
+		configContext.AddAPI(newApi)
+		d.logger.Info("Deployed API: " + newApi.Name)
+		publishDeployed("api", newApi.Key(), fileName)
+	})
 }
 
 func (d *Deployer) DeployInbounds(ctx context.Context, fileName string, xmlData string) {
@@ -153,11 +288,7 @@ func (d *Deployer) DeployInbounds(ctx context.Context, fileName string, xmlData
 		d.logger.Error("Error unmarshalling inbound:", "error", err)
 		return
 	}
-	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
-	configContext.AddInbound(newInbound)
-	d.logger.Info("Deployed inbound: " + newInbound.Name)
-
-	// Start the inbound endpoint
+	// Create the inbound endpoint, which validates its parameters
 	parametersMap := make(map[string]string)
 	for _, param := range newInbound.Parameters {
 		parametersMap[param.Name] = param.Value
@@ -167,18 +298,136 @@ func (d *Deployer) DeployInbounds(ctx context.Context, fileName string, xmlData
 		Name:         newInbound.Name,
 		Protocol:     newInbound.Protocol,
 		Parameters:   parametersMap,
+	}, d.routerService)
+	if err != nil {
+		d.logger.Error("Error creating inbound endpoint:", "error", err, "file", fileName)
+		return
+	}
+	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
+	d.activate(ctx, "inbound", newInbound.Name, fileName, xmlData, func() {
+		configContext.AddInbound(newInbound)
+		d.logger.Info("Deployed inbound: " + newInbound.Name)
+		publishDeployed("inbound", newInbound.Name, fileName)
+
+		wg := ctx.Value(utils.WaitGroupKey).(*sync.WaitGroup)
+		wg.Add(1)
+		release := leaks.Default().Acquire("inbound/"+newInbound.Name, leaks.Goroutine)
+		go func(endpoint ports.InboundEndpoint) {
+			defer wg.Done()
+			defer release()
+			events.Publish(events.Event{Type: events.InboundStarted, Kind: "inbound", Name: newInbound.Name,
+				Attributes: map[string]string{"protocol": newInbound.Protocol}})
+			err := endpoint.Start(ctx, d.inboundMediator)
+			if err != nil {
+				d.logger.Error("Error starting inbound endpoint:", "error", err)
+			}
+			stopped := events.Event{Type: events.InboundStopped, Kind: "inbound", Name: newInbound.Name}
+			if err != nil {
+				stopped.Attributes = map[string]string{"error": err.Error()}
+			}
+			events.Publish(stopped)
+		}(inboundEndpoint)
 	})
+}
+
+func (d *Deployer) DeployHealthChecks(ctx context.Context, fileName string, xmlData string) {
+	position := artifacts.Position{FileName: fileName}
+	healthCheck := types.HealthCheck{}
+	newCheck, err := healthCheck.Unmarshal(xmlData, position)
 	if err != nil {
-		d.logger.Error("Error creating inbound endpoint:", "error", err)
+		d.logger.Error("Error unmarshalling health check:", "error", err)
+		return
+	}
+	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
+	registry := d.routerService.HealthChecks()
+	probe := health.NewProbe(newCheck, configContext, &http.Client{Timeout: newCheck.Timeout})
+	if err := registry.Register(newCheck, probe); err != nil {
+		d.logger.Error("Error deploying health check:", "error", err, "file", fileName)
 		return
 	}
+	d.logger.Info("Deployed health check: " + newCheck.Name)
+	publishDeployed("healthcheck", newCheck.Name, fileName)
 
 	wg := ctx.Value(utils.WaitGroupKey).(*sync.WaitGroup)
 	wg.Add(1)
-	go func(endpoint ports.InboundEndpoint) {
+	leaks.Default().Go("healthcheck/"+newCheck.Name, func() {
 		defer wg.Done()
-		if err := endpoint.Start(ctx, d.inboundMediator); err != nil {
-			d.logger.Error("Error starting inbound endpoint:", "error", err)
-		}
-	}(inboundEndpoint)
+		registry.Run(ctx, newCheck.Name)
+	})
+}
+
+// DeployMessageProcessors registers the processor and starts forwarding the
+// messages of its store
+func (d *Deployer) DeployMessageProcessors(ctx context.Context, fileName string, xmlData string) {
+	position := artifacts.Position{FileName: fileName}
+	messageProcessor := types.MessageProcessor{}
+	newProcessor, err := messageProcessor.Unmarshal(xmlData, position)
+	if err != nil {
+		d.logger.Error("Error unmarshalling message processor:", "error", err, "file", fileName)
+		return
+	}
+	configContext := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
+	registry := messageprocessor.Default()
+	if err := registry.Register(newProcessor, configContext); err != nil {
+		d.logger.Error("Error deploying message processor:", "error", err, "file", fileName)
+		return
+	}
+	d.logger.Info("Deployed message processor: "+newProcessor.Name, "store", newProcessor.MessageStore, "endpoint", newProcessor.Endpoint)
+	publishDeployed("messageprocessor", newProcessor.Name, fileName)
+
+	wg := ctx.Value(utils.WaitGroupKey).(*sync.WaitGroup)
+	wg.Add(1)
+	leaks.Default().Go("messageprocessor/"+newProcessor.Name, func() {
+		defer wg.Done()
+		registry.Run(ctx, newProcessor.Name)
+	})
+}
+
+// inProfile reports whether the artifact file deploys with the active profile,
+// logging those that are skipped
+func (d *Deployer) inProfile(file, xmlData string) bool {
+	profiles, err := parseProfiles(xmlData)
+	if err == nil && profiles == "" {
+		return true
+	}
+	var matches bool
+	if err == nil {
+		matches, err = profile.Matches(profiles, profile.Active())
+	}
+	if err != nil {
+		d.logger.Error("Error reading profiles of artifact:", "error", err, "file", file)
+		return false
+	}
+	if !matches {
+		d.logger.Info("Skipped artifact of other profiles", "file", file, "profiles", profiles,
+			"profile", profile.Active())
+	}
+	return matches
+}
+
+// activate switches a validated artifact on, or stages it when it declares a
+// later or manual activation
+func (d *Deployer) activate(ctx context.Context, kind, name, fileName, xmlData string, activate func()) {
+	activation, err := parseActivation(xmlData)
+	if err != nil {
+		d.logger.Error("Error deploying "+kind+":", "error", err, "file", fileName)
+		return
+	}
+	if !activation.Staged(time.Now()) {
+		activate()
+		return
+	}
+	d.rollout.Stage(ctx, StagedArtifact{
+		Kind:       kind,
+		Name:       name,
+		File:       fileName,
+		ActivateAt: activation.At,
+		Manual:     activation.Manual,
+	}, activate)
+}
+
+// publishDeployed announces a deployed artifact on the runtime event bus
+func publishDeployed(kind, name, fileName string) {
+	events.Publish(events.Event{Type: events.ArtifactDeployed, Kind: kind, Name: name,
+		Attributes: map[string]string{"file": fileName}})
 }
@@ -0,0 +1,223 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package deployers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/utils"
+)
+
+// ManualActivation is the activation attribute value of artifacts that stay
+// staged until they are promoted through the admin API
+const ManualActivation = "manual"
+
+// Activation is when a validated artifact is switched on
+type Activation struct {
+	// At is the time the artifact activates, zero to activate immediately
+	At time.Time
+	// Manual artifacts wait for an explicit promotion
+	Manual bool
+}
+
+// Staged reports whether the artifact must wait before it is activated
+func (a Activation) Staged(now time.Time) bool {
+	return a.Manual || a.At.After(now)
+}
+
+// parseActivation reads the activateAt and activation attributes of the root
+// element of an artifact
+func parseActivation(xmlData string) (Activation, error) {
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return Activation{}, nil
+		}
+		if err != nil {
+			return Activation{}, err
+		}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var activation Activation
+		for _, attr := range root.Attr {
+			switch attr.Name.Local {
+			case "activateAt":
+				if activation.At, err = time.Parse(time.RFC3339, attr.Value); err != nil {
+					return Activation{}, fmt.Errorf("invalid activateAt %q, expected an RFC 3339 time: %w", attr.Value, err)
+				}
+			case "activation":
+				if attr.Value != ManualActivation {
+					return Activation{}, fmt.Errorf("invalid activation %q, expected %q", attr.Value, ManualActivation)
+				}
+				activation.Manual = true
+			}
+		}
+		return activation, nil
+	}
+}
+
+// parseProfiles reads the profiles attribute of the root element of an
+// artifact, the comma-separated profiles it is deployed with
+func parseProfiles(xmlData string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range root.Attr {
+			if attr.Name.Local == "profiles" {
+				return attr.Value, nil
+			}
+		}
+		return "", nil
+	}
+}
+
+// StagedArtifact is a validated artifact waiting for its activation
+type StagedArtifact struct {
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	File       string    `json:"file"`
+	StagedAt   time.Time `json:"stagedAt"`
+	ActivateAt time.Time `json:"activateAt,omitempty"`
+	Manual     bool      `json:"manual,omitempty"`
+
+	activate func()
+}
+
+// Rollout holds the staged artifacts and activates them when their time comes
+// or when they are promoted
+type Rollout struct {
+	mu     sync.Mutex
+	staged map[string]*StagedArtifact
+	logger *slog.Logger
+}
+
+// NewRollout creates an empty rollout
+func NewRollout(logger *slog.Logger) *Rollout {
+	return &Rollout{staged: make(map[string]*StagedArtifact), logger: logger}
+}
+
+func stagedKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// Stage holds an artifact until it is promoted. Artifacts with an activation
+// time are promoted by a timer that is cancelled with ctx.
+func (r *Rollout) Stage(ctx context.Context, artifact StagedArtifact, activate func()) {
+	staged := artifact
+	staged.StagedAt = time.Now()
+	staged.activate = activate
+	key := stagedKey(artifact.Kind, artifact.Name)
+	r.mu.Lock()
+	r.staged[key] = &staged
+	r.mu.Unlock()
+	r.logger.Info("Staged "+artifact.Kind+": "+artifact.Name, "file", artifact.File,
+		"activateAt", artifact.ActivateAt, "manual", artifact.Manual)
+	events.Publish(events.Event{Type: events.ArtifactStaged, Kind: artifact.Kind, Name: artifact.Name,
+		Attributes: map[string]string{"file": artifact.File}})
+
+	if staged.Manual || staged.ActivateAt.IsZero() {
+		return
+	}
+	timer := time.NewTimer(time.Until(staged.ActivateAt))
+	run := func() {
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			r.promote(key, &staged)
+		}
+	}
+	if wg, ok := ctx.Value(utils.WaitGroupKey).(*sync.WaitGroup); ok {
+		wg.Add(1)
+		leaks.Default().Go("rollout/"+key, func() {
+			defer wg.Done()
+			run()
+		})
+		return
+	}
+	leaks.Default().Go("rollout/"+key, run)
+}
+
+// Promote activates a staged artifact ahead of its activation time
+func (r *Rollout) Promote(kind, name string) error {
+	key := stagedKey(kind, name)
+	r.mu.Lock()
+	staged, ok := r.staged[key]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no staged %s named %s", kind, name)
+	}
+	if !r.promote(key, staged) {
+		return fmt.Errorf("%s %s is already activated", kind, name)
+	}
+	return nil
+}
+
+// promote activates the staged artifact once, whether promoted by its timer or
+// through the admin API
+func (r *Rollout) promote(key string, staged *StagedArtifact) bool {
+	r.mu.Lock()
+	if r.staged[key] != staged {
+		r.mu.Unlock()
+		return false
+	}
+	delete(r.staged, key)
+	r.mu.Unlock()
+	r.logger.Info("Activating staged "+staged.Kind+": "+staged.Name, "file", staged.File)
+	staged.activate()
+	return true
+}
+
+// Staged lists the artifacts waiting for activation, ordered by kind and name
+func (r *Rollout) Staged() []StagedArtifact {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	staged := make([]StagedArtifact, 0, len(r.staged))
+	for _, artifact := range r.staged {
+		staged = append(staged, *artifact)
+	}
+	slices.SortFunc(staged, func(a, b StagedArtifact) int {
+		return strings.Compare(stagedKey(a.Kind, a.Name), stagedKey(b.Kind, b.Name))
+	})
+	return staged
+}
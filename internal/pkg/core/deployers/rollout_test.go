@@ -0,0 +1,119 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package deployers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/profile"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseActivation(t *testing.T) {
+	tests := []struct {
+		name     string
+		xmlData  string
+		expected Activation
+		wantErr  bool
+	}{
+		{name: "immediate", xmlData: `<api name="Orders"/>`},
+		{
+			name:     "scheduled",
+			xmlData:  `<?xml version="1.0"?><sequence name="Log" activateAt="2030-01-02T03:00:00Z"><log/></sequence>`,
+			expected: Activation{At: time.Date(2030, 1, 2, 3, 0, 0, 0, time.UTC)},
+		},
+		{name: "manual", xmlData: `<inboundEndpoint name="Files" activation="manual"/>`, expected: Activation{Manual: true}},
+		{name: "invalid time", xmlData: `<api activateAt="tomorrow"/>`, wantErr: true},
+		{name: "invalid activation", xmlData: `<api activation="later"/>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activation, err := parseActivation(tt.xmlData)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.At.Equal(activation.At))
+			assert.Equal(t, tt.expected.Manual, activation.Manual)
+		})
+	}
+}
+
+func TestDeployer_InProfile(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+	d := NewDeployer(t.TempDir(), nil, nil)
+	t.Cleanup(func() { profile.SetActive("") })
+
+	profile.SetActive(profile.Development)
+	assert.True(t, d.inProfile("APIs/Orders.xml", `<api name="Orders"/>`))
+	assert.True(t, d.inProfile("Endpoints/Mock.xml", `<?xml version="1.0"?><endpoint name="Orders" profiles="dev,test"/>`))
+	assert.False(t, d.inProfile("Endpoints/Orders.xml", `<endpoint name="Orders" profiles="!dev,!test"/>`))
+	assert.False(t, d.inProfile("APIs/Invalid.xml", `<api name="Orders" profiles="Dev"/>`))
+
+	profile.SetActive(profile.Production)
+	assert.False(t, d.inProfile("Endpoints/Mock.xml", `<endpoint name="Orders" profiles="dev,test"/>`))
+	assert.True(t, d.inProfile("Endpoints/Orders.xml", `<endpoint name="Orders" profiles="!dev,!test"/>`))
+}
+
+func TestRollout_Promote(t *testing.T) {
+	rollout := NewRollout(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var activated atomic.Int32
+	rollout.Stage(context.Background(), StagedArtifact{Kind: "api", Name: "Orders", Manual: true}, func() {
+		activated.Add(1)
+	})
+
+	staged := rollout.Staged()
+	require.Len(t, staged, 1)
+	assert.Equal(t, "Orders", staged[0].Name)
+	assert.Zero(t, activated.Load(), "manual artifacts wait for promotion")
+
+	require.NoError(t, rollout.Promote("api", "Orders"))
+	assert.Equal(t, int32(1), activated.Load())
+	assert.Empty(t, rollout.Staged())
+	assert.Error(t, rollout.Promote("api", "Orders"), "an artifact is activated once")
+}
+
+func TestRollout_ActivateAt(t *testing.T) {
+	rollout := NewRollout(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	activated := make(chan struct{})
+	rollout.Stage(context.Background(), StagedArtifact{Kind: "sequence", Name: "Log", ActivateAt: time.Now().Add(20 * time.Millisecond)},
+		func() { close(activated) })
+
+	select {
+	case <-activated:
+	case <-time.After(time.Second):
+		t.Fatal("staged artifact was not activated at its activation time")
+	}
+	assert.Empty(t, rollout.Staged())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rollout.Stage(ctx, StagedArtifact{Kind: "sequence", Name: "Later", ActivateAt: time.Now().Add(time.Hour)},
+		func() { t.Error("cancelled rollout must not activate") })
+	cancel()
+	assert.Len(t, rollout.Staged(), 1)
+}
@@ -0,0 +1,89 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// xmlElementName matches the names aggregated XML payloads can be wrapped in
+var xmlElementName = regexp.MustCompile(`^[A-Za-z_][\w.-]*$`)
+
+// AggregateMediator is the XML form of the aggregate mediator, e.g.
+// <aggregate correlation="payload.orderId" min="2" max="3" timeout="5s" wrapper="quotes"/>
+type AggregateMediator struct {
+	XMLName     xml.Name `xml:"aggregate"`
+	Correlation string   `xml:"correlation,attr"`
+	Min         string   `xml:"min,attr"`
+	Max         string   `xml:"max,attr"`
+	Timeout     string   `xml:"timeout,attr"`
+	Wrapper     string   `xml:"wrapper,attr"`
+}
+
+func (aggregateMediator AggregateMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&aggregateMediator, &start); err != nil {
+		return artifacts.AggregateMediator{}, errors.New("error in unmarshalling aggregate mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->aggregate"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("aggregate mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	m := aggregateMediator
+
+	mediator := artifacts.AggregateMediator{Wrapper: m.Wrapper, Position: position}
+	if m.Correlation != "" {
+		correlation, err := expression.Compile(m.Correlation, artifacts.ExpressionVariables...)
+		if err != nil {
+			return artifacts.AggregateMediator{}, invalid("invalid correlation expression: %v", err)
+		}
+		mediator.Correlation = correlation
+	}
+	var err error
+	if m.Min != "" {
+		if mediator.Min, err = strconv.Atoi(m.Min); err != nil || mediator.Min < 0 {
+			return artifacts.AggregateMediator{}, invalid("min must be a non-negative integer, got: %s", m.Min)
+		}
+	}
+	if m.Max != "" {
+		if mediator.Max, err = strconv.Atoi(m.Max); err != nil || mediator.Max < 1 {
+			return artifacts.AggregateMediator{}, invalid("max must be a positive integer, got: %s", m.Max)
+		}
+		if mediator.Max < mediator.Min {
+			return artifacts.AggregateMediator{}, invalid("max %d is less than min %d", mediator.Max, mediator.Min)
+		}
+	}
+	if m.Timeout != "" {
+		if mediator.Timeout, err = time.ParseDuration(m.Timeout); err != nil || mediator.Timeout <= 0 {
+			return artifacts.AggregateMediator{}, invalid("timeout must be a positive duration, got: %s", m.Timeout)
+		}
+	}
+	if m.Wrapper != "" && !xmlElementName.MatchString(m.Wrapper) {
+		return artifacts.AggregateMediator{}, invalid("wrapper must be an XML element name, got: %s", m.Wrapper)
+	}
+	return mediator, nil
+}
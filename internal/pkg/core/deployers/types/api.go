@@ -22,10 +22,16 @@ package types
 import (
 	"encoding/xml"
 	"fmt"
+	"math"
+	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/asyncreply"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
 )
 
 // Regular expression to find path parameters in the format {paramName}
@@ -35,22 +41,29 @@ type Resource struct {
 	Methods       string                    `xml:"methods,attr"`
 	URITemplate   artifacts.URITemplateInfo `xml:"uri-template,attr"`
 	InSequence    artifacts.Sequence        `xml:"inSequence"`
+	OutSequence   artifacts.Sequence        `xml:"outSequence"`
 	FaultSequence artifacts.Sequence        `xml:"faultSequence"`
 }
 
 type API struct {
-	Context     string               `xml:"context,attr"`
-	Name        string               `xml:"name,attr"`
-	Version     string               `xml:"version,attr"`
-	VersionType string               `xml:"version-type,attr"`
-	Resources   []artifacts.Resource `xml:"resource"`
-	Position    artifacts.Position
+	Context        string               `xml:"context,attr"`
+	Name           string               `xml:"name,attr"`
+	Version        string               `xml:"version,attr"`
+	VersionType    string               `xml:"version-type,attr"`
+	Hostname       string               `xml:"hostname,attr"`
+	CORS           string               `xml:"cors,attr"`
+	SecurityPolicy string               `xml:"securityPolicy,attr"`
+	Authorization  string               `xml:"authorization,attr"`
+	CSRF           string               `xml:"csrf,attr"`
+	Resources      []artifacts.Resource `xml:"resource"`
+	Position       artifacts.Position
 }
 
 func (api *API) Unmarshal(xmlData string, position artifacts.Position) (artifacts.API, error) {
 	decoder := xml.NewDecoder(strings.NewReader(xmlData))
 	newAPI := artifacts.API{}
 	newAPI.Position = position
+	var deprecated, sunset, deprecationLink, warningPercent string
 	for {
 		token, err := decoder.Token()
 		if err != nil {
@@ -71,6 +84,40 @@ func (api *API) Unmarshal(xmlData string, position artifacts.Position) (artifact
 						newAPI.Version = attr.Value
 					case "version-type":
 						newAPI.VersionType = attr.Value
+					case "default-version":
+						isDefault, err := strconv.ParseBool(attr.Value)
+						if err != nil {
+							return artifacts.API{}, fmt.Errorf("default-version must be either 'true' or 'false', got: %s", attr.Value)
+						}
+						newAPI.IsDefaultVersion = isDefault
+					case "hostname":
+						newAPI.Hostname = strings.ToLower(strings.TrimSpace(attr.Value))
+					case "cors":
+						newAPI.CORS = attr.Value
+					case "securityPolicy":
+						newAPI.SecurityPolicy = attr.Value
+					case "authorization":
+						newAPI.Authorization = attr.Value
+					case "csrf":
+						csrf, err := strconv.ParseBool(attr.Value)
+						if err != nil {
+							return artifacts.API{}, fmt.Errorf("csrf must be either 'true' or 'false', got: %s", attr.Value)
+						}
+						newAPI.CSRF = csrf
+					case "timeout":
+						timeout, err := parseBudget(attr.Value)
+						if err != nil {
+							return artifacts.API{}, err
+						}
+						newAPI.Timeout = timeout
+					case "deprecated":
+						deprecated = attr.Value
+					case "sunset":
+						sunset = attr.Value
+					case "deprecationLink":
+						deprecationLink = attr.Value
+					case "deprecationWarningPercent":
+						warningPercent = attr.Value
 					}
 				}
 			case "resource":
@@ -80,6 +127,66 @@ func (api *API) Unmarshal(xmlData string, position artifacts.Position) (artifact
 					return artifacts.API{}, err
 				}
 				newAPI.Resources = append(newAPI.Resources, res)
+			case "errorTemplate":
+				var errorTemplate struct {
+					ContentType string `xml:"contentType,attr"`
+					Body        string `xml:",chardata"`
+				}
+				if err := decoder.DecodeElement(&errorTemplate, &elem); err != nil {
+					return artifacts.API{}, err
+				}
+				t, err := problem.ParseTemplate(errorTemplate.ContentType, strings.TrimSpace(errorTemplate.Body))
+				if err != nil {
+					return artifacts.API{}, err
+				}
+				newAPI.ErrorTemplate = t
+			case "slo":
+				slo, err := parseSLO(elem)
+				if err != nil {
+					return artifacts.API{}, err
+				}
+				newAPI.SLO = slo
+				if err := decoder.Skip(); err != nil {
+					return artifacts.API{}, err
+				}
+			case "selfTest":
+				test, err := parseSelfTest(decoder, elem)
+				if err != nil {
+					return artifacts.API{}, fmt.Errorf("API %s: %w", newAPI.Name, err)
+				}
+				for _, existing := range newAPI.SelfTests {
+					if existing.Name == test.Name {
+						return artifacts.API{}, fmt.Errorf("API %s: duplicate selfTest %s", newAPI.Name, test.Name)
+					}
+				}
+				newAPI.SelfTests = append(newAPI.SelfTests, test)
+			case "mirror":
+				mirror, err := parseMirror(elem)
+				if err != nil {
+					return artifacts.API{}, fmt.Errorf("API %s: %w", newAPI.Name, err)
+				}
+				newAPI.Mirror = mirror
+				if err := decoder.Skip(); err != nil {
+					return artifacts.API{}, err
+				}
+			case "overflow":
+				overflow, err := parseOverflow(elem)
+				if err != nil {
+					return artifacts.API{}, fmt.Errorf("API %s: %w", newAPI.Name, err)
+				}
+				newAPI.Overflow = overflow
+				if err := decoder.Skip(); err != nil {
+					return artifacts.API{}, err
+				}
+			case "statusMap":
+				mapping, err := parseStatusMapping(elem)
+				if err != nil {
+					return artifacts.API{}, fmt.Errorf("API %s: %w", newAPI.Name, err)
+				}
+				newAPI.StatusMappings = append(newAPI.StatusMappings, mapping)
+				if err := decoder.Skip(); err != nil {
+					return artifacts.API{}, err
+				}
 			default:
 				// Skip unknown elements
 				if err := decoder.Skip(); err != nil {
@@ -119,15 +226,199 @@ func (api *API) Unmarshal(xmlData string, position artifacts.Position) (artifact
 		return artifacts.API{}, fmt.Errorf("version-type must be either 'context' or 'url', got: %s", newAPI.VersionType)
 	}
 
+	if newAPI.IsDefaultVersion && !hasVersion {
+		return artifacts.API{}, fmt.Errorf("default-version requires version and version-type to be specified")
+	}
+
+	// Validate hostname if specified. It is matched against the Host header,
+	// so it must be a bare host name without scheme, port or path.
+	if newAPI.Hostname != "" && strings.ContainsAny(newAPI.Hostname, "/: \t") {
+		return artifacts.API{}, fmt.Errorf("invalid API hostname '%s': expected a host name without scheme, port or path", newAPI.Hostname)
+	}
+
+	if newAPI.CORS != "" && newAPI.CORS != "true" && newAPI.CORS != "false" {
+		return artifacts.API{}, fmt.Errorf("cors must be either 'true' or 'false', got: %s", newAPI.CORS)
+	}
+
+	deprecation, err := parseDeprecation(deprecated, sunset, deprecationLink, warningPercent)
+	if err != nil {
+		return artifacts.API{}, err
+	}
+	newAPI.Deprecation = deprecation
+
+	// Status mappings may be declared after the resources they apply to
+	for i := range newAPI.Resources {
+		newAPI.Resources[i].StatusMappings = newAPI.StatusMappings
+		if newAPI.Resources[i].Timeout == 0 {
+			newAPI.Resources[i].Timeout = newAPI.Timeout
+		}
+	}
+
 	return newAPI, nil
 }
 
+// parseBudget reads the timeout of an API or resource
+func parseBudget(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be a positive duration, got: %s", value)
+	}
+	return timeout, nil
+}
+
+// parseDeprecation builds the deprecation details of an API. deprecated is either
+// "true", "false" or the RFC 3339 date the version was deprecated on.
+func parseDeprecation(deprecated, sunset, link, warningPercent string) (*artifacts.Deprecation, error) {
+	if deprecated == "" || deprecated == "false" {
+		if sunset != "" || link != "" || warningPercent != "" {
+			return nil, fmt.Errorf("sunset, deprecationLink and deprecationWarningPercent require deprecated to be set")
+		}
+		return nil, nil
+	}
+
+	deprecation := &artifacts.Deprecation{Link: link}
+	if deprecated != "true" {
+		since, err := time.Parse(time.RFC3339, deprecated)
+		if err != nil {
+			return nil, fmt.Errorf("deprecated must be 'true', 'false' or an RFC 3339 date, got: %s", deprecated)
+		}
+		deprecation.Since = since
+	}
+	if sunset != "" {
+		sunsetTime, err := time.Parse(time.RFC3339, sunset)
+		if err != nil {
+			return nil, fmt.Errorf("sunset must be an RFC 3339 date, got: %s", sunset)
+		}
+		deprecation.Sunset = sunsetTime
+	}
+	if warningPercent != "" {
+		percent, err := strconv.Atoi(warningPercent)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("deprecationWarningPercent must be an integer between 0 and 100, got: %s", warningPercent)
+		}
+		deprecation.WarningPercent = percent
+	}
+	return deprecation, nil
+}
+
+// parseSLO reads the attributes of an <slo> element, e.g.
+// <slo window="5m" maxLatency="300ms" percentile="95" maxErrorRate="0.01" degradedSequence="Fallback"/>
+func parseSLO(elem xml.StartElement) (*artifacts.SLO, error) {
+	slo := &artifacts.SLO{Window: time.Minute, Percentile: 95}
+	for _, attr := range elem.Attr {
+		var err error
+		switch attr.Name.Local {
+		case "window":
+			slo.Window, err = time.ParseDuration(attr.Value)
+			if err == nil && slo.Window <= 0 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "maxLatency":
+			slo.MaxLatency, err = time.ParseDuration(attr.Value)
+		case "percentile":
+			slo.Percentile, err = strconv.ParseFloat(attr.Value, 64)
+			if err == nil && (slo.Percentile <= 0 || slo.Percentile > 100) {
+				err = fmt.Errorf("must be between 0 and 100")
+			}
+		case "maxErrorRate":
+			slo.MaxErrorRate, err = strconv.ParseFloat(attr.Value, 64)
+			if err == nil && (slo.MaxErrorRate < 0 || slo.MaxErrorRate > 1) {
+				err = fmt.Errorf("must be between 0 and 1")
+			}
+		case "minRequests":
+			slo.MinRequests, err = strconv.Atoi(attr.Value)
+		case "degradedSequence":
+			slo.DegradedSequence = attr.Value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid slo %s '%s': %v", attr.Name.Local, attr.Value, err)
+		}
+	}
+	if slo.MaxLatency <= 0 && slo.MaxErrorRate <= 0 {
+		return nil, fmt.Errorf("slo requires maxLatency or maxErrorRate")
+	}
+	return slo, nil
+}
+
+// selfTest is the XML form of a sample request, e.g.
+// <selfTest name="getOrder" method="GET" path="/orders/1" expectedStatus="200">
+//
+//	<header name="Accept" value="application/json"/>
+//	<payload>{"id":1}</payload>
+//
+// </selfTest>
+type selfTest struct {
+	Name           string `xml:"name,attr"`
+	Method         string `xml:"method,attr"`
+	Path           string `xml:"path,attr"`
+	ExpectedStatus string `xml:"expectedStatus,attr"`
+	Timeout        string `xml:"timeout,attr"`
+	Critical       string `xml:"critical,attr"`
+	Headers        []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"header"`
+	Payload string `xml:"payload"`
+}
+
+func parseSelfTest(decoder *xml.Decoder, elem xml.StartElement) (artifacts.SelfTest, error) {
+	var st selfTest
+	if err := decoder.DecodeElement(&st, &elem); err != nil {
+		return artifacts.SelfTest{}, err
+	}
+	if st.Name == "" {
+		return artifacts.SelfTest{}, fmt.Errorf("selfTest name is required")
+	}
+	if !strings.HasPrefix(st.Path, "/") {
+		return artifacts.SelfTest{}, fmt.Errorf("selfTest %s path must begin with '/', got: %s", st.Name, st.Path)
+	}
+	test := artifacts.SelfTest{
+		Name:     st.Name,
+		Method:   strings.ToUpper(st.Method),
+		Path:     st.Path,
+		Payload:  strings.TrimSpace(st.Payload),
+		Timeout:  5 * time.Second,
+		Critical: true,
+	}
+	if test.Method == "" {
+		test.Method = http.MethodGet
+	}
+	for _, header := range st.Headers {
+		if header.Name == "" {
+			return artifacts.SelfTest{}, fmt.Errorf("selfTest %s header name is required", st.Name)
+		}
+		if test.Headers == nil {
+			test.Headers = make(map[string]string)
+		}
+		test.Headers[header.Name] = header.Value
+	}
+	var err error
+	if st.ExpectedStatus != "" {
+		if test.ExpectedStatus, err = strconv.Atoi(st.ExpectedStatus); err != nil || test.ExpectedStatus < 100 || test.ExpectedStatus > 599 {
+			return artifacts.SelfTest{}, fmt.Errorf("invalid selfTest %s expectedStatus '%s'", st.Name, st.ExpectedStatus)
+		}
+	}
+	if st.Timeout != "" {
+		if test.Timeout, err = time.ParseDuration(st.Timeout); err != nil || test.Timeout <= 0 {
+			return artifacts.SelfTest{}, fmt.Errorf("invalid selfTest %s timeout '%s'", st.Name, st.Timeout)
+		}
+	}
+	if st.Critical != "" {
+		if test.Critical, err = strconv.ParseBool(st.Critical); err != nil {
+			return artifacts.SelfTest{}, fmt.Errorf("critical must be either 'true' or 'false', got: %s", st.Critical)
+		}
+	}
+	return test, nil
+}
+
 // implements custom unmarshaling for Resource
 func (r *Resource) Unmarshal(decoder *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Resource, error) {
 	// Extract attributes from the <resource> element
 	res := artifacts.Resource{}
 	var methodsStr string
 	var uriTemplate string
+	var asyncTTL string
+	var asyncCallbacks string
 
 	for _, attr := range start.Attr {
 		switch attr.Name.Local {
@@ -135,6 +426,54 @@ func (r *Resource) Unmarshal(decoder *xml.Decoder, start xml.StartElement, posit
 			methodsStr = attr.Value
 		case "uri-template":
 			uriTemplate = attr.Value
+		case "async":
+			async, err := strconv.ParseBool(attr.Value)
+			if err != nil {
+				return artifacts.Resource{}, fmt.Errorf("async must be either 'true' or 'false', got: %s", attr.Value)
+			}
+			if async && res.Async == nil {
+				res.Async = &artifacts.AsyncReply{TTL: asyncreply.DefaultTTL}
+			}
+		case "asyncTTL":
+			asyncTTL = attr.Value
+		case "asyncCallbacks":
+			asyncCallbacks = attr.Value
+		case "timeout":
+			timeout, err := parseBudget(attr.Value)
+			if err != nil {
+				return artifacts.Resource{}, err
+			}
+			res.Timeout = timeout
+		case "type":
+			switch attr.Value {
+			case "", "sequence":
+			case artifacts.ResourceTypeEcho, artifacts.ResourceTypeStatic:
+				res.Type = attr.Value
+			default:
+				return artifacts.Resource{}, fmt.Errorf("resource type must be sequence, echo or static, got: %s", attr.Value)
+			}
+		}
+	}
+	if asyncTTL != "" {
+		if res.Async == nil {
+			return artifacts.Resource{}, fmt.Errorf("asyncTTL requires async=\"true\"")
+		}
+		ttl, err := time.ParseDuration(asyncTTL)
+		if err != nil || ttl <= 0 {
+			return artifacts.Resource{}, fmt.Errorf("asyncTTL must be a positive duration, got: %s", asyncTTL)
+		}
+		res.Async.TTL = ttl
+	}
+	if asyncCallbacks != "" {
+		if res.Async == nil {
+			return artifacts.Resource{}, fmt.Errorf("asyncCallbacks requires async=\"true\"")
+		}
+		for _, prefix := range strings.Split(asyncCallbacks, ",") {
+			prefix = strings.TrimSpace(prefix)
+			if err := asyncreply.ValidateCallback(prefix); err != nil {
+				return artifacts.Resource{}, fmt.Errorf("asyncCallbacks: %w", err)
+			}
+			res.Async.Callbacks = append(res.Async.Callbacks, prefix)
 		}
 	}
 
@@ -164,16 +503,33 @@ parsingLoop:
 		switch elem := token.(type) {
 		case xml.StartElement:
 			switch elem.Name.Local {
-			case "inSequence", "faultSequence":
+			case "inSequence", "outSequence", "faultSequence":
+				if elem.Name.Local != "faultSequence" && res.Type != "" {
+					return artifacts.Resource{}, fmt.Errorf("%s resources cannot have an %s", res.Type, elem.Name.Local)
+				}
 				seq, err := r.decodeSequence(decoder, position, elem.Name.Local, res)
 				if err != nil {
 					return artifacts.Resource{}, err
 				}
-				if elem.Name.Local == "inSequence" {
+				switch elem.Name.Local {
+				case "inSequence":
 					res.InSequence = seq
-				} else {
+				case "outSequence":
+					res.OutSequence = seq
+				default:
 					res.FaultSequence = seq
 				}
+			case "response":
+				if res.Type != artifacts.ResourceTypeStatic {
+					return artifacts.Resource{}, fmt.Errorf("only static resources have a response")
+				}
+				line, _ := decoder.InputPos()
+				mediator, err := r.decodeStaticResponse(decoder, elem, artifacts.Position{FileName: position.FileName, LineNo: line,
+					Hierarchy: position.Hierarchy + "->" + res.URITemplate.FullTemplate + "->response"})
+				if err != nil {
+					return artifacts.Resource{}, err
+				}
+				res.InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{mediator}}
 			default:
 				// Skip unknown elements
 				if err := decoder.Skip(); err != nil {
@@ -187,9 +543,61 @@ parsingLoop:
 			}
 		}
 	}
+
+	switch res.Type {
+	case artifacts.ResourceTypeEcho:
+		res.InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{artifacts.EchoMediator{Position: artifacts.Position{
+			FileName: position.FileName, LineNo: position.LineNo, Hierarchy: position.Hierarchy + "->" + res.URITemplate.FullTemplate}}}}
+	case artifacts.ResourceTypeStatic:
+		if len(res.InSequence.MediatorList) == 0 {
+			return artifacts.Resource{}, fmt.Errorf("static resources require a response element")
+		}
+	}
 	return res, nil
 }
 
+// staticResponse is the response of a static resource, e.g.
+//
+//	<response status="200" contentType="application/json">
+//	    <header name="Cache-Control" value="no-store"/>
+//	    <payload><![CDATA[{"status": "UP"}]]></payload>
+//	</response>
+type staticResponse struct {
+	Status      string `xml:"status,attr"`
+	ContentType string `xml:"contentType,attr"`
+	Headers     []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"header"`
+	Payload string `xml:"payload"`
+}
+
+func (r *Resource) decodeStaticResponse(decoder *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.StaticResponseMediator, error) {
+	var response staticResponse
+	if err := decoder.DecodeElement(&response, &start); err != nil {
+		return artifacts.StaticResponseMediator{}, fmt.Errorf("invalid static response: %w", err)
+	}
+	mediator := artifacts.StaticResponseMediator{Status: http.StatusOK, ContentType: response.ContentType,
+		Payload: strings.TrimSpace(response.Payload), Position: position}
+	if response.Status != "" {
+		status, err := strconv.Atoi(response.Status)
+		if err != nil || status < 200 || status > 599 {
+			return artifacts.StaticResponseMediator{}, fmt.Errorf("static response status must be between 200 and 599, got: %s", response.Status)
+		}
+		mediator.Status = status
+	}
+	for _, header := range response.Headers {
+		if header.Name == "" || strings.ContainsAny(header.Name, " \t\r\n:") || strings.ContainsAny(header.Value, "\r\n") {
+			return artifacts.StaticResponseMediator{}, fmt.Errorf("invalid static response header '%s'", header.Name)
+		}
+		if mediator.Headers == nil {
+			mediator.Headers = make(map[string]string)
+		}
+		mediator.Headers[header.Name] = header.Value
+	}
+	return mediator, nil
+}
+
 func (r *Resource) decodeSequence(decoder *xml.Decoder, position artifacts.Position, sequenceType string, res artifacts.Resource) (artifacts.Sequence, error) {
 	line, _ := decoder.InputPos()
 
@@ -207,7 +615,7 @@ func (r *Resource) decodeSequence(decoder *xml.Decoder, position artifacts.Posit
 		}
 
 		if startElem, ok := token.(xml.StartElement); ok {
-			if startElem.Name.Local == "sequence" {
+			if startElem.Name.Local == "sequence" && attribute(startElem, "key") == "" {
 				// Handle nested sequence format
 				decodeSeq := Sequence{}
 				seq, err := decodeSeq.unmarshal(decoder, position)
@@ -223,13 +631,11 @@ func (r *Resource) decodeSequence(decoder *xml.Decoder, position artifacts.Posit
 				}
 
 				// Process the first element we found
-				switch startElem.Name.Local {
-				case "log":
-					logMediator := LogMediator{}
-					mediator, err := logMediator.Unmarshal(decoder, startElem, position)
-					if err != nil {
-						return artifacts.Sequence{}, err
-					}
+				mediator, ok, err := unmarshalMediator(decoder, startElem, position)
+				if err != nil {
+					return artifacts.Sequence{}, err
+				}
+				if ok {
 					mediatorList = append(mediatorList, mediator)
 				}
 
@@ -244,13 +650,11 @@ func (r *Resource) decodeSequence(decoder *xml.Decoder, position artifacts.Posit
 					position := artifacts.Position{LineNo: line, FileName: position.FileName, Hierarchy: position.Hierarchy}
 					switch element := token.(type) {
 					case xml.StartElement:
-						switch element.Name.Local {
-						case "log":
-							logMediator := LogMediator{}
-							mediator, err := logMediator.Unmarshal(decoder, element, position)
-							if err != nil {
-								return artifacts.Sequence{}, err
-							}
+						mediator, ok, err := unmarshalMediator(decoder, element, position)
+						if err != nil {
+							return artifacts.Sequence{}, err
+						}
+						if ok {
 							mediatorList = append(mediatorList, mediator)
 						}
 					case xml.EndElement:
@@ -268,8 +672,8 @@ func (r *Resource) decodeSequence(decoder *xml.Decoder, position artifacts.Posit
 
 func (r *Resource) parseURITemplate(uriTemplate string) (artifacts.URITemplateInfo, error) {
 	parsedInfo := artifacts.URITemplateInfo{
-		FullTemplate:  uriTemplate,
-		PathParameters: []string{},
+		FullTemplate:    uriTemplate,
+		PathParameters:  []string{},
 		QueryParameters: make(map[string]string),
 	}
 
@@ -330,4 +734,52 @@ func (r *Resource) parseURITemplate(uriTemplate string) (artifacts.URITemplateIn
 		}
 	}
 	return parsedInfo, nil
-}
\ No newline at end of file
+}
+
+// parseOverflow reads the attributes of an <overflow> element, e.g.
+// <overflow maxSize="10MB" sequence="ClaimCheck"/>
+// <overflow maxSize="512KB" endpoint="BlobStoreEP"/>
+func parseOverflow(elem xml.StartElement) (*artifacts.Overflow, error) {
+	overflow := &artifacts.Overflow{}
+	for _, attr := range elem.Attr {
+		switch attr.Name.Local {
+		case "maxSize":
+			size, err := parseByteSize(attr.Value)
+			if err != nil || size <= 0 {
+				return nil, fmt.Errorf("overflow maxSize must be a positive size such as 512KB or 10MB, got: %s", attr.Value)
+			}
+			overflow.MaxSize = size
+		case "sequence":
+			overflow.Sequence = attr.Value
+		case "endpoint":
+			overflow.Endpoint = attr.Value
+		}
+	}
+	if overflow.MaxSize == 0 {
+		return nil, fmt.Errorf("overflow requires maxSize")
+	}
+	if (overflow.Sequence == "") == (overflow.Endpoint == "") {
+		return nil, fmt.Errorf("overflow requires exactly one of sequence and endpoint")
+	}
+	return overflow, nil
+}
+
+// parseByteSize reads a number of bytes with an optional KB, MB or GB suffix
+func parseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(strings.ToUpper(value))
+	multiplier := int64(1)
+	for suffix, size := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(value, suffix) {
+			value, multiplier = strings.TrimSpace(strings.TrimSuffix(value, suffix)), size
+			break
+		}
+	}
+	size, err := strconv.ParseInt(strings.TrimSuffix(value, "B"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if size > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("size %s is too large", value)
+	}
+	return size * multiplier, nil
+}
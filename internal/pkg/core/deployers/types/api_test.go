@@ -20,9 +20,11 @@ package types
 
 import (
 	"testing"
+	"time"
 
 	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAPI_Unmarshal(t *testing.T) {
@@ -110,6 +112,21 @@ func TestAPI_Unmarshal_ValidationErrors(t *testing.T) {
 			xmlData:  `<api context="/test" name="TestAPI" version="1.0" version-type="invalid"></api>`,
 			expected: "version-type must be either 'context' or 'url', got: invalid",
 		},
+		{
+			name:     "Invalid sunset date",
+			xmlData:  `<api context="/test" name="TestAPI" deprecated="true" sunset="next year"></api>`,
+			expected: "sunset must be an RFC 3339 date, got: next year",
+		},
+		{
+			name:     "Sunset without deprecation",
+			xmlData:  `<api context="/test" name="TestAPI" sunset="2025-06-30T00:00:00Z"></api>`,
+			expected: "sunset, deprecationLink and deprecationWarningPercent require deprecated to be set",
+		},
+		{
+			name:     "Hostname with port",
+			xmlData:  `<api context="/test" name="TestAPI" hostname="api.foo.com:8290"></api>`,
+			expected: "invalid API hostname 'api.foo.com:8290': expected a host name without scheme, port or path",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -248,4 +265,375 @@ func TestAPI_UnmarshalWithNestedSequence(t *testing.T) {
 	faultLogMediator := resource.FaultSequence.MediatorList[0].(artifacts.LogMediator)
 	assert.Equal(t, "TestAPI->/resource1->faultSequence->log", faultLogMediator.Position.Hierarchy)
 	assert.Equal(t, 9, faultLogMediator.Position.LineNo)
-}
\ No newline at end of file
+}
+
+func TestAPI_Unmarshal_WithHostname(t *testing.T) {
+	xmlData := `<api context="/test" name="TestAPI" hostname="API.Foo.com">
+		<resource methods="GET" uri-template="/resource1"></resource>
+	</api>`
+
+	api := &API{}
+	result, err := api.Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	assert.Equal(t, "api.foo.com", result.Hostname)
+}
+
+func TestAPI_Unmarshal_WithSLO(t *testing.T) {
+	tests := []struct {
+		name    string
+		slo     string
+		want    *artifacts.SLO
+		wantErr string
+	}{
+		{
+			name: "latency and error rate",
+			slo:  `<slo window="5m" maxLatency="300ms" percentile="99" maxErrorRate="0.02" minRequests="20" degradedSequence="Fallback"/>`,
+			want: &artifacts.SLO{Window: 5 * time.Minute, MaxLatency: 300 * time.Millisecond, Percentile: 99,
+				MaxErrorRate: 0.02, MinRequests: 20, DegradedSequence: "Fallback"},
+		},
+		{
+			name: "defaults",
+			slo:  `<slo maxLatency="1s"/>`,
+			want: &artifacts.SLO{Window: time.Minute, MaxLatency: time.Second, Percentile: 95},
+		},
+		{name: "no objective", slo: `<slo window="1m"/>`, wantErr: "slo requires maxLatency or maxErrorRate"},
+		{name: "invalid error rate", slo: `<slo maxErrorRate="5"/>`, wantErr: "invalid slo maxErrorRate '5': must be between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xmlData := `<api context="/test" name="TestAPI">` + tt.slo + `
+				<resource methods="GET" uri-template="/resource1"></resource>
+			</api>`
+			api := &API{}
+			result, err := api.Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result.SLO)
+			assert.Len(t, result.Resources, 1)
+		})
+	}
+}
+
+func TestAPI_Unmarshal_WithMirror(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirror  string
+		want    *artifacts.Mirror
+		wantErr string
+	}{
+		{name: "percentage", mirror: `<mirror endpoint="OrdersV2EP" percent="5"/>`, want: &artifacts.Mirror{Endpoint: "OrdersV2EP", Percent: 5}},
+		{name: "all traffic by default", mirror: `<mirror endpoint="OrdersV2EP"/>`, want: &artifacts.Mirror{Endpoint: "OrdersV2EP", Percent: 100}},
+		{name: "no mirror", mirror: ``},
+		{
+			name:    "invalid percent",
+			mirror:  `<mirror endpoint="OrdersV2EP" percent="150"/>`,
+			wantErr: "API TestAPI: mirror percent must be a number above 0 and at most 100, got: 150",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xmlData := `<api context="/test" name="TestAPI">` + tt.mirror + `
+				<resource methods="GET" uri-template="/resource1"></resource>
+			</api>`
+			result, err := (&API{}).Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result.Mirror)
+			assert.Len(t, result.Resources, 1)
+		})
+	}
+}
+
+func TestAPI_Unmarshal_WithOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		overflow string
+		want     *artifacts.Overflow
+		wantErr  string
+	}{
+		{name: "sequence", overflow: `<overflow maxSize="10MB" sequence="ClaimCheck"/>`, want: &artifacts.Overflow{MaxSize: 10 << 20, Sequence: "ClaimCheck"}},
+		{name: "endpoint", overflow: `<overflow maxSize="512 kb" endpoint="BlobStoreEP"/>`, want: &artifacts.Overflow{MaxSize: 512 << 10, Endpoint: "BlobStoreEP"}},
+		{name: "bytes", overflow: `<overflow maxSize="4096" sequence="ClaimCheck"/>`, want: &artifacts.Overflow{MaxSize: 4096, Sequence: "ClaimCheck"}},
+		{name: "no overflow", overflow: ``},
+		{name: "no size", overflow: `<overflow sequence="ClaimCheck"/>`, wantErr: "API TestAPI: overflow requires maxSize"},
+		{name: "invalid size", overflow: `<overflow maxSize="lots" sequence="ClaimCheck"/>`,
+			wantErr: "API TestAPI: overflow maxSize must be a positive size such as 512KB or 10MB, got: lots"},
+		{name: "too large", overflow: `<overflow maxSize="99999999999GB" sequence="ClaimCheck"/>`,
+			wantErr: "API TestAPI: overflow maxSize must be a positive size such as 512KB or 10MB, got: 99999999999GB"},
+		{name: "no target", overflow: `<overflow maxSize="1MB"/>`, wantErr: "API TestAPI: overflow requires exactly one of sequence and endpoint"},
+		{name: "two targets", overflow: `<overflow maxSize="1MB" sequence="A" endpoint="B"/>`,
+			wantErr: "API TestAPI: overflow requires exactly one of sequence and endpoint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xmlData := `<api context="/test" name="TestAPI">` + tt.overflow + `
+				<resource methods="POST" uri-template="/resource1"></resource>
+			</api>`
+			result, err := (&API{}).Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result.Overflow)
+			assert.Len(t, result.Resources, 1)
+		})
+	}
+}
+
+func TestAPI_Unmarshal_WithStatusMappings(t *testing.T) {
+	xmlData := `<api context="/orders" name="OrdersAPI">
+		<resource methods="GET" uri-template="/"></resource>
+		<statusMap from="5xx" to="503" payload='{"error":"unavailable"}' contentType="application/json"/>
+		<resource methods="POST" uri-template="/"></resource>
+	</api>`
+	result, err := (&API{}).Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+	require.NoError(t, err)
+	want := artifacts.StatusMappings{{From: []artifacts.StatusRange{{Min: 500, Max: 599}}, To: 503,
+		Payload: []byte(`{"error":"unavailable"}`), ContentType: "application/json"}}
+	assert.Equal(t, want, result.StatusMappings)
+	if assert.Len(t, result.Resources, 2) {
+		assert.Equal(t, want, result.Resources[0].StatusMappings, "mappings declared after a resource apply to it")
+		assert.Equal(t, want, result.Resources[1].StatusMappings)
+	}
+
+	_, err = (&API{}).Unmarshal(`<api context="/orders" name="OrdersAPI"><statusMap from="5xx"/></api>`, artifacts.Position{FileName: "testfile.xml"})
+	assert.EqualError(t, err, "API OrdersAPI: statusMap requires from and to")
+}
+
+func TestAPI_Unmarshal_WithTimeout(t *testing.T) {
+	xmlData := `<api context="/orders" name="OrdersAPI" timeout="2s">
+		<resource methods="GET" uri-template="/"></resource>
+		<resource methods="POST" uri-template="/" timeout="500ms"></resource>
+	</api>`
+	result, err := (&API{}).Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, result.Timeout)
+	if assert.Len(t, result.Resources, 2) {
+		assert.Equal(t, 2*time.Second, result.Resources[0].Timeout, "resources take the API's budget")
+		assert.Equal(t, 500*time.Millisecond, result.Resources[1].Timeout)
+	}
+
+	_, err = (&API{}).Unmarshal(`<api context="/orders" name="OrdersAPI" timeout="0s"></api>`, artifacts.Position{FileName: "testfile.xml"})
+	assert.EqualError(t, err, "timeout must be a positive duration, got: 0s")
+	_, err = (&API{}).Unmarshal(`<api context="/orders" name="OrdersAPI"><resource methods="GET" uri-template="/" timeout="soon"></resource></api>`, artifacts.Position{FileName: "testfile.xml"})
+	assert.ErrorContains(t, err, "timeout must be a positive duration, got: soon")
+}
+
+func TestAPI_Unmarshal_AsyncResource(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   string
+		want    *artifacts.AsyncReply
+		wantErr string
+	}{
+		{name: "synchronous", attrs: ``},
+		{name: "default ttl", attrs: `async="true"`, want: &artifacts.AsyncReply{TTL: 10 * time.Minute}},
+		{name: "custom ttl", attrs: `async="true" asyncTTL="1h"`, want: &artifacts.AsyncReply{TTL: time.Hour}},
+		{name: "disabled", attrs: `async="false"`},
+		{name: "invalid flag", attrs: `async="yes"`, wantErr: "async must be either 'true' or 'false', got: yes"},
+		{name: "ttl without async", attrs: `asyncTTL="1h"`, wantErr: `asyncTTL requires async="true"`},
+		{name: "invalid ttl", attrs: `async="true" asyncTTL="-1s"`, wantErr: "asyncTTL must be a positive duration, got: -1s"},
+		{name: "callbacks", attrs: `async="true" asyncCallbacks="https://hooks.example.com/orders/, https://partner.example.org"`,
+			want: &artifacts.AsyncReply{TTL: 10 * time.Minute, Callbacks: []string{"https://hooks.example.com/orders/", "https://partner.example.org"}}},
+		{name: "callbacks without async", attrs: `asyncCallbacks="https://hooks.example.com"`, wantErr: `asyncCallbacks requires async="true"`},
+		{name: "invalid callback", attrs: `async="true" asyncCallbacks="hooks.example.com"`, wantErr: "asyncCallbacks: callback URL hooks.example.com must be an absolute http or https URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xmlData := `<api context="/test" name="TestAPI">
+				<resource methods="POST" uri-template="/orders" ` + tt.attrs + `></resource>
+			</api>`
+			api := &API{}
+			result, err := api.Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result.Resources[0].Async)
+		})
+	}
+}
+
+func TestAPI_Unmarshal_WithErrorTemplate(t *testing.T) {
+	tests := []struct {
+		name            string
+		errorTemplate   string
+		wantContentType string
+		wantErr         string
+	}{
+		{
+			name:            "json body",
+			errorTemplate:   `<errorTemplate><![CDATA[{"code": {{.Status}}, "message": {{json .Detail}}}]]></errorTemplate>`,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "xml body",
+			errorTemplate:   `<errorTemplate contentType="application/xml"><![CDATA[<error>{{.Title}}</error>]]></errorTemplate>`,
+			wantContentType: "application/xml",
+		},
+		{name: "unknown field", errorTemplate: `<errorTemplate>{{.Code}}</errorTemplate>`, wantErr: "invalid error template"},
+		{name: "syntax error", errorTemplate: `<errorTemplate>{{.Status</errorTemplate>`, wantErr: "invalid error template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xmlData := `<api context="/test" name="TestAPI">` + tt.errorTemplate + `
+				<resource methods="GET" uri-template="/resource1"></resource>
+			</api>`
+			api := &API{}
+			result, err := api.Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantContentType, result.ErrorTemplate.ContentType)
+			assert.Len(t, result.Resources, 1)
+		})
+	}
+}
+
+func TestAPI_Unmarshal_WithSelfTests(t *testing.T) {
+	tests := []struct {
+		name     string
+		selfTest string
+		want     []artifacts.SelfTest
+		wantErr  string
+	}{
+		{
+			name: "request with headers and payload",
+			selfTest: `<selfTest name="createOrder" method="post" path="/orders?dryRun=true" expectedStatus="201" timeout="2s" critical="false">
+				<header name="Content-Type" value="application/json"/>
+				<payload>{"id":1}</payload>
+			</selfTest>`,
+			want: []artifacts.SelfTest{{Name: "createOrder", Method: "POST", Path: "/orders?dryRun=true",
+				Headers: map[string]string{"Content-Type": "application/json"}, Payload: `{"id":1}`,
+				ExpectedStatus: 201, Timeout: 2 * time.Second}},
+		},
+		{
+			name:     "defaults",
+			selfTest: `<selfTest name="listOrders" path="/orders"/>`,
+			want:     []artifacts.SelfTest{{Name: "listOrders", Method: "GET", Path: "/orders", Timeout: 5 * time.Second, Critical: true}},
+		},
+		{name: "missing name", selfTest: `<selfTest path="/orders"/>`, wantErr: "API TestAPI: selfTest name is required"},
+		{name: "relative path", selfTest: `<selfTest name="a" path="orders"/>`, wantErr: "API TestAPI: selfTest a path must begin with '/', got: orders"},
+		{name: "invalid status", selfTest: `<selfTest name="a" path="/" expectedStatus="99"/>`, wantErr: "API TestAPI: invalid selfTest a expectedStatus '99'"},
+		{
+			name:     "duplicate name",
+			selfTest: `<selfTest name="a" path="/"/><selfTest name="a" path="/orders"/>`,
+			wantErr:  "API TestAPI: duplicate selfTest a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xmlData := `<api context="/test" name="TestAPI">` + tt.selfTest + `
+				<resource methods="GET" uri-template="/resource1"></resource>
+			</api>`
+			api := &API{}
+			result, err := api.Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result.SelfTests)
+			assert.Len(t, result.Resources, 1)
+		})
+	}
+}
+
+func TestAPI_Unmarshal_ResourceTypes(t *testing.T) {
+	xmlData := `<api context="/test" name="TestAPI">
+		<resource methods="POST" uri-template="/echo" type="echo"/>
+		<resource methods="GET" uri-template="/health" type="static">
+			<response status="503" contentType="application/json">
+				<header name="Cache-Control" value="no-store"/>
+				<payload><![CDATA[{"status": "DOWN"}]]></payload>
+			</response>
+		</resource>
+		<resource methods="GET" uri-template="/ping" type="static">
+			<response><payload>pong</payload></response>
+		</resource>
+	</api>`
+	api := &API{}
+	result, err := api.Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Resources, 3) {
+		echo := result.Resources[0]
+		assert.Equal(t, artifacts.ResourceTypeEcho, echo.Type)
+		assert.IsType(t, artifacts.EchoMediator{}, echo.InSequence.MediatorList[0])
+
+		health := result.Resources[1]
+		assert.Equal(t, artifacts.ResourceTypeStatic, health.Type)
+		static := health.InSequence.MediatorList[0].(artifacts.StaticResponseMediator)
+		assert.Equal(t, 503, static.Status)
+		assert.Equal(t, "application/json", static.ContentType)
+		assert.Equal(t, map[string]string{"Cache-Control": "no-store"}, static.Headers)
+		assert.Equal(t, `{"status": "DOWN"}`, static.Payload)
+
+		ping := result.Resources[2].InSequence.MediatorList[0].(artifacts.StaticResponseMediator)
+		assert.Equal(t, 200, ping.Status)
+		assert.Equal(t, "pong", ping.Payload)
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		wantErr  string
+	}{
+		{name: "unknown type", resource: `<resource uri-template="/a" type="mock"/>`, wantErr: "resource type must be sequence, echo or static, got: mock"},
+		{name: "echo with sequence", resource: `<resource uri-template="/a" type="echo"><inSequence><respond/></inSequence></resource>`, wantErr: "echo resources cannot have an inSequence"},
+		{name: "static with out sequence", resource: `<resource uri-template="/a" type="static"><outSequence><log/></outSequence></resource>`, wantErr: "static resources cannot have an outSequence"},
+		{name: "static without response", resource: `<resource uri-template="/a" type="static"/>`, wantErr: "static resources require a response element"},
+		{name: "response without static", resource: `<resource uri-template="/a"><response/></resource>`, wantErr: "only static resources have a response"},
+		{name: "bad status", resource: `<resource uri-template="/a" type="static"><response status="42"/></resource>`, wantErr: "static response status must be between 200 and 599, got: 42"},
+		{name: "bad header", resource: `<resource uri-template="/a" type="static"><response><header name="X Y" value="1"/></response></resource>`, wantErr: "invalid static response header 'X Y'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.Unmarshal(`<api context="/test" name="TestAPI">`+tt.resource+`</api>`, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAPI_Unmarshal_OutSequence(t *testing.T) {
+	xmlData := `
+	<api context="/test" name="TestAPI">
+		<resource methods="GET" uri-template="/orders">
+			<inSequence>
+				<loopback/>
+			</inSequence>
+			<outSequence>
+				<header name="X-Served-By" value="synapse"/>
+			</outSequence>
+		</resource>
+	</api>`
+
+	api := &API{}
+	result, err := api.Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Resources, 1) {
+		resource := result.Resources[0]
+		loopback := resource.InSequence.MediatorList[0].(artifacts.LoopbackMediator)
+		assert.Equal(t, "TestAPI->/orders->inSequence->loopback", loopback.Position.Hierarchy)
+		if assert.Len(t, resource.OutSequence.MediatorList, 1) {
+			assert.IsType(t, artifacts.HeaderMediator{}, resource.OutSequence.MediatorList[0])
+		}
+	}
+}
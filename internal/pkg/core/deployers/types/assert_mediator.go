@@ -0,0 +1,65 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// AssertMediator is the XML form of the assert mediator, checked only when
+// [assertions] is enabled, e.g.
+// <assert expression="payload.order.id != nil" message="orders have an id"/>
+// <assert expression="headers['x-tenant']" equals="'acme'"/>
+type AssertMediator struct {
+	XMLName    xml.Name `xml:"assert"`
+	Expression string   `xml:"expression,attr"`
+	Equals     string   `xml:"equals,attr"`
+	Message    string   `xml:"message,attr"`
+}
+
+func (assertMediator AssertMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&assertMediator, &start); err != nil {
+		return artifacts.AssertMediator{}, errors.New("error in unmarshalling assert mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->assert"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("assert mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if assertMediator.Expression == "" {
+		return artifacts.AssertMediator{}, invalid("missing required attribute 'expression'")
+	}
+	program, err := expression.Compile(assertMediator.Expression, artifacts.ExpressionVariables...)
+	if err != nil {
+		return artifacts.AssertMediator{}, invalid("%v", err)
+	}
+	mediator := artifacts.AssertMediator{Expression: program, Message: assertMediator.Message, Position: position}
+	if assertMediator.Equals != "" {
+		if mediator.Equals, err = expression.Compile(assertMediator.Equals, artifacts.ExpressionVariables...); err != nil {
+			return artifacts.AssertMediator{}, invalid("equals: %v", err)
+		}
+	}
+	return mediator, nil
+}
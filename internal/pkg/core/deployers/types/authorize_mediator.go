@@ -0,0 +1,131 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+type AuthorizeMediator struct {
+	XMLName         xml.Name            `xml:"authorize"`
+	Header          string              `xml:"header,attr"`
+	VerificationKey string              `xml:"verificationKey,attr"`
+	Algorithms      string              `xml:"algorithms,attr"`
+	Issuer          string              `xml:"issuer,attr"`
+	Audience        string              `xml:"audience,attr"`
+	Default         string              `xml:"default,attr"`
+	Rules           []AuthorizationRule `xml:"rule"`
+}
+
+// AuthorizationRule holds a condition without the surrounding braces, such as:
+// .HasClaim "scope" "orders:write"
+type AuthorizationRule struct {
+	Name        string       `xml:"name,attr"`
+	Effect      string       `xml:"effect,attr"`
+	Condition   string       `xml:"condition,attr"`
+	Message     string       `xml:"message,attr"`
+	Obligations []Obligation `xml:"obligation"`
+}
+
+// Obligation sets a property or a header to a template value, such as:
+// {{.Claim "tenant"}}
+type Obligation struct {
+	Property string `xml:"property,attr"`
+	Header   string `xml:"header,attr"`
+	Value    string `xml:"value,attr"`
+}
+
+func (authorizeMediator AuthorizeMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&authorizeMediator, &start); err != nil {
+		return artifacts.AuthorizeMediator{}, errors.New("error in unmarshalling authorize mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->authorize"
+	m := authorizeMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("authorize mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	mediator := artifacts.AuthorizeMediator{
+		Header:          m.Header,
+		VerificationKey: m.VerificationKey,
+		Issuer:          m.Issuer,
+		Audience:        m.Audience,
+		DefaultEffect:   m.Default,
+		Position:        position,
+	}
+	if mediator.Header == "" {
+		mediator.Header = "Authorization"
+	}
+	if mediator.DefaultEffect == "" {
+		mediator.DefaultEffect = artifacts.EffectDeny
+	}
+	if mediator.DefaultEffect != artifacts.EffectPermit && mediator.DefaultEffect != artifacts.EffectDeny {
+		return artifacts.AuthorizeMediator{}, invalid("default must be 'permit' or 'deny', got: %s", mediator.DefaultEffect)
+	}
+	for _, algorithm := range strings.Split(m.Algorithms, ",") {
+		if algorithm = strings.TrimSpace(algorithm); algorithm != "" {
+			mediator.Algorithms = append(mediator.Algorithms, algorithm)
+		}
+	}
+
+	for i, rule := range m.Rules {
+		name := rule.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+		if rule.Effect != artifacts.EffectPermit && rule.Effect != artifacts.EffectDeny {
+			return artifacts.AuthorizeMediator{}, invalid("rule %s: effect must be 'permit' or 'deny', got: %s", name, rule.Effect)
+		}
+		if rule.Condition == "" {
+			return artifacts.AuthorizeMediator{}, invalid("rule %s requires a condition", name)
+		}
+		condition, err := template.New(name).Funcs(artifacts.PreconditionFuncs).Parse("{{" + rule.Condition + "}}")
+		if err != nil {
+			return artifacts.AuthorizeMediator{}, invalid("invalid condition of rule %s: %v", name, err)
+		}
+		compiled := artifacts.AuthorizationRule{Name: name, Effect: rule.Effect, Condition: condition, Message: rule.Message}
+		for _, obligation := range rule.Obligations {
+			if (obligation.Property == "") == (obligation.Header == "") {
+				return artifacts.AuthorizeMediator{}, invalid("obligation of rule %s must set either a property or a header", name)
+			}
+			value, err := template.New(name).Funcs(artifacts.PreconditionFuncs).Parse(obligation.Value)
+			if err != nil {
+				return artifacts.AuthorizeMediator{}, invalid("invalid obligation value of rule %s: %v", name, err)
+			}
+			compiled.Obligations = append(compiled.Obligations, artifacts.Obligation{
+				Property: obligation.Property,
+				Header:   obligation.Header,
+				Value:    value,
+			})
+		}
+		mediator.Rules = append(mediator.Rules, compiled)
+	}
+	if len(mediator.Rules) == 0 && mediator.DefaultEffect == artifacts.EffectDeny {
+		return artifacts.AuthorizeMediator{}, invalid("denies every request: declare at least one rule")
+	}
+	return mediator, nil
+}
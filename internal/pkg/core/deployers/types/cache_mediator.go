@@ -0,0 +1,87 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// CacheMediator is the XML form of the cache mediator, a finder such as
+// <cache timeout="5m" headers="Accept,X-Tenant"/> before the backend call and
+// a <cache collector="true"/> once the response is built
+type CacheMediator struct {
+	XMLName   xml.Name `xml:"cache"`
+	Collector string   `xml:"collector,attr"`
+	Timeout   string   `xml:"timeout,attr"`
+	Headers   string   `xml:"headers,attr"`
+	Scope     string   `xml:"scope,attr"`
+}
+
+func (cacheMediator CacheMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&cacheMediator, &start); err != nil {
+		return artifacts.CacheMediator{}, errors.New("error in unmarshalling cache mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->cache"
+	m := cacheMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("cache mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	collector := false
+	if m.Collector != "" {
+		var err error
+		if collector, err = strconv.ParseBool(m.Collector); err != nil {
+			return artifacts.CacheMediator{}, invalid("collector must be true or false, got: %s", m.Collector)
+		}
+	}
+	if collector {
+		if m.Timeout != "" || m.Headers != "" || m.Scope != "" {
+			return artifacts.CacheMediator{}, invalid("a collector takes timeout, headers and scope from its finder")
+		}
+		return artifacts.CacheMediator{Collector: true, Position: position}, nil
+	}
+
+	ttl := artifacts.DefaultCacheTTL
+	if m.Timeout != "" {
+		var err error
+		if ttl, err = time.ParseDuration(m.Timeout); err != nil || ttl <= 0 {
+			return artifacts.CacheMediator{}, invalid("timeout must be a positive duration such as 5m, got: %s", m.Timeout)
+		}
+	}
+	var headers []string
+	for _, name := range strings.Split(m.Headers, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			headers = append(headers, http.CanonicalHeaderKey(name))
+		}
+	}
+	// Without an explicit scope, the entries of each finder are kept apart
+	if m.Scope == "" {
+		m.Scope = fmt.Sprintf("%s:%d", position.FileName, position.LineNo)
+	}
+	return artifacts.CacheMediator{Scope: m.Scope, TTL: ttl, Headers: headers, Position: position}, nil
+}
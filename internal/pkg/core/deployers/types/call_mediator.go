@@ -0,0 +1,91 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+)
+
+// CallMediator is the XML form of the call mediator, e.g.
+// <call endpoint="InventoryEP"/>
+// <call uri="http://inventory:8080/items/{{.Property "itemId"}}" method="GET" timeout="5s"/>
+type CallMediator struct {
+	XMLName  xml.Name `xml:"call"`
+	Endpoint string   `xml:"endpoint,attr"`
+	URI      string   `xml:"uri,attr"`
+	Method   string   `xml:"method,attr"`
+	Timeout  string   `xml:"timeout,attr"`
+}
+
+func (callMediator CallMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&callMediator, &start); err != nil {
+		return artifacts.CallMediator{}, errors.New("error in unmarshalling call mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->call"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("call mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if (callMediator.Endpoint == "") == (callMediator.URI == "") {
+		return artifacts.CallMediator{}, invalid("exactly one of 'endpoint' and 'uri' is required")
+	}
+	mediator := artifacts.CallMediator{Endpoint: callMediator.Endpoint, Send: outbound.Send, Position: position}
+	if callMediator.Endpoint != "" {
+		if callMediator.Method != "" || callMediator.Timeout != "" {
+			return artifacts.CallMediator{}, invalid("method and timeout apply to 'uri' only, set them on endpoint %s", callMediator.Endpoint)
+		}
+		return mediator, nil
+	}
+
+	// An inline call is an http endpoint named after its URI
+	endpoint := artifacts.Endpoint{
+		Name:        callMediator.URI,
+		Protocol:    artifacts.ProtocolHTTP,
+		EndpointUrl: artifacts.EndpointUrl{Method: strings.ToUpper(callMediator.Method), URL: callMediator.URI},
+		Options:     make(map[string]*template.Template),
+		Position:    position,
+	}
+	for name, value := range map[string]string{
+		outbound.HTTPURIOption:     callMediator.URI,
+		outbound.HTTPMethodOption:  callMediator.Method,
+		outbound.HTTPTimeoutOption: callMediator.Timeout,
+	} {
+		if value == "" {
+			continue
+		}
+		option, err := template.New("call." + name).Funcs(artifacts.PreconditionFuncs).Parse(value)
+		if err != nil {
+			return artifacts.CallMediator{}, invalid("invalid %s template: %v", name, err)
+		}
+		endpoint.Options[name] = option
+	}
+	if err := outbound.Validate(endpoint); err != nil {
+		return artifacts.CallMediator{}, invalid("%v", err)
+	}
+	mediator.Inline = &endpoint
+	return mediator, nil
+}
@@ -0,0 +1,110 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/claimcheck"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// StorePayloadMediator is the XML form of the store payload mediator, e.g.
+// <storePayload store="file" property="ORDER_CLAIM" maxSize="5MB"/>
+type StorePayloadMediator struct {
+	XMLName  xml.Name `xml:"storePayload"`
+	Store    string   `xml:"store,attr"`
+	Property string   `xml:"property,attr"`
+	MaxSize  string   `xml:"maxSize,attr"`
+}
+
+func (storePayloadMediator StorePayloadMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&storePayloadMediator, &start); err != nil {
+		return artifacts.StorePayloadMediator{}, errors.New("error in unmarshalling storePayload mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->storePayload"
+	m := storePayloadMediator
+
+	store, err := claimCheckStore(m.Store)
+	if err != nil {
+		return artifacts.StorePayloadMediator{}, fmt.Errorf("storePayload mediator in %s at line %d: %v", position.FileName, position.LineNo, err)
+	}
+	if m.Property == "" {
+		m.Property = artifacts.ClaimCheckTokenProperty
+	}
+	maxSize := int64(claimcheck.DefaultMaxSize)
+	if m.MaxSize != "" {
+		if maxSize, err = parseByteSize(m.MaxSize); err != nil || maxSize <= 0 {
+			return artifacts.StorePayloadMediator{}, fmt.Errorf("storePayload mediator in %s at line %d: maxSize must be a positive size such as 512KB or 10MB, got: %s", position.FileName, position.LineNo, m.MaxSize)
+		}
+	}
+	return artifacts.StorePayloadMediator{Store: store, Property: m.Property, MaxSize: maxSize, Position: position}, nil
+}
+
+// RestorePayloadMediator is the XML form of the restore payload mediator, e.g.
+// <restorePayload store="file" token="properties.ORDER_CLAIM" remove="true"/>
+type RestorePayloadMediator struct {
+	XMLName xml.Name `xml:"restorePayload"`
+	Store   string   `xml:"store,attr"`
+	Token   string   `xml:"token,attr"`
+	Remove  string   `xml:"remove,attr"`
+}
+
+func (restorePayloadMediator RestorePayloadMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&restorePayloadMediator, &start); err != nil {
+		return artifacts.RestorePayloadMediator{}, errors.New("error in unmarshalling restorePayload mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->restorePayload"
+	m := restorePayloadMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("restorePayload mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	store, err := claimCheckStore(m.Store)
+	if err != nil {
+		return artifacts.RestorePayloadMediator{}, invalid("%v", err)
+	}
+	if m.Token == "" {
+		m.Token = artifacts.DefaultClaimCheckToken
+	}
+	token, err := expression.Compile(m.Token, artifacts.ExpressionVariables...)
+	if err != nil {
+		return artifacts.RestorePayloadMediator{}, invalid("invalid token expression: %v", err)
+	}
+	remove := false
+	if m.Remove != "" {
+		if remove, err = strconv.ParseBool(m.Remove); err != nil {
+			return artifacts.RestorePayloadMediator{}, invalid("remove must be true or false, got: %s", m.Remove)
+		}
+	}
+	return artifacts.RestorePayloadMediator{Store: store, Token: token, Remove: remove, Position: position}, nil
+}
+
+// claimCheckStore looks up the named store, the in-memory one by default
+func claimCheckStore(name string) (claimcheck.Store, error) {
+	if name == "" {
+		name = claimcheck.MemoryStoreName
+	}
+	return claimcheck.Lookup(name)
+}
@@ -0,0 +1,123 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// CloneMediator is the XML form of the clone mediator, e.g.
+//
+//	<clone id="orders" concurrency="4" continueParent="true">
+//	    <target sequence="AuditOrder"/>
+//	    <target>
+//	        <sequence>
+//	            <log category="INFO"/>
+//	        </sequence>
+//	    </target>
+//	</clone>
+type CloneMediator struct{}
+
+func (cloneMediator CloneMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	position.Hierarchy = position.Hierarchy + "->clone"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("clone mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	mediator := artifacts.CloneMediator{Position: position}
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "id":
+			mediator.ID = attr.Value
+		case "concurrency":
+			concurrency, err := strconv.Atoi(attr.Value)
+			if err != nil || concurrency < 1 {
+				return artifacts.CloneMediator{}, invalid("concurrency must be a positive integer, got: %s", attr.Value)
+			}
+			mediator.Concurrency = concurrency
+		case "continueParent":
+			continueParent, err := strconv.ParseBool(attr.Value)
+			if err != nil {
+				return artifacts.CloneMediator{}, invalid("continueParent must be true or false, got: %s", attr.Value)
+			}
+			mediator.ContinueParent = continueParent
+		}
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return artifacts.CloneMediator{}, invalid("%v", err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if element.Name.Local != "target" {
+				return artifacts.CloneMediator{}, invalid("unexpected element '%s', expected target", element.Name.Local)
+			}
+			target, err := unmarshalCloneTarget(d, element, position, invalid)
+			if err != nil {
+				return artifacts.CloneMediator{}, err
+			}
+			mediator.Targets = append(mediator.Targets, target)
+		case xml.EndElement:
+			if len(mediator.Targets) == 0 {
+				return artifacts.CloneMediator{}, invalid("at least one target is required")
+			}
+			return mediator, nil
+		}
+	}
+}
+
+func unmarshalCloneTarget(d *xml.Decoder, start xml.StartElement, position artifacts.Position, invalid func(string, ...any) error) (artifacts.CloneTarget, error) {
+	var target artifacts.CloneTarget
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "sequence" {
+			target.SequenceName = attr.Value
+		}
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return artifacts.CloneTarget{}, invalid("%v", err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if element.Name.Local != "sequence" {
+				return artifacts.CloneTarget{}, invalid("unexpected element '%s' in target, expected sequence", element.Name.Local)
+			}
+			if target.Sequence != nil {
+				return artifacts.CloneTarget{}, invalid("a target has only one sequence")
+			}
+			sequence, err := unmarshalBranch(d, position, "target")
+			if err != nil {
+				return artifacts.CloneTarget{}, err
+			}
+			target.Sequence = &sequence
+		case xml.EndElement:
+			if (target.Sequence == nil) == (target.SequenceName == "") {
+				return artifacts.CloneTarget{}, invalid("a target needs exactly one of the 'sequence' attribute and a sequence element")
+			}
+			return target, nil
+		}
+	}
+}
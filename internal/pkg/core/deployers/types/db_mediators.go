@@ -0,0 +1,157 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// DBStatement is the XML form of a statement of the dblookup and dbreport
+// mediators
+type DBStatement struct {
+	SQL        string `xml:"sql"`
+	Parameters []struct {
+		Expression string `xml:"expression,attr"`
+		Type       string `xml:"type,attr"`
+	} `xml:"parameter"`
+	Results []struct {
+		Name   string `xml:"name,attr"`
+		Column string `xml:"column,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"result"`
+}
+
+// DBLookupMediator is the XML form of the dblookup mediator, e.g.
+//
+//	<dblookup datasource="customers">
+//	  <statement>
+//	    <sql>SELECT name, tier FROM customers WHERE id = ?</sql>
+//	    <parameter expression="payload.customerId" type="integer"/>
+//	    <result name="customer.tier" column="tier"/>
+//	  </statement>
+//	</dblookup>
+type DBLookupMediator struct {
+	XMLName    xml.Name      `xml:"dblookup"`
+	DataSource string        `xml:"datasource,attr"`
+	Statements []DBStatement `xml:"statement"`
+}
+
+func (dbLookupMediator DBLookupMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&dbLookupMediator, &start); err != nil {
+		return artifacts.DBLookupMediator{}, errors.New("error in unmarshalling dblookup mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->dblookup"
+	statements, err := dbStatements(dbLookupMediator.DataSource, dbLookupMediator.Statements, true)
+	if err != nil {
+		return artifacts.DBLookupMediator{}, fmt.Errorf("dblookup mediator in %s at line %d: %v", position.FileName, position.LineNo, err)
+	}
+	return artifacts.DBLookupMediator{DataSource: dbLookupMediator.DataSource, Statements: statements, Position: position}, nil
+}
+
+// DBReportMediator is the XML form of the dbreport mediator, e.g.
+//
+//	<dbreport datasource="orders" useTransaction="true">
+//	  <statement>
+//	    <sql>INSERT INTO audit (order_id, status) VALUES (?, ?)</sql>
+//	    <parameter expression="payload.id"/>
+//	    <parameter expression="properties['status']"/>
+//	  </statement>
+//	</dbreport>
+type DBReportMediator struct {
+	XMLName        xml.Name      `xml:"dbreport"`
+	DataSource     string        `xml:"datasource,attr"`
+	UseTransaction string        `xml:"useTransaction,attr"`
+	Statements     []DBStatement `xml:"statement"`
+}
+
+func (dbReportMediator DBReportMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&dbReportMediator, &start); err != nil {
+		return artifacts.DBReportMediator{}, errors.New("error in unmarshalling dbreport mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->dbreport"
+	m := dbReportMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("dbreport mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	statements, err := dbStatements(m.DataSource, m.Statements, false)
+	if err != nil {
+		return artifacts.DBReportMediator{}, invalid("%v", err)
+	}
+	useTransaction := false
+	if m.UseTransaction != "" {
+		if useTransaction, err = strconv.ParseBool(m.UseTransaction); err != nil {
+			return artifacts.DBReportMediator{}, invalid("useTransaction must be true or false, got: %s", m.UseTransaction)
+		}
+	}
+	return artifacts.DBReportMediator{DataSource: m.DataSource, Statements: statements, UseTransaction: useTransaction, Position: position}, nil
+}
+
+// dbStatements compiles the statements of a mediator. Lookups must store at
+// least one column of each statement; reports store none.
+func dbStatements(dataSource string, statements []DBStatement, lookup bool) ([]artifacts.DBStatement, error) {
+	if dataSource == "" {
+		return nil, errors.New("datasource is required")
+	}
+	if len(statements) == 0 {
+		return nil, errors.New("at least one statement is required")
+	}
+	compiled := make([]artifacts.DBStatement, len(statements))
+	for i, statement := range statements {
+		sql := strings.TrimSpace(statement.SQL)
+		if sql == "" {
+			return nil, fmt.Errorf("statement %d: sql is required", i+1)
+		}
+		compiled[i].SQL = sql
+		for j, parameter := range statement.Parameters {
+			program, err := expression.Compile(parameter.Expression, artifacts.ExpressionVariables...)
+			if err != nil {
+				return nil, fmt.Errorf("statement %d: parameter %d: invalid expression: %v", i+1, j+1, err)
+			}
+			if parameter.Type != "" && !artifacts.IsPropertyType(parameter.Type) {
+				return nil, fmt.Errorf("statement %d: parameter %d: type must be string, integer, double, boolean or json, got: %s", i+1, j+1, parameter.Type)
+			}
+			compiled[i].Parameters = append(compiled[i].Parameters, artifacts.DBParameter{Expression: program, Type: parameter.Type})
+		}
+		switch {
+		case lookup && len(statement.Results) == 0:
+			return nil, fmt.Errorf("statement %d: at least one result is required", i+1)
+		case !lookup && len(statement.Results) > 0:
+			return nil, fmt.Errorf("statement %d: results do not apply to dbreport, use dblookup", i+1)
+		}
+		for _, result := range statement.Results {
+			if result.Name == "" || result.Column == "" {
+				return nil, fmt.Errorf("statement %d: a result needs a name and a column", i+1)
+			}
+			if result.Type != "" && !artifacts.IsPropertyType(result.Type) {
+				return nil, fmt.Errorf("statement %d: result %s: type must be string, integer, double, boolean or json, got: %s", i+1, result.Name, result.Type)
+			}
+			compiled[i].Results = append(compiled[i].Results, artifacts.DBResult{Property: result.Name, Column: result.Column, Type: result.Type})
+		}
+	}
+	return compiled, nil
+}
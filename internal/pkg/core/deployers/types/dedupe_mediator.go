@@ -0,0 +1,93 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/dedupe"
+)
+
+// DedupeMediator is the XML form of the dedupe mediator, e.g.
+// <dedupe key='.Header "Message-Id"' ttl="10m" onDuplicate="reject"/>
+type DedupeMediator struct {
+	XMLName     xml.Name `xml:"dedupe"`
+	Key         string   `xml:"key,attr"`
+	TTL         string   `xml:"ttl,attr"`
+	Scope       string   `xml:"scope,attr"`
+	Store       string   `xml:"store,attr"`
+	OnDuplicate string   `xml:"onDuplicate,attr"`
+}
+
+func (dedupeMediator DedupeMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&dedupeMediator, &start); err != nil {
+		return artifacts.DedupeMediator{}, errors.New("error in unmarshalling dedupe mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->dedupe"
+	m := dedupeMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("dedupe mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	if m.Key == "" {
+		return artifacts.DedupeMediator{}, invalid("missing required attribute 'key'")
+	}
+	key, err := template.New("dedupe").Funcs(artifacts.PreconditionFuncs).Parse("{{" + m.Key + "}}")
+	if err != nil {
+		return artifacts.DedupeMediator{}, invalid("invalid key expression: %v", err)
+	}
+	ttl, err := time.ParseDuration(m.TTL)
+	if err != nil || ttl <= 0 {
+		return artifacts.DedupeMediator{}, invalid("ttl must be a positive duration such as 10m, got: %s", m.TTL)
+	}
+	if m.Store == "" {
+		m.Store = dedupe.MemoryStoreName
+	}
+	store, err := dedupe.Lookup(m.Store)
+	if err != nil {
+		return artifacts.DedupeMediator{}, invalid("%v", err)
+	}
+	switch m.OnDuplicate {
+	case "":
+		m.OnDuplicate = artifacts.DuplicateReject
+	case artifacts.DuplicateReject, artifacts.DuplicateMark:
+	default:
+		return artifacts.DedupeMediator{}, invalid("onDuplicate must be 'reject' or 'mark', got: %s", m.OnDuplicate)
+	}
+	// Without an explicit scope, the keys of each mediator are kept apart
+	if m.Scope == "" {
+		m.Scope = fmt.Sprintf("%s:%d", position.FileName, position.LineNo)
+	}
+
+	return artifacts.DedupeMediator{
+		Key:         key,
+		TTL:         ttl,
+		Scope:       m.Scope,
+		Store:       store,
+		OnDuplicate: m.OnDuplicate,
+		Position:    position,
+	}, nil
+}
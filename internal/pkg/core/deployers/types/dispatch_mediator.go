@@ -0,0 +1,53 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// DispatchMediator is the XML form of the dispatch mediator, e.g.
+// <dispatch key="'handler_' + payload.eventType" default="UnknownEventHandler"/>
+type DispatchMediator struct {
+	XMLName xml.Name `xml:"dispatch"`
+	Key     string   `xml:"key,attr"`
+	Default string   `xml:"default,attr"`
+}
+
+func (dispatchMediator DispatchMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&dispatchMediator, &start); err != nil {
+		return artifacts.DispatchMediator{}, errors.New("error in unmarshalling dispatch mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->dispatch"
+	if dispatchMediator.Key == "" {
+		return artifacts.DispatchMediator{}, fmt.Errorf("dispatch mediator in %s at line %d: missing required attribute 'key'", position.FileName, position.LineNo)
+	}
+	key, err := expression.Compile(dispatchMediator.Key, artifacts.ExpressionVariables...)
+	if err != nil {
+		return artifacts.DispatchMediator{}, fmt.Errorf("dispatch mediator in %s at line %d: %w", position.FileName, position.LineNo, err)
+	}
+	return artifacts.DispatchMediator{Key: key, Default: dispatchMediator.Default, Position: position}, nil
+}
@@ -0,0 +1,119 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/encryption"
+	"github.com/apache/synapse-go/internal/pkg/core/masking"
+)
+
+type EncryptMediator struct {
+	XMLName           xml.Name         `xml:"encrypt"`
+	Format            string           `xml:"format,attr"`
+	Key               string           `xml:"key,attr"`
+	KeyAlgorithm      string           `xml:"keyAlgorithm,attr"`
+	ContentEncryption string           `xml:"contentEncryption,attr"`
+	Fields            []EncryptedField `xml:"field"`
+}
+
+type DecryptMediator struct {
+	XMLName      xml.Name         `xml:"decrypt"`
+	Format       string           `xml:"format,attr"`
+	Key          string           `xml:"key,attr"`
+	KeyAlgorithm string           `xml:"keyAlgorithm,attr"`
+	ContentType  string           `xml:"contentType,attr"`
+	Fields       []EncryptedField `xml:"field"`
+}
+
+type EncryptedField struct {
+	Path string `xml:"path,attr"`
+}
+
+func (encryptMediator EncryptMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&encryptMediator, &start); err != nil {
+		return artifacts.EncryptMediator{}, errors.New("error in unmarshalling encrypt mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->encrypt"
+
+	format, fields, err := parseEncryptionOptions("encrypt", encryptMediator.Format, encryptMediator.Key, encryptMediator.Fields, position)
+	if err != nil {
+		return artifacts.EncryptMediator{}, err
+	}
+	return artifacts.EncryptMediator{
+		Format:            format,
+		KeyAlias:          encryptMediator.Key,
+		KeyAlgorithm:      encryptMediator.KeyAlgorithm,
+		ContentEncryption: encryptMediator.ContentEncryption,
+		Fields:            fields,
+		Position:          position,
+	}, nil
+}
+
+func (decryptMediator DecryptMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&decryptMediator, &start); err != nil {
+		return artifacts.DecryptMediator{}, errors.New("error in unmarshalling decrypt mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->decrypt"
+
+	format, fields, err := parseEncryptionOptions("decrypt", decryptMediator.Format, decryptMediator.Key, decryptMediator.Fields, position)
+	if err != nil {
+		return artifacts.DecryptMediator{}, err
+	}
+	return artifacts.DecryptMediator{
+		Format:       format,
+		KeyAlias:     decryptMediator.Key,
+		KeyAlgorithm: decryptMediator.KeyAlgorithm,
+		ContentType:  decryptMediator.ContentType,
+		Fields:       fields,
+		Position:     position,
+	}, nil
+}
+
+// parseEncryptionOptions validates the attributes shared by the encrypt and
+// decrypt mediators and applies their defaults
+func parseEncryptionOptions(mediator, format, key string, fields []EncryptedField, position artifacts.Position) (string, []string, error) {
+	if format == "" {
+		format = encryption.FormatJWE
+	}
+	if format != encryption.FormatJWE && format != encryption.FormatAESGCM {
+		return "", nil, fmt.Errorf("%s mediator in %s at line %d: format must be 'jwe' or 'aes-gcm', got: %s", mediator, position.FileName, position.LineNo, format)
+	}
+	if key == "" {
+		return "", nil, fmt.Errorf("%s mediator in %s at line %d: missing required attribute 'key'", mediator, position.FileName, position.LineNo)
+	}
+
+	paths := make([]string, 0, len(fields))
+	rules := make([]masking.Rule, 0, len(fields))
+	for _, field := range fields {
+		paths = append(paths, field.Path)
+		rules = append(rules, masking.Rule{Path: field.Path, Action: masking.ActionTransform, Transformer: encryption.FieldEncrypter{}})
+	}
+	// Compile the paths once so invalid expressions fail the deployment
+	if _, err := masking.NewPolicy(rules); err != nil {
+		return "", nil, fmt.Errorf("%s mediator in %s at line %d: %w", mediator, position.FileName, position.LineNo, err)
+	}
+	return format, paths, nil
+}
@@ -0,0 +1,197 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+)
+
+// Endpoint is the XML form of an endpoint artifact. Its only child element is
+// the protocol block, whose attributes are options of that protocol, e.g.
+// <endpoint name="OrdersEP"><http method="POST" uri-template="http://backend/orders"/></endpoint>
+// <endpoint name="TelemetryEP"><kafka topic="telemetry" key="{{.Property "deviceId"}}"/></endpoint>
+// <endpoint name="ArchiveEP"><file uri="file:///var/spool/orders" name="{{.Property "orderId"}}.json"/></endpoint>
+// <endpoint name="OnCallSMS"><smpp url="smpp://smsc:2775" systemId="synapse" to="{{.Property "phone"}}"/></endpoint>
+// A <mirror> element next to the protocol block shadows the endpoint's traffic,
+// <hedge percentile="95"/> races slow idempotent http calls with a second request,
+// and <header name="x-order-id" property="orderId"/> elements copy message
+// properties into transport headers such as Kafka record headers.
+// <statusMap from="404" to="200" payload="[]"/> elements translate the
+// statuses of responses to calls.
+type Endpoint struct{}
+
+// Defaults of hedged endpoints
+const (
+	defaultHedgePercentile = 95
+	defaultHedgeMinDelay   = 10 * time.Millisecond
+)
+
+func (ep *Endpoint) Unmarshal(xmlData string, position artifacts.Position) (artifacts.Endpoint, error) {
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	endpoint := artifacts.Endpoint{FileName: position.FileName, Options: make(map[string]*template.Template)}
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return artifacts.Endpoint{}, fmt.Errorf("error in unmarshalling endpoint in %s: %w", position.FileName, err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				if element.Name.Local != "endpoint" {
+					return artifacts.Endpoint{}, fmt.Errorf("expected an endpoint element in %s, got %s", position.FileName, element.Name.Local)
+				}
+				endpoint.Name = attribute(element, "name")
+				if endpoint.Name == "" {
+					return artifacts.Endpoint{}, fmt.Errorf("endpoint name is required")
+				}
+			case depth == 2 && element.Name.Local == "mirror":
+				if endpoint.Mirror != nil {
+					return artifacts.Endpoint{}, fmt.Errorf("endpoint %s declares more than one mirror", endpoint.Name)
+				}
+				mirror, err := parseMirror(element)
+				if err != nil {
+					return artifacts.Endpoint{}, fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+				}
+				if mirror.Endpoint == endpoint.Name {
+					return artifacts.Endpoint{}, fmt.Errorf("endpoint %s cannot mirror to itself", endpoint.Name)
+				}
+				endpoint.Mirror = mirror
+			case depth == 2 && element.Name.Local == "hedge":
+				if endpoint.Hedge != nil {
+					return artifacts.Endpoint{}, fmt.Errorf("endpoint %s declares more than one hedge", endpoint.Name)
+				}
+				hedge, err := parseHedge(element)
+				if err != nil {
+					return artifacts.Endpoint{}, fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+				}
+				endpoint.Hedge = hedge
+			case depth == 2 && element.Name.Local == "header":
+				mapping := artifacts.HeaderMapping{Header: attribute(element, "name"), Property: attribute(element, "property")}
+				if mapping.Header == "" || mapping.Property == "" {
+					return artifacts.Endpoint{}, fmt.Errorf("endpoint %s: header mappings need a name and a property", endpoint.Name)
+				}
+				endpoint.Headers = append(endpoint.Headers, mapping)
+			case depth == 2 && element.Name.Local == "statusMap":
+				mapping, err := parseStatusMapping(element)
+				if err != nil {
+					return artifacts.Endpoint{}, fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+				}
+				endpoint.StatusMappings = append(endpoint.StatusMappings, mapping)
+			case depth == 2 && endpoint.Protocol != "":
+				return artifacts.Endpoint{}, fmt.Errorf("endpoint %s must declare exactly one protocol block, found %s and %s",
+					endpoint.Name, endpoint.Protocol, element.Name.Local)
+			case depth == 2:
+				endpoint.Protocol = element.Name.Local
+				for _, attr := range element.Attr {
+					if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+						continue
+					}
+					option, err := template.New(endpoint.Name + "." + attr.Name.Local).Funcs(artifacts.PreconditionFuncs).Parse(attr.Value)
+					if err != nil {
+						return artifacts.Endpoint{}, fmt.Errorf("endpoint %s: invalid %s template: %w", endpoint.Name, attr.Name.Local, err)
+					}
+					endpoint.Options[attr.Name.Local] = option
+				}
+				if endpoint.Protocol == artifacts.ProtocolHTTP {
+					endpoint.EndpointUrl = artifacts.EndpointUrl{
+						Method: strings.ToUpper(attribute(element, outbound.HTTPMethodOption)),
+						URL:    attribute(element, outbound.HTTPURIOption),
+					}
+				}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if endpoint.Protocol == "" {
+		return artifacts.Endpoint{}, fmt.Errorf("endpoint %s must declare a protocol block such as <http>", endpoint.Name)
+	}
+	if endpoint.Hedge != nil && endpoint.Protocol != artifacts.ProtocolHTTP {
+		return artifacts.Endpoint{}, fmt.Errorf("endpoint %s: hedging applies to http endpoints only", endpoint.Name)
+	}
+	position.Hierarchy = endpoint.Name
+	endpoint.Position = position
+	if err := outbound.Validate(endpoint); err != nil {
+		return artifacts.Endpoint{}, fmt.Errorf("invalid endpoint %s: %w", endpoint.Name, err)
+	}
+	return endpoint, nil
+}
+
+// parseMirror reads the attributes of a <mirror> element, e.g.
+// <mirror endpoint="OrdersV2EP" percent="10"/>
+func parseMirror(elem xml.StartElement) (*artifacts.Mirror, error) {
+	mirror := &artifacts.Mirror{Endpoint: attribute(elem, "endpoint"), Percent: 100}
+	if mirror.Endpoint == "" {
+		return nil, fmt.Errorf("mirror endpoint is required")
+	}
+	if percent := attribute(elem, "percent"); percent != "" {
+		value, err := strconv.ParseFloat(percent, 64)
+		if err != nil || value <= 0 || value > 100 {
+			return nil, fmt.Errorf("mirror percent must be a number above 0 and at most 100, got: %s", percent)
+		}
+		mirror.Percent = value
+	}
+	return mirror, nil
+}
+
+// parseHedge reads the attributes of a <hedge> element, e.g.
+// <hedge percentile="95" minDelay="20ms"/>
+func parseHedge(elem xml.StartElement) (*artifacts.Hedge, error) {
+	hedge := &artifacts.Hedge{Percentile: defaultHedgePercentile, MinDelay: defaultHedgeMinDelay}
+	if percentile := attribute(elem, "percentile"); percentile != "" {
+		value, err := strconv.ParseFloat(percentile, 64)
+		if err != nil || value <= 0 || value >= 100 {
+			return nil, fmt.Errorf("hedge percentile must be a number above 0 and below 100, got: %s", percentile)
+		}
+		hedge.Percentile = value
+	}
+	if minDelay := attribute(elem, "minDelay"); minDelay != "" {
+		value, err := time.ParseDuration(minDelay)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("hedge minDelay must be a positive duration, got: %s", minDelay)
+		}
+		hedge.MinDelay = value
+	}
+	return hedge, nil
+}
+
+func attribute(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
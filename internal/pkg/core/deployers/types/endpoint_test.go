@@ -0,0 +1,209 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpoint_Unmarshal(t *testing.T) {
+	xmlData := `<endpoint name="OrdersEP" xmlns="http://ws.apache.org/ns/synapse">
+    <http method="put" uri-template="http://backend/orders/{{.Property &quot;orderId&quot;}}" timeout="5s"/>
+</endpoint>`
+	endpoint, err := (&Endpoint{}).Unmarshal(xmlData, artifacts.Position{FileName: "OrdersEP.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, "OrdersEP", endpoint.Name)
+	assert.Equal(t, artifacts.ProtocolHTTP, endpoint.Protocol)
+	assert.Equal(t, artifacts.EndpointUrl{Method: "PUT", URL: `http://backend/orders/{{.Property "orderId"}}`}, endpoint.EndpointUrl)
+	assert.Equal(t, "OrdersEP", endpoint.Position.Hierarchy)
+
+	msg := synctx.CreateMsgContext()
+	msg.Properties["orderId"] = 42
+	uri, err := endpoint.Option("uri-template", msg)
+	require.NoError(t, err)
+	assert.Equal(t, "http://backend/orders/42", uri)
+	assert.Nil(t, endpoint.Mirror)
+}
+
+func TestEndpoint_UnmarshalMirror(t *testing.T) {
+	xmlData := `<endpoint name="OrdersEP">
+    <http method="POST" uri-template="http://backend/orders"/>
+    <mirror endpoint="OrdersV2EP" percent="12.5"/>
+</endpoint>`
+	endpoint, err := (&Endpoint{}).Unmarshal(xmlData, artifacts.Position{FileName: "OrdersEP.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, artifacts.ProtocolHTTP, endpoint.Protocol)
+	assert.Equal(t, &artifacts.Mirror{Endpoint: "OrdersV2EP", Percent: 12.5}, endpoint.Mirror)
+
+	endpoint, err = (&Endpoint{}).Unmarshal(`<endpoint name="OrdersEP"><mirror endpoint="OrdersV2EP"/><http uri-template="http://a"/></endpoint>`,
+		artifacts.Position{FileName: "OrdersEP.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, &artifacts.Mirror{Endpoint: "OrdersV2EP", Percent: 100}, endpoint.Mirror)
+}
+
+func TestEndpoint_UnmarshalHedge(t *testing.T) {
+	endpoint, err := (&Endpoint{}).Unmarshal(`<endpoint name="CatalogEP"><http method="GET" uri-template="http://a"/><hedge percentile="99" minDelay="50ms"/></endpoint>`,
+		artifacts.Position{FileName: "CatalogEP.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, &artifacts.Hedge{Percentile: 99, MinDelay: 50 * time.Millisecond}, endpoint.Hedge)
+
+	endpoint, err = (&Endpoint{}).Unmarshal(`<endpoint name="CatalogEP"><http uri-template="http://a"/><hedge/></endpoint>`,
+		artifacts.Position{FileName: "CatalogEP.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, &artifacts.Hedge{Percentile: 95, MinDelay: 10 * time.Millisecond}, endpoint.Hedge)
+}
+
+func TestEndpoint_UnmarshalHeaders(t *testing.T) {
+	xmlData := `<endpoint name="OrdersEP">
+    <http method="POST" uri-template="http://backend/orders"/>
+    <header name="X-Order-Id" property="orderId"/>
+    <header name="X-Priority" property="priority"/>
+</endpoint>`
+	endpoint, err := (&Endpoint{}).Unmarshal(xmlData, artifacts.Position{FileName: "OrdersEP.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, []artifacts.HeaderMapping{
+		{Header: "X-Order-Id", Property: "orderId"},
+		{Header: "X-Priority", Property: "priority"},
+	}, endpoint.Headers)
+
+	msg := synctx.CreateMsgContext()
+	msg.Properties["orderId"] = 42
+	endpoint.MapHeaders(msg)
+	assert.Equal(t, map[string]string{"X-Order-Id": "42"}, msg.Headers)
+}
+
+func TestEndpoint_UnmarshalStatusMappings(t *testing.T) {
+	xmlData := `<endpoint name="OrdersEP">
+    <http method="GET" uri-template="http://backend/orders"/>
+    <statusMap from="404" to="200" payload="[]" contentType="application/json"/>
+    <statusMap from="502-504, 4xx" to="503"/>
+</endpoint>`
+	endpoint, err := (&Endpoint{}).Unmarshal(xmlData, artifacts.Position{FileName: "OrdersEP.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, artifacts.StatusMappings{
+		{From: []artifacts.StatusRange{{Min: 404, Max: 404}}, To: 200, Payload: []byte("[]"), ContentType: "application/json"},
+		{From: []artifacts.StatusRange{{Min: 502, Max: 504}, {Min: 400, Max: 499}}, To: 503},
+	}, endpoint.StatusMappings)
+
+	tests := []struct {
+		name      string
+		statusMap string
+		wantErr   string
+	}{
+		{name: "no to", statusMap: `<statusMap from="404"/>`, wantErr: "statusMap requires from and to"},
+		{name: "bad range", statusMap: `<statusMap from="504-502" to="503"/>`, wantErr: "statusMap from: expected a status, a range such as 502-504 or a class such as 5xx, got: '504-502'"},
+		{name: "bad class", statusMap: `<statusMap from="9xx" to="503"/>`, wantErr: "statusMap from: expected a status, a range such as 502-504 or a class such as 5xx, got: '9xx'"},
+		{name: "bad to", statusMap: `<statusMap from="404" to="OK"/>`, wantErr: "statusMap to must be a status between 100 and 599, got: OK"},
+		{name: "content type without payload", statusMap: `<statusMap from="404" to="200" contentType="application/json"/>`,
+			wantErr: "statusMap contentType applies to a payload only"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xmlData := `<endpoint name="EP"><http uri-template="http://backend"/>` + tt.statusMap + `</endpoint>`
+			_, err := (&Endpoint{}).Unmarshal(xmlData, artifacts.Position{FileName: "EP.xml"})
+			assert.ErrorContains(t, err, "endpoint EP: "+tt.wantErr)
+		})
+	}
+}
+
+func TestEndpoint_UnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		xmlData string
+		wantErr string
+	}{
+		{name: "missing name", xmlData: `<endpoint><http uri-template="http://backend"/></endpoint>`, wantErr: "endpoint name is required"},
+		{name: "no protocol block", xmlData: `<endpoint name="EP"/>`, wantErr: "endpoint EP must declare a protocol block such as <http>"},
+		{
+			name:    "two protocol blocks",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a"/><http uri-template="http://b"/></endpoint>`,
+			wantErr: "endpoint EP must declare exactly one protocol block, found http and http",
+		},
+		{name: "missing uri", xmlData: `<endpoint name="EP"><http method="GET"/></endpoint>`, wantErr: "invalid endpoint EP: http endpoint EP requires a uri-template"},
+		{
+			name:    "unknown option",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a" topic="orders"/></endpoint>`,
+			wantErr: "invalid endpoint EP: unknown http option 'topic', expected one of method, uri-template, timeout",
+		},
+		{
+			name:    "invalid timeout",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a" timeout="soon"/></endpoint>`,
+			wantErr: "invalid endpoint EP: http endpoint EP: timeout must be a positive duration, got 'soon'",
+		},
+		{
+			name:    "protocol without sender",
+			xmlData: `<endpoint name="EP"><kafka topic="orders"/></endpoint>`,
+			wantErr: "invalid endpoint EP: no outbound sender for protocol kafka, supported protocols are apns, fcm, file, http, smpp",
+		},
+		{
+			name:    "mirror without endpoint",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a"/><mirror percent="10"/></endpoint>`,
+			wantErr: "endpoint EP: mirror endpoint is required",
+		},
+		{
+			name:    "mirror percent out of range",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a"/><mirror endpoint="EP2" percent="0"/></endpoint>`,
+			wantErr: "endpoint EP: mirror percent must be a number above 0 and at most 100, got: 0",
+		},
+		{
+			name:    "mirror to itself",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a"/><mirror endpoint="EP"/></endpoint>`,
+			wantErr: "endpoint EP cannot mirror to itself",
+		},
+		{
+			name:    "hedge percentile out of range",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a"/><hedge percentile="100"/></endpoint>`,
+			wantErr: "endpoint EP: hedge percentile must be a number above 0 and below 100, got: 100",
+		},
+		{
+			name:    "hedge invalid delay",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a"/><hedge minDelay="fast"/></endpoint>`,
+			wantErr: "endpoint EP: hedge minDelay must be a positive duration, got: fast",
+		},
+		{
+			name:    "hedge on a file endpoint",
+			xmlData: `<endpoint name="EP"><file uri="file:///tmp/out"/><hedge/></endpoint>`,
+			wantErr: "endpoint EP: hedging applies to http endpoints only",
+		},
+		{
+			name:    "header without property",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a"/><header name="X-Order-Id"/></endpoint>`,
+			wantErr: "endpoint EP: header mappings need a name and a property",
+		},
+		{
+			name:    "invalid template",
+			xmlData: `<endpoint name="EP"><http uri-template="http://a/{{.Property"/></endpoint>`,
+			wantErr: "endpoint EP: invalid uri-template template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Endpoint{}).Unmarshal(tt.xmlData, artifacts.Position{FileName: "EP.xml"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// EnrichMediator is the XML form of the enrich mediator, e.g.
+//
+//	<enrich>
+//	    <source type="property" property="customer"/>
+//	    <target type="key" key="order.customer" action="merge"/>
+//	</enrich>
+//	<enrich>
+//	    <source type="inline"><status>accepted</status></source>
+//	    <target type="child" xpath="order"/>
+//	</enrich>
+type EnrichMediator struct {
+	XMLName xml.Name      `xml:"enrich"`
+	Source  *enrichSource `xml:"source"`
+	Target  *enrichTarget `xml:"target"`
+}
+
+type enrichSource struct {
+	Type       string `xml:"type,attr"`
+	Property   string `xml:"property,attr"`
+	Expression string `xml:"expression,attr"`
+	Inline     string `xml:",innerxml"`
+	Text       string `xml:",chardata"`
+}
+
+type enrichTarget struct {
+	Type     string `xml:"type,attr"`
+	Property string `xml:"property,attr"`
+	Key      string `xml:"key,attr"`
+	XPath    string `xml:"xpath,attr"`
+	Action   string `xml:"action,attr"`
+}
+
+func (enrichMediator EnrichMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&enrichMediator, &start); err != nil {
+		return artifacts.EnrichMediator{}, errors.New("error in unmarshalling enrich mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->enrich"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("enrich mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if enrichMediator.Source == nil || enrichMediator.Target == nil {
+		return artifacts.EnrichMediator{}, invalid("requires a source and a target")
+	}
+	mediator := artifacts.EnrichMediator{Position: position}
+
+	source := enrichMediator.Source
+	mediator.Source.Type = source.Type
+	switch source.Type {
+	case artifacts.EnrichSourceBody:
+	case artifacts.EnrichSourceProperty:
+		if source.Property == "" {
+			return artifacts.EnrichMediator{}, invalid("property source is missing required attribute 'property'")
+		}
+		mediator.Source.Property = source.Property
+	case artifacts.EnrichSourceExpression:
+		if source.Expression == "" {
+			return artifacts.EnrichMediator{}, invalid("expression source is missing required attribute 'expression'")
+		}
+		program, err := expression.Compile(source.Expression, artifacts.ExpressionVariables...)
+		if err != nil {
+			return artifacts.EnrichMediator{}, invalid("%v", err)
+		}
+		mediator.Source.Expression = program
+	case artifacts.EnrichSourceInline:
+		// Content without elements, e.g. JSON in a CDATA section, is text
+		inline := strings.TrimSpace(source.Inline)
+		if !strings.HasPrefix(inline, "<") || strings.HasPrefix(inline, "<![CDATA[") {
+			inline = strings.TrimSpace(source.Text)
+		}
+		if inline == "" {
+			return artifacts.EnrichMediator{}, invalid("inline source has no content")
+		}
+		if _, err := artifacts.ParseFragment([]byte(inline), ""); err != nil {
+			return artifacts.EnrichMediator{}, invalid("inline source: %v", err)
+		}
+		mediator.Source.Inline = inline
+	default:
+		return artifacts.EnrichMediator{}, invalid("source type must be body, property, expression or inline, got: %s", source.Type)
+	}
+
+	target := enrichMediator.Target
+	mediator.Target.Type = target.Type
+	mediator.Target.Action = target.Action
+	if mediator.Target.Action == "" {
+		mediator.Target.Action = artifacts.EnrichActionReplace
+	}
+	if mediator.Target.Action != artifacts.EnrichActionReplace && mediator.Target.Action != artifacts.EnrichActionMerge {
+		return artifacts.EnrichMediator{}, invalid("action must be replace or merge, got: %s", mediator.Target.Action)
+	}
+	switch target.Type {
+	case artifacts.EnrichTargetBody, artifacts.EnrichTargetProperty:
+		if target.Type == artifacts.EnrichTargetProperty {
+			if target.Property == "" {
+				return artifacts.EnrichMediator{}, invalid("property target is missing required attribute 'property'")
+			}
+			mediator.Target.Property = target.Property
+		}
+		if mediator.Target.Action == artifacts.EnrichActionMerge {
+			return artifacts.EnrichMediator{}, invalid("action merge applies to key and child targets only")
+		}
+	case artifacts.EnrichTargetKey:
+		path, err := enrichPath(target.Key, ".")
+		if err != nil {
+			return artifacts.EnrichMediator{}, invalid("invalid key '%s'", target.Key)
+		}
+		mediator.Target.Path = path
+	case artifacts.EnrichTargetChild:
+		path, err := enrichPath(target.XPath, "/")
+		if err != nil {
+			return artifacts.EnrichMediator{}, invalid("invalid xpath '%s'", target.XPath)
+		}
+		mediator.Target.Path = path
+	default:
+		return artifacts.EnrichMediator{}, invalid("target type must be body, property, key or child, got: %s", target.Type)
+	}
+	return mediator, nil
+}
+
+// enrichPath splits the path of a key or child target; the empty path is the
+// root of the payload
+func enrichPath(path, separator string) ([]string, error) {
+	path = strings.TrimPrefix(path, separator)
+	if path == "" {
+		return nil, nil
+	}
+	segments := strings.Split(path, separator)
+	if slices.Contains(segments, "") {
+		return nil, errors.New("empty path segment")
+	}
+	return segments, nil
+}
@@ -0,0 +1,72 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// EvalMediator is the XML form of the eval mediator, e.g.
+// <eval expression="len(payload.items) > 0 &amp;&amp; headers['x-tenant'] != nil" status="422" message="an order needs items"/>
+// <eval expression="payload.total * 1.2" property="TOTAL_WITH_TAX"/>
+type EvalMediator struct {
+	XMLName    xml.Name `xml:"eval"`
+	Expression string   `xml:"expression,attr"`
+	Property   string   `xml:"property,attr"`
+	Status     string   `xml:"status,attr"`
+	Message    string   `xml:"message,attr"`
+}
+
+func (evalMediator EvalMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&evalMediator, &start); err != nil {
+		return artifacts.EvalMediator{}, errors.New("error in unmarshalling eval mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->eval"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("eval mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if evalMediator.Expression == "" {
+		return artifacts.EvalMediator{}, invalid("missing required attribute 'expression'")
+	}
+	program, err := expression.Compile(evalMediator.Expression, artifacts.ExpressionVariables...)
+	if err != nil {
+		return artifacts.EvalMediator{}, invalid("%v", err)
+	}
+	mediator := artifacts.EvalMediator{Expression: program, Property: evalMediator.Property, Message: evalMediator.Message,
+		Status: http.StatusBadRequest, Position: position}
+	if evalMediator.Property != "" && (evalMediator.Status != "" || evalMediator.Message != "") {
+		return artifacts.EvalMediator{}, invalid("status and message apply to guards, not to expressions stored in a property")
+	}
+	if evalMediator.Status != "" {
+		status, err := strconv.Atoi(evalMediator.Status)
+		if err != nil || status < 400 || status > 599 {
+			return artifacts.EvalMediator{}, invalid("status must be an HTTP error status between 400 and 599, got: %s", evalMediator.Status)
+		}
+		mediator.Status = status
+	}
+	return mediator, nil
+}
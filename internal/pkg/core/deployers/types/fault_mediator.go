@@ -0,0 +1,119 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// FaultMediator is the XML form of the fault mediator, e.g.
+//
+//	<makefault version="json" status="422">
+//	    <code value="ORDER_INVALID"/>
+//	    <reason value="The order has no items"/>
+//	    <detail expression="payload.id"/>
+//	</makefault>
+//
+// SOAP faults take one of the fault codes of their version, e.g. Client.
+type FaultMediator struct {
+	XMLName xml.Name   `xml:"makefault"`
+	Version string     `xml:"version,attr"`
+	Status  string     `xml:"status,attr"`
+	Code    *faultText `xml:"code"`
+	Reason  *faultText `xml:"reason"`
+	Detail  *faultText `xml:"detail"`
+}
+
+type faultText struct {
+	Value      *string `xml:"value,attr"`
+	Expression string  `xml:"expression,attr"`
+}
+
+func (faultMediator FaultMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&faultMediator, &start); err != nil {
+		return artifacts.FaultMediator{}, errors.New("error in unmarshalling makefault mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->makefault"
+	m := faultMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("makefault mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	mediator := artifacts.FaultMediator{Version: m.Version, Status: http.StatusInternalServerError, Position: position}
+	switch m.Version {
+	case artifacts.FaultVersionSOAP11, artifacts.FaultVersionSOAP12, artifacts.FaultVersionJSON:
+	case "":
+		return artifacts.FaultMediator{}, invalid("missing required attribute 'version'")
+	default:
+		return artifacts.FaultMediator{}, invalid("version must be soap11, soap12 or json, got: %s", m.Version)
+	}
+	if m.Status != "" {
+		status, err := strconv.Atoi(m.Status)
+		if err != nil || status < 400 || status > 599 {
+			return artifacts.FaultMediator{}, invalid("status must be an HTTP error status, got: %s", m.Status)
+		}
+		mediator.Status = status
+	}
+
+	if m.Code == nil || m.Code.Value == nil || *m.Code.Value == "" {
+		return artifacts.FaultMediator{}, invalid("missing required element 'code' with a value")
+	}
+	mediator.Code = *m.Code.Value
+	if codes, soap := artifacts.FaultCodes[m.Version]; soap && !slices.Contains(codes, mediator.Code) {
+		return artifacts.FaultMediator{}, invalid("code of a %s fault must be one of %s, got: %s", m.Version, strings.Join(codes, ", "), mediator.Code)
+	}
+
+	if m.Reason == nil {
+		return artifacts.FaultMediator{}, invalid("missing required element 'reason'")
+	}
+	var err error
+	if mediator.Reason, err = m.Reason.compile(); err != nil {
+		return artifacts.FaultMediator{}, invalid("reason: %v", err)
+	}
+	if m.Detail != nil {
+		if mediator.Detail, err = m.Detail.compile(); err != nil {
+			return artifacts.FaultMediator{}, invalid("detail: %v", err)
+		}
+	}
+	return mediator, nil
+}
+
+func (t faultText) compile() (artifacts.FaultText, error) {
+	if (t.Value == nil) == (t.Expression == "") {
+		return artifacts.FaultText{}, errors.New("exactly one of 'value' and 'expression' is required")
+	}
+	if t.Value != nil {
+		return artifacts.FaultText{Value: *t.Value}, nil
+	}
+	program, err := expression.Compile(t.Expression, artifacts.ExpressionVariables...)
+	if err != nil {
+		return artifacts.FaultText{}, err
+	}
+	return artifacts.FaultText{Expression: program}, nil
+}
@@ -0,0 +1,101 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// HeaderMediator is the XML form of the header mediator, e.g.
+// <header name="X-Order-Id" expression="params.id"/>
+// <header name="Cache-Control" value="no-store"/>
+// <header name="Server" action="remove"/>
+type HeaderMediator struct {
+	XMLName    xml.Name `xml:"header"`
+	Name       string   `xml:"name,attr"`
+	Value      *string  `xml:"value,attr"`
+	Expression string   `xml:"expression,attr"`
+	Scope      string   `xml:"scope,attr"`
+	Action     string   `xml:"action,attr"`
+}
+
+func (headerMediator HeaderMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&headerMediator, &start); err != nil {
+		return artifacts.HeaderMediator{}, errors.New("error in unmarshalling header mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->header"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("header mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if headerMediator.Name == "" {
+		return artifacts.HeaderMediator{}, invalid("missing required attribute 'name'")
+	}
+	if strings.ContainsAny(headerMediator.Name, " \t\r\n:") {
+		return artifacts.HeaderMediator{}, invalid("invalid header name '%s'", headerMediator.Name)
+	}
+	mediator := artifacts.HeaderMediator{Name: headerMediator.Name, Action: headerMediator.Action,
+		Scope: headerMediator.Scope, Position: position}
+	if mediator.Action == "" {
+		mediator.Action = artifacts.PropertyActionSet
+	}
+	if mediator.Scope == "" {
+		mediator.Scope = artifacts.HeaderScopeTransport
+	}
+	switch mediator.Scope {
+	case artifacts.HeaderScopeTransport:
+	case artifacts.HeaderScopeSOAP:
+		return artifacts.HeaderMediator{}, invalid("scope soap is not supported yet")
+	default:
+		return artifacts.HeaderMediator{}, invalid("scope must be transport or soap, got: %s", mediator.Scope)
+	}
+
+	switch mediator.Action {
+	case artifacts.PropertyActionRemove:
+		if headerMediator.Value != nil || headerMediator.Expression != "" {
+			return artifacts.HeaderMediator{}, invalid("value and expression do not apply to action 'remove'")
+		}
+	case artifacts.PropertyActionSet:
+		if (headerMediator.Value == nil) == (headerMediator.Expression == "") {
+			return artifacts.HeaderMediator{}, invalid("exactly one of 'value' and 'expression' is required")
+		}
+		if headerMediator.Expression != "" {
+			program, err := expression.Compile(headerMediator.Expression, artifacts.ExpressionVariables...)
+			if err != nil {
+				return artifacts.HeaderMediator{}, invalid("%v", err)
+			}
+			mediator.Expression = program
+			break
+		}
+		if strings.ContainsAny(*headerMediator.Value, "\r\n") {
+			return artifacts.HeaderMediator{}, invalid("header values cannot contain line breaks")
+		}
+		mediator.Value = *headerMediator.Value
+	default:
+		return artifacts.HeaderMediator{}, invalid("action must be set or remove, got: %s", mediator.Action)
+	}
+	return mediator, nil
+}
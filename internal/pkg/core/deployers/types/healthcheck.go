@@ -0,0 +1,108 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// HealthCheck is the XML form of a health probe, e.g.
+// <healthCheck name="OrdersBackend" endpoint="OrdersEP" interval="30s" timeout="5s" failureThreshold="3" critical="true"/>
+type HealthCheck struct {
+	Name             string `xml:"name,attr"`
+	Endpoint         string `xml:"endpoint,attr"`
+	URI              string `xml:"uri,attr"`
+	Method           string `xml:"method,attr"`
+	Sequence         string `xml:"sequence,attr"`
+	ExpectedStatus   string `xml:"expectedStatus,attr"`
+	Interval         string `xml:"interval,attr"`
+	Timeout          string `xml:"timeout,attr"`
+	FailureThreshold string `xml:"failureThreshold,attr"`
+	Critical         string `xml:"critical,attr"`
+}
+
+func (hc *HealthCheck) Unmarshal(xmlData string, position artifacts.Position) (artifacts.HealthCheck, error) {
+	if err := xml.Unmarshal([]byte(xmlData), hc); err != nil {
+		return artifacts.HealthCheck{}, fmt.Errorf("error in unmarshalling health check in %s: %w", position.FileName, err)
+	}
+	if hc.Name == "" {
+		return artifacts.HealthCheck{}, fmt.Errorf("health check name is required")
+	}
+	position.Hierarchy = hc.Name
+	check := artifacts.HealthCheck{
+		Name:             hc.Name,
+		Endpoint:         hc.Endpoint,
+		URL:              hc.URI,
+		Method:           hc.Method,
+		Sequence:         hc.Sequence,
+		Interval:         30 * time.Second,
+		Timeout:          5 * time.Second,
+		FailureThreshold: 1,
+		Critical:         true,
+		Position:         position,
+	}
+
+	targets := 0
+	for _, target := range []string{hc.Endpoint, hc.URI, hc.Sequence} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return artifacts.HealthCheck{}, fmt.Errorf("health check %s must set exactly one of endpoint, uri and sequence", hc.Name)
+	}
+	if check.Method == "" {
+		check.Method = http.MethodGet
+	}
+
+	var err error
+	if hc.Interval != "" {
+		if check.Interval, err = time.ParseDuration(hc.Interval); err != nil || check.Interval <= 0 {
+			return artifacts.HealthCheck{}, fmt.Errorf("invalid health check interval '%s'", hc.Interval)
+		}
+	}
+	if hc.Timeout != "" {
+		if check.Timeout, err = time.ParseDuration(hc.Timeout); err != nil || check.Timeout <= 0 {
+			return artifacts.HealthCheck{}, fmt.Errorf("invalid health check timeout '%s'", hc.Timeout)
+		}
+	}
+	if hc.ExpectedStatus != "" {
+		if check.ExpectedStatus, err = strconv.Atoi(hc.ExpectedStatus); err != nil || check.ExpectedStatus < 100 || check.ExpectedStatus > 599 {
+			return artifacts.HealthCheck{}, fmt.Errorf("invalid health check expectedStatus '%s'", hc.ExpectedStatus)
+		}
+	}
+	if hc.FailureThreshold != "" {
+		if check.FailureThreshold, err = strconv.Atoi(hc.FailureThreshold); err != nil || check.FailureThreshold < 1 {
+			return artifacts.HealthCheck{}, fmt.Errorf("invalid health check failureThreshold '%s'", hc.FailureThreshold)
+		}
+	}
+	if hc.Critical != "" {
+		if check.Critical, err = strconv.ParseBool(hc.Critical); err != nil {
+			return artifacts.HealthCheck{}, fmt.Errorf("critical must be either 'true' or 'false', got: %s", hc.Critical)
+		}
+	}
+	return check, nil
+}
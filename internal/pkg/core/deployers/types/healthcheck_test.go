@@ -0,0 +1,68 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheck_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		xmlData string
+		want    artifacts.HealthCheck
+		wantErr string
+	}{
+		{
+			name:    "endpoint with defaults",
+			xmlData: `<healthCheck name="OrdersBackend" endpoint="OrdersEP"/>`,
+			want: artifacts.HealthCheck{Name: "OrdersBackend", Endpoint: "OrdersEP", Method: "GET", Interval: 30 * time.Second,
+				Timeout: 5 * time.Second, FailureThreshold: 1, Critical: true},
+		},
+		{
+			name:    "url",
+			xmlData: `<healthCheck name="Billing" uri="http://billing/health" method="HEAD" expectedStatus="204" interval="10s" timeout="2s" failureThreshold="3" critical="false"/>`,
+			want: artifacts.HealthCheck{Name: "Billing", URL: "http://billing/health", Method: "HEAD", ExpectedStatus: 204,
+				Interval: 10 * time.Second, Timeout: 2 * time.Second, FailureThreshold: 3},
+		},
+		{name: "missing name", xmlData: `<healthCheck sequence="Ping"/>`, wantErr: "health check name is required"},
+		{name: "no target", xmlData: `<healthCheck name="Empty"/>`, wantErr: "health check Empty must set exactly one of endpoint, uri and sequence"},
+		{name: "two targets", xmlData: `<healthCheck name="Both" sequence="Ping" endpoint="EP"/>`, wantErr: "health check Both must set exactly one of endpoint, uri and sequence"},
+		{name: "invalid interval", xmlData: `<healthCheck name="Ping" sequence="Ping" interval="often"/>`, wantErr: "invalid health check interval 'often'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthCheck := &HealthCheck{}
+			result, err := healthCheck.Unmarshal(tt.xmlData, artifacts.Position{FileName: "health.xml"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			tt.want.Position = artifacts.Position{FileName: "health.xml", Hierarchy: tt.want.Name}
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// IterateMediator is the XML form of the iterate and foreach mediators, e.g.
+//
+//	<iterate expression="$.order.items" parallel="true" sequence="ProcessItem"/>
+//	<foreach expression="//order/item">
+//	    <sequence>
+//	        <property name="status" value="checked"/>
+//	    </sequence>
+//	</foreach>
+//
+// foreach runs the items one after another and aggregates the results into
+// the payload; iterate does neither unless asked with parallel and aggregate.
+type IterateMediator struct {
+	foreach bool
+}
+
+func (iterateMediator IterateMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	name := start.Name.Local
+	position.Hierarchy = position.Hierarchy + "->" + name
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%s mediator in %s at line %d: %s", name, position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	mediator := artifacts.IterateMediator{Aggregate: iterateMediator.foreach, Position: position}
+	var expression string
+	for _, attr := range start.Attr {
+		var err error
+		switch attr.Name.Local {
+		case "expression":
+			expression = attr.Value
+		case "sequence":
+			mediator.SequenceName = attr.Value
+		case "parallel":
+			if iterateMediator.foreach {
+				return artifacts.IterateMediator{}, invalid("foreach runs items in order; use iterate for parallel")
+			}
+			mediator.Parallel, err = strconv.ParseBool(attr.Value)
+		case "aggregate":
+			mediator.Aggregate, err = strconv.ParseBool(attr.Value)
+		}
+		if err != nil {
+			return artifacts.IterateMediator{}, invalid("%s must be true or false, got: %s", attr.Name.Local, attr.Value)
+		}
+	}
+	if expression == "" {
+		return artifacts.IterateMediator{}, invalid("missing required attribute 'expression'")
+	}
+	path, err := artifacts.CompileSplitPath(expression)
+	if err != nil {
+		return artifacts.IterateMediator{}, invalid("%v", err)
+	}
+	mediator.Path = path
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return artifacts.IterateMediator{}, invalid("%v", err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if element.Name.Local != "sequence" {
+				return artifacts.IterateMediator{}, invalid("unexpected element '%s', expected sequence", element.Name.Local)
+			}
+			if mediator.Sequence != nil {
+				return artifacts.IterateMediator{}, invalid("only one sequence is allowed")
+			}
+			sequence, err := unmarshalBranch(d, position, "sequence")
+			if err != nil {
+				return artifacts.IterateMediator{}, err
+			}
+			mediator.Sequence = &sequence
+		case xml.EndElement:
+			if (mediator.Sequence == nil) == (mediator.SequenceName == "") {
+				return artifacts.IterateMediator{}, invalid("exactly one of the 'sequence' attribute and a sequence element is required")
+			}
+			return mediator, nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/jq"
+)
+
+// JSONTransformMediator is the XML form of the jsontransform mediator, whose
+// text is a jq program, e.g.
+//
+//	<jsontransform><![CDATA[
+//	    {id: .order.id, tenant: $params.tenant,
+//	     lines: [.order.items[] | select(.qty > 0) | {sku, qty}]}
+//	]]></jsontransform>
+type JSONTransformMediator struct {
+	XMLName xml.Name `xml:"jsontransform"`
+	Program string   `xml:",chardata"`
+}
+
+func (jsonTransformMediator JSONTransformMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&jsonTransformMediator, &start); err != nil {
+		return artifacts.JSONTransformMediator{}, errors.New("error in unmarshalling jsontransform mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->jsontransform"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("jsontransform mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	source := strings.TrimSpace(jsonTransformMediator.Program)
+	if source == "" {
+		return artifacts.JSONTransformMediator{}, invalid("missing jq program")
+	}
+	program, err := jq.Compile(source, artifacts.JSONTransformVariables...)
+	if err != nil {
+		return artifacts.JSONTransformMediator{}, invalid("%v", err)
+	}
+	return artifacts.JSONTransformMediator{Program: program, Position: position}, nil
+}
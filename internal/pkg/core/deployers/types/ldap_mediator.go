@@ -0,0 +1,85 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// defaultLDAPUser looks up the caller authenticated by the API's security
+// policy
+const defaultLDAPUser = "properties['AUTHENTICATED_USER']"
+
+// ldapAttributeName matches an attribute description such as mail or
+// msDS-UserAccountDisabled
+var ldapAttributeName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+// LDAPMediator is the XML form of the ldap mediator, such as
+// <ldap user="headers['X-User']" attributes="mail,memberOf"/>
+type LDAPMediator struct {
+	XMLName    xml.Name `xml:"ldap"`
+	User       string   `xml:"user,attr"`
+	Attributes string   `xml:"attributes,attr"`
+	Prefix     string   `xml:"prefix,attr"`
+}
+
+func (ldapMediator LDAPMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&ldapMediator, &start); err != nil {
+		return artifacts.LDAPMediator{}, errors.New("error in unmarshalling ldap mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->ldap"
+	m := ldapMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("ldap mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	if m.User == "" {
+		m.User = defaultLDAPUser
+	}
+	user, err := expression.Compile(m.User, artifacts.ExpressionVariables...)
+	if err != nil {
+		return artifacts.LDAPMediator{}, invalid("invalid user expression: %v", err)
+	}
+	var attributes []string
+	for _, name := range strings.Split(m.Attributes, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !ldapAttributeName.MatchString(name) {
+			return artifacts.LDAPMediator{}, invalid("invalid attribute name: %s", name)
+		}
+		attributes = append(attributes, name)
+	}
+	if len(attributes) == 0 {
+		return artifacts.LDAPMediator{}, invalid("attributes must list at least one attribute")
+	}
+	if m.Prefix == "" {
+		m.Prefix = artifacts.DefaultLDAPPrefix
+	}
+	return artifacts.LDAPMediator{User: user, Attributes: attributes, Prefix: m.Prefix, Position: position}, nil
+}
@@ -0,0 +1,64 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/masking"
+)
+
+type MaskMediator struct {
+	XMLName xml.Name    `xml:"mask"`
+	Fields  []MaskField `xml:"field"`
+}
+
+type MaskField struct {
+	Path        string `xml:"path,attr"`
+	Action      string `xml:"action,attr"`
+	Replacement string `xml:"replacement,attr"`
+}
+
+func (maskMediator MaskMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&maskMediator, &start); err != nil {
+		return artifacts.MaskMediator{}, errors.New("error in unmarshalling mask mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->mask"
+	if len(maskMediator.Fields) == 0 {
+		return artifacts.MaskMediator{}, fmt.Errorf("mask mediator in %s at line %d must declare at least one field", position.FileName, position.LineNo)
+	}
+
+	rules := make([]masking.Rule, 0, len(maskMediator.Fields))
+	for _, field := range maskMediator.Fields {
+		rules = append(rules, masking.Rule{Path: field.Path, Action: field.Action, Replacement: field.Replacement})
+	}
+	policy, err := masking.NewPolicy(rules)
+	if err != nil {
+		return artifacts.MaskMediator{}, fmt.Errorf("mask mediator in %s at line %d: %w", position.FileName, position.LineNo, err)
+	}
+	return artifacts.MaskMediator{
+		Policy:   policy,
+		Position: position,
+	}, nil
+}
@@ -28,3 +28,64 @@ import (
 type Mediator interface {
 	Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error)
 }
+
+// mediatorFactories maps the element name of each supported mediator to its
+// decoder
+var mediatorFactories = map[string]func() Mediator{
+	"log":             func() Mediator { return LogMediator{} },
+	"authorize":       func() Mediator { return AuthorizeMediator{} },
+	"mask":            func() Mediator { return MaskMediator{} },
+	"dedupe":          func() Mediator { return DedupeMediator{} },
+	"call":            func() Mediator { return CallMediator{} },
+	"dispatch":        func() Mediator { return DispatchMediator{} },
+	"eval":            func() Mediator { return EvalMediator{} },
+	"assert":          func() Mediator { return AssertMediator{} },
+	"property":        func() Mediator { return PropertyMediator{} },
+	"payloadFactory":  func() Mediator { return PayloadFactoryMediator{} },
+	"saga":            func() Mediator { return SagaMediator{} },
+	"scatterGather":   func() Mediator { return ScatterGatherMediator{} },
+	"switch":          func() Mediator { return SwitchMediator{} },
+	"sign":            func() Mediator { return SignMediator{} },
+	"verify":          func() Mediator { return VerifyMediator{} },
+	"encrypt":         func() Mediator { return EncryptMediator{} },
+	"decrypt":         func() Mediator { return DecryptMediator{} },
+	"wsSecurity":      func() Mediator { return WSSecurityMediator{} },
+	"tokenExchange":   func() Mediator { return TokenExchangeMediator{} },
+	"validateRequest": func() Mediator { return ValidateRequestMediator{} },
+	"respond":         func() Mediator { return RespondMediator{} },
+	"loopback":        func() Mediator { return LoopbackMediator{} },
+	"header":          func() Mediator { return HeaderMediator{} },
+	"enrich":          func() Mediator { return EnrichMediator{} },
+	"storePayload":    func() Mediator { return StorePayloadMediator{} },
+	"restorePayload":  func() Mediator { return RestorePayloadMediator{} },
+	"drop":            func() Mediator { return DropMediator{} },
+	"makefault":       func() Mediator { return FaultMediator{} },
+	"iterate":         func() Mediator { return IterateMediator{} },
+	"foreach":         func() Mediator { return IterateMediator{foreach: true} },
+	"clone":           func() Mediator { return CloneMediator{} },
+	"aggregate":       func() Mediator { return AggregateMediator{} },
+	"cache":           func() Mediator { return CacheMediator{} },
+	"ldap":            func() Mediator { return LDAPMediator{} },
+	"dblookup":        func() Mediator { return DBLookupMediator{} },
+	"dbreport":        func() Mediator { return DBReportMediator{} },
+	"store":           func() Mediator { return StoreMediator{} },
+	"sequence":        func() Mediator { return SequenceMediator{} },
+	"xslt":            func() Mediator { return XSLTMediator{} },
+	"jsontransform":   func() Mediator { return JSONTransformMediator{} },
+}
+
+// unmarshalMediator decodes the mediator starting at start. ok is false when the
+// element is not a known mediator.
+func unmarshalMediator(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (mediator artifacts.Mediator, ok bool, err error) {
+	// A <sequence> without a key wraps mediators instead of referring to a
+	// deployed sequence
+	if start.Name.Local == "sequence" && attribute(start, "key") == "" {
+		return nil, false, nil
+	}
+	factory, ok := mediatorFactories[start.Name.Local]
+	if !ok {
+		return nil, false, nil
+	}
+	mediator, err = factory().Unmarshal(d, start, position)
+	return mediator, true, err
+}
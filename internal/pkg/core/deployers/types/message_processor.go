@@ -0,0 +1,118 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// Defaults of message processors
+const (
+	defaultProcessorInterval            = time.Second
+	defaultProcessorRetryInterval       = time.Second
+	defaultProcessorMaxDeliveryAttempts = 4
+)
+
+// MessageProcessor is the XML form of a message processor, e.g.
+// <messageProcessor name="OrdersForwarder" messageStore="OrdersStore" endpoint="OrdersBackend"
+//
+//	interval="500ms" retryInterval="2s" backoffMultiplier="2" maxRetryInterval="1m"
+//	maxDeliveryAttempts="5" faultSequence="OrdersDeadLetter" onFailure="deactivate"/>
+type MessageProcessor struct {
+	Name                string `xml:"name,attr"`
+	MessageStore        string `xml:"messageStore,attr"`
+	Endpoint            string `xml:"endpoint,attr"`
+	Interval            string `xml:"interval,attr"`
+	RetryInterval       string `xml:"retryInterval,attr"`
+	BackoffMultiplier   string `xml:"backoffMultiplier,attr"`
+	MaxRetryInterval    string `xml:"maxRetryInterval,attr"`
+	MaxDeliveryAttempts string `xml:"maxDeliveryAttempts,attr"`
+	FaultSequence       string `xml:"faultSequence,attr"`
+	OnFailure           string `xml:"onFailure,attr"`
+}
+
+func (mp *MessageProcessor) Unmarshal(xmlData string, position artifacts.Position) (artifacts.MessageProcessor, error) {
+	if err := xml.Unmarshal([]byte(xmlData), mp); err != nil {
+		return artifacts.MessageProcessor{}, fmt.Errorf("error in unmarshalling message processor in %s: %w", position.FileName, err)
+	}
+	if mp.Name == "" {
+		return artifacts.MessageProcessor{}, fmt.Errorf("message processor name is required")
+	}
+	if mp.MessageStore == "" || mp.Endpoint == "" {
+		return artifacts.MessageProcessor{}, fmt.Errorf("message processor %s: messageStore and endpoint are required", mp.Name)
+	}
+	position.Hierarchy = mp.Name
+	processor := artifacts.MessageProcessor{
+		Name:                mp.Name,
+		MessageStore:        mp.MessageStore,
+		Endpoint:            mp.Endpoint,
+		Interval:            defaultProcessorInterval,
+		RetryInterval:       defaultProcessorRetryInterval,
+		BackoffMultiplier:   1,
+		MaxDeliveryAttempts: defaultProcessorMaxDeliveryAttempts,
+		FaultSequence:       mp.FaultSequence,
+		OnFailure:           mp.OnFailure,
+		Position:            position,
+	}
+	switch processor.OnFailure {
+	case "":
+		processor.OnFailure = artifacts.ProcessorOnFailureDeactivate
+	case artifacts.ProcessorOnFailureDeactivate, artifacts.ProcessorOnFailureDrop:
+	default:
+		return artifacts.MessageProcessor{}, fmt.Errorf("message processor %s: onFailure must be deactivate or drop, got: %s", mp.Name, mp.OnFailure)
+	}
+
+	var err error
+	for _, d := range []struct {
+		name   string
+		value  string
+		target *time.Duration
+	}{
+		{"interval", mp.Interval, &processor.Interval},
+		{"retryInterval", mp.RetryInterval, &processor.RetryInterval},
+		{"maxRetryInterval", mp.MaxRetryInterval, &processor.MaxRetryInterval},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if *d.target, err = time.ParseDuration(d.value); err != nil || *d.target <= 0 {
+			return artifacts.MessageProcessor{}, fmt.Errorf("message processor %s: invalid %s '%s'", mp.Name, d.name, d.value)
+		}
+	}
+	if mp.BackoffMultiplier != "" {
+		if processor.BackoffMultiplier, err = strconv.ParseFloat(mp.BackoffMultiplier, 64); err != nil || processor.BackoffMultiplier < 1 {
+			return artifacts.MessageProcessor{}, fmt.Errorf("message processor %s: invalid backoffMultiplier '%s', expected a number of at least 1", mp.Name, mp.BackoffMultiplier)
+		}
+	}
+	if mp.MaxDeliveryAttempts != "" {
+		if processor.MaxDeliveryAttempts, err = strconv.Atoi(mp.MaxDeliveryAttempts); err != nil || processor.MaxDeliveryAttempts < 1 {
+			return artifacts.MessageProcessor{}, fmt.Errorf("message processor %s: invalid maxDeliveryAttempts '%s'", mp.Name, mp.MaxDeliveryAttempts)
+		}
+	}
+	if processor.MaxRetryInterval > 0 && processor.MaxRetryInterval < processor.RetryInterval {
+		return artifacts.MessageProcessor{}, fmt.Errorf("message processor %s: maxRetryInterval %s is shorter than retryInterval %s", mp.Name, processor.MaxRetryInterval, processor.RetryInterval)
+	}
+	return processor, nil
+}
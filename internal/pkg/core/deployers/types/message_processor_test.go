@@ -0,0 +1,84 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageProcessor_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		xmlData string
+		want    artifacts.MessageProcessor
+		wantErr string
+	}{
+		{
+			name:    "defaults",
+			xmlData: `<messageProcessor name="Forwarder" messageStore="Orders" endpoint="Backend"/>`,
+			want: artifacts.MessageProcessor{Name: "Forwarder", MessageStore: "Orders", Endpoint: "Backend",
+				Interval: time.Second, RetryInterval: time.Second, BackoffMultiplier: 1, MaxDeliveryAttempts: 4, OnFailure: "deactivate"},
+		},
+		{
+			name: "backoff and dead letter sequence",
+			xmlData: `<messageProcessor name="Forwarder" messageStore="Orders" endpoint="Backend" interval="500ms"
+				retryInterval="2s" backoffMultiplier="2" maxRetryInterval="1m" maxDeliveryAttempts="5"
+				faultSequence="DeadLetter" onFailure="drop"/>`,
+			want: artifacts.MessageProcessor{Name: "Forwarder", MessageStore: "Orders", Endpoint: "Backend",
+				Interval: 500 * time.Millisecond, RetryInterval: 2 * time.Second, BackoffMultiplier: 2, MaxRetryInterval: time.Minute,
+				MaxDeliveryAttempts: 5, FaultSequence: "DeadLetter", OnFailure: "drop"},
+		},
+		{name: "missing name", xmlData: `<messageProcessor messageStore="Orders" endpoint="Backend"/>`, wantErr: "message processor name is required"},
+		{name: "missing endpoint", xmlData: `<messageProcessor name="P" messageStore="Orders"/>`, wantErr: "message processor P: messageStore and endpoint are required"},
+		{name: "unknown onFailure", xmlData: `<messageProcessor name="P" messageStore="O" endpoint="B" onFailure="retry"/>`, wantErr: "message processor P: onFailure must be deactivate or drop, got: retry"},
+		{name: "invalid interval", xmlData: `<messageProcessor name="P" messageStore="O" endpoint="B" interval="soon"/>`, wantErr: "message processor P: invalid interval 'soon'"},
+		{name: "shrinking backoff", xmlData: `<messageProcessor name="P" messageStore="O" endpoint="B" backoffMultiplier="0.5"/>`, wantErr: "message processor P: invalid backoffMultiplier '0.5', expected a number of at least 1"},
+		{name: "no attempts", xmlData: `<messageProcessor name="P" messageStore="O" endpoint="B" maxDeliveryAttempts="0"/>`, wantErr: "message processor P: invalid maxDeliveryAttempts '0'"},
+		{name: "cap below first retry", xmlData: `<messageProcessor name="P" messageStore="O" endpoint="B" retryInterval="10s" maxRetryInterval="5s"/>`, wantErr: "message processor P: maxRetryInterval 5s is shorter than retryInterval 10s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageProcessor := &MessageProcessor{}
+			result, err := messageProcessor.Unmarshal(tt.xmlData, artifacts.Position{FileName: "processor.xml"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			tt.want.Position = artifacts.Position{FileName: "processor.xml", Hierarchy: tt.want.Name}
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func TestMessageProcessor_RetryWait(t *testing.T) {
+	processor := artifacts.MessageProcessor{RetryInterval: time.Second, BackoffMultiplier: 2, MaxRetryInterval: 5 * time.Second}
+	assert.Equal(t, time.Second, processor.RetryWait(1))
+	assert.Equal(t, 2*time.Second, processor.RetryWait(2))
+	assert.Equal(t, 4*time.Second, processor.RetryWait(3))
+	assert.Equal(t, 5*time.Second, processor.RetryWait(4))
+	processor.BackoffMultiplier = 1
+	assert.Equal(t, time.Second, processor.RetryWait(4))
+}
@@ -0,0 +1,83 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// MessageStore is the XML form of a message store, e.g.
+// <messageStore name="OrdersStore" type="file" directory="stores/orders" maxSize="10000"/>
+// <messageStore name="AlertsStore" type="memory" aging="1m"/>
+type MessageStore struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	Directory string `xml:"directory,attr"`
+	MaxSize   string `xml:"maxSize,attr"`
+	Aging     string `xml:"aging,attr"`
+}
+
+func (ms *MessageStore) Unmarshal(xmlData string, position artifacts.Position) (artifacts.MessageStore, error) {
+	if err := xml.Unmarshal([]byte(xmlData), ms); err != nil {
+		return artifacts.MessageStore{}, fmt.Errorf("error in unmarshalling message store in %s: %w", position.FileName, err)
+	}
+	if ms.Name == "" {
+		return artifacts.MessageStore{}, fmt.Errorf("message store name is required")
+	}
+	position.Hierarchy = ms.Name
+	store := artifacts.MessageStore{Name: ms.Name, Type: ms.Type, Directory: ms.Directory, Position: position}
+	if store.Type == "" {
+		store.Type = artifacts.MessageStoreTypeMemory
+	}
+
+	switch store.Type {
+	case artifacts.MessageStoreTypeMemory:
+		if ms.Directory != "" {
+			return artifacts.MessageStore{}, fmt.Errorf("message store %s: directory applies to file stores only", ms.Name)
+		}
+	case artifacts.MessageStoreTypeFile:
+		if ms.Directory == "" {
+			return artifacts.MessageStore{}, fmt.Errorf("message store %s: a file store requires a directory", ms.Name)
+		}
+		if ms.Aging != "" {
+			return artifacts.MessageStore{}, fmt.Errorf("message store %s: aging applies to memory stores only", ms.Name)
+		}
+	default:
+		return artifacts.MessageStore{}, fmt.Errorf("message store %s: type must be memory or file, got: %s", ms.Name, ms.Type)
+	}
+
+	var err error
+	if ms.MaxSize != "" {
+		if store.MaxSize, err = strconv.Atoi(ms.MaxSize); err != nil || store.MaxSize < 1 {
+			return artifacts.MessageStore{}, fmt.Errorf("message store %s: invalid maxSize '%s'", ms.Name, ms.MaxSize)
+		}
+	}
+	if ms.Aging != "" {
+		if store.Aging, err = time.ParseDuration(ms.Aging); err != nil || store.Aging <= 0 {
+			return artifacts.MessageStore{}, fmt.Errorf("message store %s: invalid aging '%s'", ms.Name, ms.Aging)
+		}
+	}
+	return store, nil
+}
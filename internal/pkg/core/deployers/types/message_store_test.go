@@ -0,0 +1,68 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageStore_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		xmlData string
+		want    artifacts.MessageStore
+		wantErr string
+	}{
+		{
+			name:    "memory by default",
+			xmlData: `<messageStore name="Alerts" aging="1m"/>`,
+			want:    artifacts.MessageStore{Name: "Alerts", Type: "memory", Aging: time.Minute},
+		},
+		{
+			name:    "file",
+			xmlData: `<messageStore name="Orders" type="file" directory="stores/orders" maxSize="100"/>`,
+			want:    artifacts.MessageStore{Name: "Orders", Type: "file", Directory: "stores/orders", MaxSize: 100},
+		},
+		{name: "missing name", xmlData: `<messageStore type="memory"/>`, wantErr: "message store name is required"},
+		{name: "unknown type", xmlData: `<messageStore name="Q" type="jms"/>`, wantErr: "message store Q: type must be memory or file, got: jms"},
+		{name: "file without directory", xmlData: `<messageStore name="Q" type="file"/>`, wantErr: "message store Q: a file store requires a directory"},
+		{name: "memory with directory", xmlData: `<messageStore name="Q" directory="q"/>`, wantErr: "message store Q: directory applies to file stores only"},
+		{name: "file with aging", xmlData: `<messageStore name="Q" type="file" directory="q" aging="1m"/>`, wantErr: "message store Q: aging applies to memory stores only"},
+		{name: "invalid maxSize", xmlData: `<messageStore name="Q" maxSize="0"/>`, wantErr: "message store Q: invalid maxSize '0'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageStore := &MessageStore{}
+			result, err := messageStore.Unmarshal(tt.xmlData, artifacts.Position{FileName: "store.xml"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			tt.want.Position = artifacts.Position{FileName: "store.xml", Hierarchy: tt.want.Name}
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
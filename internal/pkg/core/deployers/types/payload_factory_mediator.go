@@ -0,0 +1,107 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// PayloadFactoryMediator is the XML form of the payload factory mediator, e.g.
+//
+//	<payloadFactory mediaType="json">
+//	    <format>{"orderId": $1, "customer": "$2", "items": $3}</format>
+//	    <args>
+//	        <arg expression="params.orderId"/>
+//	        <arg value="acme"/>
+//	        <arg expression="payload.lines"/>
+//	    </args>
+//	</payloadFactory>
+//
+// mediaType is json (the default), xml or text. XML templates are written as
+// elements inside <format>.
+type PayloadFactoryMediator struct {
+	XMLName   xml.Name `xml:"payloadFactory"`
+	MediaType string   `xml:"mediaType,attr"`
+	Format    *struct {
+		Text  string `xml:",chardata"`
+		Inner string `xml:",innerxml"`
+	} `xml:"format"`
+	Args []struct {
+		Value      *string `xml:"value,attr"`
+		Expression string  `xml:"expression,attr"`
+	} `xml:"args>arg"`
+}
+
+func (payloadFactoryMediator PayloadFactoryMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&payloadFactoryMediator, &start); err != nil {
+		return artifacts.PayloadFactoryMediator{}, errors.New("error in unmarshalling payloadFactory mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->payloadFactory"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("payloadFactory mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	mediator := artifacts.PayloadFactoryMediator{MediaType: payloadFactoryMediator.MediaType, Position: position}
+	if mediator.MediaType == "" {
+		mediator.MediaType = artifacts.PayloadMediaTypeJSON
+	}
+	if payloadFactoryMediator.Format == nil {
+		return artifacts.PayloadFactoryMediator{}, invalid("missing required element 'format'")
+	}
+	var template string
+	switch mediator.MediaType {
+	case artifacts.PayloadMediaTypeJSON, artifacts.PayloadMediaTypeText:
+		template = strings.TrimSpace(payloadFactoryMediator.Format.Text)
+	case artifacts.PayloadMediaTypeXML:
+		template = strings.TrimSpace(payloadFactoryMediator.Format.Inner)
+	default:
+		return artifacts.PayloadFactoryMediator{}, invalid("mediaType must be json, xml or text, got: %s", mediator.MediaType)
+	}
+	if template == "" {
+		return artifacts.PayloadFactoryMediator{}, invalid("format must not be empty")
+	}
+
+	for i, arg := range payloadFactoryMediator.Args {
+		if (arg.Value == nil) == (arg.Expression == "") {
+			return artifacts.PayloadFactoryMediator{}, invalid("argument $%d needs exactly one of 'value' and 'expression'", i+1)
+		}
+		if arg.Value != nil {
+			mediator.Arguments = append(mediator.Arguments, artifacts.PayloadArgument{Value: *arg.Value})
+			continue
+		}
+		program, err := expression.Compile(arg.Expression, artifacts.ExpressionVariables...)
+		if err != nil {
+			return artifacts.PayloadFactoryMediator{}, invalid("argument $%d: %v", i+1, err)
+		}
+		mediator.Arguments = append(mediator.Arguments, artifacts.PayloadArgument{Expression: program})
+	}
+	segments, err := artifacts.ParsePayloadTemplate(template, len(mediator.Arguments))
+	if err != nil {
+		return artifacts.PayloadFactoryMediator{}, invalid("%v", err)
+	}
+	mediator.Template = segments
+	return mediator, nil
+}
@@ -0,0 +1,108 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// PropertyMediator is the XML form of the property mediator, e.g.
+// <property name="orderId" expression="params.id"/>
+// <property name="retries" value="3" type="integer"/>
+// <property name="X-Order-Region" scope="transport" expression="headers['x-region']"/>
+// <property name="HTTP_SC" value="201" type="integer"/>
+// <property name="internalToken" action="remove"/>
+type PropertyMediator struct {
+	XMLName    xml.Name `xml:"property"`
+	Name       string   `xml:"name,attr"`
+	Value      *string  `xml:"value,attr"`
+	Expression string   `xml:"expression,attr"`
+	Type       string   `xml:"type,attr"`
+	Scope      string   `xml:"scope,attr"`
+	Action     string   `xml:"action,attr"`
+}
+
+func (propertyMediator PropertyMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&propertyMediator, &start); err != nil {
+		return artifacts.PropertyMediator{}, errors.New("error in unmarshalling property mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->property"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("property mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if propertyMediator.Name == "" {
+		return artifacts.PropertyMediator{}, invalid("missing required attribute 'name'")
+	}
+	mediator := artifacts.PropertyMediator{Name: propertyMediator.Name, Action: propertyMediator.Action,
+		Scope: propertyMediator.Scope, Type: propertyMediator.Type, Position: position}
+	if mediator.Action == "" {
+		mediator.Action = artifacts.PropertyActionSet
+	}
+	if mediator.Scope == "" {
+		mediator.Scope = artifacts.PropertyScopeDefault
+	}
+	if mediator.Type == "" {
+		mediator.Type = artifacts.PropertyTypeString
+	}
+	switch mediator.Scope {
+	case artifacts.PropertyScopeDefault, artifacts.PropertyScopeTransport:
+	default:
+		return artifacts.PropertyMediator{}, invalid("scope must be default or transport, got: %s", mediator.Scope)
+	}
+	if !artifacts.IsPropertyType(mediator.Type) {
+		return artifacts.PropertyMediator{}, invalid("type must be string, integer, double, boolean or json, got: %s", mediator.Type)
+	}
+	if mediator.Scope == artifacts.PropertyScopeTransport && mediator.Type != artifacts.PropertyTypeString {
+		return artifacts.PropertyMediator{}, invalid("transport properties are headers and can only be strings")
+	}
+
+	switch mediator.Action {
+	case artifacts.PropertyActionRemove:
+		if propertyMediator.Value != nil || propertyMediator.Expression != "" || propertyMediator.Type != "" {
+			return artifacts.PropertyMediator{}, invalid("value, expression and type do not apply to action 'remove'")
+		}
+	case artifacts.PropertyActionSet:
+		if (propertyMediator.Value == nil) == (propertyMediator.Expression == "") {
+			return artifacts.PropertyMediator{}, invalid("exactly one of 'value' and 'expression' is required")
+		}
+		if propertyMediator.Expression != "" {
+			program, err := expression.Compile(propertyMediator.Expression, artifacts.ExpressionVariables...)
+			if err != nil {
+				return artifacts.PropertyMediator{}, invalid("%v", err)
+			}
+			mediator.Expression = program
+			break
+		}
+		value, err := artifacts.ConvertPropertyValue(*propertyMediator.Value, mediator.Type)
+		if err != nil {
+			return artifacts.PropertyMediator{}, invalid("%v", err)
+		}
+		mediator.Value = value
+	default:
+		return artifacts.PropertyMediator{}, invalid("action must be set or remove, got: %s", mediator.Action)
+	}
+	return mediator, nil
+}
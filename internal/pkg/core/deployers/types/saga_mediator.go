@@ -0,0 +1,125 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+)
+
+// SagaMediator is the XML form of the saga mediator, e.g.
+//
+//	<saga name="PlaceOrder">
+//	    <step name="reserve" endpoint="InventoryEP">
+//	        <compensate endpoint="InventoryReleaseEP">
+//	            <payload contentType="application/json">{"reservation": "{{.JSON "$.id"}}"}</payload>
+//	        </compensate>
+//	    </step>
+//	    <step name="charge" endpoint="PaymentEP"/>
+//	</saga>
+type SagaMediator struct {
+	XMLName xml.Name   `xml:"saga"`
+	Name    string     `xml:"name,attr"`
+	Steps   []SagaStep `xml:"step"`
+}
+
+type SagaStep struct {
+	Name       string       `xml:"name,attr"`
+	Endpoint   string       `xml:"endpoint,attr"`
+	Payload    *SagaPayload `xml:"payload"`
+	Compensate *struct {
+		Endpoint string       `xml:"endpoint,attr"`
+		Payload  *SagaPayload `xml:"payload"`
+	} `xml:"compensate"`
+}
+
+type SagaPayload struct {
+	ContentType string `xml:"contentType,attr"`
+	Template    string `xml:",chardata"`
+}
+
+func (sagaMediator SagaMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&sagaMediator, &start); err != nil {
+		return artifacts.SagaMediator{}, errors.New("error in unmarshalling saga mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->saga"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("saga mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if sagaMediator.Name == "" {
+		return artifacts.SagaMediator{}, invalid("missing required attribute 'name'")
+	}
+	if len(sagaMediator.Steps) == 0 {
+		return artifacts.SagaMediator{}, invalid("must declare at least one step")
+	}
+
+	mediator := artifacts.SagaMediator{Name: sagaMediator.Name, Send: outbound.Send, Position: position}
+	seen := make(map[string]bool)
+	for i, step := range sagaMediator.Steps {
+		if step.Name == "" {
+			step.Name = "step" + strconv.Itoa(i+1)
+		}
+		if seen[step.Name] {
+			return artifacts.SagaMediator{}, invalid("duplicate step %s", step.Name)
+		}
+		seen[step.Name] = true
+		action, err := sagaAction(step.Name, step.Endpoint, step.Payload)
+		if err != nil {
+			return artifacts.SagaMediator{}, invalid("%v", err)
+		}
+		compiled := artifacts.SagaStep{Name: step.Name, Action: action}
+		if step.Compensate != nil {
+			compensation, err := sagaAction(step.Name+" compensation", step.Compensate.Endpoint, step.Compensate.Payload)
+			if err != nil {
+				return artifacts.SagaMediator{}, invalid("%v", err)
+			}
+			compiled.Compensation = &compensation
+		}
+		mediator.Steps = append(mediator.Steps, compiled)
+	}
+	return mediator, nil
+}
+
+func sagaAction(name, endpoint string, payload *SagaPayload) (artifacts.SagaAction, error) {
+	if endpoint == "" {
+		return artifacts.SagaAction{}, fmt.Errorf("%s requires an endpoint", name)
+	}
+	action := artifacts.SagaAction{Endpoint: endpoint}
+	if payload == nil {
+		return action, nil
+	}
+	compiled, err := template.New(name).Funcs(artifacts.PreconditionFuncs).Parse(strings.TrimSpace(payload.Template))
+	if err != nil {
+		return artifacts.SagaAction{}, fmt.Errorf("invalid payload of %s: %v", name, err)
+	}
+	action.Payload = compiled
+	action.ContentType = payload.ContentType
+	if action.ContentType == "" {
+		action.ContentType = "application/json"
+	}
+	return action, nil
+}
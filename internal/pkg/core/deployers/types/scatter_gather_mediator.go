@@ -0,0 +1,106 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+)
+
+// ScatterGatherMediator is the XML form of the scatter-gather mediator, e.g.
+//
+//	<scatterGather name="Quote" timeout="3s" onPartialFailure="placeholder">
+//	    <branch name="flights" endpoint="FlightsEP"/>
+//	    <branch name="hotels" endpoint="HotelsEP">
+//	        <payload contentType="application/json">{"city": "{{.JSON "$.destination"}}"}</payload>
+//	    </branch>
+//	</scatterGather>
+type ScatterGatherMediator struct {
+	XMLName          xml.Name `xml:"scatterGather"`
+	Name             string   `xml:"name,attr"`
+	Timeout          string   `xml:"timeout,attr"`
+	OnPartialFailure string   `xml:"onPartialFailure,attr"`
+	Branches         []struct {
+		Name     string       `xml:"name,attr"`
+		Endpoint string       `xml:"endpoint,attr"`
+		Payload  *SagaPayload `xml:"payload"`
+	} `xml:"branch"`
+}
+
+func (scatterGatherMediator ScatterGatherMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&scatterGatherMediator, &start); err != nil {
+		return artifacts.ScatterGatherMediator{}, errors.New("error in unmarshalling scatterGather mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->scatterGather"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("scatterGather mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if scatterGatherMediator.Name == "" {
+		return artifacts.ScatterGatherMediator{}, invalid("missing required attribute 'name'")
+	}
+	if len(scatterGatherMediator.Branches) < 2 {
+		return artifacts.ScatterGatherMediator{}, invalid("must declare at least two branches")
+	}
+
+	mediator := artifacts.ScatterGatherMediator{
+		Name:             scatterGatherMediator.Name,
+		OnPartialFailure: scatterGatherMediator.OnPartialFailure,
+		Send:             outbound.Send,
+		Position:         position,
+	}
+	switch mediator.OnPartialFailure {
+	case "":
+		mediator.OnPartialFailure = artifacts.PartialFailureFail
+	case artifacts.PartialFailureFail, artifacts.PartialFailurePlaceholder, artifacts.PartialFailureDrop:
+	default:
+		return artifacts.ScatterGatherMediator{}, invalid("onPartialFailure must be %s, %s or %s, got '%s'",
+			artifacts.PartialFailureFail, artifacts.PartialFailurePlaceholder, artifacts.PartialFailureDrop, mediator.OnPartialFailure)
+	}
+	if scatterGatherMediator.Timeout != "" {
+		timeout, err := time.ParseDuration(scatterGatherMediator.Timeout)
+		if err != nil || timeout <= 0 {
+			return artifacts.ScatterGatherMediator{}, invalid("timeout must be a positive duration, got '%s'", scatterGatherMediator.Timeout)
+		}
+		mediator.Timeout = timeout
+	}
+
+	seen := make(map[string]bool)
+	for i, branch := range scatterGatherMediator.Branches {
+		if branch.Name == "" {
+			branch.Name = "branch" + strconv.Itoa(i+1)
+		}
+		if seen[branch.Name] {
+			return artifacts.ScatterGatherMediator{}, invalid("duplicate branch %s", branch.Name)
+		}
+		seen[branch.Name] = true
+		action, err := sagaAction(branch.Name, branch.Endpoint, branch.Payload)
+		if err != nil {
+			return artifacts.ScatterGatherMediator{}, invalid("%v", err)
+		}
+		mediator.Branches = append(mediator.Branches, artifacts.ScatterGatherBranch{Name: branch.Name, Action: action})
+	}
+	return mediator, nil
+}
@@ -48,13 +48,11 @@ OuterLoop:
 		position := artifacts.Position{LineNo: line, FileName: position.FileName, Hierarchy: position.Hierarchy}
 		switch element := token.(type) {
 		case xml.StartElement:
-			switch element.Name.Local {
-			case "log":
-				logMediator := LogMediator{}
-				mediator, err := logMediator.Unmarshal(decoder, element, position)
-				if err != nil {
-					return artifacts.Sequence{}, err
-				}
+			mediator, ok, err := unmarshalMediator(decoder, element, position)
+			if err != nil {
+				return artifacts.Sequence{}, err
+			}
+			if ok {
 				mediatorList = append(mediatorList, mediator)
 			}
 		case xml.EndElement:
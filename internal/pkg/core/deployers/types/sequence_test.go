@@ -22,9 +22,13 @@ import (
 	"encoding/xml"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/claimcheck"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnmarshalMediators(t *testing.T) {
@@ -83,3 +87,1045 @@ func TestUnmarshalMediatorsWithInvalidXML(t *testing.T) {
 	_, err := sequence.unmarshal(decoder, position)
 	assert.NotNil(t, err)
 }
+
+func TestUnmarshalMaskMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<mask>
+			<field path="$.customer.ssn"/>
+			<field path="//cardNumber" action="remove"/>
+		</mask>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 1) {
+		maskMediator, ok := newSeq.MediatorList[0].(artifacts.MaskMediator)
+		assert.True(t, ok)
+		assert.Equal(t, "sequence->mask", maskMediator.Position.Hierarchy)
+		assert.NotNil(t, maskMediator.Policy)
+	}
+
+	decoder = xml.NewDecoder(strings.NewReader(`<sequence><mask><field path="$.ssn" action="hash"/></mask></sequence>`))
+	_, err = sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.ErrorContains(t, err, "invalid masking action 'hash'")
+}
+
+func TestUnmarshalValidateRequestMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<validateRequest contentTypes="application/json, text/*">
+			<header name="X-Tenant" pattern="^[a-z]+$"/>
+			<precondition expression='eq (.Header "X-Api-Version") "2"' message="only version 2 is supported"/>
+		</validateRequest>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 1) {
+		validateMediator, ok := newSeq.MediatorList[0].(artifacts.ValidateRequestMediator)
+		assert.True(t, ok)
+		assert.Equal(t, "sequence->validateRequest", validateMediator.Position.Hierarchy)
+		assert.Equal(t, []string{"application/json", "text/*"}, validateMediator.ContentTypes)
+		assert.Len(t, validateMediator.Headers, 1)
+		assert.Equal(t, "only version 2 is supported", validateMediator.Preconditions[0].Message)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "empty", mediator: `<validateRequest/>`, wantErr: "must declare content types, headers or preconditions"},
+		{name: "bad pattern", mediator: `<validateRequest><header name="X-Tenant" pattern="("/></validateRequest>`, wantErr: "invalid pattern for header X-Tenant"},
+		{name: "bad expression", mediator: `<validateRequest><precondition expression="eq (.Header"/></validateRequest>`, wantErr: "invalid precondition"},
+		{name: "bad content type", mediator: `<validateRequest contentTypes="json"/>`, wantErr: "invalid content type 'json'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalAuthorizeMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<authorize verificationKey="idp" algorithms="RS256, ES256" issuer="https://idp.example.com">
+			<rule name="writers" effect="permit" condition='.HasClaim "scope" "orders:write"'>
+				<obligation property="tenant" value='{{.Claim "tenant"}}'/>
+			</rule>
+			<rule effect="deny" condition='gt (.JSON "$.total") 1000.0' message="Order total exceeds your limit"/>
+		</authorize>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 1) {
+		authorizeMediator, ok := newSeq.MediatorList[0].(artifacts.AuthorizeMediator)
+		assert.True(t, ok)
+		assert.Equal(t, "sequence->authorize", authorizeMediator.Position.Hierarchy)
+		assert.Equal(t, "Authorization", authorizeMediator.Header)
+		assert.Equal(t, artifacts.EffectDeny, authorizeMediator.DefaultEffect)
+		assert.Equal(t, []string{"RS256", "ES256"}, authorizeMediator.Algorithms)
+		if assert.Len(t, authorizeMediator.Rules, 2) {
+			assert.Equal(t, "writers", authorizeMediator.Rules[0].Name)
+			assert.Len(t, authorizeMediator.Rules[0].Obligations, 1)
+			assert.Equal(t, "2", authorizeMediator.Rules[1].Name)
+			assert.Equal(t, "Order total exceeds your limit", authorizeMediator.Rules[1].Message)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no rules", mediator: `<authorize/>`, wantErr: "denies every request"},
+		{name: "bad effect", mediator: `<authorize><rule effect="allow" condition="true"/></authorize>`, wantErr: "effect must be 'permit' or 'deny'"},
+		{name: "bad condition", mediator: `<authorize><rule effect="permit" condition="eq (.Claim"/></authorize>`, wantErr: "invalid condition of rule 1"},
+		{name: "bad default", mediator: `<authorize default="maybe"/>`, wantErr: "default must be 'permit' or 'deny'"},
+		{name: "obligation target", mediator: `<authorize><rule effect="permit" condition="true"><obligation value="x"/></rule></authorize>`, wantErr: "must set either a property or a header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalPropertyMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<property name="retries" value="3" type="integer"/>
+		<property name="X-Region" scope="transport" expression="headers['x-region']"/>
+		<property name="internalToken" action="remove"/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 3) {
+		retries := newSeq.MediatorList[0].(artifacts.PropertyMediator)
+		assert.Equal(t, "sequence->property", retries.Position.Hierarchy)
+		assert.Equal(t, 3, retries.Value)
+		assert.Equal(t, artifacts.PropertyActionSet, retries.Action)
+		assert.Equal(t, artifacts.PropertyScopeDefault, retries.Scope)
+
+		region := newSeq.MediatorList[1].(artifacts.PropertyMediator)
+		assert.Equal(t, artifacts.PropertyScopeTransport, region.Scope)
+		assert.NotNil(t, region.Expression)
+
+		assert.Equal(t, artifacts.PropertyActionRemove, newSeq.MediatorList[2].(artifacts.PropertyMediator).Action)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no name", mediator: `<property value="1"/>`, wantErr: "missing required attribute 'name'"},
+		{name: "no value", mediator: `<property name="a"/>`, wantErr: "exactly one of 'value' and 'expression'"},
+		{name: "value and expression", mediator: `<property name="a" value="1" expression="1"/>`, wantErr: "exactly one of 'value' and 'expression'"},
+		{name: "bad constant", mediator: `<property name="a" value="three" type="integer"/>`, wantErr: "'three' is not an integer"},
+		{name: "bad expression", mediator: `<property name="a" expression="payload."/>`, wantErr: "property mediator in testfile.xml"},
+		{name: "bad scope", mediator: `<property name="a" value="1" scope="axis2"/>`, wantErr: "scope must be default or transport"},
+		{name: "bad type", mediator: `<property name="a" value="1" type="long"/>`, wantErr: "type must be"},
+		{name: "typed header", mediator: `<property name="a" value="1" type="integer" scope="transport"/>`, wantErr: "can only be strings"},
+		{name: "remove with value", mediator: `<property name="a" value="1" action="remove"/>`, wantErr: "do not apply to action 'remove'"},
+		{name: "bad action", mediator: `<property name="a" action="copy"/>`, wantErr: "action must be set or remove"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalPayloadFactoryMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<payloadFactory>
+			<format>{"orderId": $1, "tenant": "$2"}</format>
+			<args>
+				<arg expression="params.orderId"/>
+				<arg value="acme"/>
+			</args>
+		</payloadFactory>
+		<payloadFactory mediaType="xml">
+			<format><order><id>$1</id></order></format>
+			<args><arg expression="params.orderId"/></args>
+		</payloadFactory>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		jsonFactory := newSeq.MediatorList[0].(artifacts.PayloadFactoryMediator)
+		assert.Equal(t, "sequence->payloadFactory", jsonFactory.Position.Hierarchy)
+		assert.Equal(t, artifacts.PayloadMediaTypeJSON, jsonFactory.MediaType)
+		assert.Equal(t, []artifacts.PayloadSegment{
+			{Literal: `{"orderId": `, Argument: 1}, {Literal: `, "tenant": "`, Argument: 2}, {Literal: `"}`},
+		}, jsonFactory.Template)
+		assert.Equal(t, "acme", jsonFactory.Arguments[1].Value)
+
+		xmlFactory := newSeq.MediatorList[1].(artifacts.PayloadFactoryMediator)
+		assert.Equal(t, []artifacts.PayloadSegment{{Literal: `<order><id>`, Argument: 1}, {Literal: `</id></order>`}}, xmlFactory.Template)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no format", mediator: `<payloadFactory/>`, wantErr: "missing required element 'format'"},
+		{name: "empty format", mediator: `<payloadFactory><format> </format></payloadFactory>`, wantErr: "format must not be empty"},
+		{name: "bad media type", mediator: `<payloadFactory mediaType="yaml"><format>a</format></payloadFactory>`, wantErr: "mediaType must be json, xml or text"},
+		{name: "missing argument", mediator: `<payloadFactory><format>{"a": $2}</format><args><arg value="1"/></args></payloadFactory>`,
+			wantErr: "placeholder $2 refers to a missing argument, 1 declared"},
+		{name: "argument without value", mediator: `<payloadFactory><format>$1</format><args><arg/></args></payloadFactory>`,
+			wantErr: "argument $1 needs exactly one of 'value' and 'expression'"},
+		{name: "bad expression", mediator: `<payloadFactory><format>$1</format><args><arg expression="payload."/></args></payloadFactory>`,
+			wantErr: "argument $1:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalSwitchMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<switch source="payload.tier">
+			<case regex="gold|platinum">
+				<property name="priority" value="high"/>
+				<log level="simple"/>
+			</case>
+			<case regex="silver"/>
+			<default>
+				<property name="priority" value="normal"/>
+			</default>
+		</switch>
+		<property name="after" value="switch"/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		switchMediator := newSeq.MediatorList[0].(artifacts.SwitchMediator)
+		assert.Equal(t, "sequence->switch", switchMediator.Position.Hierarchy)
+		if assert.Len(t, switchMediator.Cases, 2) {
+			assert.True(t, switchMediator.Cases[0].Regex.MatchString("platinum"))
+			assert.False(t, switchMediator.Cases[0].Regex.MatchString("golden"))
+			assert.Len(t, switchMediator.Cases[0].Sequence.MediatorList, 2)
+			property := switchMediator.Cases[0].Sequence.MediatorList[0].(artifacts.PropertyMediator)
+			assert.Equal(t, "sequence->switch->case->property", property.Position.Hierarchy)
+			assert.Empty(t, switchMediator.Cases[1].Sequence.MediatorList)
+		}
+		if assert.NotNil(t, switchMediator.Default) {
+			assert.Len(t, switchMediator.Default.MediatorList, 1)
+		}
+		assert.Equal(t, "after", newSeq.MediatorList[1].(artifacts.PropertyMediator).Name)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no source", mediator: `<switch><case regex="a"/></switch>`, wantErr: "missing required attribute 'source'"},
+		{name: "bad source", mediator: `<switch source="payload."><case regex="a"/></switch>`, wantErr: "switch mediator in testfile.xml"},
+		{name: "no case", mediator: `<switch source="payload.a"><default/></switch>`, wantErr: "must declare at least one case"},
+		{name: "no regex", mediator: `<switch source="payload.a"><case/></switch>`, wantErr: "case is missing required attribute 'regex'"},
+		{name: "bad regex", mediator: `<switch source="payload.a"><case regex="("/></switch>`, wantErr: "invalid case regex '('"},
+		{name: "two defaults", mediator: `<switch source="payload.a"><case regex="a"/><default/><default/></switch>`, wantErr: "only one default is allowed"},
+		{name: "unknown branch", mediator: `<switch source="payload.a"><when regex="a"/></switch>`, wantErr: "unexpected element 'when'"},
+		{name: "bad nested mediator", mediator: `<switch source="payload.a"><case regex="a"><property value="1"/></case></switch>`,
+			wantErr: "missing required attribute 'name'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalCallMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<call endpoint="InventoryEP"/>
+		<call uri="http://inventory:8080/items/{{.Property &quot;itemId&quot;}}" method="get" timeout="5s"/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		deployed := newSeq.MediatorList[0].(artifacts.CallMediator)
+		assert.Equal(t, "sequence->call", deployed.Position.Hierarchy)
+		assert.Equal(t, "InventoryEP", deployed.Endpoint)
+		assert.Nil(t, deployed.Inline)
+		assert.NotNil(t, deployed.Send)
+
+		inline := newSeq.MediatorList[1].(artifacts.CallMediator)
+		if assert.NotNil(t, inline.Inline) {
+			assert.Equal(t, artifacts.ProtocolHTTP, inline.Inline.Protocol)
+			assert.Equal(t, "GET", inline.Inline.EndpointUrl.Method)
+			msg := synctx.CreateMsgContext()
+			msg.Properties["itemId"] = "42"
+			uri, err := inline.Inline.Option("uri-template", msg)
+			assert.NoError(t, err)
+			assert.Equal(t, "http://inventory:8080/items/42", uri)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no target", mediator: `<call/>`, wantErr: "exactly one of 'endpoint' and 'uri' is required"},
+		{name: "two targets", mediator: `<call endpoint="A" uri="http://a"/>`, wantErr: "exactly one of 'endpoint' and 'uri' is required"},
+		{name: "method on endpoint", mediator: `<call endpoint="A" method="GET"/>`, wantErr: "method and timeout apply to 'uri' only"},
+		{name: "bad timeout", mediator: `<call uri="http://a" timeout="soon"/>`, wantErr: "timeout must be a positive duration"},
+		{name: "bad template", mediator: `<call uri="http://a/{{.Property"/>`, wantErr: "invalid uri-template template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalRespondMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<switch source="payload.cached">
+			<case regex="true">
+				<respond/>
+			</case>
+		</switch>
+		<respond></respond>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		switchMediator := newSeq.MediatorList[0].(artifacts.SwitchMediator)
+		respond := switchMediator.Cases[0].Sequence.MediatorList[0].(artifacts.RespondMediator)
+		assert.Equal(t, "sequence->switch->case->respond", respond.Position.Hierarchy)
+		assert.Equal(t, "sequence->respond", newSeq.MediatorList[1].(artifacts.RespondMediator).Position.Hierarchy)
+	}
+}
+
+func TestUnmarshalHeaderMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<header name="X-Order-Id" expression="params.id"/>
+		<header name="Cache-Control" value="no-store" scope="transport"/>
+		<header name="Server" action="remove"/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 3) {
+		expression := newSeq.MediatorList[0].(artifacts.HeaderMediator)
+		assert.Equal(t, "sequence->header", expression.Position.Hierarchy)
+		assert.Equal(t, artifacts.PropertyActionSet, expression.Action)
+		assert.Equal(t, artifacts.HeaderScopeTransport, expression.Scope)
+		assert.NotNil(t, expression.Expression)
+		assert.Equal(t, "no-store", newSeq.MediatorList[1].(artifacts.HeaderMediator).Value)
+		assert.Equal(t, artifacts.PropertyActionRemove, newSeq.MediatorList[2].(artifacts.HeaderMediator).Action)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no name", mediator: `<header value="a"/>`, wantErr: "missing required attribute 'name'"},
+		{name: "bad name", mediator: `<header name="X Order" value="a"/>`, wantErr: "invalid header name 'X Order'"},
+		{name: "no value", mediator: `<header name="X-A"/>`, wantErr: "exactly one of 'value' and 'expression' is required"},
+		{name: "value and expression", mediator: `<header name="X-A" value="a" expression="'a'"/>`, wantErr: "exactly one of 'value' and 'expression' is required"},
+		{name: "remove with value", mediator: `<header name="X-A" value="a" action="remove"/>`, wantErr: "value and expression do not apply to action 'remove'"},
+		{name: "line break", mediator: `<header name="X-A" value="a&#10;Set-Cookie: b"/>`, wantErr: "header values cannot contain line breaks"},
+		{name: "soap scope", mediator: `<header name="X-A" value="a" scope="soap"/>`, wantErr: "scope soap is not supported yet"},
+		{name: "unknown scope", mediator: `<header name="X-A" value="a" scope="axis2"/>`, wantErr: "scope must be transport or soap, got: axis2"},
+		{name: "unknown action", mediator: `<header name="X-A" value="a" action="append"/>`, wantErr: "action must be set or remove, got: append"},
+		{name: "bad expression", mediator: `<header name="X-A" expression="payload."/>`, wantErr: "header mediator in testfile.xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalEnrichMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<enrich>
+			<source type="property" property="customer"/>
+			<target type="key" key="order.customer" action="merge"/>
+		</enrich>
+		<enrich>
+			<source type="inline"><status>accepted</status></source>
+			<target type="child" xpath="order/state"/>
+		</enrich>
+		<enrich>
+			<source type="inline"><![CDATA[{"note":"a<b"}]]></source>
+			<target type="key"/>
+		</enrich>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 3) {
+		property := newSeq.MediatorList[0].(artifacts.EnrichMediator)
+		assert.Equal(t, "sequence->enrich", property.Position.Hierarchy)
+		assert.Equal(t, artifacts.EnrichSource{Type: artifacts.EnrichSourceProperty, Property: "customer"}, property.Source)
+		assert.Equal(t, artifacts.EnrichTarget{Type: artifacts.EnrichTargetKey, Path: []string{"order", "customer"}, Action: artifacts.EnrichActionMerge}, property.Target)
+
+		inline := newSeq.MediatorList[1].(artifacts.EnrichMediator)
+		assert.Equal(t, "<status>accepted</status>", inline.Source.Inline)
+		assert.Equal(t, artifacts.EnrichTarget{Type: artifacts.EnrichTargetChild, Path: []string{"order", "state"}, Action: artifacts.EnrichActionReplace}, inline.Target)
+		cdata := newSeq.MediatorList[2].(artifacts.EnrichMediator)
+		assert.Equal(t, `{"note":"a<b"}`, cdata.Source.Inline)
+		assert.Empty(t, cdata.Target.Path)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no target", mediator: `<enrich><source type="body"/></enrich>`, wantErr: "requires a source and a target"},
+		{name: "unknown source", mediator: `<enrich><source type="header"/><target type="body"/></enrich>`, wantErr: "source type must be body, property, expression or inline, got: header"},
+		{name: "source without property", mediator: `<enrich><source type="property"/><target type="body"/></enrich>`, wantErr: "property source is missing required attribute 'property'"},
+		{name: "source without expression", mediator: `<enrich><source type="expression"/><target type="body"/></enrich>`, wantErr: "expression source is missing required attribute 'expression'"},
+		{name: "bad expression", mediator: `<enrich><source type="expression" expression="payload."/><target type="body"/></enrich>`, wantErr: "enrich mediator in testfile.xml"},
+		{name: "empty inline", mediator: `<enrich><source type="inline"> </source><target type="body"/></enrich>`, wantErr: "inline source has no content"},
+		{name: "bad inline JSON", mediator: `<enrich><source type="inline"><![CDATA[{"a":]]></source><target type="body"/></enrich>`, wantErr: "inline source: invalid JSON"},
+		{name: "unknown target", mediator: `<enrich><source type="body"/><target type="header"/></enrich>`, wantErr: "target type must be body, property, key or child, got: header"},
+		{name: "target without property", mediator: `<enrich><source type="body"/><target type="property"/></enrich>`, wantErr: "property target is missing required attribute 'property'"},
+		{name: "merge into body", mediator: `<enrich><source type="body"/><target type="body" action="merge"/></enrich>`, wantErr: "action merge applies to key and child targets only"},
+		{name: "unknown action", mediator: `<enrich><source type="body"/><target type="key" key="a" action="append"/></enrich>`, wantErr: "action must be replace or merge, got: append"},
+		{name: "bad key", mediator: `<enrich><source type="body"/><target type="key" key="a..b"/></enrich>`, wantErr: "invalid key 'a..b'"},
+		{name: "bad xpath", mediator: `<enrich><source type="body"/><target type="child" xpath="a//b"/></enrich>`, wantErr: "invalid xpath 'a//b'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalClaimCheckMediators(t *testing.T) {
+	xmlData := `<sequence>
+		<storePayload/>
+		<storePayload store="memory" property="ORDER_CLAIM" maxSize="512KB"/>
+		<restorePayload token="properties.ORDER_CLAIM" remove="true"/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 3) {
+		defaults := newSeq.MediatorList[0].(artifacts.StorePayloadMediator)
+		assert.Equal(t, "sequence->storePayload", defaults.Position.Hierarchy)
+		assert.Equal(t, artifacts.ClaimCheckTokenProperty, defaults.Property)
+		assert.NotNil(t, defaults.Store)
+		assert.Equal(t, int64(claimcheck.DefaultMaxSize), defaults.MaxSize)
+		named := newSeq.MediatorList[1].(artifacts.StorePayloadMediator)
+		assert.Equal(t, "ORDER_CLAIM", named.Property)
+		assert.Equal(t, int64(512<<10), named.MaxSize)
+
+		restore := newSeq.MediatorList[2].(artifacts.RestorePayloadMediator)
+		assert.Equal(t, "sequence->restorePayload", restore.Position.Hierarchy)
+		assert.Same(t, defaults.Store, restore.Store)
+		assert.True(t, restore.Remove)
+		assert.NotNil(t, restore.Token)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "unknown store", mediator: `<storePayload store="s3"/>`, wantErr: "storePayload mediator in testfile.xml at line 1: claim check store s3 is not registered"},
+		{name: "bad maxSize", mediator: `<storePayload maxSize="0"/>`, wantErr: "maxSize must be a positive size such as 512KB or 10MB, got: 0"},
+		{name: "unknown restore store", mediator: `<restorePayload store="file"/>`, wantErr: "claim check store file is not registered"},
+		{name: "bad token", mediator: `<restorePayload token="payload."/>`, wantErr: "invalid token expression"},
+		{name: "bad remove", mediator: `<restorePayload remove="yes"/>`, wantErr: "remove must be true or false, got: yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalDropMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<switch source="payload.spam">
+			<case regex="true">
+				<drop/>
+			</case>
+		</switch>
+		<drop></drop>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		switchMediator := newSeq.MediatorList[0].(artifacts.SwitchMediator)
+		drop := switchMediator.Cases[0].Sequence.MediatorList[0].(artifacts.DropMediator)
+		assert.Equal(t, "sequence->switch->case->drop", drop.Position.Hierarchy)
+		assert.Equal(t, "sequence->drop", newSeq.MediatorList[1].(artifacts.DropMediator).Position.Hierarchy)
+	}
+}
+
+func TestUnmarshalFaultMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<makefault version="json" status="422">
+			<code value="ORDER_INVALID"/>
+			<reason value="The order has no items"/>
+			<detail expression="payload.id"/>
+		</makefault>
+		<makefault version="soap11">
+			<code value="Server"/>
+			<reason expression="properties.ERROR_MESSAGE"/>
+		</makefault>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		json := newSeq.MediatorList[0].(artifacts.FaultMediator)
+		assert.Equal(t, "sequence->makefault", json.Position.Hierarchy)
+		assert.Equal(t, artifacts.FaultVersionJSON, json.Version)
+		assert.Equal(t, 422, json.Status)
+		assert.Equal(t, "ORDER_INVALID", json.Code)
+		assert.Equal(t, "The order has no items", json.Reason.Value)
+		assert.NotNil(t, json.Detail.Expression)
+
+		soap := newSeq.MediatorList[1].(artifacts.FaultMediator)
+		assert.Equal(t, 500, soap.Status)
+		assert.NotNil(t, soap.Reason.Expression)
+		assert.Nil(t, soap.Detail.Expression)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no version", mediator: `<makefault><code value="X"/><reason value="r"/></makefault>`, wantErr: "missing required attribute 'version'"},
+		{name: "unknown version", mediator: `<makefault version="pox"><code value="X"/><reason value="r"/></makefault>`, wantErr: "version must be soap11, soap12 or json, got: pox"},
+		{name: "success status", mediator: `<makefault version="json" status="200"><code value="X"/><reason value="r"/></makefault>`, wantErr: "status must be an HTTP error status, got: 200"},
+		{name: "no code", mediator: `<makefault version="json"><reason value="r"/></makefault>`, wantErr: "missing required element 'code' with a value"},
+		{name: "soap 1.2 code in 1.1", mediator: `<makefault version="soap11"><code value="Receiver"/><reason value="r"/></makefault>`, wantErr: "code of a soap11 fault must be one of VersionMismatch, MustUnderstand, Client, Server, got: Receiver"},
+		{name: "no reason", mediator: `<makefault version="json"><code value="X"/></makefault>`, wantErr: "missing required element 'reason'"},
+		{name: "value and expression", mediator: `<makefault version="json"><code value="X"/><reason value="r" expression="payload.r"/></makefault>`, wantErr: "reason: exactly one of 'value' and 'expression' is required"},
+		{name: "bad detail", mediator: `<makefault version="json"><code value="X"/><reason value="r"/><detail expression="payload."/></makefault>`, wantErr: "makefault mediator in testfile.xml at line 1: detail:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalIterateMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<iterate expression="$.order.items" parallel="true" sequence="ProcessItem"/>
+		<foreach expression="//order/item">
+			<sequence>
+				<respond/>
+			</sequence>
+		</foreach>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		iterate := newSeq.MediatorList[0].(artifacts.IterateMediator)
+		assert.Equal(t, "sequence->iterate", iterate.Position.Hierarchy)
+		assert.Equal(t, "$.order.items", iterate.Path.String())
+		assert.Equal(t, "ProcessItem", iterate.SequenceName)
+		assert.True(t, iterate.Parallel)
+		assert.False(t, iterate.Aggregate)
+
+		foreach := newSeq.MediatorList[1].(artifacts.IterateMediator)
+		assert.True(t, foreach.Path.IsXPath())
+		assert.True(t, foreach.Aggregate, "foreach aggregates by default")
+		if assert.NotNil(t, foreach.Sequence) && assert.Len(t, foreach.Sequence.MediatorList, 1) {
+			respond := foreach.Sequence.MediatorList[0].(artifacts.RespondMediator)
+			assert.Equal(t, "sequence->foreach->sequence->respond", respond.Position.Hierarchy)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no expression", mediator: `<iterate sequence="s"/>`, wantErr: "iterate mediator in testfile.xml at line 1: missing required attribute 'expression'"},
+		{name: "bad path", mediator: `<iterate expression="items" sequence="s"/>`, wantErr: "path must be a JSONPath"},
+		{name: "no target", mediator: `<iterate expression="$.items"/>`, wantErr: "exactly one of the 'sequence' attribute and a sequence element is required"},
+		{name: "two targets", mediator: `<iterate expression="$.items" sequence="s"><sequence/></iterate>`, wantErr: "exactly one of"},
+		{name: "unexpected element", mediator: `<iterate expression="$.items"><target/></iterate>`, wantErr: "unexpected element 'target', expected sequence"},
+		{name: "parallel foreach", mediator: `<foreach expression="$.items" parallel="true" sequence="s"/>`, wantErr: "foreach runs items in order"},
+		{name: "bad aggregate", mediator: `<iterate expression="$.items" aggregate="yes" sequence="s"/>`, wantErr: "aggregate must be true or false, got: yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalCloneMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<clone id="orders" concurrency="4" continueParent="true">
+			<target sequence="AuditOrder"/>
+			<target>
+				<sequence>
+					<respond/>
+				</sequence>
+			</target>
+		</clone>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 1) {
+		clone := newSeq.MediatorList[0].(artifacts.CloneMediator)
+		assert.Equal(t, "sequence->clone", clone.Position.Hierarchy)
+		assert.Equal(t, "orders", clone.ID)
+		assert.Equal(t, 4, clone.Concurrency)
+		assert.True(t, clone.ContinueParent)
+		if assert.Len(t, clone.Targets, 2) {
+			assert.Equal(t, "AuditOrder", clone.Targets[0].SequenceName)
+			if assert.NotNil(t, clone.Targets[1].Sequence) && assert.Len(t, clone.Targets[1].Sequence.MediatorList, 1) {
+				respond := clone.Targets[1].Sequence.MediatorList[0].(artifacts.RespondMediator)
+				assert.Equal(t, "sequence->clone->target->respond", respond.Position.Hierarchy)
+			}
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no targets", mediator: `<clone/>`, wantErr: "clone mediator in testfile.xml at line 1: at least one target is required"},
+		{name: "bad concurrency", mediator: `<clone concurrency="0"><target sequence="s"/></clone>`, wantErr: "concurrency must be a positive integer, got: 0"},
+		{name: "bad continueParent", mediator: `<clone continueParent="yes"><target sequence="s"/></clone>`, wantErr: "continueParent must be true or false, got: yes"},
+		{name: "empty target", mediator: `<clone><target/></clone>`, wantErr: "a target needs exactly one of the 'sequence' attribute and a sequence element"},
+		{name: "two sequences", mediator: `<clone><target sequence="s"><sequence/></target></clone>`, wantErr: "a target needs exactly one of"},
+		{name: "unexpected element", mediator: `<clone><sequence/></clone>`, wantErr: "unexpected element 'sequence', expected target"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalAggregateMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<aggregate correlation="payload.orderId" min="2" max="3" timeout="5s" wrapper="quotes"/>
+		<aggregate/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		aggregate := newSeq.MediatorList[0].(artifacts.AggregateMediator)
+		assert.Equal(t, "sequence->aggregate", aggregate.Position.Hierarchy)
+		assert.NotNil(t, aggregate.Correlation)
+		assert.Equal(t, 2, aggregate.Min)
+		assert.Equal(t, 3, aggregate.Max)
+		assert.Equal(t, 5*time.Second, aggregate.Timeout)
+		assert.Equal(t, "quotes", aggregate.Wrapper)
+
+		defaults := newSeq.MediatorList[1].(artifacts.AggregateMediator)
+		assert.Nil(t, defaults.Correlation)
+		assert.Zero(t, defaults.Max)
+		assert.Zero(t, defaults.Timeout)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "bad correlation", mediator: `<aggregate correlation="payload.("/>`, wantErr: "aggregate mediator in testfile.xml at line 1: invalid correlation expression"},
+		{name: "bad min", mediator: `<aggregate min="-1"/>`, wantErr: "min must be a non-negative integer, got: -1"},
+		{name: "bad max", mediator: `<aggregate max="0"/>`, wantErr: "max must be a positive integer, got: 0"},
+		{name: "max below min", mediator: `<aggregate min="3" max="2"/>`, wantErr: "max 2 is less than min 3"},
+		{name: "bad timeout", mediator: `<aggregate timeout="soon"/>`, wantErr: "timeout must be a positive duration, got: soon"},
+		{name: "bad wrapper", mediator: `<aggregate wrapper="two words"/>`, wantErr: "wrapper must be an XML element name, got: two words"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalCacheMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<cache timeout="5m" headers="accept, X-Tenant"/>
+		<cache scope="orders"/>
+		<cache collector="true"/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 3) {
+		finder := newSeq.MediatorList[0].(artifacts.CacheMediator)
+		assert.Equal(t, "sequence->cache", finder.Position.Hierarchy)
+		assert.False(t, finder.Collector)
+		assert.Equal(t, 5*time.Minute, finder.TTL)
+		assert.Equal(t, []string{"Accept", "X-Tenant"}, finder.Headers)
+		assert.Equal(t, "testfile.xml:2", finder.Scope)
+
+		scoped := newSeq.MediatorList[1].(artifacts.CacheMediator)
+		assert.Equal(t, "orders", scoped.Scope)
+		assert.Equal(t, artifacts.DefaultCacheTTL, scoped.TTL)
+
+		assert.True(t, newSeq.MediatorList[2].(artifacts.CacheMediator).Collector)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "bad timeout", mediator: `<cache timeout="5"/>`, wantErr: "cache mediator in testfile.xml at line 1: timeout must be a positive duration such as 5m, got: 5"},
+		{name: "bad collector", mediator: `<cache collector="yes"/>`, wantErr: "collector must be true or false, got: yes"},
+		{name: "collector with timeout", mediator: `<cache collector="true" timeout="1m"/>`, wantErr: "a collector takes timeout, headers and scope from its finder"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalLDAPMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<ldap attributes="mail, memberOf"/>
+		<ldap user="headers['X-User']" attributes="cn" prefix="user."/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		lookup := newSeq.MediatorList[0].(artifacts.LDAPMediator)
+		assert.Equal(t, "sequence->ldap", lookup.Position.Hierarchy)
+		assert.Equal(t, "properties['AUTHENTICATED_USER']", lookup.User.String())
+		assert.Equal(t, []string{"mail", "memberOf"}, lookup.Attributes)
+		assert.Equal(t, artifacts.DefaultLDAPPrefix, lookup.Prefix)
+
+		prefixed := newSeq.MediatorList[1].(artifacts.LDAPMediator)
+		assert.Equal(t, "headers['X-User']", prefixed.User.String())
+		assert.Equal(t, "user.", prefixed.Prefix)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no attributes", mediator: `<ldap/>`, wantErr: "ldap mediator in testfile.xml at line 1: attributes must list at least one attribute"},
+		{name: "bad attribute", mediator: `<ldap attributes="mail,(cn)"/>`, wantErr: "invalid attribute name: (cn)"},
+		{name: "bad user", mediator: `<ldap user="headers[" attributes="mail"/>`, wantErr: "invalid user expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalDBMediators(t *testing.T) {
+	xmlData := `<sequence>
+		<dblookup datasource="customers">
+			<statement>
+				<sql>
+					SELECT name, tier FROM customers WHERE id = ?
+				</sql>
+				<parameter expression="payload.customerId" type="integer"/>
+				<result name="customer.name" column="name"/>
+				<result name="customer.tier" column="tier" type="integer"/>
+			</statement>
+		</dblookup>
+		<dbreport datasource="orders" useTransaction="true">
+			<statement>
+				<sql>INSERT INTO audit (order_id) VALUES (?)</sql>
+				<parameter expression="payload.id"/>
+			</statement>
+			<statement>
+				<sql>DELETE FROM drafts</sql>
+			</statement>
+		</dbreport>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		lookup := newSeq.MediatorList[0].(artifacts.DBLookupMediator)
+		assert.Equal(t, "sequence->dblookup", lookup.Position.Hierarchy)
+		assert.Equal(t, "customers", lookup.DataSource)
+		if assert.Len(t, lookup.Statements, 1) {
+			statement := lookup.Statements[0]
+			assert.Equal(t, "SELECT name, tier FROM customers WHERE id = ?", statement.SQL)
+			assert.Equal(t, "payload.customerId", statement.Parameters[0].Expression.String())
+			assert.Equal(t, artifacts.PropertyTypeInteger, statement.Parameters[0].Type)
+			assert.Equal(t, []artifacts.DBResult{
+				{Property: "customer.name", Column: "name"},
+				{Property: "customer.tier", Column: "tier", Type: artifacts.PropertyTypeInteger},
+			}, statement.Results)
+		}
+
+		report := newSeq.MediatorList[1].(artifacts.DBReportMediator)
+		assert.Equal(t, "orders", report.DataSource)
+		assert.True(t, report.UseTransaction)
+		assert.Len(t, report.Statements, 2)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no datasource", mediator: `<dblookup><statement><sql>SELECT 1</sql><result name="a" column="a"/></statement></dblookup>`, wantErr: "dblookup mediator in testfile.xml at line 1: datasource is required"},
+		{name: "no statement", mediator: `<dbreport datasource="orders"/>`, wantErr: "at least one statement is required"},
+		{name: "no sql", mediator: `<dbreport datasource="orders"><statement/></dbreport>`, wantErr: "statement 1: sql is required"},
+		{name: "lookup without result", mediator: `<dblookup datasource="orders"><statement><sql>SELECT 1</sql></statement></dblookup>`, wantErr: "at least one result is required"},
+		{name: "report with result", mediator: `<dbreport datasource="orders"><statement><sql>SELECT 1</sql><result name="a" column="a"/></statement></dbreport>`, wantErr: "results do not apply to dbreport"},
+		{name: "bad parameter", mediator: `<dbreport datasource="orders"><statement><sql>DELETE FROM a WHERE id = ?</sql><parameter expression="payload."/></statement></dbreport>`, wantErr: "parameter 1: invalid expression"},
+		{name: "bad type", mediator: `<dblookup datasource="orders"><statement><sql>SELECT 1</sql><result name="a" column="a" type="date"/></statement></dblookup>`, wantErr: "result a: type must be"},
+		{name: "bad transaction", mediator: `<dbreport datasource="orders" useTransaction="yes"><statement><sql>DELETE FROM a</sql></statement></dbreport>`, wantErr: "useTransaction must be true or false, got: yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalStoreMediator(t *testing.T) {
+	xmlData := `<sequence>
+		<store messageStore="OrdersStore" priority="payload.priority"/>
+		<store messageStore="AuditStore"/>
+	</sequence>`
+
+	decoder := xml.NewDecoder(strings.NewReader(xmlData))
+	sequence := &Sequence{}
+	newSeq, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+	assert.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		store := newSeq.MediatorList[0].(artifacts.StoreMediator)
+		assert.Equal(t, "sequence->store", store.Position.Hierarchy)
+		assert.Equal(t, "OrdersStore", store.MessageStore)
+		assert.Equal(t, "payload.priority", store.Priority.String())
+		assert.NotNil(t, store.Store)
+		assert.Nil(t, newSeq.MediatorList[1].(artifacts.StoreMediator).Priority)
+	}
+
+	tests := []struct {
+		name     string
+		mediator string
+		wantErr  string
+	}{
+		{name: "no store", mediator: `<store/>`, wantErr: "store mediator in testfile.xml at line 1: messageStore is required"},
+		{name: "bad priority", mediator: `<store messageStore="Q" priority="payload."/>`, wantErr: "invalid priority expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(`<sequence>` + tt.mediator + `</sequence>`))
+			_, err := sequence.unmarshal(decoder, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnmarshalSequenceMediator(t *testing.T) {
+	xmlData := `<sequence name="Orders">
+		<sequence key="CommonHeaders"/>
+		<log level="simple"/>
+	</sequence>`
+	newSeq, err := (&Sequence{}).Unmarshal(xmlData, artifacts.Position{FileName: "testfile.xml"})
+	require.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		reference := newSeq.MediatorList[0].(artifacts.SequenceMediator)
+		assert.Equal(t, "CommonHeaders", reference.Key)
+		assert.Equal(t, "Orders->sequence->sequence", reference.Position.Hierarchy)
+		assert.IsType(t, artifacts.LogMediator{}, newSeq.MediatorList[1])
+	}
+
+	// A reference first in an inSequence is not taken for a nested sequence
+	api, err := (&API{}).Unmarshal(`<api context="/orders" name="OrdersAPI">
+		<resource methods="GET" uri-template="/"><inSequence><sequence key="CommonHeaders"/><log/></inSequence></resource>
+	</api>`, artifacts.Position{FileName: "testfile.xml"})
+	require.NoError(t, err)
+	if assert.Len(t, api.Resources, 1) && assert.Len(t, api.Resources[0].InSequence.MediatorList, 2) {
+		assert.Equal(t, "CommonHeaders", api.Resources[0].InSequence.MediatorList[0].(artifacts.SequenceMediator).Key)
+	}
+}
+
+func TestUnmarshalXSLTMediator(t *testing.T) {
+	newSeq, err := (&Sequence{}).Unmarshal(`<sequence name="Orders">
+		<xslt key="transforms/order.xsl">
+			<property name="channel" value="mobile"/>
+		</xslt>
+	</sequence>`, artifacts.Position{FileName: "testfile.xml"})
+	require.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 1) {
+		mediator := newSeq.MediatorList[0].(artifacts.XSLTMediator)
+		assert.Equal(t, "transforms/order.xsl", mediator.Key)
+		assert.Equal(t, map[string]string{"channel": "mobile"}, mediator.Params)
+		assert.Equal(t, "Orders->sequence->xslt", mediator.Position.Hierarchy)
+	}
+
+	testCases := []struct {
+		xml      string
+		expected string
+	}{
+		{`<xslt/>`, "missing required attribute 'key'"},
+		{`<xslt key="../secrets.xsl"/>`, "key must be a path below the registry, got: ../secrets.xsl"},
+		{`<xslt key="/etc/order.xsl"/>`, "key must be a path below the registry"},
+		{`<xslt key="order.xsl"><property value="x"/></xslt>`, "property is missing required attribute 'name'"},
+		{`<xslt key="order.xsl"><property name="a" value="1"/><property name="a" value="2"/></xslt>`, "duplicate property 'a'"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.xml, func(t *testing.T) {
+			_, err := (&Sequence{}).Unmarshal(`<sequence name="Orders">`+tc.xml+`</sequence>`, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestUnmarshalJSONTransformMediator(t *testing.T) {
+	newSeq, err := (&Sequence{}).Unmarshal(`<sequence name="Orders">
+		<jsontransform><![CDATA[
+			{id: .order.id, tenant: $params.tenant, lines: [.order.items[] | select(.qty > 0)]}
+		]]></jsontransform>
+	</sequence>`, artifacts.Position{FileName: "testfile.xml"})
+	require.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 1) {
+		mediator := newSeq.MediatorList[0].(artifacts.JSONTransformMediator)
+		assert.Equal(t, "{id: .order.id, tenant: $params.tenant, lines: [.order.items[] | select(.qty > 0)]}", mediator.Program.String())
+		assert.Equal(t, "Orders->sequence->jsontransform", mediator.Position.Hierarchy)
+	}
+
+	testCases := []struct {
+		xml      string
+		expected string
+	}{
+		{`<jsontransform/>`, "missing jq program"},
+		{`<jsontransform>{id: .id</jsontransform>`, "invalid jq program"},
+		{`<jsontransform>$payload.id</jsontransform>`, "$payload is not defined"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.xml, func(t *testing.T) {
+			_, err := (&Sequence{}).Unmarshal(`<sequence name="Orders">`+tc.xml+`</sequence>`, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestUnmarshalAssertMediator(t *testing.T) {
+	newSeq, err := (&Sequence{}).Unmarshal(`<sequence name="Orders">
+		<assert expression="payload.order.id != nil" message="orders have an id"/>
+		<assert expression="headers['x-tenant']" equals="'acme'"/>
+	</sequence>`, artifacts.Position{FileName: "testfile.xml"})
+	require.NoError(t, err)
+	if assert.Len(t, newSeq.MediatorList, 2) {
+		truth := newSeq.MediatorList[0].(artifacts.AssertMediator)
+		assert.Equal(t, "payload.order.id != nil", truth.Expression.String())
+		assert.Nil(t, truth.Equals)
+		assert.Equal(t, "orders have an id", truth.Message)
+		assert.Equal(t, "Orders->sequence->assert", truth.Position.Hierarchy)
+		equality := newSeq.MediatorList[1].(artifacts.AssertMediator)
+		assert.Equal(t, "'acme'", equality.Equals.String())
+	}
+
+	testCases := []struct {
+		xml      string
+		expected string
+	}{
+		{`<assert/>`, "missing required attribute 'expression'"},
+		{`<assert expression="payload.id ==="/>`, "assert mediator in testfile.xml"},
+		{`<assert expression="payload.id" equals="body.id"/>`, "equals:"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.xml, func(t *testing.T) {
+			_, err := (&Sequence{}).Unmarshal(`<sequence name="Orders">`+tc.xml+`</sequence>`, artifacts.Position{FileName: "testfile.xml"})
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/signature"
+)
+
+type SignMediator struct {
+	XMLName     xml.Name `xml:"sign"`
+	Format      string   `xml:"format,attr"`
+	Key         string   `xml:"key,attr"`
+	Certificate string   `xml:"certificate,attr"`
+	Algorithm   string   `xml:"algorithm,attr"`
+	Target      string   `xml:"target,attr"`
+	Header      string   `xml:"header,attr"`
+}
+
+func (signMediator SignMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&signMediator, &start); err != nil {
+		return artifacts.SignMediator{}, errors.New("error in unmarshalling sign mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->sign"
+
+	format, target, header, err := parseSignatureOptions("sign", signMediator.Format, signMediator.Key, signMediator.Target, signMediator.Header, position)
+	if err != nil {
+		return artifacts.SignMediator{}, err
+	}
+	return artifacts.SignMediator{
+		Format:           format,
+		KeyAlias:         signMediator.Key,
+		CertificateAlias: signMediator.Certificate,
+		Algorithm:        signMediator.Algorithm,
+		Target:           target,
+		Header:           header,
+		Position:         position,
+	}, nil
+}
+
+type VerifyMediator struct {
+	XMLName    xml.Name `xml:"verify"`
+	Format     string   `xml:"format,attr"`
+	Key        string   `xml:"key,attr"`
+	Algorithms string   `xml:"algorithms,attr"`
+	Source     string   `xml:"source,attr"`
+	Header     string   `xml:"header,attr"`
+}
+
+func (verifyMediator VerifyMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&verifyMediator, &start); err != nil {
+		return artifacts.VerifyMediator{}, errors.New("error in unmarshalling verify mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->verify"
+
+	format, source, header, err := parseSignatureOptions("verify", verifyMediator.Format, verifyMediator.Key, verifyMediator.Source, verifyMediator.Header, position)
+	if err != nil {
+		return artifacts.VerifyMediator{}, err
+	}
+	var algorithms []string
+	for _, algorithm := range strings.Split(verifyMediator.Algorithms, ",") {
+		if algorithm = strings.TrimSpace(algorithm); algorithm != "" {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return artifacts.VerifyMediator{
+		Format:     format,
+		KeyAlias:   verifyMediator.Key,
+		Algorithms: algorithms,
+		Source:     source,
+		Header:     header,
+		Position:   position,
+	}, nil
+}
+
+// parseSignatureOptions validates the attributes shared by the sign and verify
+// mediators and applies their defaults
+func parseSignatureOptions(mediator, format, key, location, header string, position artifacts.Position) (string, string, string, error) {
+	if format == "" {
+		format = signature.FormatJWS
+	}
+	if format != signature.FormatJWS && format != signature.FormatXMLDSig {
+		return "", "", "", fmt.Errorf("%s mediator in %s at line %d: format must be 'jws' or 'xmldsig', got: %s", mediator, position.FileName, position.LineNo, format)
+	}
+	if key == "" {
+		return "", "", "", fmt.Errorf("%s mediator in %s at line %d: missing required attribute 'key'", mediator, position.FileName, position.LineNo)
+	}
+	if location == "" {
+		location = artifacts.SignatureTargetPayload
+	}
+	if location != artifacts.SignatureTargetPayload && location != artifacts.SignatureTargetHeader {
+		return "", "", "", fmt.Errorf("%s mediator in %s at line %d: signature location must be 'payload' or 'header', got: %s", mediator, position.FileName, position.LineNo, location)
+	}
+	if location == artifacts.SignatureTargetHeader && format != signature.FormatJWS {
+		return "", "", "", fmt.Errorf("%s mediator in %s at line %d: detached signatures in a header require format 'jws'", mediator, position.FileName, position.LineNo)
+	}
+	if header == "" {
+		header = artifacts.DefaultSignatureHeader
+	}
+	return format, location, header, nil
+}
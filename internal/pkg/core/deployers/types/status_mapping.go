@@ -0,0 +1,91 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// parseStatusMapping reads the attributes of a <statusMap> element of an
+// endpoint or API, e.g.
+// <statusMap from="404" to="200" payload="[]" contentType="application/json"/>
+// <statusMap from="502-504" to="503" payload='{"error":"backend unavailable"}' contentType="application/json"/>
+// from lists statuses, ranges and classes such as 5xx, separated by commas.
+// Without a payload attribute the backend's body is kept.
+func parseStatusMapping(elem xml.StartElement) (artifacts.StatusMapping, error) {
+	var mapping artifacts.StatusMapping
+	from, to := attribute(elem, "from"), attribute(elem, "to")
+	if from == "" || to == "" {
+		return artifacts.StatusMapping{}, fmt.Errorf("statusMap requires from and to")
+	}
+	for _, part := range strings.Split(from, ",") {
+		statusRange, err := parseStatusRange(strings.TrimSpace(part))
+		if err != nil {
+			return artifacts.StatusMapping{}, fmt.Errorf("statusMap from: %w", err)
+		}
+		mapping.From = append(mapping.From, statusRange)
+	}
+	status, err := strconv.Atoi(to)
+	if err != nil || status < 100 || status > 599 {
+		return artifacts.StatusMapping{}, fmt.Errorf("statusMap to must be a status between 100 and 599, got: %s", to)
+	}
+	mapping.To = status
+	for _, attr := range elem.Attr {
+		if attr.Name.Local == "payload" {
+			mapping.Payload = []byte(attr.Value)
+		}
+	}
+	mapping.ContentType = attribute(elem, "contentType")
+	if mapping.ContentType != "" && mapping.Payload == nil {
+		return artifacts.StatusMapping{}, fmt.Errorf("statusMap contentType applies to a payload only")
+	}
+	return mapping, nil
+}
+
+// parseStatusRange reads a status such as 404, a range such as 502-504 or a
+// class such as 5xx
+func parseStatusRange(value string) (artifacts.StatusRange, error) {
+	invalid := fmt.Errorf("expected a status, a range such as 502-504 or a class such as 5xx, got: '%s'", value)
+	if class, ok := strings.CutSuffix(strings.ToLower(value), "xx"); ok {
+		digit, err := strconv.Atoi(class)
+		if err != nil || digit < 1 || digit > 5 {
+			return artifacts.StatusRange{}, invalid
+		}
+		return artifacts.StatusRange{Min: digit * 100, Max: digit*100 + 99}, nil
+	}
+	low, high, isRange := strings.Cut(value, "-")
+	if !isRange {
+		high = low
+	}
+	min, err := strconv.Atoi(low)
+	if err != nil {
+		return artifacts.StatusRange{}, invalid
+	}
+	max, err := strconv.Atoi(high)
+	if err != nil || min < 100 || max > 599 || min > max {
+		return artifacts.StatusRange{}, invalid
+	}
+	return artifacts.StatusRange{Min: min, Max: max}, nil
+}
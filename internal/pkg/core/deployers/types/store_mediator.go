@@ -0,0 +1,63 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/messagestore"
+)
+
+// StoreMediator is the XML form of the store mediator, e.g.
+// <store messageStore="OrdersStore" priority="payload.priority"/>
+type StoreMediator struct {
+	XMLName      xml.Name `xml:"store"`
+	MessageStore string   `xml:"messageStore,attr"`
+	Priority     string   `xml:"priority,attr"`
+}
+
+func (storeMediator StoreMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&storeMediator, &start); err != nil {
+		return artifacts.StoreMediator{}, errors.New("error in unmarshalling store mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->store"
+	m := storeMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("store mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	if m.MessageStore == "" {
+		return artifacts.StoreMediator{}, invalid("messageStore is required")
+	}
+	mediator := artifacts.StoreMediator{MessageStore: m.MessageStore, Store: messagestore.StoreMessage, Position: position}
+	if m.Priority != "" {
+		program, err := expression.Compile(m.Priority, artifacts.ExpressionVariables...)
+		if err != nil {
+			return artifacts.StoreMediator{}, invalid("invalid priority expression: %v", err)
+		}
+		mediator.Priority = program
+	}
+	return mediator, nil
+}
@@ -0,0 +1,139 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// SwitchMediator is the XML form of the switch mediator, e.g.
+//
+//	<switch source="payload.tier">
+//	    <case regex="gold|platinum">
+//	        <property name="priority" value="high"/>
+//	    </case>
+//	    <default>
+//	        <property name="priority" value="normal"/>
+//	    </default>
+//	</switch>
+type SwitchMediator struct{}
+
+func (SwitchMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	position.Hierarchy = position.Hierarchy + "->switch"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("switch mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	mediator := artifacts.SwitchMediator{Position: position}
+	for _, attr := range start.Attr {
+		if attr.Name.Local != "source" {
+			continue
+		}
+		program, err := expression.Compile(attr.Value, artifacts.ExpressionVariables...)
+		if err != nil {
+			return artifacts.SwitchMediator{}, invalid("%v", err)
+		}
+		mediator.Source = program
+	}
+	if mediator.Source == nil {
+		return artifacts.SwitchMediator{}, invalid("missing required attribute 'source'")
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return artifacts.SwitchMediator{}, invalid("%v", err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "case":
+				var regex string
+				for _, attr := range element.Attr {
+					if attr.Name.Local == "regex" {
+						regex = attr.Value
+					}
+				}
+				if regex == "" {
+					return artifacts.SwitchMediator{}, invalid("case is missing required attribute 'regex'")
+				}
+				compiled, err := artifacts.CompileSwitchRegex(regex)
+				if err != nil {
+					return artifacts.SwitchMediator{}, invalid("invalid case regex '%s': %v", regex, err)
+				}
+				branch, err := unmarshalBranch(d, position, "case")
+				if err != nil {
+					return artifacts.SwitchMediator{}, err
+				}
+				mediator.Cases = append(mediator.Cases, artifacts.SwitchCase{Regex: compiled, Sequence: branch})
+			case "default":
+				if mediator.Default != nil {
+					return artifacts.SwitchMediator{}, invalid("only one default is allowed")
+				}
+				branch, err := unmarshalBranch(d, position, "default")
+				if err != nil {
+					return artifacts.SwitchMediator{}, err
+				}
+				mediator.Default = &branch
+			default:
+				return artifacts.SwitchMediator{}, invalid("unexpected element '%s', expected case or default", element.Name.Local)
+			}
+		case xml.EndElement:
+			if len(mediator.Cases) == 0 {
+				return artifacts.SwitchMediator{}, invalid("must declare at least one case")
+			}
+			return mediator, nil
+		}
+	}
+}
+
+// unmarshalBranch decodes the mediators of a branch up to its end element and
+// compiles them, as the branch is not a deployed sequence
+func unmarshalBranch(d *xml.Decoder, position artifacts.Position, branch string) (artifacts.Sequence, error) {
+	position.Hierarchy = position.Hierarchy + "->" + branch
+	sequence := artifacts.Sequence{Position: position}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return artifacts.Sequence{}, err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			line, _ := d.InputPos()
+			mediator, ok, err := unmarshalMediator(d, element, artifacts.Position{LineNo: line, FileName: position.FileName, Hierarchy: position.Hierarchy})
+			if err != nil {
+				return artifacts.Sequence{}, err
+			}
+			if !ok {
+				if err := d.Skip(); err != nil {
+					return artifacts.Sequence{}, err
+				}
+				continue
+			}
+			sequence.MediatorList = append(sequence.MediatorList, mediator)
+		case xml.EndElement:
+			sequence.Compile()
+			return sequence, nil
+		}
+	}
+}
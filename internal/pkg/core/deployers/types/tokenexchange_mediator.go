@@ -0,0 +1,98 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/tokenexchange"
+)
+
+type TokenExchangeMediator struct {
+	XMLName         xml.Name `xml:"tokenExchange"`
+	TokenType       string   `xml:"tokenType,attr"`
+	Header          string   `xml:"header,attr"`
+	VerificationKey string   `xml:"verificationKey,attr"`
+	Algorithms      string   `xml:"algorithms,attr"`
+	Issuer          string   `xml:"issuer,attr"`
+	Audience        string   `xml:"audience,attr"`
+	Endpoint        string   `xml:"endpoint,attr"`
+	ClientID        string   `xml:"clientId,attr"`
+	ClientSecret    string   `xml:"clientSecret,attr"`
+	TargetAudience  string   `xml:"targetAudience,attr"`
+	Scope           string   `xml:"scope,attr"`
+	Property        string   `xml:"property,attr"`
+}
+
+func (tokenExchangeMediator TokenExchangeMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&tokenExchangeMediator, &start); err != nil {
+		return artifacts.TokenExchangeMediator{}, errors.New("error in unmarshalling tokenExchange mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->tokenExchange"
+	m := tokenExchangeMediator
+
+	if m.TokenType == "" {
+		m.TokenType = tokenexchange.TokenTypeJWT
+	}
+	if m.TokenType != tokenexchange.TokenTypeJWT && m.TokenType != tokenexchange.TokenTypeSAML2 {
+		return artifacts.TokenExchangeMediator{}, fmt.Errorf("tokenExchange mediator in %s at line %d: tokenType must be 'jwt' or 'saml2', got: %s", position.FileName, position.LineNo, m.TokenType)
+	}
+	if m.VerificationKey == "" {
+		return artifacts.TokenExchangeMediator{}, fmt.Errorf("tokenExchange mediator in %s at line %d: missing required attribute 'verificationKey'", position.FileName, position.LineNo)
+	}
+	if endpoint, err := url.Parse(m.Endpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return artifacts.TokenExchangeMediator{}, fmt.Errorf("tokenExchange mediator in %s at line %d: endpoint must be an http(s) URL, got: %s", position.FileName, position.LineNo, m.Endpoint)
+	}
+	if m.Header == "" {
+		m.Header = "Authorization"
+	}
+	if m.Property == "" {
+		m.Property = artifacts.DefaultExchangedTokenProperty
+	}
+	var algorithms []string
+	for _, algorithm := range strings.Split(m.Algorithms, ",") {
+		if algorithm = strings.TrimSpace(algorithm); algorithm != "" {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+
+	return artifacts.TokenExchangeMediator{
+		TokenType:         m.TokenType,
+		Header:            m.Header,
+		VerificationKey:   m.VerificationKey,
+		Algorithms:        algorithms,
+		Issuer:            m.Issuer,
+		Audience:          m.Audience,
+		Endpoint:          m.Endpoint,
+		ClientID:          m.ClientID,
+		ClientSecretAlias: m.ClientSecret,
+		TargetAudience:    m.TargetAudience,
+		Scope:             m.Scope,
+		Property:          m.Property,
+		Exchanger:         tokenexchange.NewExchanger(nil),
+		Position:          position,
+	}, nil
+}
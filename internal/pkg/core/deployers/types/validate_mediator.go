@@ -0,0 +1,107 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+type ValidateRequestMediator struct {
+	XMLName       xml.Name              `xml:"validateRequest"`
+	ContentTypes  string                `xml:"contentTypes,attr"`
+	Headers       []RequiredHeader      `xml:"header"`
+	Preconditions []RequestPrecondition `xml:"precondition"`
+}
+
+type RequiredHeader struct {
+	Name    string `xml:"name,attr"`
+	Pattern string `xml:"pattern,attr"`
+}
+
+// RequestPrecondition holds a template expression without the surrounding
+// braces, such as: eq (.Header "X-Api-Version") "2"
+type RequestPrecondition struct {
+	Expression string `xml:"expression,attr"`
+	Message    string `xml:"message,attr"`
+}
+
+func (validateRequestMediator ValidateRequestMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&validateRequestMediator, &start); err != nil {
+		return artifacts.ValidateRequestMediator{}, errors.New("error in unmarshalling validateRequest mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->validateRequest"
+	m := validateRequestMediator
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("validateRequest mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	mediator := artifacts.ValidateRequestMediator{Position: position}
+	for _, contentType := range strings.Split(m.ContentTypes, ",") {
+		contentType = strings.TrimSpace(contentType)
+		if contentType == "" {
+			continue
+		}
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || !strings.Contains(mediaType, "/") {
+			return artifacts.ValidateRequestMediator{}, invalid("invalid content type '%s'", contentType)
+		}
+		mediator.ContentTypes = append(mediator.ContentTypes, contentType)
+	}
+	for _, header := range m.Headers {
+		if header.Name == "" {
+			return artifacts.ValidateRequestMediator{}, invalid("header requires a name")
+		}
+		required := artifacts.RequiredHeader{Name: header.Name}
+		if header.Pattern != "" {
+			pattern, err := regexp.Compile(header.Pattern)
+			if err != nil {
+				return artifacts.ValidateRequestMediator{}, invalid("invalid pattern for header %s: %v", header.Name, err)
+			}
+			required.Pattern = pattern
+		}
+		mediator.Headers = append(mediator.Headers, required)
+	}
+	for _, precondition := range m.Preconditions {
+		if precondition.Expression == "" {
+			return artifacts.ValidateRequestMediator{}, invalid("precondition requires an expression")
+		}
+		expression, err := template.New("precondition").Funcs(artifacts.PreconditionFuncs).Parse("{{" + precondition.Expression + "}}")
+		if err != nil {
+			return artifacts.ValidateRequestMediator{}, invalid("invalid precondition '%s': %v", precondition.Expression, err)
+		}
+		message := precondition.Message
+		if message == "" {
+			message = "precondition failed: " + precondition.Expression
+		}
+		mediator.Preconditions = append(mediator.Preconditions, artifacts.Precondition{Expression: expression, Message: message})
+	}
+	if len(mediator.ContentTypes) == 0 && len(mediator.Headers) == 0 && len(mediator.Preconditions) == 0 {
+		return artifacts.ValidateRequestMediator{}, invalid("must declare content types, headers or preconditions")
+	}
+	return mediator, nil
+}
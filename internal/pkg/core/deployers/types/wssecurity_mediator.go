@@ -0,0 +1,130 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/wssecurity"
+)
+
+type WSSecurityMediator struct {
+	XMLName              xml.Name         `xml:"wsSecurity"`
+	Action               string           `xml:"action,attr"`
+	Username             string           `xml:"username,attr"`
+	Password             string           `xml:"password,attr"`
+	PasswordType         string           `xml:"passwordType,attr"`
+	TimestampTTL         string           `xml:"timestampTTL,attr"`
+	RequireUsernameToken string           `xml:"requireUsernameToken,attr"`
+	RequireTimestamp     string           `xml:"requireTimestamp,attr"`
+	ClockSkew            string           `xml:"clockSkew,attr"`
+	Users                []WSSecurityUser `xml:"user"`
+}
+
+type WSSecurityUser struct {
+	Name     string `xml:"name,attr"`
+	Password string `xml:"password,attr"`
+}
+
+func (wsSecurityMediator WSSecurityMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&wsSecurityMediator, &start); err != nil {
+		return artifacts.WSSecurityMediator{}, errors.New("error in unmarshalling wsSecurity mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->wsSecurity"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("wsSecurity mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+
+	// Durations are given in seconds
+	seconds := func(name, value string) (time.Duration, error) {
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, invalid("%s must be a non-negative number of seconds, got: %s", name, value)
+		}
+		return time.Duration(n) * time.Second, nil
+	}
+	flag := func(name, value string) (bool, error) {
+		if value == "" {
+			return false, nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, invalid("%s must be either 'true' or 'false', got: %s", name, value)
+		}
+		return b, nil
+	}
+
+	switch wsSecurityMediator.Action {
+	case artifacts.WSSecurityActionAdd:
+		if wsSecurityMediator.PasswordType != "" && wsSecurityMediator.PasswordType != wssecurity.PasswordText && wsSecurityMediator.PasswordType != wssecurity.PasswordDigest {
+			return artifacts.WSSecurityMediator{}, invalid("passwordType must be 'PasswordText' or 'PasswordDigest', got: %s", wsSecurityMediator.PasswordType)
+		}
+		ttl, err := seconds("timestampTTL", wsSecurityMediator.TimestampTTL)
+		if err != nil {
+			return artifacts.WSSecurityMediator{}, err
+		}
+		if wsSecurityMediator.Username == "" && ttl == 0 {
+			return artifacts.WSSecurityMediator{}, invalid("a username or a timestampTTL is required")
+		}
+		return artifacts.WSSecurityMediator{
+			Action:        artifacts.WSSecurityActionAdd,
+			Username:      wsSecurityMediator.Username,
+			PasswordAlias: wsSecurityMediator.Password,
+			PasswordType:  wsSecurityMediator.PasswordType,
+			TimestampTTL:  ttl,
+			Position:      position,
+		}, nil
+	case artifacts.WSSecurityActionValidate:
+		requireUsernameToken, err := flag("requireUsernameToken", wsSecurityMediator.RequireUsernameToken)
+		if err != nil {
+			return artifacts.WSSecurityMediator{}, err
+		}
+		requireTimestamp, err := flag("requireTimestamp", wsSecurityMediator.RequireTimestamp)
+		if err != nil {
+			return artifacts.WSSecurityMediator{}, err
+		}
+		clockSkew, err := seconds("clockSkew", wsSecurityMediator.ClockSkew)
+		if err != nil {
+			return artifacts.WSSecurityMediator{}, err
+		}
+		users := make(map[string]string, len(wsSecurityMediator.Users))
+		for _, user := range wsSecurityMediator.Users {
+			if user.Name == "" || user.Password == "" {
+				return artifacts.WSSecurityMediator{}, invalid("user entries need a name and a password alias")
+			}
+			users[user.Name] = user.Password
+		}
+		return artifacts.WSSecurityMediator{
+			Action:    artifacts.WSSecurityActionValidate,
+			Users:     users,
+			Validator: artifacts.NewWSSecurityValidator(users, requireUsernameToken, requireTimestamp, clockSkew),
+			Position:  position,
+		}, nil
+	}
+	return artifacts.WSSecurityMediator{}, invalid("action must be 'add' or 'validate', got: %s", wsSecurityMediator.Action)
+}
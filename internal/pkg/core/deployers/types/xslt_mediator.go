@@ -0,0 +1,77 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package types
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/xslt"
+)
+
+// XSLTMediator is the XML form of the xslt mediator, e.g.
+//
+//	<xslt key="transforms/order.xsl">
+//	    <property name="channel" value="mobile"/>
+//	</xslt>
+//
+// The key is the path of the stylesheet below artifacts/Registry; properties
+// set its top-level parameters.
+type XSLTMediator struct {
+	XMLName    xml.Name `xml:"xslt"`
+	Key        string   `xml:"key,attr"`
+	Properties []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"property"`
+}
+
+func (xsltMediator XSLTMediator) Unmarshal(d *xml.Decoder, start xml.StartElement, position artifacts.Position) (artifacts.Mediator, error) {
+	if err := d.DecodeElement(&xsltMediator, &start); err != nil {
+		return artifacts.XSLTMediator{}, errors.New("error in unmarshalling xslt mediator in " + position.FileName + " at line " + strconv.Itoa(position.LineNo))
+	}
+	position.Hierarchy = position.Hierarchy + "->xslt"
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("xslt mediator in %s at line %d: %s", position.FileName, position.LineNo, fmt.Sprintf(format, args...))
+	}
+	if xsltMediator.Key == "" {
+		return artifacts.XSLTMediator{}, invalid("missing required attribute 'key'")
+	}
+	if !xslt.ValidKey(xsltMediator.Key) {
+		return artifacts.XSLTMediator{}, invalid("key must be a path below the registry, got: %s", xsltMediator.Key)
+	}
+	mediator := artifacts.XSLTMediator{Key: xsltMediator.Key, Position: position}
+	for _, property := range xsltMediator.Properties {
+		if property.Name == "" {
+			return artifacts.XSLTMediator{}, invalid("property is missing required attribute 'name'")
+		}
+		if _, ok := mediator.Params[property.Name]; ok {
+			return artifacts.XSLTMediator{}, invalid("duplicate property '%s'", property.Name)
+		}
+		if mediator.Params == nil {
+			mediator.Params = make(map[string]string)
+		}
+		mediator.Params[property.Name] = property.Value
+	}
+	return mediator, nil
+}
@@ -0,0 +1,84 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package dryrun lets mediators that call out to other systems report the call
+// instead of making it, so replays can show their side effects safely.
+package dryrun
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Property holds the *Recorder of a message mediated in dry-run mode
+const Property = "DRY_RUN"
+
+// Call is an outbound call a mediator would have made
+type Call struct {
+	Mediator string `json:"mediator"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+}
+
+// Recorder collects the outbound calls skipped during a dry run
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Record notes a skipped outbound call
+func (r *Recorder) Record(call Call) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+// Calls returns the recorded calls
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// AddToContext puts the message in dry-run mode
+func AddToContext(msgContext *synctx.MsgContext, recorder *Recorder) {
+	msgContext.Properties[Property] = recorder
+}
+
+// FromContext returns the recorder of a message in dry-run mode, or nil
+func FromContext(msgContext *synctx.MsgContext) *Recorder {
+	recorder, _ := msgContext.Properties[Property].(*Recorder)
+	return recorder
+}
+
+type recorderKey struct{}
+
+// WithRecorder attaches recorder to a request context, for the handler that
+// creates the message context
+func WithRecorder(ctx context.Context, recorder *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, recorder)
+}
+
+// FromRequestContext returns the recorder attached by WithRecorder, or nil
+func FromRequestContext(ctx context.Context) *Recorder {
+	recorder, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return recorder
+}
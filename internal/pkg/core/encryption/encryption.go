@@ -0,0 +1,173 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package encryption protects message payloads end-to-end, either as JWE
+// compact serializations or as AES-GCM ciphertexts under a shared key. Whole
+// payloads or individual JSON and XML fields can be encrypted.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const (
+	FormatJWE    = "jwe"
+	FormatAESGCM = "aes-gcm"
+)
+
+// IsSymmetric reports whether the JWE key management algorithm uses a shared key
+func IsSymmetric(keyAlgorithm string) bool {
+	return keyAlgorithm == string(jose.DIRECT) || strings.HasPrefix(keyAlgorithm, "A")
+}
+
+// defaultAlgorithms picks the JWE key management and content encryption
+// algorithms matching the key type
+func defaultAlgorithms(key any) (jose.KeyAlgorithm, jose.ContentEncryption, error) {
+	switch k := key.(type) {
+	case []byte:
+		switch len(k) {
+		case 16:
+			return jose.DIRECT, jose.A128GCM, nil
+		case 24:
+			return jose.DIRECT, jose.A192GCM, nil
+		case 32:
+			return jose.DIRECT, jose.A256GCM, nil
+		}
+		return "", "", fmt.Errorf("shared keys must be 16, 24 or 32 bytes, got %d", len(k))
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return jose.RSA_OAEP_256, jose.A256GCM, nil
+	case *ecdsa.PublicKey, *ecdsa.PrivateKey:
+		return jose.ECDH_ES_A256KW, jose.A256GCM, nil
+	}
+	return "", "", fmt.Errorf("unsupported key type %T", key)
+}
+
+// EncryptJWE encrypts plaintext for the recipient key and returns the JWE
+// compact serialization. Empty algorithms are inferred from the key, and
+// contentType is recorded in the cty header so it can be restored.
+func EncryptJWE(plaintext []byte, key any, keyAlgorithm, contentEncryption, contentType string) (string, error) {
+	defaultKeyAlgorithm, defaultEncryption, err := defaultAlgorithms(key)
+	if err != nil {
+		return "", err
+	}
+	alg, enc := jose.KeyAlgorithm(keyAlgorithm), jose.ContentEncryption(contentEncryption)
+	if alg == "" {
+		alg = defaultKeyAlgorithm
+	}
+	if enc == "" {
+		enc = defaultEncryption
+	}
+
+	options := &jose.EncrypterOptions{}
+	if contentType != "" {
+		options = options.WithContentType(jose.ContentType(contentType))
+	}
+	encrypter, err := jose.NewEncrypter(enc, jose.Recipient{Algorithm: alg, Key: key}, options)
+	if err != nil {
+		return "", fmt.Errorf("cannot create JWE encrypter: %w", err)
+	}
+	object, err := encrypter.Encrypt(plaintext)
+	if err != nil {
+		return "", fmt.Errorf("cannot encrypt payload: %w", err)
+	}
+	return object.CompactSerialize()
+}
+
+// DecryptJWE decrypts a JWE compact serialization and returns the plaintext and
+// the content type recorded by the sender. Only the given key management
+// algorithms are accepted; when none are given the default for key is.
+func DecryptJWE(token string, key any, keyAlgorithms []string) ([]byte, string, error) {
+	defaultKeyAlgorithm, _, err := defaultAlgorithms(key)
+	if err != nil {
+		return nil, "", err
+	}
+	allowed := []jose.KeyAlgorithm{defaultKeyAlgorithm}
+	if len(keyAlgorithms) > 0 {
+		allowed = allowed[:0]
+		for _, algorithm := range keyAlgorithms {
+			allowed = append(allowed, jose.KeyAlgorithm(algorithm))
+		}
+	}
+	encryptions := []jose.ContentEncryption{
+		jose.A128GCM, jose.A192GCM, jose.A256GCM,
+		jose.A128CBC_HS256, jose.A192CBC_HS384, jose.A256CBC_HS512,
+	}
+
+	object, err := jose.ParseEncrypted(strings.TrimSpace(token), allowed, encryptions)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid JWE: %w", err)
+	}
+	plaintext, err := object.Decrypt(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("JWE decryption failed: %w", err)
+	}
+	contentType, _ := object.Header.ExtraHeaders[jose.HeaderContentType].(string)
+	return plaintext, contentType, nil
+}
+
+// EncryptAESGCM encrypts plaintext with a shared 16, 24 or 32 byte key and
+// returns the base64 encoded nonce followed by the ciphertext
+func EncryptAESGCM(plaintext, key []byte) (string, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// DecryptAESGCM reverses EncryptAESGCM
+func DecryptAESGCM(ciphertext string, key []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ciphertext))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AES-GCM ciphertext: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid AES-GCM ciphertext: too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("AES-GCM decryption failed: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AES key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,78 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWE(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	sharedKey := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := []byte(`{"card":"4111111111111111"}`)
+
+	testCases := []struct {
+		name       string
+		encryptKey any
+		decryptKey any
+	}{
+		{name: "RSA recipient", encryptKey: &rsaKey.PublicKey, decryptKey: rsaKey},
+		{name: "Shared key", encryptKey: sharedKey, decryptKey: sharedKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := EncryptJWE(plaintext, tc.encryptKey, "", "", "application/json")
+			assert.NoError(t, err)
+
+			decrypted, contentType, err := DecryptJWE(token, tc.decryptKey, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, plaintext, decrypted)
+			assert.Equal(t, "application/json", contentType)
+		})
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token, err := EncryptJWE(plaintext, &rsaKey.PublicKey, "", "", "")
+	assert.NoError(t, err)
+	_, _, err = DecryptJWE(token, otherKey, nil)
+	assert.Error(t, err)
+	_, _, err = DecryptJWE(token, rsaKey, []string{"RSA-OAEP"})
+	assert.Error(t, err)
+}
+
+func TestAESGCM(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	ciphertext, err := EncryptAESGCM([]byte("secret"), key)
+	assert.NoError(t, err)
+
+	plaintext, err := DecryptAESGCM(ciphertext, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	_, err = DecryptAESGCM(ciphertext, []byte("fedcba9876543210"))
+	assert.Error(t, err)
+	_, err = EncryptAESGCM([]byte("secret"), []byte("short"))
+	assert.Error(t, err)
+}
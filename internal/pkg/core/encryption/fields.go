@@ -0,0 +1,74 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package encryption
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FieldEncrypter encrypts individual JSON and XML fields with a masking
+// transform rule. JSON values are encrypted as their JSON encoding, so numbers
+// and objects survive a round trip; XML elements have their content encrypted.
+type FieldEncrypter struct {
+	// Encrypt turns plaintext into a string that is safe in JSON and XML text
+	Encrypt func(plaintext []byte) (string, error)
+}
+
+func (e FieldEncrypter) TransformJSON(value interface{}) (interface{}, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return e.Encrypt(plaintext)
+}
+
+func (e FieldEncrypter) TransformXML(content []byte) ([]byte, error) {
+	ciphertext, err := e.Encrypt(content)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(ciphertext), nil
+}
+
+// FieldDecrypter reverses FieldEncrypter
+type FieldDecrypter struct {
+	Decrypt func(ciphertext string) ([]byte, error)
+}
+
+func (d FieldDecrypter) TransformJSON(value interface{}) (interface{}, error) {
+	ciphertext, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted JSON field must be a string, got %T", value)
+	}
+	plaintext, err := d.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(plaintext, &decoded); err != nil {
+		return nil, fmt.Errorf("decrypted field is not valid JSON: %w", err)
+	}
+	return decoded, nil
+}
+
+func (d FieldDecrypter) TransformXML(content []byte) ([]byte, error) {
+	return d.Decrypt(string(content))
+}
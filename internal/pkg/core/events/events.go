@@ -0,0 +1,147 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package events publishes runtime lifecycle events to subscribers such as the
+// log, webhooks and the admin event stream.
+package events
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event types published by the runtime
+const (
+	ArtifactDeployed   = "artifact.deployed"
+	ArtifactUndeployed = "artifact.undeployed"
+	ArtifactStaged     = "artifact.staged"
+	EndpointSuspended  = "endpoint.suspended"
+	InboundStarted     = "inbound.started"
+	InboundStopped     = "inbound.stopped"
+	ThrottleBreached   = "throttle.breached"
+	SLOBreached        = "slo.breached"
+	SLORecovered       = "slo.recovered"
+	HealthCheckFailed  = "healthcheck.failed"
+	HealthCheckHealthy = "healthcheck.recovered"
+	ClientBanned       = "client.banned"
+
+	ProcessorActivated   = "processor.activated"
+	ProcessorDeactivated = "processor.deactivated"
+)
+
+// subscriberBuffer is the number of events queued per subscriber before new
+// events are dropped for it
+const subscriberBuffer = 256
+
+// Event is a structured runtime lifecycle event
+type Event struct {
+	Type string `json:"type"`
+	// Kind is the kind of artifact the event is about, e.g. "api" or "inbound"
+	Kind       string            `json:"kind,omitempty"`
+	Name       string            `json:"name"`
+	Time       time.Time         `json:"time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Subscription receives the events matching its types
+type Subscription struct {
+	C     <-chan Event
+	c     chan Event
+	types []string
+	bus   *Bus
+	// dropped counts events discarded because the subscriber fell behind
+	dropped int
+}
+
+// Close stops delivery to the subscription and closes its channel
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// Dropped returns the number of events discarded for this subscription
+func (s *Subscription) Dropped() int {
+	s.bus.mu.RLock()
+	defer s.bus.mu.RUnlock()
+	return s.dropped
+}
+
+// Bus fans events out to subscribers. Publishing never blocks: a subscriber
+// that falls behind loses events rather than delaying the runtime.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []*Subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe returns a subscription for the given event types, or for every
+// event when no type is given
+func (b *Bus) Subscribe(types ...string) *Subscription {
+	c := make(chan Event, subscriberBuffer)
+	subscription := &Subscription{C: c, c: c, types: types, bus: b}
+	b.mu.Lock()
+	b.subscriptions = append(b.subscriptions, subscription)
+	b.mu.Unlock()
+	return subscription
+}
+
+func (b *Bus) unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	index := slices.Index(b.subscriptions, subscription)
+	if index < 0 {
+		return
+	}
+	b.subscriptions = slices.Delete(b.subscriptions, index, index+1)
+	close(subscription.c)
+}
+
+// Publish delivers event to every matching subscription
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, subscription := range b.subscriptions {
+		if len(subscription.types) > 0 && !slices.Contains(subscription.types, event.Type) {
+			continue
+		}
+		select {
+		case subscription.c <- event:
+		default:
+			subscription.dropped++
+		}
+	}
+}
+
+var defaultBus = NewBus()
+
+// Default returns the bus shared by the runtime
+func Default() *Bus {
+	return defaultBus
+}
+
+// Publish publishes event on the default bus
+func Publish(event Event) {
+	defaultBus.Publish(event)
+}
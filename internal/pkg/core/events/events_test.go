@@ -0,0 +1,126 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+func TestBus_SubscribeAndFilter(t *testing.T) {
+	bus := NewBus()
+	all := bus.Subscribe()
+	inbound := bus.Subscribe(InboundStarted, InboundStopped)
+
+	bus.Publish(Event{Type: ArtifactDeployed, Kind: "api", Name: "OrdersAPI"})
+	bus.Publish(Event{Type: InboundStarted, Kind: "inbound", Name: "FileInbound"})
+
+	assert.Equal(t, "OrdersAPI", (<-all.C).Name)
+	event := <-all.C
+	assert.Equal(t, InboundStarted, event.Type)
+	assert.False(t, event.Time.IsZero(), "publish stamps the event time")
+	assert.Equal(t, "FileInbound", (<-inbound.C).Name)
+	assert.Len(t, inbound.C, 0)
+
+	inbound.Close()
+	_, open := <-inbound.C
+	assert.False(t, open)
+	inbound.Close()
+}
+
+func TestBus_SlowSubscriberDropsEvents(t *testing.T) {
+	bus := NewBus()
+	subscription := bus.Subscribe()
+	for range subscriberBuffer + 5 {
+		bus.Publish(Event{Type: ArtifactDeployed})
+	}
+	assert.Equal(t, 5, subscription.Dropped())
+	assert.Len(t, subscription.C, subscriberBuffer)
+}
+
+func TestPostEvents(t *testing.T) {
+	received := make(chan Event, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received <- event
+	}))
+	defer receiver.Close()
+
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := NewBus()
+	Config{Webhooks: []WebhookConfig{{URL: receiver.URL, Types: []string{SLOBreached}}}}.Start(ctx, bus, loggerfactory.GetLogger("events", nil))
+
+	bus.Publish(Event{Type: ArtifactDeployed, Name: "Ignored"})
+	bus.Publish(Event{Type: SLOBreached, Kind: "api", Name: "OrdersAPI", Attributes: map[string]string{"reason": "slow"}})
+
+	select {
+	case event := <-received:
+		assert.Equal(t, SLOBreached, event.Type)
+		assert.Equal(t, "OrdersAPI", event.Name)
+		assert.Equal(t, "slow", event.Attributes["reason"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook did not receive the event")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{Log: true, Webhooks: []WebhookConfig{{URL: "https://hooks.example.com"}}}.Validate())
+	assert.EqualError(t, Config{Webhooks: []WebhookConfig{{URL: "hooks.example.com"}}}.Validate(),
+		"events: webhook needs an http(s) url, got 'hooks.example.com'")
+}
+
+func TestStreamHandler(t *testing.T) {
+	bus := NewBus()
+	server := httptest.NewServer(StreamHandler(bus))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/?types=" + ArtifactDeployed
+	conn, err := websocket.Dial(wsURL, "", server.URL)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// Wait for the handler to subscribe before publishing
+	assert.Eventually(t, func() bool {
+		bus.mu.RLock()
+		defer bus.mu.RUnlock()
+		return len(bus.subscriptions) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	bus.Publish(Event{Type: InboundStarted, Name: "Filtered"})
+	bus.Publish(Event{Type: ArtifactDeployed, Kind: "api", Name: "OrdersAPI"})
+
+	var event Event
+	assert.NoError(t, websocket.JSON.Receive(conn, &event))
+	assert.Equal(t, ArtifactDeployed, event.Type)
+	assert.Equal(t, "OrdersAPI", event.Name)
+}
@@ -0,0 +1,68 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package events
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+// StreamHandler streams events from bus to WebSocket clients as JSON text
+// messages. The types query parameter takes a comma separated list of event
+// types to receive.
+func StreamHandler(bus *Bus) http.Handler {
+	return websocket.Server{
+		// Origin checks are left to the admin API's security policy
+		Handshake: func(config *websocket.Config, r *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+			var types []string
+			if param := conn.Request().URL.Query().Get("types"); param != "" {
+				types = strings.Split(param, ",")
+			}
+			subscription := bus.Subscribe(types...)
+			defer subscription.Close()
+
+			// The client never sends data; a failed read means it went away
+			closed := make(chan struct{})
+			go func() {
+				var discard []byte
+				for websocket.Message.Receive(conn, &discard) == nil {
+				}
+				close(closed)
+			}()
+
+			for {
+				select {
+				case <-closed:
+					return
+				case <-conn.Request().Context().Done():
+					return
+				case event := <-subscription.C:
+					if err := websocket.JSON.Send(conn, event); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+}
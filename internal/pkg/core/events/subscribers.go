@@ -0,0 +1,101 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+)
+
+// Config holds the [events] section of deployment.toml
+type Config struct {
+	// Log writes every event to the runtime log
+	Log      bool            `koanf:"log"`
+	Webhooks []WebhookConfig `koanf:"webhook"`
+}
+
+// WebhookConfig posts the listed event types, or every event, to a URL
+type WebhookConfig struct {
+	URL   string   `koanf:"url"`
+	Types []string `koanf:"types"`
+}
+
+// Validate reports configuration errors in the [events] section
+func (c Config) Validate() error {
+	for _, webhook := range c.Webhooks {
+		if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("events: webhook needs an http(s) url, got '%s'", webhook.URL)
+		}
+	}
+	return nil
+}
+
+// Start subscribes the configured log and webhook subscribers to bus until ctx is done
+func (c Config) Start(ctx context.Context, bus *Bus, logger *slog.Logger) {
+	if c.Log {
+		go LogEvents(ctx, bus.Subscribe(), logger)
+	}
+	for _, hook := range c.Webhooks {
+		go PostEvents(ctx, bus.Subscribe(hook.Types...), webhook.Default(), hook.URL)
+	}
+}
+
+// LogEvents writes events from subscription to logger until ctx is done
+func LogEvents(ctx context.Context, subscription *Subscription, logger *slog.Logger) {
+	defer subscription.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-subscription.C:
+			attrs := []any{slog.String("type", event.Type), slog.String("name", event.Name)}
+			if event.Kind != "" {
+				attrs = append(attrs, slog.String("kind", event.Kind))
+			}
+			for key, value := range event.Attributes {
+				attrs = append(attrs, slog.String(key, value))
+			}
+			logger.Info("Runtime event", attrs...)
+		}
+	}
+}
+
+// PostEvents hands each event from subscription as JSON to the dispatcher for
+// delivery to target until ctx is done
+func PostEvents(ctx context.Context, subscription *Subscription, dispatcher *webhook.Dispatcher, target string) {
+	defer subscription.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-subscription.C:
+			body, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			dispatcher.Enqueue(webhook.Request{URL: target, Source: webhook.SourceEvents, ContentType: "application/json", Payload: body})
+		}
+	}
+}
@@ -0,0 +1,314 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package expression compiles and evaluates expr-lang expressions
+// (github.com/expr-lang/expr) over message data, e.g.
+//
+//	len(payload.items) > 0 && headers['x-tenant'] != ''
+//	payload.total * 1.2
+//	properties.GEOIP_COUNTRY in ['US', 'CA'] ? 'domestic' : 'export'
+//
+// Besides the builtins of expr-lang, number(s) converts a string to a number
+// and functions generate values, e.g. uuid(), now('2006-01-02'),
+// randomInt(1, 6), base64encode(s), base64decode(s) and hmac('sha256', key, s).
+//
+// Expressions are compiled when an artifact is deployed, so syntax errors,
+// unknown variables and functions, and invalid regular expressions are
+// reported then. Variables hold the values of decoded JSON: nil, bool,
+// float64, string, []any and map[string]any. Missing map keys evaluate to nil.
+package expression
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/types"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Program is a compiled expression
+type Program struct {
+	source  string
+	program *vm.Program
+}
+
+// String returns the source of the expression
+func (p *Program) String() string {
+	return p.source
+}
+
+// Compile parses source, allowing only the named variables
+func Compile(source string, variables ...string) (*Program, error) {
+	env := make(types.Map, len(variables))
+	for _, name := range variables {
+		env[name] = types.Any
+	}
+	program, err := expr.Compile(source, append([]expr.Option{expr.Env(env)}, functions...)...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	return &Program{source: source, program: program}, nil
+}
+
+// Run evaluates the program with the variables of env
+func (p *Program) Run(env map[string]any) (any, error) {
+	variables := make(map[string]any, len(env))
+	for name, value := range env {
+		variables[name] = Normalize(value)
+	}
+	value, err := expr.Run(p.program, variables)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", p.source, err)
+	}
+	return Normalize(value), nil
+}
+
+// Truthy reports whether value counts as true in a guard: only true does
+func Truthy(value any) bool {
+	b, ok := value.(bool)
+	return ok && b
+}
+
+// Normalize converts Go values to the values of decoded JSON, e.g. ints to
+// float64 and map[string]string to map[string]any, so callers can put
+// message data in env as it is
+func Normalize(value any) any {
+	switch v := value.(type) {
+	case nil, bool, float64, string:
+		return v
+	case []any:
+		return v
+	case map[string]any:
+		return v
+	case map[string]string:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[key] = item
+		}
+		return m
+	case []string:
+		s := make([]any, len(v))
+		for i, item := range v {
+			s[i] = item
+		}
+		return s
+	case fmt.Stringer:
+		return v.String()
+	}
+	if number, ok := toNumber(value); ok {
+		return number
+	}
+	return value
+}
+
+func toNumber(value any) (float64, bool) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// typeName describes a value in error messages
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "map"
+	}
+	if _, ok := toNumber(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// Functions
+
+// function declares a function with the given signature, prefixing its
+// errors with its name
+func function(name string, call func(args ...any) (any, error), signature any) expr.Option {
+	return expr.Function(name, func(args ...any) (any, error) {
+		value, err := call(args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return value, nil
+	}, signature)
+}
+
+func stringArg(value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expects a string, got %s", typeName(value))
+	}
+	return s, nil
+}
+
+var functions = []expr.Option{
+	function("number", func(args ...any) (any, error) {
+		if number, ok := toNumber(args[0]); ok {
+			return number, nil
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot convert %s to a number", typeName(args[0]))
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to a number", s)
+		}
+		return number, nil
+	}, new(func(any) float64)),
+	function("uuid", func(args ...any) (any, error) {
+		return NewUUID()
+	}, new(func() string)),
+	// now formats the current UTC time with a Go layout, or returns the Unix
+	// time in seconds or milliseconds for 'unix' and 'unixMilli'. It replaces
+	// the builtin now() of expr-lang.
+	function("now", func(args ...any) (any, error) {
+		layout, err := stringArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		switch layout {
+		case "unix":
+			return float64(now.Unix()), nil
+		case "unixMilli":
+			return float64(now.UnixMilli()), nil
+		}
+		return now.Format(layout), nil
+	}, new(func(string) any)),
+	function("randomInt", func(args ...any) (any, error) {
+		low, lowOK := wholeInt64(args[0])
+		high, highOK := wholeInt64(args[1])
+		if !lowOK || !highOK {
+			return nil, fmt.Errorf("expects two whole numbers within the int64 range, got %s and %s", typeName(args[0]), typeName(args[1]))
+		}
+		n, err := RandomInt(low, high)
+		return float64(n), err
+	}, new(func(any, any) float64)),
+	function("base64encode", func(args ...any) (any, error) {
+		s, err := stringArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString([]byte(s)), nil
+	}, new(func(string) string)),
+	function("base64decode", func(args ...any) (any, error) {
+		s, err := stringArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64: %w", err)
+		}
+		return string(decoded), nil
+	}, new(func(string) string)),
+	// hmac signs a message with a key, e.g. hmac('sha256', key, payload.id),
+	// and returns the hex encoded digest
+	function("hmac", func(args ...any) (any, error) {
+		for _, arg := range args {
+			if _, err := stringArg(arg); err != nil {
+				return nil, err
+			}
+		}
+		return HMAC(args[0].(string), args[1].(string), args[2].(string))
+	}, new(func(string, string, string) string)),
+}
+
+// NewUUID returns a random version 4 UUID
+func NewUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+}
+
+// wholeInt64 converts a whole number to int64. NaN, infinities, fractions and
+// numbers beyond the int64 range are rejected rather than converted.
+func wholeInt64(value any) (int64, bool) {
+	number, ok := toNumber(value)
+	// float64(math.MaxInt64) rounds up to 2^63, which is out of range
+	if !ok || number != math.Trunc(number) || number < math.MinInt64 || number >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(number), true
+}
+
+// RandomInt returns a random integer between low and high, inclusive
+func RandomInt(low, high int64) (int64, error) {
+	if low > high {
+		return 0, fmt.Errorf("low bound %d is greater than high bound %d", low, high)
+	}
+	span := new(big.Int).Sub(big.NewInt(high), big.NewInt(low))
+	n, err := rand.Int(rand.Reader, span.Add(span, big.NewInt(1)))
+	if err != nil {
+		return 0, err
+	}
+	return n.Add(n, big.NewInt(low)).Int64(), nil
+}
+
+var hmacHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// HMAC returns the hex encoded HMAC of message with the algorithm sha1,
+// sha256 or sha512
+func HMAC(algorithm, key, message string) (string, error) {
+	newHash, ok := hmacHashes[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unknown algorithm '%s', expected sha1, sha256 or sha512", algorithm)
+	}
+	mac := hmac.New(newHash, []byte(key))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,184 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package expression
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgram_Run(t *testing.T) {
+	env := map[string]any{
+		"payload": map[string]any{
+			"items":    []any{map[string]any{"sku": "A1", "qty": 2.0}, map[string]any{"sku": "B2", "qty": 1.0}},
+			"total":    40.0,
+			"customer": map[string]any{"tier": "gold", "email": "ana@example.com"},
+		},
+		"headers":    map[string]string{"x-tenant": "acme", "x-empty": ""},
+		"properties": map[string]any{"HTTP_SC": 201, "GEOIP_COUNTRY": "CA"},
+	}
+	variables := []string{"payload", "headers", "properties"}
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{`len(payload.items) > 0 && headers['x-tenant'] != ''`, true},
+		{`headers["x-empty"] != '' || headers.missing == nil`, true},
+		{`payload.items[0].sku`, "A1"},
+		{`payload.items[-1].qty + payload.items[0].qty`, 3.0},
+		{`payload.missing?.sku`, nil},
+		{`payload.total * 1.5 - 10 / 4`, 57.5},
+		{`int(payload.total) % 7`, 5.0},
+		{`-payload.total + 2 * (3 + 1)`, -32.0},
+		{`properties.HTTP_SC == 201`, true},
+		{`properties.GEOIP_COUNTRY in ['US', 'CA'] ? 'domestic' : 'export'`, "domestic"},
+		{`payload.total > 100 ? 'large' : payload.total > 10 ? 'medium' : 'small'`, "medium"},
+		{`'order-' + payload.items[0].sku + '-' + string(payload.total)`, "order-A1-40"},
+		{`payload.customer.email matches '^[^@]+@example\\.com$'`, true},
+		{`payload.customer.email endsWith '.org' or payload.customer.tier startsWith 'go'`, true},
+		{`not (payload.customer.tier contains 'old')`, false},
+		{`!true == false`, true},
+		{`'tier' in payload.customer`, true},
+		{`upper(payload.customer.tier) + lower('X') + trim('  y ')`, "GOLDxy"},
+		{`number('12.5') + abs(-1)`, 13.5},
+		{`string(properties.HTTP_SC)`, "201"},
+		{`len(payload.items) == 2 && len('hello') == 5`, true},
+		{`[1, 2, 3][1]`, 2.0},
+		{`1_000 >= 999.5`, true},
+		{`"a\"b"`, `a"b`},
+		{`payload.missing == nil`, true},
+		{`payload.missing ?? 'default'`, "default"},
+		{`all(payload.items, .qty > 0) && map(payload.items, .sku) == ['A1', 'B2']`, true},
+		{`base64encode('user:secret')`, "dXNlcjpzZWNyZXQ="},
+		{`base64decode(base64encode(payload.customer.tier))`, "gold"},
+		{`hmac('sha256', 'key', 'The quick brown fox jumps over the lazy dog')`, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{`randomInt(4, 4)`, 4.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			program, err := Compile(tt.expression, variables...)
+			require.NoError(t, err)
+			got, err := program.Run(env)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    string
+	}{
+		{`len(payload`, "unexpected token EOF (1:11)"},
+		{`body.items`, "unknown name body (1:1)"},
+		{`size(payload)`, "unknown name size (1:1)"},
+		{`len(payload, headers)`, "invalid number of arguments (expected 1, got 2)"},
+		{`payload.name matches '['`, "error parsing regexp: missing closing ]"},
+		{`payload.a payload.b`, "unexpected token Identifier(\"payload\") (1:11)"},
+		{`'unterminated`, "literal not terminated"},
+		{`payload # 1`, "unexpected token Operator(\"#\") (1:9)"},
+		{`true ? 1`, "unexpected token EOF"},
+		{`uuid(payload)`, "too many arguments to call uuid"},
+		{`hmac('sha256', 'key')`, "not enough arguments to call hmac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := Compile(tt.expression, "payload", "headers")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestProgram_RunErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    string
+	}{
+		{`int(payload.total) % 0`, "integer divide by zero"},
+		{`payload.name - 1`, "invalid operation: string - int"},
+		{`payload.total.value`, "cannot fetch value from float64"},
+		{`payload.items[2]`, "index out of range: 2 (array length is 2)"},
+		// Indices beyond the int range are out of range rather than wrapping
+		{`payload.items[9223372036854775807]`, "index out of range"},
+		{`payload.items[1e19]`, "index out of range"},
+		{`payload.name[1e19]`, "index out of range"},
+		{`number('abc')`, `number: cannot convert "abc" to a number`},
+		{`payload.total ? 1 : 2`, "interface conversion: interface {} is float64, not bool"},
+		{`randomInt(5, 1)`, "randomInt: low bound 5 is greater than high bound 1"},
+		{`randomInt(1.5, 2)`, "randomInt: expects two whole numbers"},
+		{`randomInt(0, 1e19)`, "randomInt: expects two whole numbers within the int64 range"},
+		{`randomInt(-1e19, 0)`, "randomInt: expects two whole numbers within the int64 range"},
+		{`randomInt(0, payload.inf)`, "randomInt: expects two whole numbers within the int64 range"},
+		{`randomInt(-payload.inf, 0)`, "randomInt: expects two whole numbers within the int64 range"},
+		{`randomInt(payload.nan, 0)`, "randomInt: expects two whole numbers within the int64 range"},
+		{`base64decode('%%')`, "base64decode: invalid base64"},
+		{`hmac('md5', 'key', payload.name)`, "hmac: unknown algorithm 'md5'"},
+	}
+	env := map[string]any{"payload": map[string]any{"total": 10.0, "name": "x", "items": []any{1.0, 2.0},
+		"inf": math.Inf(1), "nan": math.NaN()}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			program, err := Compile(tt.expression, "payload")
+			require.NoError(t, err)
+			_, err = program.Run(env)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	// Short-circuiting skips the erroring side
+	program, err := Compile(`false && int(payload.total) % 0 > 1`, "payload")
+	require.NoError(t, err)
+	result, err := program.Run(env)
+	require.NoError(t, err)
+	assert.Equal(t, false, result)
+}
+
+func TestProgram_RunGenerators(t *testing.T) {
+	run := func(source string) any {
+		program, err := Compile(source)
+		require.NoError(t, err)
+		value, err := program.Run(nil)
+		require.NoError(t, err)
+		return value
+	}
+
+	first, second := run(`uuid()`), run(`uuid()`)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, first)
+	assert.NotEqual(t, first, second)
+
+	before := time.Now().UTC()
+	assert.Equal(t, before.Format("2006-01-02"), run(`now('2006-01-02')`))
+	assert.InDelta(t, float64(before.Unix()), run(`now('unix')`), 1)
+	assert.InDelta(t, float64(before.UnixMilli()), run(`now('unixMilli')`), 1000)
+
+	for range 20 {
+		n := run(`randomInt(-2, 3)`).(float64)
+		assert.True(t, n >= -2 && n <= 3 && n == float64(int(n)), "got %v", n)
+	}
+	_, err := RandomInt(math.MinInt64, math.MaxInt64)
+	assert.NoError(t, err, "the full int64 range does not overflow")
+}
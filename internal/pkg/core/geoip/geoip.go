@@ -0,0 +1,199 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package geoip resolves the country and region of API clients from a
+// MaxMind DB, such as GeoLite2-Country or GeoLite2-City, and records them on
+// the message context so mediators and precondition expressions can block or
+// route requests by location.
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Message context properties set for located clients
+const (
+	ClientIPProperty    = "CLIENT_IP"
+	CountryProperty     = "GEOIP_COUNTRY"
+	CountryNameProperty = "GEOIP_COUNTRY_NAME"
+	RegionProperty      = "GEOIP_REGION"
+	RegionNameProperty  = "GEOIP_REGION_NAME"
+	CityProperty        = "GEOIP_CITY"
+	ContinentProperty   = "GEOIP_CONTINENT"
+)
+
+// Config holds the [geoip] section of deployment.toml
+type Config struct {
+	// Database is the path of the .mmdb file, relative to the conf directory
+	// unless absolute
+	Database string `koanf:"database"`
+	// ClientIPHeader names a header set by a trusted proxy, e.g.
+	// X-Forwarded-For, whose first address is the client. The connection's
+	// address is used when empty.
+	ClientIPHeader string `koanf:"clientIPHeader"`
+}
+
+// Validate reports configuration errors in the [geoip] section
+func (c Config) Validate() error {
+	if c.Database == "" {
+		return fmt.Errorf("geoip: database is required")
+	}
+	return nil
+}
+
+// Location is where a client address is registered
+type Location struct {
+	Country     string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
+	CountryName string `json:"countryName,omitempty"`
+	Region      string `json:"region,omitempty"` // ISO 3166-2 subdivision code, without the country
+	RegionName  string `json:"regionName,omitempty"`
+	City        string `json:"city,omitempty"`
+	Continent   string `json:"continent,omitempty"`
+}
+
+// Locator resolves client addresses to locations
+type Locator struct {
+	reader         *Reader
+	clientIPHeader string
+}
+
+// Open loads the configured database. Relative paths are resolved against
+// confPath.
+func Open(config Config, confPath string) (*Locator, error) {
+	path := config.Database
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(confPath, path)
+	}
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: cannot read database: %w", err)
+	}
+	reader, err := NewReader(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: %s: %w", path, err)
+	}
+	return &Locator{reader: reader, clientIPHeader: config.ClientIPHeader}, nil
+}
+
+// Locate returns the location of addr, if the database knows it
+func (l *Locator) Locate(addr netip.Addr) (Location, bool) {
+	value, ok, err := l.reader.Lookup(addr)
+	if err != nil || !ok {
+		return Location{}, false
+	}
+	record, _ := value.(map[string]any)
+	location := Location{
+		Country:     lookupString(record, "country", "iso_code"),
+		CountryName: lookupString(record, "country", "names", "en"),
+		City:        lookupString(record, "city", "names", "en"),
+		Continent:   lookupString(record, "continent", "code"),
+	}
+	if subdivisions, ok := record["subdivisions"].([]any); ok && len(subdivisions) > 0 {
+		subdivision, _ := subdivisions[0].(map[string]any)
+		location.Region = lookupString(subdivision, "iso_code")
+		location.RegionName = lookupString(subdivision, "names", "en")
+	}
+	return location, location != Location{}
+}
+
+// lookupString follows keys through nested maps to a string
+func lookupString(record map[string]any, keys ...string) string {
+	var value any = record
+	for _, key := range keys {
+		node, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = node[key]
+	}
+	s, _ := value.(string)
+	return s
+}
+
+// ClientIP returns the address of the client that sent r
+func (l *Locator) ClientIP(r *http.Request) (netip.Addr, bool) {
+	if l.clientIPHeader != "" {
+		if value := r.Header.Get(l.clientIPHeader); value != "" {
+			first, _, _ := strings.Cut(value, ",")
+			if addr, err := netip.ParseAddr(strings.TrimSpace(first)); err == nil {
+				return addr, true
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	return addr, err == nil
+}
+
+// Enrich records the client address of r and its location on msgContext
+func (l *Locator) Enrich(msgContext *synctx.MsgContext, r *http.Request) {
+	addr, ok := l.ClientIP(r)
+	if !ok {
+		return
+	}
+	msgContext.Properties[ClientIPProperty] = addr.String()
+	location, ok := l.Locate(addr)
+	if !ok {
+		return
+	}
+	for property, value := range map[string]string{
+		CountryProperty:     location.Country,
+		CountryNameProperty: location.CountryName,
+		RegionProperty:      location.Region,
+		RegionNameProperty:  location.RegionName,
+		CityProperty:        location.City,
+		ContinentProperty:   location.Continent,
+	} {
+		if value != "" {
+			msgContext.Properties[property] = value
+		}
+	}
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultLocator *Locator
+)
+
+// SetDefault installs the locator used by the HTTP entry points
+func SetDefault(locator *Locator) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLocator = locator
+}
+
+// Default returns the locator used by the HTTP entry points, or nil when
+// GeoIP is not configured
+func Default() *Locator {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLocator
+}
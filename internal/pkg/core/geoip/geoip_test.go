@@ -0,0 +1,292 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNode is a search tree node of a database built by buildDatabase. Each
+// side holds a child node, a data offset, or neither.
+type testNode struct {
+	children [2]*testNode
+	data     [2]int
+	hasData  [2]bool
+	index    int
+}
+
+// buildDatabase writes a MaxMind DB mapping each network to its record
+func buildDatabase(t *testing.T, ipVersion, recordSize int, networks map[string]any) []byte {
+	var data bytes.Buffer
+	root := &testNode{}
+	prefixes := make([]string, 0, len(networks))
+	for prefix := range networks {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		network := netip.MustParsePrefix(prefix)
+		offset := data.Len()
+		encodeValue(&data, networks[prefix])
+
+		bits, ip := network.Bits(), network.Addr().AsSlice()
+		if network.Addr().Is4() && ipVersion == 6 {
+			ip = append(make([]byte, 12), ip...)
+			bits += 96
+		}
+		node := root
+		for i := 0; i < bits; i++ {
+			bit := (ip[i/8] >> (7 - i%8)) & 1
+			if i == bits-1 {
+				node.data[bit], node.hasData[bit] = offset, true
+				break
+			}
+			if node.children[bit] == nil {
+				node.children[bit] = &testNode{}
+			}
+			node = node.children[bit]
+		}
+	}
+
+	var nodes []*testNode
+	queue := []*testNode{root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		node.index = len(nodes)
+		nodes = append(nodes, node)
+		for _, child := range node.children {
+			if child != nil {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	var tree bytes.Buffer
+	nodeCount := len(nodes)
+	for _, node := range nodes {
+		var records [2]uint32
+		for bit := range 2 {
+			switch {
+			case node.children[bit] != nil:
+				records[bit] = uint32(node.children[bit].index)
+			case node.hasData[bit]:
+				records[bit] = uint32(nodeCount + dataSectionSeparator + node.data[bit])
+			default:
+				records[bit] = uint32(nodeCount)
+			}
+		}
+		switch recordSize {
+		case 24:
+			for _, record := range records {
+				tree.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+			}
+		case 28:
+			left, right := records[0], records[1]
+			tree.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left),
+				byte((left>>24)<<4) | byte(right>>24&0x0f), byte(right >> 16), byte(right >> 8), byte(right)})
+		default:
+			tree.Write(binary.BigEndian.AppendUint32(nil, records[0]))
+			tree.Write(binary.BigEndian.AppendUint32(nil, records[1]))
+		}
+	}
+
+	var database bytes.Buffer
+	database.Write(tree.Bytes())
+	database.Write(make([]byte, dataSectionSeparator))
+	database.Write(data.Bytes())
+	database.Write(metadataMarker)
+	encodeValue(&database, map[string]any{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(recordSize),
+		"ip_version":    uint16(ipVersion),
+		"database_type": "Test-City",
+	})
+	return database.Bytes()
+}
+
+func writeControl(buffer *bytes.Buffer, fieldType, size int) {
+	ctrl := byte(fieldType << 5)
+	var extended []byte
+	if fieldType > 7 {
+		ctrl = 0
+		extended = []byte{byte(fieldType - 7)}
+	}
+	switch {
+	case size < 29:
+		buffer.WriteByte(ctrl | byte(size))
+		buffer.Write(extended)
+	case size < 285:
+		buffer.WriteByte(ctrl | 29)
+		buffer.Write(extended)
+		buffer.WriteByte(byte(size - 29))
+	default:
+		buffer.WriteByte(ctrl | 30)
+		buffer.Write(extended)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(size-285)))
+	}
+}
+
+func encodeValue(buffer *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case string:
+		writeControl(buffer, typeString, len(v))
+		buffer.WriteString(v)
+	case uint16:
+		writeControl(buffer, typeUint16, 2)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, v))
+	case uint32:
+		writeControl(buffer, typeUint32, 4)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, v))
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		writeControl(buffer, typeBool, size)
+	case []any:
+		writeControl(buffer, typeArray, len(v))
+		for _, item := range v {
+			encodeValue(buffer, item)
+		}
+	case map[string]any:
+		writeControl(buffer, typeMap, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			encodeValue(buffer, key)
+			encodeValue(buffer, v[key])
+		}
+	}
+}
+
+func cityRecord(country, countryName, region, regionName, city string) map[string]any {
+	return map[string]any{
+		"continent":    map[string]any{"code": "EU"},
+		"country":      map[string]any{"iso_code": country, "names": map[string]any{"en": countryName}},
+		"subdivisions": []any{map[string]any{"iso_code": region, "names": map[string]any{"en": regionName}}},
+		"city":         map[string]any{"names": map[string]any{"en": city}},
+	}
+}
+
+var testNetworks = map[string]any{
+	"81.2.69.0/24":        cityRecord("GB", "United Kingdom", "ENG", "England", "London"),
+	"2001:db8:100::/48":   cityRecord("DE", "Germany", "BE", "Land Berlin", "Berlin"),
+	"192.0.2.0/25":        map[string]any{"country": map[string]any{"iso_code": "LK"}, "is_anycast": true},
+	"198.51.100.128/26":   map[string]any{"country": map[string]any{"iso_code": "SE"}, "geoname_id": uint32(2661886)},
+	"2001:db8:200::1/128": map[string]any{"country": map[string]any{"iso_code": "FR"}},
+}
+
+func TestReader_Lookup(t *testing.T) {
+	for _, recordSize := range []int{24, 28, 32} {
+		reader, err := NewReader(buildDatabase(t, 6, recordSize, testNetworks))
+		require.NoError(t, err, "record size %d", recordSize)
+		assert.Equal(t, "Test-City", reader.DatabaseType())
+		locator := &Locator{reader: reader}
+
+		tests := []struct {
+			addr string
+			want Location
+		}{
+			{addr: "81.2.69.160", want: Location{Country: "GB", CountryName: "United Kingdom", Region: "ENG",
+				RegionName: "England", City: "London", Continent: "EU"}},
+			{addr: "::ffff:81.2.69.1", want: Location{Country: "GB", CountryName: "United Kingdom", Region: "ENG",
+				RegionName: "England", City: "London", Continent: "EU"}},
+			{addr: "2001:db8:100:1::7", want: Location{Country: "DE", CountryName: "Germany", Region: "BE",
+				RegionName: "Land Berlin", City: "Berlin", Continent: "EU"}},
+			{addr: "192.0.2.127", want: Location{Country: "LK"}},
+			{addr: "192.0.2.128"},
+			{addr: "198.51.100.190", want: Location{Country: "SE"}},
+			{addr: "2001:db8:200::1", want: Location{Country: "FR"}},
+			{addr: "2001:db8:200::2"},
+			{addr: "10.0.0.1"},
+		}
+		for _, tt := range tests {
+			location, ok := locator.Locate(netip.MustParseAddr(tt.addr))
+			assert.Equal(t, tt.want != Location{}, ok, "%s with %d-bit records", tt.addr, recordSize)
+			assert.Equal(t, tt.want, location, "%s with %d-bit records", tt.addr, recordSize)
+		}
+	}
+
+	reader, err := NewReader(buildDatabase(t, 4, 24, map[string]any{"198.51.100.0/24": map[string]any{"geoname_id": uint32(1)}}))
+	require.NoError(t, err)
+	value, ok, err := reader.Lookup(netip.MustParseAddr("198.51.100.7"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]any{"geoname_id": uint32(1)}, value)
+	_, ok, _ = reader.Lookup(netip.MustParseAddr("2001:db8::1"))
+	assert.False(t, ok, "IPv6 addresses are not in an IPv4 database")
+
+	_, err = NewReader([]byte("not a database"))
+	assert.EqualError(t, err, "not a MaxMind DB: metadata marker not found")
+}
+
+func TestDecoder_Pointers(t *testing.T) {
+	// "geo" at offset 0, then a map whose key and value point back at it
+	section := []byte{0x43, 'g', 'e', 'o', 0xe1, 0x20, 0x00, 0x20, 0x00}
+	value, next, err := decoder{section: section}.decode(4, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"geo": "geo"}, value)
+	assert.Equal(t, uint(len(section)), next)
+
+	_, _, err = decoder{section: []byte{0x5d}}.decode(0, 0)
+	assert.EqualError(t, err, "truncated size")
+}
+
+func TestLocator_Enrich(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test.mmdb"), buildDatabase(t, 6, 24, testNetworks), 0o600))
+	locator, err := Open(Config{Database: "test.mmdb", ClientIPHeader: "X-Forwarded-For"}, dir)
+	require.NoError(t, err)
+
+	request := httptest.NewRequest("GET", "/orders", nil)
+	request.RemoteAddr = "10.0.0.5:51234"
+	request.Header.Set("X-Forwarded-For", "81.2.69.160, 10.0.0.1")
+	msgContext := synctx.CreateMsgContext()
+	locator.Enrich(msgContext, request)
+	assert.Equal(t, "81.2.69.160", msgContext.Properties[ClientIPProperty])
+	assert.Equal(t, "GB", msgContext.Properties[CountryProperty])
+	assert.Equal(t, "ENG", msgContext.Properties[RegionProperty])
+	assert.Equal(t, "London", msgContext.Properties[CityProperty])
+
+	request.Header.Del("X-Forwarded-For")
+	msgContext = synctx.CreateMsgContext()
+	locator.Enrich(msgContext, request)
+	assert.Equal(t, "10.0.0.5", msgContext.Properties[ClientIPProperty], "falls back to the connection address")
+	assert.NotContains(t, msgContext.Properties, CountryProperty)
+
+	_, err = Open(Config{Database: "missing.mmdb"}, dir)
+	assert.ErrorContains(t, err, "geoip: cannot read database")
+	assert.EqualError(t, Config{}.Validate(), "geoip: database is required")
+}
@@ -0,0 +1,349 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+)
+
+// metadataMarker precedes the metadata map at the end of a MaxMind DB file
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the run of zero bytes between the search tree and
+// the data section
+const dataSectionSeparator = 16
+
+// Data section field types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// Reader looks up addresses in a MaxMind DB (.mmdb) file held in memory. It
+// implements the format described at
+// https://maxmind.github.io/MaxMind-DB/ without depending on a client library.
+type Reader struct {
+	buffer       []byte
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	databaseType string
+	tree         []byte
+	data         []byte
+	// ipv4Start is the node IPv4 lookups start from in an IPv6 tree
+	ipv4Start uint
+}
+
+// NewReader parses the metadata and layout of a MaxMind DB held in buffer
+func NewReader(buffer []byte) (*Reader, error) {
+	start := bytes.LastIndex(buffer, metadataMarker)
+	if start == -1 {
+		return nil, fmt.Errorf("not a MaxMind DB: metadata marker not found")
+	}
+	metadataSection := buffer[start+len(metadataMarker):]
+	value, _, err := decoder{section: metadataSection}.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata: %w", err)
+	}
+	metadata, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata: not a map")
+	}
+	r := &Reader{buffer: buffer}
+	r.nodeCount = metadataUint(metadata, "node_count")
+	r.recordSize = metadataUint(metadata, "record_size")
+	r.ipVersion = metadataUint(metadata, "ip_version")
+	r.databaseType, _ = metadata["database_type"].(string)
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported MaxMind DB record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported MaxMind DB IP version %d", r.ipVersion)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(start) {
+		return nil, fmt.Errorf("invalid MaxMind DB: search tree exceeds the file")
+	}
+	r.tree = buffer[:treeSize]
+	r.data = buffer[treeSize+dataSectionSeparator : start]
+
+	if r.ipVersion == 6 {
+		// IPv4 addresses live under ::/96
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+func metadataUint(metadata map[string]any, key string) uint {
+	switch value := metadata[key].(type) {
+	case uint64:
+		return uint(value)
+	case uint32:
+		return uint(value)
+	case uint16:
+		return uint(value)
+	}
+	return 0
+}
+
+// DatabaseType is the type recorded in the metadata, e.g. GeoLite2-City
+func (r *Reader) DatabaseType() string {
+	return r.databaseType
+}
+
+// Lookup returns the record of the network containing addr
+func (r *Reader) Lookup(addr netip.Addr) (any, bool, error) {
+	addr = addr.Unmap()
+	node := uint(0)
+	bits := addr.AsSlice()
+	if addr.Is4() && r.ipVersion == 6 {
+		node = r.ipv4Start
+	} else if addr.Is6() && r.ipVersion == 4 {
+		return nil, false, nil
+	}
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, false, nil
+	}
+	if node < r.nodeCount {
+		return nil, false, fmt.Errorf("invalid MaxMind DB: search tree deeper than the address")
+	}
+	offset := node - r.nodeCount - dataSectionSeparator
+	if offset >= uint(len(r.data)) {
+		return nil, false, fmt.Errorf("invalid MaxMind DB: data pointer out of range")
+	}
+	value, _, err := decoder{section: r.data}.decode(offset, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a node
+func (r *Reader) record(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		offset := node*6 + bit*3
+		b := r.tree[offset : offset+3]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.tree[node*7 : node*7+7]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		offset := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(r.tree[offset : offset+4]))
+	}
+}
+
+// decoder reads values from a data section
+type decoder struct {
+	section []byte
+}
+
+// maxDepth bounds nesting so a corrupt file cannot exhaust the stack
+const maxDepth = 64
+
+// decode returns the value at offset and the offset following it
+func (d decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, fmt.Errorf("data nested too deeply")
+	}
+	fieldType, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if fieldType == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(target, depth+1)
+		return value, next, err
+	}
+	switch fieldType {
+	case typeMap:
+		value := make(map[string]any, size)
+		for range size {
+			var key, item any
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key is not a string")
+			}
+			if item, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			value[name] = item
+		}
+		return value, offset, nil
+	case typeArray:
+		value := make([]any, 0, size)
+		for range size {
+			var item any
+			if item, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			value = append(value, item)
+		}
+		return value, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.section)) {
+		return nil, 0, fmt.Errorf("value exceeds the data section")
+	}
+	b := d.section[offset:end]
+	switch fieldType {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes:
+		return bytes.Clone(b), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), end, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("invalid unsigned integer size %d", size)
+		}
+		var value uint64
+		for _, c := range b {
+			value = value<<8 | uint64(c)
+		}
+		switch fieldType {
+		case typeUint16:
+			return uint16(value), end, nil
+		case typeUint32:
+			return uint32(value), end, nil
+		}
+		return value, end, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("invalid int32 size %d", size)
+		}
+		var value uint32
+		for _, c := range b {
+			value = value<<8 | uint32(c)
+		}
+		return int32(value), end, nil
+	case typeUint128:
+		return new(big.Int).SetBytes(b), end, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", fieldType)
+}
+
+// control reads a control byte with its extended type and size
+func (d decoder) control(offset uint) (fieldType, size, next uint, err error) {
+	if offset >= uint(len(d.section)) {
+		return 0, 0, 0, fmt.Errorf("offset %d exceeds the data section", offset)
+	}
+	ctrl := d.section[offset]
+	offset++
+	fieldType = uint(ctrl >> 5)
+	if fieldType == typeExtended {
+		if offset >= uint(len(d.section)) {
+			return 0, 0, 0, fmt.Errorf("truncated extended type")
+		}
+		fieldType = 7 + uint(d.section[offset])
+		offset++
+	}
+	size = uint(ctrl & 0x1f)
+	if fieldType == typePointer || size < 29 {
+		return fieldType, size, offset, nil
+	}
+	extra := size - 28
+	if offset+extra > uint(len(d.section)) {
+		return 0, 0, 0, fmt.Errorf("truncated size")
+	}
+	var value uint
+	for _, c := range d.section[offset : offset+extra] {
+		value = value<<8 | uint(c)
+	}
+	switch extra {
+	case 1:
+		size = 29 + value
+	case 2:
+		size = 285 + value
+	default:
+		size = 65821 + value
+	}
+	return fieldType, size, offset + extra, nil
+}
+
+// pointer resolves a pointer whose control byte carried sizeBits
+func (d decoder) pointer(sizeBits, offset uint) (target, next uint, err error) {
+	length := (sizeBits>>3)&0x3 + 1
+	if offset+length > uint(len(d.section)) {
+		return 0, 0, fmt.Errorf("truncated pointer")
+	}
+	var value uint
+	if length != 4 {
+		value = sizeBits & 0x7
+	}
+	for _, c := range d.section[offset : offset+length] {
+		value = value<<8 | uint(c)
+	}
+	switch length {
+	case 2:
+		value += 2048
+	case 3:
+		value += 526336
+	}
+	return value, offset + length, nil
+}
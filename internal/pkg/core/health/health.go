@@ -0,0 +1,198 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package health runs the probes declared by health check artifacts and keeps
+// their latest results for the readiness endpoint and the admin API.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+)
+
+const (
+	StatusPending   = "PENDING"
+	StatusHealthy   = "UP"
+	StatusUnhealthy = "DOWN"
+)
+
+// Probe checks a backend once and returns an error when it is unhealthy
+type Probe func(ctx context.Context) error
+
+// Status is the latest result of a health check
+type Status struct {
+	Name                string    `json:"name"`
+	Status              string    `json:"status"`
+	Critical            bool      `json:"critical"`
+	Target              string    `json:"target"`
+	LastChecked         time.Time `json:"lastChecked,omitzero"`
+	LastError           string    `json:"lastError,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+}
+
+type check struct {
+	config artifacts.HealthCheck
+	probe  Probe
+	status Status
+}
+
+// Registry holds the deployed health checks
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]*check
+	logger *slog.Logger
+}
+
+func NewRegistry(logger *slog.Logger) *Registry {
+	return &Registry{checks: make(map[string]*check), logger: logger}
+}
+
+// Register adds a health check. It stays pending until Run probes it.
+func (r *Registry) Register(config artifacts.HealthCheck, probe Probe) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.checks[config.Name]; exists {
+		return fmt.Errorf("health check %s is already deployed", config.Name)
+	}
+	r.checks[config.Name] = &check{
+		config: config,
+		probe:  probe,
+		status: Status{Name: config.Name, Status: StatusPending, Critical: config.Critical, Target: target(config)},
+	}
+	return nil
+}
+
+// Run probes the named check immediately and then on its interval until ctx is done
+func (r *Registry) Run(ctx context.Context, name string) {
+	r.mu.RLock()
+	c, ok := r.checks[name]
+	r.mu.RUnlock()
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+	for {
+		r.Probe(ctx, name)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe runs the named check once and records the result
+func (r *Registry) Probe(ctx context.Context, name string) {
+	r.mu.RLock()
+	c, ok := r.checks[name]
+	r.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	err := c.probe(probeCtx)
+	cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := c.status.Status
+	c.status.LastChecked = time.Now()
+	if err == nil {
+		c.status.Status = StatusHealthy
+		c.status.LastError = ""
+		c.status.ConsecutiveFailures = 0
+	} else {
+		c.status.LastError = err.Error()
+		c.status.ConsecutiveFailures++
+		if c.status.ConsecutiveFailures >= c.config.FailureThreshold {
+			c.status.Status = StatusUnhealthy
+		}
+	}
+	if c.status.Status == previous {
+		return
+	}
+	if c.status.Status == StatusUnhealthy {
+		events.Publish(events.Event{Type: events.HealthCheckFailed, Kind: "healthcheck", Name: name,
+			Attributes: map[string]string{"error": c.status.LastError}})
+		if r.logger != nil {
+			r.logger.Warn("Health check failed", slog.String("health_check", name), slog.String("error", c.status.LastError))
+		}
+	} else if c.status.Status == StatusHealthy && previous == StatusUnhealthy {
+		events.Publish(events.Event{Type: events.HealthCheckHealthy, Kind: "healthcheck", Name: name})
+		if r.logger != nil {
+			r.logger.Info("Health check recovered", slog.String("health_check", name))
+		}
+	}
+}
+
+// Statuses returns the latest result of every check, sorted by name
+func (r *Registry) Statuses() []Status {
+	r.mu.RLock()
+	statuses := make([]Status, 0, len(r.checks))
+	for _, c := range r.checks {
+		statuses = append(statuses, c.status)
+	}
+	r.mu.RUnlock()
+	slices.SortFunc(statuses, func(a, b Status) int { return strings.Compare(a.Name, b.Name) })
+	return statuses
+}
+
+// Get returns the latest result of the named check
+func (r *Registry) Get(name string) (Status, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.checks[name]
+	if !ok {
+		return Status{}, false
+	}
+	return c.status, true
+}
+
+// Ready reports whether every critical check is healthy
+func (r *Registry) Ready() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.checks {
+		if c.config.Critical && c.status.Status != StatusHealthy {
+			return false
+		}
+	}
+	return true
+}
+
+func target(config artifacts.HealthCheck) string {
+	switch {
+	case config.Endpoint != "":
+		return "endpoint:" + config.Endpoint
+	case config.Sequence != "":
+		return "sequence:" + config.Sequence
+	default:
+		return config.Method + " " + config.URL
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+)
+
+type resultMediator struct {
+	ok bool
+}
+
+func (m resultMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	return m.ok, nil
+}
+
+func TestRegistry_FailureThresholdAndReadiness(t *testing.T) {
+	registry := NewRegistry(nil)
+	var probeErr error
+	config := artifacts.HealthCheck{Name: "Orders", URL: "http://orders", Method: "GET", Timeout: time.Second, FailureThreshold: 2, Critical: true}
+	assert.NoError(t, registry.Register(config, func(ctx context.Context) error { return probeErr }))
+	assert.Error(t, registry.Register(config, nil), "duplicate names are rejected")
+	assert.NoError(t, registry.Register(artifacts.HealthCheck{Name: "Reports", Sequence: "Ping", Timeout: time.Second, FailureThreshold: 1},
+		func(ctx context.Context) error { return errors.New("down") }))
+
+	ctx := context.Background()
+	assert.False(t, registry.Ready(), "pending critical checks are not ready")
+
+	registry.Probe(ctx, "Orders")
+	registry.Probe(ctx, "Reports")
+	assert.True(t, registry.Ready(), "non-critical checks do not affect readiness")
+
+	probeErr = errors.New("connection refused")
+	registry.Probe(ctx, "Orders")
+	status, _ := registry.Get("Orders")
+	assert.Equal(t, StatusHealthy, status.Status, "one failure is below the threshold")
+	registry.Probe(ctx, "Orders")
+	status, _ = registry.Get("Orders")
+	assert.Equal(t, StatusUnhealthy, status.Status)
+	assert.Equal(t, "connection refused", status.LastError)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.False(t, registry.Ready())
+
+	statuses := registry.Statuses()
+	assert.Equal(t, []string{"Orders", "Reports"}, []string{statuses[0].Name, statuses[1].Name})
+	assert.Equal(t, "GET http://orders", statuses[0].Target)
+	assert.Equal(t, "sequence:Ping", statuses[1].Target)
+}
+
+func TestNewProbe(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer backend.Close()
+
+	configContext := &artifacts.ConfigContext{}
+	configContext.AddEndpoint(artifacts.Endpoint{Name: "OrdersEP", Protocol: artifacts.ProtocolHTTP, EndpointUrl: artifacts.EndpointUrl{Method: "GET", URL: backend.URL + "/orders"}})
+	configContext.AddSequence(artifacts.Sequence{Name: "Healthy", MediatorList: []artifacts.Mediator{resultMediator{ok: true}}})
+	configContext.AddSequence(artifacts.Sequence{Name: "Unhealthy", MediatorList: []artifacts.Mediator{resultMediator{ok: false}}})
+
+	tests := []struct {
+		name    string
+		config  artifacts.HealthCheck
+		wantErr string
+	}{
+		{name: "url", config: artifacts.HealthCheck{URL: backend.URL + "/up", Method: "GET"}},
+		{name: "url down", config: artifacts.HealthCheck{URL: backend.URL + "/down", Method: "GET"}, wantErr: "unexpected status 503"},
+		{name: "expected status", config: artifacts.HealthCheck{URL: backend.URL + "/up", Method: "GET", ExpectedStatus: 200}, wantErr: "unexpected status 204, expected 200"},
+		{name: "endpoint", config: artifacts.HealthCheck{Endpoint: "OrdersEP", Method: "GET"}},
+		{name: "missing endpoint", config: artifacts.HealthCheck{Endpoint: "BillingEP"}, wantErr: "endpoint BillingEP is not deployed"},
+		{name: "sequence", config: artifacts.HealthCheck{Sequence: "Healthy"}},
+		{name: "failing sequence", config: artifacts.HealthCheck{Sequence: "Unhealthy"}, wantErr: "sequence Unhealthy failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewProbe(tt.config, configContext, backend.Client())(context.Background())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
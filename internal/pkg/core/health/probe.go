@@ -0,0 +1,93 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// NewProbe builds the probe for a health check. Endpoints and sequences are
+// resolved on every probe, so they may be deployed after the health check.
+func NewProbe(config artifacts.HealthCheck, configContext *artifacts.ConfigContext, client *http.Client) Probe {
+	switch {
+	case config.Sequence != "":
+		return func(ctx context.Context) error {
+			sequence, ok := configContext.Snapshot().Sequences[config.Sequence]
+			if !ok {
+				return fmt.Errorf("sequence %s is not deployed", config.Sequence)
+			}
+			msgContext := synctx.CreateMsgContext()
+			if !sequence.Execute(msgContext) {
+				if message, ok := msgContext.Properties[artifacts.ErrorMessageProperty].(string); ok && message != "" {
+					return fmt.Errorf("sequence %s failed: %s", config.Sequence, message)
+				}
+				return fmt.Errorf("sequence %s failed", config.Sequence)
+			}
+			return nil
+		}
+	case config.Endpoint != "":
+		return func(ctx context.Context) error {
+			endpoint, ok := configContext.Snapshot().Endpoints[config.Endpoint]
+			if !ok {
+				return fmt.Errorf("endpoint %s is not deployed", config.Endpoint)
+			}
+			if endpoint.Protocol != artifacts.ProtocolHTTP {
+				return fmt.Errorf("endpoint %s uses %s, only http endpoints can be probed", config.Endpoint, endpoint.Protocol)
+			}
+			method := endpoint.EndpointUrl.Method
+			if method == "" {
+				method = config.Method
+			}
+			return probeURL(ctx, client, method, endpoint.EndpointUrl.URL, config.ExpectedStatus)
+		}
+	default:
+		return func(ctx context.Context) error {
+			return probeURL(ctx, client, config.Method, config.URL, config.ExpectedStatus)
+		}
+	}
+}
+
+func probeURL(ctx context.Context, client *http.Client, method, url string, expectedStatus int) error {
+	request, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	if expectedStatus != 0 {
+		if response.StatusCode != expectedStatus {
+			return fmt.Errorf("unexpected status %d, expected %d", response.StatusCode, expectedStatus)
+		}
+	} else if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return nil
+}
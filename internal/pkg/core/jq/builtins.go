@@ -0,0 +1,1023 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package jq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// builtin implements a function over its input and unevaluated arguments.
+// paths is set for functions that can be path expressions.
+type builtin struct {
+	eval  func(e *env, input any, args []node, emit func(any) error) error
+	paths func(e *env, input any, args []node, emit func([]any, any) error) error
+}
+
+type call struct {
+	name string
+	fn   builtin
+	args []node
+}
+
+func (c call) eval(e *env, input any, emit func(any) error) error {
+	return c.fn.eval(e, input, c.args, emit)
+}
+
+func (c call) paths(e *env, input any, emit func([]any, any) error) error {
+	if c.fn.paths == nil {
+		return fmt.Errorf("invalid path expression with %s/%d", c.name, len(c.args))
+	}
+	return c.fn.paths(e, input, c.args, emit)
+}
+
+// fn0 is a function of its input with one output
+func fn0(f func(input any) (any, error)) builtin {
+	return builtin{eval: func(_ *env, input any, _ []node, emit func(any) error) error {
+		output, err := f(input)
+		if err != nil {
+			return err
+		}
+		return emit(output)
+	}}
+}
+
+// fn1 is a function of its input and the value of its argument, once for
+// each output of the argument
+func fn1(f func(input, arg any) (any, error)) builtin {
+	return builtin{eval: func(e *env, input any, args []node, emit func(any) error) error {
+		return args[0].eval(e, input, func(arg any) error {
+			output, err := f(input, arg)
+			if err != nil {
+				return err
+			}
+			return emit(output)
+		})
+	}}
+}
+
+// typeFilter passes its input on when it has one of the types
+func typeFilter(types ...string) builtin {
+	matches := func(input any) bool { return slices.Contains(types, typeName(input)) }
+	return builtin{
+		eval: func(_ *env, input any, _ []node, emit func(any) error) error {
+			if matches(input) {
+				return emit(input)
+			}
+			return nil
+		},
+		paths: func(_ *env, input any, _ []node, emit func([]any, any) error) error {
+			if matches(input) {
+				return emit(nil, input)
+			}
+			return nil
+		},
+	}
+}
+
+func number(input any) (float64, error) {
+	n, ok := input.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s is not a number", describe(input))
+	}
+	return n, nil
+}
+
+func text(input any) (string, error) {
+	s, ok := input.(string)
+	if !ok {
+		return "", fmt.Errorf("%s is not a string", describe(input))
+	}
+	return s, nil
+}
+
+func items(input any) ([]any, error) {
+	switch input := input.(type) {
+	case []any:
+		return input, nil
+	case map[string]any:
+		values := make([]any, 0, len(input))
+		for _, key := range sortedKeys(input) {
+			values = append(values, input[key])
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", describe(input))
+}
+
+func math1(f func(float64) float64) builtin {
+	return fn0(func(input any) (any, error) {
+		n, err := number(input)
+		if err != nil {
+			return nil, err
+		}
+		return f(n), nil
+	})
+}
+
+func strings1(f func(s, arg string) any) builtin {
+	return fn1(func(input, arg any) (any, error) {
+		s, err := text(input)
+		if err != nil {
+			return nil, err
+		}
+		a, err := text(arg)
+		if err != nil {
+			return nil, err
+		}
+		return f(s, a), nil
+	})
+}
+
+func strings0(f func(string) string) builtin {
+	return fn0(func(input any) (any, error) {
+		s, err := text(input)
+		if err != nil {
+			return nil, err
+		}
+		return f(s), nil
+	})
+}
+
+// sortKeys returns the outputs of f for each element, as the keys sort_by
+// and group_by compare
+func sortKeys(e *env, input any, f node) ([]any, []any, error) {
+	elements, ok := input.([]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s cannot be sorted, as it is not an array", describe(input))
+	}
+	keys := make([]any, len(elements))
+	for i, element := range elements {
+		outputs, err := collect(f, e, element)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = outputs
+	}
+	return elements, keys, nil
+}
+
+// sortedBy returns the elements ordered by their keys, stable among equals
+func sortedBy(elements, keys []any) ([]any, []any) {
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return compare(keys[a], keys[b]) })
+	sortedElements, sortedKeys := make([]any, len(order)), make([]any, len(order))
+	for i, j := range order {
+		sortedElements[i], sortedKeys[i] = elements[j], keys[j]
+	}
+	return sortedElements, sortedKeys
+}
+
+// extremeBy returns the element with the smallest key, or the largest with
+// max, the last one among equals for max as jq does
+func extremeBy(elements, keys []any, max bool) any {
+	if len(elements) == 0 {
+		return nil
+	}
+	best := 0
+	for i := 1; i < len(elements); i++ {
+		order := compare(keys[i], keys[best])
+		if (max && order >= 0) || (!max && order < 0) {
+			best = i
+		}
+	}
+	return elements[best]
+}
+
+func identityKeys(elements []any) []any {
+	keys := make([]any, len(elements))
+	for i, element := range elements {
+		keys[i] = []any{element}
+	}
+	return keys
+}
+
+func toEntries(input any) (any, error) {
+	object, ok := input.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s has no keys", describe(input))
+	}
+	entries := make([]any, 0, len(object))
+	for _, key := range sortedKeys(object) {
+		entries = append(entries, map[string]any{"key": key, "value": object[key]})
+	}
+	return entries, nil
+}
+
+func fromEntries(input any) (any, error) {
+	entries, ok := input.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot be turned into an object, as it is not an array", describe(input))
+	}
+	object := make(map[string]any, len(entries))
+	for _, entry := range entries {
+		fields, ok := entry.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("entry %s is not an object", describe(entry))
+		}
+		var key, value any
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if k, ok := fields[name]; ok && k != nil && k != false {
+				key = k
+				break
+			}
+		}
+		for _, name := range []string{"value", "v", "Value", "V"} {
+			if v, ok := fields[name]; ok {
+				value = v
+				break
+			}
+		}
+		switch k := key.(type) {
+		case string:
+			object[k] = value
+		case float64, bool:
+			object[toJSON(k)] = value
+		case nil:
+			object["null"] = value
+		default:
+			return nil, fmt.Errorf("entry key %s is not a string", describe(key))
+		}
+	}
+	return object, nil
+}
+
+// contains reports whether b is contained in a: substrings, array elements
+// contained in any element, and object values contained by key
+func contains(a, b any) (bool, error) {
+	if typeName(a) != typeName(b) {
+		return false, fmt.Errorf("%s and %s cannot have their containment checked", describe(a), describe(b))
+	}
+	switch a := a.(type) {
+	case string:
+		return strings.Contains(a, b.(string)), nil
+	case []any:
+		for _, y := range b.([]any) {
+			found := false
+			for _, x := range a {
+				if typeName(x) != typeName(y) {
+					continue
+				}
+				ok, err := contains(x, y)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	case map[string]any:
+		for key, y := range b.(map[string]any) {
+			x, ok := a[key]
+			if !ok || typeName(x) != typeName(y) {
+				return false, nil
+			}
+			if ok, err := contains(x, y); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return compare(a, b) == 0, nil
+}
+
+func flatten(input any, depth float64) (any, error) {
+	elements, ok := input.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot be flattened", describe(input))
+	}
+	if depth < 0 {
+		return nil, errors.New("flatten depth must not be negative")
+	}
+	flat := []any{}
+	for _, element := range elements {
+		if inner, ok := element.([]any); ok && depth > 0 {
+			flattened, _ := flatten(inner, depth-1)
+			flat = append(flat, flattened.([]any)...)
+		} else {
+			flat = append(flat, element)
+		}
+	}
+	return flat, nil
+}
+
+// walk applies f to every value below input, children before parents
+func walk(e *env, input any, f node, emit func(any) error) error {
+	switch value := input.(type) {
+	case []any:
+		walked := []any{}
+		for _, element := range value {
+			if err := walk(e, element, f, func(output any) error {
+				walked = append(walked, output)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		input = walked
+	case map[string]any:
+		walked := make(map[string]any, len(value))
+		for key, element := range value {
+			var result any
+			found := false
+			if err := walk(e, element, f, func(output any) error {
+				if !found {
+					result, found = output, true
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if found {
+				walked[key] = result
+			}
+		}
+		input = walked
+	}
+	return f.eval(e, input, emit)
+}
+
+var (
+	regexpMu    sync.Mutex
+	regexpCache = map[string]*regexp.Regexp{}
+)
+
+// compileRegexp compiles a pattern of test, capture, sub and gsub, cached
+// since patterns are usually literals
+func compileRegexp(pattern any) (*regexp.Regexp, error) {
+	source, err := text(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpMu.Lock()
+	defer regexpMu.Unlock()
+	if re, ok := regexpCache[source]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", source, err)
+	}
+	if len(regexpCache) < 256 {
+		regexpCache[source] = re
+	}
+	return re, nil
+}
+
+// captures returns the named groups of a match as an object
+func captures(re *regexp.Regexp, s string, match []int) map[string]any {
+	groups := map[string]any{}
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if match[2*i] < 0 {
+			groups[name] = nil
+		} else {
+			groups[name] = s[match[2*i]:match[2*i+1]]
+		}
+	}
+	return groups
+}
+
+// substitute replaces the first match of a regular expression, or all with
+// global, by the output of replacement for the object of the named groups
+func substitute(global bool) builtin {
+	return builtin{eval: func(e *env, input any, args []node, emit func(any) error) error {
+		s, err := text(input)
+		if err != nil {
+			return err
+		}
+		return args[0].eval(e, input, func(pattern any) error {
+			re, err := compileRegexp(pattern)
+			if err != nil {
+				return err
+			}
+			limit := 1
+			if global {
+				limit = -1
+			}
+			var b strings.Builder
+			last := 0
+			for _, match := range re.FindAllStringSubmatchIndex(s, limit) {
+				replacement, _, err := first(args[1], e, captures(re, s, match))
+				if err != nil {
+					return err
+				}
+				r, err := text(replacement)
+				if err != nil {
+					return err
+				}
+				b.WriteString(s[last:match[0]])
+				b.WriteString(r)
+				last = match[1]
+			}
+			b.WriteString(s[last:])
+			return emit(b.String())
+		})
+	}}
+}
+
+// builtins are keyed by name and arity, as jq tells functions apart
+var builtins = map[string]builtin{
+	"empty/0": {
+		eval:  func(*env, any, []node, func(any) error) error { return nil },
+		paths: func(*env, any, []node, func([]any, any) error) error { return nil },
+	},
+	"error/0": {eval: func(_ *env, input any, _ []node, _ func(any) error) error {
+		return &valueError{input}
+	}},
+	"error/1": {eval: func(e *env, input any, args []node, _ func(any) error) error {
+		return args[0].eval(e, input, func(message any) error { return &valueError{message} })
+	}},
+	"not/0": fn0(func(input any) (any, error) { return !truthy(input), nil }),
+	"length/0": fn0(func(input any) (any, error) {
+		switch input := input.(type) {
+		case nil:
+			return 0.0, nil
+		case float64:
+			return math.Abs(input), nil
+		case string:
+			return float64(utf8.RuneCountInString(input)), nil
+		case []any:
+			return float64(len(input)), nil
+		case map[string]any:
+			return float64(len(input)), nil
+		}
+		return nil, fmt.Errorf("%s has no length", describe(input))
+	}),
+	"keys/0": fn0(func(input any) (any, error) {
+		switch input := input.(type) {
+		case map[string]any:
+			keys := []any{}
+			for _, key := range sortedKeys(input) {
+				keys = append(keys, key)
+			}
+			return keys, nil
+		case []any:
+			keys := make([]any, len(input))
+			for i := range input {
+				keys[i] = float64(i)
+			}
+			return keys, nil
+		}
+		return nil, fmt.Errorf("%s has no keys", describe(input))
+	}),
+	"has/1": fn1(func(input, key any) (any, error) {
+		switch input := input.(type) {
+		case map[string]any:
+			if key, ok := key.(string); ok {
+				_, exists := input[key]
+				return exists, nil
+			}
+		case []any:
+			if key, ok := key.(float64); ok {
+				return key >= 0 && int(key) < len(input), nil
+			}
+		}
+		return nil, fmt.Errorf("cannot check whether %s has a key %s", typeName(input), describe(key))
+	}),
+	"contains/1": fn1(func(input, arg any) (any, error) { return contains(input, arg) }),
+	"add/0": fn0(func(input any) (any, error) {
+		if input == nil {
+			return nil, nil
+		}
+		elements, err := items(input)
+		if err != nil {
+			return nil, err
+		}
+		var sum any
+		for _, element := range elements {
+			if sum, err = arithmetic("+", sum, element); err != nil {
+				return nil, err
+			}
+		}
+		return sum, nil
+	}),
+	"any/0": fn0(func(input any) (any, error) {
+		elements, err := items(input)
+		return slices.ContainsFunc(elements, truthy), err
+	}),
+	"all/0": fn0(func(input any) (any, error) {
+		elements, err := items(input)
+		return !slices.ContainsFunc(elements, func(v any) bool { return !truthy(v) }), err
+	}),
+	"any/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		elements, err := items(input)
+		if err != nil {
+			return err
+		}
+		for _, element := range elements {
+			outputs, err := collect(args[0], e, element)
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(outputs, truthy) {
+				return emit(true)
+			}
+		}
+		return emit(false)
+	}},
+	"all/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		elements, err := items(input)
+		if err != nil {
+			return err
+		}
+		for _, element := range elements {
+			outputs, err := collect(args[0], e, element)
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(outputs, func(v any) bool { return !truthy(v) }) {
+				return emit(false)
+			}
+		}
+		return emit(true)
+	}},
+	"range/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		return args[0].eval(e, input, func(upto any) error {
+			n, err := number(upto)
+			if err != nil {
+				return err
+			}
+			for i := 0.0; i < n; i++ {
+				if err := e.step(); err != nil {
+					return err
+				}
+				if err := emit(i); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}},
+	"range/2": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		return args[0].eval(e, input, func(from any) error {
+			return args[1].eval(e, input, func(upto any) error {
+				start, err := number(from)
+				if err != nil {
+					return err
+				}
+				end, err := number(upto)
+				if err != nil {
+					return err
+				}
+				for i := start; i < end; i++ {
+					if err := e.step(); err != nil {
+						return err
+					}
+					if err := emit(i); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	}},
+	"floor/0": math1(math.Floor),
+	"ceil/0":  math1(math.Ceil),
+	"round/0": math1(math.Round),
+	"sqrt/0":  math1(math.Sqrt),
+	"abs/0":   math1(math.Abs),
+	"tostring/0": fn0(func(input any) (any, error) {
+		if s, ok := input.(string); ok {
+			return s, nil
+		}
+		return toJSON(input), nil
+	}),
+	"tonumber/0": fn0(func(input any) (any, error) {
+		switch input := input.(type) {
+		case float64:
+			return input, nil
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse %s as a number", describe(input))
+			}
+			return n, nil
+		}
+		return nil, fmt.Errorf("%s cannot be parsed as a number", describe(input))
+	}),
+	"type/0":   fn0(func(input any) (any, error) { return typeName(input), nil }),
+	"tojson/0": fn0(func(input any) (any, error) { return toJSON(input), nil }),
+	"fromjson/0": fn0(func(input any) (any, error) {
+		s, err := text(input)
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, fmt.Errorf("%s is not valid JSON: %w", describe(input), err)
+		}
+		return value, nil
+	}),
+	"reverse/0": fn0(func(input any) (any, error) {
+		switch input := input.(type) {
+		case nil:
+			return []any{}, nil
+		case string:
+			runes := []rune(input)
+			slices.Reverse(runes)
+			return string(runes), nil
+		case []any:
+			reversed := slices.Clone(input)
+			slices.Reverse(reversed)
+			return reversed, nil
+		}
+		return nil, fmt.Errorf("%s cannot be reversed", describe(input))
+	}),
+	"sort/0": fn0(func(input any) (any, error) {
+		elements, ok := input.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s cannot be sorted, as it is not an array", describe(input))
+		}
+		sorted, _ := sortedBy(elements, identityKeys(elements))
+		return sorted, nil
+	}),
+	"sort_by/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		elements, keys, err := sortKeys(e, input, args[0])
+		if err != nil {
+			return err
+		}
+		sorted, _ := sortedBy(elements, keys)
+		return emit(sorted)
+	}},
+	"group_by/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		elements, keys, err := sortKeys(e, input, args[0])
+		if err != nil {
+			return err
+		}
+		sorted, keys := sortedBy(elements, keys)
+		groups := []any{}
+		for i, element := range sorted {
+			if i == 0 || compare(keys[i], keys[i-1]) != 0 {
+				groups = append(groups, []any{})
+			}
+			groups[len(groups)-1] = append(groups[len(groups)-1].([]any), element)
+		}
+		return emit(groups)
+	}},
+	"unique/0": fn0(func(input any) (any, error) {
+		elements, ok := input.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s cannot be sorted, as it is not an array", describe(input))
+		}
+		sorted, _ := sortedBy(elements, identityKeys(elements))
+		return slices.CompactFunc(sorted, func(a, b any) bool { return compare(a, b) == 0 }), nil
+	}),
+	"unique_by/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		elements, keys, err := sortKeys(e, input, args[0])
+		if err != nil {
+			return err
+		}
+		sorted, keys := sortedBy(elements, keys)
+		unique := []any{}
+		for i, element := range sorted {
+			if i == 0 || compare(keys[i], keys[i-1]) != 0 {
+				unique = append(unique, element)
+			}
+		}
+		return emit(unique)
+	}},
+	"min/0": fn0(func(input any) (any, error) {
+		elements, err := items(input)
+		return extremeBy(elements, identityKeys(elements), false), err
+	}),
+	"max/0": fn0(func(input any) (any, error) {
+		elements, err := items(input)
+		return extremeBy(elements, identityKeys(elements), true), err
+	}),
+	"min_by/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		elements, keys, err := sortKeys(e, input, args[0])
+		if err != nil {
+			return err
+		}
+		return emit(extremeBy(elements, keys, false))
+	}},
+	"max_by/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		elements, keys, err := sortKeys(e, input, args[0])
+		if err != nil {
+			return err
+		}
+		return emit(extremeBy(elements, keys, true))
+	}},
+	"to_entries/0":   fn0(toEntries),
+	"from_entries/0": fn0(fromEntries),
+	"with_entries/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		entries, err := toEntries(input)
+		if err != nil {
+			return err
+		}
+		mapped := []any{}
+		for _, entry := range entries.([]any) {
+			outputs, err := collect(args[0], e, entry)
+			if err != nil {
+				return err
+			}
+			mapped = append(mapped, outputs...)
+		}
+		object, err := fromEntries(mapped)
+		if err != nil {
+			return err
+		}
+		return emit(object)
+	}},
+	"map/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		elements, err := items(input)
+		if err != nil {
+			return err
+		}
+		mapped := []any{}
+		for _, element := range elements {
+			outputs, err := collect(args[0], e, element)
+			if err != nil {
+				return err
+			}
+			mapped = append(mapped, outputs...)
+		}
+		return emit(mapped)
+	}},
+	"map_values/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		result, err := update(iterate{identity{}}, e, input, func(value any) (any, bool, error) {
+			return first(args[0], e, value)
+		})
+		if err != nil {
+			return err
+		}
+		return emit(result)
+	}},
+	"select/1": {
+		eval: func(e *env, input any, args []node, emit func(any) error) error {
+			return args[0].eval(e, input, func(cond any) error {
+				if truthy(cond) {
+					return emit(input)
+				}
+				return nil
+			})
+		},
+		paths: func(e *env, input any, args []node, emit func([]any, any) error) error {
+			return args[0].eval(e, input, func(cond any) error {
+				if truthy(cond) {
+					return emit(nil, input)
+				}
+				return nil
+			})
+		},
+	},
+	"recurse/0": {
+		eval: func(e *env, input any, _ []node, emit func(any) error) error {
+			return recurse{}.eval(e, input, emit)
+		},
+		paths: func(e *env, input any, _ []node, emit func([]any, any) error) error {
+			return recurse{}.paths(e, input, emit)
+		},
+	},
+	"recurse/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		var r func(depth int, value any) error
+		r = func(depth int, value any) error {
+			if depth > maxDepth {
+				return fmt.Errorf("%w: recurse deeper than %d levels", errBudget, maxDepth)
+			}
+			if err := e.step(); err != nil {
+				return err
+			}
+			if err := emit(value); err != nil {
+				return err
+			}
+			return args[0].eval(e, value, func(child any) error {
+				return r(depth+1, child)
+			})
+		}
+		return r(0, input)
+	}},
+	"flatten/0": fn0(func(input any) (any, error) { return flatten(input, math.Inf(1)) }),
+	"flatten/1": fn1(func(input, depth any) (any, error) {
+		d, err := number(depth)
+		if err != nil {
+			return nil, err
+		}
+		return flatten(input, d)
+	}),
+	"first/0": fn0(func(input any) (any, error) { return indexValue(input, 0.0) }),
+	"last/0":  fn0(func(input any) (any, error) { return indexValue(input, -1.0) }),
+	"first/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		value, found, err := first(args[0], e, input)
+		if err != nil || !found {
+			return err
+		}
+		return emit(value)
+	}},
+	"last/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		outputs, err := collect(args[0], e, input)
+		if err != nil || len(outputs) == 0 {
+			return err
+		}
+		return emit(outputs[len(outputs)-1])
+	}},
+	"limit/2": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		return args[0].eval(e, input, func(n any) error {
+			limit, err := number(n)
+			if err != nil {
+				return err
+			}
+			if limit <= 0 {
+				return nil
+			}
+			taken := 0.0
+			err = args[1].eval(e, input, func(output any) error {
+				if err := emit(output); err != nil {
+					return outerError{err}.wrap()
+				}
+				if taken++; taken >= limit {
+					return errBreak
+				}
+				return nil
+			})
+			var outer *outerError
+			if errors.As(err, &outer) {
+				return outer.err
+			}
+			if errors.Is(err, errBreak) {
+				return nil
+			}
+			return err
+		})
+	}},
+	"ascii_downcase/0": strings0(strings.ToLower),
+	"ascii_upcase/0":   strings0(strings.ToUpper),
+	"trim/0":           strings0(strings.TrimSpace),
+	"ltrim/0":          strings0(func(s string) string { return strings.TrimLeft(s, " \t\r\n") }),
+	"rtrim/0":          strings0(func(s string) string { return strings.TrimRight(s, " \t\r\n") }),
+	"startswith/1":     strings1(func(s, prefix string) any { return strings.HasPrefix(s, prefix) }),
+	"endswith/1":       strings1(func(s, suffix string) any { return strings.HasSuffix(s, suffix) }),
+	"split/1":          strings1(func(s, separator string) any { return splitString(s, separator) }),
+	"ltrimstr/1": fn1(func(input, prefix any) (any, error) {
+		s, ok1 := input.(string)
+		p, ok2 := prefix.(string)
+		if ok1 && ok2 {
+			return strings.TrimPrefix(s, p), nil
+		}
+		return input, nil
+	}),
+	"rtrimstr/1": fn1(func(input, suffix any) (any, error) {
+		s, ok1 := input.(string)
+		p, ok2 := suffix.(string)
+		if ok1 && ok2 {
+			return strings.TrimSuffix(s, p), nil
+		}
+		return input, nil
+	}),
+	"join/1": fn1(func(input, separator any) (any, error) {
+		elements, err := items(input)
+		if err != nil {
+			return nil, err
+		}
+		sep, err := text(separator)
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(elements))
+		for i, element := range elements {
+			switch element := element.(type) {
+			case nil:
+			case string:
+				parts[i] = element
+			case float64, bool:
+				parts[i] = toJSON(element)
+			default:
+				return nil, fmt.Errorf("cannot join with %s", describe(element))
+			}
+		}
+		return strings.Join(parts, sep), nil
+	}),
+	"test/1": fn1(func(input, pattern any) (any, error) {
+		s, err := text(input)
+		if err != nil {
+			return nil, err
+		}
+		re, err := compileRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	}),
+	"capture/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		s, err := text(input)
+		if err != nil {
+			return err
+		}
+		return args[0].eval(e, input, func(pattern any) error {
+			re, err := compileRegexp(pattern)
+			if err != nil {
+				return err
+			}
+			if match := re.FindStringSubmatchIndex(s); match != nil {
+				return emit(captures(re, s, match))
+			}
+			return nil
+		})
+	}},
+	"sub/2":  substitute(false),
+	"gsub/2": substitute(true),
+	"walk/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		return walk(e, input, args[0], emit)
+	}},
+	"del/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		var paths [][]any
+		if err := pathsOf(args[0], e, input, func(path []any, _ any) error {
+			paths = append(paths, path)
+			return nil
+		}); err != nil {
+			return err
+		}
+		result, err := deletePaths(input, paths)
+		if err != nil {
+			return err
+		}
+		return emit(result)
+	}},
+	"path/1": {eval: func(e *env, input any, args []node, emit func(any) error) error {
+		return pathsOf(args[0], e, input, func(path []any, _ any) error {
+			return emit(append([]any{}, path...))
+		})
+	}},
+	"paths/0": {eval: func(e *env, input any, _ []node, emit func(any) error) error {
+		return recurse{}.paths(e, input, func(path []any, _ any) error {
+			if len(path) == 0 {
+				return nil
+			}
+			return emit(append([]any{}, path...))
+		})
+	}},
+	"getpath/1": {
+		eval: func(e *env, input any, args []node, emit func(any) error) error {
+			return args[0].eval(e, input, func(path any) error {
+				keys, ok := path.([]any)
+				if !ok {
+					return fmt.Errorf("path must be an array, got %s", describe(path))
+				}
+				value, err := getPath(input, keys)
+				if err != nil {
+					return err
+				}
+				return emit(value)
+			})
+		},
+		paths: func(e *env, input any, args []node, emit func([]any, any) error) error {
+			return args[0].eval(e, input, func(path any) error {
+				keys, ok := path.([]any)
+				if !ok {
+					return fmt.Errorf("path must be an array, got %s", describe(path))
+				}
+				value, err := getPath(input, keys)
+				if err != nil {
+					return err
+				}
+				return emit(keys, value)
+			})
+		},
+	},
+	"values/0":    typeFilter("boolean", "number", "string", "array", "object"),
+	"nulls/0":     typeFilter("null"),
+	"booleans/0":  typeFilter("boolean"),
+	"numbers/0":   typeFilter("number"),
+	"strings/0":   typeFilter("string"),
+	"arrays/0":    typeFilter("array"),
+	"objects/0":   typeFilter("object"),
+	"iterables/0": typeFilter("array", "object"),
+	"scalars/0":   typeFilter("null", "boolean", "number", "string"),
+}
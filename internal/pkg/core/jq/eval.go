@@ -0,0 +1,877 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package jq
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// node is a compiled filter. eval passes each output for input to emit, and
+// stops at the first error.
+type node interface {
+	eval(e *env, input any, emit func(any) error) error
+}
+
+// pathNode is a filter that can be a path expression, the left side of an
+// assignment or the argument of del. paths passes the path of each output
+// below input, with the value there.
+type pathNode interface {
+	paths(e *env, input any, emit func(path []any, value any) error) error
+}
+
+// pathsOf runs paths of n, or fails when n is not a path expression
+func pathsOf(n node, e *env, input any, emit func(path []any, value any) error) error {
+	p, ok := n.(pathNode)
+	if !ok {
+		return errors.New("invalid path expression")
+	}
+	return p.paths(e, input, emit)
+}
+
+// collect returns all outputs of n for input
+func collect(n node, e *env, input any) ([]any, error) {
+	var outputs []any
+	err := n.eval(e, input, func(output any) error {
+		outputs = append(outputs, output)
+		return nil
+	})
+	return outputs, err
+}
+
+// collectCounted is collect for the values a run keeps, counted against its
+// budget
+func collectCounted(n node, e *env, input any) ([]any, error) {
+	var outputs []any
+	err := n.eval(e, input, func(output any) error {
+		outputs = append(outputs, output)
+		return e.collect()
+	})
+	return outputs, err
+}
+
+// first returns the first output of n for input
+func first(n node, e *env, input any) (any, bool, error) {
+	var result any
+	found := false
+	err := n.eval(e, input, func(output any) error {
+		result, found = output, true
+		return errBreak
+	})
+	if err != nil && !errors.Is(err, errBreak) {
+		return nil, false, err
+	}
+	return result, found, nil
+}
+
+type identity struct{}
+
+func (identity) eval(_ *env, input any, emit func(any) error) error {
+	return emit(input)
+}
+
+func (identity) paths(_ *env, input any, emit func([]any, any) error) error {
+	return emit(nil, input)
+}
+
+// recurse is .., the input and everything below it, parents first
+type recurse struct{}
+
+func (r recurse) eval(e *env, input any, emit func(any) error) error {
+	return r.paths(e, input, func(_ []any, value any) error { return emit(value) })
+}
+
+func (recurse) paths(_ *env, input any, emit func([]any, any) error) error {
+	var walk func(path []any, value any) error
+	walk = func(path []any, value any) error {
+		if err := emit(path, value); err != nil {
+			return err
+		}
+		switch value := value.(type) {
+		case []any:
+			for i, item := range value {
+				if err := walk(append(slices.Clip(path), float64(i)), item); err != nil {
+					return err
+				}
+			}
+		case map[string]any:
+			for _, key := range sortedKeys(value) {
+				if err := walk(append(slices.Clip(path), key), value[key]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(nil, input)
+}
+
+type literal struct{ value any }
+
+func (l literal) eval(_ *env, _ any, emit func(any) error) error {
+	return emit(l.value)
+}
+
+type variable struct{ name string }
+
+func (v variable) eval(e *env, _ any, emit func(any) error) error {
+	value, _ := e.lookup(v.name)
+	return emit(value)
+}
+
+// index is target[key], with .name and ."name" for string keys. The key is
+// evaluated against the same input as the target.
+type index struct {
+	target node
+	key    node
+}
+
+func (ix index) eval(e *env, input any, emit func(any) error) error {
+	return ix.target.eval(e, input, func(target any) error {
+		return ix.key.eval(e, input, func(key any) error {
+			value, err := indexValue(target, key)
+			if err != nil {
+				return err
+			}
+			return emit(value)
+		})
+	})
+}
+
+func (ix index) paths(e *env, input any, emit func([]any, any) error) error {
+	return pathsOf(ix.target, e, input, func(path []any, target any) error {
+		return ix.key.eval(e, input, func(key any) error {
+			value, err := indexValue(target, key)
+			if err != nil {
+				return err
+			}
+			return emit(append(slices.Clip(path), key), value)
+		})
+	})
+}
+
+func indexValue(target, key any) (any, error) {
+	switch target := target.(type) {
+	case nil:
+		switch key.(type) {
+		case string, float64, nil:
+			return nil, nil
+		}
+	case map[string]any:
+		if key, ok := key.(string); ok {
+			return target[key], nil
+		}
+	case []any:
+		if key, ok := key.(float64); ok {
+			i, ok := arrayIndex(key, len(target))
+			if !ok {
+				return nil, nil
+			}
+			return target[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cannot index %s with %s", typeName(target), describe(key))
+}
+
+// arrayIndex resolves a possibly negative index into an array of length
+// elements. It is compared as a float: int() of a value beyond the int range
+// is undefined.
+func arrayIndex(number float64, length int) (int, bool) {
+	index := math.Floor(number)
+	if index < 0 {
+		index += float64(length)
+	}
+	if !(index >= 0 && index < float64(length)) {
+		return 0, false
+	}
+	return int(index), true
+}
+
+// clampIndex resolves a possibly negative slice bound into [0, length]
+func clampIndex(number float64, length int) int {
+	index := math.Floor(number)
+	if index < 0 {
+		index += float64(length)
+	}
+	switch {
+	case math.IsNaN(index) || index <= 0:
+		return 0
+	case index >= float64(length):
+		return length
+	}
+	return int(index)
+}
+
+// slice is target[from:to], of an array or string
+type slice struct {
+	target   node
+	from, to node // nil for the start or end
+}
+
+func (s slice) eval(e *env, input any, emit func(any) error) error {
+	bound := func(n node) (any, error) {
+		if n == nil {
+			return nil, nil
+		}
+		value, _, err := first(n, e, input)
+		return value, err
+	}
+	from, err := bound(s.from)
+	if err != nil {
+		return err
+	}
+	to, err := bound(s.to)
+	if err != nil {
+		return err
+	}
+	return s.target.eval(e, input, func(target any) error {
+		var length int
+		switch target := target.(type) {
+		case nil:
+			return emit(nil)
+		case []any:
+			length = len(target)
+		case string:
+			length = len([]rune(target))
+		default:
+			return fmt.Errorf("cannot slice %s", describe(target))
+		}
+		start, end, err := sliceBounds(from, to, length)
+		if err != nil {
+			return err
+		}
+		if array, ok := target.([]any); ok {
+			return emit(slices.Clone(array[start:end]))
+		}
+		return emit(string([]rune(target.(string))[start:end]))
+	})
+}
+
+func sliceBounds(from, to any, length int) (int, int, error) {
+	bound := func(v any, fallback int) (int, error) {
+		if v == nil {
+			return fallback, nil
+		}
+		number, ok := v.(float64)
+		if !ok {
+			return 0, fmt.Errorf("slice indices must be numbers, got %s", describe(v))
+		}
+		return clampIndex(number, length), nil
+	}
+	start, err := bound(from, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := bound(to, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, max(start, end), nil
+}
+
+// iterate is target[], the elements of an array or the values of an object
+type iterate struct{ target node }
+
+func (it iterate) eval(e *env, input any, emit func(any) error) error {
+	return it.target.eval(e, input, func(target any) error {
+		return iterateValue(target, func(_ any, value any) error { return emit(value) })
+	})
+}
+
+func (it iterate) paths(e *env, input any, emit func([]any, any) error) error {
+	return pathsOf(it.target, e, input, func(path []any, target any) error {
+		return iterateValue(target, func(key any, value any) error {
+			return emit(append(slices.Clip(path), key), value)
+		})
+	})
+}
+
+// iterateValue passes the elements of an array or the values of an object,
+// by sorted key, with their index or key
+func iterateValue(target any, emit func(key, value any) error) error {
+	switch target := target.(type) {
+	case []any:
+		for i, item := range target {
+			if err := emit(float64(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		for _, key := range sortedKeys(target) {
+			if err := emit(key, target[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot iterate over %s", describe(target))
+}
+
+// optional is body?, which ends quietly at the first error of body
+type optional struct{ body node }
+
+func (o optional) eval(e *env, input any, emit func(any) error) error {
+	return suppress(o.body.eval(e, input, func(output any) error {
+		return outerError{emit(output)}.wrap()
+	}))
+}
+
+func (o optional) paths(e *env, input any, emit func([]any, any) error) error {
+	return suppress(pathsOf(o.body, e, input, func(path []any, value any) error {
+		return outerError{emit(path, value)}.wrap()
+	}))
+}
+
+// outerError marks errors raised downstream of a try or optional, which
+// must pass through it rather than be caught
+type outerError struct{ err error }
+
+func (o outerError) Error() string { return o.err.Error() }
+
+func (o outerError) Unwrap() error { return o.err }
+
+func (o outerError) wrap() error {
+	if o.err == nil {
+		return nil
+	}
+	return &o
+}
+
+// suppress drops an error raised inside a try or optional
+func suppress(err error) error {
+	var outer *outerError
+	if errors.As(err, &outer) {
+		return outer.err
+	}
+	if errors.Is(err, errBudget) {
+		return err
+	}
+	return nil
+}
+
+type try struct {
+	body    node
+	handler node // nil without catch
+}
+
+func (t try) eval(e *env, input any, emit func(any) error) error {
+	err := t.body.eval(e, input, func(output any) error {
+		return outerError{emit(output)}.wrap()
+	})
+	var outer *outerError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &outer):
+		return outer.err
+	case errors.Is(err, errBudget):
+		return err
+	case errors.Is(err, errBreak) || t.handler == nil:
+		return nil
+	}
+	return t.handler.eval(e, errorValue(err), emit)
+}
+
+type pipe struct{ left, right node }
+
+func (p pipe) eval(e *env, input any, emit func(any) error) error {
+	return p.left.eval(e, input, func(output any) error {
+		if err := e.step(); err != nil {
+			return err
+		}
+		return p.right.eval(e, output, emit)
+	})
+}
+
+func (p pipe) paths(e *env, input any, emit func([]any, any) error) error {
+	return pathsOf(p.left, e, input, func(leftPath []any, value any) error {
+		return pathsOf(p.right, e, value, func(rightPath []any, value any) error {
+			return emit(append(slices.Clip(leftPath), rightPath...), value)
+		})
+	})
+}
+
+type comma struct{ left, right node }
+
+func (c comma) eval(e *env, input any, emit func(any) error) error {
+	if err := c.left.eval(e, input, emit); err != nil {
+		return err
+	}
+	return c.right.eval(e, input, emit)
+}
+
+func (c comma) paths(e *env, input any, emit func([]any, any) error) error {
+	if err := pathsOf(c.left, e, input, emit); err != nil {
+		return err
+	}
+	return pathsOf(c.right, e, input, emit)
+}
+
+// array is [body], collecting the outputs of body
+type array struct{ body node }
+
+func (a array) eval(e *env, input any, emit func(any) error) error {
+	items := []any{}
+	if a.body != nil {
+		outputs, err := collectCounted(a.body, e, input)
+		if err != nil {
+			return err
+		}
+		items = append(items, outputs...)
+	}
+	return emit(items)
+}
+
+type objectEntry struct {
+	key, value node
+}
+
+// object constructs an object, once for each combination of the outputs of
+// its keys and values
+type object struct{ entries []objectEntry }
+
+func (o object) eval(e *env, input any, emit func(any) error) error {
+	var build func(i int, partial map[string]any) error
+	build = func(i int, partial map[string]any) error {
+		if i == len(o.entries) {
+			result := make(map[string]any, len(partial))
+			for key, value := range partial {
+				result[key] = value
+			}
+			return emit(result)
+		}
+		entry := o.entries[i]
+		return entry.key.eval(e, input, func(key any) error {
+			name, ok := key.(string)
+			if !ok {
+				return fmt.Errorf("object keys must be strings, got %s", describe(key))
+			}
+			return entry.value.eval(e, input, func(value any) error {
+				previous, existed := partial[name]
+				partial[name] = value
+				err := build(i+1, partial)
+				if existed {
+					partial[name] = previous
+				} else {
+					delete(partial, name)
+				}
+				return err
+			})
+		})
+	}
+	return build(0, make(map[string]any, len(o.entries)))
+}
+
+// interpolation is a string with \(...) parts, once for each combination of
+// their outputs
+type interpolation struct{ parts []node }
+
+func (in interpolation) eval(e *env, input any, emit func(any) error) error {
+	var build func(i int, prefix string) error
+	build = func(i int, prefix string) error {
+		if i == len(in.parts) {
+			return emit(prefix)
+		}
+		return in.parts[i].eval(e, input, func(part any) error {
+			text, ok := part.(string)
+			if !ok {
+				text = toJSON(part)
+			}
+			return build(i+1, prefix+text)
+		})
+	}
+	return build(0, "")
+}
+
+type negate struct{ operand node }
+
+func (n negate) eval(e *env, input any, emit func(any) error) error {
+	return n.operand.eval(e, input, func(value any) error {
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s cannot be negated", describe(value))
+		}
+		return emit(-number)
+	})
+}
+
+// binary is an arithmetic or comparison operator, applied to each
+// combination of the outputs of its operands
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (b binary) eval(e *env, input any, emit func(any) error) error {
+	return b.right.eval(e, input, func(right any) error {
+		return b.left.eval(e, input, func(left any) error {
+			var result any
+			switch b.op {
+			case "==":
+				result = compare(left, right) == 0
+			case "!=":
+				result = compare(left, right) != 0
+			case "<":
+				result = compare(left, right) < 0
+			case "<=":
+				result = compare(left, right) <= 0
+			case ">":
+				result = compare(left, right) > 0
+			case ">=":
+				result = compare(left, right) >= 0
+			default:
+				var err error
+				if result, err = arithmetic(b.op, left, right); err != nil {
+					return err
+				}
+			}
+			return emit(result)
+		})
+	})
+}
+
+// logical is and or or, evaluating the right operand only when the left
+// does not decide the result
+type logical struct {
+	and         bool
+	left, right node
+}
+
+func (l logical) eval(e *env, input any, emit func(any) error) error {
+	return l.left.eval(e, input, func(left any) error {
+		if truthy(left) != l.and {
+			return emit(!l.and)
+		}
+		return l.right.eval(e, input, func(right any) error {
+			return emit(truthy(right))
+		})
+	})
+}
+
+// alternative is left // right: the outputs of left other than false and
+// null, or those of right when there are none
+type alternative struct{ left, right node }
+
+func (a alternative) eval(e *env, input any, emit func(any) error) error {
+	found := false
+	err := a.left.eval(e, input, func(output any) error {
+		if !truthy(output) {
+			return nil
+		}
+		found = true
+		return outerError{emit(output)}.wrap()
+	})
+	var outer *outerError
+	if errors.As(err, &outer) {
+		return outer.err
+	}
+	if errors.Is(err, errBudget) {
+		return err
+	}
+	if found {
+		return nil
+	}
+	return a.right.eval(e, input, emit)
+}
+
+type conditional struct {
+	cond, then, otherwise node // otherwise is the input without else
+}
+
+func (c conditional) eval(e *env, input any, emit func(any) error) error {
+	return c.cond.eval(e, input, func(cond any) error {
+		if truthy(cond) {
+			return c.then.eval(e, input, emit)
+		}
+		return c.otherwise.eval(e, input, emit)
+	})
+}
+
+func (c conditional) paths(e *env, input any, emit func([]any, any) error) error {
+	return c.cond.eval(e, input, func(cond any) error {
+		if truthy(cond) {
+			return pathsOf(c.then, e, input, emit)
+		}
+		return pathsOf(c.otherwise, e, input, emit)
+	})
+}
+
+// bind is source as $name | body, running body once for each output of
+// source
+type bind struct {
+	source node
+	name   string
+	body   node
+}
+
+func (b bind) eval(e *env, input any, emit func(any) error) error {
+	return b.source.eval(e, input, func(value any) error {
+		if err := e.step(); err != nil {
+			return err
+		}
+		return b.body.eval(e.bind(b.name, value), input, emit)
+	})
+}
+
+func (b bind) paths(e *env, input any, emit func([]any, any) error) error {
+	return b.source.eval(e, input, func(value any) error {
+		return pathsOf(b.body, e.bind(b.name, value), input, emit)
+	})
+}
+
+// reduce is reduce source as $name (init; update). The accumulator becomes
+// the last output of update, or null when it has none.
+type reduce struct {
+	source       node
+	name         string
+	init, update node
+}
+
+func (r reduce) eval(e *env, input any, emit func(any) error) error {
+	return r.init.eval(e, input, func(acc any) error {
+		err := r.source.eval(e, input, func(value any) error {
+			if err := e.step(); err != nil {
+				return err
+			}
+			outputs, err := collect(r.update, e.bind(r.name, value), acc)
+			if err != nil {
+				return err
+			}
+			acc = nil
+			if len(outputs) > 0 {
+				acc = outputs[len(outputs)-1]
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return emit(acc)
+	})
+}
+
+// assign is lhs = rhs, lhs |= rhs or lhs op= rhs
+type assign struct {
+	op       string
+	lhs, rhs node
+}
+
+func (a assign) eval(e *env, input any, emit func(any) error) error {
+	if a.op == "|=" {
+		result, err := update(a.lhs, e, input, func(value any) (any, bool, error) {
+			return first(a.rhs, e, value)
+		})
+		if err != nil {
+			return err
+		}
+		return emit(result)
+	}
+	// The right side is evaluated against the input, once for each output
+	return a.rhs.eval(e, input, func(rhs any) error {
+		result, err := update(a.lhs, e, input, func(value any) (any, bool, error) {
+			if a.op == "=" {
+				return rhs, true, nil
+			}
+			updated, err := arithmetic(strings.TrimSuffix(a.op, "="), value, rhs)
+			return updated, true, err
+		})
+		if err != nil {
+			return err
+		}
+		return emit(result)
+	})
+}
+
+// update replaces the value at each path of lhs with the result of f, and
+// deletes it when f has none
+func update(lhs node, e *env, input any, f func(value any) (any, bool, error)) (any, error) {
+	var paths [][]any
+	if err := pathsOf(lhs, e, input, func(path []any, _ any) error {
+		paths = append(paths, path)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	result := input
+	var deleted [][]any
+	for _, path := range paths {
+		value, err := getPath(result, path)
+		if err != nil {
+			return nil, err
+		}
+		updated, ok, err := f(value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			deleted = append(deleted, path)
+			continue
+		}
+		if result, err = setPath(result, path, updated); err != nil {
+			return nil, err
+		}
+	}
+	return deletePaths(result, deleted)
+}
+
+func getPath(value any, path []any) (any, error) {
+	for _, key := range path {
+		if value == nil {
+			return nil, nil
+		}
+		var err error
+		if value, err = indexValue(value, key); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// maxArrayIndex bounds the index an assignment may grow an array to, so a
+// computed index cannot allocate gigabytes
+const maxArrayIndex = 1 << 20
+
+// setPath returns a copy of root with the value at path replaced, creating
+// objects and arrays on the way as needed
+func setPath(root any, path []any, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	switch key := path[0].(type) {
+	case string:
+		var object map[string]any
+		switch root := root.(type) {
+		case nil:
+			object = map[string]any{}
+		case map[string]any:
+			object = make(map[string]any, len(root)+1)
+			for k, v := range root {
+				object[k] = v
+			}
+		default:
+			return nil, fmt.Errorf("cannot index %s with %s", typeName(root), describe(key))
+		}
+		child, err := setPath(object[key], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		object[key] = child
+		return object, nil
+	case float64:
+		var items []any
+		switch root := root.(type) {
+		case nil:
+		case []any:
+			items = slices.Clone(root)
+		default:
+			return nil, fmt.Errorf("cannot index %s with %s", typeName(root), describe(key))
+		}
+		index := math.Floor(key)
+		if index < 0 {
+			if index += float64(len(items)); index < 0 {
+				return nil, errors.New("out of bounds negative array index")
+			}
+		}
+		// Compared as floats: int() of an index beyond the int range is undefined
+		if !(index < float64(len(items))) && !(index < maxArrayIndex) {
+			return nil, errors.New("Array index too large")
+		}
+		i := int(index)
+		for len(items) <= i {
+			items = append(items, nil)
+		}
+		child, err := setPath(items[i], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = child
+		return items, nil
+	}
+	return nil, fmt.Errorf("invalid path component %s", describe(path[0]))
+}
+
+// deletePaths removes the values at paths, the last array elements first so
+// earlier indexes stay valid
+func deletePaths(root any, paths [][]any) (any, error) {
+	slices.SortFunc(paths, func(a, b []any) int { return compare(b, a) })
+	for _, path := range paths {
+		var err error
+		if root, err = deletePath(root, path); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+func deletePath(root any, path []any) (any, error) {
+	if len(path) == 0 || root == nil {
+		return nil, nil
+	}
+	key := path[0]
+	switch root := root.(type) {
+	case map[string]any:
+		name, ok := key.(string)
+		if !ok {
+			break
+		}
+		child, exists := root[name]
+		if !exists {
+			return root, nil
+		}
+		object := make(map[string]any, len(root))
+		for k, v := range root {
+			object[k] = v
+		}
+		if len(path) == 1 {
+			delete(object, name)
+			return object, nil
+		}
+		updated, err := deletePath(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+		object[name] = updated
+		return object, nil
+	case []any:
+		number, ok := key.(float64)
+		if !ok {
+			break
+		}
+		i, ok := arrayIndex(number, len(root))
+		if !ok {
+			return root, nil
+		}
+		if len(path) == 1 {
+			return slices.Delete(slices.Clone(root), i, i+1), nil
+		}
+		updated, err := deletePath(root[i], path[1:])
+		if err != nil {
+			return nil, err
+		}
+		items := slices.Clone(root)
+		items[i] = updated
+		return items, nil
+	}
+	return nil, fmt.Errorf("cannot delete field at %s of %s", describe(key), typeName(root))
+}
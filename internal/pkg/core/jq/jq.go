@@ -0,0 +1,416 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package jq reshapes JSON with programs in a subset of the jq language, e.g.
+//
+//	{id: .order.id, lines: [.order.items[] | select(.qty > 0) | {sku, qty}]}
+//	.customer |= {name: "\(.first) \(.last)"} | del(.internal)
+//	reduce .items[] as $item (0; . + $item.price * $item.qty)
+//
+// Supported are paths (.a.b, ."key", .[0], .[1:3], .[], ..), the pipe and
+// comma operators, array and object construction, string interpolation,
+// arithmetic, comparison, and, or and the alternative operator //,
+// if-then-elif-else, try-catch and the optional operator ?, variables bound
+// with as, reduce, assignment with =, |= and the arithmetic update
+// operators, and the commonly used builtins such as map, select, sort_by,
+// group_by, to_entries, with_entries, walk, del, split, join, test and gsub.
+// Function definitions, formats such as @csv and I/O builtins are not.
+// Assignments fail with "Array index too large" rather than grow an array
+// beyond 1<<20 elements, and a run fails once it passes more than 1<<22
+// values between filters, collects more than 1<<20 values or nests
+// recurse(f) deeper than 4096 levels.
+//
+// Values are those of decoded JSON: nil, bool, float64, string, []any and
+// map[string]any. Objects are maps, so their keys are written sorted rather
+// than in the order they were constructed.
+package jq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Program is a compiled jq program. It is safe for concurrent use.
+type Program struct {
+	source string
+	root   node
+}
+
+// String returns the source of the program
+func (p *Program) String() string {
+	return p.source
+}
+
+// Compile parses source, allowing only the named variables besides those the
+// program binds itself
+func Compile(source string, variables ...string) (*Program, error) {
+	p := &parser{lexer: lexer{source: source}, scope: variables}
+	root, err := p.parseProgram()
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq program %q: %w", source, err)
+	}
+	return &Program{source: source, root: root}, nil
+}
+
+// Run applies the program to input and returns its outputs, with vars bound
+// to the variables named when it was compiled
+func (p *Program) Run(input any, vars map[string]any) ([]any, error) {
+	e := &env{budget: &budget{}}
+	for name, value := range vars {
+		e = e.bind(name, value)
+	}
+	outputs, err := collectCounted(p.root, e, input)
+	if err != nil {
+		return nil, fmt.Errorf("jq program %q: %w", p.source, err)
+	}
+	return outputs, nil
+}
+
+// env binds variables, innermost first. The root of a run binds none and
+// holds the budget shared by the whole run.
+type env struct {
+	name   string
+	value  any
+	parent *env
+	budget *budget
+}
+
+func (e *env) bind(name string, value any) *env {
+	bound := &env{name: name, value: value, parent: e}
+	if e != nil {
+		bound.budget = e.budget
+	}
+	return bound
+}
+
+const (
+	// maxSteps bounds the values a run passes between filters, so a
+	// generator such as range(.n) cannot run for as long as the payload asks
+	maxSteps = 1 << 22
+	// maxCollected bounds the values a run collects into arrays and outputs
+	maxCollected = 1 << 20
+	// maxDepth bounds the nesting of recurse(f)
+	maxDepth = 1 << 12
+)
+
+// errBudget ends a run that exceeds its budget. try, ? and // do not catch it.
+var errBudget = errors.New("budget exceeded")
+
+// budget counts the work of one run
+type budget struct {
+	steps, collected int
+}
+
+// step counts a value passed between filters
+func (e *env) step() error {
+	if e == nil || e.budget == nil {
+		return nil
+	}
+	if e.budget.steps++; e.budget.steps > maxSteps {
+		return fmt.Errorf("%w: more than %d steps", errBudget, maxSteps)
+	}
+	return nil
+}
+
+// collect counts a value kept in an array or output
+func (e *env) collect() error {
+	if e == nil || e.budget == nil {
+		return nil
+	}
+	if e.budget.collected++; e.budget.collected > maxCollected {
+		return fmt.Errorf("%w: more than %d collected values", errBudget, maxCollected)
+	}
+	return nil
+}
+
+func (e *env) lookup(name string) (any, bool) {
+	for ; e != nil; e = e.parent {
+		if e.name == name {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+// valueError is raised by the error builtin, and caught by try with its value
+type valueError struct {
+	value any
+}
+
+func (e *valueError) Error() string {
+	if message, ok := e.value.(string); ok {
+		return message
+	}
+	return toJSON(e.value) + " (not a string)"
+}
+
+// errorValue is the value try passes to its catch handler for err
+func errorValue(err error) any {
+	var ve *valueError
+	if errors.As(err, &ve) {
+		return ve.value
+	}
+	return err.Error()
+}
+
+// errBreak stops a generator once enough outputs were taken
+var errBreak = errors.New("break")
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// describe names a value in error messages, e.g. string ("abc")
+func describe(v any) string {
+	text := toJSON(v)
+	if len(text) > 30 {
+		text = text[:27] + "..."
+	}
+	return fmt.Sprintf("%s (%s)", typeName(v), text)
+}
+
+func toJSON(v any) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func truthy(v any) bool {
+	return v != nil && v != false
+}
+
+// typeOrder ranks types in the order jq sorts values in
+func typeOrder(v any) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []any:
+		return 5
+	}
+	return 6
+}
+
+// compare orders values as jq does: null, false, true, numbers, strings,
+// arrays and objects, arrays element by element and objects by their sorted
+// keys, then by their values
+func compare(a, b any) int {
+	if order := typeOrder(a) - typeOrder(b); order != 0 {
+		return order
+	}
+	switch a := a.(type) {
+	case float64:
+		return cmpFloat(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case []any:
+		b := b.([]any)
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if order := compare(a[i], b[i]); order != 0 {
+				return order
+			}
+		}
+		return len(a) - len(b)
+	case map[string]any:
+		b := b.(map[string]any)
+		keysA, keysB := sortedKeys(a), sortedKeys(b)
+		if order := slices.Compare(keysA, keysB); order != 0 {
+			return order
+		}
+		for _, key := range keysA {
+			if order := compare(a[key], b[key]); order != 0 {
+				return order
+			}
+		}
+	}
+	return 0
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// arithmetic applies a binary arithmetic operator to two values
+func arithmetic(op string, a, b any) (any, error) {
+	switch op {
+	case "+":
+		switch {
+		case a == nil:
+			return b, nil
+		case b == nil:
+			return a, nil
+		}
+		switch x := a.(type) {
+		case float64:
+			if y, ok := b.(float64); ok {
+				return x + y, nil
+			}
+		case string:
+			if y, ok := b.(string); ok {
+				return x + y, nil
+			}
+		case []any:
+			if y, ok := b.([]any); ok {
+				return append(slices.Clone(x), y...), nil
+			}
+		case map[string]any:
+			if y, ok := b.(map[string]any); ok {
+				merged := make(map[string]any, len(x)+len(y))
+				for key, value := range x {
+					merged[key] = value
+				}
+				for key, value := range y {
+					merged[key] = value
+				}
+				return merged, nil
+			}
+		}
+	case "-":
+		switch x := a.(type) {
+		case float64:
+			if y, ok := b.(float64); ok {
+				return x - y, nil
+			}
+		case []any:
+			if y, ok := b.([]any); ok {
+				var kept []any
+				for _, item := range x {
+					if !slices.ContainsFunc(y, func(other any) bool { return compare(item, other) == 0 }) {
+						kept = append(kept, item)
+					}
+				}
+				if kept == nil {
+					kept = []any{}
+				}
+				return kept, nil
+			}
+		}
+	case "*":
+		switch x := a.(type) {
+		case float64:
+			if y, ok := b.(float64); ok {
+				return x * y, nil
+			}
+		case map[string]any:
+			if y, ok := b.(map[string]any); ok {
+				return deepMerge(x, y), nil
+			}
+		}
+	case "/":
+		switch x := a.(type) {
+		case float64:
+			if y, ok := b.(float64); ok {
+				if y == 0 {
+					return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", describe(a), describe(b))
+				}
+				return x / y, nil
+			}
+		case string:
+			if y, ok := b.(string); ok {
+				return splitString(x, y), nil
+			}
+		}
+	case "%":
+		x, ok1 := a.(float64)
+		y, ok2 := b.(float64)
+		if ok1 && ok2 {
+			if math.Trunc(y) == 0 {
+				return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", describe(a), describe(b))
+			}
+			return float64(int64(x) % int64(y)), nil
+		}
+	}
+	verb := map[string]string{"+": "added", "-": "subtracted", "*": "multiplied", "/": "divided", "%": "divided"}[op]
+	return nil, fmt.Errorf("%s and %s cannot be %s", describe(a), describe(b), verb)
+}
+
+func deepMerge(a, b map[string]any) map[string]any {
+	merged := make(map[string]any, len(a)+len(b))
+	for key, value := range a {
+		merged[key] = value
+	}
+	for key, value := range b {
+		x, ok1 := merged[key].(map[string]any)
+		y, ok2 := value.(map[string]any)
+		if ok1 && ok2 {
+			merged[key] = deepMerge(x, y)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+func splitString(s, separator string) []any {
+	if s == "" {
+		return []any{}
+	}
+	parts := strings.Split(s, separator)
+	split := make([]any, len(parts))
+	for i, part := range parts {
+		split[i] = part
+	}
+	return split
+}
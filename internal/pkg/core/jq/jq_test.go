@@ -0,0 +1,232 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package jq
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const order = `{
+	"order": {"id": 42, "customer": {"first": "Jane", "last": "Doe"}, "internal": true,
+		"items": [
+			{"sku": "A-1", "qty": 2, "price": 10.5, "tags": ["new"]},
+			{"sku": "B-2", "qty": 0, "price": 3, "tags": []},
+			{"sku": "C-3", "qty": 1, "price": 7, "tags": ["sale", "new"]}
+		]}
+}`
+
+func decode(t *testing.T, data string) any {
+	t.Helper()
+	var value any
+	require.NoError(t, json.Unmarshal([]byte(data), &value))
+	return value
+}
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		program  string
+		expected string // JSON array of the outputs
+	}{
+		{"Identity", ".order.id", `[42]`},
+		{"Optional index on a non object", ".order.id.x?", `[]`},
+		{"Quoted key and index", `.order."items"[-1].sku`, `["C-3"]`},
+		{"Slice", `[.order.items[1:][].sku]`, `[["B-2","C-3"]]`},
+		{"Slice beyond the int range", `[(.order.items[-1e19:1e19] | length), (.order.items[1e19:] | length)]`, `[[3,0]]`},
+		{"Index beyond the int range", `[.order.items[1e19], .order.items[-1e19]]`, `[[null,null]]`},
+		{"Delete beyond the int range", `.order.items | del(.[1e19], .[-1e19]) | length`, `[3]`},
+		{"Iterate and comma", `.order.items[] | .sku, .qty`, `["A-1",2,"B-2",0,"C-3",1]`},
+		{
+			"Object construction",
+			`{id: .order.id, lines: [.order.items[] | select(.qty > 0) | {sku, qty}]}`,
+			`[{"id":42,"lines":[{"sku":"A-1","qty":2},{"sku":"C-3","qty":1}]}]`,
+		},
+		{"Computed keys and variables", `.order as $o | {($o.customer.first): $o.id, $o}|keys`, `[["Jane","o"]]`},
+		{"String interpolation", `"\(.order.customer.first) \(.order.customer.last) #\(.order.id)"`, `["Jane Doe #42"]`},
+		{"Arithmetic", `[1 + 2 * 3, 10 / 4, 7 % 3, "a" + "b", [1] + [2], {"a":1} + {"b":2}, null + 1]`, `[[7,2.5,1,"ab",[1,2],{"a":1,"b":2},1]]`},
+		{"Subtraction and string division", `[[1,2,3,2] - [2], "a,b" / ","]`, `[[[1,3],["a","b"]]]`},
+		{"Object multiplication merges deeply", `{"a":{"b":1,"c":2}} * {"a":{"c":3}}`, `[{"a":{"b":1,"c":3}}]`},
+		{"Comparison and logic", `[1 < 2, "a" >= "b", null == false, true and false, false or true, (.missing | not)]`, `[[true,false,false,false,true,true]]`},
+		{"Alternative", `[.missing // "default", (false, 1) // 2, (null, false) // 3]`, `[["default",1,3]]`},
+		{"If elif else", `.order.items[] | if .qty > 1 then "many" elif .qty == 1 then "one" else "none" end`, `["many","none","one"]`},
+		{"Reduce", `reduce .order.items[] as $item (0; . + $item.price * $item.qty)`, `[28]`},
+		{"Try catch", `try error("boom") catch "caught: \(.)"`, `["caught: boom"]`},
+		{"Try without catch", `[.order.items[] | try (if .qty == 0 then error("x") else .sku end)]`, `[["A-1","C-3"]]`},
+		{"Update assignment", `.order.items[].qty |= . * 10 | [.order.items[].qty]`, `[[20,0,10]]`},
+		{"Arithmetic update", `.order.id += 1 | .order.id`, `[43]`},
+		{"Plain assignment", `.order.customer.first = "Ann" | .order.customer`, `[{"first":"Ann","last":"Doe"}]`},
+		{"Assignment creates paths", `null | .a.b[1] = 1`, `[{"a":{"b":[null,1]}}]`},
+		{"Delete", `del(.order.internal, .order.items[1]) | .order | keys`, `[["customer","id","items"]]`},
+		{"Delete array elements", `[1,2,3,4] | del(.[0,2])`, `[[2,4]]`},
+		{"Map and add", `[.order.items[] | .qty] | add`, `[3]`},
+		{"Map values", `{"a":1,"b":2} | map_values(. + 1)`, `[{"a":2,"b":3}]`},
+		{"Sort by and group by", `.order.items | [sort_by(.price)[].sku, (group_by(.qty > 0) | map(length))]`, `[["B-2","C-3","A-1",[1,2]]]`},
+		{"Unique min max", `[3,1,2,1] | [unique, min, max, (map({v:.}) | max_by(.v).v)]`, `[[[1,2,3],1,3,3]]`},
+		{"Entries", `{"a":1,"b":2} | with_entries(select(.value > 1) | .key |= ascii_upcase)`, `[{"B":2}]`},
+		{"From entries key names", `[{"name":"a","value":1},{"k":"b","v":2}] | from_entries`, `[{"a":1,"b":2}]`},
+		{"Flatten", `[1,[2,[3]]] | [flatten, flatten(1)]`, `[[[1,2,3],[1,2,[3]]]]`},
+		{"Range limit first", `[range(5)] + [limit(2; range(10; 20))] + [first(range(3; 9))]`, `[[0,1,2,3,4,10,11,3]]`},
+		{"Any all", `.order.items | [any(.qty == 0), all(.price > 1), ([true, false] | any, all)]`, `[[true,true,true,false]]`},
+		{"Contains", `[("foobar" | contains("bar")), ({"a":[1,2],"b":1} | contains({"a":[1]}))]`, `[[true,true]]`},
+		{"Strings", `"  Hello, World " | trim | [ascii_downcase, ltrimstr("Hello"), startswith("Hello"), (split(", ") | join("-")), length]`, `[["hello, world",", World",true,"Hello-World",12]]`},
+		{"Regular expressions", `"order-42-x" | [test("\\d+"), capture("(?<n>\\d+)").n, sub("-"; "_"), gsub("(?<d>\\d)"; "<\(.d)>")]`, `[[true,"42","order_42-x","order-<4><2>-x"]]`},
+		{"Conversions", `[(1 | tostring), ("2.5" | tonumber), ([1,"a"] | tojson), ("{\"a\":1}" | fromjson), (null | type)]`, `[["1",2.5,"[1,\"a\"]",{"a":1},"null"]]`},
+		{"Type filters", `[.order.items[0][] | scalars]`, `[[10.5,2,"A-1"]]`},
+		{"Recursive descent", `[.. | .sku? // empty]`, `[["A-1","B-2","C-3"]]`},
+		{"Walk", `[1,[2]] | walk(if type == "number" then . * 2 else . end)`, `[[2,[4]]]`},
+		{"Paths and getpath", `{"a":{"b":1}} | [paths] + [getpath(["a","b"])]`, `[[["a"],["a","b"],1]]`},
+		{"Optional iteration", `[.order.id[]?]`, `[[]]`},
+		{"Comments", "# pick the id\n.order.id # done", `[42]`},
+		{"Math", `[3.7 | floor, ceil, round], (-2 | abs), (16 | sqrt)`, `[[3,4,4],2,4]`},
+	}
+	input := decode(t, order)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			program, err := Compile(tc.program)
+			require.NoError(t, err)
+			outputs, err := program.Run(input, nil)
+			require.NoError(t, err)
+			if outputs == nil {
+				outputs = []any{}
+			}
+			assert.Equal(t, decode(t, tc.expected), outputs)
+		})
+	}
+}
+
+func TestRunDoesNotModifyInput(t *testing.T) {
+	input := decode(t, order)
+	program, err := Compile(`.order.items[0].qty = 5 | del(.order.customer)`)
+	require.NoError(t, err)
+	_, err = program.Run(input, nil)
+	require.NoError(t, err)
+	assert.Equal(t, decode(t, order), input)
+}
+
+func TestRunVariables(t *testing.T) {
+	program, err := Compile(`{id: .id, user: $headers["x-user"], limit: ($query.limit | tonumber)}`, "headers", "query")
+	require.NoError(t, err)
+	outputs, err := program.Run(map[string]any{"id": 1.0}, map[string]any{
+		"headers": map[string]any{"x-user": "jane"},
+		"query":   map[string]any{"limit": "10"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"id": 1.0, "user": "jane", "limit": 10.0}}, outputs)
+}
+
+func TestRunInfiniteIndices(t *testing.T) {
+	program, err := Compile(`[(.[-$inf:$inf] | length), (.[:-$inf] | length), .[$inf], .[-$inf], (del(.[$inf]) | length)]`, "inf")
+	require.NoError(t, err)
+	outputs, err := program.Run([]any{1.0, 2.0}, map[string]any{"inf": math.Inf(1)})
+	require.NoError(t, err)
+	assert.Equal(t, []any{[]any{2.0, 0.0, nil, nil, 2.0}}, outputs)
+}
+
+func TestRunErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		program string
+		input   string
+		errMsg  string
+	}{
+		{"Index a string", `.a.b`, `{"a":"x"}`, `cannot index string with string ("b")`},
+		{"Iterate a number", `.[]`, `1`, `cannot iterate over number (1)`},
+		{"Add incompatible values", `.a + 1`, `{"a":"x"}`, `cannot be added`},
+		{"Error builtin", `error("bad order")`, `null`, `bad order`},
+		{"Divide by zero", `1 / 0`, `null`, `cannot be divided because the divisor is zero`},
+		{"Invalid path", `(1 + 1) |= 3`, `null`, `invalid path expression`},
+		{"Array index too large", `.items[.n] = 1`, `{"items":[],"n":2e7}`, `Array index too large`},
+		{"Array index beyond int", `.[1e19] = 1`, `null`, `Array index too large`},
+		{"Array index too large in update", `.[.[0] * 1e9] |= 1`, `[1]`, `Array index too large`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			program, err := Compile(tc.program)
+			require.NoError(t, err)
+			_, err = program.Run(decode(t, tc.input), nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
+
+func TestRunBudget(t *testing.T) {
+	testCases := []struct {
+		name    string
+		program string
+		errMsg  string
+	}{
+		{"Collected values", `[range(.n)]`, "budget exceeded: more than 1048576 collected values"},
+		{"Outputs", `range(.n)`, "budget exceeded: more than 1048576 collected values"},
+		{"Steps", `range(.n) | empty`, "budget exceeded: more than 4194304 steps"},
+		{"Reduce", `reduce range(.n) as $i (0; . + $i)`, "budget exceeded: more than 4194304 steps"},
+		{"Not caught by try", `try (range(.n) | empty) catch "caught"`, "budget exceeded"},
+		{"Not caught by ? or //", `(range(.n) | empty)? // "fallback"`, "budget exceeded"},
+		{"Limit of an endless recurse", `.n as $n | [limit($n; 0 | recurse(. + 1))]`, "budget exceeded"},
+		{"Deep recurse", `[0 | recurse(if . < 5000 then . + 1 else empty end)] | length`, "budget exceeded: recurse deeper than 4096 levels"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			program, err := Compile(tc.program)
+			require.NoError(t, err)
+			_, err = program.Run(map[string]any{"n": 3e7}, nil)
+			assert.ErrorContains(t, err, tc.errMsg)
+		})
+	}
+
+	// Budgets are per run
+	program, err := Compile(`[range(.n)] | length`)
+	require.NoError(t, err)
+	for range 3 {
+		outputs, err := program.Run(map[string]any{"n": 1e6}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []any{1e6}, outputs)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		program string
+		errMsg  string
+	}{
+		{"Empty", ``, `unexpected end of program`},
+		{"Unterminated string", `"abc`, `unterminated string`},
+		{"Unbalanced bracket", `[.a`, `expected ']'`},
+		{"Unknown function", `frobnicate(.)`, `frobnicate/1 is not defined`},
+		{"Wrong arity", `map`, `map/0 is not defined`},
+		{"Undefined variable", `$missing`, `$missing is not defined`},
+		{"Trailing input", `.a )`, `unexpected ')'`},
+		{"Unsupported definitions", `def f: .; f`, `not supported`},
+		{"Unsupported formats", `@csv`, `not supported`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Compile(tc.program)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
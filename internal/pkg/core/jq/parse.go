@@ -0,0 +1,739 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package jq
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	// tokenField is .name
+	tokenField
+	// tokenVariable is $name
+	tokenVariable
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value any // of numbers
+	// parts of strings: text, and the source of \(...) at odd indexes
+	parts []string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of program"
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+type lexer struct {
+	source string
+	pos    int
+}
+
+// operators are matched longest first
+var operators = []string{"|=", "+=", "-=", "*=", "/=", "%=", "==", "!=", "<=", ">=", "//", "..",
+	".", "[", "]", "{", "}", "(", ")", "|", ",", ":", ";", "=", "<", ">", "+", "-", "*", "/", "%", "?"}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func (l *lexer) ident() string {
+	start := l.pos
+	for l.pos < len(l.source) && isIdentChar(l.source[l.pos]) {
+		l.pos++
+	}
+	return l.source[start:l.pos]
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.source) {
+		if c := l.source[l.pos]; c == '#' {
+			for l.pos < len(l.source) && l.source[l.pos] != '\n' {
+				l.pos++
+			}
+		} else if strings.ContainsRune(" \t\r\n", rune(c)) {
+			l.pos++
+		} else {
+			break
+		}
+	}
+	start := l.pos
+	if l.pos >= len(l.source) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+	c := l.source[l.pos]
+	switch {
+	case c == '"':
+		return l.string()
+	case c >= '0' && c <= '9' || (c == '.' && l.pos+1 < len(l.source) && l.source[l.pos+1] >= '0' && l.source[l.pos+1] <= '9'):
+		for l.pos < len(l.source) && (l.source[l.pos] >= '0' && l.source[l.pos] <= '9' || l.source[l.pos] == '.') {
+			l.pos++
+		}
+		if l.pos < len(l.source) && (l.source[l.pos] == 'e' || l.source[l.pos] == 'E') {
+			l.pos++
+			if l.pos < len(l.source) && (l.source[l.pos] == '+' || l.source[l.pos] == '-') {
+				l.pos++
+			}
+			for l.pos < len(l.source) && l.source[l.pos] >= '0' && l.source[l.pos] <= '9' {
+				l.pos++
+			}
+		}
+		text := l.source[start:l.pos]
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid number '%s' at %d", text, start)
+		}
+		return token{kind: tokenNumber, text: text, value: number, pos: start}, nil
+	case isIdentStart(c):
+		return token{kind: tokenIdent, text: l.ident(), pos: start}, nil
+	case c == '.' && l.pos+1 < len(l.source) && isIdentStart(l.source[l.pos+1]):
+		l.pos++
+		name := l.ident()
+		return token{kind: tokenField, text: "." + name, value: name, pos: start}, nil
+	case c == '$' && l.pos+1 < len(l.source) && isIdentStart(l.source[l.pos+1]):
+		l.pos++
+		name := l.ident()
+		return token{kind: tokenVariable, text: "$" + name, value: name, pos: start}, nil
+	case c == '@':
+		l.pos++
+		return token{}, fmt.Errorf("formats such as @%s are not supported", l.ident())
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.source[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokenOperator, text: op, pos: start}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unexpected character '%c' at %d", c, start)
+}
+
+// string lexes a string literal. The source of each \(...) is kept as a part
+// of its own, to be parsed in the scope of the string.
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	var parts []string
+	var text strings.Builder
+	for {
+		if l.pos >= len(l.source) {
+			return token{}, fmt.Errorf("unterminated string at %d", start)
+		}
+		c := l.source[l.pos]
+		if c == '"' {
+			l.pos++
+			parts = append(parts, text.String())
+			return token{kind: tokenString, text: l.source[start:l.pos], parts: parts, pos: start}, nil
+		}
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(l.source[l.pos:])
+			text.WriteRune(r)
+			l.pos += size
+			continue
+		}
+		if l.pos+1 >= len(l.source) {
+			return token{}, fmt.Errorf("unterminated string at %d", start)
+		}
+		escape := l.source[l.pos+1]
+		l.pos += 2
+		switch escape {
+		case '"', '\\', '/':
+			text.WriteByte(escape)
+		case 'b':
+			text.WriteByte('\b')
+		case 'f':
+			text.WriteByte('\f')
+		case 'n':
+			text.WriteByte('\n')
+		case 'r':
+			text.WriteByte('\r')
+		case 't':
+			text.WriteByte('\t')
+		case 'u':
+			if l.pos+4 > len(l.source) {
+				return token{}, fmt.Errorf("invalid escape at %d", l.pos-2)
+			}
+			code, err := strconv.ParseUint(l.source[l.pos:l.pos+4], 16, 32)
+			if err != nil {
+				return token{}, fmt.Errorf("invalid escape at %d", l.pos-2)
+			}
+			text.WriteRune(rune(code))
+			l.pos += 4
+		case '(':
+			end, err := l.interpolationEnd()
+			if err != nil {
+				return token{}, err
+			}
+			parts = append(parts, text.String(), l.source[l.pos:end])
+			text.Reset()
+			l.pos = end + 1
+		default:
+			return token{}, fmt.Errorf("invalid escape '\\%c' at %d", escape, l.pos-2)
+		}
+	}
+}
+
+// interpolationEnd returns the position of the ')' closing the \( before
+// pos, skipping parentheses in nested strings
+func (l *lexer) interpolationEnd() (int, error) {
+	depth := 1
+	for i := l.pos; i < len(l.source); i++ {
+		switch l.source[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i, nil
+			}
+		case '"':
+			nested := lexer{source: l.source, pos: i}
+			if _, err := nested.string(); err != nil {
+				return 0, err
+			}
+			i = nested.pos - 1
+		}
+	}
+	return 0, fmt.Errorf("unterminated interpolation at %d", l.pos-2)
+}
+
+type parser struct {
+	lexer lexer
+	token token
+	err   error
+	// scope holds the variables that may be referred to
+	scope []string
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.token, p.err = p.lexer.next()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf(format+" at %d", append(args, p.token.pos)...)
+}
+
+func (p *parser) is(text string) bool {
+	return p.err == nil && (p.token.kind == tokenOperator || p.token.kind == tokenIdent) && p.token.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if !p.is(text) {
+		return p.errorf("expected '%s', got %s", text, p.token)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseProgram() (node, error) {
+	p.next()
+	root, err := p.parsePipe()
+	if err == nil && p.err != nil {
+		err = p.err
+	}
+	if err == nil && p.token.kind != tokenEOF {
+		err = p.errorf("unexpected %s", p.token)
+	}
+	return root, err
+}
+
+func (p *parser) parsePipe() (node, error) {
+	left, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if !p.is("|") {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	return pipe{left, right}, nil
+}
+
+// precedence of infix operators; '|' binds loosest and is parsed apart
+var precedence = map[string]int{
+	",":  1,
+	"//": 2,
+	"=":  3, "|=": 3, "+=": 3, "-=": 3, "*=": 3, "/=": 3, "%=": 3,
+	"or":  4,
+	"and": 5,
+	"==":  6, "!=": 6, "<": 6, "<=": 6, ">": 6, ">=": 6,
+	"+": 7, "-": 7,
+	"*": 8, "/": 8, "%": 8,
+}
+
+func (p *parser) infix() (string, int, bool) {
+	if p.err != nil || (p.token.kind != tokenOperator && p.token.kind != tokenIdent) {
+		return "", 0, false
+	}
+	prec, ok := precedence[p.token.text]
+	return p.token.text, prec, ok
+}
+
+func (p *parser) parseBinary(minPrecedence int) (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, prec, ok := p.infix()
+		if !ok || prec < minPrecedence {
+			return left, nil
+		}
+		p.next()
+		// The alternative operator is right associative
+		next := prec + 1
+		if op == "//" {
+			next = prec
+		}
+		right, err := p.parseBinary(next)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case ",":
+			left = comma{left, right}
+		case "//":
+			left = alternative{left, right}
+		case "and", "or":
+			left = logical{and: op == "and", left: left, right: right}
+		case "=", "|=", "+=", "-=", "*=", "/=", "%=":
+			left = assign{op: op, lhs: left, rhs: right}
+		default:
+			left = binary{op: op, left: left, right: right}
+		}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.is("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negate{operand}, nil
+	}
+	return p.parsePostfix(true)
+}
+
+// parsePostfix parses a term with the suffixes .name, [...], [] and ?, and,
+// when allowed, a following 'as $name | body'
+func (p *parser) parsePostfix(allowBind bool) (node, error) {
+	term, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.err != nil:
+			return nil, p.err
+		case p.token.kind == tokenField:
+			term = index{term, literal{p.token.value}}
+			p.next()
+		case p.is("."):
+			p.next()
+			if p.token.kind == tokenString {
+				key, err := p.parseString()
+				if err != nil {
+					return nil, err
+				}
+				term = index{term, key}
+			} else if !p.is("[") {
+				return nil, p.errorf("unexpected %s after '.'", p.token)
+			}
+		case p.is("["):
+			if term, err = p.parseBracket(term); err != nil {
+				return nil, err
+			}
+		case p.is("?"):
+			p.next()
+			term = optional{term}
+		case allowBind && p.is("as"):
+			p.next()
+			if p.token.kind != tokenVariable {
+				return nil, p.errorf("expected a variable after 'as', got %s", p.token)
+			}
+			name := p.token.value.(string)
+			p.next()
+			if err := p.expect("|"); err != nil {
+				return nil, err
+			}
+			p.scope = append(p.scope, name)
+			body, err := p.parsePipe()
+			p.scope = p.scope[:len(p.scope)-1]
+			if err != nil {
+				return nil, err
+			}
+			return bind{source: term, name: name, body: body}, nil
+		default:
+			return term, nil
+		}
+	}
+}
+
+// parseBracket parses [], [key], [from:to], [from:] and [:to] after term
+func (p *parser) parseBracket(term node) (node, error) {
+	p.next()
+	if p.is("]") {
+		p.next()
+		return iterate{term}, nil
+	}
+	var from node
+	if !p.is(":") {
+		var err error
+		if from, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is("]") && from != nil {
+		p.next()
+		return index{term, from}, nil
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	var to node
+	if !p.is("]") {
+		var err error
+		if to, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return slice{target: term, from: from, to: to}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	t := p.token
+	switch t.kind {
+	case tokenEOF:
+		return nil, p.errorf("unexpected end of program")
+	case tokenNumber:
+		p.next()
+		return literal{t.value}, nil
+	case tokenString:
+		return p.parseString()
+	case tokenField:
+		p.next()
+		return index{identity{}, literal{t.value}}, nil
+	case tokenVariable:
+		name := t.value.(string)
+		if !slices.Contains(p.scope, name) {
+			return nil, p.errorf("$%s is not defined", name)
+		}
+		p.next()
+		return variable{name}, nil
+	case tokenIdent:
+		return p.parseKeyword()
+	}
+	switch t.text {
+	case ".":
+		p.next()
+		if p.token.kind == tokenString {
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			return index{identity{}, key}, nil
+		}
+		return identity{}, nil
+	case "..":
+		p.next()
+		return recurse{}, nil
+	case "(":
+		p.next()
+		body, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return body, p.expect(")")
+	case "[":
+		p.next()
+		if p.is("]") {
+			p.next()
+			return array{}, nil
+		}
+		body, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return array{body}, p.expect("]")
+	case "{":
+		return p.parseObject()
+	}
+	return nil, p.errorf("unexpected %s", t)
+}
+
+func (p *parser) parseKeyword() (node, error) {
+	t := p.token
+	switch t.text {
+	case "true", "false":
+		p.next()
+		return literal{t.text == "true"}, nil
+	case "null":
+		p.next()
+		return literal{nil}, nil
+	case "if":
+		return p.parseIf()
+	case "try":
+		p.next()
+		body, err := p.parsePostfix(false)
+		if err != nil {
+			return nil, err
+		}
+		t := try{body: body}
+		if p.is("catch") {
+			p.next()
+			if t.handler, err = p.parsePostfix(false); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case "reduce":
+		return p.parseReduce()
+	case "def", "foreach", "label", "import", "include":
+		return nil, p.errorf("'%s' is not supported", t.text)
+	}
+	return p.parseCall()
+}
+
+func (p *parser) parseIf() (node, error) {
+	p.next()
+	cond, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	c := conditional{cond: cond, then: then, otherwise: identity{}}
+	switch {
+	case p.is("elif"):
+		// elif continues as a nested if sharing the end
+		if c.otherwise, err = p.parseIf(); err != nil {
+			return nil, err
+		}
+		return c, nil
+	case p.is("else"):
+		p.next()
+		if c.otherwise, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	return c, p.expect("end")
+}
+
+func (p *parser) parseReduce() (node, error) {
+	p.next()
+	source, err := p.parsePostfix(false)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("as"); err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenVariable {
+		return nil, p.errorf("expected a variable after 'as', got %s", p.token)
+	}
+	r := reduce{source: source, name: p.token.value.(string)}
+	p.next()
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	if r.init, err = p.parsePipe(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	p.scope = append(p.scope, r.name)
+	r.update, err = p.parsePipe()
+	p.scope = p.scope[:len(p.scope)-1]
+	if err != nil {
+		return nil, err
+	}
+	return r, p.expect(")")
+}
+
+func (p *parser) parseCall() (node, error) {
+	name := p.token.text
+	p.next()
+	var args []node
+	if p.is("(") {
+		p.next()
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.is(";") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	fn, ok := builtins[fmt.Sprintf("%s/%d", name, len(args))]
+	if !ok {
+		return nil, p.errorf("%s/%d is not defined", name, len(args))
+	}
+	return call{name: name, fn: fn, args: args}, nil
+}
+
+// parseString parses a string literal, with its \(...) parts parsed in the
+// current scope
+func (p *parser) parseString() (node, error) {
+	t := p.token
+	p.next()
+	if len(t.parts) == 1 {
+		return literal{t.parts[0]}, nil
+	}
+	var parts []node
+	for i, part := range t.parts {
+		if i%2 == 0 {
+			if part != "" {
+				parts = append(parts, literal{part})
+			}
+			continue
+		}
+		inner := &parser{lexer: lexer{source: part}, scope: p.scope}
+		n, err := inner.parseProgram()
+		if err != nil {
+			return nil, fmt.Errorf("in interpolation \\(%s): %w", part, err)
+		}
+		parts = append(parts, n)
+	}
+	return interpolation{parts}, nil
+}
+
+// parseObject parses {key: value, ...}, with the shorthands {name},
+// {"name"}, {$name} and computed keys {(expression): value}
+func (p *parser) parseObject() (node, error) {
+	p.next()
+	var o object
+	for !p.is("}") {
+		var entry objectEntry
+		var err error
+		switch t := p.token; {
+		case p.err != nil:
+			return nil, p.err
+		case t.kind == tokenVariable:
+			name := t.value.(string)
+			if !slices.Contains(p.scope, name) {
+				return nil, p.errorf("$%s is not defined", name)
+			}
+			p.next()
+			entry = objectEntry{key: literal{name}, value: variable{name}}
+		case t.kind == tokenIdent || t.kind == tokenString:
+			if t.kind == tokenString {
+				if entry.key, err = p.parseString(); err != nil {
+					return nil, err
+				}
+			} else {
+				p.next()
+				entry.key = literal{t.text}
+			}
+			if p.is(":") {
+				p.next()
+				entry.value, err = p.parseObjectValue()
+			} else {
+				entry.value = index{identity{}, entry.key}
+			}
+		case p.is("("):
+			p.next()
+			if entry.key, err = p.parsePipe(); err == nil {
+				if err = p.expect(")"); err == nil {
+					if err = p.expect(":"); err == nil {
+						entry.value, err = p.parseObjectValue()
+					}
+				}
+			}
+		default:
+			return nil, p.errorf("unexpected %s in object", t)
+		}
+		if err != nil {
+			return nil, err
+		}
+		o.entries = append(o.entries, entry)
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	return o, p.expect("}")
+}
+
+// parseObjectValue parses the value of an object entry: an expression
+// without ',' that may be piped
+func (p *parser) parseObjectValue() (node, error) {
+	value, err := p.parseBinary(2)
+	if err != nil {
+		return nil, err
+	}
+	for p.is("|") {
+		p.next()
+		right, err := p.parseBinary(2)
+		if err != nil {
+			return nil, err
+		}
+		value = pipe{value, right}
+	}
+	return value, nil
+}
@@ -0,0 +1,183 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER tags used by the LDAP messages this client exchanges
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	tagBindRequest       = 0x60
+	tagBindResponse      = 0x61
+	tagUnbindRequest     = 0x42
+	tagSearchRequest     = 0x63
+	tagSearchResultEntry = 0x64
+	tagSearchResultDone  = 0x65
+	tagSearchResultRef   = 0x73
+
+	tagSimpleAuth = 0x80
+
+	tagFilterAnd      = 0xa0
+	tagFilterOr       = 0xa1
+	tagFilterNot      = 0xa2
+	tagFilterEquality = 0xa3
+	tagFilterPresent  = 0x87
+)
+
+// maxElementSize bounds the elements read from a server
+const maxElementSize = 16 << 20
+
+// element is a decoded BER element. The content of constructed elements is
+// decoded on demand with children.
+type element struct {
+	tag     byte
+	content []byte
+}
+
+// encode returns the BER encoding of an element with the given content
+func encode(tag byte, content ...[]byte) []byte {
+	length := 0
+	for _, part := range content {
+		length += len(part)
+	}
+	out := []byte{tag}
+	switch {
+	case length < 0x80:
+		out = append(out, byte(length))
+	default:
+		var digits []byte
+		for n := length; n > 0; n >>= 8 {
+			digits = append([]byte{byte(n)}, digits...)
+		}
+		out = append(out, 0x80|byte(len(digits)))
+		out = append(out, digits...)
+	}
+	for _, part := range content {
+		out = append(out, part...)
+	}
+	return out
+}
+
+func encodeInt(tag byte, value int) []byte {
+	// Minimal two's complement, big endian
+	content := []byte{byte(value)}
+	for n := value >> 8; !(n == 0 && content[0]&0x80 == 0) && !(n == -1 && content[0]&0x80 != 0); n >>= 8 {
+		content = append([]byte{byte(n)}, content...)
+	}
+	return encode(tag, content)
+}
+
+func encodeString(tag byte, value string) []byte {
+	return encode(tag, []byte(value))
+}
+
+func encodeBool(value bool) []byte {
+	if value {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0x00})
+}
+
+// readElement reads one element from a stream
+func readElement(r *bufio.Reader) (element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	length := int(first)
+	if first&0x80 != 0 {
+		digits := int(first & 0x7f)
+		if digits == 0 || digits > 4 {
+			return element{}, fmt.Errorf("unsupported BER length of %d bytes", digits)
+		}
+		length = 0
+		for range digits {
+			b, err := r.ReadByte()
+			if err != nil {
+				return element{}, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxElementSize {
+		return element{}, fmt.Errorf("BER element of %d bytes exceeds the limit of %d", length, maxElementSize)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return element{}, err
+	}
+	return element{tag: tag, content: content}, nil
+}
+
+// children decodes the content of a constructed element
+func (e element) children() ([]element, error) {
+	var children []element
+	data := e.content
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errors.New("truncated BER element")
+		}
+		tag, length, header := data[0], int(data[1]), 2
+		if data[1]&0x80 != 0 {
+			digits := int(data[1] & 0x7f)
+			if digits == 0 || digits > 4 || len(data) < 2+digits {
+				return nil, errors.New("invalid BER length")
+			}
+			length = 0
+			for _, b := range data[2 : 2+digits] {
+				length = length<<8 | int(b)
+			}
+			header += digits
+		}
+		if length < 0 || len(data) < header+length {
+			return nil, errors.New("truncated BER element")
+		}
+		children = append(children, element{tag: tag, content: data[header : header+length]})
+		data = data[header+length:]
+	}
+	return children, nil
+}
+
+// int decodes an INTEGER or ENUMERATED element
+func (e element) int() int {
+	if len(e.content) == 0 {
+		return 0
+	}
+	value := int(int8(e.content[0]))
+	for _, b := range e.content[1:] {
+		value = value<<8 | int(b)
+	}
+	return value
+}
@@ -0,0 +1,284 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Result codes reported by servers
+const (
+	ResultSuccess            = 0
+	ResultNoSuchObject       = 32
+	ResultInvalidCredentials = 49
+)
+
+// Search scopes
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+// ErrInvalidCredentials is returned by Bind when the server rejects the
+// credentials
+var ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+
+// ResultError is an operation a server answered with a result code other
+// than success
+type ResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// Entry is a search result
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Values returns the values of an attribute. Attribute names are case
+// insensitive, and servers may return them cased as in their schema.
+func (e Entry) Values(name string) []string {
+	if values, ok := e.Attributes[name]; ok {
+		return values
+	}
+	for attribute, values := range e.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Conn is a connection to an LDAP server. Operations are sent one at a time.
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	nextID  int
+	// boundDN is the identity the connection is bound as
+	boundDN string
+}
+
+// Dial connects to an ldap:// or ldaps:// URL
+func Dial(rawURL string, timeout time.Duration, tlsConfig *tls.Config) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid URL '%s': %w", rawURL, err)
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.Dial("tcp", hostPort(u, "389"))
+	case "ldaps":
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: u.Hostname()}
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u, "636"), tlsConfig)
+	default:
+		return nil, fmt.Errorf("ldap: URL scheme must be ldap or ldaps, got: %s", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap: cannot connect to %s: %w", u.Host, err)
+	}
+	return &Conn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// Bind authenticates the connection with a simple bind. An empty password
+// is refused: servers treat it as an anonymous bind that always succeeds.
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return ErrInvalidCredentials
+	}
+	id, err := c.send(encode(tagBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(tagSimpleAuth, password),
+	))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != tagBindResponse {
+		return fmt.Errorf("ldap: unexpected response 0x%02x to bind", op.tag)
+	}
+	if err := resultError(op); err != nil {
+		c.boundDN = ""
+		var result *ResultError
+		if errors.As(err, &result) && result.Code == ResultInvalidCredentials {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+	c.boundDN = dn
+	return nil
+}
+
+// Search returns the entries below baseDN matching filter, with the listed
+// attributes. sizeLimit bounds the number of entries when positive.
+func (c *Conn) Search(baseDN string, scope int, filter string, attributes []string, sizeLimit int) ([]Entry, error) {
+	encodedFilter, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var encodedAttributes [][]byte
+	for _, attribute := range attributes {
+		encodedAttributes = append(encodedAttributes, encodeString(tagOctetString, attribute))
+	}
+	id, err := c.send(encode(tagSearchRequest,
+		encodeString(tagOctetString, baseDN),
+		encodeInt(tagEnumerated, scope),
+		// Never dereference aliases
+		encodeInt(tagEnumerated, 0),
+		encodeInt(tagInteger, sizeLimit),
+		encodeInt(tagInteger, int(c.timeout/time.Second)),
+		encodeBool(false),
+		encodedFilter,
+		encode(tagSequence, encodedAttributes...),
+	))
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case tagSearchResultEntry:
+			entry, err := decodeEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case tagSearchResultRef:
+			// Referrals to other servers are not followed
+		case tagSearchResultDone:
+			return entries, resultError(op)
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response 0x%02x to search", op.tag)
+		}
+	}
+}
+
+// Close sends an unbind request and closes the connection
+func (c *Conn) Close() error {
+	c.send(encode(tagUnbindRequest))
+	return c.conn.Close()
+}
+
+// send writes a request and returns its message ID
+func (c *Conn) send(op []byte) (int, error) {
+	c.nextID++
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	if _, err := c.conn.Write(encode(tagSequence, encodeInt(tagInteger, c.nextID), op)); err != nil {
+		return 0, fmt.Errorf("ldap: %w", err)
+	}
+	return c.nextID, nil
+}
+
+// receive reads the protocol operation of the next response to message id
+func (c *Conn) receive(id int) (element, error) {
+	for {
+		message, err := readElement(c.reader)
+		if err != nil {
+			return element{}, fmt.Errorf("ldap: %w", err)
+		}
+		parts, err := message.children()
+		if err != nil || message.tag != tagSequence || len(parts) < 2 || parts[0].tag != tagInteger {
+			return element{}, errors.New("ldap: malformed response")
+		}
+		// Unsolicited notifications use message ID 0 and end the connection
+		if parts[0].int() == 0 {
+			return element{}, errors.New("ldap: connection closed by server")
+		}
+		if parts[0].int() == id {
+			return parts[1], nil
+		}
+	}
+}
+
+// resultError decodes the LDAPResult of a response
+func resultError(op element) error {
+	parts, err := op.children()
+	if err != nil || len(parts) < 3 {
+		return errors.New("ldap: malformed result")
+	}
+	if code := parts[0].int(); code != ResultSuccess {
+		return &ResultError{Code: code, Message: string(parts[2].content)}
+	}
+	return nil
+}
+
+func decodeEntry(op element) (Entry, error) {
+	parts, err := op.children()
+	if err != nil || len(parts) < 2 {
+		return Entry{}, errors.New("ldap: malformed search entry")
+	}
+	entry := Entry{DN: string(parts[0].content), Attributes: make(map[string][]string)}
+	attributes, err := parts[1].children()
+	if err != nil {
+		return Entry{}, errors.New("ldap: malformed search entry")
+	}
+	for _, attribute := range attributes {
+		pair, err := attribute.children()
+		if err != nil || len(pair) < 2 {
+			return Entry{}, errors.New("ldap: malformed search entry")
+		}
+		values, err := pair[1].children()
+		if err != nil {
+			return Entry{}, errors.New("ldap: malformed search entry")
+		}
+		name := string(pair[0].content)
+		for _, value := range values {
+			entry.Attributes[name] = append(entry.Attributes[name], string(value.content))
+		}
+	}
+	return entry, nil
+}
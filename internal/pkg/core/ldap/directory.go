@@ -0,0 +1,341 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package ldap looks up and authenticates users in an LDAP or Active
+// Directory server. A Directory keeps a pool of connections bound as a
+// service account and caches the entries it finds and the logins it
+// verifies.
+package ldap
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the [ldap] section
+const (
+	DefaultUserFilter = "(uid={user})"
+	DefaultPoolSize   = 4
+	DefaultTimeout    = 5 * time.Second
+	DefaultCacheTTL   = 5 * time.Minute
+)
+
+// maxCacheEntries bounds each cache of a directory
+const maxCacheEntries = 10000
+
+// Config holds the [ldap] section of deployment.toml, or the ldap table of a
+// basic auth security policy
+type Config struct {
+	// URL is ldap://host:389 or ldaps://host:636
+	URL string `koanf:"url"`
+	// BindDN and BindPassword identify the service account users are
+	// looked up with
+	BindDN       string `koanf:"bindDN"`
+	BindPassword string `koanf:"bindPassword"`
+	// BaseDN is searched, with its whole subtree, for users
+	BaseDN string `koanf:"baseDN"`
+	// UserFilter finds the entry of a user, with {user} replaced by the
+	// escaped user name. Active Directory uses (sAMAccountName={user}).
+	UserFilter string `koanf:"userFilter"`
+	PoolSize   int    `koanf:"poolSize"`
+	Timeout    string `koanf:"timeout"`
+	// CacheTTL is how long entries and verified logins are reused, 0s to
+	// ask the server every time
+	CacheTTL string `koanf:"cacheTTL"`
+}
+
+type settings struct {
+	userFilter string
+	poolSize   int
+	timeout    time.Duration
+	cacheTTL   time.Duration
+}
+
+// Validate reports configuration errors
+func (c Config) Validate() error {
+	_, err := c.settings()
+	return err
+}
+
+func (c Config) settings() (settings, error) {
+	s := settings{userFilter: c.UserFilter, poolSize: c.PoolSize, timeout: DefaultTimeout, cacheTTL: DefaultCacheTTL}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return s, fmt.Errorf("ldap: url must be an ldap:// or ldaps:// URL, got: %s", c.URL)
+	}
+	if c.BindDN == "" || c.BindPassword == "" {
+		return s, errors.New("ldap: bindDN and bindPassword are required")
+	}
+	if c.BaseDN == "" {
+		return s, errors.New("ldap: baseDN is required")
+	}
+	if s.userFilter == "" {
+		s.userFilter = DefaultUserFilter
+	}
+	if !strings.Contains(s.userFilter, "{user}") {
+		return s, fmt.Errorf("ldap: userFilter must contain {user}, got: %s", s.userFilter)
+	}
+	if _, err := compileFilter(strings.ReplaceAll(s.userFilter, "{user}", "user")); err != nil {
+		return s, fmt.Errorf("ldap: userFilter: %w", err)
+	}
+	if s.poolSize < 0 {
+		return s, fmt.Errorf("ldap: poolSize must not be negative, got %d", c.PoolSize)
+	}
+	if s.poolSize == 0 {
+		s.poolSize = DefaultPoolSize
+	}
+	if c.Timeout != "" {
+		if s.timeout, err = time.ParseDuration(c.Timeout); err != nil || s.timeout <= 0 {
+			return s, fmt.Errorf("ldap: timeout must be a positive duration, got: %s", c.Timeout)
+		}
+	}
+	if c.CacheTTL != "" {
+		if s.cacheTTL, err = time.ParseDuration(c.CacheTTL); err != nil || s.cacheTTL < 0 {
+			return s, fmt.Errorf("ldap: cacheTTL must be a duration, got: %s", c.CacheTTL)
+		}
+	}
+	return s, nil
+}
+
+type cachedEntry struct {
+	entry   Entry
+	expires time.Time
+}
+
+// Directory looks up and authenticates users. It is safe for concurrent use.
+type Directory struct {
+	config   Config
+	settings settings
+	now      func() time.Time
+
+	// idle holds pooled connections; slots bounds the open ones
+	idle  chan *Conn
+	slots chan struct{}
+
+	mu      sync.Mutex
+	entries map[string]cachedEntry
+	logins  map[[sha256.Size]byte]time.Time
+}
+
+// NewDirectory returns a directory for a validated configuration.
+// Connections are opened on first use.
+func NewDirectory(config Config) (*Directory, error) {
+	s, err := config.settings()
+	if err != nil {
+		return nil, err
+	}
+	return &Directory{
+		config:   config,
+		settings: s,
+		now:      time.Now,
+		idle:     make(chan *Conn, s.poolSize),
+		slots:    make(chan struct{}, s.poolSize),
+		entries:  make(map[string]cachedEntry),
+		logins:   make(map[[sha256.Size]byte]time.Time),
+	}, nil
+}
+
+// Lookup returns the entry of a user with the listed attributes, and false
+// when no entry matches the user filter
+func (d *Directory) Lookup(user string, attributes []string) (Entry, bool, error) {
+	key := user + "\x00" + strings.Join(attributes, ",")
+	d.mu.Lock()
+	cached, ok := d.entries[key]
+	d.mu.Unlock()
+	if ok && d.now().Before(cached.expires) {
+		return cached.entry, true, nil
+	}
+
+	conn, err := d.acquire()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if conn.boundDN != d.config.BindDN {
+		if err = conn.Bind(d.config.BindDN, d.config.BindPassword); err != nil {
+			d.release(conn, err)
+			return Entry{}, false, fmt.Errorf("ldap: service account bind failed: %w", err)
+		}
+	}
+	filter := strings.ReplaceAll(d.settings.userFilter, "{user}", EscapeFilter(user))
+	// Two entries are enough to tell that a user is ambiguous
+	entries, err := conn.Search(d.config.BaseDN, ScopeWholeSubtree, filter, attributes, 2)
+	d.release(conn, err)
+	var result *ResultError
+	switch {
+	case errors.As(err, &result) && result.Code == ResultNoSuchObject:
+		return Entry{}, false, nil
+	case err != nil:
+		return Entry{}, false, err
+	case len(entries) == 0:
+		return Entry{}, false, nil
+	case len(entries) > 1:
+		return Entry{}, false, fmt.Errorf("ldap: user %s matches more than one entry", user)
+	}
+
+	if d.settings.cacheTTL > 0 {
+		d.mu.Lock()
+		if len(d.entries) >= maxCacheEntries {
+			d.entries = make(map[string]cachedEntry)
+		}
+		d.entries[key] = cachedEntry{entry: entries[0], expires: d.now().Add(d.settings.cacheTTL)}
+		d.mu.Unlock()
+	}
+	return entries[0], true, nil
+}
+
+// Authenticate reports whether password is the password of user, by binding
+// as the user's entry. Verified logins are cached, failures never are.
+func (d *Directory) Authenticate(user, password string) (bool, error) {
+	if user == "" || password == "" {
+		return false, nil
+	}
+	login := sha256.Sum256([]byte(user + "\x00" + password))
+	d.mu.Lock()
+	expires, ok := d.logins[login]
+	d.mu.Unlock()
+	if ok && d.now().Before(expires) {
+		return true, nil
+	}
+
+	// 1.1 requests no attributes, only the DN
+	entry, found, err := d.Lookup(user, []string{"1.1"})
+	if err != nil || !found {
+		return false, err
+	}
+	conn, err := d.acquire()
+	if err != nil {
+		return false, err
+	}
+	err = conn.Bind(entry.DN, password)
+	d.release(conn, err)
+	var result *ResultError
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		return false, nil
+	case errors.As(err, &result):
+		// Disabled or locked accounts are refused with other result codes
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	if d.settings.cacheTTL > 0 {
+		d.mu.Lock()
+		if len(d.logins) >= maxCacheEntries {
+			d.logins = make(map[[sha256.Size]byte]time.Time)
+		}
+		d.logins[login] = d.now().Add(d.settings.cacheTTL)
+		d.mu.Unlock()
+	}
+	return true, nil
+}
+
+// acquire returns a pooled connection, opening one when the pool has room,
+// or waits up to the timeout for one to be released
+func (d *Directory) acquire() (*Conn, error) {
+	select {
+	case conn := <-d.idle:
+		return conn, nil
+	default:
+	}
+	timer := time.NewTimer(d.settings.timeout)
+	defer timer.Stop()
+	select {
+	case conn := <-d.idle:
+		return conn, nil
+	case d.slots <- struct{}{}:
+		conn, err := Dial(d.config.URL, d.settings.timeout, nil)
+		if err != nil {
+			<-d.slots
+			return nil, err
+		}
+		return conn, nil
+	case <-timer.C:
+		return nil, errors.New("ldap: timed out waiting for a pooled connection")
+	}
+}
+
+// release returns a connection to the pool, or closes it when the operation
+// failed for a reason other than the server's answer
+func (d *Directory) release(conn *Conn, err error) {
+	var result *ResultError
+	if err == nil || errors.Is(err, ErrInvalidCredentials) || errors.As(err, &result) {
+		d.idle <- conn
+		return
+	}
+	conn.conn.Close()
+	<-d.slots
+}
+
+// Close closes the idle connections of the pool
+func (d *Directory) Close() {
+	for {
+		select {
+		case conn := <-d.idle:
+			conn.Close()
+			<-d.slots
+		default:
+			return
+		}
+	}
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = make(map[Config]*Directory)
+
+	defaultMu        sync.RWMutex
+	defaultDirectory *Directory
+)
+
+// Shared returns the directory of a configuration, creating it on first use,
+// so every API authenticating against the same server shares one pool
+func Shared(config Config) (*Directory, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if directory, ok := shared[config]; ok {
+		return directory, nil
+	}
+	directory, err := NewDirectory(config)
+	if err != nil {
+		return nil, err
+	}
+	shared[config] = directory
+	return directory, nil
+}
+
+// SetDefault replaces the directory of the [ldap] section
+func SetDefault(directory *Directory) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultDirectory = directory
+}
+
+// Default returns the directory of the [ldap] section, nil when none is
+// configured
+func Default() *Directory {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultDirectory
+}
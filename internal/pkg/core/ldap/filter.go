@@ -0,0 +1,140 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// EscapeFilter escapes a value for use in a search filter, so user input
+// cannot change the filter's meaning
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter encodes a search filter such as
+// (&(objectClass=person)(uid=jdoe)). Equality and presence assertions can be
+// combined with &, | and !; substring and ordering assertions are not
+// supported.
+func compileFilter(filter string) ([]byte, error) {
+	encoded, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter '%s': %w", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filter '%s': unexpected '%s'", filter, rest)
+	}
+	return encoded, nil
+}
+
+// parseFilter encodes the filter at the start of s and returns the rest
+func parseFilter(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("expected '(' at '%s'", s)
+	}
+	s = s[1:]
+	var encoded []byte
+	switch {
+	case strings.HasPrefix(s, "&") || strings.HasPrefix(s, "|"):
+		operator, tag := s[0], byte(tagFilterAnd)
+		if operator == '|' {
+			tag = tagFilterOr
+		}
+		s = s[1:]
+		var operands [][]byte
+		for strings.HasPrefix(s, "(") {
+			operand, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			operands = append(operands, operand)
+			s = rest
+		}
+		if len(operands) == 0 {
+			return nil, "", fmt.Errorf("'%c' needs at least one operand", operator)
+		}
+		encoded = encode(tag, operands...)
+	case strings.HasPrefix(s, "!"):
+		operand, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		encoded, s = encode(tagFilterNot, operand), rest
+	default:
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return nil, "", fmt.Errorf("missing ')'")
+		}
+		attribute, value, ok := strings.Cut(s[:end], "=")
+		switch {
+		case !ok || attribute == "":
+			return nil, "", fmt.Errorf("'%s' is not an attribute assertion", s[:end])
+		case strings.ContainsAny(attribute[len(attribute)-1:], "<>~:"):
+			return nil, "", fmt.Errorf("'%s' is not supported, only equality and presence", s[:end])
+		case value == "*":
+			encoded = encodeString(tagFilterPresent, attribute)
+		case strings.Contains(value, "*"):
+			return nil, "", fmt.Errorf("substring assertion '%s' is not supported", s[:end])
+		default:
+			unescaped, err := unescapeFilterValue(value)
+			if err != nil {
+				return nil, "", err
+			}
+			encoded = encode(tagFilterEquality, encodeString(tagOctetString, attribute), encodeString(tagOctetString, unescaped))
+		}
+		s = s[end:]
+	}
+	if !strings.HasPrefix(s, ")") {
+		return nil, "", fmt.Errorf("missing ')'")
+	}
+	return encoded, s[1:], nil
+}
+
+// unescapeFilterValue decodes the \XX escapes of a filter value
+func unescapeFilterValue(value string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("incomplete escape in '%s'", value)
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in '%s'", value)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
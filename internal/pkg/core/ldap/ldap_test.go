@@ -0,0 +1,310 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer answers binds against passwords and equality searches on uid
+type fakeServer struct {
+	listener  net.Listener
+	passwords map[string]string
+	entries   []Entry
+	binds     atomic.Int32
+	searches  atomic.Int32
+	conns     atomic.Int32
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{
+		listener: listener,
+		passwords: map[string]string{
+			"cn=service,dc=example,dc=com":         "service-secret",
+			"uid=jdoe,ou=people,dc=example,dc=com": "jdoe-secret",
+		},
+		entries: []Entry{{
+			DN: "uid=jdoe,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"uid":      {"jdoe"},
+				"mail":     {"jdoe@example.com"},
+				"memberOf": {"cn=admins,dc=example,dc=com", "cn=users,dc=example,dc=com"},
+			},
+		}},
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.conns.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	bound := ""
+	for {
+		message, err := readElement(reader)
+		if err != nil {
+			return
+		}
+		parts, _ := message.children()
+		id := encodeInt(tagInteger, parts[0].int())
+		reply := func(op []byte) { conn.Write(encode(tagSequence, id, op)) }
+		result := func(tag byte, code int) []byte {
+			return encode(tag, encodeInt(tagEnumerated, code), encodeString(tagOctetString, ""), encodeString(tagOctetString, ""))
+		}
+		fields, _ := parts[1].children()
+		switch parts[1].tag {
+		case tagBindRequest:
+			s.binds.Add(1)
+			dn, password := string(fields[1].content), string(fields[2].content)
+			if expected, ok := s.passwords[dn]; !ok || expected != password {
+				reply(result(tagBindResponse, ResultInvalidCredentials))
+				continue
+			}
+			bound = dn
+			reply(result(tagBindResponse, ResultSuccess))
+		case tagSearchRequest:
+			s.searches.Add(1)
+			if bound != "cn=service,dc=example,dc=com" {
+				reply(result(tagSearchResultDone, 50))
+				continue
+			}
+			assertion, _ := fields[6].children()
+			attribute, value := string(assertion[0].content), string(assertion[1].content)
+			requested, _ := fields[7].children()
+			for _, entry := range s.entries {
+				if entry.Attributes[attribute] == nil || entry.Attributes[attribute][0] != value {
+					continue
+				}
+				var attributes [][]byte
+				for _, name := range requested {
+					values := entry.Attributes[string(name.content)]
+					if values == nil {
+						continue
+					}
+					var encoded [][]byte
+					for _, v := range values {
+						encoded = append(encoded, encodeString(tagOctetString, v))
+					}
+					attributes = append(attributes, encode(tagSequence, encodeString(tagOctetString, string(name.content)), encode(tagSet, encoded...)))
+				}
+				reply(encode(tagSearchResultEntry, encodeString(tagOctetString, entry.DN), encode(tagSequence, attributes...)))
+			}
+			reply(result(tagSearchResultDone, ResultSuccess))
+		case tagUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *fakeServer) config() Config {
+	return Config{
+		URL:          "ldap://" + s.listener.Addr().String(),
+		BindDN:       "cn=service,dc=example,dc=com",
+		BindPassword: "service-secret",
+		BaseDN:       "dc=example,dc=com",
+		PoolSize:     2,
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{URL: "ldaps://ad.example.com", BindDN: "cn=service", BindPassword: "secret", BaseDN: "dc=example,dc=com"}
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "active directory filter", modify: func(c *Config) { c.UserFilter = "(&(objectClass=user)(sAMAccountName={user}))" }},
+		{name: "http URL", modify: func(c *Config) { c.URL = "http://ad.example.com" }, wantErr: "url must be"},
+		{name: "no service account", modify: func(c *Config) { c.BindPassword = "" }, wantErr: "bindDN and bindPassword"},
+		{name: "no base DN", modify: func(c *Config) { c.BaseDN = "" }, wantErr: "baseDN is required"},
+		{name: "filter without user", modify: func(c *Config) { c.UserFilter = "(uid=admin)" }, wantErr: "must contain {user}"},
+		{name: "substring filter", modify: func(c *Config) { c.UserFilter = "(cn={user}*)" }, wantErr: "not supported"},
+		{name: "negative pool", modify: func(c *Config) { c.PoolSize = -1 }, wantErr: "poolSize"},
+		{name: "invalid timeout", modify: func(c *Config) { c.Timeout = "soon" }, wantErr: "timeout"},
+		{name: "invalid cache TTL", modify: func(c *Config) { c.CacheTTL = "-1s" }, wantErr: "cacheTTL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			err := config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	encoded, err := compileFilter(`(&(objectClass=*)(!(cn=a\2ab))(|(uid=x)(uid=y)))`)
+	require.NoError(t, err)
+	assert.Equal(t, encode(tagFilterAnd,
+		encodeString(tagFilterPresent, "objectClass"),
+		encode(tagFilterNot, encode(tagFilterEquality, encodeString(tagOctetString, "cn"), encodeString(tagOctetString, "a*b"))),
+		encode(tagFilterOr,
+			encode(tagFilterEquality, encodeString(tagOctetString, "uid"), encodeString(tagOctetString, "x")),
+			encode(tagFilterEquality, encodeString(tagOctetString, "uid"), encodeString(tagOctetString, "y")),
+		),
+	), encoded)
+
+	for _, filter := range []string{"uid=x", "(uid=x", "(uid=x))", "(&)", "(uid>=x)", "(uid=x*)", `(uid=\2)`} {
+		_, err := compileFilter(filter)
+		assert.Error(t, err, filter)
+	}
+	assert.Equal(t, `a\2a\28b\29\5c`, EscapeFilter(`a*(b)\`))
+}
+
+func TestEncodeInt(t *testing.T) {
+	for _, value := range []int{0, 3, 127, 128, 255, 256, 65535, -1, -129} {
+		e := element{content: encodeInt(tagInteger, value)[2:]}
+		assert.Equal(t, value, e.int(), value)
+	}
+	long := encode(tagOctetString, make([]byte, 300))
+	assert.Equal(t, []byte{tagOctetString, 0x82, 0x01, 0x2c}, long[:4])
+}
+
+func TestDirectory_LookupAndAuthenticate(t *testing.T) {
+	server := newFakeServer(t)
+	directory, err := NewDirectory(server.config())
+	require.NoError(t, err)
+	defer directory.Close()
+
+	entry, found, err := directory.Lookup("jdoe", []string{"mail", "memberOf"})
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "uid=jdoe,ou=people,dc=example,dc=com", entry.DN)
+	assert.Equal(t, []string{"jdoe@example.com"}, entry.Attributes["mail"])
+	assert.Len(t, entry.Attributes["memberOf"], 2)
+
+	_, found, err = directory.Lookup("nobody", []string{"mail"})
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// A user name cannot widen the filter
+	_, found, err = directory.Lookup("*", []string{"mail"})
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	ok, err := directory.Authenticate("jdoe", "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = directory.Authenticate("jdoe", "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = directory.Authenticate("nobody", "jdoe-secret")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = directory.Authenticate("jdoe", "jdoe-secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The connection bound as jdoe is rebound as the service account
+	_, found, err = directory.Lookup("jdoe", []string{"uid"})
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	// Verified logins and entries are served from the cache
+	binds, searches := server.binds.Load(), server.searches.Load()
+	ok, err = directory.Authenticate("jdoe", "jdoe-secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, _, err = directory.Lookup("jdoe", []string{"mail", "memberOf"})
+	require.NoError(t, err)
+	assert.Equal(t, binds, server.binds.Load())
+	assert.Equal(t, searches, server.searches.Load())
+
+	directory.now = func() time.Time { return time.Now().Add(DefaultCacheTTL) }
+	_, _, err = directory.Lookup("jdoe", []string{"mail", "memberOf"})
+	require.NoError(t, err)
+	assert.Equal(t, searches+1, server.searches.Load())
+}
+
+func TestDirectory_Pool(t *testing.T) {
+	server := newFakeServer(t)
+	directory, err := NewDirectory(server.config())
+	require.NoError(t, err)
+	defer directory.Close()
+	directory.settings.cacheTTL = 0
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, found, err := directory.Lookup("jdoe", []string{"mail"})
+			assert.NoError(t, err)
+			assert.True(t, found)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, server.conns.Load(), int32(2))
+	assert.Equal(t, int32(20), server.searches.Load())
+}
+
+func TestDirectory_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	config := Config{URL: "ldap://" + listener.Addr().String(), BindDN: "cn=service", BindPassword: "secret", BaseDN: "dc=example,dc=com", Timeout: "200ms"}
+	listener.Close()
+
+	directory, err := NewDirectory(config)
+	require.NoError(t, err)
+	_, err = directory.Authenticate("jdoe", "secret")
+	assert.ErrorContains(t, err, "cannot connect")
+	// The failed connection frees its slot
+	assert.Empty(t, directory.slots)
+}
+
+func TestShared(t *testing.T) {
+	config := Config{URL: "ldap://localhost", BindDN: "cn=service", BindPassword: "secret", BaseDN: "dc=example,dc=com"}
+	first, err := Shared(config)
+	require.NoError(t, err)
+	second, err := Shared(config)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	_, err = Shared(Config{URL: "ldap://localhost"})
+	assert.Error(t, err)
+}
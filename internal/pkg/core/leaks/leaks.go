@@ -0,0 +1,222 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package leaks tracks the goroutines, files and connections opened by each
+// runtime subsystem so that resources still open at shutdown can be reported.
+// Tracking is off unless leak detection is enabled in the [debug] section.
+package leaks
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Resource kinds
+const (
+	Goroutine  = "goroutine"
+	File       = "file"
+	Connection = "connection"
+)
+
+// Config holds the [debug] section of deployment.toml
+type Config struct {
+	LeakDetection bool `koanf:"leak_detection"`
+}
+
+// Resource is a tracked resource that has not been released
+type Resource struct {
+	Subsystem string    `json:"subsystem"`
+	Kind      string    `json:"kind"`
+	Opened    time.Time `json:"opened"`
+	// Stack is where the resource was acquired
+	Stack string `json:"stack"`
+}
+
+// Count summarizes the resources of one kind opened by a subsystem
+type Count struct {
+	Subsystem string `json:"subsystem"`
+	Kind      string `json:"kind"`
+	Open      int    `json:"open"`
+	Total     int    `json:"total"`
+}
+
+// Snapshot is the state of the tracker reported by the admin API
+type Snapshot struct {
+	Enabled bool `json:"enabled"`
+	// Goroutines counts every goroutine in the process, tracked or not
+	Goroutines int     `json:"goroutines"`
+	Counts     []Count `json:"counts"`
+}
+
+type countKey struct {
+	subsystem string
+	kind      string
+}
+
+// Tracker records acquired resources until they are released
+type Tracker struct {
+	enabled atomic.Bool
+
+	mu     sync.Mutex
+	nextID uint64
+	open   map[uint64]*Resource
+	totals map[countKey]int
+	conns  map[net.Conn]func()
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		open:   make(map[uint64]*Resource),
+		totals: make(map[countKey]int),
+		conns:  make(map[net.Conn]func()),
+	}
+}
+
+// Enable starts tracking. Resources acquired before are not tracked.
+func (t *Tracker) Enable() {
+	t.enabled.Store(true)
+}
+
+func (t *Tracker) Enabled() bool {
+	return t.enabled.Load()
+}
+
+// Acquire records a resource and returns the function that releases it. The
+// release function may be called more than once.
+func (t *Tracker) Acquire(subsystem, kind string) func() {
+	if !t.enabled.Load() {
+		return func() {}
+	}
+	resource := &Resource{Subsystem: subsystem, Kind: kind, Opened: time.Now(), Stack: string(debug.Stack())}
+	t.mu.Lock()
+	t.nextID++
+	id := t.nextID
+	t.open[id] = resource
+	t.totals[countKey{subsystem, kind}]++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.open, id)
+			t.mu.Unlock()
+		})
+	}
+}
+
+// Go runs f in a goroutine tracked under the subsystem
+func (t *Tracker) Go(subsystem string, f func()) {
+	release := t.Acquire(subsystem, Goroutine)
+	go func() {
+		defer release()
+		f()
+	}()
+}
+
+// ConnState returns an http.Server ConnState hook that tracks the server's
+// connections under the subsystem
+func (t *Tracker) ConnState(subsystem string) func(net.Conn, http.ConnState) {
+	return func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			release := t.Acquire(subsystem, Connection)
+			t.mu.Lock()
+			t.conns[conn] = release
+			t.mu.Unlock()
+		case http.StateClosed, http.StateHijacked:
+			t.mu.Lock()
+			release, ok := t.conns[conn]
+			delete(t.conns, conn)
+			t.mu.Unlock()
+			if ok {
+				release()
+			}
+		}
+	}
+}
+
+// Open returns the resources that have not been released, oldest first
+func (t *Tracker) Open() []Resource {
+	t.mu.Lock()
+	resources := make([]Resource, 0, len(t.open))
+	for _, resource := range t.open {
+		resources = append(resources, *resource)
+	}
+	t.mu.Unlock()
+	slices.SortFunc(resources, func(a, b Resource) int {
+		return a.Opened.Compare(b.Opened)
+	})
+	return resources
+}
+
+// Snapshot counts the open and total resources per subsystem and kind
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	open := make(map[countKey]int)
+	for _, resource := range t.open {
+		open[countKey{resource.Subsystem, resource.Kind}]++
+	}
+	counts := make([]Count, 0, len(t.totals))
+	for key, total := range t.totals {
+		counts = append(counts, Count{Subsystem: key.subsystem, Kind: key.kind, Open: open[key], Total: total})
+	}
+	t.mu.Unlock()
+	slices.SortFunc(counts, func(a, b Count) int {
+		if a.Subsystem != b.Subsystem {
+			return cmp.Compare(a.Subsystem, b.Subsystem)
+		}
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+	return Snapshot{Enabled: t.enabled.Load(), Goroutines: runtime.NumGoroutine(), Counts: counts}
+}
+
+// Report logs every resource still open and returns how many there were
+func (t *Tracker) Report(logger *slog.Logger) int {
+	if !t.enabled.Load() {
+		return 0
+	}
+	resources := t.Open()
+	for _, resource := range resources {
+		logger.Warn(fmt.Sprintf("Leaked %s", resource.Kind),
+			slog.String("subsystem", resource.Subsystem),
+			slog.Duration("age", time.Since(resource.Opened)),
+			slog.String("stack", resource.Stack))
+	}
+	if len(resources) == 0 {
+		logger.Info("No leaked resources detected")
+	}
+	return len(resources)
+}
+
+var defaultTracker = NewTracker()
+
+// Default returns the tracker used by the runtime
+func Default() *Tracker {
+	return defaultTracker
+}
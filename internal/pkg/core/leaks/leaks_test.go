@@ -0,0 +1,83 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package leaks
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker_DisabledTracksNothing(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Acquire("router", Goroutine)()
+	assert.Empty(t, tracker.Snapshot().Counts)
+	assert.Equal(t, 0, tracker.Report(slog.New(slog.NewTextHandler(io.Discard, nil))))
+}
+
+func TestTracker_ReportsUnreleasedResources(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Enable()
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	tracker.Go("inbound/Orders", func() {
+		<-done
+		close(finished)
+	})
+	release := tracker.Acquire("deployer", File)
+	release()
+	release()
+	leaked := tracker.Acquire("deployer", File)
+	defer leaked()
+
+	assert.Equal(t, []Count{
+		{Subsystem: "deployer", Kind: File, Open: 1, Total: 2},
+		{Subsystem: "inbound/Orders", Kind: Goroutine, Open: 1, Total: 1},
+	}, tracker.Snapshot().Counts)
+
+	close(done)
+	<-finished
+	assert.Eventually(t, func() bool { return len(tracker.Open()) == 1 }, time.Second, time.Millisecond)
+	open := tracker.Open()
+	assert.Equal(t, "deployer", open[0].Subsystem)
+	assert.Contains(t, open[0].Stack, "TestTracker_ReportsUnreleasedResources")
+	assert.Equal(t, 1, tracker.Report(slog.New(slog.NewTextHandler(io.Discard, nil))))
+}
+
+func TestTracker_ConnState(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Enable()
+	hook := tracker.ConnState("router")
+	server, client := net.Pipe()
+	defer client.Close()
+
+	hook(server, http.StateNew)
+	hook(server, http.StateActive)
+	assert.Len(t, tracker.Open(), 1)
+	hook(server, http.StateClosed)
+	assert.Empty(t, tracker.Open())
+	assert.Equal(t, []Count{{Subsystem: "router", Kind: Connection, Open: 0, Total: 1}}, tracker.Snapshot().Counts)
+}
@@ -0,0 +1,205 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package masking
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonSegment is one step of a JSONPath expression
+type jsonSegment struct {
+	name      string
+	index     int // -1 unless the segment is an array index
+	wildcard  bool
+	recursive bool // preceded by ".."
+}
+
+// compileJSONPath parses the subset of JSONPath used for masking: dot and
+// bracket member access, array indexes, wildcards and recursive descent
+func compileJSONPath(path string) ([]jsonSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath must start with '$'")
+	}
+	var segments []jsonSegment
+	rest := path[1:]
+	for rest != "" {
+		segment := jsonSegment{index: -1}
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segment.recursive = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+		default:
+			return nil, fmt.Errorf("unexpected character '%c'", rest[0])
+		}
+
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '['")
+			}
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case selector == "*":
+				segment.wildcard = true
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				segment.name = selector[1 : len(selector)-1]
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid selector '[%s]'", selector)
+				}
+				segment.index = index
+			}
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			segment.name = rest[:end]
+			rest = rest[end:]
+			if segment.name == "*" {
+				segment.name = ""
+				segment.wildcard = true
+			} else if segment.name == "" {
+				return nil, fmt.Errorf("empty member name")
+			}
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("path must select a field")
+	}
+	return segments, nil
+}
+
+func (p *Policy) applyJSON(payload []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("cannot mask JSON payload: %w", err)
+	}
+	for _, rule := range p.rules {
+		if rule.jsonPath != nil {
+			var err error
+			if document, err = rule.applyJSON(document, rule.jsonPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		return nil, fmt.Errorf("cannot mask JSON payload: %w", err)
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+// applyJSON masks the nodes below node selected by segments and returns the
+// resulting node
+func (r compiledRule) applyJSON(node interface{}, segments []jsonSegment) (interface{}, error) {
+	var err error
+	segment := segments[0]
+	if segment.recursive {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			for key, value := range n {
+				if n[key], err = r.applyJSON(value, segments); err != nil {
+					return nil, err
+				}
+			}
+		case []interface{}:
+			for i, value := range n {
+				if n[i], err = r.applyJSON(value, segments); err != nil {
+					return nil, err
+				}
+			}
+		}
+		segment.recursive = false
+	}
+	last := len(segments) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if segment.index >= 0 {
+			return n, nil
+		}
+		for key, value := range n {
+			if !segment.wildcard && key != segment.name {
+				continue
+			}
+			switch {
+			case !last:
+				n[key], err = r.applyJSON(value, segments[1:])
+			case r.Action == ActionRemove:
+				delete(n, key)
+			default:
+				n[key], err = r.replaceJSON(value)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	case []interface{}:
+		if !segment.wildcard && segment.index < 0 {
+			return n, nil
+		}
+		kept := n[:0]
+		for i, value := range n {
+			if !segment.wildcard && i != segment.index {
+				kept = append(kept, value)
+				continue
+			}
+			switch {
+			case !last:
+				value, err = r.applyJSON(value, segments[1:])
+			case r.Action == ActionRemove:
+				continue
+			default:
+				value, err = r.replaceJSON(value)
+			}
+			if err != nil {
+				return nil, err
+			}
+			kept = append(kept, value)
+		}
+		return kept, nil
+	}
+	return node, nil
+}
+
+// replaceJSON returns the new value of a selected field
+func (r compiledRule) replaceJSON(value interface{}) (interface{}, error) {
+	if r.Action == ActionTransform {
+		return r.Transformer.TransformJSON(value)
+	}
+	return r.Replacement, nil
+}
@@ -0,0 +1,160 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package masking removes or redacts sensitive fields, such as card numbers or
+// national identifiers, from JSON and XML payloads. Policies are attached to the
+// message context by the mask mediator and applied when the response is written
+// and when payloads are logged. Transform rules reuse the same field selection
+// to rewrite values in place, e.g. for field-level encryption.
+package masking
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	ActionRedact    = "redact"
+	ActionRemove    = "remove"
+	ActionTransform = "transform"
+
+	// DefaultReplacement is written in place of redacted values
+	DefaultReplacement = "****"
+
+	// PolicyProperty holds the *Policy collected for a message
+	PolicyProperty = "MASKING_POLICY"
+)
+
+// Rule selects a field with a JSONPath ($.a.b) or XPath (/a/b, //b) expression
+// and either removes it or replaces its value
+type Rule struct {
+	Path        string
+	Action      string
+	Replacement string
+	// Transformer computes the new value of fields selected by transform rules
+	Transformer Transformer
+}
+
+// Transformer rewrites selected fields in place, e.g. to encrypt them
+type Transformer interface {
+	// TransformJSON receives the decoded JSON value of the field
+	TransformJSON(value interface{}) (interface{}, error)
+	// TransformXML receives the raw content between the element's tags and
+	// returns well-formed XML content
+	TransformXML(content []byte) ([]byte, error)
+}
+
+type compiledRule struct {
+	Rule
+	jsonPath []jsonSegment
+	xmlPath  []xmlStep
+}
+
+// Policy is an immutable set of compiled masking rules
+type Policy struct {
+	rules []compiledRule
+}
+
+// NewPolicy validates and compiles the given rules
+func NewPolicy(rules []Rule) (*Policy, error) {
+	policy := &Policy{}
+	for _, rule := range rules {
+		compiled := compiledRule{Rule: rule}
+		switch compiled.Action {
+		case "":
+			compiled.Action = ActionRedact
+		case ActionRedact, ActionRemove:
+		case ActionTransform:
+			if rule.Transformer == nil {
+				return nil, fmt.Errorf("transform rule for path %s has no transformer", rule.Path)
+			}
+		default:
+			return nil, fmt.Errorf("invalid masking action '%s' for path %s: must be 'redact' or 'remove'", rule.Action, rule.Path)
+		}
+		if compiled.Replacement == "" {
+			compiled.Replacement = DefaultReplacement
+		}
+
+		var err error
+		switch {
+		case strings.HasPrefix(rule.Path, "$"):
+			compiled.jsonPath, err = compileJSONPath(rule.Path)
+		case strings.HasPrefix(rule.Path, "/"):
+			compiled.xmlPath, err = compileXPath(rule.Path)
+		default:
+			err = fmt.Errorf("path must be a JSONPath starting with '$' or an XPath starting with '/'")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid masking path %s: %w", rule.Path, err)
+		}
+		policy.rules = append(policy.rules, compiled)
+	}
+	return policy, nil
+}
+
+// With returns a policy holding the rules of both policies
+func (p *Policy) With(other *Policy) *Policy {
+	if p == nil {
+		return other
+	}
+	if other == nil {
+		return p
+	}
+	rules := make([]compiledRule, 0, len(p.rules)+len(other.rules))
+	rules = append(rules, p.rules...)
+	rules = append(rules, other.rules...)
+	return &Policy{rules: rules}
+}
+
+// Apply masks the payload according to its content type. Payloads that are
+// neither JSON nor XML are returned unchanged.
+func (p *Policy) Apply(payload []byte, contentType string) ([]byte, error) {
+	if p == nil || len(p.rules) == 0 || len(payload) == 0 {
+		return payload, nil
+	}
+	contentType = strings.ToLower(contentType)
+	trimmed := bytes.TrimSpace(payload)
+	switch {
+	case strings.Contains(contentType, "json"):
+		return p.applyJSON(payload)
+	case strings.Contains(contentType, "xml"):
+		return p.applyXML(payload)
+	case contentType == "" && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		return p.applyJSON(payload)
+	case contentType == "" && len(trimmed) > 0 && trimmed[0] == '<':
+		return p.applyXML(payload)
+	}
+	return payload, nil
+}
+
+// AddToContext attaches policy to the message, in addition to any policy added
+// earlier in the mediation flow
+func AddToContext(msgContext *synctx.MsgContext, policy *Policy) {
+	existing, _ := msgContext.Properties[PolicyProperty].(*Policy)
+	msgContext.Properties[PolicyProperty] = existing.With(policy)
+}
+
+// FromContext returns the policy attached to the message, or nil
+func FromContext(msgContext *synctx.MsgContext) *Policy {
+	policy, _ := msgContext.Properties[PolicyProperty].(*Policy)
+	return policy
+}
@@ -0,0 +1,132 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package masking
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_ApplyJSON(t *testing.T) {
+	payload := `{"name":"Jane","ssn":"123-45-6789","cards":[{"number":"4111111111111111","type":"visa"},{"number":"5500000000000004","type":"mc"}],"contact":{"email":"jane@example.com","phone":"555-0100"}}`
+
+	testCases := []struct {
+		name     string
+		rules    []Rule
+		expected string
+	}{
+		{
+			name:     "Redact member",
+			rules:    []Rule{{Path: "$.ssn"}},
+			expected: `{"cards":[{"number":"4111111111111111","type":"visa"},{"number":"5500000000000004","type":"mc"}],"contact":{"email":"jane@example.com","phone":"555-0100"},"name":"Jane","ssn":"****"}`,
+		},
+		{
+			name:     "Remove through wildcard",
+			rules:    []Rule{{Path: "$.cards[*].number", Action: ActionRemove}},
+			expected: `{"cards":[{"type":"visa"},{"type":"mc"}],"contact":{"email":"jane@example.com","phone":"555-0100"},"name":"Jane","ssn":"123-45-6789"}`,
+		},
+		{
+			name:     "Recursive descent with custom replacement",
+			rules:    []Rule{{Path: "$..number", Replacement: "XXXX"}, {Path: "$['contact'].phone", Action: ActionRemove}},
+			expected: `{"cards":[{"number":"XXXX","type":"visa"},{"number":"XXXX","type":"mc"}],"contact":{"email":"jane@example.com"},"name":"Jane","ssn":"123-45-6789"}`,
+		},
+		{
+			name:     "Array index",
+			rules:    []Rule{{Path: "$.cards[1]", Action: ActionRemove}},
+			expected: `{"cards":[{"number":"4111111111111111","type":"visa"}],"contact":{"email":"jane@example.com","phone":"555-0100"},"name":"Jane","ssn":"123-45-6789"}`,
+		},
+		{
+			name:     "XPath rules ignore JSON",
+			rules:    []Rule{{Path: "//ssn"}},
+			expected: `{"cards":[{"number":"4111111111111111","type":"visa"},{"number":"5500000000000004","type":"mc"}],"contact":{"email":"jane@example.com","phone":"555-0100"},"name":"Jane","ssn":"123-45-6789"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := NewPolicy(tc.rules)
+			assert.NoError(t, err)
+			masked, err := policy.Apply([]byte(payload), "application/json")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(masked))
+		})
+	}
+}
+
+func TestPolicy_ApplyXML(t *testing.T) {
+	payload := `<p:customer xmlns:p="http://example.com/p"><p:name>Jane</p:name><p:ssn>123-45-6789</p:ssn><cards><card><number>4111</number><cvv/></card></cards></p:customer>`
+
+	testCases := []struct {
+		name     string
+		rules    []Rule
+		expected string
+	}{
+		{
+			name:     "Redact descendant",
+			rules:    []Rule{{Path: "//ssn"}},
+			expected: `<p:customer xmlns:p="http://example.com/p"><p:name>Jane</p:name><p:ssn>****</p:ssn><cards><card><number>4111</number><cvv/></card></cards></p:customer>`,
+		},
+		{
+			name:     "Remove absolute path",
+			rules:    []Rule{{Path: "/customer/cards/card/number", Action: ActionRemove}},
+			expected: `<p:customer xmlns:p="http://example.com/p"><p:name>Jane</p:name><p:ssn>123-45-6789</p:ssn><cards><card><cvv/></card></cards></p:customer>`,
+		},
+		{
+			name:     "Redact self-closing and wildcard",
+			rules:    []Rule{{Path: "//card/*", Replacement: "<hidden>"}},
+			expected: `<p:customer xmlns:p="http://example.com/p"><p:name>Jane</p:name><p:ssn>123-45-6789</p:ssn><cards><card><number>&lt;hidden&gt;</number><cvv>&lt;hidden&gt;</cvv></card></cards></p:customer>`,
+		},
+		{
+			name:     "Non-matching absolute path",
+			rules:    []Rule{{Path: "/ssn"}},
+			expected: payload,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := NewPolicy(tc.rules)
+			assert.NoError(t, err)
+			masked, err := policy.Apply([]byte(payload), "")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(masked))
+		})
+	}
+}
+
+func TestNewPolicy_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "Unknown action", rule: Rule{Path: "$.ssn", Action: "hash"}},
+		{name: "Relative path", rule: Rule{Path: "ssn"}},
+		{name: "Unterminated bracket", rule: Rule{Path: "$.cards[0"}},
+		{name: "XPath predicate", rule: Rule{Path: "//card[1]"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewPolicy([]Rule{tc.rule})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package masking
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlStep is one location step of an XPath expression, matched against local
+// element names
+type xmlStep struct {
+	name       string // "*" matches any element
+	descendant bool   // preceded by "//"
+}
+
+// compileXPath parses the subset of XPath used for masking: absolute and
+// descendant element steps with optional wildcards
+func compileXPath(path string) ([]xmlStep, error) {
+	var steps []xmlStep
+	rest := path
+	for rest != "" {
+		step := xmlStep{}
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			step.descendant = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "/"):
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("unexpected character '%c'", rest[0])
+		}
+		end := strings.Index(rest, "/")
+		if end < 0 {
+			end = len(rest)
+		}
+		step.name = rest[:end]
+		rest = rest[end:]
+		if step.name == "" || strings.ContainsAny(step.name, "[]@()") {
+			return nil, fmt.Errorf("unsupported location step '%s'", step.name)
+		}
+		// Prefixes are not resolved, so elements are matched on local names only
+		if i := strings.Index(step.name, ":"); i >= 0 {
+			step.name = step.name[i+1:]
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("path must select an element")
+	}
+	return steps, nil
+}
+
+// matchXPath reports whether the element stack, from the root to the current
+// element, is selected by steps
+func matchXPath(steps []xmlStep, stack []string) bool {
+	if len(steps) == 0 {
+		return len(stack) == 0
+	}
+	if len(stack) == 0 {
+		return false
+	}
+	step := steps[len(steps)-1]
+	if step.name != "*" && step.name != stack[len(stack)-1] {
+		return false
+	}
+	steps, parents := steps[:len(steps)-1], stack[:len(stack)-1]
+	if !step.descendant {
+		return matchXPath(steps, parents)
+	}
+	for i := len(parents); i >= 0; i-- {
+		if matchXPath(steps, parents[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) matchXML(stack []string) *compiledRule {
+	for i := range p.rules {
+		if p.rules[i].xmlPath != nil && matchXPath(p.rules[i].xmlPath, stack) {
+			return &p.rules[i]
+		}
+	}
+	return nil
+}
+
+// applyXML masks selected elements while copying everything else byte for byte,
+// so namespace declarations and formatting are preserved
+func (p *Policy) applyXML(payload []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(payload))
+	var out bytes.Buffer
+	var stack []string
+	// Depth of the element being removed or redacted, or 0
+	removeDepth, redactDepth := 0, 0
+	// The transform rule of the redacted element and where its content starts
+	var transform *compiledRule
+	contentStart := int64(0)
+
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot mask XML payload: %w", err)
+		}
+		end := decoder.InputOffset()
+		raw := payload[start:end]
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if removeDepth > 0 || redactDepth > 0 {
+				continue
+			}
+			rule := p.matchXML(stack)
+			switch {
+			case rule == nil:
+				out.Write(raw)
+			case rule.Action == ActionRemove:
+				removeDepth = len(stack)
+			default:
+				redactDepth = len(stack)
+				if bytes.HasSuffix(raw, []byte("/>")) {
+					// Expand self-closing elements so the replacement has a place to go
+					out.Write(bytes.TrimSpace(raw[:len(raw)-2]))
+					out.WriteByte('>')
+				} else {
+					out.Write(raw)
+				}
+				if rule.Action == ActionTransform {
+					transform, contentStart = rule, end
+				} else {
+					xml.EscapeText(&out, []byte(rule.Replacement))
+				}
+			}
+		case xml.EndElement:
+			depth := len(stack)
+			if depth > 0 {
+				stack = stack[:depth-1]
+			}
+			switch {
+			case removeDepth > 0:
+				if depth == removeDepth {
+					removeDepth = 0
+				}
+			case redactDepth > 0:
+				if depth == redactDepth {
+					redactDepth = 0
+					if transform != nil {
+						var content []byte
+						if start > contentStart {
+							content = payload[contentStart:start]
+						}
+						replaced, err := transform.Transformer.TransformXML(content)
+						if err != nil {
+							return nil, err
+						}
+						out.Write(replaced)
+						transform = nil
+					}
+					name := t.Name.Local
+					if t.Name.Space != "" {
+						name = t.Name.Space + ":" + name
+					}
+					out.WriteString("</" + name + ">")
+				}
+			default:
+				out.Write(raw)
+			}
+		default:
+			if removeDepth == 0 && redactDepth == 0 {
+				out.Write(raw)
+			}
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,360 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package messageprocessor runs the message processors, the forwarding half
+// of store-and-forward. A processor leases the messages of its store one at
+// a time and sends them to its endpoint. A failed message is retried with
+// backoff while the processor holds its lease, so later messages wait behind
+// it; once its attempts are used up it is handed to the fault sequence and
+// then either dropped or kept while the processor deactivates itself.
+package messageprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/apache/synapse-go/internal/pkg/core/messagestore"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const componentName = "messageprocessor"
+
+const (
+	StateActive   = "ACTIVE"
+	StateInactive = "INACTIVE"
+)
+
+// Forwarder sends a message to an endpoint
+type Forwarder func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error
+
+// Status is the admin view of a message processor
+type Status struct {
+	Name           string    `json:"name"`
+	MessageStore   string    `json:"messageStore"`
+	Endpoint       string    `json:"endpoint"`
+	State          string    `json:"state"`
+	Forwarded      uint64    `json:"forwarded"`
+	FailedAttempts uint64    `json:"failedAttempts"`
+	Dropped        uint64    `json:"dropped"`
+	LastForwarded  time.Time `json:"lastForwarded,omitzero"`
+	LastError      string    `json:"lastError,omitempty"`
+	DeactivatedAt  time.Time `json:"deactivatedAt,omitzero"`
+}
+
+type processor struct {
+	config        artifacts.MessageProcessor
+	configContext *artifacts.ConfigContext
+	status        Status
+	// wake interrupts the waits of the processor when its state changes
+	wake chan struct{}
+}
+
+// Registry holds the deployed message processors
+type Registry struct {
+	mu         sync.RWMutex
+	processors map[string]*processor
+	forward    Forwarder
+}
+
+func NewRegistry(forward Forwarder) *Registry {
+	return &Registry{processors: make(map[string]*processor), forward: forward}
+}
+
+// Register adds an active processor. Its store, endpoint and fault sequence
+// are resolved on every message, so they may be deployed after it.
+func (r *Registry) Register(config artifacts.MessageProcessor, configContext *artifacts.ConfigContext) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.processors[config.Name]; exists {
+		return fmt.Errorf("message processor %s is already deployed", config.Name)
+	}
+	r.processors[config.Name] = &processor{
+		config:        config,
+		configContext: configContext,
+		status: Status{Name: config.Name, MessageStore: config.MessageStore, Endpoint: config.Endpoint,
+			State: StateActive},
+		wake: make(chan struct{}, 1),
+	}
+	return nil
+}
+
+// Run forwards the messages of the named processor until ctx is done,
+// polling its store on its interval while the store is empty
+func (r *Registry) Run(ctx context.Context, name string) {
+	p := r.get(name)
+	if p == nil {
+		return
+	}
+	for {
+		active := r.active(p)
+		if active && r.Process(ctx, name) {
+			continue
+		}
+		// Inactive processors wait to be activated
+		var poll <-chan time.Time
+		var timer *time.Timer
+		if active {
+			timer = time.NewTimer(p.config.Interval)
+			poll = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-p.wake:
+		case <-poll:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Process forwards the next message of the named processor, retrying it
+// until it is delivered or its attempts are used up, and reports whether
+// there was a message. Every lease of a message gets the processor's
+// maximum delivery attempts, while the retry state exposed to mediation
+// counts all attempts since the message was stored.
+func (r *Registry) Process(ctx context.Context, name string) bool {
+	p := r.get(name)
+	if p == nil || !r.active(p) {
+		return false
+	}
+	store, err := messagestore.Lookup(p.config.MessageStore)
+	if err != nil {
+		r.update(p, func(s *Status) { s.LastError = err.Error() })
+		return false
+	}
+	stored, err := store.Next(ctx)
+	if err != nil {
+		if !errors.Is(err, messagestore.ErrEmpty) {
+			r.update(p, func(s *Status) { s.LastError = err.Error() })
+		}
+		return false
+	}
+	// The lease is settled even when ctx is done
+	settle := context.WithoutCancel(ctx)
+	for attempts := 1; ; attempts++ {
+		msg := stored.Context()
+		stored.Retry.Expose(msg)
+		err := r.send(ctx, p, msg)
+		if err == nil {
+			if err := store.Ack(settle, stored.ID); err != nil {
+				r.update(p, func(s *Status) { s.LastError = err.Error() })
+			}
+			r.update(p, func(s *Status) {
+				s.Forwarded++
+				s.LastForwarded = time.Now()
+			})
+			return true
+		}
+		if ctx.Err() != nil {
+			// Shutting down is not a failure of the message
+			store.Release(settle, stored)
+			return false
+		}
+		msg.Properties[artifacts.ErrorMessageProperty] = err.Error()
+		stored.Retry.Failed(msg, time.Now())
+		r.update(p, func(s *Status) {
+			s.FailedAttempts++
+			s.LastError = err.Error()
+		})
+		if attempts >= p.config.MaxDeliveryAttempts {
+			r.exhausted(settle, p, store, stored, msg)
+			return true
+		}
+		if !r.wait(ctx, p, p.config.RetryWait(attempts)) {
+			store.Release(settle, stored)
+			return false
+		}
+	}
+}
+
+// send forwards msg to the processor's endpoint; a server error response is
+// a failed attempt
+func (r *Registry) send(ctx context.Context, p *processor, msg *synctx.MsgContext) error {
+	endpoint, ok := p.configContext.Snapshot().Endpoints[p.config.Endpoint]
+	if !ok {
+		return fmt.Errorf("endpoint %s is not deployed", p.config.Endpoint)
+	}
+	// A status stored with the message belongs to the flow that stored it
+	delete(msg.Properties, artifacts.HTTPStatusProperty)
+	if err := r.forward(ctx, endpoint, msg); err != nil {
+		return err
+	}
+	if status, _ := msg.Properties[artifacts.HTTPStatusProperty].(int); status >= 500 {
+		return fmt.Errorf("endpoint %s returned status %d", p.config.Endpoint, status)
+	}
+	return nil
+}
+
+// exhausted hands a message that used up its attempts to the fault sequence
+// and then drops it or deactivates the processor
+func (r *Registry) exhausted(ctx context.Context, p *processor, store messagestore.Store, stored messagestore.Message, msg *synctx.MsgContext) {
+	logger := loggerfactory.GetLogger(componentName, nil)
+	if p.config.FaultSequence != "" {
+		if sequence, ok := p.configContext.Snapshot().Sequences[p.config.FaultSequence]; ok {
+			sequence.Execute(msg)
+		} else {
+			logger.Warn("Fault sequence of message processor is not deployed",
+				slog.String("processor", p.config.Name), slog.String("sequence", p.config.FaultSequence))
+		}
+	}
+	if p.config.OnFailure == artifacts.ProcessorOnFailureDrop {
+		store.Ack(ctx, stored.ID)
+		r.update(p, func(s *Status) { s.Dropped++ })
+		logger.Warn("Message processor dropped a message after its delivery attempts",
+			slog.String("processor", p.config.Name), slog.String("message_id", stored.ID),
+			slog.Int("attempts", stored.Retry.Attempts), slog.String("error", stored.Retry.LastError))
+		return
+	}
+	store.Release(ctx, stored)
+	r.setState(p, StateInactive, map[string]string{
+		"messageId": stored.ID,
+		"attempts":  strconv.Itoa(stored.Retry.Attempts),
+		"error":     stored.Retry.LastError,
+	})
+	logger.Warn("Message processor deactivated after a message used up its delivery attempts",
+		slog.String("processor", p.config.Name), slog.String("message_id", stored.ID),
+		slog.Int("attempts", stored.Retry.Attempts), slog.String("error", stored.Retry.LastError))
+}
+
+// wait sleeps between retries and reports whether the processor should go
+// on, i.e. ctx is not done and the processor was not deactivated meanwhile
+func (r *Registry) wait(ctx context.Context, p *processor, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return r.active(p)
+		case <-p.wake:
+			if !r.active(p) {
+				return false
+			}
+		}
+	}
+}
+
+// Activate resumes the named processor
+func (r *Registry) Activate(name string) error {
+	p := r.get(name)
+	if p == nil {
+		return fmt.Errorf("message processor %s is not deployed", name)
+	}
+	r.setState(p, StateActive, nil)
+	return nil
+}
+
+// Deactivate stops the named processor after its current attempt. The
+// message being retried goes back to the store.
+func (r *Registry) Deactivate(name string) error {
+	p := r.get(name)
+	if p == nil {
+		return fmt.Errorf("message processor %s is not deployed", name)
+	}
+	r.setState(p, StateInactive, nil)
+	return nil
+}
+
+func (r *Registry) setState(p *processor, state string, attributes map[string]string) {
+	r.mu.Lock()
+	previous := p.status.State
+	p.status.State = state
+	if state == StateInactive && previous != StateInactive {
+		p.status.DeactivatedAt = time.Now()
+	} else if state == StateActive {
+		p.status.DeactivatedAt = time.Time{}
+	}
+	r.mu.Unlock()
+	if state == previous {
+		return
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	eventType := events.ProcessorActivated
+	if state == StateInactive {
+		eventType = events.ProcessorDeactivated
+	}
+	events.Publish(events.Event{Type: eventType, Kind: "messageprocessor", Name: p.config.Name, Attributes: attributes})
+}
+
+func (r *Registry) get(name string) *processor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.processors[name]
+}
+
+func (r *Registry) active(p *processor) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return p.status.State == StateActive
+}
+
+func (r *Registry) update(p *processor, change func(*Status)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change(&p.status)
+}
+
+// Statuses returns the status of every processor, sorted by name
+func (r *Registry) Statuses() []Status {
+	r.mu.RLock()
+	statuses := make([]Status, 0, len(r.processors))
+	for _, p := range r.processors {
+		statuses = append(statuses, p.status)
+	}
+	r.mu.RUnlock()
+	slices.SortFunc(statuses, func(a, b Status) int { return strings.Compare(a.Name, b.Name) })
+	return statuses
+}
+
+// Get returns the status of the named processor
+func (r *Registry) Get(name string) (Status, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.processors[name]
+	if !ok {
+		return Status{}, false
+	}
+	return p.status, true
+}
+
+var defaultRegistry = NewRegistry(outbound.Send)
+
+// Default returns the registry of the deployed message processors
+func Default() *Registry {
+	return defaultRegistry
+}
@@ -0,0 +1,188 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package messageprocessor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/apache/synapse-go/internal/pkg/core/messagestore"
+	"github.com/apache/synapse-go/internal/pkg/core/redelivery"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMediator struct {
+	errors *[]string
+}
+
+func (m recordingMediator) Execute(msg *synctx.MsgContext) (bool, error) {
+	*m.errors = append(*m.errors, msg.Properties[artifacts.ErrorMessageProperty].(string))
+	return true, nil
+}
+
+// setup deploys a memory store holding the payloads and a processor
+// forwarding them to the Backend endpoint
+func setup(t *testing.T, onFailure string, forward Forwarder, payloads ...string) (*Registry, messagestore.Store, *[]string) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+	store := messagestore.NewMemoryStore(0, 0)
+	messagestore.Register(t.Name(), store)
+	for _, payload := range payloads {
+		msg := synctx.CreateMsgContext()
+		msg.Message.RawPayload = []byte(payload)
+		_, err := messagestore.StoreMessage(context.Background(), t.Name(), msg, 4)
+		require.NoError(t, err)
+	}
+
+	var faults []string
+	configContext := &artifacts.ConfigContext{}
+	configContext.AddEndpoint(artifacts.Endpoint{Name: "Backend", Protocol: artifacts.ProtocolHTTP})
+	configContext.AddSequence(artifacts.Sequence{Name: "DeadLetter", MediatorList: []artifacts.Mediator{recordingMediator{errors: &faults}}})
+	registry := NewRegistry(forward)
+	require.NoError(t, registry.Register(artifacts.MessageProcessor{
+		Name:                "Forwarder",
+		MessageStore:        t.Name(),
+		Endpoint:            "Backend",
+		Interval:            time.Millisecond,
+		RetryInterval:       time.Millisecond,
+		BackoffMultiplier:   2,
+		MaxDeliveryAttempts: 3,
+		FaultSequence:       "DeadLetter",
+		OnFailure:           onFailure,
+	}, configContext))
+	return registry, store, &faults
+}
+
+func TestProcess_RetriesUntilDelivered(t *testing.T) {
+	var delivered []string
+	var attempts []int
+	registry, store, faults := setup(t, artifacts.ProcessorOnFailureDeactivate, func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+		assert.Equal(t, "Backend", endpoint.Name)
+		assert.NotContains(t, msg.Properties, artifacts.HTTPStatusProperty, "the status of the storing flow is cleared")
+		attempt := msg.Properties[redelivery.AttemptProperty].(int)
+		attempts = append(attempts, attempt)
+		switch {
+		case string(msg.Message.RawPayload) == "first" && attempt == 1:
+			return errors.New("connection refused")
+		case string(msg.Message.RawPayload) == "first" && attempt == 2:
+			assert.Equal(t, "connection refused", msg.Properties[redelivery.LastErrorProperty])
+			msg.Properties[artifacts.HTTPStatusProperty] = 503
+			return nil
+		}
+		msg.Properties[artifacts.HTTPStatusProperty] = 202
+		delivered = append(delivered, string(msg.Message.RawPayload))
+		return nil
+	}, "first", "second")
+
+	assert.True(t, registry.Process(context.Background(), "Forwarder"))
+	assert.True(t, registry.Process(context.Background(), "Forwarder"))
+	assert.False(t, registry.Process(context.Background(), "Forwarder"), "the store is empty")
+
+	assert.Equal(t, []string{"first", "second"}, delivered, "later messages wait behind a retried one")
+	assert.Equal(t, []int{1, 2, 3, 1}, attempts)
+	assert.Empty(t, *faults)
+	assert.Equal(t, 0, store.Len())
+	status, ok := registry.Get("Forwarder")
+	require.True(t, ok)
+	assert.Equal(t, StateActive, status.State)
+	assert.Equal(t, uint64(2), status.Forwarded)
+	assert.Equal(t, uint64(2), status.FailedAttempts)
+	assert.Equal(t, "endpoint Backend returned status 503", status.LastError)
+}
+
+func TestProcess_DeactivatesAfterMaxAttempts(t *testing.T) {
+	subscription := events.Default().Subscribe(events.ProcessorDeactivated)
+	defer subscription.Close()
+	failing := true
+	var delivered []string
+	registry, store, faults := setup(t, artifacts.ProcessorOnFailureDeactivate, func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+		if failing {
+			return errors.New("backend unavailable")
+		}
+		delivered = append(delivered, string(msg.Message.RawPayload))
+		return nil
+	}, "first", "second")
+
+	assert.True(t, registry.Process(context.Background(), "Forwarder"))
+	assert.Equal(t, []string{"backend unavailable"}, *faults)
+	assert.Equal(t, 2, store.Len(), "the message stays in the store")
+	status, _ := registry.Get("Forwarder")
+	assert.Equal(t, StateInactive, status.State)
+	assert.False(t, status.DeactivatedAt.IsZero())
+	select {
+	case event := <-subscription.C:
+		assert.Equal(t, "Forwarder", event.Name)
+		assert.Equal(t, "3", event.Attributes["attempts"])
+	case <-time.After(time.Second):
+		t.Fatal("no deactivation event")
+	}
+	assert.False(t, registry.Process(context.Background(), "Forwarder"), "inactive processors do not forward")
+
+	failing = false
+	require.NoError(t, registry.Activate("Forwarder"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		registry.Run(ctx, "Forwarder")
+	}()
+	require.Eventually(t, func() bool { return store.Len() == 0 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	assert.ElementsMatch(t, []string{"first", "second"}, delivered)
+	assert.EqualError(t, registry.Activate("Missing"), "message processor Missing is not deployed")
+}
+
+func TestProcess_DropsAfterMaxAttempts(t *testing.T) {
+	registry, store, faults := setup(t, artifacts.ProcessorOnFailureDrop, func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+		if string(msg.Message.RawPayload) == "poison" {
+			return errors.New("rejected")
+		}
+		return nil
+	}, "poison", "next")
+
+	assert.True(t, registry.Process(context.Background(), "Forwarder"))
+	assert.True(t, registry.Process(context.Background(), "Forwarder"))
+	assert.Equal(t, []string{"rejected"}, *faults)
+	assert.Equal(t, 0, store.Len())
+	status, _ := registry.Get("Forwarder")
+	assert.Equal(t, StateActive, status.State)
+	assert.Equal(t, uint64(1), status.Dropped)
+	assert.Equal(t, uint64(1), status.Forwarded)
+}
+
+func TestProcess_ReleasesOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	registry, store, _ := setup(t, artifacts.ProcessorOnFailureDeactivate, func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+		cancel()
+		return ctx.Err()
+	}, "first")
+
+	assert.False(t, registry.Process(ctx, "Forwarder"))
+	msg, err := store.Next(context.Background())
+	require.NoError(t, err, "the message is released for the next run")
+	assert.Equal(t, 0, msg.Retry.Attempts)
+}
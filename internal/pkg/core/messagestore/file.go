@@ -0,0 +1,230 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package messagestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/priority"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const componentName = "messagestore"
+
+// QuarantineDirectory is the subdirectory of a file store that unreadable
+// message files are moved to
+const QuarantineDirectory = "quarantine"
+
+// FileStore keeps each message in a JSON file of a directory, so messages
+// survive restarts. File names sort by priority, highest first, then by the
+// time messages were stored; file stores do not age messages. The directory
+// is listed once when the store is opened and the order of the waiting
+// messages kept in memory from then on.
+type FileStore struct {
+	directory string
+	maxSize   int
+
+	mu sync.Mutex
+	// waiting holds the file names of the messages not leased, in the order
+	// they are processed
+	waiting []string
+	// leased maps the ids of leased messages to their file names
+	leased map[string]string
+}
+
+// OpenFileStore creates a store in directory, creating the directory. Messages
+// left there by an earlier run are kept.
+func OpenFileStore(directory string, maxSize int) (*FileStore, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create message store directory: %w", err)
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list message store directory: %w", err)
+	}
+	var waiting []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" && !strings.HasPrefix(entry.Name(), ".") {
+			waiting = append(waiting, entry.Name())
+		}
+	}
+	return &FileStore{directory: directory, maxSize: maxSize, waiting: waiting, leased: make(map[string]string)}, nil
+}
+
+func (s *FileStore) Put(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxSize > 0 && len(s.waiting)+len(s.leased) >= s.maxSize {
+		return ErrFull
+	}
+	name := fmt.Sprintf("%d-%019d-%s.json", priority.Highest-priority.Clamp(msg.Priority), msg.StoredAt.UnixNano(), msg.ID)
+	if err := s.write(name, msg); err != nil {
+		return err
+	}
+	s.wait(name)
+	return nil
+}
+
+// Next leases the first waiting message. Files that cannot be read or
+// decoded are moved to the quarantine directory and skipped.
+func (s *FileStore) Next(ctx context.Context) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.waiting) > 0 {
+		name := s.waiting[0]
+		s.waiting = s.waiting[1:]
+		data, err := os.ReadFile(filepath.Join(s.directory, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		var msg Message
+		if err == nil {
+			msg, err = decode(data)
+		}
+		if err != nil {
+			s.quarantine(name, err)
+			continue
+		}
+		s.leased[msg.ID] = name
+		return msg, nil
+	}
+	return Message{}, ErrEmpty
+}
+
+func (s *FileStore) Ack(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, ok := s.leased[id]
+	if !ok {
+		return ErrNotLeased
+	}
+	if err := os.Remove(filepath.Join(s.directory, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(s.leased, id)
+	return nil
+}
+
+// Release rewrites the message in place, so it keeps its position in the
+// store
+func (s *FileStore) Release(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, ok := s.leased[msg.ID]
+	if !ok {
+		return ErrNotLeased
+	}
+	if err := s.write(name, msg); err != nil {
+		return err
+	}
+	delete(s.leased, msg.ID)
+	s.wait(name)
+	return nil
+}
+
+func (s *FileStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.waiting) + len(s.leased)
+}
+
+// wait inserts the file name among the waiting messages in processing order
+func (s *FileStore) wait(name string) {
+	i, _ := slices.BinarySearch(s.waiting, name)
+	s.waiting = slices.Insert(s.waiting, i, name)
+}
+
+// quarantine moves an unreadable message file aside so it does not block the
+// messages behind it
+func (s *FileStore) quarantine(name string, cause error) {
+	logger := loggerfactory.GetLogger(componentName, nil)
+	directory := filepath.Join(s.directory, QuarantineDirectory)
+	err := os.MkdirAll(directory, 0o755)
+	if err == nil {
+		err = os.Rename(filepath.Join(s.directory, name), filepath.Join(directory, name))
+	}
+	if err != nil {
+		logger.Error("Cannot quarantine unreadable message file",
+			slog.String("file", name),
+			slog.String("error", err.Error()))
+		return
+	}
+	logger.Warn("Quarantined unreadable message file",
+		slog.String("file", name),
+		slog.String("directory", directory),
+		slog.String("error", cause.Error()))
+}
+
+// write stores the message through a temporary file, so a crash never
+// leaves a partial message behind
+func (s *FileStore) write(name string, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.directory, ".message-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filepath.Join(s.directory, name))
+}
+
+// decode reads a stored message. Whole numbers among the properties are
+// restored as ints, the type mediators give numeric properties.
+func decode(data []byte) (Message, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var msg Message
+	if err := decoder.Decode(&msg); err != nil {
+		return Message{}, err
+	}
+	for name, value := range msg.Properties {
+		number, ok := value.(json.Number)
+		if !ok {
+			continue
+		}
+		if i, err := number.Int64(); err == nil {
+			msg.Properties[name] = int(i)
+		} else if f, err := number.Float64(); err == nil {
+			msg.Properties[name] = f
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,94 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package messagestore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/priority"
+)
+
+// MemoryStore keeps messages in a priority queue in memory, for a single
+// instance. Messages are lost on restart.
+type MemoryStore struct {
+	maxSize int
+	queue   *priority.Queue[Message]
+
+	mu     sync.Mutex
+	leased map[string]Message
+}
+
+// NewMemoryStore creates a store of at most maxSize messages, unbounded when
+// 0, whose messages gain a priority level for every aging interval they wait
+func NewMemoryStore(maxSize int, aging time.Duration) *MemoryStore {
+	return &MemoryStore{maxSize: maxSize, queue: priority.NewQueue[Message](aging), leased: make(map[string]Message)}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxSize > 0 && s.queue.Len()+len(s.leased) >= s.maxSize {
+		return ErrFull
+	}
+	s.queue.Push(msg, msg.Priority)
+	return nil
+}
+
+func (s *MemoryStore) Next(ctx context.Context) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.queue.Pop()
+	if !ok {
+		return Message{}, ErrEmpty
+	}
+	s.leased[msg.ID] = msg
+	return msg, nil
+}
+
+func (s *MemoryStore) Ack(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leased[id]; !ok {
+		return ErrNotLeased
+	}
+	delete(s.leased, id)
+	return nil
+}
+
+// Release queues the message again behind the waiting messages of its
+// priority
+func (s *MemoryStore) Release(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leased[msg.ID]; !ok {
+		return ErrNotLeased
+	}
+	delete(s.leased, msg.ID)
+	s.queue.Push(msg, msg.Priority)
+	return nil
+}
+
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queue.Len() + len(s.leased)
+}
@@ -0,0 +1,192 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package messagestore keeps messages for later processing, the store half
+// of store-and-forward. Each deployed message store is registered under its
+// name; the store mediator puts messages in it and a consumer such as a
+// message processor leases them one at a time, acknowledging the messages it
+// forwarded and releasing the others with their retry state updated.
+package messagestore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/redelivery"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+var (
+	// ErrEmpty is returned by Next when no message is waiting
+	ErrEmpty = errors.New("message store is empty")
+	// ErrFull is returned by Put when the store holds its maximum size
+	ErrFull = errors.New("message store is full")
+	// ErrNotLeased is returned for messages Next did not hand out
+	ErrNotLeased = errors.New("message is not leased")
+)
+
+// Message is a stored copy of a message context
+type Message struct {
+	ID          string            `json:"id"`
+	ContentType string            `json:"contentType,omitempty"`
+	Payload     []byte            `json:"payload,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	// Properties holds the string, boolean and numeric properties of the
+	// message; other values only make sense in the flow that set them
+	Properties map[string]any   `json:"properties,omitempty"`
+	Priority   int              `json:"priority"`
+	StoredAt   time.Time        `json:"storedAt"`
+	Retry      redelivery.State `json:"retry"`
+}
+
+// Store keeps messages until they are processed. Implementations are safe
+// for concurrent use.
+type Store interface {
+	// Put adds a message, returning ErrFull when the store is at its maximum
+	// size
+	Put(ctx context.Context, msg Message) error
+	// Next leases the message to process next, returning ErrEmpty when none
+	// is waiting. A leased message is not handed out again until released.
+	Next(ctx context.Context) (Message, error)
+	// Ack removes a leased message once it has been processed
+	Ack(ctx context.Context, id string) error
+	// Release returns a leased message to the store, e.g. with the retry
+	// state of a failed attempt
+	Release(ctx context.Context, msg Message) error
+	// Len returns the number of messages held, leased ones included
+	Len() int
+}
+
+// NewID returns a random id for a new message
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// FromContext copies a message context for storing. Messages stored again
+// from within a retried flow keep their retry state.
+func FromContext(msg *synctx.MsgContext, priority int) (Message, error) {
+	id, err := NewID()
+	if err != nil {
+		return Message{}, err
+	}
+	stored := Message{
+		ID:          id,
+		ContentType: msg.Message.ContentType,
+		Payload:     slices.Clone(msg.Message.RawPayload),
+		Headers:     maps.Clone(msg.Headers),
+		Properties:  make(map[string]any),
+		Priority:    priority,
+		StoredAt:    time.Now().UTC(),
+		Retry:       redelivery.FromContext(msg),
+	}
+	for name, value := range msg.Properties {
+		switch value.(type) {
+		case string, bool, int, int64, float64:
+			stored.Properties[name] = value
+		}
+	}
+	return stored, nil
+}
+
+// Context rebuilds a message context from the stored message
+func (m Message) Context() *synctx.MsgContext {
+	msg := synctx.CreateMsgContext()
+	msg.Message.ContentType = m.ContentType
+	msg.Message.RawPayload = slices.Clone(m.Payload)
+	maps.Copy(msg.Headers, m.Headers)
+	maps.Copy(msg.Properties, m.Properties)
+	return msg
+}
+
+// Open creates the store an artifact declares. Relative directories of file
+// stores are resolved against home.
+func Open(store artifacts.MessageStore, home string) (Store, error) {
+	switch store.Type {
+	case artifacts.MessageStoreTypeMemory:
+		return NewMemoryStore(store.MaxSize, store.Aging), nil
+	case artifacts.MessageStoreTypeFile:
+		directory := store.Directory
+		if !filepath.IsAbs(directory) {
+			directory = filepath.Join(home, directory)
+		}
+		return OpenFileStore(directory, store.MaxSize)
+	}
+	return nil, fmt.Errorf("unknown message store type '%s'", store.Type)
+}
+
+var (
+	storesMu sync.RWMutex
+	stores   = make(map[string]Store)
+)
+
+// Register makes a store available under the name, replacing the store
+// registered before
+func Register(name string, store Store) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	stores[name] = store
+}
+
+// Lookup returns the store registered under the name
+func Lookup(name string) (Store, error) {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+	store, ok := stores[name]
+	if !ok {
+		names := make([]string, 0, len(stores))
+		for registered := range stores {
+			names = append(names, registered)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("message store %s is not deployed, deployed stores are %s", name, strings.Join(names, ", "))
+	}
+	return store, nil
+}
+
+// StoreMessage puts a copy of msg in the named store and returns its id. It
+// is the artifacts.MessageStorer of store mediators.
+func StoreMessage(ctx context.Context, name string, msg *synctx.MsgContext, priority int) (string, error) {
+	store, err := Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	stored, err := FromContext(msg, priority)
+	if err != nil {
+		return "", err
+	}
+	if err := store.Put(ctx, stored); err != nil {
+		return "", fmt.Errorf("message store %s: %w", name, err)
+	}
+	return stored.ID, nil
+}
@@ -0,0 +1,183 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package messagestore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/redelivery"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func message(t *testing.T, payload string, priority int) Message {
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte(payload)
+	stored, err := FromContext(msg, priority)
+	require.NoError(t, err)
+	return stored
+}
+
+func TestStores_Order(t *testing.T) {
+	file, err := OpenFileStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	for name, store := range map[string]Store{"memory": NewMemoryStore(0, 0), "file": file} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, store.Put(ctx, message(t, "low", 1)))
+			require.NoError(t, store.Put(ctx, message(t, "urgent", 9)))
+			require.NoError(t, store.Put(ctx, message(t, "second", 4)))
+			require.NoError(t, store.Put(ctx, message(t, "third", 4)))
+			assert.Equal(t, 4, store.Len())
+
+			var order []string
+			for {
+				msg, err := store.Next(ctx)
+				if err == ErrEmpty {
+					break
+				}
+				require.NoError(t, err)
+				order = append(order, string(msg.Payload))
+				require.NoError(t, store.Ack(ctx, msg.ID))
+			}
+			assert.Equal(t, []string{"urgent", "second", "third", "low"}, order)
+			assert.Equal(t, 0, store.Len())
+		})
+	}
+}
+
+func TestStores_LeaseAndRelease(t *testing.T) {
+	file, err := OpenFileStore(t.TempDir(), 2)
+	require.NoError(t, err)
+	for name, store := range map[string]Store{"memory": NewMemoryStore(2, 0), "file": file} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, store.Put(ctx, message(t, "a", 4)))
+			require.NoError(t, store.Put(ctx, message(t, "b", 4)))
+			assert.ErrorIs(t, store.Put(ctx, message(t, "c", 4)), ErrFull)
+
+			leased, err := store.Next(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "a", string(leased.Payload))
+			// A leased message is not handed out twice and still counts
+			next, err := store.Next(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "b", string(next.Payload))
+			_, err = store.Next(ctx)
+			assert.ErrorIs(t, err, ErrEmpty)
+			assert.Equal(t, 2, store.Len())
+
+			leased.Retry = redelivery.State{Attempts: 1, LastError: "backend down"}
+			require.NoError(t, store.Release(ctx, leased))
+			assert.ErrorIs(t, store.Release(ctx, leased), ErrNotLeased)
+			again, err := store.Next(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, leased.ID, again.ID)
+			assert.Equal(t, 1, again.Retry.Attempts)
+			assert.Equal(t, "backend down", again.Retry.LastError)
+			assert.ErrorIs(t, store.Ack(ctx, "unknown"), ErrNotLeased)
+		})
+	}
+}
+
+func TestFileStore_Reopen(t *testing.T) {
+	directory := t.TempDir()
+	store, err := OpenFileStore(directory, 0)
+	require.NoError(t, err)
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte(`{"orderId":7}`)
+	msg.Message.ContentType = "application/json"
+	msg.Headers["X-Tenant"] = "acme"
+	msg.Properties["orderId"] = 7
+	msg.Properties["express"] = true
+	msg.Properties[artifacts.SnapshotProperty] = &artifacts.Snapshot{}
+	stored, err := FromContext(msg, 4)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), stored))
+
+	reopened, err := OpenFileStore(directory, 0)
+	require.NoError(t, err)
+	next, err := reopened.Next(context.Background())
+	require.NoError(t, err)
+	restored := next.Context()
+	assert.Equal(t, `{"orderId":7}`, string(restored.Message.RawPayload))
+	assert.Equal(t, "application/json", restored.Message.ContentType)
+	assert.Equal(t, "acme", restored.Headers["X-Tenant"])
+	assert.Equal(t, map[string]any{"orderId": 7, "express": true}, restored.Properties)
+}
+
+func TestFileStore_Quarantine(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "0-0000000000000000000-corrupt.json"), []byte("{not json"), 0o644))
+	store, err := OpenFileStore(directory, 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, message(t, "good", 4)))
+	assert.Equal(t, 2, store.Len())
+
+	next, err := store.Next(ctx)
+	require.NoError(t, err, "a corrupt file does not block the messages behind it")
+	assert.Equal(t, "good", string(next.Payload))
+	require.NoError(t, store.Ack(ctx, next.ID))
+	_, err = store.Next(ctx)
+	assert.ErrorIs(t, err, ErrEmpty)
+	assert.Equal(t, 0, store.Len())
+
+	_, err = os.Stat(filepath.Join(directory, QuarantineDirectory, "0-0000000000000000000-corrupt.json"))
+	assert.NoError(t, err, "the corrupt file is moved to the quarantine directory")
+	reopened, err := OpenFileStore(directory, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, reopened.Len(), "quarantined files are not reloaded")
+}
+
+func TestStoreMessage(t *testing.T) {
+	store := NewMemoryStore(0, time.Minute)
+	Register("Orders", store)
+	t.Cleanup(func() {
+		storesMu.Lock()
+		delete(stores, "Orders")
+		storesMu.Unlock()
+	})
+
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte("order")
+	msg.Properties[redelivery.AttemptProperty] = 3
+	msg.Properties[redelivery.LastErrorProperty] = "timeout"
+	id, err := StoreMessage(context.Background(), "Orders", msg, 6)
+	require.NoError(t, err)
+
+	stored, err := store.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, id, stored.ID)
+	assert.Equal(t, 6, stored.Priority)
+	assert.Equal(t, 2, stored.Retry.Attempts)
+	assert.Equal(t, "timeout", stored.Retry.LastError)
+
+	_, err = StoreMessage(context.Background(), "Missing", msg, 4)
+	assert.EqualError(t, err, "message store Missing is not deployed, deployed stores are Orders")
+}
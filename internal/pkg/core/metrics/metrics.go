@@ -0,0 +1,356 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package metrics records request latencies, event counts and gauges and exposes them
+// in the OpenMetrics text format, with trace ID exemplars when tracing is enabled.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the media type of the OpenMetrics text exposition format
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// DefaultBuckets are the latency histogram upper bounds in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Config holds the [metrics] section of deployment.toml
+type Config struct {
+	Enabled bool      `koanf:"enabled"`
+	Path    string    `koanf:"path"`
+	Buckets []float64 `koanf:"buckets"`
+}
+
+// Validate reports configuration errors in the [metrics] section
+func (c Config) Validate() error {
+	if c.Path != "" && !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("metrics: path must begin with '/', got '%s'", c.Path)
+	}
+	if !slices.IsSorted(c.Buckets) {
+		return fmt.Errorf("metrics: buckets must be in increasing order")
+	}
+	return nil
+}
+
+// Exemplar links a histogram observation to the trace that produced it
+type Exemplar struct {
+	TraceID string
+	Value   float64
+	Time    time.Time
+}
+
+type series struct {
+	labels    string
+	counts    []uint64 // per bucket, not cumulative; the last entry is +Inf
+	exemplars []*Exemplar
+	count     uint64
+	sum       float64
+}
+
+// Histogram is a latency histogram partitioned by label values
+type Histogram struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// Observe records a value, in seconds for latency histograms. A non-empty traceID becomes the exemplar
+// of the bucket the value falls into.
+func (h *Histogram) Observe(value float64, traceID string, labelValues ...string) {
+	labels := formatLabels(h.labelNames, labelValues)
+	index := len(h.buckets)
+	for i, bound := range h.buckets {
+		if value <= bound {
+			index = i
+			break
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[labels]
+	if !ok {
+		s = &series{labels: labels, counts: make([]uint64, len(h.buckets)+1), exemplars: make([]*Exemplar, len(h.buckets)+1)}
+		h.series[labels] = s
+	}
+	s.counts[index]++
+	s.count++
+	s.sum += value
+	if traceID != "" {
+		s.exemplars[index] = &Exemplar{TraceID: traceID, Value: value, Time: time.Now()}
+	}
+}
+
+func (h *Histogram) write(w io.Writer) {
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+	// Histograms of counts, such as batch sizes, have no unit
+	if strings.HasSuffix(h.name, "_seconds") {
+		fmt.Fprintf(w, "# UNIT %s seconds\n", h.name)
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s := h.series[key]
+		var cumulative uint64
+		for i, count := range s.counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(h.buckets) {
+				le = formatFloat(h.buckets[i])
+			}
+			fmt.Fprintf(w, "%s_bucket{%s} %d", h.name, joinLabels(s.labels, `le="`+le+`"`), cumulative)
+			if exemplar := s.exemplars[i]; exemplar != nil {
+				fmt.Fprintf(w, ` # {trace_id="%s"} %s %s`, exemplar.TraceID, formatFloat(exemplar.Value),
+					strconv.FormatFloat(float64(exemplar.Time.UnixMilli())/1000, 'f', 3, 64))
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, braces(s.labels), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, braces(s.labels), formatFloat(s.sum))
+	}
+}
+
+// Counter is a monotonically increasing count partitioned by label values
+type Counter struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// Inc increments the count of the series with the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	labels := formatLabels(c.labelNames, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[labels]++
+}
+
+// Value returns the count of the series with the given label values
+func (c *Counter) Value(labelValues ...string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[formatLabels(c.labelNames, labelValues)]
+}
+
+func (c *Counter) write(w io.Writer) {
+	fmt.Fprintf(w, "# TYPE %s counter\n# HELP %s %s\n", c.name, c.name, c.help)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]string, 0, len(c.counts))
+	for key := range c.counts {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s_total%s %d\n", c.name, braces(key), c.counts[key])
+	}
+}
+
+// Gauge is a value that can go up and down, partitioned by label values
+type Gauge struct {
+	name       string
+	help       string
+	unit       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Set replaces the value of the series with the given label values
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	labels := formatLabels(g.labelNames, labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[labels] = value
+}
+
+// Value returns the value of the series with the given label values
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[formatLabels(g.labelNames, labelValues)]
+}
+
+func (g *Gauge) write(w io.Writer) {
+	fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+	if g.unit != "" {
+		fmt.Fprintf(w, "# UNIT %s %s\n", g.name, g.unit)
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	keys := make([]string, 0, len(g.values))
+	for key := range g.values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, braces(key), formatFloat(g.values[key]))
+	}
+}
+
+// Registry holds the runtime's metrics
+type Registry struct {
+	mu         sync.Mutex
+	histograms []*Histogram
+	counters   []*Counter
+	gauges     []*Gauge
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Histogram returns the histogram with the given name, creating it on first use
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, h := range r.histograms {
+		if h.name == name {
+			return h
+		}
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	h := &Histogram{name: name, help: help, labelNames: labelNames, buckets: buckets, series: make(map[string]*series)}
+	r.histograms = append(r.histograms, h)
+	return h
+}
+
+// Counter returns the counter with the given name, creating it on first use.
+// The name must not carry the _total suffix, which is added on exposition.
+func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.counters {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &Counter{name: name, help: help, labelNames: labelNames, counts: make(map[string]uint64)}
+	r.counters = append(r.counters, c)
+	return c
+}
+
+// Gauge returns the gauge with the given name, creating it on first use. A
+// non-empty unit, e.g. seconds, must also end the name.
+func (r *Registry) Gauge(name, unit, help string, labelNames ...string) *Gauge {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range r.gauges {
+		if g.name == name {
+			return g
+		}
+	}
+	g := &Gauge{name: name, help: help, unit: unit, labelNames: labelNames, values: make(map[string]float64)}
+	r.gauges = append(r.gauges, g)
+	return g
+}
+
+// WriteOpenMetrics writes every metric in the OpenMetrics text format
+func (r *Registry) WriteOpenMetrics(w io.Writer) {
+	r.mu.Lock()
+	histograms := slices.Clone(r.histograms)
+	counters := slices.Clone(r.counters)
+	gauges := slices.Clone(r.gauges)
+	r.mu.Unlock()
+	for _, h := range histograms {
+		h.write(w)
+	}
+	for _, c := range counters {
+		c.write(w)
+	}
+	for _, g := range gauges {
+		g.write(w)
+	}
+	fmt.Fprintln(w, "# EOF")
+}
+
+// Handler serves the registry in the OpenMetrics text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteOpenMetrics(w)
+	})
+}
+
+var defaultRegistry = NewRegistry()
+
+// Default returns the registry exposed by the runtime
+func Default() *Registry {
+	return defaultRegistry
+}
+
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+		pairs[i] = name + `="` + value + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
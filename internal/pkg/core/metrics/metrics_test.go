@@ -0,0 +1,126 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram_OpenMetricsWithExemplars(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.Histogram("synapse_test_duration_seconds", "Test latency.", []float64{0.1, 1}, "api")
+	histogram.Observe(0.05, "", "OrdersAPI")
+	histogram.Observe(0.5, "4bf92f3577b34da6a3ce929d0e0e4736", "OrdersAPI")
+	histogram.Observe(3, "", "OrdersAPI")
+	assert.Same(t, histogram, registry.Histogram("synapse_test_duration_seconds", "", nil), "histograms are looked up by name")
+
+	var out bytes.Buffer
+	registry.WriteOpenMetrics(&out)
+
+	// The exemplar timestamp varies, so it is matched as a number
+	expected := regexp.QuoteMeta(`# TYPE synapse_test_duration_seconds histogram
+# UNIT synapse_test_duration_seconds seconds
+# HELP synapse_test_duration_seconds Test latency.
+synapse_test_duration_seconds_bucket{api="OrdersAPI",le="0.1"} 1
+synapse_test_duration_seconds_bucket{api="OrdersAPI",le="1"} 2 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 0.5 `) +
+		`[0-9.]+` + regexp.QuoteMeta(`
+synapse_test_duration_seconds_bucket{api="OrdersAPI",le="+Inf"} 3
+synapse_test_duration_seconds_count{api="OrdersAPI"} 3
+synapse_test_duration_seconds_sum{api="OrdersAPI"} 3.55
+# EOF
+`)
+	assert.Regexp(t, "^"+expected+"$", out.String())
+}
+
+func TestCounter_OpenMetrics(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.Counter("synapse_test_panics", "Test panics.", "mediator")
+	counter.Inc("LogMediator")
+	counter.Inc("LogMediator")
+	counter.Inc("MaskMediator")
+	assert.Same(t, counter, registry.Counter("synapse_test_panics", ""), "counters are looked up by name")
+	assert.Equal(t, uint64(2), counter.Value("LogMediator"))
+
+	var out bytes.Buffer
+	registry.WriteOpenMetrics(&out)
+	assert.Equal(t, `# TYPE synapse_test_panics counter
+# HELP synapse_test_panics Test panics.
+synapse_test_panics_total{mediator="LogMediator"} 2
+synapse_test_panics_total{mediator="MaskMediator"} 1
+# EOF
+`, out.String())
+}
+
+func TestGauge_OpenMetrics(t *testing.T) {
+	registry := NewRegistry()
+	gauge := registry.Gauge("synapse_test_lag_seconds", "seconds", "Test lag.", "inbound")
+	gauge.Set(2.5, "orders")
+	gauge.Set(0.5, "orders")
+	gauge.Set(1, "invoices")
+	assert.Equal(t, 0.5, gauge.Value("orders"))
+	assert.Same(t, gauge, registry.Gauge("synapse_test_lag_seconds", "", ""), "gauges are looked up by name")
+
+	var out bytes.Buffer
+	registry.WriteOpenMetrics(&out)
+	assert.Equal(t, `# TYPE synapse_test_lag_seconds gauge
+# UNIT synapse_test_lag_seconds seconds
+# HELP synapse_test_lag_seconds Test lag.
+synapse_test_lag_seconds{inbound="invoices"} 1
+synapse_test_lag_seconds{inbound="orders"} 0.5
+# EOF
+`, out.String())
+}
+
+func TestHistogram_WithoutUnit(t *testing.T) {
+	registry := NewRegistry()
+	registry.Histogram("synapse_test_batch_size", "Test batches.", []float64{10}).Observe(3, "")
+
+	var out bytes.Buffer
+	registry.WriteOpenMetrics(&out)
+	assert.NotContains(t, out.String(), "# UNIT")
+	assert.Contains(t, out.String(), "synapse_test_batch_size_sum 3\n")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "defaults", config: Config{Enabled: true}},
+		{name: "custom", config: Config{Path: "/stats", Buckets: []float64{0.1, 0.5, 1}}},
+		{name: "relative path", config: Config{Path: "metrics"}, wantErr: "metrics: path must begin with '/', got 'metrics'"},
+		{name: "unsorted buckets", config: Config{Buckets: []float64{1, 0.5}}, wantErr: "metrics: buckets must be in increasing order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
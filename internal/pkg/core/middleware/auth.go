@@ -0,0 +1,203 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/ldap"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+)
+
+// SecurityConfig holds the [security] section of deployment.toml
+type SecurityConfig struct {
+	Policies []PolicyConfig `koanf:"policy"`
+}
+
+// PolicyConfig holds one [[security.policy]] entry
+type PolicyConfig struct {
+	Name string `koanf:"name"`
+	// Type is "basic" or "apikey"
+	Type string `koanf:"type"`
+	// Realm is reported in the WWW-Authenticate header of basic auth challenges
+	Realm string `koanf:"realm"`
+	// Users maps user names to passwords for basic auth
+	Users map[string]string `koanf:"users"`
+	// LDAP is nil unless basic auth credentials not found in Users are
+	// verified against an LDAP or Active Directory server
+	LDAP *ldap.Config `koanf:"ldap"`
+	// Header carries the API key, X-API-Key by default
+	Header string `koanf:"header"`
+	// Keys maps API keys to the identity of the consumer owning them
+	Keys map[string]string `koanf:"keys"`
+	// Lockout is nil unless repeated authentication failures lock clients out
+	Lockout *LockoutConfig `koanf:"lockout"`
+}
+
+// Validate checks that every policy is complete and uniquely named
+func (c SecurityConfig) Validate() error {
+	names := make(map[string]bool)
+	for _, policy := range c.Policies {
+		if policy.Name == "" {
+			return fmt.Errorf("security policy name is required")
+		}
+		if names[policy.Name] {
+			return fmt.Errorf("duplicate security policy: %s", policy.Name)
+		}
+		names[policy.Name] = true
+
+		switch policy.Type {
+		case "basic":
+			if len(policy.Users) == 0 && policy.LDAP == nil {
+				return fmt.Errorf("security policy %s: basic auth requires at least one user or an ldap directory", policy.Name)
+			}
+			if policy.LDAP != nil {
+				if err := policy.LDAP.Validate(); err != nil {
+					return fmt.Errorf("security policy %s: %w", policy.Name, err)
+				}
+			}
+		case "apikey":
+			if len(policy.Keys) == 0 {
+				return fmt.Errorf("security policy %s: apikey requires at least one key", policy.Name)
+			}
+		default:
+			return fmt.Errorf("security policy %s: type must be either 'basic' or 'apikey', got: %s", policy.Name, policy.Type)
+		}
+		if policy.Lockout != nil {
+			if _, err := policy.Lockout.settings(); err != nil {
+				return fmt.Errorf("security policy %s: %w", policy.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Policy returns the named policy
+func (c SecurityConfig) Policy(name string) (PolicyConfig, error) {
+	for _, policy := range c.Policies {
+		if policy.Name == name {
+			return policy, nil
+		}
+	}
+	return PolicyConfig{}, fmt.Errorf("security policy not found: %s", name)
+}
+
+// authenticate returns the identity of the caller if the request carries
+// valid credentials. An error means the credentials could not be checked.
+func (p PolicyConfig) authenticate(r *http.Request, directory *ldap.Directory) (string, bool, error) {
+	switch p.Type {
+	case "basic":
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			return "", false, nil
+		}
+		if expected, exists := p.Users[user]; exists {
+			return user, subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1, nil
+		}
+		if directory == nil {
+			return "", false, nil
+		}
+		ok, err := directory.Authenticate(user, password)
+		return user, ok, err
+	case "apikey":
+		header := p.Header
+		if header == "" {
+			header = "X-API-Key"
+		}
+		key := r.Header.Get(header)
+		if key == "" {
+			return "", false, nil
+		}
+		for candidate, identity := range p.Keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+				return identity, true, nil
+			}
+		}
+	}
+	return "", false, nil
+}
+
+type principalKey struct{}
+
+// Principal returns the identity authenticated for the request, if any
+func Principal(r *http.Request) (string, bool) {
+	principal, ok := r.Context().Value(principalKey{}).(string)
+	return principal, ok
+}
+
+// Authenticate rejects requests that do not satisfy policy with 401
+// Unauthorized. When the policy has a lockout, clients locked out after
+// repeated failures are rejected with 429 Too Many Requests before their
+// credentials are checked. Requests whose credentials cannot be checked
+// because the LDAP server is unreachable are rejected with 503 Service
+// Unavailable and do not count as failures.
+func Authenticate(policy PolicyConfig, next http.Handler) (http.Handler, error) {
+	guard, err := lockoutFor(policy)
+	if err != nil {
+		return nil, err
+	}
+	var directory *ldap.Directory
+	if policy.LDAP != nil {
+		if directory, err = ldap.Shared(*policy.LDAP); err != nil {
+			return nil, fmt.Errorf("security policy %s: %w", policy.Name, err)
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var identities []string
+		if guard != nil {
+			identities = guard.identities(r)
+			if remaining, locked := guard.lockedOut(identities); locked {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+				problem.Write(w, r, http.StatusTooManyRequests, "Too many failed authentication attempts")
+				return
+			}
+		}
+		principal, ok, err := policy.authenticate(r, directory)
+		if err != nil {
+			slog.Error("cannot verify credentials", "policy", policy.Name, "error", err)
+			problem.Write(w, r, http.StatusServiceUnavailable, "Authentication service unavailable")
+			return
+		}
+		if !ok {
+			if guard != nil {
+				guard.fail(identities)
+			}
+			if policy.Type == "basic" {
+				realm := policy.Realm
+				if realm == "" {
+					realm = "synapse"
+				}
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+			}
+			problem.Write(w, r, http.StatusUnauthorized, "")
+			return
+		}
+		if guard != nil {
+			guard.succeed(identities)
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	}), nil
+}
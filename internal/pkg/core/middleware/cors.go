@@ -0,0 +1,103 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig holds the [cors] section of deployment.toml
+type CORSConfig struct {
+	Enabled          bool     `koanf:"enabled"`
+	AllowOrigins     []string `koanf:"allowOrigins"`
+	AllowMethods     []string `koanf:"allowMethods"`
+	AllowHeaders     []string `koanf:"allowHeaders"`
+	ExposeHeaders    []string `koanf:"exposeHeaders"`
+	AllowCredentials bool     `koanf:"allowCredentials"`
+	// MaxAge is the preflight cache duration in seconds
+	MaxAge int `koanf:"maxAge"`
+}
+
+var defaultAllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// allowsOrigin reports whether the origin may access the resource
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	return slices.Contains(c.AllowOrigins, "*") || slices.Contains(c.AllowOrigins, origin)
+}
+
+// CORS answers preflight requests and decorates responses with the
+// Access-Control-* headers described by config
+func CORS(config CORSConfig, next http.Handler) http.Handler {
+	allowMethods := config.AllowMethods
+	if len(allowMethods) == 0 {
+		allowMethods = defaultAllowMethods
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+
+		isPreflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !config.allowsOrigin(origin) {
+			if isPreflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A wildcard cannot be combined with credentials, so echo the origin instead
+		if slices.Contains(config.AllowOrigins, "*") && !config.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if isPreflight {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowMethods, ", "))
+			if len(config.AllowHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowHeaders, ", "))
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				w.Header().Set("Access-Control-Allow-Headers", requested)
+			}
+			if config.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if len(config.ExposeHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposeHeaders, ", "))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
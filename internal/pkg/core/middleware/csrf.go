@@ -0,0 +1,171 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+)
+
+const (
+	DefaultCSRFCookieName = "XSRF-TOKEN"
+	DefaultCSRFHeaderName = "X-XSRF-TOKEN"
+)
+
+// CSRFConfig holds the [csrf] section of deployment.toml. Protection is enabled
+// per artifact; the section only tunes how tokens are issued and checked.
+type CSRFConfig struct {
+	CookieName string `koanf:"cookieName"`
+	HeaderName string `koanf:"headerName"`
+	// SameSite is "strict", "lax" or "none"; defaults to "strict"
+	SameSite   string `koanf:"sameSite"`
+	Secure     bool   `koanf:"secure"`
+	CookiePath string `koanf:"cookiePath"`
+	// TrustedOrigins may submit unsafe requests in addition to the request's own
+	// origin and the explicit origins allowed by [cors]
+	TrustedOrigins []string `koanf:"trustedOrigins"`
+}
+
+// Validate reports configuration errors in the [csrf] section
+func (c CSRFConfig) Validate() error {
+	switch strings.ToLower(c.SameSite) {
+	case "", "strict", "lax":
+	case "none":
+		if !c.Secure {
+			return fmt.Errorf("csrf: sameSite 'none' requires secure = true")
+		}
+	default:
+		return fmt.Errorf("csrf: sameSite must be one of 'strict', 'lax' or 'none', got: %s", c.SameSite)
+	}
+	return nil
+}
+
+func (c CSRFConfig) cookieName() string {
+	if c.CookieName == "" {
+		return DefaultCSRFCookieName
+	}
+	return c.CookieName
+}
+
+func (c CSRFConfig) headerName() string {
+	if c.HeaderName == "" {
+		return DefaultCSRFHeaderName
+	}
+	return c.HeaderName
+}
+
+func (c CSRFConfig) sameSite() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+// trustsOrigin reports whether a request from origin may change state. Origins
+// allowed by the CORS configuration are trusted so the two features agree on
+// which browser applications may call the API; a CORS wildcard is not.
+func (c CSRFConfig) trustsOrigin(origin string, r *http.Request, cors CORSConfig) bool {
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+	if slices.Contains(c.TrustedOrigins, origin) {
+		return true
+	}
+	return cors.Enabled && slices.Contains(cors.AllowOrigins, origin)
+}
+
+// isSafeMethod reports whether the method is defined as read-only by RFC 9110
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func newCSRFToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// CSRF protects browser-facing handlers with the double-submit cookie pattern.
+// Safe requests are issued a token cookie; unsafe requests must echo the cookie
+// value in the token header and, when the browser sends an Origin header, come
+// from a trusted origin.
+func CSRF(config CSRFConfig, cors CORSConfig, next http.Handler) http.Handler {
+	cookieName := config.cookieName()
+	headerName := config.headerName()
+	cookiePath := config.CookiePath
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(cookieName)
+		hasToken := err == nil && cookie.Value != ""
+
+		if isSafeMethod(r.Method) {
+			if !hasToken {
+				token, err := newCSRFToken()
+				if err != nil {
+					problem.Write(w, r, http.StatusInternalServerError, "Cannot issue a CSRF token")
+					return
+				}
+				// The cookie must be readable by scripts so they can copy it into
+				// the request header, hence no HttpOnly
+				http.SetCookie(w, &http.Cookie{
+					Name:     cookieName,
+					Value:    token,
+					Path:     cookiePath,
+					Secure:   config.Secure,
+					SameSite: config.sameSite(),
+				})
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" && !config.trustsOrigin(origin, r, cors) {
+			problem.Write(w, r, http.StatusForbidden, "Untrusted origin")
+			return
+		}
+		header := r.Header.Get(headerName)
+		if !hasToken || header == "" || subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+			problem.Write(w, r, http.StatusForbidden, "Missing or invalid CSRF token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
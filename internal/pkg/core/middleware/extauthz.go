@@ -0,0 +1,319 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	componentName = "middleware"
+
+	// FailureModeDeny rejects requests when the authorization service cannot
+	// be reached or answers with an error
+	FailureModeDeny = "deny"
+	// FailureModeAllow lets requests through when the service fails
+	FailureModeAllow = "allow"
+
+	defaultAuthorizationTimeout = time.Second
+	// maxCachedDecisions bounds the decision cache of one service
+	maxCachedDecisions = 10000
+)
+
+// AuthorizationConfig holds the [authorization] section of deployment.toml
+type AuthorizationConfig struct {
+	Services []AuthorizationService `koanf:"service"`
+}
+
+// AuthorizationService holds one [[authorization.service]] entry, an external
+// service that decides whether requests may proceed
+type AuthorizationService struct {
+	Name string `koanf:"name"`
+	URL  string `koanf:"url"`
+	// Protocol is "http", the only protocol supported
+	Protocol string `koanf:"protocol"`
+	// Timeout bounds each check, one second by default
+	Timeout string `koanf:"timeout"`
+	// FailureMode is "deny" (the default) or "allow"
+	FailureMode string `koanf:"failureMode"`
+	// CacheTTL is how long decisions are reused for identical requests; empty
+	// disables caching
+	CacheTTL string `koanf:"cacheTTL"`
+	// Headers lists the request headers sent to the service; empty sends all
+	Headers []string `koanf:"headers"`
+}
+
+// Validate checks that every service is complete and uniquely named
+func (c AuthorizationConfig) Validate() error {
+	names := make(map[string]bool)
+	for _, service := range c.Services {
+		if service.Name == "" {
+			return fmt.Errorf("authorization service name is required")
+		}
+		if names[service.Name] {
+			return fmt.Errorf("duplicate authorization service: %s", service.Name)
+		}
+		names[service.Name] = true
+		if _, err := newAuthorizer(service); err != nil {
+			return fmt.Errorf("authorization service %s: %w", service.Name, err)
+		}
+	}
+	return nil
+}
+
+// Service returns the named authorization service
+func (c AuthorizationConfig) Service(name string) (AuthorizationService, error) {
+	for _, service := range c.Services {
+		if service.Name == name {
+			return service, nil
+		}
+	}
+	return AuthorizationService{}, fmt.Errorf("authorization service not found: %s", name)
+}
+
+// CheckRequest describes the request being authorized
+type CheckRequest struct {
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Host      string            `json:"host"`
+	Headers   map[string]string `json:"headers"`
+	Principal string            `json:"principal,omitempty"`
+}
+
+// CheckResponse is the decision of the authorization service
+type CheckResponse struct {
+	Allowed bool `json:"allowed"`
+	// Headers are added to allowed requests before they are mediated
+	Headers map[string]string `json:"headers,omitempty"`
+	// Status and Reason are returned to denied clients; Status defaults to 403
+	Status int    `json:"status,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuthorizationClient sends check requests to an authorization service
+type AuthorizationClient interface {
+	Check(ctx context.Context, request CheckRequest) (CheckResponse, error)
+}
+
+// httpAuthorizationClient posts check requests as JSON and reads the decision
+// from the JSON response body
+type httpAuthorizationClient struct {
+	url    string
+	client *http.Client
+}
+
+func (c httpAuthorizationClient) Check(ctx context.Context, check CheckRequest) (CheckResponse, error) {
+	body, err := json.Marshal(check)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := c.client.Do(request)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return CheckResponse{}, fmt.Errorf("authorization service answered %s", response.Status)
+	}
+	var decision CheckResponse
+	if err := json.NewDecoder(response.Body).Decode(&decision); err != nil {
+		return CheckResponse{}, fmt.Errorf("invalid authorization response: %w", err)
+	}
+	return decision, nil
+}
+
+// authorizer checks requests against one service, caching its decisions
+type authorizer struct {
+	service  AuthorizationService
+	client   AuthorizationClient
+	timeout  time.Duration
+	cacheTTL time.Duration
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	cache map[string]cachedDecision
+}
+
+type cachedDecision struct {
+	response CheckResponse
+	expiry   time.Time
+}
+
+func newAuthorizer(service AuthorizationService) (*authorizer, error) {
+	switch service.Protocol {
+	case "", "http":
+	default:
+		return nil, fmt.Errorf("protocol must be 'http', got: %s", service.Protocol)
+	}
+	if !strings.HasPrefix(service.URL, "http://") && !strings.HasPrefix(service.URL, "https://") {
+		return nil, fmt.Errorf("url must be an http(s) URL, got: %s", service.URL)
+	}
+	switch service.FailureMode {
+	case "", FailureModeDeny, FailureModeAllow:
+	default:
+		return nil, fmt.Errorf("failureMode must be '%s' or '%s', got: %s", FailureModeDeny, FailureModeAllow, service.FailureMode)
+	}
+	a := &authorizer{service: service, timeout: defaultAuthorizationTimeout, cache: make(map[string]cachedDecision)}
+	var err error
+	if service.Timeout != "" {
+		if a.timeout, err = time.ParseDuration(service.Timeout); err != nil || a.timeout <= 0 {
+			return nil, fmt.Errorf("timeout must be a positive duration, got: %s", service.Timeout)
+		}
+	}
+	if service.CacheTTL != "" {
+		if a.cacheTTL, err = time.ParseDuration(service.CacheTTL); err != nil || a.cacheTTL < 0 {
+			return nil, fmt.Errorf("cacheTTL must be a duration, got: %s", service.CacheTTL)
+		}
+	}
+	a.client = httpAuthorizationClient{url: service.URL, client: &http.Client{}}
+	a.logger = loggerfactory.GetLogger(componentName, a)
+	return a, nil
+}
+
+func (a *authorizer) UpdateLogger() {
+	a.logger = loggerfactory.GetLogger(componentName, a)
+}
+
+// checkRequest describes r with the headers the service is configured to see
+func (a *authorizer) checkRequest(r *http.Request) CheckRequest {
+	check := CheckRequest{Method: r.Method, Path: r.URL.RequestURI(), Host: r.Host, Headers: make(map[string]string)}
+	if len(a.service.Headers) == 0 {
+		for name := range r.Header {
+			check.Headers[name] = r.Header.Get(name)
+		}
+	} else {
+		for _, name := range a.service.Headers {
+			if value := r.Header.Get(name); value != "" {
+				check.Headers[http.CanonicalHeaderKey(name)] = value
+			}
+		}
+	}
+	check.Principal, _ = Principal(r)
+	return check
+}
+
+func cacheKey(check CheckRequest) string {
+	var key strings.Builder
+	fmt.Fprintf(&key, "%s\x00%s\x00%s\x00%s", check.Method, check.Host, check.Path, check.Principal)
+	for _, name := range slices.Sorted(maps.Keys(check.Headers)) {
+		fmt.Fprintf(&key, "\x00%s=%s", name, check.Headers[name])
+	}
+	return key.String()
+}
+
+// decide returns the service's decision for the request, from the cache when
+// an identical request was decided within the cache TTL
+func (a *authorizer) decide(ctx context.Context, check CheckRequest) (CheckResponse, error) {
+	key := cacheKey(check)
+	now := time.Now()
+	if a.cacheTTL > 0 {
+		a.mu.Lock()
+		cached, ok := a.cache[key]
+		a.mu.Unlock()
+		if ok && now.Before(cached.expiry) {
+			return cached.response, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	decision, err := a.client.Check(ctx, check)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	if a.cacheTTL > 0 {
+		a.mu.Lock()
+		if len(a.cache) >= maxCachedDecisions {
+			maps.DeleteFunc(a.cache, func(_ string, cached cachedDecision) bool { return now.After(cached.expiry) })
+			if len(a.cache) >= maxCachedDecisions {
+				clear(a.cache)
+			}
+		}
+		a.cache[key] = cachedDecision{response: decision, expiry: now.Add(a.cacheTTL)}
+		a.mu.Unlock()
+	}
+	return decision, nil
+}
+
+// ExternalAuthorize asks the authorization service whether each request may
+// proceed. Allowed requests carry the headers returned by the service; denied
+// requests are answered with the service's status, 403 Forbidden by default.
+// When the service fails, the request is denied with 403 unless the service's
+// failure mode is "allow".
+func ExternalAuthorize(service AuthorizationService, next http.Handler) (http.Handler, error) {
+	a, err := newAuthorizer(service)
+	if err != nil {
+		return nil, fmt.Errorf("authorization service %s: %w", service.Name, err)
+	}
+	return a.wrap(next), nil
+}
+
+func (a *authorizer) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decision, err := a.decide(r.Context(), a.checkRequest(r))
+		if err != nil {
+			if a.service.FailureMode == FailureModeAllow {
+				a.logger.Warn("Authorization service failed, allowing request",
+					slog.String("service", a.service.Name),
+					slog.String("error", err.Error()))
+				next.ServeHTTP(w, r)
+				return
+			}
+			a.logger.Error("Authorization service failed, denying request",
+				slog.String("service", a.service.Name),
+				slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusForbidden, "")
+			return
+		}
+		if !decision.Allowed {
+			status := decision.Status
+			if status < 400 || status > 599 {
+				status = http.StatusForbidden
+			}
+			problem.Write(w, r, status, decision.Reason)
+			return
+		}
+		if len(decision.Headers) > 0 {
+			r = r.Clone(r.Context())
+			for name, value := range decision.Headers {
+				r.Header.Set(name, value)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,123 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap_ExternalAuthorization(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+	var checks atomic.Int32
+	authzServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks.Add(1)
+		var check CheckRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&check))
+		assert.NotContains(t, check.Headers, "X-Api-Key", "only the configured headers are sent")
+		var decision CheckResponse
+		switch {
+		case check.Principal == "acme" && check.Headers["X-Tenant"] == "acme":
+			decision = CheckResponse{Allowed: true, Headers: map[string]string{"X-Plan": "gold"}}
+		case check.Headers["X-Tenant"] == "":
+			decision = CheckResponse{Status: http.StatusBadRequest, Reason: "tenant required"}
+		}
+		json.NewEncoder(w).Encode(decision)
+	}))
+	defer authzServer.Close()
+
+	deploymentConfig := map[string]interface{}{
+		"security": SecurityConfig{Policies: []PolicyConfig{
+			{Name: "partners", Type: "apikey", Keys: map[string]string{"k-123": "acme"}},
+		}},
+		"authorization": AuthorizationConfig{Services: []AuthorizationService{
+			{Name: "policies", URL: authzServer.URL, CacheTTL: "1m", Headers: []string{"X-Tenant"}},
+			{Name: "unreachable-open", URL: "http://127.0.0.1:1", FailureMode: FailureModeAllow},
+			{Name: "unreachable-closed", URL: "http://127.0.0.1:1"},
+		}},
+	}
+	plan := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Plan")))
+	})
+
+	testCases := []struct {
+		name           string
+		service        string
+		tenant         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Allowed with injected header", service: "policies", tenant: "acme", expectedStatus: http.StatusOK, expectedBody: "gold"},
+		{name: "Denied", service: "policies", tenant: "globex", expectedStatus: http.StatusForbidden},
+		{name: "Denied with status", service: "policies", expectedStatus: http.StatusBadRequest},
+		{name: "Fail open", service: "unreachable-open", tenant: "acme", expectedStatus: http.StatusOK},
+		{name: "Fail closed", service: "unreachable-closed", tenant: "acme", expectedStatus: http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, err := Wrap(plan, deploymentConfig, Options{SecurityPolicy: "partners", Authorization: tc.service})
+			require.NoError(t, err)
+			for range 2 {
+				req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+				req.Header.Set("X-API-Key", "k-123")
+				if tc.tenant != "" {
+					req.Header.Set("X-Tenant", tc.tenant)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				assert.Equal(t, tc.expectedStatus, rec.Code)
+				if tc.expectedBody != "" {
+					assert.Equal(t, tc.expectedBody, rec.Body.String())
+				}
+			}
+		})
+	}
+	assert.Equal(t, int32(3), checks.Load(), "repeated requests are decided from the cache")
+
+	_, err := Wrap(plan, deploymentConfig, Options{Authorization: "missing"})
+	assert.EqualError(t, err, "authorization service not found: missing")
+}
+
+func TestAuthorizationConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		service  AuthorizationService
+		expected string
+	}{
+		{name: "Missing name", service: AuthorizationService{URL: "http://authz"}, expected: "authorization service name is required"},
+		{name: "Invalid URL", service: AuthorizationService{Name: "a", URL: "authz:9000"}, expected: "authorization service a: url must be an http(s) URL, got: authz:9000"},
+		{name: "gRPC", service: AuthorizationService{Name: "a", URL: "http://authz", Protocol: "grpc"}, expected: "authorization service a: protocol must be 'http', got: grpc"},
+		{name: "Invalid failure mode", service: AuthorizationService{Name: "a", URL: "http://authz", FailureMode: "open"}, expected: "authorization service a: failureMode must be 'deny' or 'allow', got: open"},
+		{name: "Invalid timeout", service: AuthorizationService{Name: "a", URL: "http://authz", Timeout: "0s"}, expected: "authorization service a: timeout must be a positive duration, got: 0s"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := AuthorizationConfig{Services: []AuthorizationService{tc.service}}.Validate()
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,336 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+)
+
+// Identities authentication failures are counted against
+const (
+	TrackByIP   = "ip"
+	TrackByUser = "user"
+)
+
+// LockoutConfig holds the lockout table of a [[security.policy]] entry. Clients
+// that keep failing authentication are first slowed down, then banned, to
+// protect the policy's credentials from guessing and credential stuffing.
+type LockoutConfig struct {
+	// MaxFailures failed attempts within Window ban the client for BanDuration
+	MaxFailures int    `koanf:"maxFailures"`
+	Window      string `koanf:"window"`
+	BanDuration string `koanf:"banDuration"`
+	// After DelayAfter failures, each further failure locks the client out for
+	// Delay, doubling with every failure. Zero disables the delays.
+	DelayAfter int    `koanf:"delayAfter"`
+	Delay      string `koanf:"delay"`
+	// TrackBy lists what failures are counted against: the client IP, and for
+	// basic auth the user name. Defaults to the client IP.
+	TrackBy []string `koanf:"trackBy"`
+}
+
+const (
+	defaultLockoutWindow      = 5 * time.Minute
+	defaultLockoutBanDuration = 15 * time.Minute
+	defaultLockoutDelay       = time.Second
+)
+
+// lockoutSettings is a validated LockoutConfig
+type lockoutSettings struct {
+	maxFailures int
+	window      time.Duration
+	banDuration time.Duration
+	delayAfter  int
+	delay       time.Duration
+	trackBy     []string
+}
+
+func (c LockoutConfig) settings() (lockoutSettings, error) {
+	s := lockoutSettings{maxFailures: c.MaxFailures, delayAfter: c.DelayAfter, trackBy: c.TrackBy}
+	if s.maxFailures <= 0 {
+		return s, fmt.Errorf("lockout maxFailures must be positive, got %d", c.MaxFailures)
+	}
+	if s.delayAfter < 0 || (s.delayAfter > 0 && s.delayAfter >= s.maxFailures) {
+		return s, fmt.Errorf("lockout delayAfter must be between 0 and maxFailures, got %d", c.DelayAfter)
+	}
+	var err error
+	if s.window, err = lockoutDuration("window", c.Window, defaultLockoutWindow); err != nil {
+		return s, err
+	}
+	if s.banDuration, err = lockoutDuration("banDuration", c.BanDuration, defaultLockoutBanDuration); err != nil {
+		return s, err
+	}
+	if s.delay, err = lockoutDuration("delay", c.Delay, defaultLockoutDelay); err != nil {
+		return s, err
+	}
+	if len(s.trackBy) == 0 {
+		s.trackBy = []string{TrackByIP}
+	}
+	for _, identity := range s.trackBy {
+		if identity != TrackByIP && identity != TrackByUser {
+			return s, fmt.Errorf("lockout trackBy must list '%s' or '%s', got: %s", TrackByIP, TrackByUser, identity)
+		}
+	}
+	return s, nil
+}
+
+func lockoutDuration(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("lockout %s %q must be a positive duration", name, value)
+	}
+	return d, nil
+}
+
+// LockoutStatus describes a client with recent authentication failures
+type LockoutStatus struct {
+	Policy string `json:"policy"`
+	// Client is the tracked identity, e.g. "ip:192.0.2.7" or "user:admin"
+	Client   string `json:"client"`
+	Failures int    `json:"failures"`
+	// LockedUntil is zero unless the client is currently locked out
+	LockedUntil time.Time `json:"lockedUntil,omitempty"`
+	Banned      bool      `json:"banned"`
+}
+
+type lockoutRecord struct {
+	failures    []time.Time
+	lockedUntil time.Time
+	banned      bool
+}
+
+// lockout tracks the authentication failures of one security policy
+type lockout struct {
+	policy   string
+	config   LockoutConfig
+	settings lockoutSettings
+	now      func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*lockoutRecord
+	lastSweep time.Time
+}
+
+var (
+	lockoutsMu sync.Mutex
+	lockouts   = make(map[string]*lockout)
+)
+
+// lockoutFor returns the lockout shared by every artifact secured by the
+// policy, or nil when the policy has none. A lockout is kept while the
+// policy's lockout configuration does not change.
+func lockoutFor(policy PolicyConfig) (*lockout, error) {
+	lockoutsMu.Lock()
+	defer lockoutsMu.Unlock()
+	if policy.Lockout == nil {
+		delete(lockouts, policy.Name)
+		return nil, nil
+	}
+	if existing, ok := lockouts[policy.Name]; ok && reflect.DeepEqual(existing.config, *policy.Lockout) {
+		return existing, nil
+	}
+	settings, err := policy.Lockout.settings()
+	if err != nil {
+		return nil, fmt.Errorf("security policy %s: %w", policy.Name, err)
+	}
+	l := &lockout{policy: policy.Name, config: *policy.Lockout, settings: settings, now: time.Now,
+		clients: make(map[string]*lockoutRecord)}
+	lockouts[policy.Name] = l
+	return l, nil
+}
+
+// identities returns the identities the request's failures are counted against
+func (l *lockout) identities(r *http.Request) []string {
+	var identities []string
+	for _, trackBy := range l.settings.trackBy {
+		switch trackBy {
+		case TrackByIP:
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			identities = append(identities, "ip:"+host)
+		case TrackByUser:
+			if user, _, ok := r.BasicAuth(); ok && user != "" {
+				identities = append(identities, "user:"+user)
+			}
+		}
+	}
+	return identities
+}
+
+// lockedOut returns how long the longest lockout of the identities lasts
+func (l *lockout) lockedOut(identities []string) (time.Duration, bool) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var remaining time.Duration
+	for _, identity := range identities {
+		if record, ok := l.clients[identity]; ok && now.Before(record.lockedUntil) {
+			remaining = max(remaining, record.lockedUntil.Sub(now))
+		}
+	}
+	return remaining, remaining > 0
+}
+
+// fail records a failed attempt, locking out identities that crossed a threshold
+func (l *lockout) fail(identities []string) {
+	now := l.now()
+	var banned []string
+	l.mu.Lock()
+	l.sweep(now)
+	for _, identity := range identities {
+		record, ok := l.clients[identity]
+		if !ok {
+			record = &lockoutRecord{}
+			l.clients[identity] = record
+		}
+		record.failures = append(slices.DeleteFunc(record.failures, func(failure time.Time) bool {
+			return now.Sub(failure) >= l.settings.window
+		}), now)
+		failures := len(record.failures)
+		switch {
+		case failures >= l.settings.maxFailures:
+			record.lockedUntil = now.Add(l.settings.banDuration)
+			record.banned = true
+			record.failures = nil
+			banned = append(banned, identity)
+		case l.settings.delayAfter > 0 && failures > l.settings.delayAfter:
+			delay := l.settings.delay
+			for i := l.settings.delayAfter + 1; i < failures && delay < l.settings.banDuration; i++ {
+				delay *= 2
+			}
+			record.lockedUntil = now.Add(min(delay, l.settings.banDuration))
+		}
+	}
+	l.mu.Unlock()
+
+	for _, identity := range banned {
+		events.Publish(events.Event{Type: events.ClientBanned, Kind: "policy", Name: l.policy, Time: now,
+			Attributes: map[string]string{"client": identity, "duration": l.settings.banDuration.String()}})
+	}
+}
+
+// succeed forgets the failures of the user that authenticated. Failures of
+// the client IP only expire with the window, so an attacker holding one valid
+// account cannot reset its count between guesses at others.
+func (l *lockout) succeed(identities []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, identity := range identities {
+		if strings.HasPrefix(identity, TrackByUser+":") {
+			delete(l.clients, identity)
+		}
+	}
+}
+
+// sweep forgets clients without recent failures or an active lockout, at most
+// once per window. Callers hold l.mu.
+func (l *lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.settings.window {
+		return
+	}
+	for identity, record := range l.clients {
+		recent := slices.ContainsFunc(record.failures, func(failure time.Time) bool {
+			return now.Sub(failure) < l.settings.window
+		})
+		if !recent && !now.Before(record.lockedUntil) {
+			delete(l.clients, identity)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *lockout) statuses() []LockoutStatus {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var statuses []LockoutStatus
+	for identity, record := range l.clients {
+		status := LockoutStatus{Policy: l.policy, Client: identity}
+		for _, failure := range record.failures {
+			if now.Sub(failure) < l.settings.window {
+				status.Failures++
+			}
+		}
+		if now.Before(record.lockedUntil) {
+			status.LockedUntil = record.lockedUntil
+			status.Banned = record.banned
+		}
+		if status.Failures > 0 || !status.LockedUntil.IsZero() {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// Lockouts returns the clients with recent authentication failures across
+// every security policy, ordered by policy and client
+func Lockouts() []LockoutStatus {
+	lockoutsMu.Lock()
+	current := make([]*lockout, 0, len(lockouts))
+	for _, l := range lockouts {
+		current = append(current, l)
+	}
+	lockoutsMu.Unlock()
+
+	statuses := []LockoutStatus{}
+	for _, l := range current {
+		statuses = append(statuses, l.statuses()...)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Policy != statuses[j].Policy {
+			return statuses[i].Policy < statuses[j].Policy
+		}
+		return statuses[i].Client < statuses[j].Client
+	})
+	return statuses
+}
+
+// ReleaseLockout lifts the lockout of a client under a policy and forgets its
+// failures. It reports whether the client was known.
+func ReleaseLockout(policy, client string) bool {
+	lockoutsMu.Lock()
+	l, ok := lockouts[policy]
+	lockoutsMu.Unlock()
+	if !ok {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.clients[client]; !ok {
+		return false
+	}
+	delete(l.clients, client)
+	return true
+}
@@ -0,0 +1,155 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticate_Lockout(t *testing.T) {
+	policy := PolicyConfig{Name: "lockout-test", Type: "basic", Users: map[string]string{"admin": "secret"},
+		Lockout: &LockoutConfig{MaxFailures: 5, Window: "1m", BanDuration: "10m", DelayAfter: 2, Delay: "1s"}}
+	handler, err := Authenticate(policy, okHandler())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		lockoutsMu.Lock()
+		delete(lockouts, policy.Name)
+		lockoutsMu.Unlock()
+	})
+	guard := lockouts[policy.Name]
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard.now = func() time.Time { return now }
+	banned := events.Default().Subscribe(events.ClientBanned)
+	defer banned.Close()
+
+	attempt := func(remoteAddr, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.RemoteAddr = remoteAddr
+		req.SetBasicAuth("admin", password)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	const attacker = "192.0.2.7:4711"
+
+	// Two failures are free, the third locks the client out for the delay
+	assert.Equal(t, http.StatusUnauthorized, attempt(attacker, "guess-1").Code)
+	assert.Equal(t, http.StatusUnauthorized, attempt(attacker, "guess-2").Code)
+	assert.Equal(t, http.StatusUnauthorized, attempt(attacker, "guess-3").Code)
+	rec := attempt(attacker, "secret")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "credentials are not checked while locked out")
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, attempt("198.51.100.1:80", "secret").Code, "other clients are not affected")
+
+	// Each further failure doubles the delay
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusUnauthorized, attempt(attacker, "guess-4").Code)
+	assert.Equal(t, "2", attempt(attacker, "guess-5").Header().Get("Retry-After"))
+	assert.Equal(t, []LockoutStatus{{Policy: policy.Name, Client: "ip:192.0.2.7", Failures: 4, LockedUntil: now.Add(2 * time.Second)}},
+		Lockouts())
+
+	// The fifth failure within the window bans the client
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, http.StatusUnauthorized, attempt(attacker, "guess-6").Code)
+	assert.Equal(t, "600", attempt(attacker, "secret").Header().Get("Retry-After"))
+	select {
+	case event := <-banned.C:
+		assert.Equal(t, policy.Name, event.Name)
+		assert.Equal(t, "ip:192.0.2.7", event.Attributes["client"])
+	default:
+		t.Fatal("the ban was not published")
+	}
+	statuses := Lockouts()
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Banned)
+
+	// Releasing the ban clears the failures
+	assert.True(t, ReleaseLockout(policy.Name, "ip:192.0.2.7"))
+	assert.False(t, ReleaseLockout(policy.Name, "ip:192.0.2.7"))
+	assert.Equal(t, http.StatusOK, attempt(attacker, "secret").Code)
+	assert.Empty(t, Lockouts())
+}
+
+func TestAuthenticate_LockoutSuccessKeepsIPFailures(t *testing.T) {
+	policy := PolicyConfig{Name: "lockout-success-test", Type: "basic",
+		Users:   map[string]string{"admin": "secret", "mallory": "own-password"},
+		Lockout: &LockoutConfig{MaxFailures: 3, Window: "1m", BanDuration: "10m", TrackBy: []string{TrackByIP, TrackByUser}}}
+	handler, err := Authenticate(policy, okHandler())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		lockoutsMu.Lock()
+		delete(lockouts, policy.Name)
+		lockoutsMu.Unlock()
+	})
+
+	attempt := func(user, password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.RemoteAddr = "192.0.2.7:4711"
+		req.SetBasicAuth(user, password)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Logging in with an account of its own between guesses does not reset
+	// the failures of the client IP
+	assert.Equal(t, http.StatusUnauthorized, attempt("admin", "guess-1"))
+	assert.Equal(t, http.StatusUnauthorized, attempt("admin", "guess-2"))
+	assert.Equal(t, http.StatusOK, attempt("mallory", "own-password"))
+	assert.Equal(t, http.StatusUnauthorized, attempt("admin", "guess-3"))
+	assert.Equal(t, http.StatusTooManyRequests, attempt("mallory", "own-password"), "the client IP is banned")
+
+	statuses := Lockouts()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "ip:192.0.2.7", statuses[0].Client)
+	assert.True(t, statuses[0].Banned)
+	assert.Equal(t, "user:admin", statuses[1].Client)
+	assert.True(t, statuses[1].Banned)
+}
+
+func TestLockoutConfig_Validate(t *testing.T) {
+	tests := []struct {
+		lockout LockoutConfig
+		wantErr string
+	}{
+		{lockout: LockoutConfig{MaxFailures: 5}},
+		{lockout: LockoutConfig{MaxFailures: 5, DelayAfter: 3, Delay: "500ms", TrackBy: []string{"ip", "user"}}},
+		{lockout: LockoutConfig{}, wantErr: "security policy p: lockout maxFailures must be positive, got 0"},
+		{lockout: LockoutConfig{MaxFailures: 5, DelayAfter: 5}, wantErr: "security policy p: lockout delayAfter must be between 0 and maxFailures, got 5"},
+		{lockout: LockoutConfig{MaxFailures: 5, Window: "forever"}, wantErr: `security policy p: lockout window "forever" must be a positive duration`},
+		{lockout: LockoutConfig{MaxFailures: 5, TrackBy: []string{"key"}}, wantErr: "security policy p: lockout trackBy must list 'ip' or 'user', got: key"},
+	}
+	for _, tt := range tests {
+		config := SecurityConfig{Policies: []PolicyConfig{{Name: "p", Type: "apikey", Keys: map[string]string{"k": "c"}, Lockout: &tt.lockout}}}
+		err := config.Validate()
+		if tt.wantErr == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.wantErr)
+		}
+	}
+}
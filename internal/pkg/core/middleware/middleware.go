@@ -0,0 +1,140 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package middleware provides the HTTP handler wrappers shared by every HTTP entry
+// point of the runtime (APIs served by the router and HTTP inbound endpoints), so
+// CORS, CSRF, security policies and external authorization are enforced
+// identically regardless of how a request enters the gateway.
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync/atomic"
+)
+
+// Options selects the middleware applied to a single artifact
+type Options struct {
+	// CORS is "true" or "false" to override the global [cors] setting, or empty
+	// to inherit it
+	CORS string
+	// SecurityPolicy names a [[security.policy]] entry; empty means unsecured
+	SecurityPolicy string
+	// Authorization names an [[authorization.service]] that decides whether
+	// authenticated requests may proceed
+	Authorization string
+	// CSRF enables double-submit token checks for browser-facing artifacts
+	CSRF bool
+}
+
+// Wrap applies the middleware selected by opts around next. deploymentConfig is
+// the parsed deployment.toml held by the config context; it may be nil. With
+// relaxed security, the policies and authorization services named by opts
+// are still looked up but not enforced, and CSRF tokens are not checked.
+func Wrap(next http.Handler, deploymentConfig map[string]interface{}, opts Options) (http.Handler, error) {
+	handler := next
+	relaxed := relaxedSecurity.Load()
+
+	// Authorization runs after authentication so the service sees the principal
+	if opts.Authorization != "" {
+		authorizationConfig, _ := deploymentConfig["authorization"].(AuthorizationConfig)
+		service, err := authorizationConfig.Service(opts.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		if !relaxed {
+			if handler, err = ExternalAuthorize(service, handler); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.SecurityPolicy != "" {
+		securityConfig, _ := deploymentConfig["security"].(SecurityConfig)
+		policy, err := securityConfig.Policy(opts.SecurityPolicy)
+		if err != nil {
+			return nil, err
+		}
+		if !relaxed {
+			if handler, err = Authenticate(policy, handler); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	corsConfig, _ := deploymentConfig["cors"].(CORSConfig)
+	var csrfHeader string
+	if opts.CSRF && !relaxed {
+		csrfConfig, _ := deploymentConfig["csrf"].(CSRFConfig)
+		handler = CSRF(csrfConfig, corsConfig, handler)
+		csrfHeader = csrfConfig.headerName()
+	}
+
+	var corsOverride *bool
+	if opts.CORS != "" {
+		enabled, err := strconv.ParseBool(opts.CORS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cors value: must be true/false, got '%s'", opts.CORS)
+		}
+		corsOverride = &enabled
+	}
+	// CORS runs first so preflight requests are answered without credentials.
+	// The [cors] defaults are resolved per request as they can be reloaded.
+	inner := handler
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := corsConfig
+		if reloaded := reloadedCORS.Load(); reloaded != nil {
+			config = *reloaded
+		}
+		enabled := config.Enabled
+		if corsOverride != nil {
+			enabled = *corsOverride
+		}
+		if !enabled {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		// Cross-origin callers must be allowed to send the token header
+		if csrfHeader != "" && len(config.AllowHeaders) > 0 && !slices.Contains(config.AllowHeaders, csrfHeader) {
+			config.AllowHeaders = append(slices.Clone(config.AllowHeaders), csrfHeader)
+		}
+		CORS(config, inner).ServeHTTP(w, r)
+	}), nil
+}
+
+// relaxedSecurity is switched on by profiles such as dev
+var relaxedSecurity atomic.Bool
+
+// RelaxSecurity stops enforcing security policies, authorization services and
+// CSRF checks in the handlers wrapped afterwards, so artifacts can be called
+// without credentials during development
+func RelaxSecurity(relaxed bool) {
+	relaxedSecurity.Store(relaxed)
+}
+
+// reloadedCORS replaces the [cors] section of deployment.toml once it is reloaded
+var reloadedCORS atomic.Pointer[CORSConfig]
+
+// ReloadCORS replaces the [cors] defaults of every wrapped handler, including
+// those wrapped before the reload
+func ReloadCORS(config CORSConfig) {
+	reloadedCORS.Store(&config)
+}
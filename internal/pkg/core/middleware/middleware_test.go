@@ -0,0 +1,311 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/ldap"
+	"github.com/stretchr/testify/assert"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := Principal(r)
+		w.Write([]byte(principal))
+	})
+}
+
+func TestWrap_CORSPreflight(t *testing.T) {
+	deploymentConfig := map[string]interface{}{
+		"cors": CORSConfig{
+			Enabled:      true,
+			AllowOrigins: []string{"https://app.example.com"},
+			AllowHeaders: []string{"Authorization"},
+			MaxAge:       600,
+		},
+	}
+	handler, err := Wrap(okHandler(), deploymentConfig, Options{})
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodOptions, "/orders", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Authorization", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	req = httptest.NewRequest(http.MethodOptions, "/orders", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Artifacts can opt out of the global CORS configuration
+	handler, err = Wrap(okHandler(), deploymentConfig, Options{CORS: "false"})
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestReloadCORS(t *testing.T) {
+	t.Cleanup(func() { reloadedCORS.Store(nil) })
+	handler, err := Wrap(okHandler(), nil, Options{CSRF: true})
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+	preflight := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/orders", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	assert.Empty(t, preflight().Header().Get("Access-Control-Allow-Origin"), "CORS is disabled at startup")
+
+	// Handlers wrapped before the reload pick up the new defaults
+	ReloadCORS(CORSConfig{Enabled: true, AllowOrigins: []string{"https://app.example.com"}, AllowHeaders: []string{"Authorization"}})
+	rec := preflight()
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Authorization, X-XSRF-TOKEN", rec.Header().Get("Access-Control-Allow-Headers"))
+}
+
+func TestWrap_RelaxedSecurity(t *testing.T) {
+	RelaxSecurity(true)
+	t.Cleanup(func() { RelaxSecurity(false) })
+	deploymentConfig := map[string]interface{}{
+		"security": SecurityConfig{Policies: []PolicyConfig{
+			{Name: "partners", Type: "apikey", Keys: map[string]string{"k-123": "acme"}},
+		}},
+	}
+
+	handler, err := Wrap(okHandler(), deploymentConfig, Options{SecurityPolicy: "partners", CSRF: true})
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "requests without credentials or CSRF token are served")
+
+	// Unknown policies are still reported so the artifact deploys the same way
+	// without relaxed security
+	_, err = Wrap(okHandler(), deploymentConfig, Options{SecurityPolicy: "missing"})
+	assert.Error(t, err)
+}
+
+func TestWrap_SecurityPolicies(t *testing.T) {
+	deploymentConfig := map[string]interface{}{
+		"security": SecurityConfig{Policies: []PolicyConfig{
+			{Name: "partners", Type: "apikey", Keys: map[string]string{"k-123": "acme"}},
+			{Name: "internal", Type: "basic", Users: map[string]string{"admin": "secret"}},
+		}},
+	}
+
+	testCases := []struct {
+		name           string
+		policy         string
+		prepare        func(r *http.Request)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Valid API key",
+			policy:         "partners",
+			prepare:        func(r *http.Request) { r.Header.Set("X-API-Key", "k-123") },
+			expectedStatus: http.StatusOK,
+			expectedBody:   "acme",
+		},
+		{
+			name:           "Invalid API key",
+			policy:         "partners",
+			prepare:        func(r *http.Request) { r.Header.Set("X-API-Key", "wrong") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Valid basic credentials",
+			policy:         "internal",
+			prepare:        func(r *http.Request) { r.SetBasicAuth("admin", "secret") },
+			expectedStatus: http.StatusOK,
+			expectedBody:   "admin",
+		},
+		{
+			name:           "Missing basic credentials",
+			policy:         "internal",
+			prepare:        func(r *http.Request) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, err := Wrap(okHandler(), deploymentConfig, Options{SecurityPolicy: tc.policy})
+			if err != nil {
+				t.Fatalf("Wrap() error = %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			tc.prepare(req)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedBody != "" {
+				assert.Equal(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	_, err := Wrap(okHandler(), deploymentConfig, Options{SecurityPolicy: "missing"})
+	assert.EqualError(t, err, "security policy not found: missing")
+}
+
+func TestAuthenticate_LDAP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing listens on the directory's address
+	listener.Close()
+	policy := PolicyConfig{
+		Name:  "staff",
+		Type:  "basic",
+		Users: map[string]string{"admin": "secret"},
+		LDAP: &ldap.Config{
+			URL:          "ldap://" + listener.Addr().String(),
+			BindDN:       "cn=service,dc=example,dc=com",
+			BindPassword: "service-secret",
+			BaseDN:       "dc=example,dc=com",
+			Timeout:      "200ms",
+		},
+	}
+	assert.NoError(t, SecurityConfig{Policies: []PolicyConfig{policy}}.Validate())
+	handler, err := Authenticate(policy, okHandler())
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	serve := func(user, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.SetBasicAuth(user, password)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	// Configured users are checked without the directory
+	assert.Equal(t, http.StatusOK, serve("admin", "secret").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("admin", "wrong").Code)
+	// Other users are checked against the directory, which is down
+	assert.Equal(t, http.StatusServiceUnavailable, serve("jdoe", "secret").Code)
+
+	policy.LDAP.BaseDN = ""
+	assert.ErrorContains(t, SecurityConfig{Policies: []PolicyConfig{policy}}.Validate(), "security policy staff: ldap: baseDN is required")
+	policy.Users, policy.LDAP = nil, nil
+	assert.ErrorContains(t, SecurityConfig{Policies: []PolicyConfig{policy}}.Validate(), "requires at least one user or an ldap directory")
+}
+
+func TestWrap_CSRF(t *testing.T) {
+	deploymentConfig := map[string]interface{}{
+		"cors": CORSConfig{
+			Enabled:      true,
+			AllowOrigins: []string{"https://app.example.com"},
+			AllowHeaders: []string{"Content-Type"},
+		},
+	}
+	handler, err := Wrap(okHandler(), deploymentConfig, Options{CSRF: true})
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+
+	// Safe requests are issued a token
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, DefaultCSRFCookieName, cookies[0].Name)
+		assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	}
+	token := &http.Cookie{Name: DefaultCSRFCookieName, Value: "token-value"}
+
+	// The CORS layer lets cross-origin callers send the token header
+	req := httptest.NewRequest(http.MethodOptions, "/orders", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "Content-Type, "+DefaultCSRFHeaderName, rec.Header().Get("Access-Control-Allow-Headers"))
+
+	testCases := []struct {
+		name     string
+		origin   string
+		header   string
+		cookie   *http.Cookie
+		expected int
+	}{
+		{name: "Matching token", header: "token-value", cookie: token, expected: http.StatusOK},
+		{name: "Matching token from CORS origin", origin: "https://app.example.com", header: "token-value", cookie: token, expected: http.StatusOK},
+		{name: "Same origin", origin: "http://example.com", header: "token-value", cookie: token, expected: http.StatusOK},
+		{name: "Untrusted origin", origin: "https://evil.example.com", header: "token-value", cookie: token, expected: http.StatusForbidden},
+		{name: "Missing header", cookie: token, expected: http.StatusForbidden},
+		{name: "Missing cookie", header: "token-value", expected: http.StatusForbidden},
+		{name: "Mismatched token", header: "other-value", cookie: token, expected: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.header != "" {
+				req.Header.Set(DefaultCSRFHeaderName, tc.header)
+			}
+			if tc.cookie != nil {
+				req.AddCookie(tc.cookie)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expected, rec.Code)
+		})
+	}
+}
+
+func TestCSRFConfig_Validate(t *testing.T) {
+	assert.NoError(t, CSRFConfig{SameSite: "Lax"}.Validate())
+	assert.NoError(t, CSRFConfig{SameSite: "none", Secure: true}.Validate())
+	assert.EqualError(t, CSRFConfig{SameSite: "none"}.Validate(), "csrf: sameSite 'none' requires secure = true")
+	assert.Error(t, CSRFConfig{SameSite: "sometimes"}.Validate())
+}
@@ -0,0 +1,211 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package mtom decodes and encodes SOAP messages optimized with MTOM, where
+// binary content travels as MIME parts of a multipart/related message and the
+// SOAP envelope refers to them with XOP include elements.
+package mtom
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// XOPContentType is the media type of the root part of an MTOM message
+	XOPContentType = "application/xop+xml"
+	// XOPNamespace is the namespace of the xop:Include element
+	XOPNamespace = "http://www.w3.org/2004/08/xop/include"
+
+	rootContentID = "root.message@synapse.apache.org"
+	// defaultSOAPContentType is used when the root part does not name the
+	// SOAP content type it carries
+	defaultSOAPContentType = "text/xml"
+)
+
+// Message is a decoded MTOM message
+type Message struct {
+	// Payload is the SOAP envelope with its xop:Include elements
+	Payload []byte
+	// ContentType is the content type of the SOAP envelope, e.g. text/xml
+	ContentType string
+	Attachments []synctx.Attachment
+}
+
+// IsMTOM reports whether the content type is an XOP package
+func IsMTOM(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "multipart/related" && strings.EqualFold(params["type"], XOPContentType)
+}
+
+// Decode splits an MTOM message into its SOAP envelope and attachments. The
+// root part is the one named by the start parameter, or the first part.
+func Decode(contentType string, body io.Reader) (Message, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/related" {
+		return Message{}, fmt.Errorf("not an MTOM message: %q", contentType)
+	}
+	if params["boundary"] == "" {
+		return Message{}, fmt.Errorf("MTOM message has no boundary")
+	}
+	start := strings.Trim(params["start"], "<>")
+
+	var message Message
+	rootFound := false
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Message{}, fmt.Errorf("cannot read MTOM part: %w", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return Message{}, fmt.Errorf("cannot read MTOM part: %w", err)
+		}
+		contentID := strings.Trim(part.Header.Get("Content-ID"), "<>")
+		if !rootFound && (start == "" || contentID == start) {
+			rootFound = true
+			message.Payload = data
+			message.ContentType = soapContentType(part.Header.Get("Content-Type"), params["start-info"])
+			continue
+		}
+		message.Attachments = append(message.Attachments, synctx.Attachment{
+			ContentID:   contentID,
+			ContentType: part.Header.Get("Content-Type"),
+			Data:        data,
+		})
+	}
+	if !rootFound {
+		return Message{}, fmt.Errorf("MTOM message has no root part")
+	}
+	return message, nil
+}
+
+// soapContentType derives the content type of the envelope from the type
+// parameter of the root part, falling back to the package's start-info
+func soapContentType(rootContentType, startInfo string) string {
+	if _, params, err := mime.ParseMediaType(rootContentType); err == nil && params["type"] != "" {
+		return params["type"]
+	}
+	if startInfo != "" {
+		return startInfo
+	}
+	return defaultSOAPContentType
+}
+
+// References returns the content IDs of the attachments the payload refers to
+// with xop:Include elements, in document order
+func References(payload []byte) []string {
+	var references []string
+	decoder := xml.NewDecoder(bytes.NewReader(payload))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return references
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Space != XOPNamespace || element.Name.Local != "Include" {
+			continue
+		}
+		for _, attr := range element.Attr {
+			if attr.Name.Local != "href" || !strings.HasPrefix(attr.Value, "cid:") {
+				continue
+			}
+			contentID, err := url.PathUnescape(strings.TrimPrefix(attr.Value, "cid:"))
+			if err == nil {
+				references = append(references, contentID)
+			}
+		}
+	}
+}
+
+// Encode packages the payload and the attachments it references as an MTOM
+// message and returns the body with its content type. Attachments the payload
+// does not refer to are left out.
+func Encode(payload []byte, contentType string, attachments []synctx.Attachment) ([]byte, string, error) {
+	if contentType == "" {
+		contentType = defaultSOAPContentType
+	}
+	soapType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid SOAP content type %q: %w", contentType, err)
+	}
+	byID := make(map[string]synctx.Attachment, len(attachments))
+	for _, attachment := range attachments {
+		byID[attachment.ContentID] = attachment
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	root, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {fmt.Sprintf("%s; charset=UTF-8; type=%q", XOPContentType, soapType)},
+		"Content-Transfer-Encoding": {"binary"},
+		"Content-ID":                {"<" + rootContentID + ">"},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	root.Write(payload)
+	written := make(map[string]bool)
+	for _, contentID := range References(payload) {
+		attachment, ok := byID[contentID]
+		if !ok {
+			return nil, "", fmt.Errorf("payload refers to unknown attachment %q", contentID)
+		}
+		if written[contentID] {
+			continue
+		}
+		written[contentID] = true
+		attachmentType := attachment.ContentType
+		if attachmentType == "" {
+			attachmentType = "application/octet-stream"
+		}
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachmentType},
+			"Content-Transfer-Encoding": {"binary"},
+			"Content-ID":                {"<" + contentID + ">"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		part.Write(attachment.Data)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	packageType := mime.FormatMediaType("multipart/related", map[string]string{
+		"type":       XOPContentType,
+		"boundary":   writer.Boundary(),
+		"start":      "<" + rootContentID + ">",
+		"start-info": soapType,
+	})
+	return body.Bytes(), packageType, nil
+}
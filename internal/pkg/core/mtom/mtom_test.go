@@ -0,0 +1,85 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package mtom
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const envelope = `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">` +
+	`<soap:Body><upload><file><xop:Include xmlns:xop="http://www.w3.org/2004/08/xop/include" href="cid:scan%40example.com"/></file></upload></soap:Body>` +
+	`</soap:Envelope>`
+
+func TestEncodeDecode(t *testing.T) {
+	scan := synctx.Attachment{ContentID: "scan@example.com", ContentType: "image/png", Data: []byte{0x89, 'P', 'N', 'G', 0, 1, 2}}
+	unused := synctx.Attachment{ContentID: "unused@example.com", Data: []byte("not referenced")}
+
+	body, contentType, err := Encode([]byte(envelope), "application/soap+xml; charset=UTF-8", []synctx.Attachment{scan, unused})
+	require.NoError(t, err)
+	assert.True(t, IsMTOM(contentType))
+	_, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	assert.Equal(t, "application/soap+xml", params["start-info"])
+	assert.NotContains(t, string(body), "not referenced")
+
+	message, err := Decode(contentType, bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, envelope, string(message.Payload))
+	assert.Equal(t, "application/soap+xml", message.ContentType)
+	assert.Equal(t, []synctx.Attachment{scan}, message.Attachments)
+}
+
+func TestDecode_RootPart(t *testing.T) {
+	body := "--b\r\n" +
+		"Content-Type: image/png\r\nContent-ID: <scan@example.com>\r\n\r\nPNG\r\n" +
+		"--b\r\n" +
+		"Content-Type: application/xop+xml; type=\"text/xml\"\r\nContent-ID: <root@example.com>\r\n\r\n" + envelope + "\r\n" +
+		"--b--\r\n"
+	contentType := `multipart/related; type="application/xop+xml"; boundary=b; start="<root@example.com>"`
+
+	message, err := Decode(contentType, strings.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, envelope, string(message.Payload), "the start parameter selects the root part")
+	assert.Equal(t, "text/xml", message.ContentType)
+	require.Len(t, message.Attachments, 1)
+	assert.Equal(t, "PNG", string(message.Attachments[0].Data))
+
+	_, err = Decode(`multipart/related; type="application/xop+xml"; boundary=b; start="<missing>"`, strings.NewReader(body))
+	assert.EqualError(t, err, "MTOM message has no root part")
+}
+
+func TestIsMTOM(t *testing.T) {
+	assert.True(t, IsMTOM(`multipart/related; type="application/xop+xml"; boundary=b`))
+	assert.False(t, IsMTOM(`multipart/related; type="text/xml"; boundary=b`))
+	assert.False(t, IsMTOM("text/xml"))
+}
+
+func TestEncode_UnknownAttachment(t *testing.T) {
+	_, _, err := Encode([]byte(envelope), "text/xml", nil)
+	assert.EqualError(t, err, `payload refers to unknown attachment "scan@example.com"`)
+	assert.Equal(t, []string{"scan@example.com"}, References([]byte(envelope)))
+}
@@ -0,0 +1,86 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package ordering serializes the mediation of messages that share a key,
+// such as an account ID, while messages with different keys are mediated in
+// parallel. Backends that require per-entity ordering can then be fed from
+// concurrent inbound sources.
+package ordering
+
+import (
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+)
+
+// KeyProperty holds the ordering key of a message. Messages without one are
+// not ordered.
+const KeyProperty = "ORDERING_KEY"
+
+// Sequencer runs the functions queued under a key one at a time, in the order
+// they were queued. Each key with queued work has its own goroutine, which
+// exits once the queue is empty.
+type Sequencer struct {
+	subsystem string
+
+	mu     sync.Mutex
+	queues map[string][]func()
+}
+
+// NewSequencer creates a sequencer whose goroutines are tracked under the
+// subsystem
+func NewSequencer(subsystem string) *Sequencer {
+	return &Sequencer{subsystem: subsystem, queues: make(map[string][]func())}
+}
+
+// Go runs f once the functions queued before it under the same key have
+// returned. It does not wait for f to run.
+func (s *Sequencer) Go(key string, f func()) {
+	s.mu.Lock()
+	queue, running := s.queues[key]
+	s.queues[key] = append(queue, f)
+	s.mu.Unlock()
+	if !running {
+		leaks.Default().Go(s.subsystem, func() { s.drain(key) })
+	}
+}
+
+func (s *Sequencer) drain(key string) {
+	for {
+		s.mu.Lock()
+		queue := s.queues[key]
+		if len(queue) == 0 {
+			delete(s.queues, key)
+			s.mu.Unlock()
+			return
+		}
+		f := queue[0]
+		queue[0] = nil
+		s.queues[key] = queue[1:]
+		s.mu.Unlock()
+		f()
+	}
+}
+
+// Keys returns the number of keys with queued or running work
+func (s *Sequencer) Keys() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queues)
+}
@@ -0,0 +1,76 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package ordering
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequencer_OrdersPerKey(t *testing.T) {
+	sequencer := NewSequencer("test")
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	var wg sync.WaitGroup
+	for i := range 100 {
+		for _, key := range []string{"acct-1", "acct-2", "acct-3"} {
+			wg.Add(1)
+			sequencer.Go(key, func() {
+				defer wg.Done()
+				mu.Lock()
+				seen[key] = append(seen[key], i)
+				mu.Unlock()
+			})
+		}
+	}
+	wg.Wait()
+
+	for key, order := range seen {
+		assert.Len(t, order, 100, key)
+		assert.IsIncreasing(t, order, "%s was mediated out of order", key)
+	}
+	assert.Eventually(t, func() bool { return sequencer.Keys() == 0 }, time.Second, time.Millisecond,
+		"idle keys are forgotten")
+}
+
+func TestSequencer_ParallelAcrossKeys(t *testing.T) {
+	sequencer := NewSequencer("test")
+	release := make(chan struct{})
+	done := make(chan string, 2)
+	sequencer.Go("blocked", func() {
+		<-release
+		done <- "blocked"
+	})
+	sequencer.Go("blocked", func() { done <- "queued" })
+	sequencer.Go("other", func() { done <- "other" })
+
+	select {
+	case key := <-done:
+		assert.Equal(t, "other", key, "a blocked key does not hold up the others")
+	case <-time.After(5 * time.Second):
+		t.Fatal("other key was not run")
+	}
+	close(release)
+	assert.Equal(t, "blocked", <-done)
+	assert.Equal(t, "queued", <-done)
+}
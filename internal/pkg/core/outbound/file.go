@@ -0,0 +1,242 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Options of <file> endpoint blocks, e.g.
+// <file uri="file:///var/spool/orders" name="order-{{.Property "orderId"}}-{{now "20060102T150405"}}.json" mode="create"/>
+const (
+	FileURIOption  = "uri"
+	FileNameOption = "name"
+	FileModeOption = "mode"
+)
+
+// Write modes of file endpoints
+const (
+	// FileModeCreate writes a new file and fails when the name is taken
+	FileModeCreate = "create"
+	// FileModeOverwrite replaces the file when it exists
+	FileModeOverwrite = "overwrite"
+	// FileModeAppend adds the payload to the end of the file
+	FileModeAppend = "append"
+)
+
+// FileWrittenProperty holds the location of the file a message was written to
+const FileWrittenProperty = "FILE_WRITTEN_PATH"
+
+// FileSystem stores files for one URI scheme
+type FileSystem interface {
+	// Write stores data as name below the directory of target and returns
+	// the location of the file
+	Write(ctx context.Context, target *url.URL, name string, data []byte, mode string) (string, error)
+}
+
+var (
+	fileSystemsMu sync.RWMutex
+	fileSystems   = map[string]FileSystem{
+		"file": LocalFileSystem{},
+	}
+)
+
+// RegisterFileSystem makes file endpoints with URIs of the scheme, such as
+// sftp or s3, write through fileSystem
+func RegisterFileSystem(scheme string, fileSystem FileSystem) {
+	fileSystemsMu.Lock()
+	defer fileSystemsMu.Unlock()
+	fileSystems[scheme] = fileSystem
+}
+
+// FileSystemFor returns the file system of a URI scheme
+func FileSystemFor(scheme string) (FileSystem, error) {
+	fileSystemsMu.RLock()
+	defer fileSystemsMu.RUnlock()
+	fileSystem, ok := fileSystems[scheme]
+	if !ok {
+		schemes := make([]string, 0, len(fileSystems))
+		for name := range fileSystems {
+			schemes = append(schemes, name)
+		}
+		sort.Strings(schemes)
+		return nil, fmt.Errorf("no file system for scheme %s, supported schemes are %s", scheme, strings.Join(schemes, ", "))
+	}
+	return fileSystem, nil
+}
+
+// FileSender writes the payload of each message to a file. Files are
+// written to a temporary name and renamed, so readers never see a partial
+// file; appends are written in place.
+type FileSender struct{}
+
+func NewFileSender() *FileSender {
+	return &FileSender{}
+}
+
+func (s *FileSender) Options() []string {
+	return []string{FileURIOption, FileNameOption, FileModeOption}
+}
+
+func (s *FileSender) Validate(endpoint artifacts.Endpoint) error {
+	if !endpoint.HasOption(FileURIOption) || !endpoint.HasOption(FileNameOption) {
+		return fmt.Errorf("file endpoint %s requires a uri and a name", endpoint.Name)
+	}
+	// Options that do not depend on the message are checked when deployed
+	msg := synctx.CreateMsgContext()
+	if mode, err := endpoint.Option(FileModeOption, msg); err == nil && mode != "" {
+		if err := checkFileMode(mode); err != nil {
+			return fmt.Errorf("file endpoint %s: %w", endpoint.Name, err)
+		}
+	}
+	if uri, err := endpoint.Option(FileURIOption, msg); err == nil && uri != "" {
+		if _, _, err := fileTarget(uri); err != nil {
+			return fmt.Errorf("file endpoint %s: %w", endpoint.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *FileSender) Send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+	uri, err := endpoint.Option(FileURIOption, msg)
+	if err != nil {
+		return err
+	}
+	name, err := endpoint.Option(FileNameOption, msg)
+	if err != nil {
+		return err
+	}
+	mode, err := endpoint.Option(FileModeOption, msg)
+	if err != nil {
+		return err
+	}
+	if mode == "" {
+		mode = FileModeCreate
+	}
+	if err := checkFileMode(mode); err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("endpoint %s: file name '%s' must stay within the target directory", endpoint.Name, name)
+	}
+	fileSystem, target, err := fileTarget(uri)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	location, err := fileSystem.Write(ctx, target, name, msg.Message.RawPayload, mode)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	msg.Properties[FileWrittenProperty] = location
+	return nil
+}
+
+func checkFileMode(mode string) error {
+	switch mode {
+	case FileModeCreate, FileModeOverwrite, FileModeAppend:
+		return nil
+	}
+	return fmt.Errorf("mode must be create, overwrite or append, got '%s'", mode)
+}
+
+// fileTarget parses the directory URI of a file endpoint. Plain paths are
+// local directories.
+func fileTarget(uri string) (FileSystem, *url.URL, error) {
+	target, err := url.Parse(uri)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid uri '%s'", uri)
+	}
+	if target.Scheme == "" {
+		target = &url.URL{Scheme: "file", Path: uri}
+	}
+	fileSystem, err := FileSystemFor(target.Scheme)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fileSystem, target, nil
+}
+
+// LocalFileSystem writes to directories of the local file system
+type LocalFileSystem struct{}
+
+func (LocalFileSystem) Write(ctx context.Context, target *url.URL, name string, data []byte, mode string) (string, error) {
+	if target.Host != "" && target.Host != "localhost" {
+		return "", fmt.Errorf("file URIs must not name a remote host, got '%s'", target.Host)
+	}
+	path := filepath.Join(filepath.FromSlash(target.Path), name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if mode == FileModeAppend {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			return "", err
+		}
+		return path, file.Close()
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return "", err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return "", err
+	}
+	if err := temp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if mode == FileModeOverwrite {
+		return path, os.Rename(temp.Name(), path)
+	}
+	// A link fails when the name is taken, so concurrent writers cannot
+	// replace each other's files
+	if err := os.Link(temp.Name(), path); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return "", fmt.Errorf("file %s already exists", path)
+		}
+		return "", err
+	}
+	return path, nil
+}
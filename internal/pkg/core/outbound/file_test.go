@@ -0,0 +1,117 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fileEndpoint(options map[string]string) artifacts.Endpoint {
+	ep := endpoint(artifacts.ProtocolFile, nil)
+	for name, value := range options {
+		ep.Options[name] = templateOption(name, value)
+	}
+	return ep
+}
+
+func TestFileSender_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]string
+		wantErr string
+	}{
+		{name: "local", options: map[string]string{FileURIOption: "file:///var/out", FileNameOption: "a.json"}},
+		{name: "plain path", options: map[string]string{FileURIOption: "/var/out", FileNameOption: "a.json", FileModeOption: "append"}},
+		{name: "templated mode", options: map[string]string{FileURIOption: "/var/out", FileNameOption: "a", FileModeOption: `{{.Property "mode"}}`}},
+		{name: "no name", options: map[string]string{FileURIOption: "/var/out"}, wantErr: "requires a uri and a name"},
+		{name: "bad mode", options: map[string]string{FileURIOption: "/var/out", FileNameOption: "a", FileModeOption: "truncate"},
+			wantErr: "mode must be create, overwrite or append"},
+		{name: "unsupported scheme", options: map[string]string{FileURIOption: "sftp://host/out", FileNameOption: "a"},
+			wantErr: "no file system for scheme sftp, supported schemes are file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(fileEndpoint(tt.options))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestFileSender_Send(t *testing.T) {
+	dir := t.TempDir()
+	msg := synctx.CreateMsgContext()
+	msg.Properties["orderId"] = "42"
+	msg.Message.RawPayload = []byte(`{"id":42}`)
+
+	create := fileEndpoint(map[string]string{FileURIOption: "file://" + dir, FileNameOption: `orders/{{.Property "orderId"}}.json`})
+	require.NoError(t, Send(context.Background(), create, msg))
+	path := filepath.Join(dir, "orders", "42.json")
+	assert.Equal(t, path, msg.Properties[FileWrittenProperty])
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":42}`, string(content))
+
+	err = Send(context.Background(), create, msg)
+	assert.ErrorContains(t, err, "already exists", "create does not replace files")
+
+	msg.Message.RawPayload = []byte(`{"id":42,"v":2}`)
+	overwrite := fileEndpoint(map[string]string{FileURIOption: dir, FileNameOption: `orders/{{.Property "orderId"}}.json`, FileModeOption: "overwrite"})
+	require.NoError(t, Send(context.Background(), overwrite, msg))
+	content, _ = os.ReadFile(path)
+	assert.Equal(t, `{"id":42,"v":2}`, string(content))
+
+	appendLog := fileEndpoint(map[string]string{FileURIOption: dir, FileNameOption: "orders.log", FileModeOption: "append"})
+	for _, line := range []string{"a\n", "b\n"} {
+		msg.Message.RawPayload = []byte(line)
+		require.NoError(t, Send(context.Background(), appendLog, msg))
+	}
+	content, _ = os.ReadFile(filepath.Join(dir, "orders.log"))
+	assert.Equal(t, "a\nb\n", string(content))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "orders"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+}
+
+func TestFileSender_Names(t *testing.T) {
+	dir := t.TempDir()
+	msg := synctx.CreateMsgContext()
+	msg.Properties["orderId"] = "../../etc/passwd"
+
+	escape := fileEndpoint(map[string]string{FileURIOption: dir, FileNameOption: `{{.Property "orderId"}}`})
+	assert.ErrorContains(t, Send(context.Background(), escape, msg), "must stay within the target directory")
+
+	stamped := fileEndpoint(map[string]string{FileURIOption: dir, FileNameOption: `batch-{{now "20060102"}}.csv`})
+	require.NoError(t, Send(context.Background(), stamped, msg))
+	assert.FileExists(t, filepath.Join(dir, "batch-"+time.Now().UTC().Format("20060102")+".csv"))
+}
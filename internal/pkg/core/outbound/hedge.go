@@ -0,0 +1,185 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// hedgeSamples is the number of recent response times the delay of a
+	// hedged endpoint is computed from
+	hedgeSamples = 128
+	// minHedgeSamples is the number of responses observed before the
+	// percentile replaces the minimum delay
+	minHedgeSamples = 16
+)
+
+// idempotentMethods may be sent twice without changing the outcome
+var idempotentMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete}
+
+// HedgeStats counts the hedged calls of one endpoint
+type HedgeStats struct {
+	Endpoint string `json:"endpoint"`
+	// Delay is the current wait before a second request is sent
+	Delay string `json:"delay"`
+	// Hedged calls sent a second request; HedgeWins were answered by it
+	Hedged    int64 `json:"hedged"`
+	HedgeWins int64 `json:"hedgeWins"`
+}
+
+// hedgeState keeps the recent response times of a hedged endpoint
+type hedgeState struct {
+	latencies []time.Duration // ring buffer of hedgeSamples entries
+	next      int
+	stats     HedgeStats
+}
+
+var (
+	hedgesMu sync.Mutex
+	hedges   = make(map[string]*hedgeState)
+)
+
+// hedgeable reports whether a call to the endpoint may be hedged, i.e. the
+// endpoint declares hedging and the call's method is idempotent
+func hedgeable(endpoint artifacts.Endpoint, msg *synctx.MsgContext) bool {
+	if endpoint.Hedge == nil || endpoint.Protocol != artifacts.ProtocolHTTP {
+		return false
+	}
+	method, err := endpoint.Option(HTTPMethodOption, msg)
+	if err != nil || method == "" {
+		return false
+	}
+	return slices.Contains(idempotentMethods, strings.ToUpper(method))
+}
+
+// sendHedged sends msg and, when no response arrives within the endpoint's
+// hedge delay, a copy of it. The first response is kept and the other
+// request is cancelled. A failed request waits for the other one.
+func sendHedged(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext, send func(context.Context, artifacts.Endpoint, *synctx.MsgContext) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		msg    *synctx.MsgContext
+		err    error
+		hedged bool
+	}
+	results := make(chan result, 2)
+	launch := func(hedged bool) {
+		attempt := msg.Clone()
+		go func() {
+			start := time.Now()
+			err := send(ctx, endpoint, attempt)
+			if err == nil {
+				recordHedgeLatency(endpoint.Name, time.Since(start))
+			}
+			results <- result{msg: attempt, err: err, hedged: hedged}
+		}()
+	}
+
+	launch(false)
+	inFlight := 1
+	timer := time.NewTimer(hedgeDelay(endpoint))
+	defer timer.Stop()
+	hedgeTimer := timer.C
+	for {
+		select {
+		case <-hedgeTimer:
+			hedgeTimer = nil
+			launch(true)
+			inFlight++
+			updateHedge(endpoint.Name, func(state *hedgeState) { state.stats.Hedged++ })
+		case r := <-results:
+			inFlight--
+			if r.err != nil && inFlight > 0 {
+				continue
+			}
+			if r.err == nil {
+				msg.Message = r.msg.Message
+				msg.Headers = r.msg.Headers
+				msg.Properties = r.msg.Properties
+				if r.hedged {
+					updateHedge(endpoint.Name, func(state *hedgeState) { state.stats.HedgeWins++ })
+				}
+			}
+			return r.err
+		}
+	}
+}
+
+// hedgeDelay returns the configured percentile of the endpoint's recent
+// response times, at least its minimum delay
+func hedgeDelay(endpoint artifacts.Endpoint) time.Duration {
+	delay := endpoint.Hedge.MinDelay
+	updateHedge(endpoint.Name, func(state *hedgeState) {
+		if len(state.latencies) >= minHedgeSamples {
+			latencies := slices.Clone(state.latencies)
+			slices.Sort(latencies)
+			index := int(math.Ceil(endpoint.Hedge.Percentile/100*float64(len(latencies)))) - 1
+			delay = max(latencies[max(0, min(index, len(latencies)-1))], delay)
+		}
+		state.stats.Delay = delay.String()
+	})
+	return delay
+}
+
+func recordHedgeLatency(endpoint string, latency time.Duration) {
+	updateHedge(endpoint, func(state *hedgeState) {
+		if len(state.latencies) < hedgeSamples {
+			state.latencies = append(state.latencies, latency)
+			return
+		}
+		state.latencies[state.next] = latency
+		state.next = (state.next + 1) % hedgeSamples
+	})
+}
+
+func updateHedge(endpoint string, update func(state *hedgeState)) {
+	hedgesMu.Lock()
+	defer hedgesMu.Unlock()
+	state, ok := hedges[endpoint]
+	if !ok {
+		state = &hedgeState{stats: HedgeStats{Endpoint: endpoint}}
+		hedges[endpoint] = state
+	}
+	update(state)
+}
+
+// Hedges returns the counters of every hedged endpoint, sorted by endpoint
+func Hedges() []HedgeStats {
+	hedgesMu.Lock()
+	defer hedgesMu.Unlock()
+	all := make([]HedgeStats, 0, len(hedges))
+	for _, state := range hedges {
+		all = append(all, state.stats)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Endpoint < all[j].Endpoint })
+	return all
+}
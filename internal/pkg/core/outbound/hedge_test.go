@@ -0,0 +1,154 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hedgedEndpoint is an http endpoint named after the test, with no response
+// times recorded
+func hedgedEndpoint(t *testing.T, options map[string]string) artifacts.Endpoint {
+	hedgesMu.Lock()
+	delete(hedges, t.Name())
+	hedgesMu.Unlock()
+	hedged := endpoint("http", options)
+	hedged.Name = t.Name()
+	hedged.Hedge = &artifacts.Hedge{Percentile: 95, MinDelay: 20 * time.Millisecond}
+	return hedged
+}
+
+func hedgeStats(t *testing.T) HedgeStats {
+	for _, stats := range Hedges() {
+		if stats.Endpoint == t.Name() {
+			return stats
+		}
+	}
+	return HedgeStats{}
+}
+
+func TestSend_Hedged(t *testing.T) {
+	var requests atomic.Int32
+	cancelled := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// The first request is stuck until the hedge wins
+			<-r.Context().Done()
+			close(cancelled)
+			return
+		}
+		w.Write([]byte("hedge"))
+	}))
+	defer backend.Close()
+
+	msg := synctx.CreateMsgContext()
+	require.NoError(t, Send(context.Background(), hedgedEndpoint(t, map[string]string{HTTPURIOption: backend.URL, HTTPMethodOption: "GET"}), msg))
+	assert.Equal(t, "hedge", string(msg.Message.RawPayload))
+	assert.Equal(t, http.StatusOK, msg.Properties[artifacts.HTTPStatusProperty])
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the slower request was not cancelled")
+	}
+	assert.Equal(t, HedgeStats{Endpoint: t.Name(), Delay: "20ms", Hedged: 1, HedgeWins: 1}, hedgeStats(t))
+}
+
+func TestSend_HedgedOnlyWhenIdempotent(t *testing.T) {
+	var requests atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(60 * time.Millisecond)
+	}))
+	defer backend.Close()
+
+	require.NoError(t, Send(context.Background(), hedgedEndpoint(t, map[string]string{HTTPURIOption: backend.URL, HTTPMethodOption: "POST"}), synctx.CreateMsgContext()))
+	require.NoError(t, Send(context.Background(), hedgedEndpoint(t, map[string]string{HTTPURIOption: backend.URL}), synctx.CreateMsgContext()))
+	assert.Equal(t, int32(2), requests.Load(), "posts are never sent twice")
+	assert.Equal(t, HedgeStats{}, hedgeStats(t))
+}
+
+func TestSendHedged(t *testing.T) {
+	hedged := hedgedEndpoint(t, nil)
+
+	t.Run("fast response is not hedged", func(t *testing.T) {
+		var calls atomic.Int32
+		err := sendHedged(context.Background(), hedged, synctx.CreateMsgContext(), func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+			calls.Add(1)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("failure before the delay is returned", func(t *testing.T) {
+		var calls atomic.Int32
+		err := sendHedged(context.Background(), hedged, synctx.CreateMsgContext(), func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+			calls.Add(1)
+			return errors.New("connection refused")
+		})
+		assert.EqualError(t, err, "connection refused")
+		assert.Equal(t, int32(1), calls.Load(), "hedging is not a retry")
+	})
+
+	t.Run("failed request waits for the other", func(t *testing.T) {
+		var calls atomic.Int32
+		msg := synctx.CreateMsgContext()
+		err := sendHedged(context.Background(), hedged, msg, func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+			if calls.Add(1) == 1 {
+				time.Sleep(40 * time.Millisecond)
+				return errors.New("reset by peer")
+			}
+			time.Sleep(60 * time.Millisecond)
+			msg.Message.RawPayload = []byte("second")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(msg.Message.RawPayload))
+	})
+}
+
+func TestHedgeDelay(t *testing.T) {
+	hedged := hedgedEndpoint(t, nil)
+	assert.Equal(t, 20*time.Millisecond, hedgeDelay(hedged), "the minimum delay applies before enough responses are seen")
+	for i := 1; i <= 100; i++ {
+		recordHedgeLatency(hedged.Name, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 95*time.Millisecond, hedgeDelay(hedged))
+	hedged.Hedge.MinDelay = 200 * time.Millisecond
+	assert.Equal(t, 200*time.Millisecond, hedgeDelay(hedged))
+
+	// Old response times leave the window
+	for i := 0; i < hedgeSamples; i++ {
+		recordHedgeLatency(hedged.Name, time.Millisecond)
+	}
+	hedged.Hedge.MinDelay = time.Microsecond
+	assert.Equal(t, time.Millisecond, hedgeDelay(hedged))
+}
@@ -0,0 +1,218 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Options of <http> endpoint blocks
+const (
+	HTTPMethodOption  = "method"
+	HTTPURIOption     = "uri-template"
+	HTTPTimeoutOption = "timeout"
+	// HTTPHostsOption overrides the addresses of hosts for the endpoint, as
+	// comma-separated name=address pairs
+	HTTPHostsOption = "hosts"
+)
+
+// maxIdleConnsPerHost is the number of idle connections kept to each backend
+const maxIdleConnsPerHost = 64
+
+// hopHeaders are not forwarded from the inbound request to the backend
+var hopHeaders = []string{"Connection", "Content-Length", "Host", "Keep-Alive", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// HTTPSender sends messages as HTTP requests. A client given to the sender
+// is shared by every endpoint; otherwise each endpoint has its own pool of
+// connections, which is drained when the endpoint is redeployed.
+type HTTPSender struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	pools map[string]*connectionPool
+}
+
+// connectionPool holds the connections of one endpoint definition
+type connectionPool struct {
+	client *http.Client
+	// calls counts the requests in flight, so a drained pool is closed once
+	// they complete
+	calls sync.WaitGroup
+}
+
+// NewHTTPSender creates a sender using the client, or per-endpoint pools when
+// nil. The pools keep more idle connections per backend than
+// http.DefaultTransport and resolve hosts with the resolver set by
+// SetResolver.
+func NewHTTPSender(client *http.Client) *HTTPSender {
+	return &HTTPSender{client: client, pools: make(map[string]*connectionPool)}
+}
+
+// acquire returns the client for a call to the endpoint and a function to
+// call when the response has been read
+func (s *HTTPSender) acquire(endpoint string) (*http.Client, func()) {
+	if s.client != nil {
+		return s.client, func() {}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pool, ok := s.pools[endpoint]
+	if !ok {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		transport.DialContext = dialContext
+		pool = &connectionPool{client: &http.Client{Transport: transport}}
+		s.pools[endpoint] = pool
+	}
+	pool.calls.Add(1)
+	return pool.client, pool.calls.Done
+}
+
+// Drain stops new calls to the endpoint from using its current connections,
+// and closes them once the calls in flight complete
+func (s *HTTPSender) Drain(endpoint string) {
+	s.mu.Lock()
+	pool, ok := s.pools[endpoint]
+	delete(s.pools, endpoint)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	go func() {
+		pool.calls.Wait()
+		pool.client.CloseIdleConnections()
+	}()
+}
+
+func (s *HTTPSender) Options() []string {
+	return []string{HTTPMethodOption, HTTPURIOption, HTTPTimeoutOption, HTTPHostsOption}
+}
+
+func (s *HTTPSender) Validate(endpoint artifacts.Endpoint) error {
+	if !endpoint.HasOption(HTTPURIOption) {
+		return fmt.Errorf("http endpoint %s requires a uri-template", endpoint.Name)
+	}
+	if endpoint.HasOption(HTTPTimeoutOption) {
+		timeout, err := endpoint.Option(HTTPTimeoutOption, synctx.CreateMsgContext())
+		if duration, parseErr := time.ParseDuration(timeout); err != nil || parseErr != nil || duration <= 0 {
+			return fmt.Errorf("http endpoint %s: timeout must be a positive duration, got '%s'", endpoint.Name, timeout)
+		}
+	}
+	if endpoint.HasOption(HTTPHostsOption) {
+		hosts, err := endpoint.Option(HTTPHostsOption, synctx.CreateMsgContext())
+		if err == nil {
+			_, err = ParseHostOverrides(hosts)
+		}
+		if err != nil {
+			return fmt.Errorf("http endpoint %s: %w", endpoint.Name, err)
+		}
+	}
+	return nil
+}
+
+// Send makes the request and replaces the message's payload, content type and
+// headers with the response. The response status is stored in the HTTP_SC
+// property; only failures to get a response are errors.
+func (s *HTTPSender) Send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+	method, err := endpoint.Option(HTTPMethodOption, msg)
+	if err != nil {
+		return err
+	}
+	if method == "" {
+		method = http.MethodPost
+	}
+	target, err := endpoint.Option(HTTPURIOption, msg)
+	if err != nil {
+		return err
+	}
+	if _, err := url.ParseRequestURI(target); err != nil {
+		return fmt.Errorf("endpoint %s: invalid URL '%s'", endpoint.Name, target)
+	}
+	if timeout, _ := endpoint.Option(HTTPTimeoutOption, msg); timeout != "" {
+		duration, _ := time.ParseDuration(timeout) // checked by Validate
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+	if hosts, _ := endpoint.Option(HTTPHostsOption, msg); hosts != "" {
+		overrides, err := ParseHostOverrides(hosts)
+		if err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+		}
+		ctx = WithHostOverrides(ctx, overrides)
+	}
+
+	var body io.Reader
+	if len(msg.Message.RawPayload) > 0 && method != http.MethodGet && method != http.MethodHead {
+		body = bytes.NewReader(msg.Message.RawPayload)
+	}
+	request, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), target, body)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	for name, value := range msg.Headers {
+		request.Header.Set(name, value)
+	}
+	for _, name := range hopHeaders {
+		request.Header.Del(name)
+	}
+	if body != nil && msg.Message.ContentType != "" {
+		request.Header.Set("Content-Type", msg.Message.ContentType)
+	}
+	// The backend is told how long it has, the shorter of the endpoint's
+	// timeout and the latency budget left for the message
+	if deadline, ok := ctx.Deadline(); ok {
+		request.Header.Set(artifacts.RequestTimeoutHeader, artifacts.FormatRequestTimeout(deadline, time.Now()))
+	}
+
+	client, done := s.acquire(endpoint.Name)
+	defer done()
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	defer response.Body.Close()
+	payload, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: cannot read response: %w", endpoint.Name, err)
+	}
+
+	msg.Message.RawPayload = payload
+	msg.Message.ContentType = response.Header.Get("Content-Type")
+	msg.Message.Attachments = nil
+	msg.Headers = make(map[string]string, len(response.Header))
+	for name := range response.Header {
+		msg.Headers[name] = response.Header.Get(name)
+	}
+	msg.Properties[artifacts.HTTPStatusProperty] = response.StatusCode
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/transactions"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	componentName = "outbound"
+
+	// MirrorTimeout bounds a mirrored send whose endpoint sets no timeout
+	MirrorTimeout = 30 * time.Second
+	// maxMirrorsInFlight bounds the background sends to mirrors. Copies are
+	// dropped rather than queued when a mirror is slower than production.
+	maxMirrorsInFlight = 256
+)
+
+// MirrorStats counts the copies sent to one mirror endpoint
+type MirrorStats struct {
+	Endpoint string `json:"endpoint"`
+	Sent     int64  `json:"sent"`
+	Failed   int64  `json:"failed"`
+	// Dropped copies were sampled but not sent because too many mirrored
+	// sends were in flight, or the mirror endpoint is not deployed
+	Dropped int64 `json:"dropped"`
+}
+
+var (
+	mirrorSlots = make(chan struct{}, maxMirrorsInFlight)
+
+	mirrorStatsMu sync.Mutex
+	mirrorStats   = make(map[string]*MirrorStats)
+)
+
+// SampleMirror decides whether a message is mirrored, for the mirror's
+// percentage of messages
+func SampleMirror(mirror artifacts.Mirror) bool {
+	return mirror.Percent >= 100 || rand.Float64()*100 < mirror.Percent
+}
+
+// Mirror sends msg to the mirror endpoint in the background and discards the
+// response. msg must be a copy owned by the mirror. The mirror endpoint's own
+// mirror is not followed, so mirrors cannot form a loop.
+func Mirror(mirror artifacts.Mirror, msg *synctx.MsgContext) {
+	endpoint, ok := artifacts.SnapshotFromContext(msg).Endpoints[mirror.Endpoint]
+	if !ok {
+		recordMirror(mirror.Endpoint, func(stats *MirrorStats) { stats.Dropped++ })
+		loggerfactory.GetLogger(componentName, nil).Warn("Mirror endpoint is not deployed",
+			slog.String("endpoint", mirror.Endpoint))
+		return
+	}
+	sender, err := SenderFor(endpoint.Protocol)
+	if err != nil {
+		recordMirror(mirror.Endpoint, func(stats *MirrorStats) { stats.Dropped++ })
+		return
+	}
+	select {
+	case mirrorSlots <- struct{}{}:
+	default:
+		recordMirror(mirror.Endpoint, func(stats *MirrorStats) { stats.Dropped++ })
+		return
+	}
+	go func() {
+		defer func() { <-mirrorSlots }()
+		ctx, cancel := context.WithTimeout(context.Background(), MirrorTimeout)
+		defer cancel()
+		if err := sender.Send(ctx, endpoint, msg); err != nil {
+			recordMirror(mirror.Endpoint, func(stats *MirrorStats) { stats.Failed++ })
+			loggerfactory.GetLogger(componentName, nil).Debug("Mirrored send failed",
+				slog.String("endpoint", mirror.Endpoint), slog.Any("error", err))
+			return
+		}
+		recordMirror(mirror.Endpoint, func(stats *MirrorStats) { stats.Sent++ })
+	}()
+}
+
+// copyForMirror copies the parts of msg a sender reads, so the mirror can
+// send it while the original is sent and its payload replaced by the reply
+func copyForMirror(msg *synctx.MsgContext) *synctx.MsgContext {
+	mirrored := synctx.CreateMsgContext()
+	maps.Copy(mirrored.Properties, msg.Properties)
+	// Shadow traffic is not part of the caller's transaction
+	delete(mirrored.Properties, transactions.Property)
+	maps.Copy(mirrored.Headers, msg.Headers)
+	mirrored.Message = msg.Message
+	mirrored.Message.RawPayload = slices.Clone(msg.Message.RawPayload)
+	return mirrored
+}
+
+func recordMirror(endpoint string, update func(stats *MirrorStats)) {
+	mirrorStatsMu.Lock()
+	defer mirrorStatsMu.Unlock()
+	stats, ok := mirrorStats[endpoint]
+	if !ok {
+		stats = &MirrorStats{Endpoint: endpoint}
+		mirrorStats[endpoint] = stats
+	}
+	update(stats)
+}
+
+// Mirrors returns the counters of every mirror endpoint, sorted by endpoint
+func Mirrors() []MirrorStats {
+	mirrorStatsMu.Lock()
+	defer mirrorStatsMu.Unlock()
+	all := make([]MirrorStats, 0, len(mirrorStats))
+	for _, stats := range mirrorStats {
+		all = append(all, *stats)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Endpoint < all[j].Endpoint })
+	return all
+}
@@ -0,0 +1,40 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// NotificationIDProperty holds the identifier the SMS centre or push service
+// assigned to the last notification sent, e.g. the SMPP message_id or the
+// apns-id of a push
+const NotificationIDProperty = "NOTIFICATION_ID"
+
+// notificationText renders a text option of a notification endpoint. When
+// the block does not set it, the payload of the message is sent, so a
+// payloadFactory before the call can build the text.
+func notificationText(endpoint artifacts.Endpoint, name string, msg *synctx.MsgContext) (string, error) {
+	if !endpoint.HasOption(name) {
+		return string(msg.Message.RawPayload), nil
+	}
+	return endpoint.Option(name, msg)
+}
@@ -0,0 +1,175 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package outbound sends messages to endpoints over the protocol their
+// artifact declares. Each protocol has a Sender, so mediators that call
+// backends work with any protocol that has one registered.
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/transactions"
+)
+
+// Sender delivers messages over one protocol
+type Sender interface {
+	// Options lists the attributes the protocol block accepts
+	Options() []string
+	// Validate checks the protocol block of an endpoint when it is deployed
+	Validate(endpoint artifacts.Endpoint) error
+	// Send delivers the message to the endpoint. Protocols with a reply
+	// replace the message's payload with it.
+	Send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error
+}
+
+var (
+	sendersMu sync.RWMutex
+	senders   = map[string]Sender{
+		artifacts.ProtocolHTTP: NewHTTPSender(nil),
+		artifacts.ProtocolFile: NewFileSender(),
+		artifacts.ProtocolSMPP: NewSMPPSender(),
+		artifacts.ProtocolFCM:  NewFCMSender(nil),
+		artifacts.ProtocolAPNs: NewAPNsSender(nil),
+	}
+)
+
+// drainer is implemented by senders that keep connections per endpoint
+type drainer interface {
+	// Drain retires the connections of the endpoint once its calls in
+	// flight complete; later calls open new ones
+	Drain(endpoint string)
+}
+
+// Drain retires the connections of a redeployed endpoint in every sender
+// that keeps them, so new calls use the new definition without resetting
+// the calls in flight
+func Drain(endpoint string) {
+	sendersMu.RLock()
+	defer sendersMu.RUnlock()
+	for _, sender := range senders {
+		if d, ok := sender.(drainer); ok {
+			d.Drain(endpoint)
+		}
+	}
+}
+
+// Register makes a sender available to endpoints of the protocol, replacing
+// the sender registered before
+func Register(protocol string, sender Sender) {
+	sendersMu.Lock()
+	defer sendersMu.Unlock()
+	senders[protocol] = sender
+}
+
+// Protocols lists the protocols with a registered sender
+func Protocols() []string {
+	sendersMu.RLock()
+	defer sendersMu.RUnlock()
+	protocols := make([]string, 0, len(senders))
+	for protocol := range senders {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// SenderFor returns the sender of a protocol
+func SenderFor(protocol string) (Sender, error) {
+	sendersMu.RLock()
+	sender, ok := senders[protocol]
+	sendersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no outbound sender for protocol %s, supported protocols are %s",
+			protocol, strings.Join(Protocols(), ", "))
+	}
+	return sender, nil
+}
+
+// Validate checks that the endpoint's protocol has a sender and that its
+// protocol block only sets options the sender understands
+func Validate(endpoint artifacts.Endpoint) error {
+	sender, err := SenderFor(endpoint.Protocol)
+	if err != nil {
+		return err
+	}
+	options := sender.Options()
+	for name := range endpoint.Options {
+		if !slices.Contains(options, name) {
+			return fmt.Errorf("unknown %s option '%s', expected one of %s", endpoint.Protocol, name, strings.Join(options, ", "))
+		}
+	}
+	return sender.Validate(endpoint)
+}
+
+// Send delivers the message with the sender of the endpoint's protocol, and
+// a copy to the endpoint's mirror when it has one. Mapped properties become
+// headers of the message before it is sent. With stubs active, the
+// response is recorded or replayed; replayed requests are not mirrored.
+// Slow idempotent calls to hedged endpoints are raced by a second request.
+// With wire logging on, the message and its reply are logged.
+// The call is noted in the transaction of the message when it is published.
+// The call must complete by the deadline of the message, if it has one, and
+// is not made at all once the deadline has passed.
+func Send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+	sender, err := SenderFor(endpoint.Protocol)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := artifacts.Deadline(msg); ok {
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("endpoint %s: %w", endpoint.Name, artifacts.ErrBudgetExhausted)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	if recorder := transactions.FromContext(msg); recorder != nil {
+		start := time.Now()
+		defer func() { recorder.RecordBackend(endpoint.Name, time.Since(start), err) }()
+	}
+	endpoint.MapHeaders(msg)
+	if WireLogging() {
+		logWire(">>", endpoint, msg, nil)
+		defer func() { logWire("<<", endpoint, msg, err) }()
+	}
+	send := func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+		if endpoint.Mirror != nil && SampleMirror(*endpoint.Mirror) {
+			Mirror(*endpoint.Mirror, copyForMirror(msg))
+		}
+		if hedgeable(endpoint, msg) {
+			return sendHedged(ctx, endpoint, msg, sender.Send)
+		}
+		return sender.Send(ctx, endpoint, msg)
+	}
+	if stubs := ActiveStubs(); stubs != nil && stubs.applies(endpoint) {
+		err = stubs.send(ctx, endpoint, msg, send)
+		return err
+	}
+	err = send(ctx, endpoint, msg)
+	return err
+}
@@ -0,0 +1,445 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/transactions"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func endpoint(protocol string, options map[string]string) artifacts.Endpoint {
+	compiled := make(map[string]*template.Template)
+	for name, value := range options {
+		compiled[name] = templateOption(name, value)
+	}
+	return artifacts.Endpoint{Name: "TestEP", Protocol: protocol, Options: compiled}
+}
+
+func templateOption(name, value string) *template.Template {
+	return template.Must(template.New(name).Funcs(artifacts.PreconditionFuncs).Parse(value))
+}
+
+// recordingSender records the messages it sends
+type recordingSender struct {
+	sent []string
+}
+
+func (s *recordingSender) Options() []string { return []string{"topic"} }
+
+func (s *recordingSender) Validate(endpoint artifacts.Endpoint) error { return nil }
+
+func (s *recordingSender) Send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+	topic, err := endpoint.Option("topic", msg)
+	s.sent = append(s.sent, topic+":"+string(msg.Message.RawPayload))
+	return err
+}
+
+func TestRegister(t *testing.T) {
+	sender := &recordingSender{}
+	Register("test", sender)
+	t.Cleanup(func() {
+		sendersMu.Lock()
+		delete(senders, "test")
+		sendersMu.Unlock()
+	})
+	assert.Equal(t, []string{"apns", "fcm", "file", "http", "smpp", "test"}, Protocols())
+
+	assert.NoError(t, Validate(endpoint("test", map[string]string{"topic": "orders"})))
+	assert.EqualError(t, Validate(endpoint("test", map[string]string{"queue": "orders"})),
+		"unknown test option 'queue', expected one of topic")
+
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte("created")
+	require.NoError(t, Send(context.Background(), endpoint("test", map[string]string{"topic": "orders"}), msg))
+	assert.Equal(t, []string{"orders:created"}, sender.sent)
+
+	assert.EqualError(t, Send(context.Background(), endpoint("amqp", nil), msg),
+		"no outbound sender for protocol amqp, supported protocols are apns, fcm, file, http, smpp, test")
+}
+
+func TestHTTPSender_Send(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/orders/42", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.JSONEq(t, `{"qty":1}`, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Backend", "orders")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":42}`))
+	}))
+	defer backend.Close()
+
+	msg := synctx.CreateMsgContext()
+	msg.Properties["orderId"] = "42"
+	msg.Message.RawPayload = []byte(`{"qty":1}`)
+	msg.Message.ContentType = "application/json"
+	msg.Headers["Authorization"] = "Bearer token"
+	msg.Headers["Host"] = "gateway.example.com"
+
+	sender := NewHTTPSender(backend.Client())
+	ep := endpoint("http", map[string]string{
+		HTTPMethodOption:  "PUT",
+		HTTPURIOption:     backend.URL + `/orders/{{.Property "orderId"}}`,
+		HTTPTimeoutOption: "5s",
+	})
+	require.NoError(t, sender.Validate(ep))
+	require.NoError(t, sender.Send(context.Background(), ep, msg))
+
+	assert.Equal(t, `{"id":42}`, string(msg.Message.RawPayload))
+	assert.Equal(t, "application/json", msg.Message.ContentType)
+	assert.Equal(t, "orders", msg.Headers["X-Backend"])
+	assert.Equal(t, http.StatusCreated, msg.Properties[artifacts.HTTPStatusProperty])
+}
+
+func TestHTTPSender_Errors(t *testing.T) {
+	sender := NewHTTPSender(nil)
+	assert.EqualError(t, sender.Validate(endpoint("http", nil)), "http endpoint TestEP requires a uri-template")
+
+	msg := synctx.CreateMsgContext()
+	err := sender.Send(context.Background(), endpoint("http", map[string]string{HTTPURIOption: "not a url"}), msg)
+	assert.EqualError(t, err, "endpoint TestEP: invalid URL 'not a url'")
+
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backend.Close()
+	err = sender.Send(context.Background(), endpoint("http", map[string]string{HTTPURIOption: backend.URL}), msg)
+	assert.ErrorContains(t, err, "endpoint TestEP:")
+}
+
+func TestSend_RecordsTransactionBackends(t *testing.T) {
+	sender := &recordingSender{}
+	Register("test", sender)
+	t.Cleanup(func() {
+		sendersMu.Lock()
+		delete(senders, "test")
+		sendersMu.Unlock()
+	})
+
+	recorder := &transactions.Recorder{}
+	msg := synctx.CreateMsgContext()
+	transactions.AddToContext(msg, recorder)
+	require.NoError(t, Send(context.Background(), endpoint("test", map[string]string{"topic": "orders"}), msg))
+	assert.Error(t, Send(context.Background(), endpoint("test", map[string]string{"topic": "{{.Missing}}"}), msg))
+
+	event := recorder.Event("orders:v1", httptest.NewRequest(http.MethodGet, "/orders", nil), http.StatusOK, time.Now(), 0)
+	require.Len(t, event.Backends, 2)
+	assert.Equal(t, "TestEP", event.Backends[0].Endpoint)
+	assert.Empty(t, event.Backends[0].Error)
+	assert.NotEmpty(t, event.Backends[1].Error)
+}
+
+func TestSend_WireLogging(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+	SetWireLogging(true)
+	t.Cleanup(func() { SetWireLogging(false) })
+	sender := &recordingSender{}
+	Register("test", sender)
+	t.Cleanup(func() {
+		sendersMu.Lock()
+		delete(senders, "test")
+		sendersMu.Unlock()
+	})
+
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte(strings.Repeat("x", maxWirePayload+1))
+	require.NoError(t, Send(context.Background(), endpoint("test", map[string]string{"topic": "orders"}), msg))
+	assert.Error(t, Send(context.Background(), endpoint("test", map[string]string{"topic": "{{.Missing}}"}), msg))
+	assert.Len(t, sender.sent, 2, "logging does not change what is sent")
+}
+
+func TestSend_MapsHeaders(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer backend.Close()
+
+	orders := endpoint("http", map[string]string{HTTPURIOption: backend.URL})
+	orders.Headers = []artifacts.HeaderMapping{{Header: "X-Order-Id", Property: "orderId"}, {Header: "X-Tenant", Property: "tenant"}}
+	msg := synctx.CreateMsgContext()
+	msg.Properties["orderId"] = 42
+	require.NoError(t, Send(context.Background(), orders, msg))
+	assert.Equal(t, "42", got.Get("X-Order-Id"))
+	assert.Empty(t, got.Values("X-Tenant"))
+}
+
+func TestSend_LatencyBudget(t *testing.T) {
+	// The budget header of every call, sent from the handler goroutines
+	got := make(chan string, 10)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get(artifacts.RequestTimeoutHeader)
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer backend.Close()
+
+	msg := synctx.CreateMsgContext()
+	require.NoError(t, Send(context.Background(), endpoint("http", map[string]string{HTTPURIOption: backend.URL}), msg))
+	artifacts.SetDeadline(msg, time.Now().Add(time.Minute))
+	require.NoError(t, Send(context.Background(), endpoint("http", map[string]string{HTTPURIOption: backend.URL, HTTPTimeoutOption: "5s"}), msg))
+	require.NoError(t, Send(context.Background(), endpoint("http", map[string]string{HTTPURIOption: backend.URL, HTTPTimeoutOption: "2m"}), msg))
+	require.Len(t, got, 3)
+	assert.Empty(t, <-got, "calls without a deadline carry no budget")
+	budget, ok := artifacts.ParseRequestTimeout(<-got)
+	require.True(t, ok)
+	assert.InDelta(t, 5*time.Second, budget, float64(time.Second), "the endpoint's shorter timeout applies")
+	budget, _ = artifacts.ParseRequestTimeout(<-got)
+	assert.InDelta(t, time.Minute, budget, float64(time.Second), "the message's shorter deadline applies")
+
+	artifacts.SetDeadline(msg, time.Now().Add(50*time.Millisecond))
+	err := Send(context.Background(), endpoint("http", map[string]string{HTTPURIOption: backend.URL + "/slow"}), msg)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-got
+
+	artifacts.SetDeadline(msg, time.Now().Add(-time.Millisecond))
+	err = Send(context.Background(), endpoint("http", map[string]string{HTTPURIOption: backend.URL}), msg)
+	assert.ErrorIs(t, err, artifacts.ErrBudgetExhausted)
+	assert.Empty(t, got, "no call is made once the budget is exhausted")
+}
+
+func TestSend_Mirror(t *testing.T) {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+
+	mirrored := make(chan string, 1)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mirrored <- r.URL.Path + " " + string(body)
+		w.Write([]byte(`{"version":2}`))
+	}))
+	defer mirror.Close()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":1}`))
+	}))
+	defer backend.Close()
+
+	primary := endpoint("http", map[string]string{HTTPURIOption: backend.URL + "/orders"})
+	primary.Mirror = &artifacts.Mirror{Endpoint: "OrdersV2EP", Percent: 100}
+	shadow := endpoint("http", map[string]string{HTTPURIOption: mirror.URL + "/v2/orders"})
+	shadow.Name = "OrdersV2EP"
+
+	msg := synctx.CreateMsgContext()
+	msg.Message.RawPayload = []byte(`{"qty":1}`)
+	msg.Properties[artifacts.SnapshotProperty] = &artifacts.Snapshot{Endpoints: map[string]artifacts.Endpoint{"OrdersV2EP": shadow}}
+	require.NoError(t, Send(context.Background(), primary, msg))
+
+	// The caller gets the primary's response; the mirror gets the request
+	assert.Equal(t, `{"version":1}`, string(msg.Message.RawPayload))
+	select {
+	case request := <-mirrored:
+		assert.Equal(t, `/v2/orders {"qty":1}`, request)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request was not mirrored")
+	}
+	assert.Eventually(t, func() bool {
+		for _, stats := range Mirrors() {
+			if stats.Endpoint == "OrdersV2EP" {
+				return stats.Sent == 1
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A mirror that is not deployed does not fail the send
+	primary.Mirror = &artifacts.Mirror{Endpoint: "MissingEP", Percent: 100}
+	require.NoError(t, Send(context.Background(), primary, msg))
+	assert.Contains(t, Mirrors(), MirrorStats{Endpoint: "MissingEP", Dropped: 1})
+}
+
+func TestSampleMirror(t *testing.T) {
+	assert.True(t, SampleMirror(artifacts.Mirror{Percent: 100}))
+	sampled := 0
+	for range 10000 {
+		if SampleMirror(artifacts.Mirror{Percent: 10}) {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 1000, sampled, 200)
+}
+
+func TestSend_Stubs(t *testing.T) {
+	calls := 0
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	defer backend.Close()
+	orders := endpoint("http", map[string]string{HTTPURIOption: backend.URL + `/orders/{{.Property "orderId"}}`})
+	orders.Name = "OrdersEP"
+	newMsg := func(orderID, payload, token string) *synctx.MsgContext {
+		msg := synctx.CreateMsgContext()
+		msg.Properties["orderId"] = orderID
+		msg.Message.RawPayload = []byte(payload)
+		msg.Message.ContentType = "application/json"
+		msg.Headers["Authorization"] = token
+		return msg
+	}
+	directory := t.TempDir()
+	t.Cleanup(func() { SetStubs(nil) })
+
+	recorder, err := OpenStubs(StubConfig{Mode: StubRecord, Directory: "stubs"}, directory)
+	require.NoError(t, err)
+	SetStubs(recorder)
+	require.NoError(t, Send(context.Background(), orders, newMsg("1", `{"qty":1}`, "token-a")))
+	require.NoError(t, Send(context.Background(), orders, newMsg("2", `{"qty":2}`, "token-a")))
+	assert.Equal(t, 2, calls)
+	recordings, err := recorder.Recordings()
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+	assert.Equal(t, "OrdersEP", recordings[0].Endpoint)
+	assert.Equal(t, http.StatusCreated, recordings[0].Status)
+
+	replayer, err := OpenStubs(StubConfig{Mode: StubReplay, Directory: filepath.Join(directory, "stubs")}, "")
+	require.NoError(t, err)
+	SetStubs(replayer)
+
+	// Headers outside matchHeaders, such as tokens, do not affect matching
+	msg := newMsg("2", `{"qty":2}`, "token-b")
+	require.NoError(t, Send(context.Background(), orders, msg))
+	assert.Equal(t, 2, calls, "the backend is not called in replay mode")
+	assert.Equal(t, `{"echo":{"qty":2}}`, string(msg.Message.RawPayload))
+	assert.Equal(t, "application/json", msg.Message.ContentType)
+	assert.Equal(t, http.StatusCreated, msg.Properties[artifacts.HTTPStatusProperty])
+
+	err = Send(context.Background(), orders, newMsg("3", `{"qty":3}`, "token-a"))
+	assert.ErrorContains(t, err, "endpoint OrdersEP: no recorded response for request")
+
+	passthrough, err := OpenStubs(StubConfig{Mode: StubReplay, Directory: "stubs", Passthrough: true}, directory)
+	require.NoError(t, err)
+	SetStubs(passthrough)
+	require.NoError(t, Send(context.Background(), orders, newMsg("3", `{"qty":3}`, "token-a")))
+	assert.Equal(t, 3, calls)
+
+	// Endpoints outside the configured list are always called
+	filtered, err := OpenStubs(StubConfig{Mode: StubReplay, Directory: "stubs", Endpoints: []string{"InventoryEP"}}, directory)
+	require.NoError(t, err)
+	SetStubs(filtered)
+	require.NoError(t, Send(context.Background(), orders, newMsg("1", `{"qty":1}`, "token-a")))
+	assert.Equal(t, 4, calls)
+}
+
+func TestStubConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  StubConfig
+		wantErr string
+	}{
+		{name: "record", config: StubConfig{Mode: StubRecord, Directory: "stubs"}},
+		{name: "replay with passthrough", config: StubConfig{Mode: StubReplay, Directory: "stubs", Passthrough: true}},
+		{name: "unknown mode", config: StubConfig{Mode: "mock", Directory: "stubs"}, wantErr: "stubs: mode must be record or replay, got 'mock'"},
+		{name: "no directory", config: StubConfig{Mode: StubRecord}, wantErr: "stubs: directory is required"},
+		{name: "passthrough while recording", config: StubConfig{Mode: StubRecord, Directory: "stubs", Passthrough: true}, wantErr: "stubs: passthrough only applies to replay mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	_, err := OpenStubs(StubConfig{Mode: StubReplay, Directory: "missing"}, t.TempDir())
+	assert.ErrorContains(t, err, "does not exist, record responses first")
+}
+
+func TestHTTPSender_Drain(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var mu sync.Mutex
+	opened, closed := 0, 0
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+	backend.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch state {
+		case http.StateNew:
+			opened++
+		case http.StateClosed:
+			closed++
+		}
+	}
+	backend.Start()
+	defer backend.Close()
+	connections := func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return opened, closed
+	}
+
+	sender := NewHTTPSender(nil)
+	send := func(path string) error {
+		msg := synctx.CreateMsgContext()
+		err := sender.Send(context.Background(), endpoint("http", map[string]string{HTTPURIOption: backend.URL + path, HTTPMethodOption: "GET"}), msg)
+		if err == nil && string(msg.Message.RawPayload) != path {
+			err = fmt.Errorf("unexpected response %s", msg.Message.RawPayload)
+		}
+		return err
+	}
+	require.NoError(t, send("/fast"))
+
+	slow := make(chan error, 1)
+	go func() { slow <- send("/slow") }()
+	<-started
+	sender.Drain("TestEP")
+	require.NoError(t, send("/fast"), "new calls use a new pool")
+	newConns, closedConns := connections()
+	assert.Equal(t, 2, newConns)
+	assert.Equal(t, 0, closedConns, "calls in flight keep their connection")
+
+	close(release)
+	require.NoError(t, <-slow)
+	assert.Eventually(t, func() bool {
+		_, closed := connections()
+		return closed == 1
+	}, 5*time.Second, 10*time.Millisecond, "the drained pool is closed once its calls complete")
+
+	sender.Drain("UnknownEP")
+}
@@ -0,0 +1,463 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// Options of <fcm> endpoint blocks, e.g.
+// <fcm credentialsAlias="fcm-service-account" token="{{.Property "deviceToken"}}" title="Order failed" body="Order {{.Property "orderId"}} failed"/>
+// The credentials are the JSON key of a Google service account.
+const (
+	FCMCredentialsAliasOption = "credentialsAlias"
+	FCMTokenOption            = "token"
+	FCMTopicOption            = "topic"
+	FCMTitleOption            = "title"
+	FCMBodyOption             = "body"
+	// FCMURLOption overrides the send URL, which is derived from the
+	// project of the service account otherwise
+	FCMURLOption     = "url"
+	FCMTimeoutOption = "timeout"
+)
+
+// Options of <apns> endpoint blocks, e.g.
+// <apns keyAlias="apns-key" keyId="ABC123DEFG" teamId="DEF123GHIJ" topic="com.example.app" token="{{.Property "deviceToken"}}" title="Order failed"/>
+// The key is the .p8 signing key of the Apple developer account.
+const (
+	APNsKeyAliasOption = "keyAlias"
+	APNsKeyIDOption    = "keyId"
+	APNsTeamIDOption   = "teamId"
+	APNsTopicOption    = "topic"
+	APNsTokenOption    = "token"
+	APNsTitleOption    = "title"
+	APNsBodyOption     = "body"
+	APNsPushTypeOption = "pushType"
+	// APNsURLOption selects the APNs server, e.g.
+	// https://api.sandbox.push.apple.com for development builds
+	APNsURLOption     = "url"
+	APNsTimeoutOption = "timeout"
+)
+
+const (
+	defaultPushTimeout = 10 * time.Second
+	defaultAPNsURL     = "https://api.push.apple.com"
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+	// apnsTokenLifetime is how long a provider token is reused. APNs refuses
+	// tokens older than an hour and tokens renewed more often than every
+	// 20 minutes.
+	apnsTokenLifetime = 50 * time.Minute
+	// tokenRenewal is how long before expiry an access token is replaced
+	tokenRenewal = time.Minute
+)
+
+// accessToken is a bearer token reused until shortly before it expires
+type accessToken struct {
+	value   string
+	expires time.Time
+}
+
+// tokenCache holds the bearer tokens of push senders by credentials
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]accessToken
+}
+
+// get returns the cached token for key, or one from fetch when none is
+// valid any more
+func (c *tokenCache) get(key string, fetch func() (accessToken, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if token, ok := c.tokens[key]; ok && time.Now().Add(tokenRenewal).Before(token.expires) {
+		return token.value, nil
+	}
+	token, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	if c.tokens == nil {
+		c.tokens = make(map[string]accessToken)
+	}
+	c.tokens[key] = token
+	return token.value, nil
+}
+
+// pushClient returns client, or a client that resolves hosts with the
+// resolver set by SetResolver when nil
+func pushClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialContext
+	return &http.Client{Transport: transport}
+}
+
+// pushOptions renders the options of a push endpoint, leaving out the texts
+// that default to the payload
+func pushOptions(endpoint artifacts.Endpoint, names []string, texts []string, msg *synctx.MsgContext) (map[string]string, error) {
+	options := make(map[string]string)
+	for _, name := range names {
+		if slices.Contains(texts, name) {
+			continue
+		}
+		value, err := endpoint.Option(name, msg)
+		if err != nil {
+			return nil, err
+		}
+		options[name] = value
+	}
+	return options, nil
+}
+
+// validatePushTimeout checks a timeout option that does not depend on the
+// message
+func validatePushTimeout(endpoint artifacts.Endpoint, name string) error {
+	if timeout, err := endpoint.Option(name, synctx.CreateMsgContext()); err == nil && timeout != "" {
+		if _, err := parseTimeout(timeout); err != nil {
+			return fmt.Errorf("%s endpoint %s: %w", endpoint.Protocol, endpoint.Name, err)
+		}
+	}
+	return nil
+}
+
+func pushTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultPushTimeout, nil
+	}
+	return parseTimeout(value)
+}
+
+// signJWT signs claims as a compact JWT, adding a kid header when keyID is set
+func signJWT(key crypto.Signer, algorithm jose.SignatureAlgorithm, keyID string, claims any) (string, error) {
+	options := (&jose.SignerOptions{}).WithType("JWT")
+	if keyID != "" {
+		options = options.WithHeader("kid", keyID)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: algorithm, Key: key}, options)
+	if err != nil {
+		return "", fmt.Errorf("cannot create JWT signer: %w", err)
+	}
+	return jwt.Signed(signer).Claims(claims).Serialize()
+}
+
+// FCMSender sends each message as a push notification through Firebase
+// Cloud Messaging. Access tokens of the service account are cached until
+// shortly before they expire.
+type FCMSender struct {
+	client *http.Client
+	tokens tokenCache
+}
+
+// NewFCMSender creates a sender using the client, or its own client when nil
+func NewFCMSender(client *http.Client) *FCMSender {
+	return &FCMSender{client: pushClient(client)}
+}
+
+func (s *FCMSender) Options() []string {
+	return []string{FCMCredentialsAliasOption, FCMTokenOption, FCMTopicOption, FCMTitleOption, FCMBodyOption,
+		FCMURLOption, FCMTimeoutOption}
+}
+
+func (s *FCMSender) Validate(endpoint artifacts.Endpoint) error {
+	if !endpoint.HasOption(FCMCredentialsAliasOption) {
+		return fmt.Errorf("fcm endpoint %s requires a credentialsAlias", endpoint.Name)
+	}
+	if endpoint.HasOption(FCMTokenOption) == endpoint.HasOption(FCMTopicOption) {
+		return fmt.Errorf("fcm endpoint %s requires exactly one of token and topic", endpoint.Name)
+	}
+	return validatePushTimeout(endpoint, FCMTimeoutOption)
+}
+
+// serviceAccount is the part of a Google service account key the sender uses
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+func (s *FCMSender) Send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+	options, err := pushOptions(endpoint, s.Options(), []string{FCMBodyOption}, msg)
+	if err != nil {
+		return err
+	}
+	body, err := notificationText(endpoint, FCMBodyOption, msg)
+	if err != nil {
+		return err
+	}
+	if options[FCMTokenOption] == "" && options[FCMTopicOption] == "" {
+		return fmt.Errorf("endpoint %s: the token and topic are empty", endpoint.Name)
+	}
+	timeout, err := pushTimeout(options[FCMTimeoutOption])
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	account, err := loadServiceAccount(options[FCMCredentialsAliasOption])
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	target := options[FCMURLOption]
+	if target == "" {
+		target = "https://fcm.googleapis.com/v1/projects/" + url.PathEscape(account.ProjectID) + "/messages:send"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	token, err := s.tokens.get(options[FCMCredentialsAliasOption]+"\x00"+account.ClientEmail, func() (accessToken, error) {
+		return s.fetchToken(ctx, account)
+	})
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+
+	message := map[string]any{
+		"notification": map[string]string{"title": options[FCMTitleOption], "body": body},
+	}
+	if options[FCMTokenOption] != "" {
+		message["token"] = options[FCMTokenOption]
+	} else {
+		message["topic"] = options[FCMTopicOption]
+	}
+	payload, err := json.Marshal(map[string]any{"message": message})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	var reply struct {
+		Name string `json:"name"`
+	}
+	if err := s.call(req, &reply); err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	msg.Properties[NotificationIDProperty] = reply.Name
+	return nil
+}
+
+// loadServiceAccount reads the service account key registered under alias
+func loadServiceAccount(alias string) (serviceAccount, error) {
+	data, err := secrets.Default().Get(alias)
+	if err != nil {
+		return serviceAccount{}, err
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return serviceAccount{}, fmt.Errorf("secret '%s' is not a service account key: %w", alias, err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return serviceAccount{}, fmt.Errorf("secret '%s' lacks the project_id, client_email, private_key or token_uri of a service account", alias)
+	}
+	return account, nil
+}
+
+// fetchToken exchanges a JWT signed with the service account key for an
+// access token (RFC 7523)
+func (s *FCMSender) fetchToken(ctx context.Context, account serviceAccount) (accessToken, error) {
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return accessToken{}, fmt.Errorf("the private_key of %s is not PEM encoded", account.ClientEmail)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("invalid private_key of %s: %w", account.ClientEmail, err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return accessToken{}, fmt.Errorf("unsupported private_key type %T of %s", parsed, account.ClientEmail)
+	}
+	now := time.Now()
+	assertion, err := signJWT(key, jose.RS256, "", map[string]any{
+		"iss":   account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return accessToken{}, err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return accessToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var reply struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := s.call(req, &reply); err != nil {
+		return accessToken{}, fmt.Errorf("cannot obtain an access token for %s: %w", account.ClientEmail, err)
+	}
+	if reply.AccessToken == "" {
+		return accessToken{}, fmt.Errorf("no access token issued for %s", account.ClientEmail)
+	}
+	return accessToken{value: reply.AccessToken, expires: now.Add(time.Duration(reply.ExpiresIn) * time.Second)}, nil
+}
+
+// call sends the request and decodes a JSON reply into out. Replies other
+// than 2xx are errors carrying the status and body.
+func (s *FCMSender) call(req *http.Request, out any) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("request to %s failed with status %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid reply from %s: %w", req.URL.Host, err)
+	}
+	return nil
+}
+
+// APNsSender sends each message as a push notification through the Apple
+// Push Notification service. Provider tokens are signed with the team's key
+// and reused for apnsTokenLifetime.
+type APNsSender struct {
+	client *http.Client
+	tokens tokenCache
+}
+
+// NewAPNsSender creates a sender using the client, or its own client when nil
+func NewAPNsSender(client *http.Client) *APNsSender {
+	return &APNsSender{client: pushClient(client)}
+}
+
+func (s *APNsSender) Options() []string {
+	return []string{APNsKeyAliasOption, APNsKeyIDOption, APNsTeamIDOption, APNsTopicOption, APNsTokenOption,
+		APNsTitleOption, APNsBodyOption, APNsPushTypeOption, APNsURLOption, APNsTimeoutOption}
+}
+
+func (s *APNsSender) Validate(endpoint artifacts.Endpoint) error {
+	for _, name := range []string{APNsKeyAliasOption, APNsKeyIDOption, APNsTeamIDOption, APNsTopicOption, APNsTokenOption} {
+		if !endpoint.HasOption(name) {
+			return fmt.Errorf("apns endpoint %s requires a keyAlias, a keyId, a teamId, a topic and a token", endpoint.Name)
+		}
+	}
+	return validatePushTimeout(endpoint, APNsTimeoutOption)
+}
+
+func (s *APNsSender) Send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+	options, err := pushOptions(endpoint, s.Options(), []string{APNsBodyOption}, msg)
+	if err != nil {
+		return err
+	}
+	body, err := notificationText(endpoint, APNsBodyOption, msg)
+	if err != nil {
+		return err
+	}
+	if options[APNsTokenOption] == "" {
+		return fmt.Errorf("endpoint %s: the device token is empty", endpoint.Name)
+	}
+	timeout, err := pushTimeout(options[APNsTimeoutOption])
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	server := options[APNsURLOption]
+	if server == "" {
+		server = defaultAPNsURL
+	}
+	pushType := options[APNsPushTypeOption]
+	if pushType == "" {
+		pushType = "alert"
+	}
+
+	alias, keyID, teamID := options[APNsKeyAliasOption], options[APNsKeyIDOption], options[APNsTeamIDOption]
+	token, err := s.tokens.get(alias+"\x00"+keyID+"\x00"+teamID, func() (accessToken, error) {
+		key, err := secrets.Default().PrivateKey(alias)
+		if err != nil {
+			return accessToken{}, err
+		}
+		now := time.Now()
+		value, err := signJWT(key, jose.ES256, keyID, map[string]any{"iss": teamID, "iat": now.Unix()})
+		if err != nil {
+			return accessToken{}, err
+		}
+		return accessToken{value: value, expires: now.Add(apnsTokenLifetime)}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"aps": map[string]any{"alert": map[string]string{"title": options[APNsTitleOption], "body": body}},
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	target := strings.TrimSuffix(server, "/") + "/3/device/" + url.PathEscape(options[APNsTokenOption])
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("apns-topic", options[APNsTopicOption])
+	req.Header.Set("apns-push-type", pushType)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var reply struct {
+			Reason string `json:"reason"`
+		}
+		json.NewDecoder(resp.Body).Decode(&reply)
+		return fmt.Errorf("endpoint %s: notification rejected with status %d: %s", endpoint.Name, resp.StatusCode, reply.Reason)
+	}
+	msg.Properties[NotificationIDProperty] = resp.Header.Get("apns-id")
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pkcs8PEM(t *testing.T, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func useSecret(t *testing.T, alias, value string) {
+	store, err := secrets.NewStore(secrets.Config{Entries: []secrets.Entry{{Alias: alias, Value: value}}}, "")
+	require.NoError(t, err)
+	secrets.SetDefault(store)
+	t.Cleanup(func() { secrets.SetDefault(&secrets.Store{}) })
+}
+
+func TestPushSenders_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		options  map[string]string
+		wantErr  string
+	}{
+		{name: "fcm token", protocol: artifacts.ProtocolFCM,
+			options: map[string]string{FCMCredentialsAliasOption: "fcm", FCMTokenOption: "device"}},
+		{name: "fcm token and topic", protocol: artifacts.ProtocolFCM,
+			options: map[string]string{FCMCredentialsAliasOption: "fcm", FCMTokenOption: "device", FCMTopicOption: "ops"},
+			wantErr: "requires exactly one of token and topic"},
+		{name: "fcm bad timeout", protocol: artifacts.ProtocolFCM,
+			options: map[string]string{FCMCredentialsAliasOption: "fcm", FCMTopicOption: "ops", FCMTimeoutOption: "-1s"},
+			wantErr: "fcm endpoint TestEP: timeout must be a positive duration"},
+		{name: "apns", protocol: artifacts.ProtocolAPNs, options: map[string]string{APNsKeyAliasOption: "apns",
+			APNsKeyIDOption: "K", APNsTeamIDOption: "T", APNsTopicOption: "com.example", APNsTokenOption: "device"}},
+		{name: "apns no topic", protocol: artifacts.ProtocolAPNs, options: map[string]string{APNsKeyAliasOption: "apns",
+			APNsKeyIDOption: "K", APNsTeamIDOption: "T", APNsTokenOption: "device"},
+			wantErr: "requires a keyAlias, a keyId, a teamId, a topic and a token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(optionEndpoint(tt.protocol, tt.options))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestFCMSender_Send(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	var tokenRequests int
+	var sent map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			require.NoError(t, r.ParseForm())
+			parsed, err := jwt.ParseSigned(r.Form.Get("assertion"), []jose.SignatureAlgorithm{jose.RS256})
+			require.NoError(t, err)
+			var claims map[string]any
+			require.NoError(t, parsed.Claims(&key.PublicKey, &claims))
+			assert.Equal(t, "synapse@example.iam.gserviceaccount.com", claims["iss"])
+			assert.Equal(t, fcmScope, claims["scope"])
+			w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600}`))
+		case "/v1/projects/alerts/messages:send":
+			assert.Equal(t, "Bearer ya29.token", r.Header.Get("Authorization"))
+			body, _ := io.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(body, &sent))
+			w.Write([]byte(`{"name":"projects/alerts/messages/0:1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	account, err := json.Marshal(map[string]string{
+		"project_id": "alerts", "client_email": "synapse@example.iam.gserviceaccount.com",
+		"private_key": pkcs8PEM(t, key), "token_uri": server.URL + "/token",
+	})
+	require.NoError(t, err)
+	useSecret(t, "fcm", string(account))
+
+	sender := NewFCMSender(nil)
+	ep := optionEndpoint(artifacts.ProtocolFCM, map[string]string{
+		FCMCredentialsAliasOption: "fcm",
+		FCMTopicOption:            "on-call",
+		FCMTitleOption:            "Order failed",
+		FCMURLOption:              server.URL + "/v1/projects/alerts/messages:send",
+	})
+	for i := 0; i < 2; i++ {
+		msg := synctx.CreateMsgContext()
+		msg.Message.RawPayload = []byte("Order 17 could not be shipped")
+		require.NoError(t, sender.Send(context.Background(), ep, msg))
+		assert.Equal(t, "projects/alerts/messages/0:1", msg.Properties[NotificationIDProperty])
+	}
+	assert.Equal(t, 1, tokenRequests, "the access token is reused")
+	assert.Equal(t, map[string]any{"message": map[string]any{
+		"topic":        "on-call",
+		"notification": map[string]any{"title": "Order failed", "body": "Order 17 could not be shipped"},
+	}}, sent)
+}
+
+func TestAPNsSender_Send(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	useSecret(t, "apns", pkcs8PEM(t, key))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/3/device/bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+			return
+		}
+		assert.Equal(t, "/3/device/abc123", r.URL.Path)
+		assert.Equal(t, "com.example.app", r.Header.Get("apns-topic"))
+		assert.Equal(t, "alert", r.Header.Get("apns-push-type"))
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "bearer ")
+		require.True(t, ok)
+		parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{jose.ES256})
+		require.NoError(t, err)
+		assert.Equal(t, "KEY123", parsed.Headers[0].KeyID)
+		var claims map[string]any
+		require.NoError(t, parsed.Claims(&key.PublicKey, &claims))
+		assert.Equal(t, "TEAM42", claims["iss"])
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"aps":{"alert":{"title":"","body":"Order 17 failed"}}}`, string(body))
+		w.Header().Set("apns-id", "EC1BF194-B3B2-424A-89A9-5A918A6E6B5C")
+	}))
+	defer server.Close()
+
+	options := map[string]string{
+		APNsKeyAliasOption: "apns", APNsKeyIDOption: "KEY123", APNsTeamIDOption: "TEAM42",
+		APNsTopicOption: "com.example.app", APNsTokenOption: `{{.Property "device"}}`,
+		APNsBodyOption: `Order {{.Property "orderId"}} failed`, APNsURLOption: server.URL,
+	}
+	sender := NewAPNsSender(nil)
+	msg := synctx.CreateMsgContext()
+	msg.Properties["device"] = "abc123"
+	msg.Properties["orderId"] = "17"
+	require.NoError(t, sender.Send(context.Background(), optionEndpoint(artifacts.ProtocolAPNs, options), msg))
+	assert.Equal(t, "EC1BF194-B3B2-424A-89A9-5A918A6E6B5C", msg.Properties[NotificationIDProperty])
+
+	msg.Properties["device"] = "bad"
+	err = sender.Send(context.Background(), optionEndpoint(artifacts.ProtocolAPNs, options), msg)
+	assert.EqualError(t, err, "endpoint TestEP: notification rejected with status 400: BadDeviceToken")
+}
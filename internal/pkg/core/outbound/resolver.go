@@ -0,0 +1,257 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNS preferences for hosts with both IPv4 and IPv6 addresses
+const (
+	PreferIPv4 = "ipv4"
+	PreferIPv6 = "ipv6"
+)
+
+// DNSConfig holds the [dns] section of deployment.toml
+type DNSConfig struct {
+	// CacheTTL keeps resolved addresses for this long; no caching when empty
+	CacheTTL string `koanf:"cacheTTL"`
+	// Prefer tries addresses of one family first, "ipv4" or "ipv6"
+	Prefer string `koanf:"prefer"`
+	// Hosts maps host names to the addresses used instead of DNS, like
+	// entries of /etc/hosts
+	Hosts map[string]string `koanf:"hosts"`
+}
+
+// Validate reports configuration errors in the [dns] section
+func (c DNSConfig) Validate() error {
+	if _, err := c.ttl(); err != nil {
+		return err
+	}
+	if c.Prefer != "" && c.Prefer != PreferIPv4 && c.Prefer != PreferIPv6 {
+		return fmt.Errorf("dns: prefer must be %s or %s, got '%s'", PreferIPv4, PreferIPv6, c.Prefer)
+	}
+	for host, address := range c.Hosts {
+		if _, err := netip.ParseAddr(address); err != nil {
+			return fmt.Errorf("dns: host %s must map to an IP address, got '%s'", host, address)
+		}
+	}
+	return nil
+}
+
+func (c DNSConfig) ttl() (time.Duration, error) {
+	if c.CacheTTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(c.CacheTTL)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("dns: cacheTTL must be a non-negative duration, got '%s'", c.CacheTTL)
+	}
+	return ttl, nil
+}
+
+// ParseHostOverrides reads host overrides written as "name=address" pairs
+// separated by commas, e.g. "inventory.internal=10.0.0.5, billing=10.0.0.6"
+func ParseHostOverrides(value string) (map[string]string, error) {
+	hosts := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, address, ok := strings.Cut(entry, "=")
+		host, address = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(address)
+		if !ok || host == "" {
+			return nil, fmt.Errorf("host override '%s' must be name=address", entry)
+		}
+		if _, err := netip.ParseAddr(address); err != nil {
+			return nil, fmt.Errorf("host override %s must map to an IP address, got '%s'", host, address)
+		}
+		hosts[host] = address
+	}
+	return hosts, nil
+}
+
+type hostOverridesKey struct{}
+
+// WithHostOverrides returns a context whose connections resolve the hosts to
+// the given addresses before any other source
+func WithHostOverrides(ctx context.Context, hosts map[string]string) context.Context {
+	return context.WithValue(ctx, hostOverridesKey{}, hosts)
+}
+
+type cachedAddresses struct {
+	addresses []string
+	expires   time.Time
+}
+
+// Resolver resolves the hosts of outbound connections from per-request
+// overrides, static hosts, a cache of earlier lookups and finally DNS. When
+// a lookup fails, expired cached addresses are used rather than failing the
+// call, which rides out short DNS outages.
+type Resolver struct {
+	ttl    time.Duration
+	prefer string
+	hosts  map[string]string
+	dialer *net.Dialer
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedAddresses
+}
+
+// NewResolver creates the resolver of config
+func NewResolver(config DNSConfig) (*Resolver, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	ttl, _ := config.ttl()
+	hosts := make(map[string]string, len(config.Hosts))
+	for host, address := range config.Hosts {
+		hosts[strings.ToLower(host)] = address
+	}
+	return &Resolver{
+		ttl:    ttl,
+		prefer: config.Prefer,
+		hosts:  hosts,
+		dialer: &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		lookup: net.DefaultResolver.LookupIPAddr,
+		now:    time.Now,
+		cache:  make(map[string]cachedAddresses),
+	}, nil
+}
+
+// Resolve returns the addresses of host in the order they are tried
+func (r *Resolver) Resolve(ctx context.Context, host string) ([]string, error) {
+	if _, err := netip.ParseAddr(host); err == nil {
+		return []string{host}, nil
+	}
+	name := strings.ToLower(host)
+	if overrides, ok := ctx.Value(hostOverridesKey{}).(map[string]string); ok {
+		if address, ok := overrides[name]; ok {
+			return []string{address}, nil
+		}
+	}
+	if address, ok := r.hosts[name]; ok {
+		return []string{address}, nil
+	}
+
+	r.mu.Lock()
+	cached, ok := r.cache[name]
+	r.mu.Unlock()
+	if ok && r.now().Before(cached.expires) {
+		return cached.addresses, nil
+	}
+	ips, err := r.lookup(ctx, host)
+	if err != nil || len(ips) == 0 {
+		if ok {
+			return cached.addresses, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("no addresses for %s", host)
+		}
+		return nil, err
+	}
+	addresses := r.order(ips)
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.cache[name] = cachedAddresses{addresses: addresses, expires: r.now().Add(r.ttl)}
+		r.mu.Unlock()
+	}
+	return addresses, nil
+}
+
+// order lists the addresses of the preferred family first, keeping the
+// order DNS returned within each family
+func (r *Resolver) order(ips []net.IPAddr) []string {
+	var first, rest []string
+	for _, ip := range ips {
+		address := ip.String()
+		isIPv4 := ip.IP.To4() != nil
+		if (r.prefer == PreferIPv4 && isIPv4) || (r.prefer == PreferIPv6 && !isIPv4) {
+			first = append(first, address)
+		} else {
+			rest = append(rest, address)
+		}
+	}
+	return append(first, rest...)
+}
+
+// DialContext connects to address, trying each resolved address of its host
+// in turn
+func (r *Resolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := r.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, ip := range addresses {
+		conn, err := r.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+var (
+	resolverMu     sync.RWMutex
+	activeResolver *Resolver
+	plainDialer    = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+)
+
+// SetResolver makes the HTTP sender resolve hosts with resolver; nil restores
+// plain DNS lookups
+func SetResolver(resolver *Resolver) {
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	activeResolver = resolver
+}
+
+// dialContext dials with the active resolver, or with one without cache and
+// static hosts when only the request overrides hosts
+func dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	resolverMu.RLock()
+	resolver := activeResolver
+	resolverMu.RUnlock()
+	if resolver == nil {
+		if _, ok := ctx.Value(hostOverridesKey{}).(map[string]string); !ok {
+			return plainDialer.DialContext(ctx, network, address)
+		}
+		resolver, _ = NewResolver(DNSConfig{})
+	}
+	return resolver.DialContext(ctx, network, address)
+}
@@ -0,0 +1,139 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  DNSConfig
+		wantErr string
+	}{
+		{name: "empty", config: DNSConfig{}},
+		{name: "full", config: DNSConfig{CacheTTL: "30s", Prefer: PreferIPv6, Hosts: map[string]string{"db": "::1"}}},
+		{name: "invalid ttl", config: DNSConfig{CacheTTL: "soon"}, wantErr: "dns: cacheTTL must be a non-negative duration, got 'soon'"},
+		{name: "unknown preference", config: DNSConfig{Prefer: "ipx"}, wantErr: "dns: prefer must be ipv4 or ipv6, got 'ipx'"},
+		{name: "host without address", config: DNSConfig{Hosts: map[string]string{"db": "db.internal"}}, wantErr: "dns: host db must map to an IP address, got 'db.internal'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	resolver, err := NewResolver(DNSConfig{CacheTTL: "1m", Prefer: PreferIPv6, Hosts: map[string]string{"Pinned.internal": "10.0.0.9"}})
+	require.NoError(t, err)
+	now := time.Now()
+	resolver.now = func() time.Time { return now }
+	lookups := 0
+	var lookupErr error
+	resolver.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups++
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}, {IP: net.ParseIP("fd00::1")}, {IP: net.ParseIP("10.0.0.2")}}, nil
+	}
+	ctx := context.Background()
+
+	addresses, err := resolver.Resolve(ctx, "backend.internal")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fd00::1", "10.0.0.1", "10.0.0.2"}, addresses)
+
+	_, err = resolver.Resolve(ctx, "backend.internal")
+	require.NoError(t, err)
+	assert.Equal(t, 1, lookups, "cached addresses are reused within the TTL")
+
+	now = now.Add(2 * time.Minute)
+	lookupErr = errors.New("no such host")
+	addresses, err = resolver.Resolve(ctx, "backend.internal")
+	require.NoError(t, err, "stale addresses are used while DNS fails")
+	assert.Equal(t, []string{"fd00::1", "10.0.0.1", "10.0.0.2"}, addresses)
+	assert.Equal(t, 2, lookups)
+
+	_, err = resolver.Resolve(ctx, "unknown.internal")
+	assert.EqualError(t, err, "no such host")
+
+	addresses, err = resolver.Resolve(ctx, "pinned.internal")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.9"}, addresses)
+
+	addresses, err = resolver.Resolve(WithHostOverrides(ctx, map[string]string{"pinned.internal": "10.0.0.7"}), "pinned.internal")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.7"}, addresses, "endpoint overrides win over static hosts")
+
+	addresses, err = resolver.Resolve(ctx, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1"}, addresses)
+	assert.Equal(t, 3, lookups)
+}
+
+func TestParseHostOverrides(t *testing.T) {
+	hosts, err := ParseHostOverrides(" Orders.internal=10.0.0.5, billing=::1 ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"orders.internal": "10.0.0.5", "billing": "::1"}, hosts)
+
+	_, err = ParseHostOverrides("orders.internal")
+	assert.EqualError(t, err, "host override 'orders.internal' must be name=address")
+	_, err = ParseHostOverrides("orders.internal=orders")
+	assert.EqualError(t, err, "host override orders.internal must map to an IP address, got 'orders'")
+}
+
+func TestHTTPSender_HostsOption(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer backend.Close()
+	_, port, err := net.SplitHostPort(backend.Listener.Addr().String())
+	require.NoError(t, err)
+
+	sender := NewHTTPSender(nil)
+	ep := endpoint("http", map[string]string{
+		HTTPURIOption:   "http://orders.invalid:" + port + "/",
+		HTTPHostsOption: "orders.invalid=127.0.0.1",
+	})
+	require.NoError(t, sender.Validate(ep))
+	msg := synctx.CreateMsgContext()
+	require.NoError(t, sender.Send(context.Background(), ep, msg))
+	assert.Equal(t, "orders.invalid:"+port, string(msg.Message.RawPayload))
+
+	ep = endpoint("http", map[string]string{HTTPURIOption: "http://orders.invalid/", HTTPHostsOption: "orders.invalid"})
+	assert.EqualError(t, sender.Validate(ep), "http endpoint TestEP: host override 'orders.invalid' must be name=address")
+}
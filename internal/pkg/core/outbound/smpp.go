@@ -0,0 +1,378 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/secrets"
+)
+
+// Options of <smpp> endpoint blocks, e.g.
+// <smpp url="smpps://smsc.example.com:3550" systemId="synapse" passwordAlias="smsc-password" from="ALERTS" to="{{.Property "phone"}}" text="Order {{.Property "orderId"}} failed"/>
+const (
+	SMPPURLOption           = "url"
+	SMPPSystemIDOption      = "systemId"
+	SMPPPasswordAliasOption = "passwordAlias"
+	SMPPSystemTypeOption    = "systemType"
+	SMPPFromOption          = "from"
+	SMPPToOption            = "to"
+	SMPPTextOption          = "text"
+	SMPPTimeoutOption       = "timeout"
+)
+
+// defaultSMPPTimeout bounds a whole session with the SMS centre when the
+// endpoint sets no timeout
+const defaultSMPPTimeout = 10 * time.Second
+
+// SMPP v3.4 command ids
+const (
+	smppGenericNack         = 0x80000000
+	smppBindTransmitter     = 0x00000002
+	smppBindTransmitterResp = 0x80000002
+	smppSubmitSM            = 0x00000004
+	smppSubmitSMResp        = 0x80000004
+	smppUnbind              = 0x00000006
+	smppUnbindResp          = 0x80000006
+	smppEnquireLink         = 0x00000015
+	smppEnquireLinkResp     = 0x80000015
+)
+
+const (
+	smppInterfaceVersion = 0x34
+	// smppMaxShortMessage is the longest text short_message carries; longer
+	// texts go in the message_payload TLV
+	smppMaxShortMessage   = 254
+	smppTagMessagePayload = 0x0424
+	smppCodingDefault     = 0x00
+	smppCodingUCS2        = 0x08
+	// smppMaxPDU guards against corrupt length fields
+	smppMaxPDU = 64 * 1024
+)
+
+// SMPPSender submits each message as an SMS to an SMS centre. Every send
+// binds as a transmitter, submits the text and unbinds, so no session is
+// kept between messages.
+type SMPPSender struct{}
+
+func NewSMPPSender() *SMPPSender {
+	return &SMPPSender{}
+}
+
+func (s *SMPPSender) Options() []string {
+	return []string{SMPPURLOption, SMPPSystemIDOption, SMPPPasswordAliasOption, SMPPSystemTypeOption,
+		SMPPFromOption, SMPPToOption, SMPPTextOption, SMPPTimeoutOption}
+}
+
+func (s *SMPPSender) Validate(endpoint artifacts.Endpoint) error {
+	if !endpoint.HasOption(SMPPURLOption) || !endpoint.HasOption(SMPPSystemIDOption) || !endpoint.HasOption(SMPPToOption) {
+		return fmt.Errorf("smpp endpoint %s requires a url, a systemId and a to address", endpoint.Name)
+	}
+	msg := synctx.CreateMsgContext()
+	if uri, err := endpoint.Option(SMPPURLOption, msg); err == nil && uri != "" {
+		if _, _, err := smppTarget(uri); err != nil {
+			return fmt.Errorf("smpp endpoint %s: %w", endpoint.Name, err)
+		}
+	}
+	if timeout, err := endpoint.Option(SMPPTimeoutOption, msg); err == nil && timeout != "" {
+		if _, err := parseTimeout(timeout); err != nil {
+			return fmt.Errorf("smpp endpoint %s: %w", endpoint.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *SMPPSender) Send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+	options := make(map[string]string)
+	for _, name := range s.Options() {
+		if name == SMPPTextOption {
+			continue
+		}
+		value, err := endpoint.Option(name, msg)
+		if err != nil {
+			return err
+		}
+		options[name] = value
+	}
+	text, err := notificationText(endpoint, SMPPTextOption, msg)
+	if err != nil {
+		return err
+	}
+	if options[SMPPToOption] == "" {
+		return fmt.Errorf("endpoint %s: the to address is empty", endpoint.Name)
+	}
+	address, secure, err := smppTarget(options[SMPPURLOption])
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	timeout := defaultSMPPTimeout
+	if options[SMPPTimeoutOption] != "" {
+		if timeout, err = parseTimeout(options[SMPPTimeoutOption]); err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+		}
+	}
+	var password string
+	if alias := options[SMPPPasswordAliasOption]; alias != "" {
+		secret, err := secrets.Default().Get(alias)
+		if err != nil {
+			return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+		}
+		password = string(secret)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := dialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: cannot connect to %s: %w", endpoint.Name, address, err)
+	}
+	if secure {
+		host, _, _ := net.SplitHostPort(address)
+		conn = tls.Client(conn, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	session := &smppSession{conn: conn, reader: bufio.NewReader(conn)}
+	if err := session.bind(options[SMPPSystemIDOption], password, options[SMPPSystemTypeOption]); err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	id, err := session.submit(options[SMPPFromOption], options[SMPPToOption], text)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	// The message is accepted once submit_sm_resp arrives, so a failed
+	// unbind does not fail the send
+	session.unbind()
+	msg.Properties[NotificationIDProperty] = id
+	return nil
+}
+
+// smppTarget parses smpp://host:port and smpps://host:port URLs. Port 2775
+// is used when none is given.
+func smppTarget(uri string) (string, bool, error) {
+	target, err := url.Parse(uri)
+	if err != nil || target.Host == "" {
+		return "", false, fmt.Errorf("invalid url '%s', expected smpp://host:port or smpps://host:port", uri)
+	}
+	if target.Scheme != "smpp" && target.Scheme != "smpps" {
+		return "", false, fmt.Errorf("url scheme must be smpp or smpps, got '%s'", target.Scheme)
+	}
+	port := target.Port()
+	if port == "" {
+		port = "2775"
+	}
+	return net.JoinHostPort(target.Hostname(), port), target.Scheme == "smpps", nil
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be a positive duration such as 10s, got '%s'", value)
+	}
+	return timeout, nil
+}
+
+// smppSession is one bound connection to an SMS centre
+type smppSession struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	sequence uint32
+}
+
+type smppPDU struct {
+	command  uint32
+	status   uint32
+	sequence uint32
+	body     []byte
+}
+
+func (s *smppSession) bind(systemID, password, systemType string) error {
+	var body bytes.Buffer
+	writeCString(&body, systemID)
+	writeCString(&body, password)
+	writeCString(&body, systemType)
+	// interface_version, addr_ton, addr_npi and an empty address_range
+	body.Write([]byte{smppInterfaceVersion, 0, 0, 0})
+	resp, err := s.call(smppBindTransmitter, body.Bytes(), smppBindTransmitterResp)
+	if err != nil {
+		return fmt.Errorf("bind failed: %w", err)
+	}
+	if resp.status != 0 {
+		return fmt.Errorf("bind rejected by the SMS centre with status 0x%08X", resp.status)
+	}
+	return nil
+}
+
+// submit sends the text as one short message and returns the message_id
+// the SMS centre assigned. Texts outside ASCII are sent as UCS-2.
+func (s *smppSession) submit(from, to, text string) (string, error) {
+	coding, message := encodeSMSText(text)
+	if len(message) > math.MaxUint16 {
+		return "", fmt.Errorf("message of %d bytes is longer than the %d bytes of a message_payload", len(message), math.MaxUint16)
+	}
+	var body bytes.Buffer
+	writeCString(&body, "") // service_type
+	ton, npi, source := smppAddress(from)
+	body.Write([]byte{ton, npi})
+	writeCString(&body, source)
+	ton, npi, destination := smppAddress(to)
+	body.Write([]byte{ton, npi})
+	writeCString(&body, destination)
+	// esm_class, protocol_id, priority_flag
+	body.Write([]byte{0, 0, 0})
+	writeCString(&body, "") // schedule_delivery_time
+	writeCString(&body, "") // validity_period
+	// registered_delivery, replace_if_present_flag, data_coding, sm_default_msg_id
+	body.Write([]byte{0, 0, coding, 0})
+	if len(message) <= smppMaxShortMessage {
+		body.WriteByte(byte(len(message)))
+		body.Write(message)
+	} else {
+		body.WriteByte(0)
+		binary.Write(&body, binary.BigEndian, uint16(smppTagMessagePayload))
+		binary.Write(&body, binary.BigEndian, uint16(len(message)))
+		body.Write(message)
+	}
+	resp, err := s.call(smppSubmitSM, body.Bytes(), smppSubmitSMResp)
+	if err != nil {
+		return "", fmt.Errorf("submit failed: %w", err)
+	}
+	if resp.status != 0 {
+		return "", fmt.Errorf("message rejected by the SMS centre with status 0x%08X", resp.status)
+	}
+	id, _, _ := bytes.Cut(resp.body, []byte{0})
+	return string(id), nil
+}
+
+func (s *smppSession) unbind() {
+	s.call(smppUnbind, nil, smppUnbindResp)
+}
+
+// call writes a request and waits for its response. Enquire links the SMS
+// centre sends meanwhile are answered.
+func (s *smppSession) call(command uint32, body []byte, response uint32) (smppPDU, error) {
+	s.sequence++
+	sequence := s.sequence
+	if err := s.write(smppPDU{command: command, sequence: sequence, body: body}); err != nil {
+		return smppPDU{}, err
+	}
+	for {
+		pdu, err := s.read()
+		if err != nil {
+			return smppPDU{}, err
+		}
+		switch {
+		case pdu.command == smppEnquireLink:
+			if err := s.write(smppPDU{command: smppEnquireLinkResp, sequence: pdu.sequence}); err != nil {
+				return smppPDU{}, err
+			}
+		case pdu.sequence != sequence:
+			continue
+		case pdu.command == smppGenericNack:
+			return smppPDU{}, fmt.Errorf("request refused with status 0x%08X", pdu.status)
+		case pdu.command != response:
+			return smppPDU{}, fmt.Errorf("unexpected response 0x%08X", pdu.command)
+		default:
+			return pdu, nil
+		}
+	}
+}
+
+func (s *smppSession) write(pdu smppPDU) error {
+	packet := make([]byte, 16, 16+len(pdu.body))
+	binary.BigEndian.PutUint32(packet[0:], uint32(16+len(pdu.body)))
+	binary.BigEndian.PutUint32(packet[4:], pdu.command)
+	binary.BigEndian.PutUint32(packet[8:], pdu.status)
+	binary.BigEndian.PutUint32(packet[12:], pdu.sequence)
+	_, err := s.conn.Write(append(packet, pdu.body...))
+	return err
+}
+
+func (s *smppSession) read() (smppPDU, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(s.reader, header); err != nil {
+		return smppPDU{}, err
+	}
+	length := binary.BigEndian.Uint32(header[0:])
+	if length < 16 || length > smppMaxPDU {
+		return smppPDU{}, fmt.Errorf("invalid PDU length %d", length)
+	}
+	pdu := smppPDU{
+		command:  binary.BigEndian.Uint32(header[4:]),
+		status:   binary.BigEndian.Uint32(header[8:]),
+		sequence: binary.BigEndian.Uint32(header[12:]),
+		body:     make([]byte, length-16),
+	}
+	if _, err := io.ReadFull(s.reader, pdu.body); err != nil {
+		return smppPDU{}, err
+	}
+	return pdu, nil
+}
+
+func writeCString(buffer *bytes.Buffer, value string) {
+	buffer.WriteString(value)
+	buffer.WriteByte(0)
+}
+
+// smppAddress returns the type of number, numbering plan and digits of an
+// address. Numbers with a leading + are international, other numbers are
+// left to the SMS centre, and names such as ALERTS are alphanumeric.
+func smppAddress(address string) (byte, byte, string) {
+	switch {
+	case address == "":
+		return 0, 0, ""
+	case strings.HasPrefix(address, "+"):
+		return 1, 1, address[1:]
+	case strings.Trim(address, "0123456789") == "":
+		return 0, 1, address
+	default:
+		return 5, 0, address
+	}
+}
+
+func encodeSMSText(text string) (byte, []byte) {
+	for _, r := range text {
+		if r > 0x7F {
+			var encoded []byte
+			for _, unit := range utf16.Encode([]rune(text)) {
+				encoded = binary.BigEndian.AppendUint16(encoded, unit)
+			}
+			return smppCodingUCS2, encoded
+		}
+	}
+	return smppCodingDefault, []byte(text)
+}
@@ -0,0 +1,168 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"math"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func optionEndpoint(protocol string, options map[string]string) artifacts.Endpoint {
+	ep := endpoint(protocol, nil)
+	for name, value := range options {
+		ep.Options[name] = templateOption(name, value)
+	}
+	return ep
+}
+
+// fakeSMSC accepts one session, answers an enquire link before the bind
+// response and records the PDUs it receives
+func fakeSMSC(t *testing.T, bindStatus uint32) (string, <-chan []smppPDU) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan []smppPDU, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		session := &smppSession{conn: conn, reader: bufio.NewReader(conn)}
+		var pdus []smppPDU
+		defer func() { received <- pdus }()
+		for {
+			pdu, err := session.read()
+			if err != nil {
+				return
+			}
+			pdus = append(pdus, pdu)
+			switch pdu.command {
+			case smppBindTransmitter:
+				session.write(smppPDU{command: smppEnquireLink, sequence: 99})
+				session.write(smppPDU{command: smppBindTransmitterResp, status: bindStatus, sequence: pdu.sequence, body: []byte("SMSC\x00")})
+			case smppSubmitSM:
+				session.write(smppPDU{command: smppSubmitSMResp, sequence: pdu.sequence, body: []byte("msg-42\x00")})
+			case smppUnbind:
+				session.write(smppPDU{command: smppUnbindResp, sequence: pdu.sequence})
+				return
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMPPSender_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]string
+		wantErr string
+	}{
+		{name: "valid", options: map[string]string{SMPPURLOption: "smpp://smsc", SMPPSystemIDOption: "synapse", SMPPToOption: "+94771234567"}},
+		{name: "no to", options: map[string]string{SMPPURLOption: "smpp://smsc", SMPPSystemIDOption: "synapse"},
+			wantErr: "requires a url, a systemId and a to address"},
+		{name: "bad scheme", options: map[string]string{SMPPURLOption: "http://smsc", SMPPSystemIDOption: "synapse", SMPPToOption: "1"},
+			wantErr: "url scheme must be smpp or smpps"},
+		{name: "bad timeout", options: map[string]string{SMPPURLOption: "smpp://smsc", SMPPSystemIDOption: "s", SMPPToOption: "1", SMPPTimeoutOption: "soon"},
+			wantErr: "timeout must be a positive duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(optionEndpoint(artifacts.ProtocolSMPP, tt.options))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSMPPSender_Send(t *testing.T) {
+	address, received := fakeSMSC(t, 0)
+	ep := optionEndpoint(artifacts.ProtocolSMPP, map[string]string{
+		SMPPURLOption:      "smpp://" + address,
+		SMPPSystemIDOption: "synapse",
+		SMPPFromOption:     "ALERTS",
+		SMPPToOption:       `{{.Property "phone"}}`,
+		SMPPTextOption:     `Order {{.Property "orderId"}} failed`,
+	})
+	msg := synctx.CreateMsgContext()
+	msg.Properties["phone"] = "+94771234567"
+	msg.Properties["orderId"] = "17"
+
+	require.NoError(t, Send(context.Background(), ep, msg))
+	assert.Equal(t, "msg-42", msg.Properties[NotificationIDProperty])
+
+	pdus := <-received
+	require.Len(t, pdus, 4)
+	assert.Equal(t, uint32(smppBindTransmitter), pdus[0].command)
+	assert.True(t, bytes.HasPrefix(pdus[0].body, []byte("synapse\x00")))
+	assert.Equal(t, uint32(smppEnquireLinkResp), pdus[1].command)
+	assert.Equal(t, uint32(99), pdus[1].sequence)
+	assert.Equal(t, uint32(smppSubmitSM), pdus[2].command)
+	assert.True(t, bytes.Contains(pdus[2].body, []byte("\x05\x00ALERTS\x00\x01\x0194771234567\x00")))
+	assert.True(t, bytes.HasSuffix(pdus[2].body, []byte("\x0fOrder 17 failed")))
+	assert.Equal(t, uint32(smppUnbind), pdus[3].command)
+}
+
+func TestSMPPSender_BindRejected(t *testing.T) {
+	address, _ := fakeSMSC(t, 0x0E)
+	ep := optionEndpoint(artifacts.ProtocolSMPP, map[string]string{
+		SMPPURLOption: "smpp://" + address, SMPPSystemIDOption: "synapse", SMPPToOption: "1234",
+	})
+	err := Send(context.Background(), ep, synctx.CreateMsgContext())
+	assert.EqualError(t, err, "endpoint TestEP: bind rejected by the SMS centre with status 0x0000000E")
+}
+
+func TestSMPPSender_MessageTooLong(t *testing.T) {
+	address, received := fakeSMSC(t, 0)
+	ep := optionEndpoint(artifacts.ProtocolSMPP, map[string]string{
+		SMPPURLOption: "smpp://" + address, SMPPSystemIDOption: "synapse", SMPPToOption: "1234",
+		SMPPTextOption: `{{.Property "text"}}`,
+	})
+	msg := synctx.CreateMsgContext()
+	msg.Properties["text"] = strings.Repeat("a", math.MaxUint16+1)
+	err := Send(context.Background(), ep, msg)
+	assert.EqualError(t, err, "endpoint TestEP: message of 65536 bytes is longer than the 65535 bytes of a message_payload")
+	pdus := <-received
+	require.Len(t, pdus, 2, "no submit_sm is sent")
+	assert.Equal(t, uint32(smppBindTransmitter), pdus[0].command)
+}
+
+func TestEncodeSMSText(t *testing.T) {
+	coding, text := encodeSMSText("alert")
+	assert.Equal(t, byte(smppCodingDefault), coding)
+	assert.Equal(t, []byte("alert"), text)
+
+	coding, text = encodeSMSText("é")
+	assert.Equal(t, byte(smppCodingUCS2), coding)
+	assert.Equal(t, []byte{0x00, 0xE9}, text)
+}
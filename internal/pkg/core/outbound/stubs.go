@@ -0,0 +1,329 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Stub modes
+const (
+	// StubRecord sends to the backends and saves every response
+	StubRecord = "record"
+	// StubReplay answers from the saved responses without calling backends
+	StubReplay = "replay"
+)
+
+// StubConfig holds the [stubs] section of deployment.toml
+type StubConfig struct {
+	Mode string `koanf:"mode"`
+	// Directory holds the recordings, relative to the conf directory unless
+	// absolute
+	Directory string `koanf:"directory"`
+	// Endpoints limits recording and replay to the named endpoints; all
+	// endpoints when empty
+	Endpoints []string `koanf:"endpoints"`
+	// MatchHeaders are request headers that tell recordings apart. Other
+	// headers, such as tokens and trace IDs, are ignored.
+	MatchHeaders []string `koanf:"matchHeaders"`
+	// Passthrough sends requests without a recording to the backend in replay
+	// mode, instead of failing them
+	Passthrough bool `koanf:"passthrough"`
+}
+
+// Validate reports configuration errors in the [stubs] section
+func (c StubConfig) Validate() error {
+	if c.Mode != StubRecord && c.Mode != StubReplay {
+		return fmt.Errorf("stubs: mode must be %s or %s, got '%s'", StubRecord, StubReplay, c.Mode)
+	}
+	if c.Directory == "" {
+		return fmt.Errorf("stubs: directory is required")
+	}
+	if c.Passthrough && c.Mode != StubReplay {
+		return fmt.Errorf("stubs: passthrough only applies to %s mode", StubReplay)
+	}
+	return nil
+}
+
+// Recording is a saved endpoint response and the request it answered
+type Recording struct {
+	Endpoint string    `json:"endpoint"`
+	Key      string    `json:"key"`
+	Recorded time.Time `json:"recorded"`
+	Request  struct {
+		Options map[string]string `json:"options,omitempty"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Payload stubPayload       `json:"payload"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status,omitempty"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Payload stubPayload       `json:"payload"`
+	} `json:"response"`
+}
+
+// stubPayload is a message body saved as text when it is UTF-8, so that
+// recordings can be read and edited, and as base64 otherwise
+type stubPayload struct {
+	ContentType string `json:"contentType,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Base64      string `json:"base64,omitempty"`
+}
+
+func newStubPayload(contentType string, payload []byte) stubPayload {
+	if utf8.Valid(payload) {
+		return stubPayload{ContentType: contentType, Text: string(payload)}
+	}
+	return stubPayload{ContentType: contentType, Base64: base64.StdEncoding.EncodeToString(payload)}
+}
+
+func (p stubPayload) bytes() ([]byte, error) {
+	if p.Base64 != "" {
+		return base64.StdEncoding.DecodeString(p.Base64)
+	}
+	return []byte(p.Text), nil
+}
+
+// Stubs records endpoint responses to disk, or replays them in place of the
+// backends, for offline development and deterministic end-to-end tests
+type Stubs struct {
+	config    StubConfig
+	directory string
+	mu        sync.Mutex
+}
+
+// OpenStubs prepares the recordings directory of config, resolving a relative
+// directory against confPath
+func OpenStubs(config StubConfig, confPath string) (*Stubs, error) {
+	directory := config.Directory
+	if !filepath.IsAbs(directory) {
+		directory = filepath.Join(confPath, directory)
+	}
+	if config.Mode == StubRecord {
+		if err := os.MkdirAll(directory, 0o755); err != nil {
+			return nil, fmt.Errorf("cannot create stubs directory: %w", err)
+		}
+	} else if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("stubs directory %s does not exist, record responses first", directory)
+	}
+	return &Stubs{config: config, directory: directory}, nil
+}
+
+// Mode returns the mode the stubs are in
+func (s *Stubs) Mode() string {
+	return s.config.Mode
+}
+
+// applies reports whether the endpoint is recorded or replayed
+func (s *Stubs) applies(endpoint artifacts.Endpoint) bool {
+	return len(s.config.Endpoints) == 0 || slices.Contains(s.config.Endpoints, endpoint.Name)
+}
+
+// request renders what identifies a request to the endpoint: its options,
+// the configured headers and the payload
+func (s *Stubs) request(endpoint artifacts.Endpoint, msg *synctx.MsgContext) (options, headers map[string]string, key string, err error) {
+	options = make(map[string]string, len(endpoint.Options))
+	for name := range endpoint.Options {
+		if options[name], err = endpoint.Option(name, msg); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	headers = make(map[string]string)
+	for _, name := range s.config.MatchHeaders {
+		for header, value := range msg.Headers {
+			if strings.EqualFold(header, name) {
+				headers[strings.ToLower(name)] = value
+			}
+		}
+	}
+
+	hash := sha256.New()
+	for _, values := range []map[string]string{options, headers} {
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			fmt.Fprintf(hash, "%s=%s\n", name, values[name])
+		}
+		hash.Write([]byte{0})
+	}
+	hash.Write(msg.Message.RawPayload)
+	return options, headers, hex.EncodeToString(hash.Sum(nil))[:32], nil
+}
+
+func (s *Stubs) path(endpoint, key string) string {
+	return filepath.Join(s.directory, url.PathEscape(endpoint), key+".json")
+}
+
+// send records or replays the exchange with the endpoint, calling send for
+// exchanges that reach the backend
+func (s *Stubs) send(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext,
+	send func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error) error {
+	options, headers, key, err := s.request(endpoint, msg)
+	if err != nil {
+		return err
+	}
+	if s.config.Mode == StubReplay {
+		replayed, err := s.replay(endpoint, key, msg)
+		switch {
+		case err != nil:
+			return err
+		case replayed:
+			return nil
+		case s.config.Passthrough:
+			return send(ctx, endpoint, msg)
+		}
+		return fmt.Errorf("endpoint %s: no recorded response for request %s", endpoint.Name, key)
+	}
+
+	recording := &Recording{Endpoint: endpoint.Name, Key: key, Recorded: time.Now().UTC()}
+	recording.Request.Options = options
+	recording.Request.Headers = headers
+	recording.Request.Payload = newStubPayload(msg.Message.ContentType, msg.Message.RawPayload)
+	if err := send(ctx, endpoint, msg); err != nil {
+		return err
+	}
+	recording.Response.Status, _ = msg.Properties[artifacts.HTTPStatusProperty].(int)
+	recording.Response.Headers = maps.Clone(msg.Headers)
+	recording.Response.Payload = newStubPayload(msg.Message.ContentType, msg.Message.RawPayload)
+	return s.save(recording)
+}
+
+// replay answers msg from the recording of key, reporting false when there
+// is none
+func (s *Stubs) replay(endpoint artifacts.Endpoint, key string, msg *synctx.MsgContext) (bool, error) {
+	data, err := os.ReadFile(s.path(endpoint.Name, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("endpoint %s: cannot read recording: %w", endpoint.Name, err)
+	}
+	var recording Recording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return false, fmt.Errorf("endpoint %s: invalid recording %s: %w", endpoint.Name, key, err)
+	}
+	payload, err := recording.Response.Payload.bytes()
+	if err != nil {
+		return false, fmt.Errorf("endpoint %s: invalid recording %s: %w", endpoint.Name, key, err)
+	}
+	msg.Message.RawPayload = payload
+	msg.Message.ContentType = recording.Response.Payload.ContentType
+	msg.Message.Attachments = nil
+	msg.Headers = make(map[string]string, len(recording.Response.Headers))
+	maps.Copy(msg.Headers, recording.Response.Headers)
+	if recording.Response.Status != 0 {
+		msg.Properties[artifacts.HTTPStatusProperty] = recording.Response.Status
+	}
+	return true, nil
+}
+
+// save writes the recording, replacing an earlier one of the same request
+func (s *Stubs) save(recording *Recording) error {
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.path(recording.Endpoint, recording.Key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cannot save recording: %w", err)
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o644); err != nil {
+		return fmt.Errorf("cannot save recording: %w", err)
+	}
+	return os.Rename(temp, path)
+}
+
+// StubEntry describes a saved recording in listings
+type StubEntry struct {
+	Endpoint string    `json:"endpoint"`
+	Key      string    `json:"key"`
+	Recorded time.Time `json:"recorded"`
+	Status   int       `json:"status,omitempty"`
+}
+
+// Recordings lists the saved recordings, sorted by endpoint and time
+func (s *Stubs) Recordings() ([]StubEntry, error) {
+	files, err := filepath.Glob(filepath.Join(s.directory, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]StubEntry, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var recording Recording
+		if err := json.Unmarshal(data, &recording); err != nil {
+			return nil, fmt.Errorf("invalid recording %s: %w", file, err)
+		}
+		entries = append(entries, StubEntry{Endpoint: recording.Endpoint, Key: recording.Key,
+			Recorded: recording.Recorded, Status: recording.Response.Status})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Endpoint != entries[j].Endpoint {
+			return entries[i].Endpoint < entries[j].Endpoint
+		}
+		return entries[i].Recorded.Before(entries[j].Recorded)
+	})
+	return entries, nil
+}
+
+var (
+	stubsMu     sync.RWMutex
+	activeStubs *Stubs
+)
+
+// SetStubs records or replays the responses of every endpoint sent to with
+// Send, or stops doing so when stubs is nil
+func SetStubs(stubs *Stubs) {
+	stubsMu.Lock()
+	defer stubsMu.Unlock()
+	activeStubs = stubs
+}
+
+// ActiveStubs returns the stubs in use, or nil when endpoints are called
+// normally
+func ActiveStubs() *Stubs {
+	stubsMu.RLock()
+	defer stubsMu.RUnlock()
+	return activeStubs
+}
@@ -0,0 +1,72 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package outbound
+
+import (
+	"log/slog"
+	"maps"
+	"sync/atomic"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+// maxWirePayload bounds the bytes of a payload written to the wire log
+const maxWirePayload = 4096
+
+// wireLogging is switched on by profiles such as dev
+var wireLogging atomic.Bool
+
+// SetWireLogging logs every message sent with Send and the reply to it,
+// headers and payload included. Credentials in headers are logged as well,
+// so it is meant for development only.
+func SetWireLogging(enabled bool) {
+	wireLogging.Store(enabled)
+}
+
+// WireLogging reports whether messages sent to endpoints are logged
+func WireLogging() bool {
+	return wireLogging.Load()
+}
+
+// logWire writes a message exchanged with endpoint to the wire logger.
+// direction is ">>" for requests and "<<" for replies.
+func logWire(direction string, endpoint artifacts.Endpoint, msg *synctx.MsgContext, err error) {
+	payload := msg.Message.RawPayload
+	truncated := len(payload) > maxWirePayload
+	if truncated {
+		payload = payload[:maxWirePayload]
+	}
+	attrs := []any{
+		slog.String("endpoint", endpoint.Name),
+		slog.String("protocol", endpoint.Protocol),
+		slog.Any("headers", maps.Clone(msg.Headers)),
+		slog.String("contentType", msg.Message.ContentType),
+		slog.String("payload", string(payload)),
+	}
+	if truncated {
+		attrs = append(attrs, slog.Int("size", len(msg.Message.RawPayload)))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	loggerfactory.GetLogger("wire", nil).Info(direction+" "+endpoint.Name, attrs...)
+}
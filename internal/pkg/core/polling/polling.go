@@ -0,0 +1,207 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package polling tracks the health of polling inbound endpoints: how long
+// polls take, how many messages they fetch, how far processing lags behind
+// the source and when the endpoint last succeeded. The figures are exported
+// as metrics and kept per endpoint for the admin API.
+package polling
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+)
+
+// Metric names
+const (
+	PollDurationMetric  = "synapse_inbound_poll_duration_seconds"
+	BatchSizeMetric     = "synapse_inbound_poll_batch_size"
+	FailuresMetric      = "synapse_inbound_poll_failures"
+	LastSuccessMetric   = "synapse_inbound_last_success_timestamp_seconds"
+	ProcessingLagMetric = "synapse_inbound_processing_lag_seconds"
+	ConsumerLagMetric   = "synapse_inbound_consumer_lag_messages"
+)
+
+// Stages a failure is counted against
+const (
+	StagePoll    = "poll"
+	StageMessage = "message"
+)
+
+// BatchBuckets are the upper bounds of the batch size histogram
+var BatchBuckets = []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// Status is the admin view of a polling endpoint
+type Status struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+
+	Polls            uint64        `json:"polls"`
+	PollFailures     uint64        `json:"pollFailures"`
+	Messages         uint64        `json:"messages"`
+	MessageFailures  uint64        `json:"messageFailures"`
+	LastPoll         time.Time     `json:"lastPoll,omitzero"`
+	LastPollDuration time.Duration `json:"lastPollDuration"`
+	LastBatchSize    int           `json:"lastBatchSize"`
+	LastSuccess      time.Time     `json:"lastSuccess,omitzero"`
+	LastError        string        `json:"lastError,omitempty"`
+	// ProcessingLag is how long the last message waited at the source, e.g.
+	// since a file was written or a record was produced
+	ProcessingLag time.Duration `json:"processingLag"`
+	// ConsumerLag is the number of messages waiting at the source, reported by
+	// brokers that know it; -1 when unknown
+	ConsumerLag int64 `json:"consumerLag"`
+}
+
+// Stats records the polls of one inbound endpoint
+type Stats struct {
+	registry *metrics.Registry
+	now      func() time.Time
+
+	mu     sync.Mutex
+	status Status
+}
+
+// RecordPoll records a poll that started at the given time and fetched
+// batchSize messages, or failed with err
+func (s *Stats) RecordPoll(started time.Time, batchSize int, err error) {
+	now := s.now()
+	duration := now.Sub(started)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry.Histogram(PollDurationMetric, "Duration of inbound endpoint polls.", nil, "inbound", "protocol").
+		Observe(duration.Seconds(), "", s.status.Name, s.status.Protocol)
+	s.status.Polls++
+	s.status.LastPoll = now
+	s.status.LastPollDuration = duration
+	if err != nil {
+		s.status.PollFailures++
+		s.status.LastError = err.Error()
+		s.registry.Counter(FailuresMetric, "Failed inbound endpoint polls and messages.", "inbound", "protocol", "stage").
+			Inc(s.status.Name, s.status.Protocol, StagePoll)
+		return
+	}
+	s.status.LastBatchSize = batchSize
+	s.registry.Histogram(BatchSizeMetric, "Messages fetched by inbound endpoint polls.", BatchBuckets, "inbound", "protocol").
+		Observe(float64(batchSize), "", s.status.Name, s.status.Protocol)
+	s.succeededLocked(now)
+}
+
+// RecordMessage records the mediation of a message produced at the source at
+// the given time, zero when unknown
+func (s *Stats) RecordMessage(produced time.Time, err error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Messages++
+	if !produced.IsZero() {
+		s.status.ProcessingLag = max(now.Sub(produced), 0)
+		s.registry.Gauge(ProcessingLagMetric, "seconds", "Time the last message waited at the source of an inbound endpoint.", "inbound", "protocol").
+			Set(s.status.ProcessingLag.Seconds(), s.status.Name, s.status.Protocol)
+	}
+	if err != nil {
+		s.status.MessageFailures++
+		s.status.LastError = err.Error()
+		s.registry.Counter(FailuresMetric, "Failed inbound endpoint polls and messages.", "inbound", "protocol", "stage").
+			Inc(s.status.Name, s.status.Protocol, StageMessage)
+		return
+	}
+	s.succeededLocked(now)
+}
+
+// SetConsumerLag records the number of messages waiting at the source
+func (s *Stats) SetConsumerLag(lag int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.ConsumerLag = lag
+	s.registry.Gauge(ConsumerLagMetric, "", "Messages waiting at the source of an inbound endpoint.", "inbound", "protocol").
+		Set(float64(lag), s.status.Name, s.status.Protocol)
+}
+
+func (s *Stats) succeededLocked(now time.Time) {
+	s.status.LastSuccess = now
+	s.registry.Gauge(LastSuccessMetric, "seconds", "Time of the last successful poll or message of an inbound endpoint.", "inbound", "protocol").
+		Set(float64(now.UnixMilli())/1000, s.status.Name, s.status.Protocol)
+}
+
+// Status returns a copy of the endpoint's figures
+func (s *Stats) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Registry holds the stats of every polling endpoint
+type Registry struct {
+	metrics *metrics.Registry
+	now     func() time.Time
+
+	mu    sync.Mutex
+	stats map[string]*Stats
+}
+
+func NewRegistry(metricsRegistry *metrics.Registry) *Registry {
+	return &Registry{metrics: metricsRegistry, now: time.Now, stats: make(map[string]*Stats)}
+}
+
+// For returns the stats of the named endpoint, creating them on first use so a
+// redeployed endpoint keeps its history
+func (r *Registry) For(name, protocol string) *Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.stats[name]
+	if !ok {
+		stats = &Stats{registry: r.metrics, now: r.now, status: Status{Name: name, ConsumerLag: -1}}
+		r.stats[name] = stats
+	}
+	stats.mu.Lock()
+	stats.status.Protocol = protocol
+	stats.mu.Unlock()
+	return stats
+}
+
+// Remove forgets an undeployed endpoint
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.stats, name)
+}
+
+// Statuses returns the figures of every endpoint ordered by name
+func (r *Registry) Statuses() []Status {
+	r.mu.Lock()
+	statuses := make([]Status, 0, len(r.stats))
+	for _, stats := range r.stats {
+		statuses = append(statuses, stats.Status())
+	}
+	r.mu.Unlock()
+	slices.SortFunc(statuses, func(a, b Status) int { return strings.Compare(a.Name, b.Name) })
+	return statuses
+}
+
+var defaultRegistry = NewRegistry(metrics.Default())
+
+// Default returns the registry the runtime's inbound endpoints report to
+func Default() *Registry {
+	return defaultRegistry
+}
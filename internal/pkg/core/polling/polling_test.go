@@ -0,0 +1,84 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package polling
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	registry := NewRegistry(metricsRegistry)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	registry.now = func() time.Time { return now }
+
+	stats := registry.For("orders", "file")
+	assert.Same(t, stats, registry.For("orders", "file"))
+	assert.Equal(t, int64(-1), stats.Status().ConsumerLag)
+
+	stats.RecordPoll(now.Add(-200*time.Millisecond), 3, nil)
+	stats.RecordMessage(now.Add(-5*time.Second), nil)
+	stats.RecordMessage(now.Add(-2*time.Second), errors.New("mediation failed"))
+	stats.RecordPoll(now.Add(-time.Second), 0, errors.New("directory not found"))
+	stats.SetConsumerLag(42)
+
+	status := stats.Status()
+	assert.Equal(t, uint64(2), status.Polls)
+	assert.Equal(t, uint64(1), status.PollFailures)
+	assert.Equal(t, uint64(2), status.Messages)
+	assert.Equal(t, uint64(1), status.MessageFailures)
+	assert.Equal(t, time.Second, status.LastPollDuration)
+	assert.Equal(t, 3, status.LastBatchSize)
+	assert.Equal(t, 2*time.Second, status.ProcessingLag)
+	assert.Equal(t, int64(42), status.ConsumerLag)
+	assert.Equal(t, now, status.LastSuccess)
+	assert.Equal(t, "directory not found", status.LastError)
+
+	assert.Equal(t, uint64(1), metricsRegistry.Counter(FailuresMetric, "").Value("orders", "file", StagePoll))
+	assert.Equal(t, uint64(1), metricsRegistry.Counter(FailuresMetric, "").Value("orders", "file", StageMessage))
+	assert.Equal(t, 2.0, metricsRegistry.Gauge(ProcessingLagMetric, "", "").Value("orders", "file"))
+	assert.Equal(t, float64(now.Unix()), metricsRegistry.Gauge(LastSuccessMetric, "", "").Value("orders", "file"))
+
+	var out bytes.Buffer
+	metricsRegistry.WriteOpenMetrics(&out)
+	assert.Contains(t, out.String(), `synapse_inbound_poll_duration_seconds_count{inbound="orders",protocol="file"} 2`)
+	assert.Contains(t, out.String(), `synapse_inbound_poll_batch_size_bucket{inbound="orders",protocol="file",le="5"} 1`)
+	assert.Contains(t, out.String(), `synapse_inbound_consumer_lag_messages{inbound="orders",protocol="file"} 42`)
+}
+
+func TestRegistry_Statuses(t *testing.T) {
+	registry := NewRegistry(metrics.NewRegistry())
+	registry.For("invoices", "file")
+	registry.For("audit", "file")
+	registry.For("orders", "file")
+	registry.Remove("orders")
+
+	statuses := registry.Statuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "audit", statuses[0].Name)
+	assert.Equal(t, "invoices", statuses[1].Name)
+}
@@ -0,0 +1,162 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package priority orders stored messages for store-and-forward processing.
+// Higher priority messages are forwarded first and messages of the same
+// priority in the order they were stored. So that a steady flow of urgent
+// messages cannot hold back the others forever, a waiting message gains one
+// priority level for every aging interval it waits.
+package priority
+
+import (
+	"container/heap"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+)
+
+// Priorities range from Lowest to Highest, as for JMS messages
+const (
+	Lowest  = 0
+	Default = 4
+	Highest = 9
+)
+
+// Clamp bounds a priority to the range Lowest to Highest
+func Clamp(priority int) int {
+	return min(max(priority, Lowest), Highest)
+}
+
+// Extract evaluates the priority expression of a message over env. A result
+// that is not a number, or a failing expression, yields Default so a message
+// is never held back by a malformed priority.
+func Extract(program *expression.Program, env map[string]any) int {
+	if program == nil {
+		return Default
+	}
+	value, err := program.Run(env)
+	if err != nil {
+		return Default
+	}
+	var number float64
+	switch v := expression.Normalize(value).(type) {
+	case float64:
+		number = v
+	case string:
+		if number, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+			return Default
+		}
+	default:
+		return Default
+	}
+	if math.IsNaN(number) {
+		return Default
+	}
+	return int(math.Round(min(max(number, Lowest), Highest)))
+}
+
+// Queue holds values by priority. It is safe for concurrent use.
+type Queue[T any] struct {
+	// aging is how long a value waits to gain a priority level; 0 disables
+	// aging
+	aging time.Duration
+	start time.Time
+	now   func() time.Time
+
+	mu    sync.Mutex
+	items items[T]
+	seq   uint64
+}
+
+// NewQueue creates a queue whose values gain a priority level for every aging
+// interval they wait. An aging of 0 orders values by priority alone.
+func NewQueue[T any](aging time.Duration) *Queue[T] {
+	return &Queue[T]{aging: aging, start: time.Now(), now: time.Now}
+}
+
+// Push adds a value with a priority, which is clamped to Lowest to Highest
+func (q *Queue[T]) Push(value T, priority int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq++
+	item := &item[T]{value: value, priority: Clamp(priority), seq: q.seq}
+	if q.aging > 0 {
+		// A value that waited one aging interval ranks with values pushed
+		// now at one level above it. Every value ages at the same rate, so
+		// the ranking fixed at push time stays correct.
+		item.rank = float64(item.priority) - float64(q.now().Sub(q.start))/float64(q.aging)
+	} else {
+		item.rank = float64(item.priority)
+	}
+	heap.Push(&q.items, item)
+}
+
+// Pop removes and returns the value to forward next
+func (q *Queue[T]) Pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return heap.Pop(&q.items).(*item[T]).value, true
+}
+
+// Len returns the number of values in the queue
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+type item[T any] struct {
+	value    T
+	priority int
+	rank     float64
+	// seq keeps values of equal rank in the order they were pushed
+	seq uint64
+}
+
+// items implements heap.Interface, highest rank first
+type items[T any] []*item[T]
+
+func (h items[T]) Len() int { return len(h) }
+
+func (h items[T]) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank > h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h items[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *items[T]) Push(x any) { *h = append(*h, x.(*item[T])) }
+
+func (h *items[T]) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return last
+}
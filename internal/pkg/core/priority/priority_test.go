@@ -0,0 +1,97 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package priority
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		payload    any
+		want       int
+	}{
+		{name: "number", expression: "payload.priority", payload: map[string]any{"priority": 7.0}, want: 7},
+		{name: "numeric string", expression: "payload.priority", payload: map[string]any{"priority": " 2 "}, want: 2},
+		{name: "rounded", expression: "payload.priority", payload: map[string]any{"priority": 6.6}, want: 7},
+		{name: "clamped high", expression: "payload.priority", payload: map[string]any{"priority": 1e30}, want: Highest},
+		{name: "clamped low", expression: "payload.priority", payload: map[string]any{"priority": -3.0}, want: Lowest},
+		{name: "computed", expression: "payload.tier == 'gold' ? 9 : 1", payload: map[string]any{"tier": "gold"}, want: 9},
+		{name: "missing", expression: "payload.priority", payload: map[string]any{}, want: Default},
+		{name: "not a number", expression: "payload.priority", payload: map[string]any{"priority": "urgent"}, want: Default},
+		{name: "failing", expression: "payload.priority - 1", payload: map[string]any{"priority": "x"}, want: Default},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := expression.Compile(tt.expression, "payload")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, Extract(program, map[string]any{"payload": tt.payload}))
+		})
+	}
+	assert.Equal(t, Default, Extract(nil, nil))
+}
+
+func TestQueue_Order(t *testing.T) {
+	queue := NewQueue[string](0)
+	queue.Push("low-1", 1)
+	queue.Push("high-1", 9)
+	queue.Push("default", Default)
+	queue.Push("high-2", 9)
+	queue.Push("low-2", -5)
+	assert.Equal(t, 5, queue.Len())
+
+	var order []string
+	for {
+		value, ok := queue.Pop()
+		if !ok {
+			break
+		}
+		order = append(order, value)
+	}
+	assert.Equal(t, []string{"high-1", "high-2", "default", "low-1", "low-2"}, order)
+}
+
+func TestQueue_Aging(t *testing.T) {
+	queue := NewQueue[string](time.Minute)
+	now := queue.start
+	queue.now = func() time.Time { return now }
+
+	queue.Push("old-low", 1)
+	now = now.Add(3 * time.Minute)
+	queue.Push("new-medium", 3)
+	queue.Push("new-high", 5)
+
+	// After 3 minutes the low message ranks as 4: behind 5, ahead of 3
+	next, _ := queue.Pop()
+	assert.Equal(t, "new-high", next)
+	next, _ = queue.Pop()
+	assert.Equal(t, "old-low", next, "a waiting message is not starved")
+	next, _ = queue.Pop()
+	assert.Equal(t, "new-medium", next)
+	_, ok := queue.Pop()
+	assert.False(t, ok)
+}
@@ -0,0 +1,87 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package problem
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Config is the [errors] section of deployment.toml: the error handling of
+// APIs that do not define their own
+type Config struct {
+	// DefaultFaultSequence names the deployed sequence that runs when a
+	// resource without a fault sequence fails
+	DefaultFaultSequence string `koanf:"default_fault_sequence"`
+	// Templates render error responses by status code, e.g. "404"
+	Templates map[string]TemplateConfig `koanf:"templates"`
+}
+
+// TemplateConfig is an error template as written in configuration
+type TemplateConfig struct {
+	ContentType string `koanf:"content_type"`
+	Body        string `koanf:"body"`
+}
+
+func (c Config) Validate() error {
+	_, err := c.StatusTemplates()
+	return err
+}
+
+// StatusTemplates compiles the configured templates
+func (c Config) StatusTemplates() (StatusTemplates, error) {
+	templates := make(StatusTemplates, len(c.Templates))
+	for key, tc := range c.Templates {
+		status, err := strconv.Atoi(key)
+		if err != nil || status < 400 || status > 599 {
+			return nil, fmt.Errorf("errors: template key must be an error status code, got '%s'", key)
+		}
+		t, err := ParseTemplate(tc.ContentType, strings.TrimSpace(tc.Body))
+		if err != nil {
+			return nil, fmt.Errorf("errors: template %d: %w", status, err)
+		}
+		templates[status] = t
+	}
+	return templates, nil
+}
+
+// StatusTemplates are the error templates of each status code, used for
+// responses of APIs without an error template of their own
+type StatusTemplates map[int]*Template
+
+type statusTemplatesKey struct{}
+
+// WithStatusTemplates attaches the gateway's error templates to a request
+// context
+func WithStatusTemplates(ctx context.Context, templates StatusTemplates) context.Context {
+	return context.WithValue(ctx, statusTemplatesKey{}, templates)
+}
+
+// templateFor returns the template of the API, or the gateway's template for
+// the status
+func templateFor(ctx context.Context, status int) *Template {
+	if t := TemplateFromContext(ctx); t != nil {
+		return t
+	}
+	templates, _ := ctx.Value(statusTemplatesKey{}).(StatusTemplates)
+	return templates[status]
+}
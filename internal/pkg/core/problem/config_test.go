@@ -0,0 +1,75 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package problem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_StatusTemplates(t *testing.T) {
+	config := Config{Templates: map[string]TemplateConfig{
+		"404": {Body: `{"error": "not_found", "detail": {{json .Detail}}}`},
+		"504": {ContentType: "application/xml", Body: `<error>{{.Title}}</error>`},
+	}}
+	require.NoError(t, config.Validate())
+	templates, err := config.StatusTemplates()
+	require.NoError(t, err)
+	assert.Len(t, templates, 2)
+	assert.Equal(t, "application/json", templates[http.StatusNotFound].ContentType)
+
+	request := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	request = request.WithContext(WithStatusTemplates(request.Context(), templates))
+	rec := httptest.NewRecorder()
+	Write(rec, request, http.StatusGatewayTimeout, "")
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `<error>Gateway Timeout</error>`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	Write(rec, request, http.StatusTooManyRequests, "Slow down")
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"), "statuses without a template are problem+json")
+
+	api, err := ParseTemplate("text/plain", `{{.Status}}`)
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	Write(rec, request.WithContext(WithTemplate(request.Context(), api)), http.StatusGatewayTimeout, "")
+	assert.Equal(t, "504", rec.Body.String(), "the API's template takes precedence")
+
+	tests := []struct {
+		name    string
+		key     string
+		body    string
+		wantErr string
+	}{
+		{name: "not a status", key: "notFound", body: `{{.Title}}`, wantErr: "errors: template key must be an error status code, got 'notFound'"},
+		{name: "not an error", key: "302", body: `{{.Title}}`, wantErr: "got '302'"},
+		{name: "bad template", key: "500", body: `{{.Message}}`, wantErr: "errors: template 500: invalid error template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Config{Templates: map[string]TemplateConfig{tt.key: {Body: tt.body}}}.Validate()
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package problem writes error responses as RFC 7807 problem details, or in
+// the format of an API's error template.
+package problem
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/apache/synapse-go/internal/pkg/core/tracing"
+)
+
+const (
+	// ContentType is the media type of RFC 7807 problem details
+	ContentType = "application/problem+json"
+	// CorrelationHeader carries the ID that ties an error response to the logs
+	CorrelationHeader = "X-Correlation-ID"
+	// DefaultType is the problem type when the status code says it all
+	DefaultType = "about:blank"
+)
+
+// Problem is the body of an error response
+type Problem struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	Detail        string `json:"detail,omitempty"`
+	Instance      string `json:"instance,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+}
+
+// New describes an error response to r
+func New(r *http.Request, status int, detail string) Problem {
+	return Problem{
+		Type:          DefaultType,
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        detail,
+		Instance:      r.URL.Path,
+		CorrelationID: CorrelationID(r),
+	}
+}
+
+// CorrelationID returns the correlation ID sent by the client, the trace ID of
+// the request, or a new random ID, in that order
+func CorrelationID(r *http.Request) string {
+	if id := r.Header.Get(CorrelationHeader); id != "" {
+		return id
+	}
+	if id := tracing.TraceID(r.Context()); id != "" {
+		return id
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Write sends an error response in the format of the API's template, or of
+// the gateway's template for the status, or as problem+json
+func Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	p := New(r, status, detail)
+	contentType := ContentType
+	var body []byte
+	if t := templateFor(r.Context(), status); t != nil {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, p); err == nil {
+			contentType = t.ContentType
+			body = buf.Bytes()
+		}
+	}
+	if body == nil {
+		body, _ = json.Marshal(p)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set(CorrelationHeader, p.CorrelationID)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// Template renders error responses in an API specific format. The template
+// receives the Problem and may use the json function to quote strings.
+type Template struct {
+	ContentType string
+	template    *template.Template
+}
+
+// ParseTemplate compiles an error template. The content type defaults to
+// application/json.
+func ParseTemplate(contentType, body string) (*Template, error) {
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	t, err := template.New("error").Funcs(template.FuncMap{"json": quoteJSON}).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid error template: %w", err)
+	}
+	// Catch references to fields a Problem does not have before serving traffic
+	if err := t.Execute(&bytes.Buffer{}, Problem{}); err != nil {
+		return nil, fmt.Errorf("invalid error template: %w", err)
+	}
+	return &Template{ContentType: contentType, template: t}, nil
+}
+
+func (t *Template) Execute(buf *bytes.Buffer, p Problem) error {
+	return t.template.Execute(buf, p)
+}
+
+func quoteJSON(value any) (string, error) {
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+type templateKey struct{}
+
+// WithTemplate attaches the error template of an API to a request context
+func WithTemplate(ctx context.Context, t *Template) context.Context {
+	return context.WithValue(ctx, templateKey{}, t)
+}
+
+// TemplateFromContext returns the error template attached by WithTemplate
+func TemplateFromContext(ctx context.Context) *Template {
+	t, _ := ctx.Value(templateKey{}).(*Template)
+	return t
+}
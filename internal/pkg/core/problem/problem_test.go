@@ -0,0 +1,87 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package problem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/tracing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite_ProblemJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, httptest.NewRequest(http.MethodGet, "/orders/42", nil), http.StatusNotFound, "No order 42")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	correlationID := rec.Header().Get(CorrelationHeader)
+	assert.Len(t, correlationID, 32)
+	assert.JSONEq(t, `{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "No order 42",
+		"instance": "/orders/42", "correlationId": "`+correlationID+`"}`, rec.Body.String())
+}
+
+func TestCorrelationID(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	var traced *http.Request
+	tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traced = r
+	})).ServeHTTP(httptest.NewRecorder(), request)
+	assert.Equal(t, tracing.TraceID(traced.Context()), CorrelationID(traced), "the trace ID is used")
+
+	traced.Header.Set(CorrelationHeader, "client-id")
+	assert.Equal(t, "client-id", CorrelationID(traced), "the client's ID takes precedence")
+}
+
+func TestWrite_Template(t *testing.T) {
+	template, err := ParseTemplate("application/xml", `<error code="{{.Status}}">{{.Detail}}</error>`)
+	assert.NoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	request = request.WithContext(WithTemplate(request.Context(), template))
+
+	rec := httptest.NewRecorder()
+	Write(rec, request, http.StatusBadRequest, "Missing order")
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `<error code="400">Missing order</error>`, rec.Body.String())
+}
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "quoted fields", body: `{"error": {{json .Title}}, "id": {{json .CorrelationID}}}`},
+		{name: "unknown field", body: `{{.Message}}`, wantErr: true},
+		{name: "unknown function", body: `{{xml .Detail}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTemplate("", tt.body)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "invalid error template")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
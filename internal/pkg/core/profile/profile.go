@@ -0,0 +1,149 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package profile selects the configuration profile the runtime starts with.
+// A profile such as dev, test or prod merges deployment-<profile>.toml and
+// LoggerConfig-<profile>.toml over the base files, limits deployment to the
+// artifacts declaring it, and turns on the switches of its [profile] section,
+// so the same artifact tree runs against mocks in development and real
+// backends in production.
+package profile
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Env is the environment variable naming the profile when no flag does
+const Env = "SYNAPSE_PROFILE"
+
+// Built-in profiles. Other names only select configuration overlays and
+// artifacts.
+const (
+	// Development relaxes security and logs the messages exchanged with endpoints
+	Development = "dev"
+	// Test enables the assert mediators of contract tests
+	Test = "test"
+	// Production refuses the settings of the other profiles
+	Production = "prod"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Config holds the [profile] section of deployment.toml
+type Config struct {
+	// Name is the active profile, from the command line or the environment
+	Name string `koanf:"-"`
+	// WireLogging logs the messages sent to endpoints and their replies
+	WireLogging bool `koanf:"wireLogging"`
+	// RelaxedSecurity serves artifacts without enforcing their security
+	// policies, authorization services and CSRF checks
+	RelaxedSecurity bool `koanf:"relaxedSecurity"`
+}
+
+// Validate reports configuration errors in the [profile] section
+func (c Config) Validate() error {
+	if c.Name != Production {
+		return nil
+	}
+	if c.RelaxedSecurity {
+		return fmt.Errorf("profile: relaxedSecurity cannot be enabled in the %s profile", Production)
+	}
+	if c.WireLogging {
+		return fmt.Errorf("profile: wireLogging cannot be enabled in the %s profile", Production)
+	}
+	return nil
+}
+
+// Defaults returns the deployment.toml settings a built-in profile turns on,
+// by key. The configuration files can turn them off again.
+func Defaults(name string) map[string]interface{} {
+	switch name {
+	case Development:
+		return map[string]interface{}{"profile.wireLogging": true, "profile.relaxedSecurity": true}
+	case Test:
+		return map[string]interface{}{"assertions.enabled": true}
+	}
+	return nil
+}
+
+// Resolve returns the profile named by flagValue, or by the SYNAPSE_PROFILE
+// environment variable when the flag is empty. No profile is active when
+// neither is set.
+func Resolve(flagValue string) (string, error) {
+	name := strings.TrimSpace(flagValue)
+	if name == "" {
+		name = strings.TrimSpace(os.Getenv(Env))
+	}
+	if name != "" && !namePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid profile '%s': use lowercase letters, digits and dashes", name)
+	}
+	return name, nil
+}
+
+// Matches reports whether an artifact declaring profiles, a comma-separated
+// list such as "dev,test" or "!prod", deploys with the active profile. An
+// empty list matches every profile. Names prefixed with ! exclude a profile;
+// otherwise the active profile must be listed, so listed artifacts are not
+// deployed when no profile is active.
+func Matches(profiles, active string) (bool, error) {
+	var included, excluded []string
+	for _, entry := range strings.Split(profiles, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, negated := strings.CutPrefix(entry, "!")
+		if !namePattern.MatchString(name) {
+			return false, fmt.Errorf("invalid profile '%s' in profiles '%s'", entry, profiles)
+		}
+		if negated {
+			excluded = append(excluded, name)
+		} else {
+			included = append(included, name)
+		}
+	}
+	if slices.Contains(excluded, active) {
+		return false, nil
+	}
+	return len(included) == 0 || slices.Contains(included, active), nil
+}
+
+var (
+	activeMu sync.RWMutex
+	active   string
+)
+
+// SetActive selects the profile the configuration is read with
+func SetActive(name string) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	active = name
+}
+
+// Active returns the selected profile, empty when there is none
+func Active() string {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return active
+}
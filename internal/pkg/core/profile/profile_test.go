@@ -0,0 +1,80 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package profile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv(Env, "test")
+	name, err := Resolve("")
+	require.NoError(t, err)
+	assert.Equal(t, Test, name, "the environment names the profile without a flag")
+
+	name, err = Resolve("dev")
+	require.NoError(t, err)
+	assert.Equal(t, Development, name, "the flag wins over the environment")
+
+	t.Setenv(Env, "")
+	name, err = Resolve("")
+	require.NoError(t, err)
+	assert.Empty(t, name)
+
+	_, err = Resolve("../prod")
+	assert.Error(t, err)
+}
+
+func TestMatches(t *testing.T) {
+	testCases := []struct {
+		profiles string
+		active   string
+		expected bool
+	}{
+		{"", "", true},
+		{"", Production, true},
+		{"dev,test", Development, true},
+		{"dev, test", Test, true},
+		{"dev,test", Production, false},
+		{"dev", "", false},
+		{"!prod", Development, true},
+		{"!prod", "", true},
+		{"!prod", Production, false},
+		{"staging,!prod", "staging", true},
+	}
+	for _, tc := range testCases {
+		matches, err := Matches(tc.profiles, tc.active)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, matches, "profiles %q with %q active", tc.profiles, tc.active)
+	}
+
+	_, err := Matches("dev,Prod", Development)
+	assert.Error(t, err)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{Name: Development, WireLogging: true, RelaxedSecurity: true}.Validate())
+	assert.NoError(t, Config{Name: Production}.Validate())
+	assert.Error(t, Config{Name: Production, RelaxedSecurity: true}.Validate())
+	assert.Error(t, Config{Name: Production, WireLogging: true}.Validate())
+}
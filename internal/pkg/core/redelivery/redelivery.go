@@ -0,0 +1,88 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package redelivery carries the retry state of a stored message from one
+// delivery attempt to the next. A message processor keeps the State with the
+// message, exposes it to each attempt's mediation with Expose, and records a
+// failed attempt with Failed, so sequences can branch on the retry state,
+// e.g. notify someone once properties.RETRY_ATTEMPT > 3.
+package redelivery
+
+import (
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+const (
+	// AttemptProperty holds the number of the attempt being mediated, from 1
+	AttemptProperty = "RETRY_ATTEMPT"
+	// FirstFailureProperty holds the time of the first failed attempt as an
+	// RFC 3339 string, unset on the first attempt
+	FirstFailureProperty = "RETRY_FIRST_FAILURE"
+	// LastErrorProperty holds the error of the previous attempt
+	LastErrorProperty = "RETRY_LAST_ERROR"
+)
+
+// State is the retry state of a stored message
+type State struct {
+	// Attempts counts the attempts that failed
+	Attempts     int       `json:"attempts"`
+	FirstFailure time.Time `json:"firstFailure,omitempty"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+// Expose sets the retry properties of the next attempt on its message
+func (s State) Expose(msg *synctx.MsgContext) {
+	msg.Properties[AttemptProperty] = s.Attempts + 1
+	if s.Attempts == 0 {
+		return
+	}
+	msg.Properties[FirstFailureProperty] = s.FirstFailure.UTC().Format(time.RFC3339)
+	msg.Properties[LastErrorProperty] = s.LastError
+}
+
+// Failed records that the attempt mediating msg failed at the given time,
+// with the error the failing mediator reported
+func (s *State) Failed(msg *synctx.MsgContext, at time.Time) {
+	s.Attempts++
+	if s.FirstFailure.IsZero() {
+		s.FirstFailure = at
+	}
+	s.LastError, _ = msg.Properties[artifacts.ErrorMessageProperty].(string)
+	if s.LastError == "" {
+		s.LastError = "the sequence failed"
+	}
+}
+
+// FromContext returns the retry state exposed on a message, for messages
+// stored again from within a retried flow
+func FromContext(msg *synctx.MsgContext) State {
+	attempt, _ := msg.Properties[AttemptProperty].(int)
+	if attempt <= 1 {
+		return State{}
+	}
+	state := State{Attempts: attempt - 1}
+	if firstFailure, ok := msg.Properties[FirstFailureProperty].(string); ok {
+		state.FirstFailure, _ = time.Parse(time.RFC3339, firstFailure)
+	}
+	state.LastError, _ = msg.Properties[LastErrorProperty].(string)
+	return state
+}
@@ -0,0 +1,63 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package redelivery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/expression"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	var state State
+	first := synctx.CreateMsgContext()
+	state.Expose(first)
+	assert.Equal(t, map[string]interface{}{AttemptProperty: 1}, first.Properties, "the first attempt has no failure to report")
+
+	failedAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	first.Properties[artifacts.ErrorMessageProperty] = "backend returned 503"
+	state.Failed(first, failedAt)
+	second := synctx.CreateMsgContext()
+	state.Expose(second)
+	state.Failed(second, failedAt.Add(time.Minute))
+	assert.Equal(t, State{Attempts: 2, FirstFailure: failedAt, LastError: "the sequence failed"}, state)
+
+	third := synctx.CreateMsgContext()
+	state.Expose(third)
+	assert.Equal(t, 3, third.Properties[AttemptProperty])
+	assert.Equal(t, "2026-03-01T09:30:00Z", third.Properties[FirstFailureProperty])
+	assert.Equal(t, "the sequence failed", third.Properties[LastErrorProperty])
+	assert.Equal(t, state, FromContext(third))
+	assert.Equal(t, State{}, FromContext(synctx.CreateMsgContext()))
+
+	// Sequences branch on the exposed state
+	notify, err := expression.Compile(`properties.RETRY_ATTEMPT > 2 && properties.RETRY_LAST_ERROR != ''`, artifacts.ExpressionVariables...)
+	require.NoError(t, err)
+	env, err := artifacts.ExpressionEnv(third)
+	require.NoError(t, err)
+	result, err := notify.Run(env)
+	require.NoError(t, err)
+	assert.Equal(t, true, result)
+}
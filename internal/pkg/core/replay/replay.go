@@ -0,0 +1,211 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package replay re-runs captured messages through the API that received them
+// or through another sequence, optionally as a dry run.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/dryrun"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+type replayKey struct{}
+
+// WithReplay marks a request context as replaying the named capture. The mark
+// cannot be set by clients, unlike a header.
+func WithReplay(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, replayKey{}, name)
+}
+
+// FromRequestContext returns the capture a request replays
+func FromRequestContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(replayKey{}).(string)
+	return name, ok
+}
+
+// Request selects a stored message and how to replay it
+type Request struct {
+	// Capture is the name of a capture file, with or without the .json suffix
+	Capture string `json:"capture"`
+	// Sequence replays the message through a named sequence instead of the
+	// API that originally received it
+	Sequence string `json:"sequence,omitempty"`
+	// DryRun reports the outbound calls mediators would make without making them
+	DryRun bool `json:"dryRun"`
+}
+
+// Result describes the outcome of a replay
+type Result struct {
+	Capture       string         `json:"capture"`
+	Target        string         `json:"target"`
+	DryRun        bool           `json:"dryRun"`
+	Success       bool           `json:"success"`
+	Status        int            `json:"status,omitempty"`
+	ContentType   string         `json:"contentType,omitempty"`
+	Payload       string         `json:"payload,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	OutboundCalls []dryrun.Call  `json:"outboundCalls,omitempty"`
+	Steps         []capture.Step `json:"steps"`
+}
+
+// Replayer replays capture files
+type Replayer struct {
+	// Directory returns the directory holding the capture files
+	Directory func() string
+	// Handler receives messages replayed through their original API
+	Handler http.Handler
+	// Sequences resolves alternate sequences by name
+	Sequences func(name string) (artifacts.Sequence, bool)
+}
+
+// Load reads a capture file from the capture directory
+func (rp *Replayer) Load(name string) (capture.Record, error) {
+	name = strings.TrimSuffix(name, ".json")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return capture.Record{}, fmt.Errorf("invalid capture name '%s'", name)
+	}
+	data, err := os.ReadFile(filepath.Join(rp.Directory(), name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return capture.Record{}, fmt.Errorf("capture %s not found", name)
+		}
+		return capture.Record{}, err
+	}
+	var record capture.Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return capture.Record{}, fmt.Errorf("capture %s is not readable: %w", name, err)
+	}
+	return record, nil
+}
+
+// Replay runs the requested message and reports the result. Errors are
+// returned for messages that cannot be replayed; mediation failures are part
+// of the result.
+func (rp *Replayer) Replay(ctx context.Context, request Request) (Result, error) {
+	name := strings.TrimSuffix(request.Capture, ".json")
+	record, err := rp.Load(name)
+	if err != nil {
+		return Result{}, err
+	}
+	var recorder *dryrun.Recorder
+	if request.DryRun {
+		recorder = &dryrun.Recorder{}
+	}
+	trace := &capture.Trace{}
+	result := Result{Capture: name, DryRun: request.DryRun}
+
+	if request.Sequence != "" {
+		sequence, ok := rp.Sequences(request.Sequence)
+		if !ok {
+			return Result{}, fmt.Errorf("sequence %s is not deployed", request.Sequence)
+		}
+		result.Target = "sequence:" + request.Sequence
+		msgContext := newMessageContext(record)
+		capture.AddToContext(msgContext, trace)
+		if recorder != nil {
+			dryrun.AddToContext(msgContext, recorder)
+		}
+		result.Success = sequence.Execute(msgContext)
+		result.ContentType = msgContext.Message.ContentType
+		result.Payload = string(msgContext.Message.RawPayload)
+		if !result.Success {
+			result.Error, _ = msgContext.Properties[artifacts.ErrorMessageProperty].(string)
+		}
+	} else {
+		result.Target = "api:" + record.API
+		httpRequest, err := http.NewRequestWithContext(ctx, record.Exchange.Method, record.Exchange.URL,
+			bytes.NewReader([]byte(record.Exchange.RequestBody)))
+		if err != nil {
+			return Result{}, fmt.Errorf("capture %s has an invalid request: %w", name, err)
+		}
+		for name, values := range record.Exchange.RequestHeaders {
+			httpRequest.Header[name] = values
+		}
+		httpRequest.Host = record.Exchange.Host
+		requestCtx := WithReplay(capture.WithTrace(httpRequest.Context(), trace), name)
+		if recorder != nil {
+			requestCtx = dryrun.WithRecorder(requestCtx, recorder)
+		}
+		response := newResponseBuffer()
+		rp.Handler.ServeHTTP(response, httpRequest.WithContext(requestCtx))
+		result.Status = response.status
+		result.Success = response.status < http.StatusBadRequest
+		result.ContentType = response.header.Get("Content-Type")
+		result.Payload = response.body.String()
+	}
+
+	result.Steps = trace.Steps()
+	if recorder != nil {
+		result.OutboundCalls = recorder.Calls()
+	}
+	return result, nil
+}
+
+// newMessageContext rebuilds the message context the router would have created
+func newMessageContext(record capture.Record) *synctx.MsgContext {
+	msgContext := synctx.CreateMsgContext()
+	msgContext.Message.RawPayload = []byte(record.Exchange.RequestBody)
+	requestHeaders := make(map[string]string, len(record.Exchange.RequestHeaders))
+	for name, values := range record.Exchange.RequestHeaders {
+		if len(values) > 0 {
+			requestHeaders[name] = values[0]
+		}
+	}
+	msgContext.Message.ContentType = requestHeaders["Content-Type"]
+	msgContext.Properties["http_request_headers"] = requestHeaders
+	return msgContext
+}
+
+// responseBuffer collects the response of a replayed request
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+func (b *responseBuffer) Header() http.Header { return b.header }
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
@@ -0,0 +1,132 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package replay
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/dryrun"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+)
+
+// callMediator stands in for a mediator that calls a backend
+type callMediator struct{}
+
+func (callMediator) Execute(msgContext *synctx.MsgContext) (bool, error) {
+	if recorder := dryrun.FromContext(msgContext); recorder != nil {
+		recorder.Record(dryrun.Call{Mediator: "call", Method: http.MethodPost, URL: "http://backend/orders"})
+		return true, nil
+	}
+	msgContext.Message.RawPayload = []byte("sent")
+	return true, nil
+}
+
+// newTestReplayer writes one capture file and returns its name with the replayer
+func newTestReplayer(t *testing.T, handler http.Handler) (*Replayer, string) {
+	directory := t.TempDir()
+	recorder := capture.NewRecorder(directory)
+	_, err := recorder.Start(capture.Filter{}, 1, 0)
+	assert.NoError(t, err)
+	request, _ := http.NewRequest(http.MethodPost, "/orders", nil)
+	claim := recorder.Claim("OrdersAPI", request)
+	path, err := claim.Write(capture.Record{
+		API: "OrdersAPI",
+		Exchange: capture.Exchange{
+			Method:         http.MethodPost,
+			Host:           "api.example.com",
+			URL:            "/orders?id=1",
+			RequestHeaders: map[string][]string{"Content-Type": {"application/json"}},
+			RequestBody:    `{"id":1}`,
+		},
+	}, nil)
+	assert.NoError(t, err)
+
+	rp := &Replayer{
+		Directory: func() string { return directory },
+		Handler:   handler,
+		Sequences: func(name string) (artifacts.Sequence, bool) {
+			if name != "Resend" {
+				return artifacts.Sequence{}, false
+			}
+			return artifacts.Sequence{Name: "Resend", MediatorList: []artifacts.Mediator{callMediator{}}}, true
+		},
+	}
+	return rp, strings.TrimSuffix(filepath.Base(path), ".json")
+}
+
+func TestReplay_ThroughSequence(t *testing.T) {
+	rp, name := newTestReplayer(t, nil)
+
+	result, err := rp.Replay(context.Background(), Request{Capture: name, Sequence: "Resend", DryRun: true})
+	assert.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "sequence:Resend", result.Target)
+	assert.Equal(t, `{"id":1}`, result.Payload, "the dry run left the payload untouched")
+	assert.Equal(t, []dryrun.Call{{Mediator: "call", Method: http.MethodPost, URL: "http://backend/orders"}}, result.OutboundCalls)
+	assert.Len(t, result.Steps, 1)
+
+	result, err = rp.Replay(context.Background(), Request{Capture: name, Sequence: "Resend"})
+	assert.NoError(t, err)
+	assert.Equal(t, "sent", result.Payload)
+	assert.Empty(t, result.OutboundCalls)
+
+	_, err = rp.Replay(context.Background(), Request{Capture: name, Sequence: "Missing"})
+	assert.EqualError(t, err, "sequence Missing is not deployed")
+}
+
+func TestReplay_ThroughOriginalAPI(t *testing.T) {
+	var received *http.Request
+	rp, name := newTestReplayer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		assert.NotNil(t, dryrun.FromRequestContext(r.Context()))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	result, err := rp.Replay(context.Background(), Request{Capture: name + ".json", DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "api:OrdersAPI", result.Target)
+	assert.True(t, result.Success)
+	assert.Equal(t, http.StatusAccepted, result.Status)
+	assert.Equal(t, `{"ok":true}`, result.Payload)
+
+	assert.Equal(t, "api.example.com", received.Host)
+	assert.Equal(t, "/orders", received.URL.Path)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	replayed, ok := FromRequestContext(received.Context())
+	assert.True(t, ok)
+	assert.Equal(t, name, replayed)
+}
+
+func TestReplay_InvalidCapture(t *testing.T) {
+	rp, _ := newTestReplayer(t, nil)
+	_, err := rp.Replay(context.Background(), Request{Capture: "../etc/passwd"})
+	assert.EqualError(t, err, "invalid capture name '../etc/passwd'")
+	_, err = rp.Replay(context.Background(), Request{Capture: "missing"})
+	assert.EqualError(t, err, "capture missing not found")
+}
@@ -0,0 +1,181 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/asyncreply"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+)
+
+// asyncStatus is the body of 202 responses pointing clients at their result
+type asyncStatus struct {
+	ID        string            `json:"id"`
+	Status    asyncreply.Status `json:"status"`
+	StatusURL string            `json:"statusUrl"`
+}
+
+// responseBuffer collects the response of a background mediation
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header { return b.header }
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// createAsyncMiddleware answers requests to async resources with 202 Accepted
+// and a status URL, and mediates them in the background. The result is kept
+// by the tracker and, when the client sent a callback URL the resource
+// allows, posted to it.
+func (rs *RouterService) createAsyncMiddleware(resource artifacts.Resource, next http.HandlerFunc) http.HandlerFunc {
+	if resource.Async == nil {
+		return next
+	}
+	ttl := resource.Async.TTL
+	return func(w http.ResponseWriter, r *http.Request) {
+		callbackURL := r.Header.Get(asyncreply.CallbackHeader)
+		if callbackURL != "" {
+			if err := asyncreply.AllowCallback(callbackURL, resource.Async.Callbacks); err != nil {
+				problem.Write(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		// The body is closed once the handler returns, before mediation ends
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "The request body could not be read")
+			return
+		}
+		result, err := rs.async.Start(r.Context(), ttl)
+		if err != nil {
+			rs.logger.Error("Failed to record async request", slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusServiceUnavailable, "The request could not be accepted")
+			return
+		}
+
+		background := r.Clone(context.WithoutCancel(r.Context()))
+		background.Body = io.NopCloser(bytes.NewReader(body))
+		go rs.mediateAsync(background, next, result, ttl, callbackURL)
+
+		statusURL := asyncreply.StatusPath + result.ID
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", statusURL)
+		w.Header().Set(asyncreply.IDHeader, result.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(asyncStatus{ID: result.ID, Status: result.Status, StatusURL: statusURL})
+	}
+}
+
+// mediateAsync runs the resource handler for an accepted request and stores
+// what it would have answered
+func (rs *RouterService) mediateAsync(r *http.Request, next http.HandlerFunc, result asyncreply.Result, ttl time.Duration, callbackURL string) {
+	buffer := &responseBuffer{header: make(http.Header)}
+	func() {
+		defer func() {
+			if value := recover(); value != nil {
+				rs.logger.Error("Recovered from panic while mediating async request",
+					slog.String("id", result.ID),
+					slog.String("panic", fmt.Sprint(value)))
+				buffer = &responseBuffer{header: make(http.Header)}
+				problem.Write(buffer, r, http.StatusInternalServerError, "The request could not be processed")
+			}
+		}()
+		next(buffer, r)
+	}()
+	if buffer.status == 0 {
+		buffer.status = http.StatusOK
+	}
+	result.StatusCode = buffer.status
+	result.Header = buffer.header
+	result.Payload = buffer.body.Bytes()
+
+	if err := rs.async.Complete(r.Context(), result, ttl); err != nil {
+		rs.logger.Error("Failed to store async result", slog.String("id", result.ID), slog.String("error", err.Error()))
+	}
+	if callbackURL != "" {
+		delivery := asyncreply.Deliver(callbackURL, result)
+		rs.logger.Debug("Scheduled async result delivery", slog.String("id", result.ID), slog.String("delivery", delivery.ID))
+	}
+}
+
+// registerAsyncStatusEndpoint serves the results of async requests. Pending
+// requests are answered with 202 Accepted, immediately or, with ?wait=, once
+// the result arrives or the wait elapses.
+func (rs *RouterService) registerAsyncStatusEndpoint() {
+	rs.router.HandleFunc("GET "+asyncreply.StatusPath+"{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if value := r.URL.Query().Get("wait"); value != "" {
+			wait, err := time.ParseDuration(value)
+			if err != nil || wait < 0 {
+				problem.Write(w, r, http.StatusBadRequest, "wait must be a non-negative duration, e.g. 30s")
+				return
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, min(wait, asyncreply.MaxWait))
+			defer cancel()
+		}
+		id := r.PathValue("id")
+		result, ok, err := rs.async.Wait(ctx, id)
+		if err != nil {
+			rs.logger.Error("Failed to look up async result", slog.String("id", id), slog.String("error", err.Error()))
+			problem.Write(w, r, http.StatusServiceUnavailable, "The result could not be looked up")
+			return
+		}
+		if !ok {
+			problem.Write(w, r, http.StatusNotFound, "No result is known for async request "+id)
+			return
+		}
+		w.Header().Set(asyncreply.IDHeader, result.ID)
+		if result.Status != asyncreply.StatusComplete {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(1))
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(asyncStatus{ID: result.ID, Status: result.Status, StatusURL: asyncreply.StatusPath + result.ID})
+			return
+		}
+		for name, values := range result.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(result.StatusCode)
+		w.Write(result.Payload)
+	})
+}
@@ -0,0 +1,106 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/replay"
+)
+
+// captureWriter copies the response of a captured message
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len() < capture.MaxBodySize {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// createCaptureMiddleware records requests claimed by the active capture
+// session, together with their response and mediation trace
+func (rs *RouterService) createCaptureMiddleware(api artifacts.API, next http.Handler) http.Handler {
+	apiKey := api.Key()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Replayed messages are already captured
+		var claim *capture.Claim
+		if _, replayed := replay.FromRequestContext(r.Context()); !replayed {
+			claim = rs.capture.Claim(apiKey, r)
+		}
+		if claim == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var requestBody []byte
+		if r.Body != nil {
+			requestBody, _ = io.ReadAll(r.Body)
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(requestBody))
+		}
+		trace := &capture.Trace{}
+		writer := &captureWriter{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(writer, r.WithContext(capture.WithTrace(r.Context(), trace)))
+
+		path, err := claim.Write(capture.Record{
+			API:      apiKey,
+			Time:     start,
+			Duration: time.Since(start),
+			Exchange: capture.Exchange{
+				Method:          r.Method,
+				Host:            r.Host,
+				URL:             r.URL.String(),
+				RequestHeaders:  r.Header,
+				RequestBody:     capture.Truncate(requestBody),
+				Status:          writer.status,
+				ResponseHeaders: w.Header(),
+				ResponseBody:    capture.Truncate(writer.body.Bytes()),
+			},
+			Steps: trace.Steps(),
+		}, trace.Policy())
+		if err != nil {
+			rs.logger.Error("Failed to write capture", slog.String("api_name", apiKey), slog.String("error", err.Error()))
+			return
+		}
+		rs.logger.Info("Captured message", slog.String("api_name", apiKey), slog.String("file", path))
+	})
+}
@@ -0,0 +1,117 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// mountedContext records which artifact owns a base path on the main router
+type mountedContext struct {
+	hostname string
+	path     string
+	owner    string
+	// family is the API name for API mounts. The unversioned default-version path
+	// of an API may be a parent of its versioned paths.
+	family string
+}
+
+// overlaps reports whether two base paths on the same host would shadow each
+// other, i.e. one equals the other or is a parent of it
+func (m mountedContext) overlaps(other mountedContext) bool {
+	if m.hostname != other.hostname {
+		return false
+	}
+	if m.path != other.path && m.family != "" && m.family == other.family {
+		return false
+	}
+	return strings.HasPrefix(m.path+"/", other.path+"/") || strings.HasPrefix(other.path+"/", m.path+"/")
+}
+
+// checkMountLocked reports a conflict between candidate and the existing mounts.
+// The caller must hold mountsMu.
+func (rs *RouterService) checkMountLocked(candidate mountedContext) error {
+	for _, existing := range rs.mounts {
+		if existing.overlaps(candidate) {
+			return fmt.Errorf("context conflict: %s%s overlaps with %s%s registered by %s",
+				candidate.hostname, candidate.path, existing.hostname, existing.path, existing.owner)
+		}
+	}
+	return nil
+}
+
+// mountLocked registers handler for every request below the candidate's base
+// path. The caller must hold mountsMu and have checked for conflicts.
+func (rs *RouterService) mountLocked(candidate mountedContext, handler http.Handler) error {
+	if err := handleSafely(rs.router, candidate.hostname+candidate.path+"/", handler); err != nil {
+		return err
+	}
+	rs.mounts = append(rs.mounts, candidate)
+	return nil
+}
+
+// mount registers handler for every request below hostname+path unless the base
+// path overlaps with one that is already registered
+func (rs *RouterService) mount(hostname, path, owner string, handler http.Handler) error {
+	rs.mountsMu.Lock()
+	defer rs.mountsMu.Unlock()
+
+	candidate := mountedContext{hostname: hostname, path: path, owner: owner}
+	if err := rs.checkMountLocked(candidate); err != nil {
+		return err
+	}
+	return rs.mountLocked(candidate, handler)
+}
+
+// checkDuplicateResources reports every method and URI template declared more
+// than once within the API
+func checkDuplicateResources(api artifacts.API) error {
+	seen := make(map[string]bool)
+	var duplicates []string
+	for _, resource := range api.Resources {
+		for _, method := range resource.Methods {
+			key := method + " " + resource.URITemplate.PathTemplate
+			if seen[key] {
+				duplicates = append(duplicates, key)
+			}
+			seen[key] = true
+		}
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("duplicate resources: %s", strings.Join(duplicates, ", "))
+	}
+	return nil
+}
+
+// handleSafely registers a handler, converting the panic ServeMux raises for
+// invalid or conflicting patterns into an error
+func handleSafely(mux *http.ServeMux, pattern string, handler http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("route conflict: %v", r)
+		}
+	}()
+	mux.Handle(pattern, handler)
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// createDeprecationMiddleware advertises a deprecated API version through the
+// Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers, and adds a 299
+// Warning header to the configured share of responses
+func (rs *RouterService) createDeprecationMiddleware(api artifacts.API, next http.Handler) http.Handler {
+	deprecation := api.Deprecation
+	if deprecation == nil {
+		return next
+	}
+
+	deprecationValue := "true"
+	if !deprecation.Since.IsZero() {
+		deprecationValue = "@" + strconv.FormatInt(deprecation.Since.Unix(), 10)
+	}
+	warning := fmt.Sprintf(`299 - "API %s version %s is deprecated"`, api.Name, api.Version)
+	if !deprecation.Sunset.IsZero() {
+		warning = fmt.Sprintf(`299 - "API %s version %s is deprecated and will be removed on %s"`,
+			api.Name, api.Version, deprecation.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("Deprecation", deprecationValue)
+		if !deprecation.Sunset.IsZero() {
+			header.Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if deprecation.Link != "" {
+			header.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, deprecation.Link))
+		}
+		if deprecation.WarningPercent > 0 && rand.IntN(100) < deprecation.WarningPercent {
+			header.Add("Warning", warning)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
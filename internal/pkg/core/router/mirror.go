@@ -0,0 +1,68 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// createMirrorMiddleware copies the API's share of requests to its mirror
+// endpoint. The copy carries the request's method in HTTP_METHOD and its
+// path below the API context in REST_URL_POSTFIX, so the mirror endpoint can
+// address the same resource, e.g.
+// uri-template="http://orders-v2{{.Property "REST_URL_POSTFIX"}}".
+func (rs *RouterService) createMirrorMiddleware(api artifacts.API, next http.Handler) http.Handler {
+	if api.Mirror == nil {
+		return next
+	}
+	mirror := *api.Mirror
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !outbound.SampleMirror(mirror) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "The request body could not be read")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		msgContext := synctx.CreateMsgContext()
+		msgContext.Message.RawPayload = body
+		msgContext.Message.ContentType = r.Header.Get("Content-Type")
+		for name := range r.Header {
+			msgContext.Headers[name] = r.Header.Get(name)
+		}
+		msgContext.Properties["HTTP_METHOD"] = r.Method
+		msgContext.Properties["REST_URL_POSTFIX"] = r.URL.RequestURI()
+		msgContext.Properties[artifacts.SnapshotProperty] = artifacts.GetConfigContext().Snapshot()
+		outbound.Mirror(mirror, msgContext)
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,68 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/analytics"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+	"github.com/apache/synapse-go/internal/pkg/core/tracing"
+)
+
+const requestDurationMetric = "synapse_api_request_duration_seconds"
+
+// createObservabilityMiddleware records request latencies when [metrics] is
+// enabled, aggregates traffic statistics when [analytics] is enabled and
+// propagates trace context when [tracing] is enabled. With metrics and tracing
+// enabled, the trace ID of a request becomes the exemplar of its latency bucket.
+func (rs *RouterService) createObservabilityMiddleware(api artifacts.API, deploymentConfig map[string]interface{}, next http.Handler) http.Handler {
+	metricsConfig, _ := deploymentConfig["metrics"].(metrics.Config)
+	tracingConfig, _ := deploymentConfig["tracing"].(tracing.Config)
+	analyticsConfig, _ := deploymentConfig["analytics"].(analytics.Config)
+
+	handler := next
+	if analyticsConfig.Enabled {
+		handler = analytics.Middleware(api.Key(), handler)
+	}
+	if metricsConfig.Enabled {
+		histogram := rs.metrics.Histogram(requestDurationMetric, "Latency of API requests.",
+			metricsConfig.Buckets, "api", "method", "code")
+		apiKey := api.Key()
+		inner := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &statusRecorder{ResponseWriter: w}
+			start := time.Now()
+			inner.ServeHTTP(recorder, r)
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			histogram.Observe(time.Since(start).Seconds(), tracing.TraceID(r.Context()), apiKey, r.Method, strconv.Itoa(status))
+		})
+	}
+	if tracingConfig.Enabled {
+		handler = tracing.Middleware(handler)
+	}
+	return handler
+}
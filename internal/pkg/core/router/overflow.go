@@ -0,0 +1,117 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/outbound"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// OverflowHeader marks responses to requests routed by the API's overflow policy
+const OverflowHeader = "X-Overflow"
+
+// createOverflowMiddleware routes requests whose body exceeds the API's
+// overflow size to its overflow sequence or endpoint. A declared
+// Content-Length decides without reading the body; otherwise at most
+// MaxSize+1 bytes are read before the request is routed, and the body is
+// passed on unread beyond that.
+func (rs *RouterService) createOverflowMiddleware(api artifacts.API, next http.Handler) http.Handler {
+	if api.Overflow == nil {
+		return next
+	}
+	overflow := *api.Overflow
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		oversized := r.ContentLength > overflow.MaxSize
+		if r.ContentLength < 0 && r.Body != nil {
+			head, err := io.ReadAll(io.LimitReader(r.Body, overflow.MaxSize+1))
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, "The request body could not be read")
+				return
+			}
+			oversized = int64(len(head)) > overflow.MaxSize
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+		}
+		if !oversized {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		snapshot := artifacts.GetConfigContext().Snapshot()
+		if overflow.Sequence != "" {
+			if sequence, ok := snapshot.Sequences[overflow.Sequence]; ok {
+				w.Header().Set(OverflowHeader, "true")
+				rs.createResourceHandler(artifacts.Resource{InSequence: sequence}).ServeHTTP(w, r)
+				return
+			}
+		} else if endpoint, ok := snapshot.Endpoints[overflow.Endpoint]; ok {
+			w.Header().Set(OverflowHeader, "true")
+			rs.forwardOverflow(w, r, endpoint)
+			return
+		}
+		rs.logger.Warn("Overflow sequence or endpoint not found",
+			slog.String("api_name", api.Name),
+			slog.String("sequence", overflow.Sequence),
+			slog.String("endpoint", overflow.Endpoint))
+		problem.Write(w, r, http.StatusRequestEntityTooLarge, "The request body is too large")
+	})
+}
+
+// forwardOverflow sends the request to the endpoint without mediation and
+// answers with its response. As with mirrors, the request's method and path
+// below the API context are in HTTP_METHOD and REST_URL_POSTFIX.
+func (rs *RouterService) forwardOverflow(w http.ResponseWriter, r *http.Request, endpoint artifacts.Endpoint) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "The request body could not be read")
+		return
+	}
+	msgContext := synctx.CreateMsgContext()
+	msgContext.Message.RawPayload = body
+	msgContext.Message.ContentType = r.Header.Get("Content-Type")
+	for name := range r.Header {
+		msgContext.Headers[name] = r.Header.Get(name)
+	}
+	msgContext.Properties["HTTP_METHOD"] = r.Method
+	msgContext.Properties["REST_URL_POSTFIX"] = r.URL.RequestURI()
+	msgContext.Properties[artifacts.SnapshotProperty] = artifacts.GetConfigContext().Snapshot()
+	if err := outbound.Send(r.Context(), endpoint, msgContext); err != nil {
+		rs.logger.Error("Failed to forward oversized request",
+			slog.String("endpoint", endpoint.Name), slog.String("error", err.Error()))
+		problem.Write(w, r, http.StatusBadGateway, "The request could not be forwarded")
+		return
+	}
+	for name, value := range msgContext.Headers {
+		w.Header().Set(name, value)
+	}
+	if status, ok := msgContext.Properties[artifacts.HTTPStatusProperty].(int); ok && status >= 100 && status <= 599 {
+		w.WriteHeader(status)
+	}
+	w.Write(msgContext.Message.RawPayload)
+}
@@ -0,0 +1,63 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+)
+
+// createRecoveryMiddleware turns a panic while serving an API request into a
+// 500 response, so one faulty resource cannot take the server down. Panics in
+// mediators are already recovered by the sequence; this covers the rest of
+// request handling.
+func (rs *RouterService) createRecoveryMiddleware(api artifacts.API, next http.Handler) http.Handler {
+	apiKey := api.Key()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			// net/http uses this panic to abort a response on purpose
+			if err, ok := value.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(value)
+			}
+			rs.logger.Error("Recovered from panic while serving API request",
+				slog.String("api", apiKey),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("panic", fmt.Sprint(value)),
+				slog.String("stack", string(debug.Stack())))
+			rs.metrics.Counter(artifacts.PanicMetric, "Panics recovered by the runtime.", "component").Inc("api:" + apiKey)
+			if recorder.status == 0 {
+				problem.Write(w, r, http.StatusInternalServerError, "The request could not be processed")
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
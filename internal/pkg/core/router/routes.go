@@ -0,0 +1,58 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"sort"
+)
+
+// RouteInfo describes a single route registered on the main listener
+type RouteInfo struct {
+	// Method is empty for handlers that accept every method
+	Method   string `json:"method,omitempty"`
+	Pattern  string `json:"pattern"`
+	APIName  string `json:"api,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// addRoutes records routes after they have been registered with the ServeMux
+func (rs *RouterService) addRoutes(routes ...RouteInfo) {
+	rs.routesMu.Lock()
+	defer rs.routesMu.Unlock()
+	rs.routes = append(rs.routes, routes...)
+}
+
+// GetRegisteredRoutes returns every route registered on the main listener, sorted
+// by pattern and method. It is intended for debugging unexpected 404 responses.
+func (rs *RouterService) GetRegisteredRoutes() []RouteInfo {
+	rs.routesMu.RLock()
+	routes := make([]RouteInfo, len(rs.routes))
+	copy(routes, rs.routes)
+	rs.routesMu.RUnlock()
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
@@ -28,18 +28,41 @@
 package router
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"encoding/json"
 
+	"github.com/apache/synapse-go/internal/app/core/ports"
 	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/asyncreply"
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/dryrun"
+	"github.com/apache/synapse-go/internal/pkg/core/geoip"
+	"github.com/apache/synapse-go/internal/pkg/core/health"
+	"github.com/apache/synapse-go/internal/pkg/core/leaks"
+	"github.com/apache/synapse-go/internal/pkg/core/masking"
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+	"github.com/apache/synapse-go/internal/pkg/core/middleware"
+	"github.com/apache/synapse-go/internal/pkg/core/mtom"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+	"github.com/apache/synapse-go/internal/pkg/core/slo"
 	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/tracing"
+	"github.com/apache/synapse-go/internal/pkg/core/transactions"
+	"github.com/apache/synapse-go/internal/pkg/core/useragent"
+	"github.com/apache/synapse-go/internal/pkg/core/utils"
 	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
 )
 
@@ -54,19 +77,85 @@ type RouterService struct {
 	port     string // :8290
 	hostname string
 	logger   *slog.Logger
+
+	// Optional HTTPS listener serving the same routes
+	tlsServer *http.Server
+	tlsPort   string // :8253
+	tlsConfig *tls.Config
+	// Wraps the plain HTTP handler, e.g. to answer ACME HTTP-01 challenges
+	httpHandlerWrapper func(http.Handler) http.Handler
+
+	routesMu sync.RWMutex
+	routes   []RouteInfo
+
+	// mountsMu serializes registrations on the main router so conflict checks
+	// and the registration itself happen atomically
+	mountsMu sync.Mutex
+	mounts   []mountedContext
+	// versionFamilies groups the versions of an API by name, guarded by mountsMu
+	versionFamilies map[string]*versionFamily
+
+	monitorsMu sync.RWMutex
+	monitors   map[string]*slo.Monitor // SLO monitors keyed by API key
+
+	// healthChecks decides readiness together with the runtime itself
+	healthChecks *health.Registry
+	metrics      *metrics.Registry
+	capture      *capture.Recorder
+	// async keeps the results of requests to async resources
+	async *asyncreply.Tracker
+	// engine mediates API requests; resources mediate themselves until one is set
+	engine ports.MediationEngine
 }
 
 // NewRouterService creates a new router service with the given port and hostname
 func NewRouterService(port string, hostname string) *RouterService {
 	rs := &RouterService{
-		router:   http.NewServeMux(),
-		hostname: hostname,
-		port:     port,
+		router:          http.NewServeMux(),
+		hostname:        hostname,
+		port:            port,
+		versionFamilies: make(map[string]*versionFamily),
+		monitors:        make(map[string]*slo.Monitor),
 	}
 	rs.logger = loggerfactory.GetLogger(componentName, rs)
+	rs.healthChecks = health.NewRegistry(rs.logger)
+	rs.metrics = metrics.Default()
+	rs.capture = capture.NewRecorder(filepath.Join(os.TempDir(), "synapse-captures"))
+	rs.async = asyncreply.Default()
 	return rs
 }
 
+// BaseURL is the URL clients reach the plain HTTP listener at, using the
+// configured hostname or localhost
+func (rs *RouterService) BaseURL() string {
+	hostname := rs.hostname
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	return "http://" + hostname + rs.port
+}
+
+// Handler returns the handler of the main listener, e.g. to replay requests
+func (rs *RouterService) Handler() http.Handler {
+	return rs.router
+}
+
+// Capture returns the flight recorder of the API handlers
+func (rs *RouterService) Capture() *capture.Recorder {
+	return rs.capture
+}
+
+// SetMediationEngine sets the engine that mediates API requests. It must be
+// called before APIs are registered.
+func (rs *RouterService) SetMediationEngine(engine ports.MediationEngine) {
+	rs.engine = engine
+}
+
+// HealthChecks returns the registry of health checks reported by /readyz
+func (rs *RouterService) HealthChecks() *health.Registry {
+	return rs.healthChecks
+}
+
 func (rs *RouterService) UpdateLogger() {
 	rs.logger = loggerfactory.GetLogger(componentName, rs)
 }
@@ -74,49 +163,173 @@ func (rs *RouterService) UpdateLogger() {
 // RegisterAPI registers a new API with the router service
 func (rs *RouterService) RegisterAPI(ctx context.Context, api artifacts.API) error {
 	// Determine base path based on context and version
-	basePath := api.Context
+	basePath := api.BasePath()
 
-	// Remove trailing slash from context if present
-	if len(basePath) > 1 && basePath[len(basePath)-1] == '/' {
-		basePath = basePath[:len(basePath)-1]
+	// Reject duplicate method and template combinations before touching any mux
+	if err := checkDuplicateResources(api); err != nil {
+		return fmt.Errorf("cannot register API %s: %w", api.Name, err)
 	}
 
-	// Handle versioning based on versionType
-	if api.Version != "" && api.VersionType != "" {
-		switch api.VersionType {
-		case "url":
-			// For URL type, add version as a path segment
-			basePath = basePath + "/" + api.Version
-		case "context":
-			// For context type, replace {version} placeholder if it exists
-			versionPattern := "{version}"
-			basePath = strings.Replace(basePath, versionPattern, api.Version, 1)
-		}
+	var deploymentConfig map[string]interface{}
+	if configContext, ok := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext); ok {
+		deploymentConfig = configContext.DeploymentConfig
+	}
+	// Gateway-wide error handling applies where the API has none of its own
+	errorsConfig, _ := deploymentConfig["errors"].(problem.Config)
+	statusTemplates, err := errorsConfig.StatusTemplates()
+	if err != nil {
+		return fmt.Errorf("cannot register API %s: %w", api.Name, err)
 	}
 
 	// Create a subrouter for this API
 	apiHandler := http.NewServeMux()
+	var routes []RouteInfo
 
 	// Register each resource in the API
 	for _, resource := range api.Resources {
+		if len(resource.FaultSequence.MediatorList) == 0 {
+			resource.DefaultFaultSequence = errorsConfig.DefaultFaultSequence
+		}
 		// Register a handler for each HTTP method in the resource
 		for _, method := range resource.Methods {
 			// Construct the full pattern: "METHOD /path/to/resource"
 			pattern := method + " " + resource.URITemplate.PathTemplate
 			// Create a wrapper handler that checks query parameters before forwarding to the resource handler
-			queryParamHandler := rs.createQueryParamMiddleware(resource, rs.createResourceHandler(resource))
-			apiHandler.HandleFunc(pattern, queryParamHandler)
+			queryParamHandler := rs.createQueryParamMiddleware(resource, rs.createAsyncMiddleware(resource, rs.createResourceHandler(resource)))
+			if err := handleSafely(apiHandler, pattern, queryParamHandler); err != nil {
+				return fmt.Errorf("cannot register API %s: %w", api.Name, err)
+			}
+			routes = append(routes, RouteInfo{
+				Method:   method,
+				Pattern:  api.Hostname + basePath + resource.URITemplate.PathTemplate,
+				APIName:  api.Name,
+				Version:  api.Version,
+				Hostname: api.Hostname,
+			})
 			rs.logger.Info("Registered route for API",
 				slog.String("api_name", api.Name),
 				slog.String("pattern", pattern))
 		}
 	}
 
-	// Register the API handler with the main router
-	rs.router.Handle(basePath+"/", http.StripPrefix(basePath, apiHandler))
+	// Apply the CORS and security policies shared with the HTTP inbound endpoints.
+	// The versioned and unversioned paths share one SLO monitor and histogram
+	sloHandler := rs.createSLOMiddleware(ctx, api, transactions.Middleware(api.Key(),
+		rs.createCaptureMiddleware(api, rs.createRecoveryMiddleware(api, rs.createOverflowMiddleware(api, rs.createMirrorMiddleware(api, withUnmatchedProblems(apiHandler)))))))
+	observedHandler := rs.createObservabilityMiddleware(api, deploymentConfig, sloHandler)
+	wrapAPIHandler := func(prefix string) (http.Handler, error) {
+		handler, err := middleware.Wrap(rs.createDeprecationMiddleware(api, http.StripPrefix(prefix, observedHandler)), deploymentConfig, middleware.Options{
+			CORS:           api.CORS,
+			SecurityPolicy: api.SecurityPolicy,
+			Authorization:  api.Authorization,
+			CSRF:           api.CSRF,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return withErrorTemplates(api, statusTemplates, handler), nil
+	}
+	handler, err := wrapAPIHandler(basePath)
+	if err != nil {
+		return fmt.Errorf("cannot register API %s: %w", api.Name, err)
+	}
+
+	// Register the API handler with the main router. When the API is bound to a
+	// virtual host the pattern is prefixed with the hostname, so ServeMux matches
+	// the Host header before dispatching on the path.
+	if api.Version == "" {
+		if err := rs.mount(api.Hostname, basePath, "API "+api.Name, handler); err != nil {
+			return fmt.Errorf("cannot register API %s: %w", api.Name, err)
+		}
+	} else {
+		// Versioned APIs are also reachable without the version segment, through
+		// the default version of their family. An API at the root context has no
+		// unversioned path that would not shadow every other API.
+		defaultPath := unversionedPath(api)
+		var defaultHandler http.Handler
+		if defaultPath != basePath && defaultPath != "/" {
+			if defaultHandler, err = wrapAPIHandler(defaultPath); err != nil {
+				return fmt.Errorf("cannot register API %s: %w", api.Name, err)
+			}
+		}
+		if err := rs.mountVersion(api, basePath, handler, defaultPath, defaultHandler); err != nil {
+			return fmt.Errorf("cannot register API %s: %w", api.Name, err)
+		}
+	}
+	rs.addRoutes(routes...)
+	if api.Hostname != "" {
+		rs.logger.Info("Bound API to virtual host",
+			slog.String("api_name", api.Name),
+			slog.String("hostname", api.Hostname))
+	}
 	return nil
 }
 
+// RegisterHandler attaches a handler to the main listener under the given pattern.
+// HTTP inbound endpoints use it to share the router's port.
+func (rs *RouterService) RegisterHandler(pattern string, handler http.Handler) error {
+	if err := rs.mount("", strings.TrimSuffix(pattern, "/"), "handler "+pattern, handler); err != nil {
+		return err
+	}
+	rs.addRoutes(RouteInfo{Pattern: pattern})
+	rs.logger.Info("Registered handler", slog.String("pattern", pattern))
+	return nil
+}
+
+// EnableTLS configures an HTTPS listener on the given port that serves the same
+// routes as the plain HTTP listener. It must be called before StartServer.
+func (rs *RouterService) EnableTLS(port string, tlsConfig *tls.Config) {
+	rs.tlsPort = port
+	rs.tlsConfig = tlsConfig
+}
+
+// SetHTTPHandlerWrapper installs a wrapper around the plain HTTP handler. It must
+// be called before StartServer.
+func (rs *RouterService) SetHTTPHandlerWrapper(wrapper func(http.Handler) http.Handler) {
+	rs.httpHandlerWrapper = wrapper
+}
+
+// withErrorTemplates makes error responses of the API, including those of the
+// security middleware, use the API's error template, or else the gateway's
+// template for the status
+func withErrorTemplates(api artifacts.API, templates problem.StatusTemplates, next http.Handler) http.Handler {
+	if api.ErrorTemplate == nil && len(templates) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if api.ErrorTemplate != nil {
+			ctx = problem.WithTemplate(ctx, api.ErrorTemplate)
+		} else {
+			ctx = problem.WithStatusTemplates(ctx, templates)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withUnmatchedProblems answers requests that match no resource of the API,
+// 404 Not Found or 405 Method Not Allowed, with problem responses
+func withUnmatchedProblems(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		// The mux decides between the two statuses, and lists the allowed methods
+		buffer := &responseBuffer{header: make(http.Header)}
+		handler.ServeHTTP(buffer, r)
+		if allow := buffer.header.Get("Allow"); allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		detail := "No resource matches " + r.URL.Path
+		if buffer.status == http.StatusMethodNotAllowed {
+			detail = "Method " + r.Method + " is not allowed on " + r.URL.Path
+		}
+		problem.Write(w, r, buffer.status, detail)
+	})
+}
+
 // createHandlerFunc creates an HTTP handler function for the given API resource
 func (rs *RouterService) createResourceHandler(resource artifacts.Resource) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -125,6 +338,66 @@ func (rs *RouterService) createResourceHandler(resource artifacts.Resource) http
 
 		// Set request body into message context properties
 		msgContext.Properties["http_request_body"] = r.Body
+		msgContext.Message.ContentType = r.Header.Get("Content-Type")
+
+		// Mediators see the SOAP envelope of an MTOM request as its body and
+		// the binary parts as attachments
+		if mtom.IsMTOM(msgContext.Message.ContentType) {
+			message, err := mtom.Decode(msgContext.Message.ContentType, r.Body)
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, "The MTOM request could not be decoded")
+				return
+			}
+			msgContext.Properties["http_request_body"] = io.NopCloser(bytes.NewReader(message.Payload))
+			msgContext.Message.ContentType = message.ContentType
+			msgContext.Message.Attachments = message.Attachments
+		}
+
+		// Set request headers into message context properties. Headers on the
+		// message context itself are written to the response.
+		requestHeaders := make(map[string]string, len(r.Header))
+		for name := range r.Header {
+			requestHeaders[name] = r.Header.Get(name)
+		}
+		msgContext.Properties["http_request_headers"] = requestHeaders
+		// The method and the path below the API context, as HTTP inbound
+		// endpoints set them
+		msgContext.Properties["HTTP_METHOD"] = r.Method
+		msgContext.Properties["REST_URL_POSTFIX"] = r.URL.RequestURI()
+		// Callers authenticated by the API's security policy
+		if principal, ok := middleware.Principal(r); ok {
+			msgContext.Properties["AUTHENTICATED_USER"] = principal
+		}
+		msgContext.Properties[artifacts.SnapshotProperty] = artifacts.GetConfigContext().Snapshot()
+		if len(resource.StatusMappings) > 0 {
+			msgContext.Properties[artifacts.StatusMappingsProperty] = resource.StatusMappings
+		}
+		// The latency budget is the resource's timeout, shortened by the
+		// caller's when it is willing to wait less
+		if resource.Timeout > 0 {
+			artifacts.SetDeadline(msgContext, time.Now().Add(resource.Timeout))
+		}
+		if budget, ok := artifacts.ParseRequestTimeout(r.Header.Get(artifacts.RequestTimeoutHeader)); ok {
+			artifacts.SetDeadline(msgContext, time.Now().Add(budget))
+		}
+		if traceID := tracing.TraceID(r.Context()); traceID != "" {
+			msgContext.Properties[tracing.TraceIDProperty] = traceID
+		}
+		if trace := capture.TraceFromRequestContext(r.Context()); trace != nil {
+			capture.AddToContext(msgContext, trace)
+		}
+		if recorder := dryrun.FromRequestContext(r.Context()); recorder != nil {
+			dryrun.AddToContext(msgContext, recorder)
+		}
+		if recorder := transactions.FromRequestContext(r.Context()); recorder != nil {
+			transactions.AddToContext(msgContext, recorder)
+		}
+		if locator := geoip.Default(); locator != nil {
+			locator.Enrich(msgContext, r)
+		}
+		if detector := useragent.Default(); detector != nil {
+			detector.Enrich(msgContext, r)
+		}
 
 		// Set path parameters into message context properties
 		pathParamsMap := make(map[string]string)
@@ -155,18 +428,77 @@ func (rs *RouterService) createResourceHandler(resource artifacts.Resource) http
 		}
 
 		// Process through mediation pipeline
-		success := resource.Mediate(msgContext)
+		var success bool
+		if rs.engine != nil {
+			success = rs.engine.MediateAPIResource(r.Context(), resource, msgContext)
+		} else {
+			success = resource.Mediate(msgContext)
+		}
+		if recorder := transactions.FromContext(msgContext); recorder != nil {
+			recorder.Complete(msgContext, resource.URITemplate.PathTemplate, success)
+		}
+
+		// A dropped message is acknowledged without a body
+		if success && artifacts.Dropped(msgContext) {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
 
-		// Write response
-		if success {
+		// Write response. A fault built by a fault mediator is written as is,
+		// with the status it chose.
+		if success || artifacts.Faulted(msgContext) {
 			for name, value := range msgContext.Headers {
 				w.Header().Set(name, value)
 			}
+			// A mediator may choose the status of a successful response
+			writeStatus := func() {
+				if status, ok := msgContext.Properties[artifacts.HTTPStatusProperty].(int); ok && status >= 100 && status <= 599 {
+					w.WriteHeader(status)
+				}
+			}
 			if msgContext.Message.RawPayload != nil {
-				w.Write(msgContext.Message.RawPayload)
+				// Apply the masking policies collected during mediation
+				contentType := msgContext.Message.ContentType
+				if contentType == "" {
+					contentType = msgContext.Headers["Content-Type"]
+				}
+				payload, err := masking.FromContext(msgContext).Apply(msgContext.Message.RawPayload, contentType)
+				if err != nil {
+					rs.logger.Error("Failed to mask response payload", slog.String("error", err.Error()))
+					problem.Write(w, r, http.StatusInternalServerError, "The response could not be prepared")
+					return
+				}
+				// Responses that still refer to attachments are re-optimized
+				if len(msgContext.Message.Attachments) > 0 && len(mtom.References(payload)) > 0 {
+					var packageType string
+					payload, packageType, err = mtom.Encode(payload, contentType, msgContext.Message.Attachments)
+					if err != nil {
+						rs.logger.Error("Failed to encode MTOM response", slog.String("error", err.Error()))
+						problem.Write(w, r, http.StatusInternalServerError, "The response could not be prepared")
+						return
+					}
+					w.Header().Set("Content-Type", packageType)
+				}
+				if len(payload) != len(msgContext.Message.RawPayload) {
+					w.Header().Del("Content-Length")
+				}
+				writeStatus()
+				w.Write(payload)
+			} else {
+				writeStatus()
 			}
 		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			// Client errors chosen by a mediator are explained to the client;
+			// server errors are not
+			status, ok := msgContext.Properties[artifacts.HTTPStatusProperty].(int)
+			if !ok || status < 400 {
+				status = http.StatusInternalServerError
+			}
+			detail := "The request could not be mediated"
+			if message, ok := msgContext.Properties[artifacts.ErrorMessageProperty].(string); ok && status < 500 {
+				detail = message
+			}
+			problem.Write(w, r, status, detail)
 		}
 	}
 	return handler
@@ -189,7 +521,7 @@ func (rs *RouterService) createQueryParamMiddleware(resource artifacts.Resource,
 		for key := range queryParams {
 			if _, exists := resource.URITemplate.QueryParameters[key]; !exists {
 				// Query parameter not defined in the template, reject the request
-				http.Error(w, fmt.Sprintf("Unsupported query parameter: %s", key), http.StatusBadRequest)
+				problem.Write(w, r, http.StatusBadRequest, fmt.Sprintf("Unsupported query parameter: %s", key))
 				return
 			}
 		}
@@ -198,7 +530,7 @@ func (rs *RouterService) createQueryParamMiddleware(resource artifacts.Resource,
 		for key := range resource.URITemplate.QueryParameters {
 			if !queryParams.Has(key) {
 				// Required query parameter is missing, reject the request
-				http.Error(w, fmt.Sprintf("Missing required query parameter: %s", key), http.StatusBadRequest)
+				problem.Write(w, r, http.StatusBadRequest, fmt.Sprintf("Missing required query parameter: %s", key))
 				return
 			}
 		}
@@ -212,14 +544,21 @@ func (rs *RouterService) createQueryParamMiddleware(resource artifacts.Resource,
 func (rs *RouterService) StartServer(ctx context.Context) {
 	//eg:- localhost:8290
 	addr := rs.hostname + rs.port
+	var handler http.Handler = rs.router
+	if rs.httpHandlerWrapper != nil {
+		handler = rs.httpHandlerWrapper(rs.router)
+	}
 	rs.server = &http.Server{
-		Addr:    addr,
-		Handler: rs.router,
+		Addr:      addr,
+		Handler:   handler,
+		ConnState: leaks.Default().ConnState(componentName),
 	}
 
 	// Register health/liveness endpoints
 	rs.registerLivelinessEndpoint()
-	rs.logger.Info("liveness endpoint registered")
+	rs.registerReadinessEndpoint()
+	rs.logger.Info("liveness and readiness endpoints registered")
+	rs.registerAsyncStatusEndpoint()
 
 	// Start the server in a goroutine
 	go func() {
@@ -229,6 +568,30 @@ func (rs *RouterService) StartServer(ctx context.Context) {
 		}
 		rs.logger.Info("HTTP server stopped serving new connections")
 	}()
+
+	if rs.tlsConfig != nil {
+		rs.startTLSServer()
+	}
+}
+
+// startTLSServer starts the HTTPS listener using the configured tls.Config
+func (rs *RouterService) startTLSServer() {
+	addr := rs.hostname + rs.tlsPort
+	rs.tlsServer = &http.Server{
+		Addr:      addr,
+		Handler:   rs.router,
+		TLSConfig: rs.tlsConfig,
+		ConnState: leaks.Default().ConnState(componentName),
+	}
+
+	go func() {
+		rs.logger.Info("Starting HTTPS server", "address", addr)
+		// Certificates are supplied through TLSConfig.GetCertificate
+		if err := rs.tlsServer.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+			rs.logger.Error("HTTPS server error", slog.String("error", err.Error()))
+		}
+		rs.logger.Info("HTTPS server stopped serving new connections")
+	}()
 }
 
 func (rs *RouterService) StopServer() {
@@ -240,9 +603,16 @@ func (rs *RouterService) StopServer() {
 			rs.logger.Error("Error shutting down HTTP server", "error", err.Error())
 		}
 	}
+	if rs.tlsServer != nil {
+		rs.logger.Info("Shutting down HTTPS server...")
+		shutdownCtx, shutdownRelease := context.WithTimeout(context.Background(), 10*time.Second)
+		defer shutdownRelease()
+		if err := rs.tlsServer.Shutdown(shutdownCtx); err != nil {
+			rs.logger.Error("Error shutting down HTTPS server", "error", err.Error())
+		}
+	}
 }
 
-
 // registerHealthEndpoints registers health and liveness endpoints
 func (rs *RouterService) registerLivelinessEndpoint() {
 	// liveliness probe endpoint
@@ -254,4 +624,22 @@ func (rs *RouterService) registerLivelinessEndpoint() {
 			"timestamp": time.Now().Format(time.RFC3339),
 		})
 	})
-}
\ No newline at end of file
+}
+
+// registerReadinessEndpoint reports the runtime as ready while every critical
+// health check is healthy
+func (rs *RouterService) registerReadinessEndpoint() {
+	rs.router.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		status, code := "UP", http.StatusOK
+		if !rs.healthChecks.Ready() {
+			status, code = "DOWN", http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":    status,
+			"checks":    rs.healthChecks.Statuses(),
+			"timestamp": time.Now().Format(time.RFC3339),
+		})
+	})
+}
@@ -0,0 +1,899 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/apache/synapse-go/internal/app/core/ports"
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/asyncreply"
+	"github.com/apache/synapse-go/internal/pkg/core/cache"
+	"github.com/apache/synapse-go/internal/pkg/core/capture"
+	"github.com/apache/synapse-go/internal/pkg/core/metrics"
+	"github.com/apache/synapse-go/internal/pkg/core/mtom"
+	"github.com/apache/synapse-go/internal/pkg/core/problem"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/tracing"
+	"github.com/apache/synapse-go/internal/pkg/core/utils"
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+)
+
+// payloadMediator writes a fixed payload so tests can tell which API handled a request
+type payloadMediator struct {
+	payload string
+}
+
+func (m payloadMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	context.Message.RawPayload = []byte(m.payload)
+	return true, nil
+}
+
+// countingMediator answers like a backend and counts the calls it gets
+type countingMediator struct {
+	calls *int
+}
+
+func (m countingMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	*m.calls++
+	context.Message.RawPayload = []byte("catalog")
+	return true, nil
+}
+
+func newTestRouterService() *RouterService {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{
+		Format:     "text",
+		OutputPath: "stdout",
+	})
+	return NewRouterService(":0", "localhost")
+}
+
+func newTestAPI(name, context, hostname, payload string) artifacts.API {
+	return artifacts.API{
+		Name:     name,
+		Context:  context,
+		Hostname: hostname,
+		Resources: []artifacts.Resource{
+			{
+				Methods:     []string{"GET"},
+				URITemplate: artifacts.URITemplateInfo{FullTemplate: "/items", PathTemplate: "/items"},
+				InSequence:  artifacts.Sequence{MediatorList: []artifacts.Mediator{payloadMediator{payload: payload}}},
+			},
+		},
+	}
+}
+
+func TestRegisterAPI_VirtualHosts(t *testing.T) {
+	rs := newTestRouterService()
+	ctx := context.Background()
+
+	assert.NoError(t, rs.RegisterAPI(ctx, newTestAPI("FooAPI", "/shop", "api.foo.com", "foo")))
+	assert.NoError(t, rs.RegisterAPI(ctx, newTestAPI("BarAPI", "/shop", "api.bar.com", "bar")))
+	assert.NoError(t, rs.RegisterAPI(ctx, newTestAPI("AnyAPI", "/shop", "", "any")))
+
+	testCases := []struct {
+		name     string
+		host     string
+		expected string
+	}{
+		{name: "foo host", host: "api.foo.com", expected: "foo"},
+		{name: "bar host with port", host: "api.bar.com:8290", expected: "bar"},
+		{name: "unbound host falls back", host: "localhost:8290", expected: "any"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/shop/items", nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+			rs.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.expected, rec.Body.String())
+		})
+	}
+}
+
+func TestGetRegisteredRoutes(t *testing.T) {
+	rs := newTestRouterService()
+	ctx := context.Background()
+
+	api := newTestAPI("OrdersAPI", "/orders", "", "orders")
+	api.Version = "v1"
+	api.VersionType = "url"
+	api.Resources[0].Methods = []string{"POST", "GET"}
+	assert.NoError(t, rs.RegisterAPI(ctx, api))
+	assert.NoError(t, rs.RegisterHandler("/inbound/", http.NotFoundHandler()))
+
+	assert.Equal(t, []RouteInfo{
+		{Pattern: "/inbound/"},
+		{Method: "GET", Pattern: "/orders/v1/items", APIName: "OrdersAPI", Version: "v1"},
+		{Method: "POST", Pattern: "/orders/v1/items", APIName: "OrdersAPI", Version: "v1"},
+	}, rs.GetRegisteredRoutes())
+}
+
+func TestRegisterAPI_Conflicts(t *testing.T) {
+	rs := newTestRouterService()
+	ctx := context.Background()
+	assert.NoError(t, rs.RegisterAPI(ctx, newTestAPI("ShopAPI", "/shop", "", "shop")))
+
+	testCases := []struct {
+		name     string
+		api      artifacts.API
+		expected string
+	}{
+		{
+			name:     "Same context",
+			api:      newTestAPI("OtherShopAPI", "/shop", "", "other"),
+			expected: "cannot register API OtherShopAPI: context conflict: /shop overlaps with /shop registered by API ShopAPI",
+		},
+		{
+			name:     "Nested context",
+			api:      newTestAPI("ShopAdminAPI", "/shop/admin", "", "admin"),
+			expected: "cannot register API ShopAdminAPI: context conflict: /shop/admin overlaps with /shop registered by API ShopAPI",
+		},
+		{
+			name: "Duplicate resource",
+			api: func() artifacts.API {
+				api := newTestAPI("CartAPI", "/cart", "", "cart")
+				api.Resources = append(api.Resources, api.Resources[0])
+				return api
+			}(),
+			expected: "cannot register API CartAPI: duplicate resources: GET /items",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.EqualError(t, rs.RegisterAPI(ctx, tc.api), tc.expected)
+		})
+	}
+
+	// Templates that differ only in wildcard names are reported instead of panicking
+	api := newTestAPI("UsersAPI", "/users", "", "users")
+	api.Resources = append(api.Resources, api.Resources[0])
+	api.Resources[0].URITemplate.PathTemplate = "/{id}"
+	api.Resources[1].URITemplate.PathTemplate = "/{name}"
+	err := rs.RegisterAPI(ctx, api)
+	assert.ErrorContains(t, err, "route conflict")
+
+	// A sibling context is still accepted and the failed APIs left no routes behind
+	assert.NoError(t, rs.RegisterAPI(ctx, newTestAPI("ShopsAPI", "/shops", "", "shops")))
+	assert.Len(t, rs.GetRegisteredRoutes(), 2)
+}
+
+func TestRegisterAPI_DeprecationHeaders(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("LegacyAPI", "/legacy", "", "legacy")
+	api.Version = "v1"
+	api.VersionType = "url"
+	api.Deprecation = &artifacts.Deprecation{
+		Since:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:         time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC),
+		Link:           "https://docs.example.com/migrate",
+		WarningPercent: 100,
+	}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/legacy/v1/items", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "@1704067200", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 30 Jun 2025 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `<https://docs.example.com/migrate>; rel="deprecation"`, rec.Header().Get("Link"))
+	assert.Equal(t, `299 - "API LegacyAPI version v1 is deprecated and will be removed on Mon, 30 Jun 2025 00:00:00 GMT"`, rec.Header().Get("Warning"))
+}
+
+func TestRegisterAPI_DefaultVersion(t *testing.T) {
+	rs := newTestRouterService()
+	ctx := context.Background()
+
+	for _, version := range []string{"1.0", "2.0"} {
+		api := newTestAPI("StockAPI", "/stock/{version}", "", "stock "+version)
+		api.Version = version
+		api.VersionType = "context"
+		api.IsDefaultVersion = version == "1.0"
+		assert.NoError(t, rs.RegisterAPI(ctx, api))
+	}
+
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := get("/stock/items")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "stock 1.0", body)
+	_, body = get("/stock/2.0/items")
+	assert.Equal(t, "stock 2.0", body)
+
+	assert.NoError(t, rs.SetDefaultVersion("StockAPI", "2.0"))
+	_, body = get("/stock/items")
+	assert.Equal(t, "stock 2.0", body)
+
+	version, versions, err := rs.GetDefaultVersion("StockAPI")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0", version)
+	assert.Equal(t, []string{"1.0", "2.0"}, versions)
+
+	assert.EqualError(t, rs.SetDefaultVersion("StockAPI", "3.0"), "version 3.0 of API StockAPI is not deployed")
+	assert.Error(t, rs.SetDefaultVersion("UnknownAPI", "1.0"))
+
+	// Clearing the default version stops routing unversioned requests
+	assert.NoError(t, rs.SetDefaultVersion("StockAPI", ""))
+	code, _ = get("/stock/items")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Another API still cannot claim the unversioned context
+	assert.ErrorContains(t, rs.RegisterAPI(ctx, newTestAPI("OtherAPI", "/stock", "", "other")), "context conflict")
+}
+
+// failingMediator fails mediation so the resource answers with a server error
+type failingMediator struct{}
+
+func (failingMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	return false, nil
+}
+
+func TestRegisterAPI_SLODegradedMode(t *testing.T) {
+	rs := newTestRouterService()
+	configContext := artifacts.GetConfigContext()
+	configContext.AddSequence(artifacts.Sequence{
+		Name:         "OrdersDegraded",
+		MediatorList: []artifacts.Mediator{payloadMediator{payload: "degraded"}},
+	})
+	ctx := context.WithValue(context.Background(), utils.ConfigContextKey, configContext)
+
+	api := newTestAPI("OrdersAPI", "/orders", "", "orders")
+	failing := artifacts.Sequence{MediatorList: []artifacts.Mediator{failingMediator{}}}
+	api.Resources[0].InSequence = failing
+	api.Resources[0].FaultSequence = failing
+	api.SLO = &artifacts.SLO{Window: time.Minute, Percentile: 95, MaxErrorRate: 0.5, DegradedSequence: "OrdersDegraded"}
+	assert.NoError(t, rs.RegisterAPI(ctx, api))
+
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/items", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	statuses := rs.GetSLOStatus()
+	assert.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Breached)
+
+	// While the objective is breached the degraded sequence answers instead
+	rec = httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/items", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "degraded", rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get("X-Degraded"))
+}
+
+func TestReadinessEndpoint(t *testing.T) {
+	rs := newTestRouterService()
+	rs.registerReadinessEndpoint()
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, ready())
+
+	var probeErr error
+	check := artifacts.HealthCheck{Name: "Orders", Timeout: time.Second, FailureThreshold: 1, Critical: true}
+	assert.NoError(t, rs.HealthChecks().Register(check, func(ctx context.Context) error { return probeErr }))
+	assert.Equal(t, http.StatusServiceUnavailable, ready(), "pending critical check")
+
+	rs.HealthChecks().Probe(context.Background(), "Orders")
+	assert.Equal(t, http.StatusOK, ready())
+
+	probeErr = errors.New("down")
+	rs.HealthChecks().Probe(context.Background(), "Orders")
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+}
+
+func TestRegisterAPI_MetricsWithTraceExemplars(t *testing.T) {
+	rs := newTestRouterService()
+	rs.metrics = metrics.NewRegistry()
+	configContext := &artifacts.ConfigContext{DeploymentConfig: map[string]interface{}{
+		"metrics": metrics.Config{Enabled: true},
+		"tracing": tracing.Config{Enabled: true},
+	}}
+	ctx := context.WithValue(context.Background(), utils.ConfigContextKey, configContext)
+	assert.NoError(t, rs.RegisterAPI(ctx, newTestAPI("OrdersAPI", "/orders", "", "orders")))
+
+	request := httptest.NewRequest(http.MethodGet, "/orders/items", nil)
+	request.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, request)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(tracing.TraceparentHeader), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	var out strings.Builder
+	rs.metrics.WriteOpenMetrics(&out)
+	assert.Contains(t, out.String(), `synapse_api_request_duration_seconds_count{api="OrdersAPI",method="GET",code="200"} 1`)
+	assert.Contains(t, out.String(), `# {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"}`)
+}
+
+func TestRegisterAPI_CaptureSession(t *testing.T) {
+	rs := newTestRouterService()
+	rs.capture = capture.NewRecorder(t.TempDir())
+	assert.NoError(t, rs.RegisterAPI(context.Background(), newTestAPI("OrdersAPI", "/orders", "", "orders")))
+	session, err := rs.capture.Start(capture.Filter{API: "OrdersAPI"}, 1, 0)
+	assert.NoError(t, err)
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/orders/items", strings.NewReader("request"))
+		request.Header.Set("Authorization", "Bearer secret")
+		// Clients cannot skip capture by claiming to be a replay
+		request.Header.Set("X-Synapse-Replay", "20240101T000000-00000000-0001")
+		rs.router.ServeHTTP(rec, request)
+		assert.Equal(t, "orders", rec.Body.String())
+	}
+
+	files, err := os.ReadDir(session.Directory)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "only the first message is captured")
+	data, err := os.ReadFile(filepath.Join(session.Directory, files[0].Name()))
+	assert.NoError(t, err)
+	var record capture.Record
+	assert.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, "request", record.Exchange.RequestBody)
+	assert.Equal(t, []string{"****"}, record.Exchange.RequestHeaders["Authorization"])
+	assert.Equal(t, http.StatusOK, record.Exchange.Status)
+	assert.Equal(t, "orders", record.Exchange.ResponseBody)
+	assert.Len(t, record.Steps, 1)
+	assert.Equal(t, "payloadMediator", record.Steps[0].Mediator)
+	assert.Equal(t, "orders", record.Steps[0].Payload)
+}
+
+// panickingMediator simulates a mediator with a bug
+type panickingMediator struct{}
+
+func (panickingMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	var headers map[string]string
+	headers["boom"] = "value"
+	return true, nil
+}
+
+func TestRegisterAPI_MediatorPanic(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("OrdersAPI", "/orders", "", "orders")
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{panickingMediator{}}}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/items", nil))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code, "the server keeps serving after a panic")
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	rs := newTestRouterService()
+	rs.metrics = metrics.NewRegistry()
+	api := artifacts.API{Name: "OrdersAPI"}
+	handler := rs.createRecoveryMiddleware(api, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("unexpected")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/items", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, uint64(1), rs.metrics.Counter(artifacts.PanicMetric, "").Value("api:OrdersAPI"))
+
+	// Deliberate aborts are left to net/http
+	abort := rs.createRecoveryMiddleware(api, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/items", nil))
+	})
+}
+
+func TestRegisterAPI_ProblemDetails(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("OrdersAPI", "/orders", "", "orders")
+	api.Resources[0].URITemplate.QueryParameters = map[string]string{"id": "id"}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	request := httptest.NewRequest(http.MethodGet, "/orders/items?id=1&debug=true", nil)
+	request.Header.Set(problem.CorrelationHeader, "req-42")
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, request)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "req-42", rec.Header().Get(problem.CorrelationHeader))
+	assert.JSONEq(t, `{"type": "about:blank", "title": "Bad Request", "status": 400,
+		"detail": "Unsupported query parameter: debug", "instance": "/items", "correlationId": "req-42"}`, rec.Body.String())
+}
+
+func TestRegisterAPI_ErrorTemplate(t *testing.T) {
+	rs := newTestRouterService()
+	template, err := problem.ParseTemplate("", `{"code": {{.Status}}, "message": {{json .Detail}}}`)
+	assert.NoError(t, err)
+	api := newTestAPI("OrdersAPI", "/orders", "", "orders")
+	failing := artifacts.Sequence{MediatorList: []artifacts.Mediator{failingMediator{}}}
+	api.Resources[0].InSequence = failing
+	api.Resources[0].FaultSequence = failing
+	api.ErrorTemplate = template
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/items", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code": 500, "message": "The request could not be mediated"}`, rec.Body.String())
+}
+
+func TestRegisterAPI_GatewayErrorHandling(t *testing.T) {
+	rs := newTestRouterService()
+	artifacts.GetConfigContext().AddSequence(artifacts.Sequence{Name: "GatewayFault",
+		MediatorList: []artifacts.Mediator{payloadMediator{payload: "gateway fault"}}})
+	configContext := &artifacts.ConfigContext{DeploymentConfig: map[string]interface{}{
+		"errors": problem.Config{DefaultFaultSequence: "GatewayFault", Templates: map[string]problem.TemplateConfig{
+			"404": {Body: `{"error": "not_found", "message": {{json .Detail}}}`},
+			"405": {Body: `{"error": "method_not_allowed"}`},
+		}},
+	}}
+	ctx := context.WithValue(context.Background(), utils.ConfigContextKey, configContext)
+
+	failing := artifacts.Sequence{MediatorList: []artifacts.Mediator{failingMediator{}}}
+	api := newTestAPI("OrdersAPI", "/orders", "", "orders")
+	api.Resources[0].InSequence = failing
+	assert.NoError(t, rs.RegisterAPI(ctx, api))
+	own := newTestAPI("StockAPI", "/stock", "", "stock")
+	own.Resources[0].InSequence = failing
+	own.Resources[0].FaultSequence = failing
+	own.ErrorTemplate, _ = problem.ParseTemplate("text/plain", `stock error {{.Status}}`)
+	assert.NoError(t, rs.RegisterAPI(ctx, own))
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantBody  string
+		wantAllow string
+	}{
+		{name: "default fault sequence", method: http.MethodGet, path: "/orders/items", wantCode: http.StatusOK, wantBody: "gateway fault"},
+		{name: "not found", method: http.MethodGet, path: "/orders/missing", wantCode: http.StatusNotFound,
+			wantBody: `{"error": "not_found", "message": "No resource matches /missing"}`},
+		{name: "method not allowed", method: http.MethodDelete, path: "/orders/items", wantCode: http.StatusMethodNotAllowed,
+			wantBody: `{"error": "method_not_allowed"}`, wantAllow: "GET, HEAD"},
+		{name: "own fault sequence", method: http.MethodGet, path: "/stock/items", wantCode: http.StatusInternalServerError, wantBody: "stock error 500"},
+		{name: "own error template", method: http.MethodGet, path: "/stock/missing", wantCode: http.StatusNotFound, wantBody: "stock error 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rs.router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, rec.Body.String())
+			assert.Equal(t, tt.wantAllow, rec.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestRegisterAPI_ResponseCache(t *testing.T) {
+	rs := newTestRouterService()
+	calls := 0
+	backend := countingMediator{calls: &calls}
+	store := cache.NewStore(10)
+	api := newTestAPI("CatalogAPI", "/catalog", "", "")
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{
+		artifacts.CacheMediator{Scope: "catalog", TTL: time.Minute, Store: store},
+		backend,
+		artifacts.CacheMediator{Collector: true, Store: store},
+	}}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	for _, query := range []string{"?page=1", "?page=1", "?page=2"} {
+		rec := httptest.NewRecorder()
+		rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog/items"+query, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "catalog", rec.Body.String())
+	}
+	assert.Equal(t, 2, calls, "the repeated request is answered from the cache")
+}
+
+func TestRegisterAPI_RequestValidation(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("OrdersAPI", "/orders", "", "orders")
+	api.Resources[0].InSequence.MediatorList = append([]artifacts.Mediator{
+		artifacts.ValidateRequestMediator{ContentTypes: []string{"application/json"}},
+	}, api.Resources[0].InSequence.MediatorList...)
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	request := httptest.NewRequest(http.MethodGet, "/orders/items", nil)
+	request.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, request)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"detail":"unsupported content type text/plain, expected one of application/json"`)
+
+	request.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, request)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "orders", rec.Body.String())
+}
+
+// stubEngine answers every API request with a fixed payload
+type stubEngine struct {
+	ports.MediationEngine
+}
+
+func (stubEngine) MediateAPIResource(ctx context.Context, resource artifacts.Resource, msg *synctx.MsgContext) bool {
+	msg.Message.RawPayload = []byte("engine")
+	return true
+}
+
+func TestRegisterAPI_MediationEngine(t *testing.T) {
+	rs := newTestRouterService()
+	rs.SetMediationEngine(stubEngine{})
+	assert.NoError(t, rs.RegisterAPI(context.Background(), newTestAPI("OrdersAPI", "/orders", "", "orders")))
+
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/items", nil))
+	assert.Equal(t, "engine", rec.Body.String())
+}
+
+// echoMediator answers with the request body
+type echoMediator struct{}
+
+func (echoMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	body, err := io.ReadAll(context.Properties["http_request_body"].(io.Reader))
+	context.Message.RawPayload = body
+	return err == nil, err
+}
+
+func TestRegisterAPI_MTOM(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("UploadAPI", "/upload", "", "")
+	api.Resources[0].Methods = []string{"POST"}
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{echoMediator{}}}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	envelope := `<Envelope><Body><xop:Include xmlns:xop="http://www.w3.org/2004/08/xop/include" href="cid:scan@example.com"/></Body></Envelope>`
+	scan := synctx.Attachment{ContentID: "scan@example.com", ContentType: "image/png", Data: []byte("PNG")}
+	body, contentType, err := mtom.Encode([]byte(envelope), "text/xml", []synctx.Attachment{scan})
+	assert.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "/upload/items", bytes.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, request)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, mtom.IsMTOM(rec.Header().Get("Content-Type")), "responses referring to attachments are re-optimized")
+
+	response, err := mtom.Decode(rec.Header().Get("Content-Type"), rec.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, envelope, string(response.Payload))
+	assert.Equal(t, []synctx.Attachment{scan}, response.Attachments)
+
+	request = httptest.NewRequest(http.MethodPost, "/upload/items", strings.NewReader("not multipart"))
+	request.Header.Set("Content-Type", `multipart/related; type="application/xop+xml"`)
+	rec = httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, request)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRegisterAPI_ResponseStatusAndHeaders(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("OrdersAPI", "/orders", "", "")
+	api.Resources[0].Methods = []string{"POST"}
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{
+		echoMediator{},
+		artifacts.PropertyMediator{Name: artifacts.HTTPStatusProperty, Value: http.StatusCreated},
+		artifacts.PropertyMediator{Name: "Location", Scope: artifacts.PropertyScopeTransport, Value: "/orders/42"},
+		artifacts.RespondMediator{},
+		artifacts.PropertyMediator{Name: artifacts.HTTPStatusProperty, Value: http.StatusTeapot},
+	}}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders/items", strings.NewReader(`{"qty":1}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code, "mediators after respond are not executed")
+	assert.Equal(t, "/orders/42", rec.Header().Get("Location"))
+	assert.Equal(t, `{"qty":1}`, rec.Body.String())
+}
+
+// budgetMediator answers with the milliseconds left until the deadline
+type budgetMediator struct{}
+
+func (budgetMediator) Execute(context *synctx.MsgContext) (bool, error) {
+	if deadline, ok := artifacts.Deadline(context); ok {
+		context.Message.RawPayload = []byte(artifacts.FormatRequestTimeout(deadline, time.Now()))
+	}
+	return true, nil
+}
+
+func TestRegisterAPI_LatencyBudget(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("OrdersAPI", "/orders", "", "")
+	api.Resources[0].Timeout = 10 * time.Second
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{budgetMediator{}}}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	budget := func(header string) time.Duration {
+		request := httptest.NewRequest(http.MethodGet, "/orders/items", nil)
+		if header != "" {
+			request.Header.Set(artifacts.RequestTimeoutHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		rs.router.ServeHTTP(rec, request)
+		left, _ := artifacts.ParseRequestTimeout(rec.Body.String())
+		return left
+	}
+	assert.InDelta(t, 10*time.Second, budget(""), float64(time.Second), "the resource's timeout applies")
+	assert.InDelta(t, 2*time.Second, budget("2000"), float64(time.Second), "a caller willing to wait less shortens it")
+	assert.InDelta(t, 10*time.Second, budget("60000"), float64(time.Second), "a caller cannot extend it")
+	assert.InDelta(t, 10*time.Second, budget("soon"), float64(time.Second))
+}
+
+func TestRegisterAPI_DroppedMessage(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("OrdersAPI", "/orders", "", "")
+	api.Resources[0].Methods = []string{"POST"}
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{
+		echoMediator{},
+		artifacts.PropertyMediator{Name: artifacts.HTTPStatusProperty, Value: http.StatusCreated},
+		artifacts.DropMediator{},
+		artifacts.PropertyMediator{Name: "Location", Scope: artifacts.PropertyScopeTransport, Value: "/orders/42"},
+	}}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders/items", strings.NewReader(`{"qty":1}`)))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Location"), "mediators after drop are not executed")
+}
+
+func TestRegisterAPI_Fault(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("OrdersAPI", "/orders", "", "")
+	api.Resources[0].Methods = []string{"POST"}
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{
+		artifacts.FaultMediator{Version: artifacts.FaultVersionJSON, Status: http.StatusUnprocessableEntity, Code: "ORDER_INVALID",
+			Reason: artifacts.FaultText{Value: "The order has no items"}},
+	}}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders/items", strings.NewReader(`{"items":[]}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"ORDER_INVALID","reason":"The order has no items"}`, rec.Body.String())
+}
+
+func TestRegisterAPI_EchoAndStaticResources(t *testing.T) {
+	rs := newTestRouterService()
+	api := newTestAPI("MockAPI", "/mock", "", "")
+	api.Resources = []artifacts.Resource{
+		{
+			Type: artifacts.ResourceTypeEcho, Methods: []string{"POST"},
+			URITemplate: artifacts.URITemplateInfo{FullTemplate: "/echo", PathTemplate: "/echo"},
+			InSequence:  artifacts.Sequence{MediatorList: []artifacts.Mediator{artifacts.EchoMediator{}}},
+		},
+		{
+			Type: artifacts.ResourceTypeStatic, Methods: []string{"GET"},
+			URITemplate: artifacts.URITemplateInfo{FullTemplate: "/health", PathTemplate: "/health"},
+			InSequence: artifacts.Sequence{MediatorList: []artifacts.Mediator{artifacts.StaticResponseMediator{
+				Status: http.StatusOK, ContentType: "application/json", Payload: `{"status":"UP"}`}}},
+		},
+	}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	request := httptest.NewRequest(http.MethodPost, "/mock/echo", strings.NewReader(`<ping/>`))
+	request.Header.Set("Content-Type", "application/xml")
+	request.Header.Set("X-Request-Id", "r-1")
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, request)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `<ping/>`, rec.Body.String())
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "r-1", rec.Header().Get("X-Request-Id"))
+
+	rec = httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/mock/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"status":"UP"}`, rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
+
+func TestRegisterAPI_AsyncResource(t *testing.T) {
+	rs := newTestRouterService()
+	rs.async = asyncreply.NewTracker(asyncreply.NewMemoryStore())
+	rs.registerAsyncStatusEndpoint()
+	api := newTestAPI("OrdersAPI", "/orders", "", "")
+	api.Resources[0].Methods = []string{"POST"}
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{echoMediator{}}}
+	callbacks := make(chan string, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callbacks <- r.Header.Get(asyncreply.IDHeader) + " " + string(body)
+	}))
+	defer callback.Close()
+	dispatcher, err := webhook.NewDispatcher(webhook.Config{AllowPrivateCallbacks: true}, nil)
+	assert.NoError(t, err)
+	webhook.SetDefault(dispatcher)
+	defer func() {
+		restored, _ := webhook.NewDispatcher(webhook.Config{}, nil)
+		webhook.SetDefault(restored)
+	}()
+	api.Resources[0].Async = &artifacts.AsyncReply{TTL: time.Minute, Callbacks: []string{callback.URL + "/hooks"}}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	request := httptest.NewRequest(http.MethodPost, "/orders/items", strings.NewReader(`{"sku":"A1"}`))
+	request.Header.Set(asyncreply.CallbackHeader, callback.URL+"/hooks/orders")
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, request)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var accepted asyncStatus
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &accepted))
+	assert.Equal(t, asyncreply.StatusPending, accepted.Status)
+	assert.Equal(t, "/_async/"+accepted.ID, accepted.StatusURL)
+	assert.Equal(t, accepted.StatusURL, rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, accepted.StatusURL+"?wait=5s", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"sku":"A1"}`, rec.Body.String())
+	assert.Equal(t, accepted.ID, rec.Header().Get(asyncreply.IDHeader))
+
+	select {
+	case delivered := <-callbacks:
+		assert.Equal(t, accepted.ID+` {"sku":"A1"}`, delivered)
+	case <-time.After(5 * time.Second):
+		t.Fatal("result was not posted to the callback URL")
+	}
+
+	rec = httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_async/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	for _, callbackURL := range []string{"file:///etc/passwd", callback.URL + "/admin", "http://169.254.169.254/hooks"} {
+		request = httptest.NewRequest(http.MethodPost, "/orders/items", nil)
+		request.Header.Set(asyncreply.CallbackHeader, callbackURL)
+		rec = httptest.NewRecorder()
+		rs.router.ServeHTTP(rec, request)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, callbackURL)
+	}
+}
+
+func TestRegisterAPI_Mirror(t *testing.T) {
+	rs := newTestRouterService()
+	mirrored := make(chan string, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mirrored <- r.Method + " " + r.URL.RequestURI() + " " + string(body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+	artifacts.GetConfigContext().AddEndpoint(artifacts.Endpoint{
+		Name:     "OrdersShadowEP",
+		Protocol: artifacts.ProtocolHTTP,
+		Options: map[string]*template.Template{
+			"method":       template.Must(template.New("method").Parse(`{{.Property "HTTP_METHOD"}}`)),
+			"uri-template": template.Must(template.New("uri").Parse(shadow.URL + `{{.Property "REST_URL_POSTFIX"}}`)),
+		},
+	})
+
+	api := newTestAPI("MirroredAPI", "/mirrored", "", "")
+	api.Resources[0].Methods = []string{"POST"}
+	api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{echoMediator{}}}
+	api.Mirror = &artifacts.Mirror{Endpoint: "OrdersShadowEP", Percent: 100}
+	assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+
+	// The client is answered by the API whatever the mirror responds
+	rec := httptest.NewRecorder()
+	rs.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mirrored/items?dry=1", strings.NewReader(`{"sku":"A1"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"sku":"A1"}`, rec.Body.String())
+
+	select {
+	case request := <-mirrored:
+		assert.Equal(t, `POST /items?dry=1 {"sku":"A1"}`, request)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request was not mirrored")
+	}
+}
+
+func TestRegisterAPI_Overflow(t *testing.T) {
+	rs := newTestRouterService()
+	stored := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Location", "/blobs/1")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "%s %s %d", r.Method, r.URL.RequestURI(), len(body))
+	}))
+	defer stored.Close()
+	artifacts.GetConfigContext().AddEndpoint(artifacts.Endpoint{
+		Name:     "BlobStoreEP",
+		Protocol: artifacts.ProtocolHTTP,
+		Options: map[string]*template.Template{
+			"method":       template.Must(template.New("method").Parse(`{{.Property "HTTP_METHOD"}}`)),
+			"uri-template": template.Must(template.New("uri").Parse(stored.URL + `{{.Property "REST_URL_POSTFIX"}}`)),
+		},
+	})
+	artifacts.GetConfigContext().AddSequence(artifacts.Sequence{Name: "ClaimCheckSeq",
+		MediatorList: []artifacts.Mediator{echoMediator{}}})
+
+	register := func(name string, overflow artifacts.Overflow) {
+		api := newTestAPI(name, "/"+name, "", "")
+		api.Resources[0].Methods = []string{"POST"}
+		api.Resources[0].InSequence = artifacts.Sequence{MediatorList: []artifacts.Mediator{echoMediator{}}}
+		api.Overflow = &overflow
+		assert.NoError(t, rs.RegisterAPI(context.Background(), api))
+	}
+	register("sequence", artifacts.Overflow{MaxSize: 8, Sequence: "ClaimCheckSeq"})
+	register("endpoint", artifacts.Overflow{MaxSize: 8, Endpoint: "BlobStoreEP"})
+	register("missing", artifacts.Overflow{MaxSize: 8, Sequence: "MissingSeq"})
+
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		chunked      bool
+		wantStatus   int
+		wantBody     string
+		wantOverflow bool
+	}{
+		{name: "small request", path: "/sequence/items", body: "12345678", wantStatus: http.StatusOK, wantBody: "12345678"},
+		{name: "small chunked request", path: "/sequence/items", body: "1234", chunked: true, wantStatus: http.StatusOK, wantBody: "1234"},
+		{name: "oversized request", path: "/sequence/items", body: "123456789", wantStatus: http.StatusOK, wantBody: "123456789", wantOverflow: true},
+		{name: "oversized chunked request", path: "/sequence/items", body: "0123456789abcdef", chunked: true, wantStatus: http.StatusOK, wantBody: "0123456789abcdef", wantOverflow: true},
+		{name: "forwarded to endpoint", path: "/endpoint/items?id=1", body: "0123456789", wantStatus: http.StatusCreated, wantBody: "POST /items?id=1 10", wantOverflow: true},
+		{name: "overflow not deployed", path: "/missing/items", body: "0123456789", wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				request.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			rs.router.ServeHTTP(rec, request)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, rec.Body.String())
+			}
+			assert.Equal(t, tt.wantOverflow, rec.Header().Get(OverflowHeader) == "true")
+		})
+	}
+}
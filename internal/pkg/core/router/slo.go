@@ -0,0 +1,119 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/slo"
+	"github.com/apache/synapse-go/internal/pkg/core/utils"
+)
+
+// statusRecorder captures the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// createSLOMiddleware records the latency and outcome of every request against
+// the API's service level objective. While the objective is breached, requests
+// are served by the degraded sequence when one is configured.
+func (rs *RouterService) createSLOMiddleware(ctx context.Context, api artifacts.API, next http.Handler) http.Handler {
+	if api.SLO == nil {
+		return next
+	}
+
+	var alerts slo.AlertsConfig
+	configContext, _ := ctx.Value(utils.ConfigContextKey).(*artifacts.ConfigContext)
+	if configContext != nil {
+		alerts, _ = configContext.DeploymentConfig["alerts"].(slo.AlertsConfig)
+	}
+	monitor := slo.NewMonitor(api.Key(), slo.Objective{
+		Window:       api.SLO.Window,
+		MaxLatency:   api.SLO.MaxLatency,
+		Percentile:   api.SLO.Percentile,
+		MaxErrorRate: api.SLO.MaxErrorRate,
+		MinRequests:  api.SLO.MinRequests,
+	}, alerts.HooksFor(api.Name, rs.logger))
+
+	rs.monitorsMu.Lock()
+	rs.monitors[api.Key()] = monitor
+	rs.monitorsMu.Unlock()
+
+	// The degraded handler is bound on first use, since the sequence may be
+	// deployed after the API
+	var degraded atomic.Pointer[http.HandlerFunc]
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := next
+		if api.SLO.DegradedSequence != "" && monitor.Breached() && configContext != nil {
+			if bound := degraded.Load(); bound != nil {
+				handler = *bound
+				w.Header().Set("X-Degraded", "true")
+			} else if sequence, ok := configContext.Snapshot().Sequences[api.SLO.DegradedSequence]; ok {
+				resourceHandler := rs.createResourceHandler(artifacts.Resource{InSequence: sequence})
+				degraded.Store(&resourceHandler)
+				handler = resourceHandler
+				w.Header().Set("X-Degraded", "true")
+			} else {
+				rs.logger.Warn("Degraded sequence not found",
+					slog.String("api_name", api.Name),
+					slog.String("sequence", api.SLO.DegradedSequence))
+			}
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		handler.ServeHTTP(recorder, r)
+		monitor.Record(time.Since(start), recorder.status >= http.StatusInternalServerError, time.Now())
+	})
+}
+
+// GetSLOStatus returns the current state of every API objective, sorted by API
+func (rs *RouterService) GetSLOStatus() []slo.Status {
+	rs.monitorsMu.RLock()
+	statuses := make([]slo.Status, 0, len(rs.monitors))
+	for _, monitor := range rs.monitors {
+		statuses = append(statuses, monitor.Status())
+	}
+	rs.monitorsMu.RUnlock()
+	slices.SortFunc(statuses, func(a, b slo.Status) int { return strings.Compare(a.API, b.API) })
+	return statuses
+}
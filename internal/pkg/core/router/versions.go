@@ -0,0 +1,157 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package router
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+)
+
+// versionFamily routes requests without a version segment to the default
+// version of an API
+type versionFamily struct {
+	hostname string
+	path     string
+
+	mu             sync.RWMutex
+	handlers       map[string]http.Handler
+	defaultVersion string
+}
+
+func (f *versionFamily) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.RLock()
+	handler := f.handlers[f.defaultVersion]
+	f.mu.RUnlock()
+	if handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// unversionedPath returns the base path of an API without its version segment
+func unversionedPath(api artifacts.API) string {
+	path := api.Context
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	if api.VersionType == "context" {
+		path = strings.Replace(path, "/{version}", "", 1)
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+// mountVersion registers a versioned API at basePath and, when defaultHandler is
+// not nil, makes it available to the default-version route of its family
+func (rs *RouterService) mountVersion(api artifacts.API, basePath string, handler http.Handler, defaultPath string, defaultHandler http.Handler) error {
+	rs.mountsMu.Lock()
+	defer rs.mountsMu.Unlock()
+
+	versioned := mountedContext{hostname: api.Hostname, path: basePath, owner: "API " + api.Name, family: api.Name}
+	if err := rs.checkMountLocked(versioned); err != nil {
+		return err
+	}
+
+	family, exists := rs.versionFamilies[api.Name]
+	if exists && (family.hostname != api.Hostname || family.path != defaultPath) {
+		return fmt.Errorf("version %s of API %s does not share the context of its other versions", api.Version, api.Name)
+	}
+	if !exists && defaultHandler != nil {
+		family = &versionFamily{hostname: api.Hostname, path: defaultPath, handlers: make(map[string]http.Handler)}
+		unversioned := mountedContext{hostname: api.Hostname, path: defaultPath, owner: "API " + api.Name + " (default version)", family: api.Name}
+		if err := rs.checkMountLocked(unversioned); err != nil {
+			return err
+		}
+		if err := rs.mountLocked(versioned, handler); err != nil {
+			return err
+		}
+		if err := rs.mountLocked(unversioned, family); err != nil {
+			return err
+		}
+		rs.versionFamilies[api.Name] = family
+	} else if err := rs.mountLocked(versioned, handler); err != nil {
+		return err
+	}
+
+	if family == nil {
+		return nil
+	}
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	family.handlers[api.Version] = defaultHandler
+	if api.IsDefaultVersion {
+		family.defaultVersion = api.Version
+		rs.logger.Info("Default version set for API",
+			slog.String("api_name", api.Name),
+			slog.String("version", api.Version))
+	}
+	return nil
+}
+
+// SetDefaultVersion changes the version serving requests without a version
+// segment. An empty version disables default-version routing for the API.
+func (rs *RouterService) SetDefaultVersion(apiName, version string) error {
+	rs.mountsMu.Lock()
+	family, exists := rs.versionFamilies[apiName]
+	rs.mountsMu.Unlock()
+	if !exists {
+		return fmt.Errorf("API %s has no versions that support default-version routing", apiName)
+	}
+
+	family.mu.Lock()
+	defer family.mu.Unlock()
+	if _, ok := family.handlers[version]; !ok && version != "" {
+		return fmt.Errorf("version %s of API %s is not deployed", version, apiName)
+	}
+	family.defaultVersion = version
+	rs.logger.Info("Default version changed for API",
+		slog.String("api_name", apiName),
+		slog.String("version", version))
+	return nil
+}
+
+// GetDefaultVersion returns the current default version of an API and the
+// versions it can be switched to
+func (rs *RouterService) GetDefaultVersion(apiName string) (string, []string, error) {
+	rs.mountsMu.Lock()
+	family, exists := rs.versionFamilies[apiName]
+	rs.mountsMu.Unlock()
+	if !exists {
+		return "", nil, fmt.Errorf("API %s has no versions that support default-version routing", apiName)
+	}
+
+	family.mu.RLock()
+	defer family.mu.RUnlock()
+	versions := make([]string, 0, len(family.handlers))
+	for version := range family.handlers {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return family.defaultVersion, versions, nil
+}
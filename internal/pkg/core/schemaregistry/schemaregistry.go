@@ -0,0 +1,372 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package schemaregistry is a client of Confluent-compatible schema
+// registries. It resolves the writer schemas of Avro, Protobuf and JSON
+// Schema payloads from the schema ID of their wire format, caching them
+// locally, and checks schemas kept in the artifacts registry against the
+// subjects they are produced to when artifacts deploy.
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the [schemaRegistry] section
+const (
+	DefaultTimeout  = 10 * time.Second
+	DefaultCacheTTL = 5 * time.Minute
+)
+
+// Schema types as the registry names them
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+	TypeJSON     = "JSON"
+)
+
+// maxCacheEntries bounds each cache of a client
+const maxCacheEntries = 10000
+
+// Config holds the [schemaRegistry] section of deployment.toml
+type Config struct {
+	// URL is the base URL of the registry, e.g. http://localhost:8081
+	URL string `koanf:"url"`
+	// Username and Password are sent with basic auth when set, e.g. the API
+	// key and secret of Confluent Cloud
+	Username string `koanf:"username"`
+	Password string `koanf:"password"`
+	Timeout  string `koanf:"timeout"`
+	// CacheTTL is how long the latest version of a subject is reused. Schemas
+	// looked up by ID never change and stay cached.
+	CacheTTL string `koanf:"cacheTTL"`
+	// Subjects are checked for compatibility when artifacts deploy
+	Subjects []SubjectConfig `koanf:"subjects"`
+}
+
+// SubjectConfig pairs a subject with the schema artifacts produce to it
+type SubjectConfig struct {
+	Subject string `koanf:"subject"`
+	// Schema is the path of the schema file below artifacts/Registry, e.g.
+	// schemas/order.avsc
+	Schema string `koanf:"schema"`
+	// Type is AVRO, PROTOBUF or JSON, by default from the extension of the
+	// schema file: .avsc, .proto or .json
+	Type string `koanf:"type"`
+}
+
+type settings struct {
+	baseURL  *url.URL
+	timeout  time.Duration
+	cacheTTL time.Duration
+}
+
+// Validate reports configuration errors
+func (c Config) Validate() error {
+	_, err := c.settings()
+	return err
+}
+
+func (c Config) settings() (settings, error) {
+	s := settings{timeout: DefaultTimeout, cacheTTL: DefaultCacheTTL}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return s, fmt.Errorf("schemaRegistry: url must be an http:// or https:// URL, got: %s", c.URL)
+	}
+	s.baseURL = u
+	if c.Password != "" && c.Username == "" {
+		return s, errors.New("schemaRegistry: password requires a username")
+	}
+	if c.Timeout != "" {
+		if s.timeout, err = time.ParseDuration(c.Timeout); err != nil || s.timeout <= 0 {
+			return s, fmt.Errorf("schemaRegistry: timeout must be a positive duration, got: %s", c.Timeout)
+		}
+	}
+	if c.CacheTTL != "" {
+		if s.cacheTTL, err = time.ParseDuration(c.CacheTTL); err != nil || s.cacheTTL < 0 {
+			return s, fmt.Errorf("schemaRegistry: cacheTTL must be a duration, got: %s", c.CacheTTL)
+		}
+	}
+	for _, subject := range c.Subjects {
+		if subject.Subject == "" || subject.Schema == "" {
+			return s, errors.New("schemaRegistry: subjects need a subject and a schema")
+		}
+		if !filepath.IsLocal(subject.Schema) {
+			return s, fmt.Errorf("schemaRegistry: schema of subject %s must be a path below the registry, got: %s", subject.Subject, subject.Schema)
+		}
+		if _, err := subject.schemaType(); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// Schema is a schema registered under an ID, and under a subject and version
+// when looked up by subject
+type Schema struct {
+	ID      int    `json:"id,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+	// Type is AVRO, PROTOBUF or JSON
+	Type       string      `json:"schemaType,omitempty"`
+	Schema     string      `json:"schema"`
+	References []Reference `json:"references,omitempty"`
+}
+
+// Reference names another schema a schema imports, e.g. a .proto file
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Error is an error response of the registry, e.g. code 40401 when a
+// subject does not exist
+type Error struct {
+	StatusCode int
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry answered %d: %s (error code %d)", e.StatusCode, e.Message, e.Code)
+}
+
+// Error codes of the registry
+const (
+	CodeSubjectNotFound = 40401
+	CodeVersionNotFound = 40402
+	CodeSchemaNotFound  = 40403
+)
+
+// IncompatibleError reports a schema the registry would reject for a
+// subject, with the reasons it gave
+type IncompatibleError struct {
+	Subject string
+	Reasons []string
+}
+
+func (e *IncompatibleError) Error() string {
+	message := "schema is incompatible with the latest version of subject " + e.Subject
+	if len(e.Reasons) > 0 {
+		message += ": " + strings.Join(e.Reasons, "; ")
+	}
+	return message
+}
+
+type cachedSchema struct {
+	schema  Schema
+	expires time.Time
+}
+
+// Client talks to a schema registry. It is safe for concurrent use.
+type Client struct {
+	config   Config
+	settings settings
+	client   *http.Client
+	now      func() time.Time
+
+	mu     sync.Mutex
+	byID   map[int]Schema
+	latest map[string]cachedSchema
+}
+
+// NewClient returns a client for a validated configuration
+func NewClient(config Config) (*Client, error) {
+	s, err := config.settings()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		config:   config,
+		settings: s,
+		client:   &http.Client{Timeout: s.timeout},
+		now:      time.Now,
+		byID:     make(map[int]Schema),
+		latest:   make(map[string]cachedSchema),
+	}, nil
+}
+
+// SchemaByID returns the schema registered under id
+func (c *Client) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.Lock()
+	schema, ok := c.byID[id]
+	c.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &schema); err != nil {
+		return Schema{}, fmt.Errorf("schemaRegistry: schema %d: %w", id, err)
+	}
+	schema.ID = id
+	if schema.Type == "" {
+		schema.Type = TypeAvro
+	}
+	c.mu.Lock()
+	if len(c.byID) >= maxCacheEntries {
+		c.byID = make(map[int]Schema)
+	}
+	c.byID[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// Latest returns the latest version of a subject
+func (c *Client) Latest(ctx context.Context, subject string) (Schema, error) {
+	c.mu.Lock()
+	cached, ok := c.latest[subject]
+	c.mu.Unlock()
+	if ok && c.now().Before(cached.expires) {
+		return cached.schema, nil
+	}
+	var schema Schema
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, &schema); err != nil {
+		return Schema{}, fmt.Errorf("schemaRegistry: subject %s: %w", subject, err)
+	}
+	if schema.Type == "" {
+		schema.Type = TypeAvro
+	}
+	if c.settings.cacheTTL > 0 {
+		c.mu.Lock()
+		if len(c.latest) >= maxCacheEntries {
+			c.latest = make(map[string]cachedSchema)
+		}
+		c.latest[subject] = cachedSchema{schema: schema, expires: c.now().Add(c.settings.cacheTTL)}
+		c.mu.Unlock()
+	}
+	return schema, nil
+}
+
+// Resolve splits a payload in the wire format into the writer schema it
+// names and the encoded data that follows
+func (c *Client) Resolve(ctx context.Context, payload []byte) (Schema, []byte, error) {
+	id, data, err := Decode(payload)
+	if err != nil {
+		return Schema{}, nil, err
+	}
+	schema, err := c.SchemaByID(ctx, id)
+	if err != nil {
+		return Schema{}, nil, err
+	}
+	return schema, data, nil
+}
+
+// CheckCompatibility asks the registry whether schema could be registered
+// as the next version of subject under its compatibility level, and returns
+// an *IncompatibleError when not. A subject without versions accepts any
+// schema.
+func (c *Client) CheckCompatibility(ctx context.Context, subject string, schema Schema) error {
+	request := Schema{Type: schema.Type, Schema: schema.Schema, References: schema.References}
+	if request.Type == TypeAvro {
+		// The registry assumes Avro and older versions reject the field
+		request.Type = ""
+	}
+	var response struct {
+		Compatible bool     `json:"is_compatible"`
+		Messages   []string `json:"messages"`
+	}
+	err := c.do(ctx, http.MethodPost, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest?verbose=true", request, &response)
+	var registryError *Error
+	if errors.As(err, &registryError) && (registryError.Code == CodeSubjectNotFound || registryError.Code == CodeVersionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("schemaRegistry: subject %s: %w", subject, err)
+	}
+	if !response.Compatible {
+		return &IncompatibleError{Subject: subject, Reasons: response.Messages}
+	}
+	return nil
+}
+
+// do sends a request to the registry and decodes its JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.settings.baseURL.String(), "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+	if c.config.Username != "" {
+		request.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(response.Body, 8<<20))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= 300 {
+		registryError := &Error{StatusCode: response.StatusCode}
+		if json.Unmarshal(data, registryError) != nil || registryError.Message == "" {
+			registryError.Message = http.StatusText(response.StatusCode)
+		}
+		return registryError
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultClient *Client
+)
+
+// SetDefault installs the client of the [schemaRegistry] section, or removes
+// it with nil
+func SetDefault(client *Client) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = client
+}
+
+// Default returns the client of the [schemaRegistry] section, nil when none
+// is configured
+func Default() *Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClient
+}
@@ -0,0 +1,215 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const orderSchema = `{"type":"record","name":"Order","fields":[{"name":"id","type":"long"}]}`
+
+// registry fakes the endpoints of a schema registry the client uses
+type registry struct {
+	requests atomic.Int32
+	// compatible answers compatibility checks, by subject
+	compatible map[string]bool
+}
+
+func (r *registry) start(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /schemas/ids/{id}", func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+		switch req.PathValue("id") {
+		case "1":
+			json.NewEncoder(w).Encode(map[string]any{"schema": orderSchema})
+		case "2":
+			json.NewEncoder(w).Encode(map[string]any{"schemaType": "PROTOBUF", "schema": `syntax = "proto3"; message Order { int64 id = 1; }`})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error_code": CodeSchemaNotFound, "message": "Schema not found"})
+		}
+	})
+	mux.HandleFunc("GET /subjects/{subject}/versions/latest", func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"subject": req.PathValue("subject"), "version": 3, "id": 1, "schema": orderSchema})
+	})
+	mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/latest", func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+		assert.Equal(t, "true", req.URL.Query().Get("verbose"))
+		user, password, _ := req.BasicAuth()
+		assert.Equal(t, "key", user)
+		assert.Equal(t, "secret", password)
+		var schema Schema
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&schema))
+		assert.NotEmpty(t, schema.Schema)
+		compatible, ok := r.compatible[req.PathValue("subject")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error_code": CodeSubjectNotFound, "message": "Subject not found"})
+			return
+		}
+		response := map[string]any{"is_compatible": compatible}
+		if !compatible {
+			response["messages"] = []string{"READER_FIELD_MISSING_DEFAULT_VALUE, location: /fields/1"}
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSchemaByID(t *testing.T) {
+	r := &registry{}
+	server := r.start(t)
+	client, err := NewClient(Config{URL: server.URL})
+	require.NoError(t, err)
+
+	schema, err := client.SchemaByID(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, Schema{ID: 1, Type: TypeAvro, Schema: orderSchema}, schema)
+	_, err = client.SchemaByID(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), r.requests.Load(), "schemas by ID are cached")
+
+	schema, data, err := client.Resolve(context.Background(), Encode(2, []byte{0, 0x08, 0x2a}))
+	require.NoError(t, err)
+	assert.Equal(t, TypeProtobuf, schema.Type)
+	indexes, message, err := MessageIndexes(data)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, indexes)
+	assert.Equal(t, []byte{0x08, 0x2a}, message)
+
+	_, err = client.SchemaByID(context.Background(), 9)
+	var registryError *Error
+	require.ErrorAs(t, err, &registryError)
+	assert.Equal(t, CodeSchemaNotFound, registryError.Code)
+	assert.EqualError(t, err, "schemaRegistry: schema 9: schema registry answered 404: Schema not found (error code 40403)")
+
+	_, _, err = client.Resolve(context.Background(), []byte(`{"id":1}`))
+	assert.ErrorIs(t, err, ErrNotWireFormat)
+}
+
+func TestLatest(t *testing.T) {
+	r := &registry{}
+	server := r.start(t)
+	client, err := NewClient(Config{URL: server.URL, CacheTTL: "1m"})
+	require.NoError(t, err)
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	schema, err := client.Latest(context.Background(), "orders-value")
+	require.NoError(t, err)
+	assert.Equal(t, Schema{ID: 1, Subject: "orders-value", Version: 3, Type: TypeAvro, Schema: orderSchema}, schema)
+	_, err = client.Latest(context.Background(), "orders-value")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), r.requests.Load())
+
+	now = now.Add(2 * time.Minute)
+	_, err = client.Latest(context.Background(), "orders-value")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), r.requests.Load(), "the latest version expires after cacheTTL")
+}
+
+func TestCheckSubjects(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(directory, "schemas"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "schemas", "order.avsc"), []byte(orderSchema), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "schemas", "payment.proto"), []byte(`syntax = "proto3";`), 0o644))
+
+	r := &registry{compatible: map[string]bool{"orders-value": true, "payments-value": false}}
+	server := r.start(t)
+	config := Config{URL: server.URL, Username: "key", Password: "secret", Subjects: []SubjectConfig{
+		{Subject: "orders-value", Schema: "schemas/order.avsc"},
+		{Subject: "new-value", Schema: "schemas/order.avsc"},
+	}}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	assert.NoError(t, client.CheckSubjects(context.Background(), directory), "compatible schemas and new subjects pass")
+
+	config.Subjects = append(config.Subjects,
+		SubjectConfig{Subject: "payments-value", Schema: "schemas/payment.proto"},
+		SubjectConfig{Subject: "refunds-value", Schema: "schemas/refund.avsc"})
+	client, err = NewClient(config)
+	require.NoError(t, err)
+	err = client.CheckSubjects(context.Background(), directory)
+	var incompatible *IncompatibleError
+	require.ErrorAs(t, err, &incompatible)
+	assert.Equal(t, "payments-value", incompatible.Subject)
+	assert.ErrorContains(t, err, "schemas/payment.proto: schema is incompatible with the latest version of subject payments-value: READER_FIELD_MISSING_DEFAULT_VALUE")
+	assert.ErrorContains(t, err, "schemaRegistry: subject refunds-value: open")
+}
+
+func TestWireFormat(t *testing.T) {
+	payload := Encode(258, []byte("data"))
+	assert.Equal(t, []byte{0, 0, 0, 1, 2, 'd', 'a', 't', 'a'}, payload)
+	id, data, err := Decode(payload)
+	require.NoError(t, err)
+	assert.Equal(t, 258, id)
+	assert.Equal(t, []byte("data"), data)
+
+	_, _, err = Decode([]byte{1, 0, 0, 0, 1})
+	assert.ErrorIs(t, err, ErrNotWireFormat)
+	_, _, err = Decode([]byte{0, 0})
+	assert.ErrorIs(t, err, ErrNotWireFormat)
+
+	encoded := AppendMessageIndexes(nil, []int{1, 0})
+	indexes, rest, err := MessageIndexes(append(encoded, 0x08))
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, indexes)
+	assert.Equal(t, []byte{0x08}, rest)
+	assert.Equal(t, []byte{0}, AppendMessageIndexes(nil, []int{0}))
+
+	_, _, err = MessageIndexes([]byte{0x06})
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		config   Config
+		expected string
+	}{
+		{Config{URL: "localhost:8081"}, "url must be an http:// or https:// URL"},
+		{Config{URL: "http://registry", Password: "secret"}, "password requires a username"},
+		{Config{URL: "http://registry", Timeout: "soon"}, "timeout must be a positive duration"},
+		{Config{URL: "http://registry", CacheTTL: "-1s"}, "cacheTTL must be a duration"},
+		{Config{URL: "http://registry", Subjects: []SubjectConfig{{Subject: "orders-value"}}}, "subjects need a subject and a schema"},
+		{Config{URL: "http://registry", Subjects: []SubjectConfig{{Subject: "orders-value", Schema: "../order.avsc"}}}, "must be a path below the registry"},
+		{Config{URL: "http://registry", Subjects: []SubjectConfig{{Subject: "orders-value", Schema: "order.txt"}}}, "cannot tell the type of order.txt"},
+		{Config{URL: "http://registry", Subjects: []SubjectConfig{{Subject: "orders-value", Schema: "order", Type: "xml"}}}, "must be AVRO, PROTOBUF or JSON"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.ErrorContains(t, tc.config.Validate(), tc.expected)
+		})
+	}
+	assert.NoError(t, Config{URL: "https://registry:8081", Subjects: []SubjectConfig{{Subject: "orders-value", Schema: "order", Type: "avro"}}}.Validate())
+}
@@ -0,0 +1,78 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// schemaType returns the configured type, or the one the file extension
+// implies
+func (s SubjectConfig) schemaType() (string, error) {
+	if s.Type != "" {
+		switch strings.ToUpper(s.Type) {
+		case TypeAvro, TypeProtobuf, TypeJSON:
+			return strings.ToUpper(s.Type), nil
+		}
+		return "", fmt.Errorf("schemaRegistry: type of subject %s must be AVRO, PROTOBUF or JSON, got: %s", s.Subject, s.Type)
+	}
+	switch strings.ToLower(filepath.Ext(s.Schema)) {
+	case ".avsc":
+		return TypeAvro, nil
+	case ".proto":
+		return TypeProtobuf, nil
+	case ".json":
+		return TypeJSON, nil
+	}
+	return "", fmt.Errorf("schemaRegistry: cannot tell the type of %s from its extension, set type for subject %s", s.Schema, s.Subject)
+}
+
+// CheckSubjects checks the schema of each configured subject, read from
+// registryDir, against the latest version of the subject, and reports every
+// incompatible or unreadable schema
+func (c *Client) CheckSubjects(ctx context.Context, registryDir string) error {
+	var errs []error
+	for _, subject := range c.config.Subjects {
+		if err := c.checkSubject(ctx, registryDir, subject); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) checkSubject(ctx context.Context, registryDir string, subject SubjectConfig) error {
+	schemaType, err := subject.schemaType()
+	if err != nil {
+		return err
+	}
+	source, err := os.ReadFile(filepath.Join(registryDir, subject.Schema))
+	if err != nil {
+		return fmt.Errorf("schemaRegistry: subject %s: %w", subject.Subject, err)
+	}
+	if err := c.CheckCompatibility(ctx, subject.Subject, Schema{Type: schemaType, Schema: string(source)}); err != nil {
+		return fmt.Errorf("%s: %w", subject.Schema, err)
+	}
+	return nil
+}
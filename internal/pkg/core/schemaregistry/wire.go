@@ -0,0 +1,92 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// magicByte starts every payload in the wire format, followed by the schema
+// ID as a big-endian 32-bit integer
+const magicByte = 0
+
+// ErrNotWireFormat is returned for payloads that do not start with the magic
+// byte and a schema ID
+var ErrNotWireFormat = errors.New("schemaRegistry: payload is not in the schema registry wire format")
+
+// Decode splits a payload in the wire format into its schema ID and the
+// encoded data
+func Decode(payload []byte) (int, []byte, error) {
+	if len(payload) < 5 || payload[0] != magicByte {
+		return 0, nil, ErrNotWireFormat
+	}
+	return int(binary.BigEndian.Uint32(payload[1:5])), payload[5:], nil
+}
+
+// Encode prefixes data with the magic byte and schema ID
+func Encode(id int, data []byte) []byte {
+	payload := make([]byte, 5, 5+len(data))
+	payload[0] = magicByte
+	binary.BigEndian.PutUint32(payload[1:5], uint32(id))
+	return append(payload, data...)
+}
+
+// MessageIndexes splits the data of a Protobuf payload into the indexes that
+// locate its message type in the schema, e.g. [1 0] for the first message
+// nested in the second, and the encoded message. A single zero stands for
+// [0], the first message.
+func MessageIndexes(data []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("schemaRegistry: invalid message indexes")
+	}
+	data = data[n:]
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+	if count < 0 || count > int64(len(data)) {
+		return nil, nil, fmt.Errorf("schemaRegistry: invalid message index count %d", count)
+	}
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(data)
+		if n <= 0 || index < 0 {
+			return nil, nil, fmt.Errorf("schemaRegistry: invalid message indexes")
+		}
+		indexes[i] = int(index)
+		data = data[n:]
+	}
+	return indexes, data, nil
+}
+
+// AppendMessageIndexes appends the indexes of a Protobuf message type to
+// data, in the short form for the first message
+func AppendMessageIndexes(data []byte, indexes []int) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return binary.AppendVarint(data, 0)
+	}
+	data = binary.AppendVarint(data, int64(len(indexes)))
+	for _, index := range indexes {
+		data = binary.AppendVarint(data, int64(index))
+	}
+	return data
+}
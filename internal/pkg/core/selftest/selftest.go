@@ -0,0 +1,163 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package selftest runs the sample requests declared by APIs against the
+// runtime before it accepts traffic. Every self-test is registered as a health
+// check, so failures are logged and critical ones keep /readyz down.
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/health"
+)
+
+// maxBody bounds the part of a failed response quoted in its error
+const maxBody = 256
+
+// Name is the health check name of a self-test
+func Name(api artifacts.API, test artifacts.SelfTest) string {
+	return "selftest:" + api.Key() + "/" + test.Name
+}
+
+// HealthCheck describes a self-test as a health check that fails on its
+// first unexpected response
+func HealthCheck(api artifacts.API, test artifacts.SelfTest) artifacts.HealthCheck {
+	return artifacts.HealthCheck{
+		Name:             Name(api, test),
+		URL:              api.BasePath() + test.Path,
+		Method:           test.Method,
+		ExpectedStatus:   test.ExpectedStatus,
+		Timeout:          test.Timeout,
+		FailureThreshold: 1,
+		Critical:         test.Critical,
+		Position:         api.Position,
+	}
+}
+
+// NewProbe serves the sample request of test with handler in process, so it
+// goes through the same routing and middleware as a client request
+func NewProbe(handler http.Handler, api artifacts.API, test artifacts.SelfTest) health.Probe {
+	host := api.Hostname
+	if host == "" {
+		host = "localhost"
+	}
+	return func(ctx context.Context) error {
+		var body io.Reader
+		if test.Payload != "" {
+			body = strings.NewReader(test.Payload)
+		}
+		request, err := http.NewRequestWithContext(ctx, test.Method, "http://"+host+api.BasePath()+test.Path, body)
+		if err != nil {
+			return err
+		}
+		request.RemoteAddr = "127.0.0.1:0"
+		for name, value := range test.Headers {
+			request.Header.Set(name, value)
+		}
+		response := &recorder{header: make(http.Header)}
+		handler.ServeHTTP(response, request)
+		if response.status == 0 {
+			response.status = http.StatusOK
+		}
+
+		if test.ExpectedStatus != 0 {
+			if response.status != test.ExpectedStatus {
+				return fmt.Errorf("unexpected status %d, expected %d%s", response.status, test.ExpectedStatus, response.quote())
+			}
+		} else if response.status < 200 || response.status > 299 {
+			return fmt.Errorf("unexpected status %d%s", response.status, response.quote())
+		}
+		return nil
+	}
+}
+
+// Run registers the self-tests of apis with registry and probes each once.
+// It returns the number of self-tests that failed.
+func Run(ctx context.Context, registry *health.Registry, handler http.Handler, apis []artifacts.API, logger *slog.Logger) int {
+	slices.SortFunc(apis, func(a, b artifacts.API) int { return strings.Compare(a.Key(), b.Key()) })
+	var names []string
+	for _, api := range apis {
+		for _, test := range api.SelfTests {
+			if err := registry.Register(HealthCheck(api, test), NewProbe(handler, api, test)); err != nil {
+				logger.Error("Error registering self-test", slog.String("api", api.Key()), slog.String("error", err.Error()))
+				continue
+			}
+			names = append(names, Name(api, test))
+		}
+	}
+	if len(names) == 0 {
+		return 0
+	}
+
+	failed := 0
+	for _, name := range names {
+		registry.Probe(ctx, name)
+		if status, _ := registry.Get(name); status.Status != health.StatusHealthy {
+			failed++
+		}
+	}
+	if failed > 0 {
+		logger.Warn("Self-tests failed", slog.Int("failed", failed), slog.Int("total", len(names)))
+	} else {
+		logger.Info("Self-tests passed", slog.Int("total", len(names)))
+	}
+	return failed
+}
+
+// recorder keeps the status and the start of the body of a self-test response
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	if remaining := maxBody - r.body.Len(); remaining > 0 {
+		r.body.Write(data[:min(len(data), remaining)])
+	}
+	return len(data), nil
+}
+
+func (r *recorder) quote() string {
+	body := strings.TrimSpace(r.body.String())
+	if body == "" {
+		return ""
+	}
+	return ": " + body
+}
@@ -0,0 +1,90 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package selftest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "1" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"no such order"}`))
+			return
+		}
+		w.Write([]byte(`{"id":1}`))
+	})
+	mux.HandleFunc("POST /orders", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/json" || string(body) != `{"id":2}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	api := artifacts.API{Name: "Orders", Context: "/orders", SelfTests: []artifacts.SelfTest{
+		{Name: "get", Method: http.MethodGet, Path: "/1", Timeout: time.Second, Critical: true},
+		{Name: "create", Method: http.MethodPost, Path: "", Headers: map[string]string{"Content-Type": "application/json"},
+			Payload: `{"id":2}`, ExpectedStatus: http.StatusCreated, Timeout: time.Second, Critical: true},
+		{Name: "missing", Method: http.MethodGet, Path: "/2", Timeout: time.Second},
+	}}
+	registry := health.NewRegistry(nil)
+
+	failed := Run(context.Background(), registry, mux, []artifacts.API{api}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Equal(t, 1, failed)
+	assert.True(t, registry.Ready(), "only critical self-tests decide readiness")
+
+	status, ok := registry.Get("selftest:Orders/create")
+	require.True(t, ok)
+	assert.Equal(t, health.StatusHealthy, status.Status)
+	assert.Equal(t, "POST /orders", status.Target)
+
+	status, ok = registry.Get("selftest:Orders/missing")
+	require.True(t, ok)
+	assert.Equal(t, health.StatusUnhealthy, status.Status)
+	assert.Equal(t, `unexpected status 404: {"error":"no such order"}`, status.LastError)
+}
+
+func TestRunCriticalFailure(t *testing.T) {
+	api := artifacts.API{Name: "Orders", Context: "/orders", Version: "2", VersionType: "url",
+		SelfTests: []artifacts.SelfTest{{Name: "get", Method: http.MethodGet, Path: "/1", ExpectedStatus: http.StatusOK,
+			Timeout: time.Second, Critical: true}}}
+	registry := health.NewRegistry(nil)
+
+	failed := Run(context.Background(), registry, http.NotFoundHandler(), []artifacts.API{api}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Equal(t, 1, failed)
+	assert.False(t, registry.Ready())
+	status, _ := registry.Get("selftest:Orders:v2/get")
+	assert.Equal(t, "GET /orders/2/1", status.Target)
+	assert.Equal(t, "unexpected status 404, expected 200: 404 page not found", status.LastError)
+}
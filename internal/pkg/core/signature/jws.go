@@ -0,0 +1,130 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package signature signs and verifies message payloads, using JWS compact
+// serialization for JSON and other payloads and enveloped XML-DSig for XML.
+package signature
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const (
+	FormatJWS     = "jws"
+	FormatXMLDSig = "xmldsig"
+)
+
+// IsSymmetric reports whether the JWS algorithm uses a shared secret
+func IsSymmetric(algorithm string) bool {
+	return strings.HasPrefix(algorithm, "HS")
+}
+
+// defaultAlgorithm picks the JWS algorithm matching the key type
+func defaultAlgorithm(key any) (jose.SignatureAlgorithm, error) {
+	switch k := key.(type) {
+	case []byte:
+		return jose.HS256, nil
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return jose.RS256, nil
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jose.EdDSA, nil
+	case *ecdsa.PrivateKey:
+		return ecdsaAlgorithm(k.Curve)
+	case *ecdsa.PublicKey:
+		return ecdsaAlgorithm(k.Curve)
+	}
+	return "", fmt.Errorf("unsupported key type %T", key)
+}
+
+func ecdsaAlgorithm(curve elliptic.Curve) (jose.SignatureAlgorithm, error) {
+	switch curve {
+	case elliptic.P256():
+		return jose.ES256, nil
+	case elliptic.P384():
+		return jose.ES384, nil
+	case elliptic.P521():
+		return jose.ES512, nil
+	}
+	return "", fmt.Errorf("unsupported elliptic curve %s", curve.Params().Name)
+}
+
+// SignJWS signs payload and returns the JWS compact serialization. A detached
+// signature omits the payload (RFC 7515 Appendix F), so it can travel in a
+// header next to the unmodified body. An empty algorithm is inferred from key.
+func SignJWS(payload []byte, key any, algorithm string, detached bool) (string, error) {
+	alg := jose.SignatureAlgorithm(algorithm)
+	if alg == "" {
+		var err error
+		if alg, err = defaultAlgorithm(key); err != nil {
+			return "", err
+		}
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create JWS signer: %w", err)
+	}
+	object, err := signer.Sign(payload)
+	if err != nil {
+		return "", fmt.Errorf("cannot sign payload: %w", err)
+	}
+	if detached {
+		return object.DetachedCompactSerialize()
+	}
+	return object.CompactSerialize()
+}
+
+// VerifyJWS verifies a JWS compact serialization and returns the signed payload.
+// For detached signatures the payload is passed separately. Only the given
+// algorithms are accepted; when none are given the algorithm matching key is.
+func VerifyJWS(token string, key any, algorithms []string, detachedPayload []byte) ([]byte, error) {
+	allowed := make([]jose.SignatureAlgorithm, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		allowed = append(allowed, jose.SignatureAlgorithm(algorithm))
+	}
+	if len(allowed) == 0 {
+		alg, err := defaultAlgorithm(key)
+		if err != nil {
+			return nil, err
+		}
+		allowed = append(allowed, alg)
+	}
+
+	var object *jose.JSONWebSignature
+	var err error
+	if detachedPayload != nil {
+		object, err = jose.ParseDetached(strings.TrimSpace(token), detachedPayload, allowed)
+	} else {
+		object, err = jose.ParseSigned(strings.TrimSpace(token), allowed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWS: %w", err)
+	}
+	payload, err := object.Verify(key)
+	if err != nil {
+		return nil, fmt.Errorf("JWS signature verification failed: %w", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,123 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package signature
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func selfSignedCertificate(t *testing.T, key *rsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	return certificate
+}
+
+func TestJWS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	payload := []byte(`{"order":42}`)
+
+	testCases := []struct {
+		name       string
+		signKey    any
+		verifyKey  any
+		algorithm  string
+		detached   bool
+		algorithms []string
+	}{
+		{name: "RSA", signKey: rsaKey, verifyKey: &rsaKey.PublicKey},
+		{name: "ECDSA detached", signKey: ecKey, verifyKey: &ecKey.PublicKey, detached: true},
+		{name: "HMAC", signKey: []byte("0123456789abcdef0123456789abcdef"), verifyKey: []byte("0123456789abcdef0123456789abcdef"), algorithm: "HS256", algorithms: []string{"HS256"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := SignJWS(payload, tc.signKey, tc.algorithm, tc.detached)
+			assert.NoError(t, err)
+
+			var detachedPayload []byte
+			if tc.detached {
+				assert.Contains(t, token, "..")
+				detachedPayload = payload
+			}
+			verified, err := VerifyJWS(token, tc.verifyKey, tc.algorithms, detachedPayload)
+			assert.NoError(t, err)
+			assert.Equal(t, payload, verified)
+
+			// A modified payload fails verification
+			if tc.detached {
+				_, err = VerifyJWS(token, tc.verifyKey, tc.algorithms, []byte(`{"order":43}`))
+			} else {
+				parts := strings.Split(token, ".")
+				parts[1] = "eyJvcmRlciI6NDN9"
+				_, err = VerifyJWS(strings.Join(parts, "."), tc.verifyKey, tc.algorithms, nil)
+			}
+			assert.Error(t, err)
+		})
+	}
+
+	// Algorithms outside the allowed list are rejected
+	token, err := SignJWS(payload, rsaKey, "PS256", false)
+	assert.NoError(t, err)
+	_, err = VerifyJWS(token, &rsaKey.PublicKey, []string{"RS256"}, nil)
+	assert.Error(t, err)
+}
+
+func TestXMLDSig(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	certificate := selfSignedCertificate(t, key)
+	payload := []byte(`<order xmlns="http://example.com/orders"><id>42</id></order>`)
+
+	signed, err := SignXML(payload, key, certificate)
+	assert.NoError(t, err)
+	assert.Contains(t, string(signed), "SignatureValue")
+	assert.NoError(t, VerifyXML(signed, certificate))
+
+	tampered := []byte(strings.Replace(string(signed), "<id>42</id>", "<id>43</id>", 1))
+	assert.Error(t, VerifyXML(tampered, certificate))
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Error(t, VerifyXML(signed, selfSignedCertificate(t, otherKey)))
+	assert.Error(t, VerifyXML(payload, certificate))
+}
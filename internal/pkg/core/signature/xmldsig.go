@@ -0,0 +1,76 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package signature
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// SignXML adds an enveloped XML-DSig signature to the root element of payload.
+// The certificate is embedded in KeyInfo so receivers can match it against
+// their trusted certificates.
+func SignXML(payload []byte, signer crypto.Signer, certificate *x509.Certificate) ([]byte, error) {
+	document := etree.NewDocument()
+	if err := document.ReadFromBytes(payload); err != nil {
+		return nil, fmt.Errorf("cannot parse XML payload: %w", err)
+	}
+	root := document.Root()
+	if root == nil {
+		return nil, fmt.Errorf("XML payload has no root element")
+	}
+
+	signingContext, err := dsig.NewSigningContext(signer, [][]byte{certificate.Raw})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create XML-DSig signer: %w", err)
+	}
+	signingContext.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signed, err := signingContext.SignEnveloped(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign XML payload: %w", err)
+	}
+	document.SetRoot(signed)
+	return document.WriteToBytes()
+}
+
+// VerifyXML verifies the enveloped XML-DSig signature of the root element of
+// payload against the trusted certificate
+func VerifyXML(payload []byte, certificate *x509.Certificate) error {
+	document := etree.NewDocument()
+	if err := document.ReadFromBytes(payload); err != nil {
+		return fmt.Errorf("cannot parse XML payload: %w", err)
+	}
+	root := document.Root()
+	if root == nil {
+		return fmt.Errorf("XML payload has no root element")
+	}
+
+	validationContext := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{
+		Roots: []*x509.Certificate{certificate},
+	})
+	if _, err := validationContext.Validate(root); err != nil {
+		return fmt.Errorf("XML-DSig signature verification failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package slo
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/events"
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+)
+
+const (
+	HookTypeLog     = "log"
+	HookTypeWebhook = "webhook"
+	HookTypeMetric  = "metric"
+)
+
+// AlertsConfig holds the [alerts] section of deployment.toml
+type AlertsConfig struct {
+	Hooks []HookConfig `koanf:"hook"`
+}
+
+// HookConfig declares one alert hook. APIs restricts the hook to the named
+// APIs; an empty list matches every API.
+type HookConfig struct {
+	Type string   `koanf:"type"`
+	URL  string   `koanf:"url"`
+	APIs []string `koanf:"apis"`
+}
+
+// Validate reports configuration errors in the [alerts] section
+func (c AlertsConfig) Validate() error {
+	for _, hook := range c.Hooks {
+		switch hook.Type {
+		case HookTypeLog, HookTypeMetric:
+		case HookTypeWebhook:
+			if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("alerts: webhook hook needs an http(s) url, got '%s'", hook.URL)
+			}
+		default:
+			return fmt.Errorf("alerts: unknown hook type '%s'", hook.Type)
+		}
+	}
+	return nil
+}
+
+// HooksFor builds the hooks that apply to api, starting with the event bus hook
+func (c AlertsConfig) HooksFor(api string, logger *slog.Logger) []Hook {
+	hooks := []Hook{EventHook{}}
+	for _, hook := range c.Hooks {
+		if len(hook.APIs) > 0 && !slices.Contains(hook.APIs, api) {
+			continue
+		}
+		switch hook.Type {
+		case HookTypeLog:
+			hooks = append(hooks, LogHook{Logger: logger})
+		case HookTypeWebhook:
+			hooks = append(hooks, NewWebhookHook(hook.URL))
+		case HookTypeMetric:
+			hooks = append(hooks, DefaultMetrics)
+		}
+	}
+	return hooks
+}
+
+// EventHook publishes objective state changes on the runtime event bus. It is
+// installed for every API, independent of the [alerts] section.
+type EventHook struct{}
+
+func (EventHook) Fire(event Event) {
+	eventType := events.SLORecovered
+	if event.Kind == EventBreached {
+		eventType = events.SLOBreached
+	}
+	attributes := map[string]string{
+		"latency":   event.Latency.String(),
+		"errorRate": strconv.FormatFloat(event.ErrorRate, 'f', -1, 64),
+	}
+	if event.Reason != "" {
+		attributes["reason"] = event.Reason
+	}
+	events.Publish(events.Event{Type: eventType, Kind: "api", Name: event.API, Time: event.Time, Attributes: attributes})
+}
+
+// LogHook writes objective state changes to the log
+type LogHook struct {
+	Logger *slog.Logger
+}
+
+func (h LogHook) Fire(event Event) {
+	attrs := []any{
+		slog.String("api", event.API),
+		slog.String("latency", event.Latency.String()),
+		slog.Float64("error_rate", event.ErrorRate),
+		slog.Int("requests", event.Requests),
+	}
+	if event.Kind == EventBreached {
+		h.Logger.Warn("SLO breached: "+event.Reason, attrs...)
+	} else {
+		h.Logger.Info("SLO recovered", attrs...)
+	}
+}
+
+// WebhookHook posts events as JSON to a URL through the webhook dispatcher,
+// so a slow receiver never delays traffic and failed alerts are retried
+type WebhookHook struct {
+	url string
+}
+
+func NewWebhookHook(url string) *WebhookHook {
+	return &WebhookHook{url: url}
+}
+
+func (h *WebhookHook) Fire(event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	webhook.Default().Enqueue(webhook.Request{URL: h.url, Source: webhook.SourceAlerts, ContentType: "application/json", Payload: body})
+}
+
+// Metrics counts objective state changes per API
+type Metrics struct {
+	mu       sync.Mutex
+	breaches map[string]int
+	recovers map[string]int
+}
+
+// DefaultMetrics is shared by every metric hook and reported by the admin API
+var DefaultMetrics = &Metrics{breaches: make(map[string]int), recovers: make(map[string]int)}
+
+func (m *Metrics) Fire(event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if event.Kind == EventBreached {
+		m.breaches[event.API]++
+	} else {
+		m.recovers[event.API]++
+	}
+}
+
+// Breaches returns the number of breaches recorded for api
+func (m *Metrics) Breaches(api string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.breaches[api]
+}
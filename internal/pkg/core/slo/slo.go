@@ -0,0 +1,208 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package slo evaluates latency and error-rate objectives over rolling windows
+// and notifies alert hooks when an objective is breached or recovers.
+package slo
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	EventBreached  = "breached"
+	EventRecovered = "recovered"
+
+	// maxSamples bounds the memory used by a window on busy APIs
+	maxSamples = 10000
+	// evaluationInterval limits how often the window is evaluated
+	evaluationInterval = time.Second
+)
+
+// Objective is the service level an API commits to
+type Objective struct {
+	Window time.Duration
+	// MaxLatency applies to the Percentile of request latencies, e.g. p95
+	MaxLatency time.Duration
+	Percentile float64
+	// MaxErrorRate is the tolerated fraction of 5xx responses, between 0 and 1
+	MaxErrorRate float64
+	// MinRequests avoids alerting on windows with too few requests
+	MinRequests int
+}
+
+// Event describes a change in the state of an objective
+type Event struct {
+	API        string        `json:"api"`
+	Kind       string        `json:"kind"`
+	Reason     string        `json:"reason,omitempty"`
+	Latency    time.Duration `json:"latencyNanos"`
+	Percentile float64       `json:"percentile"`
+	ErrorRate  float64       `json:"errorRate"`
+	Requests   int           `json:"requests"`
+	Time       time.Time     `json:"time"`
+}
+
+// Hook is notified of objective state changes. Implementations must not block.
+type Hook interface {
+	Fire(event Event)
+}
+
+// Status is a snapshot of a monitor, as reported by the admin API
+type Status struct {
+	API        string    `json:"api"`
+	Breached   bool      `json:"breached"`
+	Reason     string    `json:"reason,omitempty"`
+	Latency    string    `json:"latency"`
+	Percentile float64   `json:"percentile"`
+	ErrorRate  float64   `json:"errorRate"`
+	Requests   int       `json:"requests"`
+	Since      time.Time `json:"since,omitempty"`
+}
+
+type sample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// Monitor tracks one API against its objective
+type Monitor struct {
+	api       string
+	objective Objective
+	hooks     []Hook
+
+	mu         sync.Mutex
+	samples    []sample
+	lastEval   time.Time
+	breached   bool
+	breachedAt time.Time
+	lastStatus Status
+}
+
+// NewMonitor creates a monitor for api. Zero values in objective disable the
+// corresponding check; the window defaults to one minute.
+func NewMonitor(api string, objective Objective, hooks []Hook) *Monitor {
+	if objective.Window <= 0 {
+		objective.Window = time.Minute
+	}
+	if objective.Percentile <= 0 {
+		objective.Percentile = 95
+	}
+	return &Monitor{api: api, objective: objective, hooks: hooks, lastStatus: Status{API: api, Percentile: objective.Percentile}}
+}
+
+// Record adds a completed request to the window and re-evaluates the objective
+// at most once per evaluation interval
+func (m *Monitor) Record(latency time.Duration, failed bool, now time.Time) {
+	m.mu.Lock()
+	m.samples = append(m.samples, sample{at: now, latency: latency, failed: failed})
+	if len(m.samples) > maxSamples {
+		m.samples = m.samples[len(m.samples)-maxSamples:]
+	}
+	if now.Sub(m.lastEval) < evaluationInterval {
+		m.mu.Unlock()
+		return
+	}
+	event := m.evaluateLocked(now)
+	m.mu.Unlock()
+
+	if event != nil {
+		for _, hook := range m.hooks {
+			hook.Fire(*event)
+		}
+	}
+}
+
+// Breached reports whether the objective is currently breached
+func (m *Monitor) Breached() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.breached
+}
+
+// Status returns the result of the latest evaluation
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastStatus
+}
+
+// evaluateLocked computes the window statistics and returns an event when the
+// breach state changed. The caller must hold mu.
+func (m *Monitor) evaluateLocked(now time.Time) *Event {
+	m.lastEval = now
+	cutoff := now.Add(-m.objective.Window)
+	first := 0
+	for first < len(m.samples) && m.samples[first].at.Before(cutoff) {
+		first++
+	}
+	m.samples = m.samples[first:]
+
+	requests := len(m.samples)
+	latencies := make([]time.Duration, 0, requests)
+	failures := 0
+	for _, s := range m.samples {
+		latencies = append(latencies, s.latency)
+		if s.failed {
+			failures++
+		}
+	}
+	var latency time.Duration
+	var errorRate float64
+	if requests > 0 {
+		slices.Sort(latencies)
+		index := int(math.Ceil(m.objective.Percentile/100*float64(requests))) - 1
+		latency = latencies[max(0, min(index, requests-1))]
+		errorRate = float64(failures) / float64(requests)
+	}
+
+	reason := ""
+	if requests >= m.objective.MinRequests && requests > 0 {
+		switch {
+		case m.objective.MaxLatency > 0 && latency > m.objective.MaxLatency:
+			reason = fmt.Sprintf("p%g latency %s exceeds %s", m.objective.Percentile, latency, m.objective.MaxLatency)
+		case m.objective.MaxErrorRate > 0 && errorRate > m.objective.MaxErrorRate:
+			reason = fmt.Sprintf("error rate %.2f%% exceeds %.2f%%", errorRate*100, m.objective.MaxErrorRate*100)
+		}
+	}
+
+	breached := reason != ""
+	var event *Event
+	if breached != m.breached {
+		kind := EventRecovered
+		if breached {
+			kind = EventBreached
+			m.breachedAt = now
+		}
+		event = &Event{API: m.api, Kind: kind, Reason: reason, Latency: latency, Percentile: m.objective.Percentile,
+			ErrorRate: errorRate, Requests: requests, Time: now}
+	}
+	m.breached = breached
+	m.lastStatus = Status{API: m.api, Breached: breached, Reason: reason, Latency: latency.String(),
+		Percentile: m.objective.Percentile, ErrorRate: errorRate, Requests: requests}
+	if breached {
+		m.lastStatus.Since = m.breachedAt
+	}
+	return event
+}
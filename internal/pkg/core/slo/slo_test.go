@@ -0,0 +1,129 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingHook struct {
+	events []Event
+}
+
+func (h *recordingHook) Fire(event Event) {
+	h.events = append(h.events, event)
+}
+
+func TestMonitor_BreachAndRecover(t *testing.T) {
+	tests := []struct {
+		name      string
+		objective Objective
+		latency   time.Duration
+		failed    bool
+		reason    string
+	}{
+		{
+			name:      "latency",
+			objective: Objective{Window: time.Minute, MaxLatency: 100 * time.Millisecond, Percentile: 90},
+			latency:   200 * time.Millisecond,
+			reason:    "p90 latency 200ms exceeds 100ms",
+		},
+		{
+			name:      "error rate",
+			objective: Objective{Window: time.Minute, MaxErrorRate: 0.1},
+			latency:   time.Millisecond,
+			failed:    true,
+			reason:    "error rate 100.00% exceeds 10.00%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := &recordingHook{}
+			monitor := NewMonitor("OrdersAPI", tt.objective, []Hook{hook})
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			monitor.Record(tt.latency, tt.failed, now)
+			assert.True(t, monitor.Breached())
+			assert.Equal(t, tt.reason, monitor.Status().Reason)
+
+			// Once the bad sample leaves the window, healthy traffic recovers the objective
+			monitor.Record(time.Millisecond, false, now.Add(2*time.Minute))
+			assert.False(t, monitor.Breached())
+
+			assert.Len(t, hook.events, 2)
+			assert.Equal(t, EventBreached, hook.events[0].Kind)
+			assert.Equal(t, tt.reason, hook.events[0].Reason)
+			assert.Equal(t, EventRecovered, hook.events[1].Kind)
+		})
+	}
+}
+
+func TestMonitor_MinRequestsAndEvaluationInterval(t *testing.T) {
+	hook := &recordingHook{}
+	monitor := NewMonitor("OrdersAPI", Objective{MaxErrorRate: 0.5, MinRequests: 3}, []Hook{hook})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	monitor.Record(time.Millisecond, true, now)
+	assert.False(t, monitor.Breached(), "too few requests to evaluate")
+
+	// Samples within the evaluation interval are recorded but not evaluated
+	monitor.Record(time.Millisecond, true, now.Add(100*time.Millisecond))
+	monitor.Record(time.Millisecond, true, now.Add(200*time.Millisecond))
+	assert.False(t, monitor.Breached())
+
+	monitor.Record(time.Millisecond, false, now.Add(2*time.Second))
+	assert.True(t, monitor.Breached())
+	assert.Equal(t, 4, monitor.Status().Requests)
+	assert.Len(t, hook.events, 1)
+}
+
+func TestAlertsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AlertsConfig
+		wantErr string
+	}{
+		{name: "log and metric", config: AlertsConfig{Hooks: []HookConfig{{Type: "log"}, {Type: "metric"}}}},
+		{name: "webhook", config: AlertsConfig{Hooks: []HookConfig{{Type: "webhook", URL: "https://alerts.example.com"}}}},
+		{name: "webhook without url", config: AlertsConfig{Hooks: []HookConfig{{Type: "webhook"}}}, wantErr: "alerts: webhook hook needs an http(s) url, got ''"},
+		{name: "unknown type", config: AlertsConfig{Hooks: []HookConfig{{Type: "pager"}}}, wantErr: "alerts: unknown hook type 'pager'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	config := AlertsConfig{Hooks: []HookConfig{{Type: "metric"}, {Type: "metric", APIs: []string{"OtherAPI"}}}}
+	hooks := config.HooksFor("OrdersAPI", nil)
+	assert.Len(t, hooks, 2, "the event hook and one metric hook")
+	hooks[1].Fire(Event{API: "OrdersAPI", Kind: EventBreached})
+	assert.Equal(t, 1, DefaultMetrics.Breaches("OrdersAPI"))
+}
@@ -19,6 +19,11 @@
 
 package synctx
 
+import (
+	"bytes"
+	"slices"
+)
+
 type MsgContext struct {
 	Properties map[string]interface{}
 	Message    Message
@@ -28,6 +33,17 @@ type MsgContext struct {
 type Message struct {
 	RawPayload  []byte
 	ContentType string
+	// Attachments are the binary parts of an MTOM message, referenced from the
+	// payload by xop:Include elements
+	Attachments []Attachment
+}
+
+// Attachment is a MIME part carried alongside the payload
+type Attachment struct {
+	// ContentID identifies the part, without the enclosing angle brackets
+	ContentID   string
+	ContentType string
+	Data        []byte
 }
 
 func CreateMsgContext() *MsgContext {
@@ -36,4 +52,21 @@ func CreateMsgContext() *MsgContext {
 		Message:    Message{},
 		Headers:    make(map[string]string),
 	}
-}
\ No newline at end of file
+}
+
+// Clone copies the message context so the copy can be mediated on its own.
+// Properties and headers are copied one level deep, and the payload is
+// copied so neither context sees writes to the other's.
+func (m *MsgContext) Clone() *MsgContext {
+	clone := CreateMsgContext()
+	for name, value := range m.Properties {
+		clone.Properties[name] = value
+	}
+	for name, value := range m.Headers {
+		clone.Headers[name] = value
+	}
+	clone.Message = m.Message
+	clone.Message.RawPayload = bytes.Clone(m.Message.RawPayload)
+	clone.Message.Attachments = slices.Clone(m.Message.Attachments)
+	return clone
+}
@@ -0,0 +1,69 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package tokenexchange
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// leeway tolerated for clock differences with the identity provider
+const leeway = time.Minute
+
+// ValidateJWT verifies the signature, issuer, audience and lifetime of a JWT
+// and returns its subject and claims
+func ValidateJWT(config Config, token string, now time.Time) (Identity, error) {
+	algorithms := make([]jose.SignatureAlgorithm, 0, len(config.Algorithms))
+	for _, algorithm := range config.Algorithms {
+		algorithms = append(algorithms, jose.SignatureAlgorithm(algorithm))
+	}
+	if len(algorithms) == 0 {
+		algorithms = []jose.SignatureAlgorithm{jose.RS256, jose.ES256, jose.PS256, jose.EdDSA}
+	}
+
+	parsed, err := jwt.ParseSigned(token, algorithms)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid JWT: %w", err)
+	}
+	var claims jwt.Claims
+	var all map[string]any
+	if err := parsed.Claims(config.VerificationKey, &claims, &all); err != nil {
+		return Identity{}, fmt.Errorf("JWT signature verification failed: %w", err)
+	}
+	expected := jwt.Expected{Issuer: config.Issuer, Time: now}
+	if config.Audience != "" {
+		expected.AnyAudience = jwt.Audience{config.Audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, leeway); err != nil {
+		return Identity{}, fmt.Errorf("JWT rejected: %w", err)
+	}
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("JWT has no subject")
+	}
+
+	identity := Identity{Subject: claims.Subject, Claims: all}
+	if claims.Expiry != nil {
+		identity.Expiry = claims.Expiry.Time()
+	}
+	return identity, nil
+}
@@ -0,0 +1,105 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package tokenexchange
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/signature"
+)
+
+const namespaceSAML2 = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+type assertion struct {
+	XMLName xml.Name
+	Issuer  string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Subject struct {
+		NameID string `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:assertion Subject"`
+	Conditions struct {
+		NotBefore           string `xml:"NotBefore,attr"`
+		NotOnOrAfter        string `xml:"NotOnOrAfter,attr"`
+		AudienceRestriction []struct {
+			Audience []string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Audience"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:assertion AudienceRestriction"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:assertion Conditions"`
+}
+
+// validateSAML checks a base64 encoded SAML 2.0 assertion: its enveloped
+// signature, validity window, issuer and audience
+func validateSAML(config Config, token string, now time.Time) (Identity, error) {
+	document, err := base64.StdEncoding.DecodeString(strings.TrimSpace(token))
+	if err != nil {
+		return Identity{}, fmt.Errorf("SAML assertion must be base64 encoded: %w", err)
+	}
+	if config.Certificate == nil {
+		return Identity{}, fmt.Errorf("no certificate configured to verify SAML assertions")
+	}
+	if err := signature.VerifyXML(document, config.Certificate); err != nil {
+		return Identity{}, err
+	}
+
+	var parsed assertion
+	if err := xml.Unmarshal(document, &parsed); err != nil {
+		return Identity{}, fmt.Errorf("invalid SAML assertion: %w", err)
+	}
+	if parsed.XMLName.Space != namespaceSAML2 || parsed.XMLName.Local != "Assertion" {
+		return Identity{}, fmt.Errorf("payload is not a SAML 2.0 assertion")
+	}
+	if config.Issuer != "" && strings.TrimSpace(parsed.Issuer) != config.Issuer {
+		return Identity{}, fmt.Errorf("SAML assertion rejected: unexpected issuer '%s'", parsed.Issuer)
+	}
+
+	identity := Identity{Subject: strings.TrimSpace(parsed.Subject.NameID)}
+	if identity.Subject == "" {
+		return Identity{}, fmt.Errorf("SAML assertion has no subject")
+	}
+	if notBefore := parsed.Conditions.NotBefore; notBefore != "" {
+		t, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil || now.Add(leeway).Before(t) {
+			return Identity{}, fmt.Errorf("SAML assertion rejected: not yet valid")
+		}
+	}
+	if notOnOrAfter := parsed.Conditions.NotOnOrAfter; notOnOrAfter != "" {
+		t, err := time.Parse(time.RFC3339, notOnOrAfter)
+		if err != nil || !now.Add(-leeway).Before(t) {
+			return Identity{}, fmt.Errorf("SAML assertion rejected: expired")
+		}
+		identity.Expiry = t
+	}
+	if config.Audience != "" {
+		matched := false
+		for _, restriction := range parsed.Conditions.AudienceRestriction {
+			for _, audience := range restriction.Audience {
+				if strings.TrimSpace(audience) == config.Audience {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return Identity{}, fmt.Errorf("SAML assertion rejected: audience '%s' not allowed", config.Audience)
+		}
+	}
+	return identity, nil
+}
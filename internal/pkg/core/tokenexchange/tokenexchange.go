@@ -0,0 +1,184 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package tokenexchange bridges identity domains: it validates an inbound JWT
+// or SAML 2.0 assertion and exchanges it for a backend-specific token using
+// OAuth 2.0 Token Exchange (RFC 8693), caching the result per subject.
+package tokenexchange
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TokenTypeJWT   = "jwt"
+	TokenTypeSAML2 = "saml2"
+
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeURIJWT        = "urn:ietf:params:oauth:token-type:jwt"
+	tokenTypeURISAML2      = "urn:ietf:params:oauth:token-type:saml2"
+	tokenTypeURIAccess     = "urn:ietf:params:oauth:token-type:access_token"
+
+	// expiryMargin renews cached tokens shortly before they expire
+	expiryMargin = 30 * time.Second
+	// defaultLifetime applies when the token endpoint omits expires_in
+	defaultLifetime = 5 * time.Minute
+)
+
+// Identity is the validated subject of an inbound token
+type Identity struct {
+	Subject string
+	Expiry  time.Time
+	// Claims holds every claim of a JWT, including private ones
+	Claims map[string]any
+}
+
+// Config describes how inbound tokens are validated and exchanged
+type Config struct {
+	TokenType string
+	// VerificationKey validates JWT signatures; Certificate validates SAML
+	// assertion signatures
+	VerificationKey any
+	Algorithms      []string
+	Certificate     *x509.Certificate
+	Issuer          string
+	Audience        string
+
+	Endpoint       string
+	ClientID       string
+	ClientSecret   string
+	TargetAudience string
+	Scope          string
+}
+
+// Exchanger validates inbound tokens and exchanges them at the token endpoint
+type Exchanger struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedToken
+}
+
+type cachedToken struct {
+	token  string
+	expiry time.Time
+}
+
+// NewExchanger creates an exchanger with its own token cache
+func NewExchanger(client *http.Client) *Exchanger {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Exchanger{client: client, cache: make(map[string]cachedToken)}
+}
+
+// Validate checks the inbound token and returns its subject
+func (e *Exchanger) Validate(config Config, token string, now time.Time) (Identity, error) {
+	if config.TokenType == TokenTypeSAML2 {
+		return validateSAML(config, token, now)
+	}
+	return ValidateJWT(config, token, now)
+}
+
+// Exchange returns a backend token for the inbound token, calling the token
+// endpoint only when no unexpired token is cached for the subject
+func (e *Exchanger) Exchange(ctx context.Context, config Config, token string, identity Identity, now time.Time) (string, error) {
+	cacheKey := config.Endpoint + "\x00" + config.TargetAudience + "\x00" + config.Scope + "\x00" + identity.Subject
+	e.mu.Lock()
+	cached, ok := e.cache[cacheKey]
+	e.mu.Unlock()
+	if ok && now.Add(expiryMargin).Before(cached.expiry) {
+		return cached.token, nil
+	}
+
+	subjectTokenType := tokenTypeURIJWT
+	if config.TokenType == TokenTypeSAML2 {
+		subjectTokenType = tokenTypeURISAML2
+	}
+	form := url.Values{
+		"grant_type":           {grantTypeTokenExchange},
+		"subject_token":        {token},
+		"subject_token_type":   {subjectTokenType},
+		"requested_token_type": {tokenTypeURIAccess},
+	}
+	if config.TargetAudience != "" {
+		form.Set("audience", config.TargetAudience)
+	}
+	if config.Scope != "" {
+		form.Set("scope", config.Scope)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, config.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if config.ClientID != "" {
+		request.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+
+	response, err := e.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("token exchange request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil && response.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("invalid token exchange response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK || result.AccessToken == "" {
+		if result.Error != "" {
+			return "", fmt.Errorf("token exchange rejected: %s %s", result.Error, result.ErrorDescription)
+		}
+		return "", fmt.Errorf("token exchange failed with status %d", response.StatusCode)
+	}
+
+	expiry := now.Add(defaultLifetime)
+	if result.ExpiresIn > 0 {
+		expiry = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	// Never serve a backend token beyond the life of the token it was issued for
+	if !identity.Expiry.IsZero() && identity.Expiry.Before(expiry) {
+		expiry = identity.Expiry
+	}
+	e.mu.Lock()
+	for key, entry := range e.cache {
+		if now.After(entry.expiry) {
+			delete(e.cache, key)
+		}
+	}
+	e.cache[cacheKey] = cachedToken{token: result.AccessToken, expiry: expiry}
+	e.mu.Unlock()
+	return result.AccessToken, nil
+}
@@ -0,0 +1,169 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package tokenexchange
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/signature"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func signedJWT(t *testing.T, key *rsa.PrivateKey, claims jwt.Claims) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	return token
+}
+
+func TestExchanger_JWT(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	now := time.Now()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		clientID, secret, _ := r.BasicAuth()
+		assert.Equal(t, "gateway", clientID)
+		assert.Equal(t, "s3cret", secret)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, grantTypeTokenExchange, r.PostForm.Get("grant_type"))
+		assert.Equal(t, "orders-backend", r.PostForm.Get("audience"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"backend-token","expires_in":600}`))
+	}))
+	defer server.Close()
+
+	config := Config{
+		TokenType:       TokenTypeJWT,
+		VerificationKey: &key.PublicKey,
+		Issuer:          "https://idp.example.com",
+		Audience:        "gateway",
+		Endpoint:        server.URL,
+		ClientID:        "gateway",
+		ClientSecret:    "s3cret",
+		TargetAudience:  "orders-backend",
+	}
+	token := signedJWT(t, key, jwt.Claims{
+		Subject:  "alice",
+		Issuer:   "https://idp.example.com",
+		Audience: jwt.Audience{"gateway"},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	})
+	exchanger := NewExchanger(nil)
+
+	identity, err := exchanger.Validate(config, token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", identity.Subject)
+
+	for i := 0; i < 2; i++ {
+		exchanged, err := exchanger.Exchange(context.Background(), config, token, identity, now)
+		assert.NoError(t, err)
+		assert.Equal(t, "backend-token", exchanged)
+	}
+	assert.Equal(t, 1, requests, "the second exchange is served from the cache")
+
+	testCases := []struct {
+		name   string
+		claims jwt.Claims
+	}{
+		{name: "Expired", claims: jwt.Claims{Subject: "alice", Issuer: "https://idp.example.com", Audience: jwt.Audience{"gateway"}, Expiry: jwt.NewNumericDate(now.Add(-time.Hour))}},
+		{name: "Wrong issuer", claims: jwt.Claims{Subject: "alice", Issuer: "https://other.example.com", Audience: jwt.Audience{"gateway"}}},
+		{name: "Wrong audience", claims: jwt.Claims{Subject: "alice", Issuer: "https://idp.example.com", Audience: jwt.Audience{"other"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := exchanger.Validate(config, signedJWT(t, key, tc.claims), now)
+			assert.Error(t, err)
+		})
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, err = exchanger.Validate(config, signedJWT(t, otherKey, jwt.Claims{Subject: "alice"}), now)
+	assert.ErrorContains(t, err, "signature verification failed")
+}
+
+func TestExchanger_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_target","error_description":"unknown audience"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewExchanger(nil).Exchange(context.Background(), Config{Endpoint: server.URL}, "token", Identity{Subject: "alice"}, time.Now())
+	assert.EqualError(t, err, "token exchange rejected: invalid_target unknown audience")
+}
+
+func TestValidateSAML(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	certificate, _ := x509.ParseCertificate(der)
+
+	now := time.Now().UTC()
+	assertion := `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="a1" Version="2.0">` +
+		`<saml:Issuer>https://idp.example.com</saml:Issuer>` +
+		`<saml:Subject><saml:NameID>alice@example.com</saml:NameID></saml:Subject>` +
+		`<saml:Conditions NotBefore="` + now.Add(-time.Minute).Format(time.RFC3339) + `" NotOnOrAfter="` + now.Add(time.Hour).Format(time.RFC3339) + `">` +
+		`<saml:AudienceRestriction><saml:Audience>gateway</saml:Audience></saml:AudienceRestriction></saml:Conditions>` +
+		`</saml:Assertion>`
+	signed, err := signature.SignXML([]byte(assertion), key, certificate)
+	assert.NoError(t, err)
+	token := base64.StdEncoding.EncodeToString(signed)
+
+	config := Config{TokenType: TokenTypeSAML2, Certificate: certificate, Issuer: "https://idp.example.com", Audience: "gateway"}
+	identity, err := validateSAML(config, token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", identity.Subject)
+
+	config.Audience = "other"
+	_, err = validateSAML(config, token, now)
+	assert.EqualError(t, err, "SAML assertion rejected: audience 'other' not allowed")
+
+	config.Audience = "gateway"
+	_, err = validateSAML(config, token, now.Add(2*time.Hour))
+	assert.EqualError(t, err, "SAML assertion rejected: expired")
+
+	_, err = validateSAML(config, base64.StdEncoding.EncodeToString([]byte(assertion)), now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package tracing propagates W3C trace context (traceparent) through the
+// runtime so logs, metrics and backends can be correlated by trace ID.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// TraceparentHeader carries the W3C trace context
+	TraceparentHeader = "traceparent"
+	// TraceIDProperty exposes the trace ID of a request to mediators
+	TraceIDProperty = "TRACE_ID"
+)
+
+// Config holds the [tracing] section of deployment.toml
+type Config struct {
+	Enabled bool `koanf:"enabled"`
+}
+
+// SpanContext identifies the span of the current request
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// Traceparent formats the span context as a traceparent header value
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+type contextKey struct{}
+
+// FromContext returns the span context stored by Middleware
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(contextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// TraceID returns the trace ID of the request in ctx, or "" without tracing
+func TraceID(ctx context.Context) string {
+	sc, _ := FromContext(ctx)
+	return sc.TraceID
+}
+
+// Parse reads a version 00 traceparent header value
+func Parse(traceparent string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || parts[0] != "00" || !isHex(parts[1], 32) || !isHex(parts[2], 16) || !isHex(parts[3], 2) {
+		return SpanContext{}, fmt.Errorf("malformed traceparent '%s'", traceparent)
+	}
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return SpanContext{}, fmt.Errorf("traceparent '%s' has an all-zero ID", traceparent)
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	return SpanContext{TraceID: parts[1], SpanID: parts[2], Sampled: flags[0]&1 == 1}, nil
+}
+
+// Middleware continues the trace of an incoming traceparent header, or starts a
+// new one, and echoes the span of this hop in the response
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, err := Parse(r.Header.Get(TraceparentHeader))
+		if err != nil {
+			sc = SpanContext{TraceID: randomHex(16), Sampled: true}
+		}
+		sc.SpanID = randomHex(8)
+		w.Header().Set(TraceparentHeader, sc.Traceparent())
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, sc)))
+	})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
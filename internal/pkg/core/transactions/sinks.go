@@ -0,0 +1,326 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package transactions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/webhook"
+)
+
+const (
+	// DefaultBuffer is the number of events queued before new ones are dropped
+	DefaultBuffer = 4096
+	// endpointTimeout bounds the delivery of one event to an endpoint sink
+	endpointTimeout = 30 * time.Second
+)
+
+// Config holds the [transactions] section of deployment.toml. Each sink that
+// is set receives every event.
+type Config struct {
+	Enabled bool `koanf:"enabled"`
+	// File appends events as JSON lines, relative to the conf directory
+	File string `koanf:"file"`
+	// URL receives each event through the webhook dispatcher
+	URL string `koanf:"url"`
+	// Endpoint names an endpoint artifact events are sent to over its
+	// protocol, e.g. a kafka endpoint
+	Endpoint string `koanf:"endpoint"`
+	Buffer   int    `koanf:"buffer"`
+}
+
+// Validate reports configuration errors in the [transactions] section
+func (c Config) Validate() error {
+	if c.Enabled && c.File == "" && c.URL == "" && c.Endpoint == "" {
+		return fmt.Errorf("transactions: enabled without a file, url or endpoint sink")
+	}
+	if c.URL != "" {
+		target, err := url.Parse(c.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("transactions: url must be an http or https URL, got '%s'", c.URL)
+		}
+	}
+	if c.Buffer < 0 {
+		return fmt.Errorf("transactions: buffer must not be negative, got %d", c.Buffer)
+	}
+	return nil
+}
+
+// Sink delivers events to one destination
+type Sink interface {
+	Name() string
+	Write(event Event) error
+	Close() error
+}
+
+// FileSink appends events to a file as JSON lines
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileSink opens path for appending, creating it and its directory
+func OpenFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("transactions: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("transactions: %w", err)
+	}
+	return &FileSink{path: path, file: file}, nil
+}
+
+func (s *FileSink) Name() string {
+	return "file " + s.path
+}
+
+func (s *FileSink) Write(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Reopen closes the file and opens path again, so events go to a new file
+// once log rotation has moved the old one
+func (s *FileSink) Reopen() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("transactions: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.file
+	s.file = file
+	return previous.Close()
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// WebhookSink hands events to the webhook dispatcher, which signs and retries
+// their delivery
+type WebhookSink struct {
+	URL        string
+	Dispatcher *webhook.Dispatcher
+}
+
+func (s WebhookSink) Name() string {
+	return "url " + s.URL
+}
+
+func (s WebhookSink) Write(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.Dispatcher.Enqueue(webhook.Request{URL: s.URL, Source: webhook.SourceTransactions, ContentType: "application/json", Payload: body})
+	return nil
+}
+
+func (s WebhookSink) Close() error {
+	return nil
+}
+
+// EndpointSink sends events to an endpoint artifact, looked up in the
+// deployed configuration for every event so redeployments take effect
+type EndpointSink struct {
+	Endpoint string
+	Send     artifacts.EndpointSender
+}
+
+func (s EndpointSink) Name() string {
+	return "endpoint " + s.Endpoint
+}
+
+func (s EndpointSink) Write(event Event) error {
+	snapshot := artifacts.GetConfigContext().Snapshot()
+	endpoint, ok := snapshot.Endpoints[s.Endpoint]
+	if !ok {
+		return fmt.Errorf("endpoint %s is not deployed", s.Endpoint)
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	msg := synctx.CreateMsgContext()
+	msg.Properties[artifacts.SnapshotProperty] = snapshot
+	msg.Message.RawPayload = body
+	msg.Message.ContentType = "application/json"
+	ctx, cancel := context.WithTimeout(context.Background(), endpointTimeout)
+	defer cancel()
+	return s.Send(ctx, endpoint, msg)
+}
+
+func (s EndpointSink) Close() error {
+	return nil
+}
+
+// Publisher queues events and writes them to its sinks in the background.
+// Publishing never blocks: when the queue is full the event is dropped.
+type Publisher struct {
+	sinks  []Sink
+	logger *slog.Logger
+	queue  chan Event
+	done   chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// NewPublisher starts writing published events to sinks
+func NewPublisher(buffer int, logger *slog.Logger, sinks ...Sink) *Publisher {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	p := &Publisher{sinks: sinks, logger: logger, queue: make(chan Event, buffer), done: make(chan struct{})}
+	go p.run()
+	return p
+}
+
+// Open creates the sinks of config, resolving a relative file against
+// confPath, and starts a publisher for them
+func Open(config Config, confPath string, dispatcher *webhook.Dispatcher, send artifacts.EndpointSender,
+	logger *slog.Logger) (*Publisher, error) {
+	var sinks []Sink
+	if config.File != "" {
+		path := config.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(confPath, path)
+		}
+		sink, err := OpenFileSink(path)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if config.URL != "" {
+		sinks = append(sinks, WebhookSink{URL: config.URL, Dispatcher: dispatcher})
+	}
+	if config.Endpoint != "" {
+		sinks = append(sinks, EndpointSink{Endpoint: config.Endpoint, Send: send})
+	}
+	return NewPublisher(config.Buffer, logger, sinks...), nil
+}
+
+// Publish queues event for the sinks
+func (p *Publisher) Publish(event Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- event:
+	default:
+		p.dropped++
+	}
+}
+
+// Reopen reopens the files of file sinks after log rotation
+func (p *Publisher) Reopen() error {
+	var errs []error
+	for _, sink := range p.sinks {
+		if reopener, ok := sink.(interface{ Reopen() error }); ok {
+			if err := reopener.Reopen(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dropped returns the number of events discarded because the queue was full
+func (p *Publisher) Dropped() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dropped
+}
+
+// Close writes the queued events and closes the sinks
+func (p *Publisher) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+	<-p.done
+	for _, sink := range p.sinks {
+		if err := sink.Close(); err != nil {
+			p.logger.Error("Failed to close transaction sink", slog.String("sink", sink.Name()), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+	for event := range p.queue {
+		for _, sink := range p.sinks {
+			if err := sink.Write(event); err != nil {
+				p.logger.Error("Failed to publish transaction event", slog.String("sink", sink.Name()),
+					slog.String("id", event.ID), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+var (
+	defaultMu        sync.RWMutex
+	defaultPublisher *Publisher
+)
+
+// SetDefault replaces the publisher API requests are published with; nil
+// stops publishing
+func SetDefault(publisher *Publisher) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultPublisher = publisher
+}
+
+// Default returns the publisher API requests are published with, or nil
+func Default() *Publisher {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultPublisher
+}
@@ -0,0 +1,222 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package transactions emits one structured event per completed API request,
+// with its consumer, latency, backend calls and fault, to sinks feeding
+// billing and audit pipelines.
+package transactions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/core/tracing"
+)
+
+// Property holds the *Recorder of a message whose transaction is published
+const Property = "TRANSACTION"
+
+// consumerProperties identify the caller, most specific first
+var consumerProperties = []string{"AUTHENTICATED_USER", "CLIENT_CERT_SUBJECT"}
+
+// Event describes one completed request
+type Event struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	API  string    `json:"api"`
+	// Resource is the URI template of the resource that mediated the request
+	Resource  string    `json:"resource,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Consumer  string    `json:"consumer,omitempty"`
+	Status    int       `json:"status"`
+	LatencyMs float64   `json:"latencyMs"`
+	Backends  []Backend `json:"backends,omitempty"`
+	Fault     *Fault    `json:"fault,omitempty"`
+	TraceID   string    `json:"traceId,omitempty"`
+}
+
+// Backend is one call to an endpoint made while mediating the request
+type Backend struct {
+	Endpoint  string  `json:"endpoint"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Fault is the error that failed the mediation
+type Fault struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Recorder collects what the mediation of a request learns about its
+// transaction. Messages copied for parallel branches share the recorder.
+type Recorder struct {
+	mu       sync.Mutex
+	resource string
+	consumer string
+	fault    *Fault
+	backends []Backend
+}
+
+// RecordBackend notes a call to endpoint that took latency and failed with
+// err, or succeeded when err is nil
+func (r *Recorder) RecordBackend(endpoint string, latency time.Duration, err error) {
+	backend := Backend{Endpoint: endpoint, LatencyMs: milliseconds(latency)}
+	if err != nil {
+		backend.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.backends = append(r.backends, backend)
+}
+
+// Complete notes the resource, the consumer and, when the mediation failed,
+// the fault of the message
+func (r *Recorder) Complete(msgContext *synctx.MsgContext, resource string, success bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resource = resource
+	for _, property := range consumerProperties {
+		if consumer, ok := msgContext.Properties[property].(string); ok && consumer != "" {
+			r.consumer = consumer
+			break
+		}
+	}
+	if !success {
+		code, _ := msgContext.Properties[artifacts.ErrorCodeProperty].(string)
+		message, _ := msgContext.Properties[artifacts.ErrorMessageProperty].(string)
+		r.fault = &Fault{Code: code, Message: message}
+	}
+}
+
+// Event builds the event of a request to api answered with status
+func (r *Recorder) Event(api string, request *http.Request, status int, start time.Time, latency time.Duration) Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := Event{
+		ID:        newID(),
+		Time:      start.UTC(),
+		API:       api,
+		Resource:  r.resource,
+		Method:    request.Method,
+		Path:      request.URL.Path,
+		Consumer:  r.consumer,
+		Status:    status,
+		LatencyMs: milliseconds(latency),
+		Backends:  append([]Backend(nil), r.backends...),
+		Fault:     r.fault,
+		TraceID:   tracing.TraceID(request.Context()),
+	}
+	// Requests that fail outside mediation, e.g. in a panic, still carry a fault
+	if event.Fault == nil && status >= http.StatusInternalServerError {
+		event.Fault = &Fault{Message: http.StatusText(status)}
+	}
+	return event
+}
+
+// AddToContext has the transaction of the message recorded
+func AddToContext(msgContext *synctx.MsgContext, recorder *Recorder) {
+	msgContext.Properties[Property] = recorder
+}
+
+// FromContext returns the recorder of a message, or nil when its transaction
+// is not published
+func FromContext(msgContext *synctx.MsgContext) *Recorder {
+	recorder, _ := msgContext.Properties[Property].(*Recorder)
+	return recorder
+}
+
+type recorderKey struct{}
+
+// WithRecorder attaches recorder to a request context, for the handler that
+// creates the message context
+func WithRecorder(ctx context.Context, recorder *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, recorder)
+}
+
+// FromRequestContext returns the recorder attached by WithRecorder, or nil
+func FromRequestContext(ctx context.Context) *Recorder {
+	recorder, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return recorder
+}
+
+// statusWriter captures the status of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Middleware publishes an event for every request to api while a default
+// publisher is set. Asynchronous resources complete when they are accepted,
+// so their backend calls are not part of the event.
+func Middleware(api string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		publisher := Default()
+		if publisher == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		recorder := &Recorder{}
+		writer := &statusWriter{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(writer, r.WithContext(WithRecorder(r.Context(), recorder)))
+		status := writer.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		publisher.Publish(recorder.Event(api, r, status, start, time.Since(start)))
+	})
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
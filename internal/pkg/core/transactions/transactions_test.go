@@ -0,0 +1,245 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package transactions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink keeps the events written to it
+type memorySink struct {
+	mu     sync.Mutex
+	events []Event
+	closed bool
+}
+
+func (s *memorySink) Name() string { return "memory" }
+
+func (s *memorySink) Write(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func testLogger() {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "disabled", config: Config{}},
+		{name: "file sink", config: Config{Enabled: true, File: "transactions.jsonl"}},
+		{name: "all sinks", config: Config{Enabled: true, File: "t.jsonl", URL: "https://example.com/t", Endpoint: "TransactionsEP"}},
+		{name: "no sink", config: Config{Enabled: true}, wantErr: "without a file, url or endpoint"},
+		{name: "bad url", config: Config{Enabled: true, URL: "kafka://broker"}, wantErr: "url must be"},
+		{name: "negative buffer", config: Config{Enabled: true, File: "t.jsonl", Buffer: -1}, wantErr: "buffer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	testLogger()
+	sink := &memorySink{}
+	publisher := NewPublisher(0, loggerfactory.GetLogger("transactions", nil), sink)
+	SetDefault(publisher)
+	t.Cleanup(func() { SetDefault(nil) })
+
+	handler := Middleware("orders:v1", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := synctx.CreateMsgContext()
+		recorder := FromRequestContext(r.Context())
+		require.NotNil(t, recorder)
+		AddToContext(msg, recorder)
+		msg.Properties["AUTHENTICATED_USER"] = "alice"
+		FromContext(msg).RecordBackend("InventoryEP", 20*time.Millisecond, nil)
+		FromContext(msg).RecordBackend("PricingEP", 5*time.Millisecond, errors.New("connection refused"))
+		msg.Properties[artifacts.ErrorCodeProperty] = "BACKEND_FAILED"
+		msg.Properties[artifacts.ErrorMessageProperty] = "pricing is unavailable"
+		recorder.Complete(msg, "/orders/{id}", false)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+	publisher.Close()
+
+	require.Len(t, sink.events, 1)
+	event := sink.events[0]
+	assert.Len(t, event.ID, 32)
+	assert.Equal(t, "orders:v1", event.API)
+	assert.Equal(t, "/orders/{id}", event.Resource)
+	assert.Equal(t, http.MethodGet, event.Method)
+	assert.Equal(t, "/orders/42", event.Path)
+	assert.Equal(t, "alice", event.Consumer)
+	assert.Equal(t, http.StatusBadGateway, event.Status)
+	assert.Equal(t, []Backend{
+		{Endpoint: "InventoryEP", LatencyMs: 20},
+		{Endpoint: "PricingEP", LatencyMs: 5, Error: "connection refused"},
+	}, event.Backends)
+	assert.Equal(t, &Fault{Code: "BACKEND_FAILED", Message: "pricing is unavailable"}, event.Fault)
+	assert.True(t, sink.closed)
+}
+
+func TestMiddlewareWithoutPublisher(t *testing.T) {
+	SetDefault(nil)
+	called := false
+	handler := Middleware("orders:v1", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		assert.Nil(t, FromRequestContext(r.Context()))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+	assert.True(t, called)
+}
+
+func TestEventFaultOutsideMediation(t *testing.T) {
+	recorder := &Recorder{}
+	event := recorder.Event("orders:v1", httptest.NewRequest(http.MethodPost, "/orders", nil),
+		http.StatusInternalServerError, time.Now(), time.Millisecond)
+	assert.Equal(t, &Fault{Message: "Internal Server Error"}, event.Fault)
+
+	event = recorder.Event("orders:v1", httptest.NewRequest(http.MethodPost, "/orders", nil),
+		http.StatusNotFound, time.Now(), time.Millisecond)
+	assert.Nil(t, event.Fault)
+}
+
+func TestPublisherDropsWhenFull(t *testing.T) {
+	testLogger()
+	block := make(chan struct{})
+	sink := &blockingSink{block: block}
+	publisher := NewPublisher(1, loggerfactory.GetLogger("transactions", nil), sink)
+	for i := 0; i < 5; i++ {
+		publisher.Publish(Event{ID: "e"})
+	}
+	// One event is being written, one is queued
+	assert.GreaterOrEqual(t, publisher.Dropped(), 3)
+	close(block)
+	publisher.Close()
+	publisher.Publish(Event{ID: "late"})
+}
+
+type blockingSink struct {
+	block chan struct{}
+}
+
+func (s *blockingSink) Name() string { return "blocking" }
+
+func (s *blockingSink) Write(event Event) error {
+	<-s.block
+	return nil
+}
+
+func (s *blockingSink) Close() error { return nil }
+
+func TestOpenFileSink(t *testing.T) {
+	testLogger()
+	confPath := t.TempDir()
+	publisher, err := Open(Config{Enabled: true, File: "logs/transactions.jsonl"}, confPath, nil, nil,
+		loggerfactory.GetLogger("transactions", nil))
+	require.NoError(t, err)
+	publisher.Publish(Event{ID: "a", API: "orders:v1", Status: http.StatusOK})
+	publisher.Publish(Event{ID: "b", API: "orders:v1", Status: http.StatusCreated})
+	publisher.Close()
+
+	file, err := os.Open(filepath.Join(confPath, "logs", "transactions.jsonl"))
+	require.NoError(t, err)
+	defer file.Close()
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, []string{"a", "b"}, ids)
+}
+
+func TestFileSinkReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.jsonl")
+	sink, err := OpenFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+	require.NoError(t, sink.Write(Event{ID: "a"}))
+
+	// Log rotation moves the file away, then asks for it to be reopened
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, sink.Write(Event{ID: "b"}))
+	require.NoError(t, sink.Reopen())
+	require.NoError(t, sink.Write(Event{ID: "c"}))
+
+	rotated, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(rotated), "\n"))
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(current), `"id":"c"`)
+	assert.Equal(t, 1, strings.Count(string(current), "\n"))
+}
+
+func TestEndpointSink(t *testing.T) {
+	artifacts.GetConfigContext().AddEndpoint(artifacts.Endpoint{Name: "TransactionsEP", Protocol: "kafka"})
+
+	var sent []byte
+	sink := EndpointSink{Endpoint: "TransactionsEP", Send: func(ctx context.Context, endpoint artifacts.Endpoint, msg *synctx.MsgContext) error {
+		assert.Equal(t, "kafka", endpoint.Protocol)
+		assert.Equal(t, "application/json", msg.Message.ContentType)
+		sent = msg.Message.RawPayload
+		return nil
+	}}
+	require.NoError(t, sink.Write(Event{ID: "a", API: "orders:v1"}))
+	assert.JSONEq(t, `{"id":"a","time":"0001-01-01T00:00:00Z","api":"orders:v1","method":"","path":"","status":0,"latencyMs":0}`, string(sent))
+
+	err := EndpointSink{Endpoint: "MissingEP"}.Write(Event{ID: "a"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not deployed")
+}
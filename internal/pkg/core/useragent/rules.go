@@ -0,0 +1,73 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package useragent
+
+// BuiltinRuleset returns the rules used unless [useragent] rules names a file.
+// Order matters: browsers that embed the tokens of others, such as Edge and
+// Opera in Chrome's, come first.
+func BuiltinRuleset() *Ruleset {
+	ruleset := &Ruleset{
+		Version: "builtin",
+		Bots: []Rule{
+			{Name: "Googlebot", Pattern: `Googlebot(?:-\w+)?(?:/(\d[\w.]*))?`},
+			{Name: "Bingbot", Pattern: `bingbot(?:/(\d[\w.]*))?`},
+			{Name: "DuckDuckBot", Pattern: `DuckDuckBot(?:-\w+)?(?:/(\d[\w.]*))?`},
+			{Name: "YandexBot", Pattern: `YandexBot(?:/(\d[\w.]*))?`},
+			{Name: "Baiduspider", Pattern: `Baiduspider(?:/(\d[\w.]*))?`},
+			{Name: "Applebot", Pattern: `Applebot(?:/(\d[\w.]*))?`},
+			{Name: "facebookexternalhit", Pattern: `facebookexternalhit(?:/(\d[\w.]*))?`},
+			{Name: "Twitterbot", Pattern: `Twitterbot(?:/(\d[\w.]*))?`},
+			{Name: "GPTBot", Pattern: `GPTBot(?:/(\d[\w.]*))?`},
+			{Name: "curl", Pattern: `^curl/(\d[\w.]*)`},
+			{Name: "Wget", Pattern: `^Wget/(\d[\w.]*)`},
+			{Name: "python-requests", Pattern: `python-requests/(\d[\w.]*)`},
+			{Name: "Go-http-client", Pattern: `^Go-http-client/(\d[\w.]*)`},
+			{Name: "HeadlessChrome", Pattern: `HeadlessChrome/(\d[\w.]*)`},
+			// Generic markers of crawlers that do not identify by name
+			{Name: "crawler", Pattern: `(?i)\b(?:bot|crawler|spider|crawling)\b`},
+		},
+		Browsers: []Rule{
+			{Name: "Edge", Pattern: `Edg(?:e|A|iOS)?/(\d[\w.]*)`},
+			{Name: "Opera", Pattern: `(?:OPR|Opera)/(\d[\w.]*)`},
+			{Name: "Samsung Internet", Pattern: `SamsungBrowser/(\d[\w.]*)`},
+			{Name: "Firefox", Pattern: `(?:Firefox|FxiOS)/(\d[\w.]*)`},
+			{Name: "Chrome", Pattern: `(?:Chrome|CriOS)/(\d[\w.]*)`},
+			{Name: "Safari", Pattern: `Version/(\d[\w.]*).*Safari/`},
+			{Name: "Internet Explorer", Pattern: `(?:MSIE |Trident/.*rv:)(\d[\w.]*)`},
+		},
+		OS: []Rule{
+			{Name: "iOS", Pattern: `(?:iPhone|iPad|iPod).*? OS (\d[\d_]*)`},
+			{Name: "Android", Pattern: `Android (\d[\w.]*)`},
+			{Name: "Windows", Pattern: `Windows NT (\d+\.\d+)`},
+			{Name: "macOS", Pattern: `Mac OS X (\d[\d_.]*)`},
+			{Name: "ChromeOS", Pattern: `CrOS \w+ (\d[\w.]*)`},
+			{Name: "Linux", Pattern: `Linux`},
+		},
+		Devices: []Rule{
+			// Android phones say Mobile, so Android is left over for tablets
+			{Name: DeviceMobile, Pattern: `Mobi|iPhone|iPod`},
+			{Name: DeviceTablet, Pattern: `iPad|Tablet|Android`},
+		},
+	}
+	if err := ruleset.compile(); err != nil {
+		panic(err)
+	}
+	return ruleset
+}
@@ -0,0 +1,314 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package useragent parses User-Agent headers into the browser, operating
+// system and device of the client, and recognizes bots. Detection is driven
+// by a ruleset that can be replaced at runtime from a JSON file, so new bots
+// and browsers are recognized without a new release.
+package useragent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+)
+
+// Message context properties set from the User-Agent header
+const (
+	BrowserProperty        = "UA_BROWSER"
+	BrowserVersionProperty = "UA_BROWSER_VERSION"
+	OSProperty             = "UA_OS"
+	OSVersionProperty      = "UA_OS_VERSION"
+	DeviceProperty         = "UA_DEVICE"
+	IsBotProperty          = "UA_IS_BOT"
+	BotProperty            = "UA_BOT"
+)
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Config holds the [useragent] section of deployment.toml
+type Config struct {
+	Enabled bool `koanf:"enabled"`
+	// Rules is a JSON ruleset replacing the built-in one, relative to the
+	// conf directory unless absolute
+	Rules string `koanf:"rules"`
+}
+
+// Validate reports configuration errors in the [useragent] section
+func (c Config) Validate() error {
+	if c.Rules != "" && !c.Enabled {
+		return fmt.Errorf("useragent: rules is set but enabled is false")
+	}
+	return nil
+}
+
+// Rule recognizes one browser, operating system, device type or bot. The
+// first capture group of the pattern, if any, is the version.
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+
+	regexp *regexp.Regexp
+}
+
+// Ruleset lists the rules of each kind in the order they are tried
+type Ruleset struct {
+	Version  string `json:"version"`
+	Bots     []Rule `json:"bots"`
+	Browsers []Rule `json:"browsers"`
+	OS       []Rule `json:"os"`
+	// Devices are tried for clients that are not bots; desktop is assumed
+	// when none matches
+	Devices []Rule `json:"devices"`
+}
+
+// compile prepares the patterns of every rule
+func (rs *Ruleset) compile() error {
+	for kind, rules := range map[string][]Rule{"bot": rs.Bots, "browser": rs.Browsers, "os": rs.OS, "device": rs.Devices} {
+		for i := range rules {
+			if rules[i].Name == "" {
+				return fmt.Errorf("%s rule %d has no name", kind, i+1)
+			}
+			compiled, err := regexp.Compile(rules[i].Pattern)
+			if err != nil {
+				return fmt.Errorf("%s rule %s: %w", kind, rules[i].Name, err)
+			}
+			rules[i].regexp = compiled
+		}
+	}
+	return nil
+}
+
+// ParseRuleset decodes and compiles a JSON ruleset
+func ParseRuleset(data []byte) (*Ruleset, error) {
+	ruleset := &Ruleset{}
+	if err := json.Unmarshal(data, ruleset); err != nil {
+		return nil, err
+	}
+	if len(ruleset.Bots)+len(ruleset.Browsers)+len(ruleset.OS)+len(ruleset.Devices) == 0 {
+		return nil, fmt.Errorf("ruleset has no rules")
+	}
+	if err := ruleset.compile(); err != nil {
+		return nil, err
+	}
+	return ruleset, nil
+}
+
+// LoadRuleset reads and compiles a JSON ruleset
+func LoadRuleset(path string) (*Ruleset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read user agent rules: %w", err)
+	}
+	ruleset, err := ParseRuleset(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user agent rules %s: %w", path, err)
+	}
+	return ruleset, nil
+}
+
+// Agent is what a User-Agent header reveals about the client
+type Agent struct {
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browserVersion,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"osVersion,omitempty"`
+	Device         string `json:"device"`
+	IsBot          bool   `json:"isBot"`
+	Bot            string `json:"bot,omitempty"`
+}
+
+// Parse describes the client behind a User-Agent header
+func (rs *Ruleset) Parse(header string) Agent {
+	agent := Agent{Device: DeviceDesktop}
+	if name, _, ok := match(rs.Bots, header); ok {
+		agent.IsBot, agent.Bot, agent.Device = true, name, DeviceBot
+	}
+	agent.Browser, agent.BrowserVersion, _ = match(rs.Browsers, header)
+	agent.OS, agent.OSVersion, _ = match(rs.OS, header)
+	if !agent.IsBot {
+		if device, _, ok := match(rs.Devices, header); ok {
+			agent.Device = device
+		}
+	}
+	return agent
+}
+
+// match returns the first rule matching header and the version it captured
+func match(rules []Rule, header string) (string, string, bool) {
+	for _, rule := range rules {
+		submatch := rule.regexp.FindStringSubmatch(header)
+		if submatch == nil {
+			continue
+		}
+		var version string
+		if len(submatch) > 1 {
+			version = strings.ReplaceAll(submatch[1], "_", ".")
+		}
+		return rule.Name, version, true
+	}
+	return "", "", false
+}
+
+// cacheSize bounds the parsed headers remembered by a Detector. Clients send
+// few distinct headers, so most requests are answered from the cache.
+const cacheSize = 4096
+
+// Detector parses User-Agent headers with a replaceable ruleset
+type Detector struct {
+	mu      sync.RWMutex
+	ruleset *Ruleset
+	cache   map[string]Agent
+	// rulesPath is the file the ruleset is reloaded from, empty for the
+	// built-in rules
+	rulesPath string
+}
+
+// NewDetector creates a detector using ruleset
+func NewDetector(ruleset *Ruleset) *Detector {
+	return &Detector{ruleset: ruleset, cache: make(map[string]Agent)}
+}
+
+// Open creates the detector described by config, resolving a relative rules
+// file against confPath
+func Open(config Config, confPath string) (*Detector, error) {
+	if config.Rules == "" {
+		return NewDetector(BuiltinRuleset()), nil
+	}
+	rulesPath := config.Rules
+	if !filepath.IsAbs(rulesPath) {
+		rulesPath = filepath.Join(confPath, rulesPath)
+	}
+	ruleset, err := LoadRuleset(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+	detector := NewDetector(ruleset)
+	detector.rulesPath = rulesPath
+	return detector, nil
+}
+
+// Reload reads the rules file again, or restores the built-in rules when the
+// detector was not opened from a file. The rules in use are kept when the
+// file is invalid.
+func (d *Detector) Reload() (*Ruleset, error) {
+	ruleset := BuiltinRuleset()
+	if d.rulesPath != "" {
+		var err error
+		if ruleset, err = LoadRuleset(d.rulesPath); err != nil {
+			return nil, err
+		}
+	}
+	d.SetRuleset(ruleset)
+	return ruleset, nil
+}
+
+// Ruleset returns the rules in use
+func (d *Detector) Ruleset() *Ruleset {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.ruleset
+}
+
+// SetRuleset replaces the rules and forgets headers parsed with the old ones
+func (d *Detector) SetRuleset(ruleset *Ruleset) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ruleset = ruleset
+	d.cache = make(map[string]Agent)
+}
+
+// Parse describes the client behind a User-Agent header
+func (d *Detector) Parse(header string) Agent {
+	d.mu.RLock()
+	agent, ok := d.cache[header]
+	ruleset := d.ruleset
+	d.mu.RUnlock()
+	if ok {
+		return agent
+	}
+	agent = ruleset.Parse(header)
+	d.mu.Lock()
+	// The cache is dropped when full rather than tracking recency; a
+	// rebuild costs one parse per distinct header
+	if len(d.cache) >= cacheSize {
+		d.cache = make(map[string]Agent)
+	}
+	if d.ruleset == ruleset {
+		d.cache[header] = agent
+	}
+	d.mu.Unlock()
+	return agent
+}
+
+// Enrich records what the User-Agent header of r reveals on msgContext
+func (d *Detector) Enrich(msgContext *synctx.MsgContext, r *http.Request) {
+	header := r.Header.Get("User-Agent")
+	if header == "" {
+		return
+	}
+	agent := d.Parse(header)
+	msgContext.Properties[DeviceProperty] = agent.Device
+	msgContext.Properties[IsBotProperty] = strconv.FormatBool(agent.IsBot)
+	for property, value := range map[string]string{
+		BrowserProperty:        agent.Browser,
+		BrowserVersionProperty: agent.BrowserVersion,
+		OSProperty:             agent.OS,
+		OSVersionProperty:      agent.OSVersion,
+		BotProperty:            agent.Bot,
+	} {
+		if value != "" {
+			msgContext.Properties[property] = value
+		}
+	}
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultDetector *Detector
+)
+
+// SetDefault installs the detector used by the HTTP entry points
+func SetDefault(detector *Detector) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultDetector = detector
+}
+
+// Default returns the detector used by the HTTP entry points, or nil when
+// user agent detection is not enabled
+func Default() *Detector {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultDetector
+}
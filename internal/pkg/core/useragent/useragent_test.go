@@ -0,0 +1,165 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package useragent
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/synctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinRuleset_Parse(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   Agent
+	}{
+		{
+			name:   "Chrome on Windows",
+			header: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.91 Safari/537.36",
+			want:   Agent{Browser: "Chrome", BrowserVersion: "124.0.6367.91", OS: "Windows", OSVersion: "10.0", Device: DeviceDesktop},
+		},
+		{
+			name:   "Edge is not reported as Chrome",
+			header: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.67",
+			want:   Agent{Browser: "Edge", BrowserVersion: "124.0.2478.67", OS: "Windows", OSVersion: "10.0", Device: DeviceDesktop},
+		},
+		{
+			name:   "Safari on iPhone",
+			header: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Mobile/15E148 Safari/604.1",
+			want:   Agent{Browser: "Safari", BrowserVersion: "17.4.1", OS: "iOS", OSVersion: "17.4.1", Device: DeviceMobile},
+		},
+		{
+			name:   "Firefox on Android phone",
+			header: "Mozilla/5.0 (Android 14; Mobile; rv:125.0) Gecko/125.0 Firefox/125.0",
+			want:   Agent{Browser: "Firefox", BrowserVersion: "125.0", OS: "Android", OSVersion: "14", Device: DeviceMobile},
+		},
+		{
+			name:   "Android tablet",
+			header: "Mozilla/5.0 (Linux; Android 13; SM-X200) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			want:   Agent{Browser: "Chrome", BrowserVersion: "124.0.0.0", OS: "Android", OSVersion: "13", Device: DeviceTablet},
+		},
+		{
+			name:   "Googlebot",
+			header: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:   Agent{Device: DeviceBot, IsBot: true, Bot: "Googlebot"},
+		},
+		{
+			name:   "curl",
+			header: "curl/8.5.0",
+			want:   Agent{Device: DeviceBot, IsBot: true, Bot: "curl"},
+		},
+		{
+			name:   "unknown crawler",
+			header: "ExampleCrawler/1.0 (+https://example.com/crawler)",
+			want:   Agent{Device: DeviceBot, IsBot: true, Bot: "crawler"},
+		},
+		{
+			name:   "unrecognized client",
+			header: "inventory-sync/3.2",
+			want:   Agent{Device: DeviceDesktop},
+		},
+	}
+	ruleset := BuiltinRuleset()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ruleset.Parse(tt.header))
+		})
+	}
+}
+
+func TestParseRuleset(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "valid", data: `{"bots": [{"name": "Monitor", "pattern": "UptimeMonitor/(\\d+)"}]}`},
+		{name: "malformed JSON", data: `{"bots": [`, wantErr: "unexpected end"},
+		{name: "no rules", data: `{"version": "2"}`, wantErr: "no rules"},
+		{name: "missing name", data: `{"os": [{"pattern": "Plan9"}]}`, wantErr: "os rule 1 has no name"},
+		{name: "invalid pattern", data: `{"browsers": [{"name": "Broken", "pattern": "("}]}`, wantErr: "browser rule Broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRuleset([]byte(tt.data))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDetector_ReloadAndSetRuleset(t *testing.T) {
+	confPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(confPath, "useragent"), 0o755))
+	rulesFile := filepath.Join(confPath, "useragent", "rules.json")
+	require.NoError(t, os.WriteFile(rulesFile, []byte(`{"bots": [{"name": "Monitor", "pattern": "UptimeMonitor/(\\d+)"}]}`), 0o644))
+
+	detector, err := Open(Config{Enabled: true, Rules: "useragent/rules.json"}, confPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Monitor", detector.Parse("UptimeMonitor/2").Bot)
+	assert.False(t, detector.Parse("curl/8.5.0").IsBot, "built-in rules are replaced by the file")
+
+	// Cached results are dropped with the rules they came from
+	require.NoError(t, os.WriteFile(rulesFile, []byte(`{"bots": [{"name": "curl", "pattern": "^curl/"}]}`), 0o644))
+	_, err = detector.Reload()
+	require.NoError(t, err)
+	assert.True(t, detector.Parse("curl/8.5.0").IsBot)
+	assert.False(t, detector.Parse("UptimeMonitor/2").IsBot)
+
+	// An invalid file keeps the rules in use
+	require.NoError(t, os.WriteFile(rulesFile, []byte(`{"bots": [{"name": "x", "pattern": "("}]}`), 0o644))
+	_, err = detector.Reload()
+	require.Error(t, err)
+	assert.True(t, detector.Parse("curl/8.5.0").IsBot)
+
+	detector.SetRuleset(BuiltinRuleset())
+	assert.Equal(t, "Chrome", detector.Parse("Mozilla/5.0 (X11; Linux x86_64) Chrome/124.0.0.0 Safari/537.36").Browser)
+}
+
+func TestDetector_Enrich(t *testing.T) {
+	detector := NewDetector(BuiltinRuleset())
+
+	msgContext := synctx.CreateMsgContext()
+	r := httptest.NewRequest("GET", "/orders", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15")
+	detector.Enrich(msgContext, r)
+	assert.Equal(t, "Safari", msgContext.Properties[BrowserProperty])
+	assert.Equal(t, "17.4", msgContext.Properties[BrowserVersionProperty])
+	assert.Equal(t, "macOS", msgContext.Properties[OSProperty])
+	assert.Equal(t, "10.15.7", msgContext.Properties[OSVersionProperty])
+	assert.Equal(t, DeviceDesktop, msgContext.Properties[DeviceProperty])
+	assert.Equal(t, "false", msgContext.Properties[IsBotProperty])
+	assert.NotContains(t, msgContext.Properties, BotProperty)
+
+	// Requests without the header are left alone
+	msgContext = synctx.CreateMsgContext()
+	detector.Enrich(msgContext, httptest.NewRequest("GET", "/orders", nil))
+	assert.NotContains(t, msgContext.Properties, IsBotProperty)
+}
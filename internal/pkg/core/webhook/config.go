@@ -0,0 +1,129 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package webhook
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts    = 8
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 5 * time.Minute
+	DefaultTimeout        = 10 * time.Second
+	// DefaultHistory is the number of finished deliveries kept for the admin API
+	DefaultHistory = 1000
+	DefaultWorkers = 16
+	// DefaultQueueSize is the number of pending deliveries, queued or waiting
+	// for a retry, beyond which new deliveries fail
+	DefaultQueueSize = 10000
+)
+
+// Config holds the [webhooks] section of deployment.toml
+type Config struct {
+	// SecretAlias names the [secrets] entry payloads are signed with
+	SecretAlias    string `koanf:"secretAlias"`
+	MaxAttempts    int    `koanf:"maxAttempts"`
+	InitialBackoff string `koanf:"initialBackoff"`
+	MaxBackoff     string `koanf:"maxBackoff"`
+	Timeout        string `koanf:"timeout"`
+	History        int    `koanf:"history"`
+	// Workers is the number of deliveries attempted at the same time
+	Workers   int `koanf:"workers"`
+	QueueSize int `koanf:"queueSize"`
+	// AllowPrivateCallbacks lets callback URLs sent by clients, such as those
+	// of async resources, reach loopback, link-local and private addresses
+	AllowPrivateCallbacks bool `koanf:"allowPrivateCallbacks"`
+}
+
+// Validate reports configuration errors in the [webhooks] section
+func (c Config) Validate() error {
+	if _, err := c.Policy(); err != nil {
+		return err
+	}
+	if _, err := c.RequestTimeout(); err != nil {
+		return err
+	}
+	if c.History < 0 {
+		return fmt.Errorf("webhooks: history must not be negative, got %d", c.History)
+	}
+	if c.Workers < 0 {
+		return fmt.Errorf("webhooks: workers must not be negative, got %d", c.Workers)
+	}
+	if c.QueueSize < 0 {
+		return fmt.Errorf("webhooks: queueSize must not be negative, got %d", c.QueueSize)
+	}
+	return nil
+}
+
+// Policy returns the retry schedule of the configuration
+func (c Config) Policy() (Policy, error) {
+	policy := Policy{MaxAttempts: c.MaxAttempts, InitialBackoff: DefaultInitialBackoff, MaxBackoff: DefaultMaxBackoff}
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = DefaultMaxAttempts
+	} else if policy.MaxAttempts < 0 {
+		return Policy{}, fmt.Errorf("webhooks: maxAttempts must be positive, got %d", c.MaxAttempts)
+	}
+	var err error
+	if policy.InitialBackoff, err = duration("initialBackoff", c.InitialBackoff, DefaultInitialBackoff); err != nil {
+		return Policy{}, err
+	}
+	if policy.MaxBackoff, err = duration("maxBackoff", c.MaxBackoff, DefaultMaxBackoff); err != nil {
+		return Policy{}, err
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		return Policy{}, fmt.Errorf("webhooks: maxBackoff %s is shorter than initialBackoff %s", policy.MaxBackoff, policy.InitialBackoff)
+	}
+	return policy, nil
+}
+
+// RequestTimeout returns how long a single attempt may take
+func (c Config) RequestTimeout() (time.Duration, error) {
+	return duration("timeout", c.Timeout, DefaultTimeout)
+}
+
+func duration(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("webhooks: %s %q must be a positive duration", name, value)
+	}
+	return d, nil
+}
+
+// Policy is an exponential retry schedule: the wait after each failed attempt
+// doubles from InitialBackoff up to MaxBackoff
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns the wait after the given number of failed attempts
+func (p Policy) Backoff(attempts int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < attempts && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, p.MaxBackoff)
+}
@@ -0,0 +1,75 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of signed payloads, as
+// "t=<unix seconds>,v1=<hex digest>". The digest covers "<t>.<payload>", so a
+// receiver can reject replays of old deliveries.
+const SignatureHeader = "X-Synapse-Signature"
+
+// Sign returns the signature header value for payload sent at the given time
+func Sign(secret []byte, at time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + digest(secret, timestamp, payload)
+}
+
+// Verify checks a signature header against payload, rejecting signatures
+// older than tolerance
+func Verify(secret []byte, header string, payload []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return fmt.Errorf("malformed webhook signature")
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("webhook signature timestamp is outside the tolerance of %s", tolerance)
+	}
+	if !hmac.Equal([]byte(signature), []byte(digest(secret, timestamp, payload))) {
+		return fmt.Errorf("webhook signature does not match the payload")
+	}
+	return nil
+}
+
+func digest(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,418 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package webhook delivers HTTP callbacks on behalf of the runtime: async
+// replies, event notifications and SLO alerts. Deliveries are signed when a
+// secret is configured, retried on an exponential schedule and tracked so
+// failed deliveries can be inspected and redelivered through the admin API.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+)
+
+const (
+	componentName = "webhook"
+	// DeliveryHeader carries the delivery ID so receivers can drop repeats
+	DeliveryHeader = "X-Synapse-Delivery"
+	// SourceHeader names the component that produced the callback
+	SourceHeader = "X-Synapse-Source"
+)
+
+// Sources of webhook deliveries
+const (
+	SourceAsyncReply   = "async-reply"
+	SourceEvents       = "events"
+	SourceAlerts       = "alerts"
+	SourceAnalytics    = "analytics"
+	SourceTransactions = "transactions"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusFailed    Status = "failed"
+)
+
+// Request is a callback to deliver
+type Request struct {
+	URL         string
+	Source      string
+	ContentType string
+	Header      http.Header
+	Payload     []byte
+	// Untrusted marks URLs chosen by clients of the gateway, which may only
+	// be posted to public addresses unless the configuration allows others
+	Untrusted bool
+}
+
+// Delivery is the state of a callback and its attempts so far
+type Delivery struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Source string `json:"source"`
+	Status Status `json:"status"`
+	// Attempts counts the attempts of the current delivery, reset on redelivery
+	Attempts       int       `json:"attempts"`
+	LastStatusCode int       `json:"lastStatusCode,omitempty"`
+	LastError      string    `json:"lastError,omitempty"`
+	Created        time.Time `json:"created"`
+	Updated        time.Time `json:"updated"`
+	// NextAttempt is zero unless a retry is scheduled
+	NextAttempt time.Time `json:"nextAttempt,omitempty"`
+
+	request Request
+}
+
+// Dispatcher delivers callbacks in the background and remembers the outcome
+// of the most recent ones. A fixed pool of workers takes deliveries from a
+// bounded queue; deliveries made while the queue is full fail at once.
+type Dispatcher struct {
+	client *http.Client
+	// untrusted posts the requests with untrusted URLs
+	untrusted *http.Client
+	policy    Policy
+	secret    []byte
+	history   int
+	now       func() time.Time
+	ctx       context.Context
+	cancel    context.CancelFunc
+	inFlight  sync.WaitGroup
+	// queue holds deliveries due for an attempt. Its capacity is the limit
+	// on pending deliveries, so sends to it never block.
+	queue chan *Delivery
+
+	mu         sync.Mutex
+	deliveries map[string]*Delivery
+	pending    int
+	// finished holds the IDs of completed deliveries, oldest first
+	finished []string
+}
+
+// NewDispatcher creates a dispatcher for the given configuration, signing
+// payloads with secret unless it is empty
+func NewDispatcher(config Config, secret []byte) (*Dispatcher, error) {
+	policy, err := config.Policy()
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := config.RequestTimeout()
+	if err != nil {
+		return nil, err
+	}
+	history := config.History
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	client := &http.Client{Timeout: timeout}
+	untrusted := client
+	if !config.AllowPrivateCallbacks {
+		untrusted = newPublicClient(timeout)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		client:     client,
+		untrusted:  untrusted,
+		policy:     policy,
+		secret:     secret,
+		history:    history,
+		now:        time.Now,
+		ctx:        ctx,
+		cancel:     cancel,
+		queue:      make(chan *Delivery, queueSize),
+		deliveries: make(map[string]*Delivery),
+	}
+	d.inFlight.Add(workers)
+	for range workers {
+		go d.work()
+	}
+	return d, nil
+}
+
+// Enqueue schedules the first attempt of a callback. When the queue is full
+// the delivery fails at once and can be redelivered later.
+func (d *Dispatcher) Enqueue(request Request) Delivery {
+	id := make([]byte, 12)
+	rand.Read(id)
+	now := d.now()
+	delivery := &Delivery{
+		ID:      hex.EncodeToString(id),
+		URL:     request.URL,
+		Source:  request.Source,
+		Status:  StatusPending,
+		Created: now,
+		Updated: now,
+		request: request,
+	}
+	d.mu.Lock()
+	d.deliveries[delivery.ID] = delivery
+	if d.pending >= cap(d.queue) {
+		delivery.Status = StatusFailed
+		delivery.LastError = "webhook queue is full"
+		d.finish(delivery.ID)
+	} else {
+		d.pending++
+		d.queue <- delivery
+	}
+	snapshot := *delivery
+	d.mu.Unlock()
+
+	if snapshot.Status == StatusFailed {
+		d.logFailure(snapshot)
+	}
+	return snapshot
+}
+
+// Redeliver starts a finished delivery over with a fresh retry schedule
+func (d *Dispatcher) Redeliver(id string) (Delivery, error) {
+	d.mu.Lock()
+	delivery, ok := d.deliveries[id]
+	if !ok {
+		d.mu.Unlock()
+		return Delivery{}, fmt.Errorf("webhook delivery %s is not known", id)
+	}
+	if delivery.Status == StatusPending {
+		d.mu.Unlock()
+		return Delivery{}, fmt.Errorf("webhook delivery %s is still pending", id)
+	}
+	if d.pending >= cap(d.queue) {
+		d.mu.Unlock()
+		return Delivery{}, fmt.Errorf("webhook queue is full, delivery %s cannot be redelivered yet", id)
+	}
+	d.finished = slices.DeleteFunc(d.finished, func(finished string) bool { return finished == id })
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.LastStatusCode = 0
+	delivery.Updated = d.now()
+	d.pending++
+	d.queue <- delivery
+	snapshot := *delivery
+	d.mu.Unlock()
+
+	return snapshot, nil
+}
+
+// Get returns the delivery with the given ID
+func (d *Dispatcher) Get(id string) (Delivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery, ok := d.deliveries[id]
+	if !ok {
+		return Delivery{}, false
+	}
+	return *delivery, true
+}
+
+// Deliveries returns the known deliveries, most recent first, optionally
+// restricted to those with the given status
+func (d *Dispatcher) Deliveries(status Status) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	deliveries := make([]Delivery, 0, len(d.deliveries))
+	for _, delivery := range d.deliveries {
+		if status == "" || delivery.Status == status {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b Delivery) int {
+		if c := b.Created.Compare(a.Created); c != 0 {
+			return c
+		}
+		return b.Updated.Compare(a.Updated)
+	})
+	return deliveries
+}
+
+// Close abandons scheduled retries and waits for attempts in flight
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.inFlight.Wait()
+}
+
+// work attempts queued deliveries until the dispatcher is closed
+func (d *Dispatcher) work() {
+	defer d.inFlight.Done()
+	for {
+		select {
+		case delivery := <-d.queue:
+			d.run(delivery)
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// run makes one attempt of a delivery and records the outcome, queueing the
+// delivery again once its backoff has elapsed if the attempt may be retried
+func (d *Dispatcher) run(delivery *Delivery) {
+	statusCode, retryAfter, err := d.attempt(delivery)
+
+	d.mu.Lock()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	if err != nil {
+		delivery.LastError = err.Error()
+	}
+	delivery.Updated = d.now()
+	delivery.NextAttempt = time.Time{}
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+	case !retryable(statusCode) || delivery.Attempts >= d.policy.MaxAttempts:
+		delivery.Status = StatusFailed
+	default:
+		wait := max(d.policy.Backoff(delivery.Attempts), min(retryAfter, d.policy.MaxBackoff))
+		delivery.NextAttempt = delivery.Updated.Add(wait)
+		time.AfterFunc(wait, func() {
+			if d.ctx.Err() == nil {
+				d.queue <- delivery
+			}
+		})
+	}
+	if delivery.Status != StatusPending {
+		d.pending--
+		d.finish(delivery.ID)
+	}
+	snapshot := *delivery
+	d.mu.Unlock()
+
+	if snapshot.Status == StatusFailed {
+		d.logFailure(snapshot)
+	}
+}
+
+func (d *Dispatcher) logFailure(delivery Delivery) {
+	loggerfactory.GetLogger(componentName, nil).Warn("Webhook delivery failed",
+		slog.String("id", delivery.ID),
+		slog.String("source", delivery.Source),
+		slog.String("url", delivery.URL),
+		slog.Int("attempts", delivery.Attempts),
+		slog.String("error", delivery.LastError))
+}
+
+// finish records a completed delivery and forgets the oldest completed
+// deliveries beyond the history limit. Callers hold d.mu.
+func (d *Dispatcher) finish(id string) {
+	d.finished = append(d.finished, id)
+	for len(d.finished) > d.history {
+		delete(d.deliveries, d.finished[0])
+		d.finished = d.finished[1:]
+	}
+}
+
+// attempt posts the callback once. It returns the response status, if any,
+// and how long the receiver asked to wait before retrying.
+func (d *Dispatcher) attempt(delivery *Delivery) (int, time.Duration, error) {
+	request := delivery.request
+	httpRequest, err := http.NewRequestWithContext(d.ctx, http.MethodPost, request.URL, bytes.NewReader(request.Payload))
+	if err != nil {
+		return 0, 0, fmt.Errorf("error creating webhook request: %w", err)
+	}
+	for name, values := range request.Header {
+		httpRequest.Header[name] = values
+	}
+	if request.ContentType != "" {
+		httpRequest.Header.Set("Content-Type", request.ContentType)
+	}
+	httpRequest.Header.Set(DeliveryHeader, delivery.ID)
+	if request.Source != "" {
+		httpRequest.Header.Set(SourceHeader, request.Source)
+	}
+	if len(d.secret) > 0 {
+		httpRequest.Header.Set(SignatureHeader, Sign(d.secret, d.now(), request.Payload))
+	}
+
+	client := d.client
+	if request.Untrusted {
+		client = d.untrusted
+	}
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		return 0, 0, err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return response.StatusCode, retryAfter, fmt.Errorf("receiver responded with status %d", response.StatusCode)
+	}
+	return response.StatusCode, 0, nil
+}
+
+// retryable reports whether a failed attempt may succeed later: the receiver
+// was unreachable, timed out, throttled the request or had a server error
+func retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+var (
+	defaultMu         sync.RWMutex
+	defaultDispatcher = mustDispatcher(NewDispatcher(Config{}, nil))
+)
+
+func mustDispatcher(dispatcher *Dispatcher, err error) *Dispatcher {
+	if err != nil {
+		panic(err)
+	}
+	return dispatcher
+}
+
+// SetDefault installs the dispatcher used by the runtime's callbacks and
+// closes the one it replaces
+func SetDefault(dispatcher *Dispatcher) {
+	defaultMu.Lock()
+	previous := defaultDispatcher
+	defaultDispatcher = dispatcher
+	defaultMu.Unlock()
+	previous.Close()
+}
+
+// Default returns the dispatcher used by the runtime's callbacks
+func Default() *Dispatcher {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultDispatcher
+}
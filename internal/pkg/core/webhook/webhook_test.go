@@ -0,0 +1,228 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/synapse-go/internal/pkg/loggerfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDispatcher(t *testing.T, config Config, secret []byte) *Dispatcher {
+	loggerfactory.GetConfigManager().SetSlogHandlerConfig(loggerfactory.SlogHandlerConfig{Format: "text", OutputPath: "stdout"})
+	dispatcher, err := NewDispatcher(config, secret)
+	require.NoError(t, err)
+	t.Cleanup(dispatcher.Close)
+	return dispatcher
+}
+
+func waitForStatus(t *testing.T, dispatcher *Dispatcher, id string, status Status) Delivery {
+	var delivery Delivery
+	require.Eventually(t, func() bool {
+		delivery, _ = dispatcher.Get(id)
+		return delivery.Status == status
+	}, 5*time.Second, 5*time.Millisecond)
+	return delivery
+}
+
+func TestDispatcher_SignsAndRetries(t *testing.T) {
+	secret := []byte("s3cret")
+	var attempts atomic.Int32
+	received := make(chan *http.Request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, Verify(secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute))
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received <- r
+	}))
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, Config{InitialBackoff: "1ms", MaxBackoff: "4ms"}, secret)
+	delivery := dispatcher.Enqueue(Request{URL: receiver.URL, Source: SourceEvents, ContentType: "application/json",
+		Header: http.Header{"X-Custom": {"1"}}, Payload: []byte(`{"type":"slo.breached"}`)})
+	assert.Equal(t, StatusPending, delivery.Status)
+
+	delivered := waitForStatus(t, dispatcher, delivery.ID, StatusDelivered)
+	assert.Equal(t, 3, delivered.Attempts)
+	assert.Equal(t, http.StatusOK, delivered.LastStatusCode)
+	assert.Empty(t, delivered.LastError)
+	request := <-received
+	assert.Equal(t, delivery.ID, request.Header.Get(DeliveryHeader))
+	assert.Equal(t, SourceEvents, request.Header.Get(SourceHeader))
+	assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+	assert.Equal(t, "1", request.Header.Get("X-Custom"))
+}
+
+func TestDispatcher_FailuresAndRedelivery(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer receiver.Close()
+	dispatcher := newTestDispatcher(t, Config{MaxAttempts: 2, InitialBackoff: "1ms", MaxBackoff: "1ms"}, nil)
+
+	delivery := dispatcher.Enqueue(Request{URL: receiver.URL, Source: SourceAlerts})
+	failed := waitForStatus(t, dispatcher, delivery.ID, StatusFailed)
+	assert.Equal(t, 2, failed.Attempts, "retried until maxAttempts")
+	assert.Equal(t, "receiver responded with status 500", failed.LastError)
+	assert.Equal(t, []Delivery{failed}, dispatcher.Deliveries(StatusFailed))
+	assert.Empty(t, dispatcher.Deliveries(StatusDelivered))
+
+	status.Store(http.StatusBadRequest)
+	rejected := waitForStatus(t, dispatcher, dispatcher.Enqueue(Request{URL: receiver.URL}).ID, StatusFailed)
+	assert.Equal(t, 1, rejected.Attempts, "client errors are not retried")
+
+	status.Store(http.StatusNoContent)
+	redelivered, err := dispatcher.Redeliver(delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, redelivered.Status)
+	assert.Equal(t, 0, redelivered.Attempts)
+	delivered := waitForStatus(t, dispatcher, delivery.ID, StatusDelivered)
+	assert.Equal(t, 1, delivered.Attempts)
+
+	_, err = dispatcher.Redeliver("missing")
+	assert.EqualError(t, err, "webhook delivery missing is not known")
+}
+
+func TestDispatcher_UntrustedURLs(t *testing.T) {
+	received := make(chan struct{}, 2)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, Config{MaxAttempts: 1}, nil)
+	refused := waitForStatus(t, dispatcher, dispatcher.Enqueue(Request{URL: receiver.URL, Untrusted: true}).ID, StatusFailed)
+	assert.Contains(t, refused.LastError, "refusing to connect to non-public address 127.0.0.1")
+	waitForStatus(t, dispatcher, dispatcher.Enqueue(Request{URL: receiver.URL}).ID, StatusDelivered)
+	assert.Len(t, received, 1, "only the trusted request reached the receiver")
+
+	allowing := newTestDispatcher(t, Config{MaxAttempts: 1, AllowPrivateCallbacks: true}, nil)
+	waitForStatus(t, allowing, allowing.Enqueue(Request{URL: receiver.URL, Untrusted: true}).ID, StatusDelivered)
+}
+
+func TestPublicOnly(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:80", "[::1]:80", "10.1.2.3:443", "172.16.0.1:80", "192.168.1.1:80",
+		"169.254.169.254:80", "[fe80::1]:80", "0.0.0.0:80", "[::ffff:127.0.0.1]:80", "[fd00::1]:80"} {
+		assert.Error(t, publicOnly("tcp", address, nil), address)
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1::1]:443"} {
+		assert.NoError(t, publicOnly("tcp", address, nil), address)
+	}
+}
+
+func TestDispatcher_History(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+	dispatcher := newTestDispatcher(t, Config{History: 2}, nil)
+
+	var ids []string
+	for range 3 {
+		delivery := dispatcher.Enqueue(Request{URL: receiver.URL})
+		waitForStatus(t, dispatcher, delivery.ID, StatusDelivered)
+		ids = append(ids, delivery.ID)
+	}
+	_, ok := dispatcher.Get(ids[0])
+	assert.False(t, ok, "the oldest finished delivery is forgotten")
+	assert.Len(t, dispatcher.Deliveries(""), 2)
+}
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer receiver.Close()
+	dispatcher := newTestDispatcher(t, Config{Workers: 1, QueueSize: 1}, nil)
+
+	first := dispatcher.Enqueue(Request{URL: receiver.URL})
+	rejected := dispatcher.Enqueue(Request{URL: receiver.URL})
+	assert.Equal(t, StatusFailed, rejected.Status)
+	assert.Equal(t, "webhook queue is full", rejected.LastError)
+	_, err := dispatcher.Redeliver(rejected.ID)
+	assert.EqualError(t, err, "webhook queue is full, delivery "+rejected.ID+" cannot be redelivered yet")
+
+	close(release)
+	waitForStatus(t, dispatcher, first.ID, StatusDelivered)
+	_, err = dispatcher.Redeliver(rejected.ID)
+	require.NoError(t, err)
+	waitForStatus(t, dispatcher, rejected.ID, StatusDelivered)
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	policy, err := Config{InitialBackoff: "1s", MaxBackoff: "10s"}.Policy()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMaxAttempts, policy.MaxAttempts)
+	var waits []time.Duration
+	for attempts := 1; attempts <= 6; attempts++ {
+		waits = append(waits, policy.Backoff(attempts))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}, waits)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		config  Config
+		wantErr string
+	}{
+		{config: Config{}},
+		{config: Config{MaxAttempts: 3, InitialBackoff: "500ms", MaxBackoff: "1m", Timeout: "5s", History: 10}},
+		{config: Config{MaxAttempts: -1}, wantErr: "webhooks: maxAttempts must be positive, got -1"},
+		{config: Config{InitialBackoff: "soon"}, wantErr: `webhooks: initialBackoff "soon" must be a positive duration`},
+		{config: Config{InitialBackoff: "1m", MaxBackoff: "1s"}, wantErr: "webhooks: maxBackoff 1s is shorter than initialBackoff 1m0s"},
+		{config: Config{Timeout: "0s"}, wantErr: `webhooks: timeout "0s" must be a positive duration`},
+		{config: Config{History: -1}, wantErr: "webhooks: history must not be negative, got -1"},
+		{config: Config{Workers: -1}, wantErr: "webhooks: workers must not be negative, got -1"},
+		{config: Config{QueueSize: -1}, wantErr: "webhooks: queueSize must not be negative, got -1"},
+	}
+	for _, tt := range tests {
+		err := tt.config.Validate()
+		if tt.wantErr == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	sentAt := time.Unix(1700000000, 0)
+	header := Sign(secret, sentAt, []byte("payload"))
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, Verify(secret, header, []byte("payload"), sentAt.Add(time.Minute), 5*time.Minute))
+	assert.EqualError(t, Verify(secret, header, []byte("tampered"), sentAt, time.Minute), "webhook signature does not match the payload")
+	assert.EqualError(t, Verify([]byte("other"), header, []byte("payload"), sentAt, time.Minute), "webhook signature does not match the payload")
+	assert.EqualError(t, Verify(secret, header, []byte("payload"), sentAt.Add(time.Hour), time.Minute),
+		"webhook signature timestamp is outside the tolerance of 1m0s")
+	assert.EqualError(t, Verify(secret, "v1=abc", []byte("payload"), sentAt, time.Minute), "malformed webhook signature")
+}
@@ -0,0 +1,201 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package wsdl reads WSDL 1.1 service descriptions and generates API artifacts
+// that expose each SOAP operation as a resource, for onboarding SOAP services.
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/wsdl/soap/"
+	soap12Namespace = "http://schemas.xmlsoap.org/wsdl/soap12/"
+
+	soap11ContentType = "text/xml"
+	soap12ContentType = "application/soap+xml"
+)
+
+// Operation is a SOAP operation of a service port
+type Operation struct {
+	Name       string
+	SOAPAction string
+}
+
+// Port is a SOAP endpoint of a service
+type Port struct {
+	Name string
+	// Location is the address of the target endpoint
+	Location string
+	// SOAPVersion is "1.1" or "1.2"
+	SOAPVersion string
+	Operations  []Operation
+}
+
+// Service is a service declared by a WSDL document
+type Service struct {
+	Name  string
+	Ports []Port
+}
+
+type definitions struct {
+	Services []struct {
+		Name  string `xml:"name,attr"`
+		Ports []struct {
+			Name    string `xml:"name,attr"`
+			Binding string `xml:"binding,attr"`
+			Address []struct {
+				XMLName  xml.Name
+				Location string `xml:"location,attr"`
+			} `xml:"address"`
+		} `xml:"port"`
+	} `xml:"service"`
+	Bindings []struct {
+		Name       string `xml:"name,attr"`
+		Operations []struct {
+			Name      string `xml:"name,attr"`
+			Operation []struct {
+				XMLName    xml.Name
+				SOAPAction string `xml:"soapAction,attr"`
+			} `xml:"operation"`
+		} `xml:"operation"`
+	} `xml:"binding"`
+}
+
+// Parse reads the SOAP services of a WSDL 1.1 document. Ports bound to
+// protocols other than SOAP, such as HTTP GET, are left out.
+func Parse(r io.Reader) ([]Service, error) {
+	var document definitions
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid WSDL: %w", err)
+	}
+
+	var services []Service
+	for _, declared := range document.Services {
+		service := Service{Name: declared.Name}
+		for _, declaredPort := range declared.Ports {
+			port := Port{Name: declaredPort.Name}
+			for _, address := range declaredPort.Address {
+				switch address.XMLName.Space {
+				case soap11Namespace:
+					port.SOAPVersion, port.Location = "1.1", address.Location
+				case soap12Namespace:
+					port.SOAPVersion, port.Location = "1.2", address.Location
+				}
+			}
+			if port.SOAPVersion == "" {
+				continue
+			}
+			bindingName := localName(declaredPort.Binding)
+			for _, binding := range document.Bindings {
+				if binding.Name != bindingName {
+					continue
+				}
+				for _, operation := range binding.Operations {
+					op := Operation{Name: operation.Name}
+					for _, soapOperation := range operation.Operation {
+						if soapOperation.XMLName.Space == soap11Namespace || soapOperation.XMLName.Space == soap12Namespace {
+							op.SOAPAction = soapOperation.SOAPAction
+						}
+					}
+					port.Operations = append(port.Operations, op)
+				}
+			}
+			service.Ports = append(service.Ports, port)
+		}
+		if len(service.Ports) > 0 {
+			services = append(services, service)
+		}
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("WSDL declares no SOAP service")
+	}
+	return services, nil
+}
+
+// localName strips the namespace prefix of a qualified name such as tns:QuoteBinding
+func localName(qualified string) string {
+	if _, local, found := strings.Cut(qualified, ":"); found {
+		return local
+	}
+	return qualified
+}
+
+var nonPathCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// DefaultContext derives an API context from a service name, e.g.
+// StockQuoteService becomes /stockquoteservice
+func DefaultContext(serviceName string) string {
+	return "/" + strings.Trim(nonPathCharacters.ReplaceAllString(strings.ToLower(serviceName), "-"), "-")
+}
+
+// GenerateAPI writes an API artifact for a port of the service. Every
+// operation becomes a POST resource accepting the SOAP content type of the
+// port. The target endpoint and SOAP action of each operation are recorded in
+// the artifact for the flow that forwards requests to the service.
+func GenerateAPI(service Service, port Port, context string) ([]byte, error) {
+	if len(port.Operations) == 0 {
+		return nil, fmt.Errorf("port %s of service %s has no operations", port.Name, service.Name)
+	}
+	contentType := soap11ContentType
+	if port.SOAPVersion == "1.2" {
+		contentType = soap12ContentType
+	}
+
+	var out bytes.Buffer
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&out, "<api name=%s context=%s xmlns=\"http://ws.apache.org/ns/synapse\">\n", attr(service.Name), attr(context))
+	fmt.Fprintf(&out, "    <!-- Generated from the %s port of the %s WSDL service. Target endpoint (SOAP %s): %s -->\n",
+		comment(port.Name), comment(service.Name), port.SOAPVersion, comment(port.Location))
+	for _, operation := range port.Operations {
+		fmt.Fprintf(&out, "    <resource methods=\"POST\" uri-template=%s>\n", attr("/"+operation.Name))
+		out.WriteString("        <inSequence>\n")
+		fmt.Fprintf(&out, "            <validateRequest contentTypes=%s/>\n", attr(contentType))
+		out.WriteString("            <log category=\"INFO\">\n")
+		fmt.Fprintf(&out, "                <message>%s</message>\n",
+			text(fmt.Sprintf("%s request for %s (SOAPAction %q)", operation.Name, port.Location, operation.SOAPAction)))
+		out.WriteString("            </log>\n")
+		out.WriteString("        </inSequence>\n")
+		out.WriteString("    </resource>\n")
+	}
+	out.WriteString("</api>\n")
+	return out.Bytes(), nil
+}
+
+// attr quotes an attribute value; EscapeText also escapes the quotes
+func attr(value string) string {
+	return `"` + text(value) + `"`
+}
+
+func text(value string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
+
+// comment keeps a value from terminating the XML comment it is written into
+func comment(value string) string {
+	return strings.ReplaceAll(value, "--", "- -")
+}
@@ -0,0 +1,145 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package wsdl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/synapse-go/internal/pkg/core/artifacts"
+	"github.com/apache/synapse-go/internal/pkg/core/deployers/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stockQuoteWSDL = `<?xml version="1.0"?>
+<wsdl:definitions xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+    xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+    xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+    xmlns:http="http://schemas.xmlsoap.org/wsdl/http/"
+    xmlns:tns="http://services.samples" targetNamespace="http://services.samples">
+  <wsdl:binding name="StockQuoteSoap11Binding" type="tns:StockQuotePortType">
+    <soap:binding transport="http://schemas.xmlsoap.org/soap/http" style="document"/>
+    <wsdl:operation name="getQuote">
+      <soap:operation soapAction="urn:getQuote" style="document"/>
+    </wsdl:operation>
+    <wsdl:operation name="placeOrder">
+      <soap:operation soapAction="urn:placeOrder" style="document"/>
+    </wsdl:operation>
+  </wsdl:binding>
+  <wsdl:binding name="StockQuoteSoap12Binding" type="tns:StockQuotePortType">
+    <soap12:binding transport="http://schemas.xmlsoap.org/soap/http" style="document"/>
+    <wsdl:operation name="getQuote">
+      <soap12:operation soapAction="urn:getQuote" style="document"/>
+    </wsdl:operation>
+  </wsdl:binding>
+  <wsdl:binding name="StockQuoteHttpBinding" type="tns:StockQuotePortType">
+    <http:binding verb="POST"/>
+  </wsdl:binding>
+  <wsdl:service name="StockQuoteService">
+    <wsdl:port name="StockQuoteSoap11Endpoint" binding="tns:StockQuoteSoap11Binding">
+      <soap:address location="http://localhost:9000/services/StockQuote"/>
+    </wsdl:port>
+    <wsdl:port name="StockQuoteSoap12Endpoint" binding="tns:StockQuoteSoap12Binding">
+      <soap12:address location="http://localhost:9000/services/StockQuote.Soap12"/>
+    </wsdl:port>
+    <wsdl:port name="StockQuoteHttpEndpoint" binding="tns:StockQuoteHttpBinding">
+      <http:address location="http://localhost:9000/services/StockQuote.Http"/>
+    </wsdl:port>
+  </wsdl:service>
+</wsdl:definitions>`
+
+func TestParse(t *testing.T) {
+	services, err := Parse(strings.NewReader(stockQuoteWSDL))
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+
+	expected := Service{
+		Name: "StockQuoteService",
+		Ports: []Port{
+			{
+				Name:        "StockQuoteSoap11Endpoint",
+				Location:    "http://localhost:9000/services/StockQuote",
+				SOAPVersion: "1.1",
+				Operations: []Operation{
+					{Name: "getQuote", SOAPAction: "urn:getQuote"},
+					{Name: "placeOrder", SOAPAction: "urn:placeOrder"},
+				},
+			},
+			{
+				Name:        "StockQuoteSoap12Endpoint",
+				Location:    "http://localhost:9000/services/StockQuote.Soap12",
+				SOAPVersion: "1.2",
+				Operations:  []Operation{{Name: "getQuote", SOAPAction: "urn:getQuote"}},
+			},
+		},
+	}
+	assert.Equal(t, expected, services[0])
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		errMsg   string
+	}{
+		{"not XML", "not a wsdl", "invalid WSDL"},
+		{"no SOAP ports", `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"><service name="S"><port name="P"/></service></definitions>`, "no SOAP service"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.document))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestDefaultContext(t *testing.T) {
+	assert.Equal(t, "/stockquoteservice", DefaultContext("StockQuoteService"))
+	assert.Equal(t, "/order-service-v2", DefaultContext("Order_Service.V2"))
+}
+
+func TestGenerateAPI(t *testing.T) {
+	services, err := Parse(strings.NewReader(stockQuoteWSDL))
+	require.NoError(t, err)
+	service := services[0]
+
+	artifact, err := GenerateAPI(service, service.Ports[0], DefaultContext(service.Name))
+	require.NoError(t, err)
+	assert.Contains(t, string(artifact), "http://localhost:9000/services/StockQuote")
+	assert.Contains(t, string(artifact), `contentTypes="text/xml"`)
+
+	api, err := (&types.API{}).Unmarshal(string(artifact), artifacts.Position{FileName: "StockQuoteService.xml"})
+	require.NoError(t, err)
+	assert.Equal(t, "StockQuoteService", api.Name)
+	assert.Equal(t, "/stockquoteservice", api.Context)
+	require.Len(t, api.Resources, 2)
+	assert.Equal(t, []string{"POST"}, api.Resources[0].Methods)
+	assert.Equal(t, "/getQuote", api.Resources[0].URITemplate.FullTemplate)
+	assert.Equal(t, "/placeOrder", api.Resources[1].URITemplate.FullTemplate)
+
+	soap12, err := GenerateAPI(service, service.Ports[1], "/quotes")
+	require.NoError(t, err)
+	assert.Contains(t, string(soap12), `contentTypes="application/soap+xml"`)
+
+	_, err = GenerateAPI(service, Port{Name: "Empty"}, "/quotes")
+	assert.Error(t, err)
+}
@@ -0,0 +1,353 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package wssecurity generates and validates WS-Security headers
+// (UsernameToken and Timestamp profiles) on SOAP 1.1 and SOAP 1.2 envelopes.
+package wssecurity
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	NamespaceSOAP11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	NamespaceSOAP12 = "http://www.w3.org/2003/05/soap-envelope"
+	NamespaceWSSE   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	NamespaceWSU    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+
+	PasswordText   = "PasswordText"
+	PasswordDigest = "PasswordDigest"
+
+	tokenProfile   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0"
+	base64Encoding = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+	timeLayout     = "2006-01-02T15:04:05.000Z"
+)
+
+// Options describes the security header added to outbound messages
+type Options struct {
+	Username string
+	Password string
+	// PasswordType is PasswordText or PasswordDigest; defaults to PasswordDigest
+	PasswordType string
+	// TimestampTTL adds a Timestamp expiring after the given duration when set
+	TimestampTTL time.Duration
+}
+
+// AddSecurityHeader inserts a wsse:Security header into the SOAP envelope,
+// creating the SOAP header when the envelope has none
+func AddSecurityHeader(envelope []byte, opts Options, now time.Time) ([]byte, error) {
+	securityHeader, err := buildSecurityHeader(opts, now)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(envelope))
+	depth := 0
+	var envelopeNS, envelopePrefix string
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil, fmt.Errorf("payload is not a SOAP envelope")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse SOAP envelope: %w", err)
+		}
+		end := decoder.InputOffset()
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				if t.Name.Local != "Envelope" {
+					return nil, fmt.Errorf("payload is not a SOAP envelope")
+				}
+				envelopePrefix = t.Name.Space
+				envelopeNS = namespaceOf(t, envelopePrefix)
+				continue
+			}
+			security := securityHeader(envelopeNS)
+			qualified := t.Name.Local
+			if t.Name.Space != "" {
+				qualified = t.Name.Space + ":" + qualified
+			}
+			var out bytes.Buffer
+			if t.Name.Local == "Header" {
+				raw := envelope[start:end]
+				if bytes.HasSuffix(raw, []byte("/>")) {
+					out.Write(envelope[:start])
+					out.Write(bytes.TrimSpace(raw[:len(raw)-2]))
+					out.WriteString(">" + security + "</" + qualified + ">")
+				} else {
+					out.Write(envelope[:end])
+					out.WriteString(security)
+				}
+				out.Write(envelope[end:])
+				return out.Bytes(), nil
+			}
+			// The first child is the body, so the header goes right before it
+			header := "Header"
+			if envelopePrefix != "" {
+				header = envelopePrefix + ":Header"
+			}
+			out.Write(envelope[:start])
+			out.WriteString("<" + header + ">" + security + "</" + header + ">")
+			out.Write(envelope[start:])
+			return out.Bytes(), nil
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				return nil, fmt.Errorf("SOAP envelope has no body")
+			}
+		}
+	}
+}
+
+// namespaceOf returns the namespace URI bound to prefix on the element
+func namespaceOf(element xml.StartElement, prefix string) string {
+	for _, attr := range element.Attr {
+		if (prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns") ||
+			(attr.Name.Space == "xmlns" && attr.Name.Local == prefix) {
+			return attr.Value
+		}
+	}
+	return NamespaceSOAP11
+}
+
+// buildSecurityHeader returns a function rendering the wsse:Security element for
+// the given SOAP namespace, so mustUnderstand is qualified correctly
+func buildSecurityHeader(opts Options, now time.Time) (func(envelopeNS string) string, error) {
+	var body bytes.Buffer
+	now = now.UTC()
+	if opts.TimestampTTL > 0 {
+		body.WriteString(`<wsu:Timestamp wsu:Id="TS-1"><wsu:Created>` + now.Format(timeLayout) + `</wsu:Created><wsu:Expires>` +
+			now.Add(opts.TimestampTTL).Format(timeLayout) + `</wsu:Expires></wsu:Timestamp>`)
+	}
+	if opts.Username != "" {
+		passwordType := opts.PasswordType
+		if passwordType == "" {
+			passwordType = PasswordDigest
+		}
+		body.WriteString(`<wsse:UsernameToken wsu:Id="UT-1"><wsse:Username>`)
+		xml.EscapeText(&body, []byte(opts.Username))
+		body.WriteString(`</wsse:Username>`)
+		switch passwordType {
+		case PasswordText:
+			body.WriteString(`<wsse:Password Type="` + tokenProfile + `#PasswordText">`)
+			xml.EscapeText(&body, []byte(opts.Password))
+			body.WriteString(`</wsse:Password>`)
+		case PasswordDigest:
+			nonce := make([]byte, 16)
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, err
+			}
+			created := now.Format(timeLayout)
+			body.WriteString(`<wsse:Password Type="` + tokenProfile + `#PasswordDigest">` + passwordDigest(nonce, created, opts.Password) + `</wsse:Password>`)
+			body.WriteString(`<wsse:Nonce EncodingType="` + base64Encoding + `">` + base64.StdEncoding.EncodeToString(nonce) + `</wsse:Nonce>`)
+			body.WriteString(`<wsu:Created>` + created + `</wsu:Created>`)
+		default:
+			return nil, fmt.Errorf("unsupported password type '%s'", passwordType)
+		}
+		body.WriteString(`</wsse:UsernameToken>`)
+	}
+	if body.Len() == 0 {
+		return nil, fmt.Errorf("security header needs a username or a timestamp")
+	}
+	content := body.String()
+	return func(envelopeNS string) string {
+		return `<wsse:Security xmlns:wsse="` + NamespaceWSSE + `" xmlns:wsu="` + NamespaceWSU + `" xmlns:wssoap="` + envelopeNS + `" wssoap:mustUnderstand="1">` +
+			content + `</wsse:Security>`
+	}, nil
+}
+
+// passwordDigest computes Base64(SHA-1(nonce + created + password)) as defined
+// by the UsernameToken profile
+func passwordDigest(nonce []byte, created, password string) string {
+	hash := sha1.New()
+	hash.Write(nonce)
+	hash.Write([]byte(created))
+	hash.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// ValidationOptions describes the security header required on inbound messages
+type ValidationOptions struct {
+	// Password returns the password of a user, or false for unknown users
+	Password             func(username string) (string, bool)
+	RequireUsernameToken bool
+	RequireTimestamp     bool
+	// ClockSkew tolerated when checking Created and Expires; defaults to 5 minutes
+	ClockSkew time.Duration
+}
+
+type envelope struct {
+	XMLName xml.Name
+	Header  struct {
+		Security *security `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+	} `xml:"Header"`
+}
+
+type security struct {
+	Timestamp     *timestamp     `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Timestamp"`
+	UsernameToken *usernameToken `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd UsernameToken"`
+}
+
+type timestamp struct {
+	Created string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+	Expires string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Expires"`
+}
+
+type usernameToken struct {
+	Username string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Username"`
+	Password struct {
+		Type  string `xml:"Type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Password"`
+	Nonce   string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Nonce"`
+	Created string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+}
+
+// Validator checks inbound security headers and rejects replayed digests
+type Validator struct {
+	opts ValidationOptions
+
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewValidator creates a validator with its own nonce replay cache
+func NewValidator(opts ValidationOptions) *Validator {
+	if opts.ClockSkew == 0 {
+		opts.ClockSkew = 5 * time.Minute
+	}
+	return &Validator{opts: opts, nonces: make(map[string]time.Time)}
+}
+
+// Validate checks the security header of the SOAP envelope and returns the
+// authenticated username, if a UsernameToken was present
+func (v *Validator) Validate(payload []byte, now time.Time) (string, error) {
+	var env envelope
+	if err := xml.Unmarshal(payload, &env); err != nil {
+		return "", fmt.Errorf("cannot parse SOAP envelope: %w", err)
+	}
+	if env.XMLName.Local != "Envelope" || (env.XMLName.Space != NamespaceSOAP11 && env.XMLName.Space != NamespaceSOAP12) {
+		return "", fmt.Errorf("payload is not a SOAP envelope")
+	}
+	sec := env.Header.Security
+	if sec == nil {
+		if v.opts.RequireUsernameToken || v.opts.RequireTimestamp {
+			return "", fmt.Errorf("missing wsse:Security header")
+		}
+		return "", nil
+	}
+
+	if sec.Timestamp != nil {
+		if err := v.checkTimestamp(sec.Timestamp, now); err != nil {
+			return "", err
+		}
+	} else if v.opts.RequireTimestamp {
+		return "", fmt.Errorf("missing wsu:Timestamp")
+	}
+
+	if sec.UsernameToken == nil {
+		if v.opts.RequireUsernameToken {
+			return "", fmt.Errorf("missing wsse:UsernameToken")
+		}
+		return "", nil
+	}
+	token := sec.UsernameToken
+	password, known := v.opts.Password(token.Username)
+	if !known {
+		return "", fmt.Errorf("authentication failed for user '%s'", token.Username)
+	}
+	switch token.Password.Type {
+	case "", tokenProfile + "#PasswordText":
+		if subtle.ConstantTimeCompare([]byte(token.Password.Value), []byte(password)) != 1 {
+			return "", fmt.Errorf("authentication failed for user '%s'", token.Username)
+		}
+	case tokenProfile + "#PasswordDigest":
+		nonce, err := base64.StdEncoding.DecodeString(token.Nonce)
+		if err != nil || len(nonce) == 0 {
+			return "", fmt.Errorf("invalid wsse:Nonce")
+		}
+		created, err := time.Parse(time.RFC3339, token.Created)
+		if err != nil {
+			return "", fmt.Errorf("invalid wsu:Created in UsernameToken")
+		}
+		if created.After(now.Add(v.opts.ClockSkew)) || created.Before(now.Add(-v.opts.ClockSkew)) {
+			return "", fmt.Errorf("UsernameToken is outside the accepted time window")
+		}
+		expected := passwordDigest(nonce, token.Created, password)
+		if subtle.ConstantTimeCompare([]byte(token.Password.Value), []byte(expected)) != 1 {
+			return "", fmt.Errorf("authentication failed for user '%s'", token.Username)
+		}
+		if !v.rememberNonce(token.Nonce, now) {
+			return "", fmt.Errorf("replayed UsernameToken nonce")
+		}
+	default:
+		return "", fmt.Errorf("unsupported password type '%s'", token.Password.Type)
+	}
+	return token.Username, nil
+}
+
+func (v *Validator) checkTimestamp(ts *timestamp, now time.Time) error {
+	created, err := time.Parse(time.RFC3339, ts.Created)
+	if err != nil {
+		return fmt.Errorf("invalid wsu:Created in Timestamp")
+	}
+	if created.After(now.Add(v.opts.ClockSkew)) {
+		return fmt.Errorf("message timestamp is in the future")
+	}
+	if ts.Expires != "" {
+		expires, err := time.Parse(time.RFC3339, ts.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid wsu:Expires in Timestamp")
+		}
+		if now.Add(-v.opts.ClockSkew).After(expires) {
+			return fmt.Errorf("message has expired")
+		}
+	}
+	return nil
+}
+
+// rememberNonce records a digest nonce for the accepted time window and reports
+// whether it was new
+func (v *Validator) rememberNonce(nonce string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for seen, expiry := range v.nonces {
+		if now.After(expiry) {
+			delete(v.nonces, seen)
+		}
+	}
+	if _, replayed := v.nonces[nonce]; replayed {
+		return false
+	}
+	v.nonces[nonce] = now.Add(2 * v.opts.ClockSkew)
+	return true
+}
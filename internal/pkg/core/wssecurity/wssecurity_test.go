@@ -0,0 +1,123 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package wssecurity
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddSecurityHeader(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	opts := Options{Username: "alice", Password: "secret", PasswordType: PasswordText, TimestampTTL: 5 * time.Minute}
+
+	testCases := []struct {
+		name     string
+		envelope string
+		prefix   string
+		suffix   string
+	}{
+		{
+			name:     "No header",
+			envelope: `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><ping/></soapenv:Body></soapenv:Envelope>`,
+			prefix:   `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Header><wsse:Security `,
+			suffix:   `</wsse:Security></soapenv:Header><soapenv:Body><ping/></soapenv:Body></soapenv:Envelope>`,
+		},
+		{
+			name:     "Existing header",
+			envelope: `<Envelope xmlns="http://www.w3.org/2003/05/soap-envelope"><Header><trace>1</trace></Header><Body/></Envelope>`,
+			prefix:   `<Envelope xmlns="http://www.w3.org/2003/05/soap-envelope"><Header><wsse:Security `,
+			suffix:   `</wsse:Security><trace>1</trace></Header><Body/></Envelope>`,
+		},
+		{
+			name:     "Empty header",
+			envelope: `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Header/><s:Body/></s:Envelope>`,
+			prefix:   `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Header><wsse:Security `,
+			suffix:   `</wsse:Security></s:Header><s:Body/></s:Envelope>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secured, err := AddSecurityHeader([]byte(tc.envelope), opts, now)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(secured), tc.prefix), string(secured))
+			assert.True(t, strings.HasSuffix(string(secured), tc.suffix), string(secured))
+
+			// The generated header passes validation
+			validator := NewValidator(ValidationOptions{
+				Password:             func(string) (string, bool) { return "secret", true },
+				RequireUsernameToken: true,
+				RequireTimestamp:     true,
+			})
+			username, err := validator.Validate(secured, now)
+			assert.NoError(t, err)
+			assert.Equal(t, "alice", username)
+		})
+	}
+
+	_, err := AddSecurityHeader([]byte(`<order/>`), opts, now)
+	assert.EqualError(t, err, "payload is not a SOAP envelope")
+}
+
+func TestValidator_Validate(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	envelope := []byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body/></soapenv:Envelope>`)
+	digest, err := AddSecurityHeader(envelope, Options{Username: "alice", Password: "secret", TimestampTTL: time.Minute}, now)
+	assert.NoError(t, err)
+	passwords := func(username string) (string, bool) {
+		return "secret", username == "alice"
+	}
+
+	validator := NewValidator(ValidationOptions{Password: passwords, RequireUsernameToken: true})
+	username, err := validator.Validate(digest, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", username)
+	_, err = validator.Validate(digest, now)
+	assert.EqualError(t, err, "replayed UsernameToken nonce")
+
+	testCases := []struct {
+		name     string
+		payload  []byte
+		at       time.Time
+		password string
+		expected string
+	}{
+		{name: "Missing header", payload: envelope, at: now, password: "secret", expected: "missing wsse:Security header"},
+		{name: "Wrong password", payload: digest, at: now, password: "other", expected: "authentication failed for user 'alice'"},
+		{name: "Expired timestamp", payload: digest, at: now.Add(10 * time.Minute), password: "secret", expected: "message has expired"},
+		{name: "Not SOAP", payload: []byte(`<order/>`), at: now, password: "secret", expected: "payload is not a SOAP envelope"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := NewValidator(ValidationOptions{
+				Password:             func(string) (string, bool) { return tc.password, true },
+				RequireUsernameToken: true,
+				ClockSkew:            time.Minute,
+			})
+			_, err := validator.Validate(tc.payload, tc.at)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
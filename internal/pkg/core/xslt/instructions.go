@@ -0,0 +1,719 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package xslt
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// instruction is a compiled part of a template body, writing its result to
+// out
+type instruction interface {
+	execute(t *transformer, c *evalContext, out *etree.Element) error
+}
+
+// compileBody compiles the content of a template or instruction. Text that
+// is only whitespace is left out, as XSLT does outside xsl:text.
+func compileBody(tokens []etree.Token, calls *[]string) ([]instruction, error) {
+	var body []instruction
+	for _, token := range tokens {
+		switch token := token.(type) {
+		case *etree.CharData:
+			if !token.IsWhitespace() {
+				body = append(body, text(token.Data))
+			}
+		case *etree.Element:
+			in, err := compileInstruction(token, calls)
+			if err != nil {
+				return nil, err
+			}
+			body = append(body, in)
+		}
+	}
+	return body, nil
+}
+
+func compileInstruction(element *etree.Element, calls *[]string) (instruction, error) {
+	if !isXSL(element) {
+		return compileLiteral(element, calls)
+	}
+	name := "xsl:" + element.Tag
+	body := func() ([]instruction, error) {
+		return compileBody(element.Child, calls)
+	}
+	required := func(attr string) (expr, error) {
+		return compileInstructionAttr(element, attr)
+	}
+
+	switch element.Tag {
+	case "value-of":
+		e, err := required("select")
+		return valueOf{e}, err
+	case "text":
+		return text(element.Text()), nil
+	case "if":
+		test, err := required("test")
+		if err != nil {
+			return nil, err
+		}
+		then, err := body()
+		return ifInstruction{test, then}, err
+	case "choose":
+		var choose chooseInstruction
+		for _, branch := range element.ChildElements() {
+			switch {
+			case isXSL(branch) && branch.Tag == "when" && choose.otherwise == nil:
+				test, err := compileInstructionAttr(branch, "test")
+				if err != nil {
+					return nil, err
+				}
+				then, err := compileBody(branch.Child, calls)
+				if err != nil {
+					return nil, err
+				}
+				choose.whens = append(choose.whens, ifInstruction{test, then})
+			case isXSL(branch) && branch.Tag == "otherwise" && choose.otherwise == nil:
+				otherwise, err := compileBody(branch.Child, calls)
+				if err != nil {
+					return nil, err
+				}
+				choose.otherwise = append([]instruction{}, otherwise...)
+			default:
+				return nil, fmt.Errorf("xsl:choose: unexpected element '%s'", branch.FullTag())
+			}
+		}
+		if len(choose.whens) == 0 {
+			return nil, fmt.Errorf("xsl:choose: at least one xsl:when is required")
+		}
+		return choose, nil
+	case "for-each":
+		selectExpr, err := required("select")
+		if err != nil {
+			return nil, err
+		}
+		sorts, rest, err := compileSorts(element.Child)
+		if err != nil {
+			return nil, err
+		}
+		each, err := compileBody(rest, calls)
+		return forEach{selectExpr, sorts, each}, err
+	case "apply-templates":
+		apply := applyTemplates{mode: element.SelectAttrValue("mode", "")}
+		if element.SelectAttr("select") != nil {
+			var err error
+			if apply.selectExpr, err = required("select"); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if apply.sorts, _, err = compileSorts(element.Child); err != nil {
+			return nil, err
+		}
+		apply.params, err = compileWithParams(element, calls)
+		return apply, err
+	case "call-template":
+		templateName := element.SelectAttrValue("name", "")
+		if templateName == "" {
+			return nil, fmt.Errorf("%s: missing required attribute 'name'", name)
+		}
+		*calls = append(*calls, templateName)
+		params, err := compileWithParams(element, calls)
+		return callTemplate{templateName, params}, err
+	case "copy-of":
+		e, err := required("select")
+		return copyOf{e}, err
+	case "copy":
+		content, err := body()
+		return copyInstruction{content}, err
+	case "element", "attribute":
+		source := element.SelectAttr("name")
+		if source == nil {
+			return nil, fmt.Errorf("%s: missing required attribute 'name'", name)
+		}
+		nameAVT, err := compileAVT(source.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		content, err := body()
+		if element.Tag == "element" {
+			return elementInstruction{nameAVT, content}, err
+		}
+		return attributeInstruction{nameAVT, content}, err
+	case "comment":
+		content, err := body()
+		return commentInstruction{content}, err
+	case "variable":
+		return compileVariable(element, calls)
+	case "param":
+		return nil, fmt.Errorf("xsl:param must come first in a template")
+	case "sort":
+		return nil, fmt.Errorf("xsl:sort must come first in xsl:for-each or xsl:apply-templates")
+	}
+	return nil, fmt.Errorf("%s is not supported", name)
+}
+
+func compileInstructionAttr(element *etree.Element, attr string) (expr, error) {
+	source := element.SelectAttr(attr)
+	if source == nil {
+		return nil, fmt.Errorf("xsl:%s: missing required attribute '%s'", element.Tag, attr)
+	}
+	e, err := compileExpr(source.Value)
+	if err != nil {
+		return nil, fmt.Errorf("xsl:%s: %w", element.Tag, err)
+	}
+	return e, nil
+}
+
+// variable is a variable or parameter, bound to the value of its select
+// expression or of its content
+type variable struct {
+	name       string
+	param      bool
+	selectExpr expr
+	body       []instruction
+}
+
+func compileVariable(element *etree.Element, calls *[]string) (*variable, error) {
+	v := &variable{name: element.SelectAttrValue("name", ""), param: element.Tag == "param"}
+	if v.name == "" {
+		return nil, fmt.Errorf("xsl:%s: missing required attribute 'name'", element.Tag)
+	}
+	if element.SelectAttr("select") != nil {
+		var err error
+		v.selectExpr, err = compileInstructionAttr(element, "select")
+		return v, err
+	}
+	var err error
+	v.body, err = compileBody(element.Child, calls)
+	return v, err
+}
+
+// value evaluates the variable. Content is built into a result tree
+// fragment; a variable without either is the empty string.
+func (v *variable) value(t *transformer, c *evalContext) (value, error) {
+	if v.selectExpr != nil {
+		value, err := v.selectExpr.eval(c)
+		if err != nil {
+			return nil, fmt.Errorf("$%s: %w", v.name, err)
+		}
+		return value, nil
+	}
+	if len(v.body) == 0 {
+		return "", nil
+	}
+	fragment := etree.NewElement("")
+	if err := t.run(v.body, c, fragment); err != nil {
+		return nil, err
+	}
+	return []node{{element: fragment}}, nil
+}
+
+// execute does nothing: run binds variables for the instructions following
+// them
+func (v *variable) execute(*transformer, *evalContext, *etree.Element) error {
+	return nil
+}
+
+func compileWithParams(element *etree.Element, calls *[]string) ([]*variable, error) {
+	var params []*variable
+	for _, child := range element.ChildElements() {
+		if !isXSL(child) || child.Tag == "sort" {
+			continue
+		}
+		if child.Tag != "with-param" {
+			return nil, fmt.Errorf("xsl:%s: unexpected element '%s'", element.Tag, child.FullTag())
+		}
+		param, err := compileVariable(child, calls)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// bindParams evaluates the parameters passed to a template
+func bindParams(t *transformer, c *evalContext, params []*variable) (map[string]value, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]value, len(params))
+	for _, param := range params {
+		v, err := param.value(t, c)
+		if err != nil {
+			return nil, err
+		}
+		values[param.name] = v
+	}
+	return values, nil
+}
+
+type sortKey struct {
+	selectExpr expr
+	descending bool
+	numeric    bool
+}
+
+// compileSorts compiles the xsl:sort elements leading the content of an
+// instruction and returns the content after them
+func compileSorts(tokens []etree.Token) ([]sortKey, []etree.Token, error) {
+	var sorts []sortKey
+	for len(tokens) > 0 {
+		if text, ok := tokens[0].(*etree.CharData); ok && text.IsWhitespace() {
+			tokens = tokens[1:]
+			continue
+		}
+		element, ok := tokens[0].(*etree.Element)
+		if !ok || !isXSL(element) || element.Tag != "sort" {
+			break
+		}
+		key := sortKey{
+			descending: element.SelectAttrValue("order", "ascending") == "descending",
+			numeric:    element.SelectAttrValue("data-type", "text") == "number",
+		}
+		source := element.SelectAttrValue("select", ".")
+		var err error
+		if key.selectExpr, err = compileExpr(source); err != nil {
+			return nil, nil, fmt.Errorf("xsl:sort: %w", err)
+		}
+		sorts = append(sorts, key)
+		tokens = tokens[1:]
+	}
+	return sorts, tokens, nil
+}
+
+// sortNodes orders nodes by the sort keys, keeping document order among
+// equal nodes. Numbers that are not numbers sort first.
+func sortNodes(c *evalContext, nodes []node, keys []sortKey) ([]node, error) {
+	if len(keys) == 0 {
+		return nodes, nil
+	}
+	values := make(map[node][]value, len(nodes))
+	for i, n := range nodes {
+		inner := *c
+		inner.node, inner.position, inner.size, inner.current = n, i+1, len(nodes), n
+		for _, key := range keys {
+			v, err := key.selectExpr.eval(&inner)
+			if err != nil {
+				return nil, fmt.Errorf("xsl:sort: %w", err)
+			}
+			if key.numeric {
+				values[n] = append(values[n], toNumber(v))
+			} else {
+				values[n] = append(values[n], toString(v))
+			}
+		}
+	}
+	sorted := slices.Clone(nodes)
+	slices.SortStableFunc(sorted, func(a, b node) int {
+		for i, key := range keys {
+			var order int
+			if key.numeric {
+				x, y := values[a][i].(float64), values[b][i].(float64)
+				switch {
+				case math.IsNaN(x) && math.IsNaN(y):
+				case math.IsNaN(x):
+					order = -1
+				case math.IsNaN(y):
+					order = 1
+				default:
+					order = cmp.Compare(x, y)
+				}
+			} else {
+				order = strings.Compare(values[a][i].(string), values[b][i].(string))
+			}
+			if key.descending {
+				order = -order
+			}
+			if order != 0 {
+				return order
+			}
+		}
+		return 0
+	})
+	return sorted, nil
+}
+
+// avt is an attribute value template: text with expressions in braces, and
+// "{{" and "}}" for literal braces
+type avt []avtPart
+
+type avtPart struct {
+	text string
+	expr expr
+}
+
+func compileAVT(source string) (avt, error) {
+	var parts avt
+	var literal strings.Builder
+	for i := 0; i < len(source); i++ {
+		switch {
+		case strings.HasPrefix(source[i:], "{{"), strings.HasPrefix(source[i:], "}}"):
+			literal.WriteByte(source[i])
+			i++
+		case source[i] == '{':
+			end := strings.IndexByte(source[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '{' in '%s'", source)
+			}
+			e, err := compileExpr(source[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			if literal.Len() > 0 {
+				parts = append(parts, avtPart{text: literal.String()})
+				literal.Reset()
+			}
+			parts = append(parts, avtPart{expr: e})
+			i += end
+		case source[i] == '}':
+			return nil, fmt.Errorf("unescaped '}' in '%s'", source)
+		default:
+			literal.WriteByte(source[i])
+		}
+	}
+	if literal.Len() > 0 {
+		parts = append(parts, avtPart{text: literal.String()})
+	}
+	return parts, nil
+}
+
+func (a avt) eval(c *evalContext) (string, error) {
+	var b strings.Builder
+	for _, part := range a {
+		if part.expr == nil {
+			b.WriteString(part.text)
+			continue
+		}
+		v, err := part.expr.eval(c)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(toString(v))
+	}
+	return b.String(), nil
+}
+
+type text string
+
+func (t text) execute(_ *transformer, _ *evalContext, out *etree.Element) error {
+	out.CreateText(string(t))
+	return nil
+}
+
+type valueOf struct{ selectExpr expr }
+
+func (v valueOf) execute(_ *transformer, c *evalContext, out *etree.Element) error {
+	value, err := v.selectExpr.eval(c)
+	if err != nil {
+		return fmt.Errorf("xsl:value-of: %w", err)
+	}
+	if s := toString(value); s != "" {
+		out.CreateText(s)
+	}
+	return nil
+}
+
+type ifInstruction struct {
+	test expr
+	body []instruction
+}
+
+func (i ifInstruction) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	v, err := i.test.eval(c)
+	if err != nil {
+		return fmt.Errorf("xsl:if: %w", err)
+	}
+	if !toBool(v) {
+		return nil
+	}
+	return t.run(i.body, c, out)
+}
+
+type chooseInstruction struct {
+	whens     []ifInstruction
+	otherwise []instruction
+}
+
+func (ch chooseInstruction) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	for _, when := range ch.whens {
+		v, err := when.test.eval(c)
+		if err != nil {
+			return fmt.Errorf("xsl:when: %w", err)
+		}
+		if toBool(v) {
+			return t.run(when.body, c, out)
+		}
+	}
+	return t.run(ch.otherwise, c, out)
+}
+
+type forEach struct {
+	selectExpr expr
+	sorts      []sortKey
+	body       []instruction
+}
+
+func (f forEach) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	nodes, err := evalNodes("for-each", f.selectExpr, c)
+	if err != nil {
+		return err
+	}
+	if nodes, err = sortNodes(c, nodes, f.sorts); err != nil {
+		return err
+	}
+	for i, n := range nodes {
+		inner := *c
+		inner.node, inner.position, inner.size, inner.current = n, i+1, len(nodes), n
+		if err := t.run(f.body, &inner, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type applyTemplates struct {
+	selectExpr expr // nil selects the children
+	mode       string
+	sorts      []sortKey
+	params     []*variable
+}
+
+func (a applyTemplates) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	nodes := c.node.children()
+	if a.selectExpr != nil {
+		var err error
+		if nodes, err = evalNodes("apply-templates", a.selectExpr, c); err != nil {
+			return err
+		}
+	}
+	nodes, err := sortNodes(c, nodes, a.sorts)
+	if err != nil {
+		return err
+	}
+	params, err := bindParams(t, c, a.params)
+	if err != nil {
+		return err
+	}
+	for i, n := range nodes {
+		if err := t.apply(n, a.mode, i+1, len(nodes), params, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type callTemplate struct {
+	name   string
+	params []*variable
+}
+
+func (ct callTemplate) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	params, err := bindParams(t, c, ct.params)
+	if err != nil {
+		return err
+	}
+	inner := *c
+	return t.invoke(t.stylesheet.named[ct.name], &inner, params, out)
+}
+
+type copyOf struct{ selectExpr expr }
+
+func (co copyOf) execute(_ *transformer, c *evalContext, out *etree.Element) error {
+	v, err := co.selectExpr.eval(c)
+	if err != nil {
+		return fmt.Errorf("xsl:copy-of: %w", err)
+	}
+	nodes, ok := v.([]node)
+	if !ok {
+		if s := toString(v); s != "" {
+			out.CreateText(s)
+		}
+		return nil
+	}
+	for _, n := range nodes {
+		switch {
+		case n.attr != nil:
+			addAttr(out, n.attr.FullKey(), n.attr.Value)
+		case n.text != nil:
+			out.CreateText(n.text.Data)
+		case n.isDocument():
+			for _, child := range n.children() {
+				copyNode(child, out)
+			}
+		default:
+			copyNode(n, out)
+		}
+	}
+	return nil
+}
+
+// copyNode copies an element with its content, or a text node
+func copyNode(n node, out *etree.Element) {
+	if n.text != nil {
+		out.CreateText(n.text.Data)
+		return
+	}
+	copied := n.element.Copy()
+	out.AddChild(copied)
+	copyNamespaces(n.element, copied)
+}
+
+// addAttr sets an attribute on an output element. Attributes written outside
+// an element are ignored, as XSLT allows.
+func addAttr(out *etree.Element, name, value string) {
+	if out.Parent() == nil {
+		return
+	}
+	out.CreateAttr(name, value)
+}
+
+type copyInstruction struct{ body []instruction }
+
+func (ci copyInstruction) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	n := c.node
+	switch {
+	case n.attr != nil:
+		addAttr(out, n.attr.FullKey(), n.attr.Value)
+		return nil
+	case n.text != nil:
+		out.CreateText(n.text.Data)
+		return nil
+	case n.isDocument():
+		return t.run(ci.body, c, out)
+	}
+	copied := out.CreateElement(n.element.FullTag())
+	declareNamespace(copied, n.element.Space, n.element.NamespaceURI())
+	return t.run(ci.body, c, copied)
+}
+
+type elementInstruction struct {
+	name avt
+	body []instruction
+}
+
+func (ei elementInstruction) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	name, err := ei.name.eval(c)
+	if err != nil {
+		return fmt.Errorf("xsl:element: %w", err)
+	}
+	if name == "" {
+		return fmt.Errorf("xsl:element: name is empty")
+	}
+	return t.run(ei.body, c, out.CreateElement(name))
+}
+
+type attributeInstruction struct {
+	name avt
+	body []instruction
+}
+
+func (ai attributeInstruction) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	name, err := ai.name.eval(c)
+	if err != nil {
+		return fmt.Errorf("xsl:attribute: %w", err)
+	}
+	if name == "" {
+		return fmt.Errorf("xsl:attribute: name is empty")
+	}
+	content := etree.NewElement("")
+	if err := t.run(ai.body, c, content); err != nil {
+		return err
+	}
+	addAttr(out, name, node{element: content}.stringValue())
+	return nil
+}
+
+type commentInstruction struct{ body []instruction }
+
+func (ci commentInstruction) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	content := etree.NewElement("")
+	if err := t.run(ci.body, c, content); err != nil {
+		return err
+	}
+	out.CreateComment(node{element: content}.stringValue())
+	return nil
+}
+
+// literalElement is an element of the stylesheet outside the XSLT namespace,
+// copied to the output with its attribute values templated
+type literalElement struct {
+	tag        string
+	prefix     string
+	namespace  string
+	attributes []literalAttribute
+	body       []instruction
+}
+
+type literalAttribute struct {
+	name      string
+	prefix    string
+	namespace string
+	value     avt
+}
+
+func compileLiteral(element *etree.Element, calls *[]string) (instruction, error) {
+	literal := literalElement{tag: element.FullTag(), prefix: element.Space}
+	var ok bool
+	if literal.namespace, ok = resolvePrefix(element, element.Space); !ok && element.Space != "" {
+		return nil, fmt.Errorf("element '%s' uses an undeclared prefix", literal.tag)
+	}
+	for _, attr := range element.Attr {
+		if attr.Space == "xmlns" || (attr.Space == "" && attr.Key == "xmlns") || attr.NamespaceURI() == Namespace {
+			continue
+		}
+		value, err := compileAVT(attr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute '%s' of '%s': %w", attr.FullKey(), literal.tag, err)
+		}
+		la := literalAttribute{name: attr.FullKey(), prefix: attr.Space, value: value}
+		if attr.Space != "" && attr.Space != "xml" {
+			if la.namespace, ok = resolvePrefix(element, attr.Space); !ok {
+				return nil, fmt.Errorf("attribute '%s' of '%s' uses an undeclared prefix", la.name, literal.tag)
+			}
+		}
+		literal.attributes = append(literal.attributes, la)
+	}
+	var err error
+	literal.body, err = compileBody(element.Child, calls)
+	return literal, err
+}
+
+func (le literalElement) execute(t *transformer, c *evalContext, out *etree.Element) error {
+	element := out.CreateElement(le.tag)
+	declareNamespace(element, le.prefix, le.namespace)
+	for _, attr := range le.attributes {
+		value, err := attr.value.eval(c)
+		if err != nil {
+			return fmt.Errorf("attribute '%s' of '%s': %w", attr.name, le.tag, err)
+		}
+		if attr.prefix != "" && attr.prefix != "xml" {
+			declareNamespace(element, attr.prefix, attr.namespace)
+		}
+		element.CreateAttr(attr.name, value)
+	}
+	return t.run(le.body, c, element)
+}
@@ -0,0 +1,104 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package xslt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Registry loads stylesheets by key, their path below a directory such as
+// artifacts/Registry. A stylesheet is compiled when first loaded and again
+// only when its file changes, so transforms do not parse it per message.
+type Registry struct {
+	directory string
+	mu        sync.Mutex
+	compiled  map[string]compiled
+}
+
+type compiled struct {
+	modTime    time.Time
+	size       int64
+	stylesheet *Stylesheet
+}
+
+// NewRegistry returns a registry of the stylesheets below directory
+func NewRegistry(directory string) *Registry {
+	return &Registry{directory: directory, compiled: make(map[string]compiled)}
+}
+
+// ValidKey reports whether a key names a file below the registry directory
+func ValidKey(key string) bool {
+	return key != "" && filepath.IsLocal(filepath.FromSlash(key))
+}
+
+// Load returns the compiled stylesheet stored under key
+func (r *Registry) Load(key string) (*Stylesheet, error) {
+	if !ValidKey(key) {
+		return nil, fmt.Errorf("invalid stylesheet key '%s'", key)
+	}
+	path := filepath.Join(r.directory, filepath.FromSlash(key))
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stylesheet %s: %w", key, err)
+	}
+
+	r.mu.Lock()
+	cached, ok := r.compiled[key]
+	r.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.stylesheet, nil
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("stylesheet %s: %w", key, err)
+	}
+	stylesheet, err := Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("stylesheet %s: %w", key, err)
+	}
+	r.mu.Lock()
+	r.compiled[key] = compiled{modTime: info.ModTime(), size: info.Size(), stylesheet: stylesheet}
+	r.mu.Unlock()
+	return stylesheet, nil
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultRegistry = NewRegistry(filepath.Join("artifacts", "Registry"))
+)
+
+// SetDefault replaces the registry XSLT mediators load stylesheets from
+func SetDefault(registry *Registry) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultRegistry = registry
+}
+
+// Default returns the registry XSLT mediators load stylesheets from
+func Default() *Registry {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultRegistry
+}
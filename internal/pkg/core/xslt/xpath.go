@@ -0,0 +1,1259 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package xslt
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/beevik/etree"
+)
+
+// node is a node of the XPath data model: the document, an element, an
+// attribute or a text node. An element without a parent stands for the
+// document, or for the root of a result tree fragment.
+type node struct {
+	element *etree.Element
+	attr    *etree.Attr
+	text    *etree.CharData
+}
+
+func (n node) isDocument() bool {
+	return n.attr == nil && n.text == nil && n.element.Parent() == nil
+}
+
+func (n node) isElement() bool {
+	return n.attr == nil && n.text == nil && n.element.Parent() != nil
+}
+
+// stringValue is the text of a node: the value of an attribute, or all text
+// below a document or element
+func (n node) stringValue() string {
+	switch {
+	case n.attr != nil:
+		return n.attr.Value
+	case n.text != nil:
+		return n.text.Data
+	}
+	var b strings.Builder
+	appendText(&b, n.element)
+	return b.String()
+}
+
+func appendText(b *strings.Builder, element *etree.Element) {
+	for _, token := range element.Child {
+		switch token := token.(type) {
+		case *etree.CharData:
+			b.WriteString(token.Data)
+		case *etree.Element:
+			appendText(b, token)
+		}
+	}
+}
+
+func (n node) parent() (node, bool) {
+	switch {
+	case n.attr != nil:
+		return node{element: n.element}, true
+	case n.text != nil:
+		return node{element: n.text.Parent()}, true
+	case n.element.Parent() != nil:
+		return node{element: n.element.Parent()}, true
+	}
+	return node{}, false
+}
+
+// children returns the element and text children of a document or element.
+// Text directly below the document is whitespace between markup and is left
+// out.
+func (n node) children() []node {
+	if n.attr != nil || n.text != nil {
+		return nil
+	}
+	document := n.isDocument()
+	var children []node
+	for _, token := range n.element.Child {
+		switch token := token.(type) {
+		case *etree.Element:
+			children = append(children, node{element: token})
+		case *etree.CharData:
+			if !document {
+				children = append(children, node{element: n.element, text: token})
+			}
+		}
+	}
+	return children
+}
+
+// attributes returns the attributes of an element, without namespace
+// declarations
+func (n node) attributes() []node {
+	if !n.isElement() {
+		return nil
+	}
+	var attributes []node
+	for i := range n.element.Attr {
+		attr := &n.element.Attr[i]
+		if attr.Space == "xmlns" || (attr.Space == "" && attr.Key == "xmlns") {
+			continue
+		}
+		attributes = append(attributes, node{element: n.element, attr: attr})
+	}
+	return attributes
+}
+
+func (n node) name() string {
+	switch {
+	case n.attr != nil:
+		return n.attr.FullKey()
+	case n.isElement():
+		return n.element.FullTag()
+	}
+	return ""
+}
+
+func (n node) localName() string {
+	switch {
+	case n.attr != nil:
+		return n.attr.Key
+	case n.isElement():
+		return n.element.Tag
+	}
+	return ""
+}
+
+// order locates a node in document order: the child indexes leading to it,
+// with attributes placed after their element and before its children
+func (n node) order() []int {
+	var last []int
+	switch {
+	case n.attr != nil:
+		for i := range n.element.Attr {
+			if &n.element.Attr[i] == n.attr {
+				last = []int{-1, i}
+			}
+		}
+	case n.text != nil:
+		last = []int{n.text.Index()}
+	}
+	var order []int
+	for element := n.element; element.Parent() != nil; element = element.Parent() {
+		order = append(order, element.Index())
+	}
+	slices.Reverse(order)
+	return append(order, last...)
+}
+
+// documentOrder sorts nodes in document order and removes duplicates
+func documentOrder(nodes []node) []node {
+	seen := make(map[node]bool, len(nodes))
+	unique := nodes[:0:0]
+	for _, n := range nodes {
+		if !seen[n] {
+			seen[n] = true
+			unique = append(unique, n)
+		}
+	}
+	orders := make(map[node][]int, len(unique))
+	for _, n := range unique {
+		orders[n] = n.order()
+	}
+	slices.SortStableFunc(unique, func(a, b node) int {
+		return slices.Compare(orders[a], orders[b])
+	})
+	return unique
+}
+
+// value is the result of an expression: a node-set ([]node in document
+// order), a string, a number (float64) or a boolean
+type value any
+
+func toString(v value) string {
+	switch v := v.(type) {
+	case []node:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].stringValue()
+	case string:
+		return v
+	case float64:
+		return formatNumber(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+func toNumber(v value) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(toString(v)), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return number
+}
+
+func toBool(v value) bool {
+	switch v := v.(type) {
+	case []node:
+		return len(v) > 0
+	case string:
+		return v != ""
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case bool:
+		return v
+	}
+	return false
+}
+
+func formatNumber(number float64) string {
+	switch {
+	case math.IsNaN(number):
+		return "NaN"
+	case math.IsInf(number, 1):
+		return "Infinity"
+	case math.IsInf(number, -1):
+		return "-Infinity"
+	case number == math.Trunc(number) && math.Abs(number) < 1e15:
+		return strconv.FormatInt(int64(number), 10)
+	}
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// evalContext is the context an expression is evaluated in
+type evalContext struct {
+	node     node
+	position int
+	size     int
+	// current is the node the enclosing template or for-each is at
+	current node
+	vars    map[string]value
+}
+
+// with returns a copy of the context with a variable bound
+func (c *evalContext) with(name string, v value) *evalContext {
+	next := *c
+	next.vars = make(map[string]value, len(c.vars)+1)
+	for key, value := range c.vars {
+		next.vars[key] = value
+	}
+	next.vars[name] = v
+	return &next
+}
+
+type expr interface {
+	eval(c *evalContext) (value, error)
+}
+
+type literal struct{ value value }
+
+func (l literal) eval(*evalContext) (value, error) {
+	return l.value, nil
+}
+
+type variableRef struct{ name string }
+
+func (v variableRef) eval(c *evalContext) (value, error) {
+	value, ok := c.vars[v.name]
+	if !ok {
+		return nil, fmt.Errorf("variable $%s is not defined", v.name)
+	}
+	return value, nil
+}
+
+type negate struct{ operand expr }
+
+func (n negate) eval(c *evalContext) (value, error) {
+	v, err := n.operand.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	return -toNumber(v), nil
+}
+
+type binary struct {
+	op          string
+	left, right expr
+}
+
+func (b binary) eval(c *evalContext) (value, error) {
+	left, err := b.left.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	switch b.op {
+	case "and":
+		if !toBool(left) {
+			return false, nil
+		}
+	case "or":
+		if toBool(left) {
+			return true, nil
+		}
+	}
+	right, err := b.right.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	switch b.op {
+	case "and", "or":
+		return toBool(right), nil
+	case "+":
+		return toNumber(left) + toNumber(right), nil
+	case "-":
+		return toNumber(left) - toNumber(right), nil
+	case "*":
+		return toNumber(left) * toNumber(right), nil
+	case "div":
+		return toNumber(left) / toNumber(right), nil
+	case "mod":
+		return math.Mod(toNumber(left), toNumber(right)), nil
+	case "|":
+		leftNodes, ok1 := left.([]node)
+		rightNodes, ok2 := right.([]node)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("operands of '|' must be node-sets")
+		}
+		return documentOrder(append(slices.Clone(leftNodes), rightNodes...)), nil
+	}
+	return compare(b.op, left, right), nil
+}
+
+// compare applies a comparison operator the way XPath 1.0 does: a node-set
+// compares true when any of its nodes does
+func compare(op string, left, right value) bool {
+	if nodes, ok := left.([]node); ok {
+		for _, n := range nodes {
+			if compare(op, nodeOperand(n, right), right) {
+				return true
+			}
+		}
+		return false
+	}
+	if nodes, ok := right.([]node); ok {
+		for _, n := range nodes {
+			if compare(op, left, nodeOperand(n, left)) {
+				return true
+			}
+		}
+		return false
+	}
+	switch op {
+	case "=", "!=":
+		var equal bool
+		_, leftBool := left.(bool)
+		_, rightBool := right.(bool)
+		_, leftNumber := left.(float64)
+		_, rightNumber := right.(float64)
+		switch {
+		case leftBool || rightBool:
+			equal = toBool(left) == toBool(right)
+		case leftNumber || rightNumber:
+			equal = toNumber(left) == toNumber(right)
+		default:
+			equal = toString(left) == toString(right)
+		}
+		return equal == (op == "=")
+	case "<":
+		return toNumber(left) < toNumber(right)
+	case "<=":
+		return toNumber(left) <= toNumber(right)
+	case ">":
+		return toNumber(left) > toNumber(right)
+	case ">=":
+		return toNumber(left) >= toNumber(right)
+	}
+	return false
+}
+
+// nodeOperand converts a node compared with other: to a number or boolean
+// when other is one, otherwise to its string value
+func nodeOperand(n node, other value) value {
+	switch other.(type) {
+	case float64:
+		return toNumber(n.stringValue())
+	case bool:
+		return true
+	}
+	return n.stringValue()
+}
+
+type axis int
+
+const (
+	axisChild axis = iota
+	axisDescendant
+	axisDescendantOrSelf
+	axisSelf
+	axisParent
+	axisAncestor
+	axisAncestorOrSelf
+	axisAttribute
+	axisFollowingSibling
+	axisPrecedingSibling
+)
+
+var axes = map[string]axis{
+	"child":              axisChild,
+	"descendant":         axisDescendant,
+	"descendant-or-self": axisDescendantOrSelf,
+	"self":               axisSelf,
+	"parent":             axisParent,
+	"ancestor":           axisAncestor,
+	"ancestor-or-self":   axisAncestorOrSelf,
+	"attribute":          axisAttribute,
+	"following-sibling":  axisFollowingSibling,
+	"preceding-sibling":  axisPrecedingSibling,
+}
+
+// nodes returns the nodes on the axis from n, nearest first for the reverse
+// axes
+func (a axis) nodes(n node) []node {
+	switch a {
+	case axisChild:
+		return n.children()
+	case axisDescendant, axisDescendantOrSelf:
+		var nodes []node
+		if a == axisDescendantOrSelf {
+			nodes = append(nodes, n)
+		}
+		var walk func(node)
+		walk = func(n node) {
+			for _, child := range n.children() {
+				nodes = append(nodes, child)
+				walk(child)
+			}
+		}
+		walk(n)
+		return nodes
+	case axisSelf:
+		return []node{n}
+	case axisParent:
+		if parent, ok := n.parent(); ok {
+			return []node{parent}
+		}
+		return nil
+	case axisAncestor, axisAncestorOrSelf:
+		var nodes []node
+		if a == axisAncestorOrSelf {
+			nodes = append(nodes, n)
+		}
+		for parent, ok := n.parent(); ok; parent, ok = parent.parent() {
+			nodes = append(nodes, parent)
+		}
+		return nodes
+	case axisAttribute:
+		return n.attributes()
+	case axisFollowingSibling, axisPrecedingSibling:
+		parent, ok := n.parent()
+		if !ok || n.attr != nil {
+			return nil
+		}
+		siblings := parent.children()
+		i := slices.Index(siblings, n)
+		if a == axisFollowingSibling {
+			return siblings[i+1:]
+		}
+		preceding := slices.Clone(siblings[:i])
+		slices.Reverse(preceding)
+		return preceding
+	}
+	return nil
+}
+
+func (a axis) reverse() bool {
+	return a == axisParent || a == axisAncestor || a == axisAncestorOrSelf || a == axisPrecedingSibling
+}
+
+// nodeTest selects nodes on an axis by kind, or by name. Names without a
+// prefix match the local name of elements and attributes in any namespace;
+// names with one match the prefix the payload uses.
+type nodeTest struct {
+	kind   string // "name", "node" or "text"
+	prefix string
+	local  string // "*" matches any name
+}
+
+func (t nodeTest) matches(n node, a axis) bool {
+	switch t.kind {
+	case "node":
+		return true
+	case "text":
+		return n.text != nil
+	}
+	var prefix, local string
+	switch {
+	case a == axisAttribute && n.attr != nil:
+		prefix, local = n.attr.Space, n.attr.Key
+	case a != axisAttribute && n.isElement():
+		prefix, local = n.element.Space, n.element.Tag
+	default:
+		return false
+	}
+	return (t.local == "*" || t.local == local) && (t.prefix == "" || t.prefix == prefix)
+}
+
+type step struct {
+	axis       axis
+	test       nodeTest
+	predicates []expr
+}
+
+func (s step) eval(c *evalContext, from node) ([]node, error) {
+	var nodes []node
+	for _, n := range s.axis.nodes(from) {
+		if s.test.matches(n, s.axis) {
+			nodes = append(nodes, n)
+		}
+	}
+	nodes, err := filter(c, nodes, s.predicates)
+	if err != nil {
+		return nil, err
+	}
+	if s.axis.reverse() {
+		slices.Reverse(nodes)
+	}
+	return nodes, nil
+}
+
+// filter keeps the nodes each predicate holds for in turn. A number
+// predicate holds for the node at that position.
+func filter(c *evalContext, nodes []node, predicates []expr) ([]node, error) {
+	for _, predicate := range predicates {
+		var kept []node
+		for i, n := range nodes {
+			inner := *c
+			inner.node, inner.position, inner.size = n, i+1, len(nodes)
+			v, err := predicate.eval(&inner)
+			if err != nil {
+				return nil, err
+			}
+			if number, ok := v.(float64); ok {
+				if number == float64(i+1) {
+					kept = append(kept, n)
+				}
+			} else if toBool(v) {
+				kept = append(kept, n)
+			}
+		}
+		nodes = kept
+	}
+	return nodes, nil
+}
+
+// pathExpr is a location path, absolute or relative to the context node, or
+// the steps following a filter expression such as $items[1]/name
+type pathExpr struct {
+	start    expr
+	absolute bool
+	steps    []step
+}
+
+func (p *pathExpr) eval(c *evalContext) (value, error) {
+	var nodes []node
+	switch {
+	case p.start != nil:
+		v, err := p.start.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.steps) == 0 {
+			return v, nil
+		}
+		var ok bool
+		if nodes, ok = v.([]node); !ok {
+			return nil, fmt.Errorf("a location step must follow a node-set")
+		}
+	case p.absolute:
+		root := c.node
+		for parent, ok := root.parent(); ok; parent, ok = root.parent() {
+			root = parent
+		}
+		nodes = []node{root}
+	default:
+		nodes = []node{c.node}
+	}
+	for _, s := range p.steps {
+		var next []node
+		for _, n := range nodes {
+			selected, err := s.eval(c, n)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, selected...)
+		}
+		if len(nodes) > 1 {
+			next = documentOrder(next)
+		}
+		nodes = next
+	}
+	if nodes == nil {
+		nodes = []node{}
+	}
+	return nodes, nil
+}
+
+// filterExpr applies predicates to the node-set of a primary expression
+type filterExpr struct {
+	primary    expr
+	predicates []expr
+}
+
+func (f filterExpr) eval(c *evalContext) (value, error) {
+	v, err := f.primary.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := v.([]node)
+	if !ok {
+		return nil, fmt.Errorf("predicates apply to node-sets only")
+	}
+	return filter(c, nodes, f.predicates)
+}
+
+// function implements an XPath function over its evaluated arguments
+type function struct {
+	minArgs, maxArgs int // maxArgs -1 is unbounded
+	call             func(c *evalContext, args []value) (value, error)
+}
+
+// nodeArg returns the first node of an optional node-set argument, or the
+// context node without one
+func nodeArg(c *evalContext, args []value) (node, bool, error) {
+	if len(args) == 0 {
+		return c.node, true, nil
+	}
+	nodes, ok := args[0].([]node)
+	if !ok {
+		return node{}, false, fmt.Errorf("argument must be a node-set")
+	}
+	if len(nodes) == 0 {
+		return node{}, false, nil
+	}
+	return nodes[0], true, nil
+}
+
+// stringArg returns an optional string argument, or the string value of the
+// context node without one
+func stringArg(c *evalContext, args []value) string {
+	if len(args) == 0 {
+		return c.node.stringValue()
+	}
+	return toString(args[0])
+}
+
+var functions = map[string]function{
+	"last": {0, 0, func(c *evalContext, _ []value) (value, error) {
+		return float64(c.size), nil
+	}},
+	"position": {0, 0, func(c *evalContext, _ []value) (value, error) {
+		return float64(c.position), nil
+	}},
+	"current": {0, 0, func(c *evalContext, _ []value) (value, error) {
+		return []node{c.current}, nil
+	}},
+	"count": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		nodes, ok := args[0].([]node)
+		if !ok {
+			return nil, fmt.Errorf("count: argument must be a node-set")
+		}
+		return float64(len(nodes)), nil
+	}},
+	"sum": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		nodes, ok := args[0].([]node)
+		if !ok {
+			return nil, fmt.Errorf("sum: argument must be a node-set")
+		}
+		var sum float64
+		for _, n := range nodes {
+			sum += toNumber(n.stringValue())
+		}
+		return sum, nil
+	}},
+	"name": {0, 1, func(c *evalContext, args []value) (value, error) {
+		n, ok, err := nodeArg(c, args)
+		if !ok {
+			return "", err
+		}
+		return n.name(), nil
+	}},
+	"local-name": {0, 1, func(c *evalContext, args []value) (value, error) {
+		n, ok, err := nodeArg(c, args)
+		if !ok {
+			return "", err
+		}
+		return n.localName(), nil
+	}},
+	"string": {0, 1, func(c *evalContext, args []value) (value, error) {
+		return stringArg(c, args), nil
+	}},
+	"concat": {2, -1, func(_ *evalContext, args []value) (value, error) {
+		var b strings.Builder
+		for _, arg := range args {
+			b.WriteString(toString(arg))
+		}
+		return b.String(), nil
+	}},
+	"contains": {2, 2, func(_ *evalContext, args []value) (value, error) {
+		return strings.Contains(toString(args[0]), toString(args[1])), nil
+	}},
+	"starts-with": {2, 2, func(_ *evalContext, args []value) (value, error) {
+		return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+	}},
+	"substring-before": {2, 2, func(_ *evalContext, args []value) (value, error) {
+		before, _, found := strings.Cut(toString(args[0]), toString(args[1]))
+		if !found {
+			return "", nil
+		}
+		return before, nil
+	}},
+	"substring-after": {2, 2, func(_ *evalContext, args []value) (value, error) {
+		_, after, _ := strings.Cut(toString(args[0]), toString(args[1]))
+		return after, nil
+	}},
+	"substring": {2, 3, func(_ *evalContext, args []value) (value, error) {
+		runes := []rune(toString(args[0]))
+		start := math.Floor(toNumber(args[1]) + 0.5)
+		end := math.Inf(1)
+		if len(args) == 3 {
+			end = start + math.Floor(toNumber(args[2])+0.5)
+		}
+		var b strings.Builder
+		for i, r := range runes {
+			if position := float64(i + 1); position >= start && position < end {
+				b.WriteRune(r)
+			}
+		}
+		return b.String(), nil
+	}},
+	"string-length": {0, 1, func(c *evalContext, args []value) (value, error) {
+		return float64(len([]rune(stringArg(c, args)))), nil
+	}},
+	"normalize-space": {0, 1, func(c *evalContext, args []value) (value, error) {
+		return strings.Join(strings.Fields(stringArg(c, args)), " "), nil
+	}},
+	"translate": {3, 3, func(_ *evalContext, args []value) (value, error) {
+		from, to := []rune(toString(args[1])), []rune(toString(args[2]))
+		var b strings.Builder
+		for _, r := range toString(args[0]) {
+			i := slices.Index(from, r)
+			switch {
+			case i < 0:
+				b.WriteRune(r)
+			case i < len(to):
+				b.WriteRune(to[i])
+			}
+		}
+		return b.String(), nil
+	}},
+	"upper-case": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		return strings.ToUpper(toString(args[0])), nil
+	}},
+	"lower-case": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		return strings.ToLower(toString(args[0])), nil
+	}},
+	"not": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		return !toBool(args[0]), nil
+	}},
+	"true": {0, 0, func(*evalContext, []value) (value, error) {
+		return true, nil
+	}},
+	"false": {0, 0, func(*evalContext, []value) (value, error) {
+		return false, nil
+	}},
+	"boolean": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		return toBool(args[0]), nil
+	}},
+	"number": {0, 1, func(c *evalContext, args []value) (value, error) {
+		if len(args) == 0 {
+			return toNumber(c.node.stringValue()), nil
+		}
+		return toNumber(args[0]), nil
+	}},
+	"floor": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		return math.Floor(toNumber(args[0])), nil
+	}},
+	"ceiling": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		return math.Ceil(toNumber(args[0])), nil
+	}},
+	"round": {1, 1, func(_ *evalContext, args []value) (value, error) {
+		return math.Floor(toNumber(args[0]) + 0.5), nil
+	}},
+}
+
+type call struct {
+	name string
+	fn   function
+	args []expr
+}
+
+func (f call) eval(c *evalContext) (value, error) {
+	args := make([]value, len(f.args))
+	for i, arg := range f.args {
+		v, err := arg.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := f.fn.call(c, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", f.name, err)
+	}
+	return v, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenName
+	tokenNumber
+	tokenString
+	tokenVariable
+	tokenSymbol
+	// tokenOperator is a multiplication '*' or an operator name such as div
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits an expression into tokens. Following XPath, '*' and the
+// names and, or, div and mod are operators unless they start the expression
+// or follow '@', '::', '(', '[', ',' or another operator.
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	operatorAllowed := func() bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		last := tokens[len(tokens)-1]
+		switch last.kind {
+		case tokenOperator:
+			return false
+		case tokenSymbol:
+			return last.text == ")" || last.text == "]" || last.text == "." || last.text == ".."
+		}
+		return true
+	}
+	isNameStart := func(r rune) bool { return r == '_' || unicode.IsLetter(r) }
+	isNameChar := func(r rune) bool {
+		return isNameStart(r) || unicode.IsDigit(r) || r == '-' || r == '.'
+	}
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			end := slices.Index(runes[i+1:], r)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			tokens = append(tokens, token{tokenString, string(runes[i+1 : i+1+end])})
+			i += end + 2
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i])})
+		case r == '$':
+			start := i + 1
+			for i++; i < len(runes) && (isNameChar(runes[i]) || runes[i] == ':'); i++ {
+			}
+			if start == i {
+				return nil, fmt.Errorf("missing variable name after '$'")
+			}
+			tokens = append(tokens, token{tokenVariable, string(runes[start:i])})
+		case r == '*':
+			if operatorAllowed() {
+				tokens = append(tokens, token{tokenOperator, "*"})
+			} else {
+				tokens = append(tokens, token{tokenName, "*"})
+			}
+			i++
+		case isNameStart(r):
+			start := i
+			for i < len(runes) && isNameChar(runes[i]) {
+				i++
+			}
+			// a prefixed name, or prefix:*
+			if i+1 < len(runes) && runes[i] == ':' && runes[i+1] != ':' {
+				if runes[i+1] == '*' {
+					i += 2
+				} else if isNameStart(runes[i+1]) {
+					for i++; i < len(runes) && isNameChar(runes[i]); i++ {
+					}
+				}
+			}
+			name := string(runes[start:i])
+			if operatorAllowed() && (name == "and" || name == "or" || name == "div" || name == "mod") {
+				tokens = append(tokens, token{tokenOperator, name})
+			} else {
+				tokens = append(tokens, token{tokenName, name})
+			}
+		default:
+			symbol := string(r)
+			if i+1 < len(runes) {
+				if two := string(runes[i : i+2]); slices.Contains([]string{"//", "..", "::", "!=", "<=", ">="}, two) {
+					symbol = two
+				}
+			}
+			if !strings.Contains("/.()[]@,|+-=<>", string(r)) && len([]rune(symbol)) == 1 {
+				return nil, fmt.Errorf("unexpected character '%c'", r)
+			}
+			tokens = append(tokens, token{tokenSymbol, symbol})
+			i += len([]rune(symbol))
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// compileExpr parses an XPath 1.0 expression. Supported are location paths
+// on the child, descendant, parent, ancestor, sibling, self and attribute
+// axes with predicates, variables, the core string, number and boolean
+// functions, and the usual operators.
+func compileExpr(source string) (expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = fmt.Errorf("unexpected '%s'", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return token{kind: tokenEOF}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isSymbol(symbols ...string) bool {
+	t := p.peek()
+	return t.kind == tokenSymbol && slices.Contains(symbols, t.text)
+}
+
+func (p *parser) isOperator(names ...string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && slices.Contains(names, t.text)
+}
+
+func (p *parser) expect(symbol string) error {
+	if !p.isSymbol(symbol) {
+		if p.peek().kind == tokenEOF {
+			return fmt.Errorf("missing '%s'", symbol)
+		}
+		return fmt.Errorf("expected '%s', got '%s'", symbol, p.peek().text)
+	}
+	p.next()
+	return nil
+}
+
+// parseBinary parses operands joined by left-associative operators
+func (p *parser) parseBinary(operand func() (expr, error), isOperator func() bool) (expr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for isOperator() {
+		op := p.next().text
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	return p.parseBinary(p.parseAnd, func() bool { return p.isOperator("or") })
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	return p.parseBinary(p.parseEquality, func() bool { return p.isOperator("and") })
+}
+
+func (p *parser) parseEquality() (expr, error) {
+	return p.parseBinary(p.parseRelational, func() bool { return p.isSymbol("=", "!=") })
+}
+
+func (p *parser) parseRelational() (expr, error) {
+	return p.parseBinary(p.parseAdditive, func() bool { return p.isSymbol("<", "<=", ">", ">=") })
+}
+
+func (p *parser) parseAdditive() (expr, error) {
+	return p.parseBinary(p.parseMultiplicative, func() bool { return p.isSymbol("+", "-") })
+}
+
+func (p *parser) parseMultiplicative() (expr, error) {
+	return p.parseBinary(p.parseUnary, func() bool { return p.isOperator("*", "div", "mod") })
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isSymbol("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negate{operand}, nil
+	}
+	return p.parseBinary(p.parsePath, func() bool { return p.isSymbol("|") })
+}
+
+// parsePath parses a location path, or a primary expression with optional
+// predicates and location steps
+func (p *parser) parsePath() (expr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenSymbol && (t.text == "/" || t.text == "//"):
+		path := &pathExpr{absolute: true}
+		p.next()
+		if t.text == "//" {
+			path.steps = append(path.steps, step{axis: axisDescendantOrSelf, test: nodeTest{kind: "node"}})
+		} else if !p.startsStep() {
+			return path, nil
+		}
+		return path, p.parseSteps(path)
+	case p.startsStep():
+		path := &pathExpr{}
+		return path, p.parseSteps(path)
+	}
+
+	primary, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isSymbol("[") {
+		predicates, err := p.parsePredicates()
+		if err != nil {
+			return nil, err
+		}
+		primary = filterExpr{primary: primary, predicates: predicates}
+	}
+	if !p.isSymbol("/", "//") {
+		return primary, nil
+	}
+	path := &pathExpr{start: primary}
+	if p.next().text == "//" {
+		path.steps = append(path.steps, step{axis: axisDescendantOrSelf, test: nodeTest{kind: "node"}})
+	}
+	return path, p.parseSteps(path)
+}
+
+// startsStep reports whether the next token starts a location step rather
+// than a primary expression
+func (p *parser) startsStep() bool {
+	t := p.peek()
+	switch t.kind {
+	case tokenSymbol:
+		return t.text == "." || t.text == ".." || t.text == "@"
+	case tokenName:
+		if next := p.peekAt(1); next.kind == tokenSymbol && next.text == "(" {
+			return t.text == "node" || t.text == "text"
+		}
+		return true
+	}
+	return false
+}
+
+// parseSteps parses location steps separated by '/' or '//'
+func (p *parser) parseSteps(path *pathExpr) error {
+	for {
+		s, err := p.parseStep()
+		if err != nil {
+			return err
+		}
+		path.steps = append(path.steps, s)
+		switch {
+		case p.isSymbol("/"):
+			p.next()
+		case p.isSymbol("//"):
+			p.next()
+			path.steps = append(path.steps, step{axis: axisDescendantOrSelf, test: nodeTest{kind: "node"}})
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseStep() (step, error) {
+	switch {
+	case p.isSymbol("."):
+		p.next()
+		return step{axis: axisSelf, test: nodeTest{kind: "node"}}, nil
+	case p.isSymbol(".."):
+		p.next()
+		return step{axis: axisParent, test: nodeTest{kind: "node"}}, nil
+	}
+	s := step{axis: axisChild}
+	if p.isSymbol("@") {
+		p.next()
+		s.axis = axisAttribute
+	} else if next := p.peekAt(1); p.peek().kind == tokenName && next.kind == tokenSymbol && next.text == "::" {
+		name := p.next().text
+		a, ok := axes[name]
+		if !ok {
+			return step{}, fmt.Errorf("unsupported axis '%s'", name)
+		}
+		p.next()
+		s.axis = a
+	}
+	t := p.next()
+	if t.kind != tokenName {
+		if t.kind == tokenEOF {
+			return step{}, fmt.Errorf("missing location step")
+		}
+		return step{}, fmt.Errorf("unexpected '%s' in location step", t.text)
+	}
+	if p.isSymbol("(") {
+		if t.text != "node" && t.text != "text" {
+			return step{}, fmt.Errorf("unsupported node test '%s()'", t.text)
+		}
+		p.next()
+		if err := p.expect(")"); err != nil {
+			return step{}, err
+		}
+		s.test = nodeTest{kind: t.text}
+	} else {
+		s.test = nodeTest{kind: "name", local: t.text}
+		if prefix, local, ok := strings.Cut(t.text, ":"); ok {
+			s.test.prefix, s.test.local = prefix, local
+		}
+	}
+	predicates, err := p.parsePredicates()
+	if err != nil {
+		return step{}, err
+	}
+	s.predicates = predicates
+	return s, nil
+}
+
+func (p *parser) parsePredicates() ([]expr, error) {
+	var predicates []expr
+	for p.isSymbol("[") {
+		p.next()
+		predicate, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literal{t.text}, nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", t.text)
+		}
+		return literal{number}, nil
+	case tokenVariable:
+		return variableRef{t.text}, nil
+	case tokenName:
+		fn, ok := functions[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unsupported function '%s()'", t.text)
+		}
+		p.next() // (
+		f := call{name: t.text, fn: fn}
+		for !p.isSymbol(")") {
+			if len(f.args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			f.args = append(f.args, arg)
+		}
+		p.next()
+		if len(f.args) < fn.minArgs || (fn.maxArgs >= 0 && len(f.args) > fn.maxArgs) {
+			return nil, fmt.Errorf("wrong number of arguments to %s()", t.text)
+		}
+		return f, nil
+	case tokenSymbol:
+		if t.text == "(" {
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected '%s'", t.text)
+}
@@ -0,0 +1,505 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package xslt transforms XML documents with XSLT 1.0 stylesheets. It
+// implements the part of the language mediation flows commonly use: template
+// rules with modes and priorities, named templates and parameters,
+// variables, value-of, for-each with sort, if and choose, copy and copy-of,
+// element, attribute, text and comment constructors, and literal result
+// elements with attribute value templates, written as xml or text.
+// Stylesheets using other instructions, imports or extension elements are
+// rejected when they are compiled.
+//
+// Expressions follow XPath 1.0, with one difference: a name without a prefix
+// matches elements and attributes of that local name in any namespace, so
+// payloads with a default namespace can be matched without declaring it.
+package xslt
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// Namespace is the namespace of XSLT instructions
+const Namespace = "http://www.w3.org/1999/XSL/Transform"
+
+// maxDepth bounds nested template calls, so a stylesheet recursing without
+// end fails instead of exhausting the stack
+const maxDepth = 1000
+
+// ErrInvalidInput is returned when the document to transform is not XML
+var ErrInvalidInput = errors.New("input is not an XML document")
+
+// Stylesheet is a compiled stylesheet. It is safe for concurrent use.
+type Stylesheet struct {
+	rules   []rule
+	named   map[string]*template
+	globals []*variable
+	output  output
+	// strip lists the elements whose whitespace-only text is removed from
+	// the input
+	strip []nodeTest
+}
+
+type output struct {
+	method          string // xml or text
+	indent          bool
+	omitDeclaration bool
+	mediaType       string
+}
+
+// rule applies a template to the nodes matching one alternative of its match
+// pattern
+type rule struct {
+	pattern  *pathExpr
+	priority float64
+	template *template
+}
+
+type template struct {
+	name   string
+	mode   string
+	params []*variable
+	body   []instruction
+}
+
+// Compile parses a stylesheet
+func Compile(source []byte) (*Stylesheet, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(source); err != nil {
+		return nil, fmt.Errorf("invalid stylesheet: %w", err)
+	}
+	root := doc.Root()
+	if root == nil || !isXSL(root) || (root.Tag != "stylesheet" && root.Tag != "transform") {
+		return nil, errors.New("invalid stylesheet: root element must be xsl:stylesheet or xsl:transform")
+	}
+	s := &Stylesheet{
+		named:  make(map[string]*template),
+		output: output{method: "xml"},
+	}
+	var calls []string
+	for _, element := range root.ChildElements() {
+		// Top-level elements of other namespaces are data for extensions
+		if !isXSL(element) {
+			continue
+		}
+		var err error
+		switch element.Tag {
+		case "template":
+			err = s.compileTemplate(element, &calls)
+		case "param", "variable":
+			var v *variable
+			if v, err = compileVariable(element, &calls); err == nil {
+				s.globals = append(s.globals, v)
+			}
+		case "output":
+			err = s.compileOutput(element)
+		case "strip-space":
+			for _, name := range strings.Fields(element.SelectAttrValue("elements", "")) {
+				test := nodeTest{kind: "name", local: name}
+				if prefix, local, ok := strings.Cut(name, ":"); ok {
+					test.prefix, test.local = prefix, local
+				}
+				s.strip = append(s.strip, test)
+			}
+		case "preserve-space":
+			// whitespace is preserved unless stripped
+		default:
+			err = fmt.Errorf("xsl:%s is not supported", element.Tag)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid stylesheet: %w", err)
+		}
+	}
+	for _, name := range calls {
+		if _, ok := s.named[name]; !ok {
+			return nil, fmt.Errorf("invalid stylesheet: xsl:call-template: no template named '%s'", name)
+		}
+	}
+	return s, nil
+}
+
+func isXSL(element *etree.Element) bool {
+	return element.NamespaceURI() == Namespace
+}
+
+func (s *Stylesheet) compileTemplate(element *etree.Element, calls *[]string) error {
+	match := element.SelectAttrValue("match", "")
+	t := &template{
+		name: element.SelectAttrValue("name", ""),
+		mode: element.SelectAttrValue("mode", ""),
+	}
+	if match == "" && t.name == "" {
+		return errors.New("xsl:template: a match or name attribute is required")
+	}
+	// Parameters come first, before the instructions of the body
+	start := 0
+	for i, token := range element.Child {
+		switch token := token.(type) {
+		case *etree.CharData:
+			if token.IsWhitespace() {
+				continue
+			}
+		case *etree.Element:
+			if isXSL(token) && token.Tag == "param" {
+				v, err := compileVariable(token, calls)
+				if err != nil {
+					return err
+				}
+				t.params = append(t.params, v)
+				start = i + 1
+				continue
+			}
+		default:
+			continue
+		}
+		break
+	}
+	body, err := compileBody(element.Child[start:], calls)
+	if err != nil {
+		return err
+	}
+	t.body = body
+
+	if t.name != "" {
+		if _, ok := s.named[t.name]; ok {
+			return fmt.Errorf("xsl:template: duplicate template named '%s'", t.name)
+		}
+		s.named[t.name] = t
+	}
+	if match == "" {
+		return nil
+	}
+	pattern, err := compileExpr(match)
+	if err != nil {
+		return fmt.Errorf("xsl:template: %w", err)
+	}
+	for _, alternative := range unionOperands(pattern) {
+		path, ok := alternative.(*pathExpr)
+		if !ok || path.start != nil {
+			return fmt.Errorf("xsl:template: match '%s' is not a pattern", match)
+		}
+		r := rule{pattern: path, priority: defaultPriority(path), template: t}
+		if priority := element.SelectAttr("priority"); priority != nil {
+			if r.priority, err = strconv.ParseFloat(priority.Value, 64); err != nil {
+				return fmt.Errorf("xsl:template: priority must be a number, got: %s", priority.Value)
+			}
+		}
+		s.rules = append(s.rules, r)
+	}
+	return nil
+}
+
+func unionOperands(e expr) []expr {
+	if union, ok := e.(binary); ok && union.op == "|" {
+		return append(unionOperands(union.left), unionOperands(union.right)...)
+	}
+	return []expr{e}
+}
+
+// defaultPriority ranks patterns by how specific they are: a single named
+// step over a wildcard, and anything longer or with predicates over both
+func defaultPriority(path *pathExpr) float64 {
+	if path.absolute || len(path.steps) != 1 || len(path.steps[0].predicates) > 0 {
+		return 0.5
+	}
+	test := path.steps[0].test
+	switch {
+	case test.kind == "name" && test.local != "*":
+		return 0
+	case test.kind == "name" && test.prefix != "":
+		return -0.25
+	}
+	return -0.5
+}
+
+func (s *Stylesheet) compileOutput(element *etree.Element) error {
+	if method := element.SelectAttr("method"); method != nil {
+		if method.Value != "xml" && method.Value != "text" {
+			return fmt.Errorf("xsl:output: method must be xml or text, got: %s", method.Value)
+		}
+		s.output.method = method.Value
+	}
+	s.output.indent = element.SelectAttrValue("indent", "no") == "yes"
+	s.output.omitDeclaration = element.SelectAttrValue("omit-xml-declaration", "no") == "yes"
+	s.output.mediaType = element.SelectAttrValue("media-type", "")
+	return nil
+}
+
+// MediaType is the content type of the transform output
+func (s *Stylesheet) MediaType() string {
+	switch {
+	case s.output.mediaType != "":
+		return s.output.mediaType
+	case s.output.method == "text":
+		return "text/plain"
+	}
+	return "application/xml"
+}
+
+// Transform applies the stylesheet to an XML document. params set the
+// stylesheet's top-level parameters of the same names, as strings.
+func (s *Stylesheet) Transform(input []byte, params map[string]string) ([]byte, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(input); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if doc.Root() == nil {
+		return nil, fmt.Errorf("%w: no root element", ErrInvalidInput)
+	}
+	if len(s.strip) > 0 {
+		s.stripSpace(doc.Root())
+	}
+
+	t := &transformer{stylesheet: s}
+	root := node{element: &doc.Element}
+	c := &evalContext{node: root, position: 1, size: 1, current: root}
+	for _, global := range s.globals {
+		if param, ok := params[global.name]; ok && global.param {
+			c = c.with(global.name, param)
+			continue
+		}
+		v, err := global.value(t, c)
+		if err != nil {
+			return nil, err
+		}
+		c = c.with(global.name, v)
+	}
+	t.globals = c.vars
+
+	result := etree.NewElement("")
+	if err := t.apply(root, "", 1, 1, nil, result); err != nil {
+		return nil, err
+	}
+	if s.output.method == "text" {
+		return []byte(node{element: result}.stringValue()), nil
+	}
+	out := etree.NewDocument()
+	if !s.output.omitDeclaration {
+		out.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+	}
+	for len(result.Child) > 0 {
+		out.AddChild(result.Child[0])
+	}
+	if s.output.indent {
+		out.Indent(2)
+	}
+	return out.WriteToBytes()
+}
+
+func (s *Stylesheet) stripSpace(element *etree.Element) {
+	strip := slices.ContainsFunc(s.strip, func(test nodeTest) bool {
+		return test.matches(node{element: element}, axisChild)
+	})
+	for _, token := range slices.Clone(element.Child) {
+		switch token := token.(type) {
+		case *etree.CharData:
+			if strip && token.IsWhitespace() {
+				element.RemoveChild(token)
+			}
+		case *etree.Element:
+			s.stripSpace(token)
+		}
+	}
+}
+
+// transformer holds the state of one transform
+type transformer struct {
+	stylesheet *Stylesheet
+	globals    map[string]value
+	depth      int
+}
+
+// find returns the template of the highest priority rule matching n in mode,
+// the last one declared among equals
+func (t *transformer) find(n node, mode string) (*template, error) {
+	var best *rule
+	for i := range t.stylesheet.rules {
+		r := &t.stylesheet.rules[i]
+		if r.template.mode != mode || (best != nil && r.priority < best.priority) {
+			continue
+		}
+		matched, err := t.matches(r.pattern, n)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return best.template, nil
+}
+
+// matches reports whether a pattern matches n: whether n is among the nodes
+// the pattern selects from one of its ancestors
+func (t *transformer) matches(pattern *pathExpr, n node) (bool, error) {
+	if len(pattern.steps) > 0 {
+		last := pattern.steps[len(pattern.steps)-1]
+		if last.axis != axisDescendantOrSelf && !last.test.matches(n, last.axis) {
+			return false, nil
+		}
+	}
+	selects := func(from node) (bool, error) {
+		c := &evalContext{node: from, position: 1, size: 1, current: from, vars: t.globals}
+		v, err := pattern.eval(c)
+		if err != nil {
+			return false, err
+		}
+		nodes, _ := v.([]node)
+		return slices.Contains(nodes, n), nil
+	}
+	if pattern.absolute {
+		return selects(n)
+	}
+	for ancestor, ok := n.parent(); ok; ancestor, ok = ancestor.parent() {
+		if selected, err := selects(ancestor); selected || err != nil {
+			return selected, err
+		}
+	}
+	return false, nil
+}
+
+// apply runs the template matching n in mode, or the built-in rule: elements
+// apply templates to their children, and text and attributes are copied
+func (t *transformer) apply(n node, mode string, position, size int, params map[string]value, out *etree.Element) error {
+	template, err := t.find(n, mode)
+	if err != nil {
+		return err
+	}
+	if template != nil {
+		c := &evalContext{node: n, position: position, size: size, current: n}
+		return t.invoke(template, c, params, out)
+	}
+	if n.attr != nil || n.text != nil {
+		out.CreateText(n.stringValue())
+		return nil
+	}
+	children := n.children()
+	for i, child := range children {
+		if err := t.apply(child, mode, i+1, len(children), nil, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invoke runs a template at the node of c, with its parameters set from
+// params or their defaults
+func (t *transformer) invoke(template *template, c *evalContext, params map[string]value, out *etree.Element) error {
+	if t.depth++; t.depth > maxDepth {
+		return fmt.Errorf("templates nest deeper than %d levels", maxDepth)
+	}
+	defer func() { t.depth-- }()
+	c.vars = t.globals
+	for _, param := range template.params {
+		v, ok := params[param.name]
+		if !ok {
+			var err error
+			if v, err = param.value(t, c); err != nil {
+				return err
+			}
+		}
+		c = c.with(param.name, v)
+	}
+	return t.run(template.body, c, out)
+}
+
+// run executes instructions in order. A variable is visible to the
+// instructions following it.
+func (t *transformer) run(body []instruction, c *evalContext, out *etree.Element) error {
+	for _, in := range body {
+		if v, ok := in.(*variable); ok {
+			value, err := v.value(t, c)
+			if err != nil {
+				return err
+			}
+			c = c.with(v.name, value)
+			continue
+		}
+		if err := in.execute(t, c, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evalNodes evaluates an expression that must select nodes
+func evalNodes(instruction string, e expr, c *evalContext) ([]node, error) {
+	v, err := e.eval(c)
+	if err != nil {
+		return nil, fmt.Errorf("xsl:%s: %w", instruction, err)
+	}
+	nodes, ok := v.([]node)
+	if !ok {
+		return nil, fmt.Errorf("xsl:%s: select must be a node-set", instruction)
+	}
+	return nodes, nil
+}
+
+// resolvePrefix returns the namespace a prefix is bound to at an element,
+// the default namespace for the empty prefix
+func resolvePrefix(element *etree.Element, prefix string) (string, bool) {
+	for ; element != nil; element = element.Parent() {
+		for _, attr := range element.Attr {
+			if (prefix == "" && attr.Space == "" && attr.Key == "xmlns") || (prefix != "" && attr.Space == "xmlns" && attr.Key == prefix) {
+				return attr.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// declareNamespace binds prefix to namespace on an output element unless it
+// is bound so already
+func declareNamespace(element *etree.Element, prefix, namespace string) {
+	if prefix == "xml" || prefix == "xmlns" {
+		return
+	}
+	if bound, _ := resolvePrefix(element, prefix); bound == namespace {
+		return
+	}
+	if prefix == "" {
+		element.CreateAttr("xmlns", namespace)
+	} else {
+		element.CreateAttr("xmlns:"+prefix, namespace)
+	}
+}
+
+// copyNamespaces declares the namespaces of a copied input element and its
+// descendants that its new ancestors do not
+func copyNamespaces(original, copied *etree.Element) {
+	declareNamespace(copied, copied.Space, original.NamespaceURI())
+	for i, attr := range original.Attr {
+		if attr.Space != "" && attr.Space != "xmlns" {
+			declareNamespace(copied, copied.Attr[i].Space, attr.NamespaceURI())
+		}
+	}
+	copiedChildren := copied.ChildElements()
+	for i, child := range original.ChildElements() {
+		copyNamespaces(child, copiedChildren[i])
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package xslt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const orders = `<orders xmlns="http://example.com/orders">
+	<order id="1" status="shipped"><customer>Jane</customer><total>25.50</total></order>
+	<order id="2" status="pending"><customer>John</customer><total>7</total></order>
+	<order id="3" status="shipped"><customer>Ann</customer><total>120</total></order>
+</orders>`
+
+func stylesheet(templates string) string {
+	return `<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+	<xsl:output omit-xml-declaration="yes"/>
+	<xsl:strip-space elements="*"/>
+	` + templates + `
+</xsl:stylesheet>`
+}
+
+func TestTransform(t *testing.T) {
+	testCases := []struct {
+		name       string
+		stylesheet string
+		params     map[string]string
+		expected   string
+	}{
+		{
+			name: "Literal result with value-of and for-each",
+			stylesheet: stylesheet(`<xsl:template match="/">
+				<summary count="{count(orders/order)}">
+					<xsl:for-each select="orders/order[@status='shipped']">
+						<shipped id="{@id}"><xsl:value-of select="customer"/></shipped>
+					</xsl:for-each>
+				</summary>
+			</xsl:template>`),
+			expected: `<summary count="3"><shipped id="1">Jane</shipped><shipped id="3">Ann</shipped></summary>`,
+		},
+		{
+			name: "Template rules with apply-templates and priorities",
+			stylesheet: stylesheet(`<xsl:template match="orders"><list><xsl:apply-templates/></list></xsl:template>
+				<xsl:template match="order"><item><xsl:value-of select="@id"/></item></xsl:template>
+				<xsl:template match="order[total &gt; 100]"><big><xsl:value-of select="@id"/></big></xsl:template>`),
+			expected: `<list><item>1</item><item>2</item><big>3</big></list>`,
+		},
+		{
+			name: "Identity transform with an override",
+			stylesheet: stylesheet(`<xsl:template match="@*|node()"><xsl:copy><xsl:apply-templates select="@*|node()"/></xsl:copy></xsl:template>
+				<xsl:template match="order[@status='pending']"/>
+				<xsl:template match="@status"/>`),
+			expected: `<orders xmlns="http://example.com/orders"><order id="1"><customer>Jane</customer><total>25.50</total></order><order id="3"><customer>Ann</customer><total>120</total></order></orders>`,
+		},
+		{
+			name: "Sort, choose and arithmetic",
+			stylesheet: stylesheet(`<xsl:template match="/">
+				<totals sum="{sum(//total)}">
+					<xsl:apply-templates select="//order"><xsl:sort select="total" data-type="number" order="descending"/></xsl:apply-templates>
+				</totals>
+			</xsl:template>
+			<xsl:template match="order">
+				<xsl:choose>
+					<xsl:when test="total &lt; 10"><small><xsl:value-of select="total * 2"/></small></xsl:when>
+					<xsl:otherwise><large position="{position()}"><xsl:value-of select="round(total)"/></large></xsl:otherwise>
+				</xsl:choose>
+			</xsl:template>`),
+			expected: `<totals sum="152.5"><large position="1">120</large><large position="2">26</large><small>14</small></totals>`,
+		},
+		{
+			name: "Named templates, parameters and variables",
+			stylesheet: stylesheet(`<xsl:param name="greeting" select="'Hello'"/>
+			<xsl:template match="/">
+				<xsl:variable name="first" select="//order[1]"/>
+				<xsl:call-template name="greet"><xsl:with-param name="who" select="$first/customer"/></xsl:call-template>
+			</xsl:template>
+			<xsl:template name="greet">
+				<xsl:param name="who"/>
+				<xsl:param name="punctuation">!</xsl:param>
+				<message><xsl:value-of select="concat($greeting, ', ', upper-case($who), $punctuation)"/></message>
+			</xsl:template>`),
+			params:   map[string]string{"greeting": "Hi"},
+			expected: `<message>Hi, JANE!</message>`,
+		},
+		{
+			name: "Computed elements, attributes and copy-of",
+			stylesheet: stylesheet(`<xsl:template match="/orders">
+				<xsl:element name="{local-name()}-copy">
+					<xsl:attribute name="first"><xsl:value-of select="order[1]/@id"/></xsl:attribute>
+					<xsl:copy-of select="order[last()]/customer"/>
+				</xsl:element>
+			</xsl:template>`),
+			expected: `<orders-copy first="1"><customer xmlns="http://example.com/orders">Ann</customer></orders-copy>`,
+		},
+		{
+			name: "Text output",
+			stylesheet: `<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+				<xsl:output method="text"/>
+				<xsl:template match="/"><xsl:for-each select="//order"><xsl:value-of select="customer"/><xsl:if test="position() != last()"><xsl:text>,</xsl:text></xsl:if></xsl:for-each></xsl:template>
+			</xsl:stylesheet>`,
+			expected: `Jane,John,Ann`,
+		},
+		{
+			name: "Built-in rules copy text",
+			stylesheet: stylesheet(`<xsl:template match="total"/>
+				<xsl:template match="customer"><xsl:value-of select="."/>;</xsl:template>`),
+			expected: `Jane;John;Ann;`,
+		},
+		{
+			name: "Prefixed literal elements declare their namespace",
+			stylesheet: `<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform" xmlns:s="http://example.com/summary">
+				<xsl:output omit-xml-declaration="yes"/>
+				<xsl:template match="/"><s:summary><s:first><xsl:value-of select="substring-before(//customer, 'e')"/></s:first></s:summary></xsl:template>
+			</xsl:stylesheet>`,
+			expected: `<s:summary xmlns:s="http://example.com/summary"><s:first>Jan</s:first></s:summary>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Compile([]byte(tc.stylesheet))
+			require.NoError(t, err)
+			output, err := s.Transform([]byte(orders), tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(output))
+		})
+	}
+}
+
+func TestTransform_Output(t *testing.T) {
+	s, err := Compile([]byte(`<xsl:transform version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+		<xsl:output indent="yes"/>
+		<xsl:template match="/"><a><b/></a></xsl:template>
+	</xsl:transform>`))
+	require.NoError(t, err)
+	assert.Equal(t, "application/xml", s.MediaType())
+	output, err := s.Transform([]byte(`<in/>`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<a>\n  <b/>\n</a>\n", string(output))
+
+	_, err = s.Transform([]byte(`{"not": "xml"}`), nil)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestTransform_RecursionLimit(t *testing.T) {
+	s, err := Compile([]byte(stylesheet(`<xsl:template match="/"><xsl:call-template name="loop"/></xsl:template>
+		<xsl:template name="loop"><xsl:call-template name="loop"/></xsl:template>`)))
+	require.NoError(t, err)
+	_, err = s.Transform([]byte(`<in/>`), nil)
+	assert.ErrorContains(t, err, "templates nest deeper than 1000 levels")
+}
+
+func TestCompile_Errors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		stylesheet string
+		expected   string
+	}{
+		{"Not a stylesheet", `<root/>`, "root element must be xsl:stylesheet or xsl:transform"},
+		{"Unsupported instruction", stylesheet(`<xsl:template match="/"><xsl:number/></xsl:template>`), "xsl:number is not supported"},
+		{"Unsupported top-level element", stylesheet(`<xsl:import href="other.xsl"/>`), "xsl:import is not supported"},
+		{"Invalid expression", stylesheet(`<xsl:template match="/"><xsl:value-of select="count(("/></xsl:template>`), "xsl:value-of: invalid expression 'count(('"},
+		{"Unknown function", stylesheet(`<xsl:template match="/"><xsl:value-of select="format-number(1, '#')"/></xsl:template>`), "unsupported function 'format-number()'"},
+		{"Missing named template", stylesheet(`<xsl:template match="/"><xsl:call-template name="missing"/></xsl:template>`), "no template named 'missing'"},
+		{"Invalid pattern", stylesheet(`<xsl:template match="count(a)"/>`), "match 'count(a)' is not a pattern"},
+		{"Missing select", stylesheet(`<xsl:template match="/"><xsl:for-each/></xsl:template>`), "xsl:for-each: missing required attribute 'select'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Compile([]byte(tc.stylesheet))
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestCompileExpr(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   string
+	}{
+		{"count(//order) * 2 - 1", "5"},
+		{"//order[2]/customer", "John"},
+		{"//order[@id = 3]/../order[1]/@status", "shipped"},
+		{"//customer[. = 'Ann']/parent::*/@id", "3"},
+		{"//order[1]/following-sibling::order[1]/@id", "2"},
+		{"//order[last()]/preceding-sibling::*[1]/customer", "John"},
+		{"sum(//total) div count(//total)", "50.833333333333336"},
+		{"10 mod 4", "2"},
+		{"normalize-space('  a   b ')", "a b"},
+		{"translate('abc', 'ab', 'B')", "Bc"},
+		{"substring('12345', 2, 3)", "234"},
+		{"string-length(//order[1]/customer)", "4"},
+		{"not(//order[@status = 'cancelled']) and //order/@status = 'pending'", "true"},
+		{"//total > 100", "true"},
+		{"name(/*)", "orders"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			s, err := Compile([]byte(stylesheet(`<xsl:template match="/"><xsl:value-of select="` + tc.expression + `"/></xsl:template>`)))
+			require.NoError(t, err)
+			output, err := s.Transform([]byte(orders), nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(output))
+		})
+	}
+}
+
+func TestRegistry_Load(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(directory, "transforms"), 0o755))
+	path := filepath.Join(directory, "transforms", "names.xsl")
+	require.NoError(t, os.WriteFile(path, []byte(stylesheet(`<xsl:template match="/"><first><xsl:value-of select="//customer"/></first></xsl:template>`)), 0o644))
+	registry := NewRegistry(directory)
+
+	first, err := registry.Load("transforms/names.xsl")
+	require.NoError(t, err)
+	again, err := registry.Load("transforms/names.xsl")
+	require.NoError(t, err)
+	assert.Same(t, first, again, "an unchanged stylesheet is compiled once")
+
+	require.NoError(t, os.WriteFile(path, []byte(stylesheet(`<xsl:template match="/"><last><xsl:value-of select="//order[last()]/customer"/></last></xsl:template>`)), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	changed, err := registry.Load("transforms/names.xsl")
+	require.NoError(t, err)
+	output, err := changed.Transform([]byte(orders), nil)
+	require.NoError(t, err)
+	assert.Equal(t, `<last>Ann</last>`, string(output))
+
+	_, err = registry.Load("../names.xsl")
+	assert.ErrorContains(t, err, "invalid stylesheet key '../names.xsl'")
+	_, err = registry.Load("missing.xsl")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
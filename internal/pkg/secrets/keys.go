@@ -0,0 +1,119 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package secrets
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// PrivateKey parses the PEM encoded private key registered under alias. PKCS#8,
+// PKCS#1 and SEC 1 encodings are accepted.
+func (s *Store) PrivateKey(alias string) (crypto.Signer, error) {
+	data, err := s.Get(alias)
+	if err != nil {
+		return nil, err
+	}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		var key any
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("secret '%s': invalid private key: %w", alias, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("secret '%s': unsupported private key type %T", alias, key)
+		}
+		return signer, nil
+	}
+	return nil, fmt.Errorf("secret '%s' does not contain a PEM private key", alias)
+}
+
+// PublicKey parses the PEM encoded public key or certificate registered under
+// alias. A private key is also accepted, in which case its public half is used.
+func (s *Store) PublicKey(alias string) (crypto.PublicKey, error) {
+	certificate, err := s.Certificate(alias)
+	if err == nil {
+		return certificate.PublicKey, nil
+	}
+	data, err := s.Get(alias)
+	if err != nil {
+		return nil, err
+	}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "PUBLIC KEY" {
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("secret '%s': invalid public key: %w", alias, err)
+			}
+			return key, nil
+		}
+	}
+	signer, err := s.PrivateKey(alias)
+	if err != nil {
+		return nil, fmt.Errorf("secret '%s' does not contain a PEM public key or certificate", alias)
+	}
+	return signer.Public(), nil
+}
+
+// Certificate parses the first PEM certificate registered under alias
+func (s *Store) Certificate(alias string) (*x509.Certificate, error) {
+	data, err := s.Get(alias)
+	if err != nil {
+		return nil, err
+	}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+	return nil, fmt.Errorf("secret '%s' does not contain a PEM certificate", alias)
+}
+
+// SymmetricKey returns the shared key registered under alias. Values prefixed
+// with "base64:" are decoded; anything else is used as is.
+func (s *Store) SymmetricKey(alias string) ([]byte, error) {
+	data, err := s.Get(alias)
+	if err != nil {
+		return nil, err
+	}
+	value := strings.TrimSpace(string(data))
+	if encoded, ok := strings.CutPrefix(value, "base64:"); ok {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("secret '%s': invalid base64 key: %w", alias, err)
+		}
+		return key, nil
+	}
+	return []byte(value), nil
+}
@@ -0,0 +1,138 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+// Package secrets resolves key material and other secrets referenced by alias
+// from artifacts, so private keys and passwords never appear in artifact XML.
+// Entries are declared in the [secrets] section of deployment.toml and read
+// from files, environment variables or inline values.
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Config holds the [secrets] section of deployment.toml
+type Config struct {
+	Entries []Entry `koanf:"entry"`
+}
+
+// Entry declares one secret. Exactly one of File, Env and Value must be set.
+type Entry struct {
+	Alias string `koanf:"alias"`
+	// File is a path to the secret, relative to the conf directory unless absolute
+	File  string `koanf:"file"`
+	Env   string `koanf:"env"`
+	Value string `koanf:"value"`
+}
+
+// Validate reports configuration errors in the [secrets] section
+func (c Config) Validate() error {
+	seen := make(map[string]bool)
+	for _, entry := range c.Entries {
+		if entry.Alias == "" {
+			return fmt.Errorf("secrets: entry without an alias")
+		}
+		if seen[entry.Alias] {
+			return fmt.Errorf("secrets: duplicate alias '%s'", entry.Alias)
+		}
+		seen[entry.Alias] = true
+
+		sources := 0
+		for _, source := range []string{entry.File, entry.Env, entry.Value} {
+			if source != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("secrets: entry '%s' must set exactly one of file, env or value", entry.Alias)
+		}
+	}
+	return nil
+}
+
+// Store resolves secrets by alias
+type Store struct {
+	entries  map[string]Entry
+	confPath string
+}
+
+// NewStore creates a store for the given configuration. File paths are resolved
+// against confPath.
+func NewStore(config Config, confPath string) (*Store, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	store := &Store{entries: make(map[string]Entry), confPath: confPath}
+	for _, entry := range config.Entries {
+		store.entries[entry.Alias] = entry
+	}
+	return store, nil
+}
+
+// Get returns the secret registered under alias. Files and environment
+// variables are read on every call, so rotated secrets are picked up.
+func (s *Store) Get(alias string) ([]byte, error) {
+	entry, exists := s.entries[alias]
+	if !exists {
+		return nil, fmt.Errorf("secret '%s' not found", alias)
+	}
+	switch {
+	case entry.File != "":
+		path := entry.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.confPath, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read secret '%s': %w", alias, err)
+		}
+		return data, nil
+	case entry.Env != "":
+		value, ok := os.LookupEnv(entry.Env)
+		if !ok {
+			return nil, fmt.Errorf("secret '%s': environment variable %s is not set", alias, entry.Env)
+		}
+		return []byte(value), nil
+	default:
+		return []byte(entry.Value), nil
+	}
+}
+
+var (
+	defaultStoreMu sync.RWMutex
+	defaultStore   = &Store{entries: make(map[string]Entry)}
+)
+
+// SetDefault installs the store used by mediators to resolve key aliases
+func SetDefault(store *Store) {
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+	defaultStore = store
+}
+
+// Default returns the store installed at startup. It is empty until SetDefault
+// is called.
+func Default() *Store {
+	defaultStoreMu.RLock()
+	defer defaultStoreMu.RUnlock()
+	return defaultStore
+}
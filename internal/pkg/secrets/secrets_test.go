@@ -0,0 +1,90 @@
+/*
+ *  Licensed to the Apache Software Foundation (ASF) under one
+ *  or more contributor license agreements.  See the NOTICE file
+ *  distributed with this work for additional information
+ *  regarding copyright ownership.  The ASF licenses this file
+ *  to you under the Apache License, Version 2.0 (the
+ *  "License"); you may not use this file except in compliance
+ *  with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing,
+ *  software distributed under the License is distributed on an
+ *   * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *  KIND, either express or implied.  See the License for the
+ *  specific language governing permissions and limitations
+ *  under the License.
+ */
+
+package secrets
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "signing.pem"), keyPEM, 0o600))
+	t.Setenv("SYNAPSE_TEST_SECRET", "from-env")
+
+	store, err := NewStore(Config{Entries: []Entry{
+		{Alias: "signing", File: "signing.pem"},
+		{Alias: "env", Env: "SYNAPSE_TEST_SECRET"},
+		{Alias: "shared", Value: "base64:c2VjcmV0"},
+	}}, dir)
+	assert.NoError(t, err)
+
+	value, err := store.Get("env")
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", string(value))
+
+	shared, err := store.SymmetricKey("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(shared))
+
+	signer, err := store.PrivateKey("signing")
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, signer.Public())
+
+	// The public half of a private key can be used for verification
+	publicKey, err := store.PublicKey("signing")
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, publicKey)
+
+	_, err = store.Get("missing")
+	assert.EqualError(t, err, "secret 'missing' not found")
+	_, err = store.PrivateKey("env")
+	assert.Error(t, err)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{name: "Missing alias", config: Config{Entries: []Entry{{Value: "x"}}}, expected: "secrets: entry without an alias"},
+		{name: "Duplicate alias", config: Config{Entries: []Entry{{Alias: "a", Value: "x"}, {Alias: "a", Value: "y"}}}, expected: "secrets: duplicate alias 'a'"},
+		{name: "Several sources", config: Config{Entries: []Entry{{Alias: "a", Value: "x", Env: "X"}}}, expected: "secrets: entry 'a' must set exactly one of file, env or value"},
+		{name: "No source", config: Config{Entries: []Entry{{Alias: "a"}}}, expected: "secrets: entry 'a' must set exactly one of file, env or value"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.EqualError(t, tc.config.Validate(), tc.expected)
+		})
+	}
+}